@@ -0,0 +1,98 @@
+// Command ragadmin runs RAG maintenance jobs against the configured MySQL database.
+//
+// Usage:
+//
+//	ragadmin vacuum [-dry-run]
+//	ragadmin recount
+//	ragadmin storage
+//	ragadmin archive [-days N]
+//	ragadmin reindex
+//	ragadmin pack-embeddings [-int8]
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	appsvc "gopherai-resume/internal/app"
+	"gopherai-resume/internal/config"
+	mysqlClient "gopherai-resume/internal/platform/mysql"
+	"gopherai-resume/internal/rag"
+	"gopherai-resume/internal/repository"
+)
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("load config failed: %v", err)
+	}
+	ctx := context.Background()
+	db, err := mysqlClient.New(ctx, cfg.MySQLDSN())
+	if err != nil {
+		log.Fatalf("connect mysql failed: %v", err)
+	}
+	svc := appsvc.NewRAGMaintenanceService(
+		repository.NewRAGDocumentRepository(db),
+		repository.NewRAGChunkRepository(db),
+	)
+
+	var result interface{}
+	switch os.Args[1] {
+	case "vacuum":
+		fs := flag.NewFlagSet("vacuum", flag.ExitOnError)
+		dryRun := fs.Bool("dry-run", false, "only count orphaned chunks")
+		_ = fs.Parse(os.Args[2:])
+		result, err = svc.Vacuum(ctx, *dryRun)
+	case "recount":
+		result, err = svc.RecountChunks(ctx)
+	case "storage":
+		result, err = svc.StorageReport(ctx)
+	case "archive":
+		fs := flag.NewFlagSet("archive", flag.ExitOnError)
+		days := fs.Int("days", cfg.RAG.ArchiveAfterDays, "archive documents not retrieved for this many days")
+		_ = fs.Parse(os.Args[2:])
+		result, err = svc.ArchiveCold(ctx, *days)
+	case "reindex":
+		if cfg.RAG.VectorStore == rag.BackendMemory {
+			log.Fatalf("the memory vector index is built by the server on demand; there is nothing to reindex")
+		}
+		var store rag.VectorStore
+		store, err = rag.NewStore(rag.StoreConfig{
+			Backend:          cfg.RAG.VectorStore,
+			QdrantURL:        cfg.RAG.QdrantURL,
+			QdrantAPIKey:     cfg.RAG.QdrantAPIKey,
+			QdrantCollection: cfg.RAG.QdrantCollection,
+		})
+		if err == nil {
+			result, err = svc.Reindex(ctx, store)
+		}
+	case "pack-embeddings":
+		fs := flag.NewFlagSet("pack-embeddings", flag.ExitOnError)
+		quantize := fs.Bool("int8", cfg.RAG.EmbeddingFormat == config.EmbeddingFormatInt8, "quantize to int8 instead of float32")
+		_ = fs.Parse(os.Args[2:])
+		result, err = svc.PackEmbeddings(ctx, *quantize)
+	default:
+		usage()
+		os.Exit(2)
+	}
+	if err != nil {
+		log.Fatalf("%s failed: %v", os.Args[1], err)
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	_ = enc.Encode(result)
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: ragadmin <vacuum [-dry-run] | recount | storage | archive [-days N] | reindex | pack-embeddings [-int8]>")
+}
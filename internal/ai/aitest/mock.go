@@ -0,0 +1,157 @@
+// Package aitest provides in-memory implementations of the ai interfaces for tests
+// and offline development.
+package aitest
+
+import (
+	"context"
+	"hash/fnv"
+	"strings"
+	"sync"
+
+	"gopherai-resume/internal/ai"
+)
+
+// MockEmbedder returns deterministic vectors derived from the text, so equal texts embed equally.
+type MockEmbedder struct {
+	Dim int   // vector size; defaults to 8
+	Err error // if set, returned from every call
+
+	mu    sync.Mutex
+	Calls int
+}
+
+var _ ai.Embedder = (*MockEmbedder)(nil)
+
+func (m *MockEmbedder) Embed(ctx context.Context, cfg ai.EmbeddingConfig, text string) ([]float32, error) {
+	m.mu.Lock()
+	m.Calls++
+	m.mu.Unlock()
+	if m.Err != nil {
+		return nil, m.Err
+	}
+	return m.vector(text), nil
+}
+
+func (m *MockEmbedder) EmbedBatch(ctx context.Context, cfg ai.EmbeddingConfig, texts []string) ([][]float32, error) {
+	m.mu.Lock()
+	m.Calls++
+	m.mu.Unlock()
+	if m.Err != nil {
+		return nil, m.Err
+	}
+	out := make([][]float32, len(texts))
+	for i, t := range texts {
+		out[i] = m.vector(t)
+	}
+	return out, nil
+}
+
+func (m *MockEmbedder) vector(text string) []float32 {
+	dim := m.Dim
+	if dim <= 0 {
+		dim = 8
+	}
+	vec := make([]float32, dim)
+	for _, word := range strings.Fields(strings.ToLower(text)) {
+		h := fnv.New32a()
+		_, _ = h.Write([]byte(word))
+		vec[int(h.Sum32())%dim]++
+	}
+	return vec
+}
+
+// MockCompleter replays canned replies and records the prompts it received.
+type MockCompleter struct {
+	Replies []string // returned in order; the last one repeats
+	Err     error    // if set, returned from every call
+
+	mu      sync.Mutex
+	Prompts [][]ai.ChatMessage
+}
+
+var _ ai.Completer = (*MockCompleter)(nil)
+
+func (m *MockCompleter) Complete(ctx context.Context, cfg ai.ChatConfig, messages []ai.ChatMessage) (string, error) {
+	return m.next(messages)
+}
+
+func (m *MockCompleter) StreamComplete(
+	ctx context.Context,
+	cfg ai.ChatConfig,
+	messages []ai.ChatMessage,
+	onChunk func(chunk string) error,
+) (string, error) {
+	reply, err := m.next(messages)
+	if err != nil {
+		return "", err
+	}
+	for _, word := range strings.SplitAfter(reply, " ") {
+		if err := ctx.Err(); err != nil {
+			return "", err
+		}
+		if err := onChunk(word); err != nil {
+			return "", err
+		}
+	}
+	return reply, nil
+}
+
+func (m *MockCompleter) next(messages []ai.ChatMessage) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.Prompts = append(m.Prompts, messages)
+	if m.Err != nil {
+		return "", m.Err
+	}
+	if len(m.Replies) == 0 {
+		return "", nil
+	}
+	idx := len(m.Prompts) - 1
+	if idx >= len(m.Replies) {
+		idx = len(m.Replies) - 1
+	}
+	return m.Replies[idx], nil
+}
+
+// MockOCR returns a fixed transcription for every image.
+type MockOCR struct {
+	Text string
+	Err  error
+}
+
+var _ ai.OCR = (*MockOCR)(nil)
+
+func (m *MockOCR) RecognizeText(ctx context.Context, cfg ai.ChatConfig, image []byte, mimeType string) (string, error) {
+	if m.Err != nil {
+		return "", m.Err
+	}
+	return m.Text, nil
+}
+
+// MockToolCaller replays canned assistant messages (tool calls or final answers) in order.
+type MockToolCaller struct {
+	Replies []ai.ChatMessage // the last one repeats
+	Err     error
+
+	mu      sync.Mutex
+	Prompts [][]ai.ChatMessage
+}
+
+var _ ai.ToolCaller = (*MockToolCaller)(nil)
+
+func (m *MockToolCaller) CompleteWithTools(ctx context.Context, cfg ai.ChatConfig, messages []ai.ChatMessage, tools []ai.ToolDefinition) (ai.ChatMessage, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.Prompts = append(m.Prompts, messages)
+	if m.Err != nil {
+		return ai.ChatMessage{}, m.Err
+	}
+	if len(m.Replies) == 0 {
+		return ai.ChatMessage{Role: "assistant"}, nil
+	}
+	idx := len(m.Prompts) - 1
+	if idx >= len(m.Replies) {
+		idx = len(m.Replies) - 1
+	}
+	return m.Replies[idx], nil
+}
@@ -16,6 +16,7 @@ type EmbeddingConfig struct {
 	BaseURL string
 	APIKey  string
 	Model   string
+	Headers ProviderHeaders
 }
 
 // Embed returns the embedding vector for the given text.
@@ -39,6 +40,7 @@ func (c *OpenAICompatibleClient) Embed(ctx context.Context, cfg EmbeddingConfig,
 	if err != nil {
 		return nil, fmt.Errorf("build embedding request failed: %w", err)
 	}
+	cfg.Headers.apply(ctx, req)
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("Authorization", "Bearer "+cfg.APIKey)
 
@@ -46,6 +48,9 @@ func (c *OpenAICompatibleClient) Embed(ctx context.Context, cfg EmbeddingConfig,
 	if client == nil {
 		client = &http.Client{Timeout: 30 * time.Second}
 	}
+	if err := c.limiter.wait(ctx, req, EstimateTokens(text)); err != nil {
+		return nil, err
+	}
 	resp, err := client.Do(req)
 	if err != nil {
 		return nil, fmt.Errorf("embedding request failed: %w", err)
@@ -57,7 +62,7 @@ func (c *OpenAICompatibleClient) Embed(ctx context.Context, cfg EmbeddingConfig,
 		return nil, fmt.Errorf("read embedding response failed: %w", err)
 	}
 	if resp.StatusCode >= 300 {
-		return nil, fmt.Errorf("embedding response status %d: %s", resp.StatusCode, string(raw))
+		return nil, statusError("embedding response", resp.StatusCode, raw)
 	}
 
 	var parsed struct {
@@ -103,6 +108,7 @@ func (c *OpenAICompatibleClient) EmbedBatch(ctx context.Context, cfg EmbeddingCo
 	if err != nil {
 		return nil, fmt.Errorf("build embedding batch request failed: %w", err)
 	}
+	cfg.Headers.apply(ctx, req)
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("Authorization", "Bearer "+cfg.APIKey)
 
@@ -110,6 +116,13 @@ func (c *OpenAICompatibleClient) EmbedBatch(ctx context.Context, cfg EmbeddingCo
 	if client == nil {
 		client = &http.Client{Timeout: 60 * time.Second}
 	}
+	tokens := 0
+	for _, t := range trimmed {
+		tokens += EstimateTokens(t)
+	}
+	if err := c.limiter.wait(ctx, req, tokens); err != nil {
+		return nil, err
+	}
 	resp, err := client.Do(req)
 	if err != nil {
 		return nil, fmt.Errorf("embedding batch request failed: %w", err)
@@ -121,7 +134,7 @@ func (c *OpenAICompatibleClient) EmbedBatch(ctx context.Context, cfg EmbeddingCo
 		return nil, fmt.Errorf("read embedding batch response failed: %w", err)
 	}
 	if resp.StatusCode >= 300 {
-		return nil, fmt.Errorf("embedding batch response status %d: %s", resp.StatusCode, string(raw))
+		return nil, statusError("embedding batch response", resp.StatusCode, raw)
 	}
 
 	var parsed struct {
@@ -0,0 +1,87 @@
+package ai
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+
+	"gopherai-resume/internal/pkg/requestid"
+)
+
+// ProviderHeaders are added to every request sent to a provider, for gateway routing and
+// for correlating provider logs with ours.
+type ProviderHeaders struct {
+	// RequestID names the header that carries the request ID from the context; empty sends none.
+	RequestID string
+	// Extra headers are sent as given, e.g. routing hints or an organization ID.
+	Extra map[string]string
+	// profiles replace RequestID and Extra for the requests under their base URL. They
+	// are ordered longest base URL first, so the most specific one matches.
+	profiles []ProviderHeaderProfile
+}
+
+// ProviderHeaderProfile gives the requests under BaseURL their own headers.
+type ProviderHeaderProfile struct {
+	BaseURL   string
+	RequestID string
+	Extra     map[string]string
+}
+
+// NewProviderHeaders sends requestID and extra to every provider except those in profiles.
+// Base URLs are compared as by NewProviderLimiter; two profiles for the same base URL are
+// an error.
+func NewProviderHeaders(requestID string, extra map[string]string, profiles []ProviderHeaderProfile) (ProviderHeaders, error) {
+	normalized := make([]ProviderHeaderProfile, 0, len(profiles))
+	seen := make(map[string]bool, len(profiles))
+	for _, p := range profiles {
+		u, err := url.Parse(strings.TrimSpace(p.BaseURL))
+		if err != nil || u.Host == "" {
+			return ProviderHeaders{}, fmt.Errorf("provider headers base_url %q is not an absolute URL", p.BaseURL)
+		}
+		key := providerKey(u)
+		if seen[key] {
+			return ProviderHeaders{}, fmt.Errorf("provider headers base_url %q is configured twice", p.BaseURL)
+		}
+		seen[key] = true
+		normalized = append(normalized, ProviderHeaderProfile{BaseURL: key, RequestID: p.RequestID, Extra: p.Extra})
+	}
+	sort.Slice(normalized, func(i, j int) bool { return len(normalized[i].BaseURL) > len(normalized[j].BaseURL) })
+	return ProviderHeaders{RequestID: requestID, Extra: extra, profiles: normalized}, nil
+}
+
+// ProfilesOnly drops the default headers, which are meant for the configured provider,
+// for requests sent to another base URL. The profiles still apply under their base URLs.
+func (h ProviderHeaders) ProfilesOnly() ProviderHeaders {
+	return ProviderHeaders{profiles: h.profiles}
+}
+
+// forURL returns the request ID header and extra headers for a request URL: those of the
+// most specific profile it is under, else the defaults.
+func (h ProviderHeaders) forURL(u *url.URL) (string, map[string]string) {
+	if len(h.profiles) > 0 && u != nil {
+		key := providerKey(u)
+		for _, p := range h.profiles {
+			if key == p.BaseURL || strings.HasPrefix(key, p.BaseURL+"/") {
+				return p.RequestID, p.Extra
+			}
+		}
+	}
+	return h.RequestID, h.Extra
+}
+
+// apply sets the headers on req. It runs before Content-Type and Authorization are set, so
+// Extra cannot replace those.
+func (h ProviderHeaders) apply(ctx context.Context, req *http.Request) {
+	requestIDHeader, extra := h.forURL(req.URL)
+	for name, value := range extra {
+		req.Header.Set(name, value)
+	}
+	if requestIDHeader != "" {
+		if id := requestid.From(ctx); id != "" {
+			req.Header.Set(requestIDHeader, id)
+		}
+	}
+}
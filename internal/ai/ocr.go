@@ -0,0 +1,81 @@
+package ai
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+const ocrPrompt = "Transcribe all text in this image exactly as written, preserving line breaks and reading order. Output only the transcribed text, with no commentary."
+
+// RecognizeText runs OCR by sending the image to an OpenAI-compatible vision chat model
+// (e.g. qwen-vl-ocr, gpt-4o-mini) as a data URL.
+func (c *OpenAICompatibleClient) RecognizeText(ctx context.Context, cfg ChatConfig, image []byte, mimeType string) (string, error) {
+	if len(image) == 0 {
+		return "", fmt.Errorf("ocr image is empty")
+	}
+	dataURL := "data:" + mimeType + ";base64," + base64.StdEncoding.EncodeToString(image)
+	reqBody := map[string]interface{}{
+		"model": cfg.Model,
+		"messages": []map[string]interface{}{
+			{
+				"role": "user",
+				"content": []map[string]interface{}{
+					{"type": "image_url", "image_url": map[string]string{"url": dataURL}},
+					{"type": "text", "text": ocrPrompt},
+				},
+			},
+		},
+		"stream": false,
+	}
+	bodyBytes, err := json.Marshal(reqBody)
+	if err != nil {
+		return "", fmt.Errorf("marshal ocr request failed: %w", err)
+	}
+
+	url := strings.TrimRight(cfg.BaseURL, "/") + "/chat/completions"
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(bodyBytes))
+	if err != nil {
+		return "", fmt.Errorf("build ocr request failed: %w", err)
+	}
+	cfg.Headers.apply(ctx, req)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+cfg.APIKey)
+
+	if err := c.limiter.wait(ctx, req, EstimateTokens(ocrPrompt)+imageTokenEstimate); err != nil {
+		return "", err
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("ocr request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	raw, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("read ocr response failed: %w", err)
+	}
+	if resp.StatusCode >= 300 {
+		return "", statusError("ocr response", resp.StatusCode, raw)
+	}
+
+	var parsed struct {
+		Choices []struct {
+			Message struct {
+				Content string `json:"content"`
+			} `json:"message"`
+		} `json:"choices"`
+	}
+	if err := json.Unmarshal(raw, &parsed); err != nil {
+		return "", fmt.Errorf("parse ocr json failed: %w", err)
+	}
+	if len(parsed.Choices) == 0 {
+		return "", fmt.Errorf("empty ocr choices")
+	}
+	return strings.TrimSpace(parsed.Choices[0].Message.Content), nil
+}
@@ -0,0 +1,224 @@
+package ai
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+	"sync"
+
+	ort "github.com/yalue/onnxruntime_go"
+
+	"gopherai-resume/internal/onnx"
+)
+
+const defaultONNXEmbeddingMaxTokens = 256
+
+// ONNXEmbedder runs a sentence-transformers model (e.g. all-MiniLM-L6-v2 exported to ONNX)
+// locally with mean pooling and L2 normalization. The EmbeddingConfig passed to its methods is
+// ignored; the model is fixed at construction.
+type ONNXEmbedder struct {
+	mu sync.Mutex
+
+	modelPath string
+	vocabPath string
+	libPath   string
+	maxTokens int
+
+	tokenizer  *wordPieceTokenizer
+	session    *ort.DynamicAdvancedSession
+	inputNames []string
+	outputDims int // 2 = already pooled [batch, hidden], 3 = token states [batch, seq, hidden]
+	hiddenSize int64
+	inited     bool
+}
+
+var _ Embedder = (*ONNXEmbedder)(nil)
+
+// NewONNXEmbedder creates an embedder that lazily loads the model and vocabulary on first use.
+func NewONNXEmbedder(modelPath, vocabPath, onnxLibPath string, maxTokens int) *ONNXEmbedder {
+	if maxTokens <= 0 {
+		maxTokens = defaultONNXEmbeddingMaxTokens
+	}
+	return &ONNXEmbedder{
+		modelPath: modelPath,
+		vocabPath: vocabPath,
+		libPath:   onnxLibPath,
+		maxTokens: maxTokens,
+	}
+}
+
+func (e *ONNXEmbedder) initLocked() error {
+	if e.inited {
+		return nil
+	}
+	if err := onnx.InitEnvironment(e.libPath); err != nil {
+		return err
+	}
+	tokenizer, err := loadWordPieceTokenizer(e.vocabPath)
+	if err != nil {
+		return err
+	}
+
+	inputs, outputs, err := ort.GetInputOutputInfo(e.modelPath)
+	if err != nil {
+		return fmt.Errorf("onnx get input/output info: %w", err)
+	}
+	if len(inputs) == 0 || len(outputs) == 0 {
+		return fmt.Errorf("onnx embedding model has no inputs or outputs")
+	}
+	inputNames := make([]string, len(inputs))
+	for i := range inputs {
+		switch inputs[i].Name {
+		case "input_ids", "attention_mask", "token_type_ids":
+		default:
+			return fmt.Errorf("unsupported embedding model input %q", inputs[i].Name)
+		}
+		inputNames[i] = inputs[i].Name
+	}
+	outDims := outputs[0].Dimensions
+	if len(outDims) != 2 && len(outDims) != 3 {
+		return fmt.Errorf("unsupported embedding output shape %v", outDims)
+	}
+	hidden := outDims[len(outDims)-1]
+	if hidden <= 0 {
+		return fmt.Errorf("embedding output has dynamic hidden size")
+	}
+
+	session, err := ort.NewDynamicAdvancedSession(e.modelPath, inputNames, []string{outputs[0].Name}, nil)
+	if err != nil {
+		return fmt.Errorf("onnx new embedding session: %w", err)
+	}
+	e.tokenizer = tokenizer
+	e.session = session
+	e.inputNames = inputNames
+	e.outputDims = len(outDims)
+	e.hiddenSize = hidden
+	e.inited = true
+	return nil
+}
+
+func (e *ONNXEmbedder) Embed(ctx context.Context, cfg EmbeddingConfig, text string) ([]float32, error) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return nil, fmt.Errorf("embedding input is empty")
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if err := e.initLocked(); err != nil {
+		return nil, err
+	}
+	return e.embedLocked(text)
+}
+
+func (e *ONNXEmbedder) EmbedBatch(ctx context.Context, cfg EmbeddingConfig, texts []string) ([][]float32, error) {
+	if len(texts) == 0 {
+		return nil, nil
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if err := e.initLocked(); err != nil {
+		return nil, err
+	}
+	result := make([][]float32, 0, len(texts))
+	for _, t := range texts {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		t = strings.TrimSpace(t)
+		if t == "" {
+			continue
+		}
+		vec, err := e.embedLocked(t)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, vec)
+	}
+	if len(result) == 0 {
+		return nil, fmt.Errorf("no non-empty texts for embedding")
+	}
+	return result, nil
+}
+
+func (e *ONNXEmbedder) embedLocked(text string) ([]float32, error) {
+	ids := e.tokenizer.Encode(text, e.maxTokens)
+	seqLen := int64(len(ids))
+	shape := ort.NewShape(1, seqLen)
+
+	mask := make([]int64, len(ids))
+	for i := range mask {
+		mask[i] = 1
+	}
+	inputs := make([]ort.Value, len(e.inputNames))
+	defer func() {
+		for _, v := range inputs {
+			if v != nil {
+				_ = v.Destroy()
+			}
+		}
+	}()
+	for i, name := range e.inputNames {
+		var data []int64
+		switch name {
+		case "input_ids":
+			data = ids
+		case "attention_mask":
+			data = mask
+		case "token_type_ids":
+			data = make([]int64, len(ids))
+		}
+		tensor, err := ort.NewTensor(shape, data)
+		if err != nil {
+			return nil, fmt.Errorf("onnx new input tensor: %w", err)
+		}
+		inputs[i] = tensor
+	}
+
+	outShape := ort.NewShape(1, e.hiddenSize)
+	if e.outputDims == 3 {
+		outShape = ort.NewShape(1, seqLen, e.hiddenSize)
+	}
+	output, err := ort.NewEmptyTensor[float32](outShape)
+	if err != nil {
+		return nil, fmt.Errorf("onnx new output tensor: %w", err)
+	}
+	defer output.Destroy()
+
+	if err := e.session.Run(inputs, []ort.Value{output}); err != nil {
+		return nil, fmt.Errorf("onnx embedding run: %w", err)
+	}
+
+	out := output.GetData()
+	vec := make([]float32, e.hiddenSize)
+	if e.outputDims == 3 {
+		// Mean pooling over tokens; every token is attended since inputs are unpadded.
+		for t := int64(0); t < seqLen; t++ {
+			row := out[t*e.hiddenSize : (t+1)*e.hiddenSize]
+			for i := range vec {
+				vec[i] += row[i]
+			}
+		}
+		for i := range vec {
+			vec[i] /= float32(seqLen)
+		}
+	} else {
+		copy(vec, out)
+	}
+	normalize(vec)
+	return vec, nil
+}
+
+func normalize(vec []float32) {
+	var sum float64
+	for _, v := range vec {
+		sum += float64(v) * float64(v)
+	}
+	if sum == 0 {
+		return
+	}
+	inv := float32(1 / math.Sqrt(sum))
+	for i := range vec {
+		vec[i] *= inv
+	}
+}
@@ -15,30 +15,71 @@ import (
 type ChatMessage struct {
 	Role    string `json:"role"`
 	Content string `json:"content"`
+	// ToolCalls is set on assistant messages that request tool execution.
+	ToolCalls []ToolCall `json:"tool_calls,omitempty"`
+	// ToolCallID links a "tool" role message to the call it answers.
+	ToolCallID string `json:"tool_call_id,omitempty"`
 }
 
 type ChatConfig struct {
 	BaseURL string
 	APIKey  string
 	Model   string
+	// Sampling parameters; nil or 0 leaves the provider default.
+	Temperature *float64
+	TopP        *float64
+	MaxTokens   int
+	// Stop lists sequences at which the model stops generating; empty sends none.
+	Stop    []string
+	Headers ProviderHeaders
+}
+
+// setSampling adds the configured sampling parameters to a chat completions request body.
+func (cfg ChatConfig) setSampling(reqBody map[string]interface{}) {
+	if cfg.Temperature != nil {
+		reqBody["temperature"] = *cfg.Temperature
+	}
+	if cfg.TopP != nil {
+		reqBody["top_p"] = *cfg.TopP
+	}
+	if cfg.MaxTokens > 0 {
+		reqBody["max_tokens"] = cfg.MaxTokens
+	}
+	if len(cfg.Stop) > 0 {
+		reqBody["stop"] = cfg.Stop
+	}
 }
 
 type OpenAICompatibleClient struct {
 	httpClient *http.Client
+	// limiter paces requests per provider; nil sends them at once.
+	limiter *ProviderLimiter
 }
 
-func NewOpenAICompatibleClient() *OpenAICompatibleClient {
+func NewOpenAICompatibleClient(limiter *ProviderLimiter) *OpenAICompatibleClient {
 	return &OpenAICompatibleClient{
-		httpClient: &http.Client{Timeout: 90 * time.Second},
+		httpClient: &http.Client{Timeout: 90 * time.Second, Transport: payloadLogger{next: http.DefaultTransport}},
+		limiter:    limiter,
 	}
 }
 
+// promptTokens estimates what a chat request counts against a provider's token limit: its
+// messages plus the completion it may produce.
+func promptTokens(cfg ChatConfig, messages []ChatMessage) int {
+	tokens := cfg.MaxTokens
+	for _, m := range messages {
+		tokens += EstimateMessageTokens(m)
+	}
+	return tokens
+}
+
 func (c *OpenAICompatibleClient) Complete(ctx context.Context, cfg ChatConfig, messages []ChatMessage) (string, error) {
 	reqBody := map[string]interface{}{
 		"model":    cfg.Model,
 		"messages": messages,
 		"stream":   false,
 	}
+	cfg.setSampling(reqBody)
 
 	bodyBytes, err := json.Marshal(reqBody)
 	if err != nil {
@@ -50,9 +91,13 @@ func (c *OpenAICompatibleClient) Complete(ctx context.Context, cfg ChatConfig, m
 	if err != nil {
 		return "", fmt.Errorf("build llm request failed: %w", err)
 	}
+	cfg.Headers.apply(ctx, req)
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("Authorization", "Bearer "+cfg.APIKey)
 
+	if err := c.limiter.wait(ctx, req, promptTokens(cfg, messages)); err != nil {
+		return "", err
+	}
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
 		return "", fmt.Errorf("llm request failed: %w", err)
@@ -64,7 +109,7 @@ func (c *OpenAICompatibleClient) Complete(ctx context.Context, cfg ChatConfig, m
 		return "", fmt.Errorf("read llm response failed: %w", err)
 	}
 	if resp.StatusCode >= 300 {
-		return "", fmt.Errorf("llm response status %d: %s", resp.StatusCode, string(raw))
+		return "", statusError("llm response", resp.StatusCode, raw)
 	}
 
 	var parsed struct {
@@ -73,10 +118,12 @@ func (c *OpenAICompatibleClient) Complete(ctx context.Context, cfg ChatConfig, m
 				Content string `json:"content"`
 			} `json:"message"`
 		} `json:"choices"`
+		Usage *Usage `json:"usage"`
 	}
 	if err := json.Unmarshal(raw, &parsed); err != nil {
 		return "", fmt.Errorf("parse llm json failed: %w", err)
 	}
+	recordUsage(ctx, parsed.Usage)
 	if len(parsed.Choices) == 0 {
 		return "", fmt.Errorf("empty llm choices")
 	}
@@ -93,7 +140,10 @@ func (c *OpenAICompatibleClient) StreamComplete(
 		"model":    cfg.Model,
 		"messages": messages,
 		"stream":   true,
+		// Ask for a final chunk with the token usage; providers without it ignore the option.
+		"stream_options": map[string]interface{}{"include_usage": true},
 	}
+	cfg.setSampling(reqBody)
 	bodyBytes, err := json.Marshal(reqBody)
 	if err != nil {
 		return "", fmt.Errorf("marshal llm stream request failed: %w", err)
@@ -104,9 +154,13 @@ func (c *OpenAICompatibleClient) StreamComplete(
 	if err != nil {
 		return "", fmt.Errorf("build llm stream request failed: %w", err)
 	}
+	cfg.Headers.apply(ctx, req)
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("Authorization", "Bearer "+cfg.APIKey)
 
+	if err := c.limiter.wait(ctx, req, promptTokens(cfg, messages)); err != nil {
+		return "", err
+	}
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
 		return "", fmt.Errorf("llm stream request failed: %w", err)
@@ -115,7 +169,7 @@ func (c *OpenAICompatibleClient) StreamComplete(
 
 	if resp.StatusCode >= 300 {
 		raw, _ := io.ReadAll(resp.Body)
-		return "", fmt.Errorf("llm stream status %d: %s", resp.StatusCode, string(raw))
+		return "", statusError("llm stream", resp.StatusCode, raw)
 	}
 
 	scanner := bufio.NewScanner(resp.Body)
@@ -141,10 +195,12 @@ func (c *OpenAICompatibleClient) StreamComplete(
 					Content string `json:"content"`
 				} `json:"delta"`
 			} `json:"choices"`
+			Usage *Usage `json:"usage"`
 		}
 		if err := json.Unmarshal([]byte(payload), &chunk); err != nil {
 			continue
 		}
+		recordUsage(ctx, chunk.Usage)
 		if len(chunk.Choices) == 0 {
 			continue
 		}
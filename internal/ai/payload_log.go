@@ -0,0 +1,70 @@
+package ai
+
+import (
+	"bytes"
+	"io"
+	"log"
+	"net/http"
+	"sync"
+
+	"gopherai-resume/internal/pkg/opsflags"
+	"gopherai-resume/internal/pkg/requestid"
+)
+
+// maxLoggedPayload is how much of each request and response body is logged.
+const maxLoggedPayload = 4 << 10
+
+// payloadLogger logs provider request and response bodies while the llm_payload_logging ops
+// flag is on. Headers are never logged, so neither are API keys. Streamed responses are
+// logged when their body is closed.
+type payloadLogger struct {
+	next http.RoundTripper
+}
+
+func (t payloadLogger) RoundTrip(req *http.Request) (*http.Response, error) {
+	if !opsflags.Get().LLMPayloadLogging {
+		return t.next.RoundTrip(req)
+	}
+	id := requestid.From(req.Context())
+	var body []byte
+	if req.GetBody != nil {
+		if rc, err := req.GetBody(); err == nil {
+			body, _ = io.ReadAll(io.LimitReader(rc, maxLoggedPayload))
+			rc.Close()
+		}
+	}
+	log.Printf("llm payload: %s %s request_id=%s request=%s", req.Method, req.URL.Redacted(), id, body)
+
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = &loggedBody{ReadCloser: resp.Body, status: resp.StatusCode, url: req.URL.Redacted(), requestID: id}
+	return resp, nil
+}
+
+// loggedBody keeps the start of a response body as it is read and logs it on Close.
+type loggedBody struct {
+	io.ReadCloser
+	status    int
+	url       string
+	requestID string
+
+	buf  bytes.Buffer
+	once sync.Once
+}
+
+func (b *loggedBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if room := maxLoggedPayload - b.buf.Len(); room > 0 && n > 0 {
+		b.buf.Write(p[:min(n, room)])
+	}
+	return n, err
+}
+
+func (b *loggedBody) Close() error {
+	b.once.Do(func() {
+		log.Printf("llm payload: %s status=%d request_id=%s response=%s", b.url, b.status, b.requestID, b.buf.Bytes())
+	})
+	return b.ReadCloser.Close()
+}
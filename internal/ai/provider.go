@@ -0,0 +1,32 @@
+package ai
+
+import "context"
+
+// Embedder turns text into embedding vectors.
+type Embedder interface {
+	Embed(ctx context.Context, cfg EmbeddingConfig, text string) ([]float32, error)
+	EmbedBatch(ctx context.Context, cfg EmbeddingConfig, texts []string) ([][]float32, error)
+}
+
+// Completer produces chat completions, either in one shot or streamed chunk by chunk.
+type Completer interface {
+	Complete(ctx context.Context, cfg ChatConfig, messages []ChatMessage) (string, error)
+	StreamComplete(ctx context.Context, cfg ChatConfig, messages []ChatMessage, onChunk func(chunk string) error) (string, error)
+}
+
+// Reranker scores candidate documents against a query with a dedicated rerank model.
+type Reranker interface {
+	Rerank(ctx context.Context, cfg RerankConfig, query string, documents []string) ([]float64, error)
+}
+
+// OCR extracts the text shown in an image.
+type OCR interface {
+	RecognizeText(ctx context.Context, cfg ChatConfig, image []byte, mimeType string) (string, error)
+}
+
+var (
+	_ Embedder  = (*OpenAICompatibleClient)(nil)
+	_ Completer = (*OpenAICompatibleClient)(nil)
+	_ OCR       = (*OpenAICompatibleClient)(nil)
+	_ Reranker  = (*OpenAICompatibleClient)(nil)
+)
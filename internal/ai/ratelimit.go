@@ -0,0 +1,301 @@
+package ai
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"gopherai-resume/internal/pkg/apperr"
+	"gopherai-resume/internal/pkg/authz"
+	"gopherai-resume/internal/pkg/opsflags"
+)
+
+// ErrProviderBusy is returned when a request waited too long for the provider's rate limit,
+// or when the provider itself answered 429.
+var ErrProviderBusy = apperr.New(http.StatusServiceUnavailable, apperr.CodeProviderBusy, "the AI provider is busy, please try again shortly")
+
+// imageTokenEstimate is what an OCR request's image is counted as against a token limit.
+const imageTokenEstimate = 1000
+
+// RateLimit caps what is sent to one provider per minute; 0 leaves a dimension unlimited.
+type RateLimit struct {
+	RequestsPerMinute int
+	TokensPerMinute   int
+}
+
+func (r RateLimit) unlimited() bool {
+	return r.RequestsPerMinute <= 0 && r.TokensPerMinute <= 0
+}
+
+// ProviderRateLimit overrides the default limit for the provider at BaseURL.
+type ProviderRateLimit struct {
+	BaseURL string
+	RateLimit
+}
+
+// ProviderLimiter keeps a token bucket per provider, so bursts are smoothed out on our
+// side instead of being answered with 429 by the provider. A provider with its own limit
+// is the requests under its base URL; every other host is a provider of its own under the
+// default limit. Requests over the limit queue; users take turns, one request each, so
+// one user's burst cannot hold the others up. Requests made outside a user's HTTP
+// request, e.g. by workers, share one turn. A nil *ProviderLimiter lets everything through.
+type ProviderLimiter struct {
+	defaults RateLimit
+	// providers is ordered longest base URL first, so the most specific one matches.
+	providers []ProviderRateLimit
+	maxWait   time.Duration
+
+	mu      sync.Mutex
+	buckets map[string]*providerBucket
+}
+
+// NewProviderLimiter applies defaults to every provider except those in providers. Base
+// URLs are compared without a trailing slash and with the scheme and host in lower case;
+// two entries for the same base URL are an error. A request gives up with
+// ErrProviderBusy after waiting maxWait.
+func NewProviderLimiter(defaults RateLimit, providers []ProviderRateLimit, maxWait time.Duration) (*ProviderLimiter, error) {
+	if maxWait <= 0 {
+		maxWait = 30 * time.Second
+	}
+	normalized := make([]ProviderRateLimit, 0, len(providers))
+	seen := make(map[string]bool, len(providers))
+	for _, p := range providers {
+		u, err := url.Parse(strings.TrimSpace(p.BaseURL))
+		if err != nil || u.Host == "" {
+			return nil, fmt.Errorf("provider limit base_url %q is not an absolute URL", p.BaseURL)
+		}
+		key := providerKey(u)
+		if seen[key] {
+			return nil, fmt.Errorf("provider limit base_url %q is configured twice", p.BaseURL)
+		}
+		seen[key] = true
+		normalized = append(normalized, ProviderRateLimit{BaseURL: key, RateLimit: p.RateLimit})
+	}
+	sort.Slice(normalized, func(i, j int) bool { return len(normalized[i].BaseURL) > len(normalized[j].BaseURL) })
+	return &ProviderLimiter{
+		defaults:  defaults,
+		providers: normalized,
+		maxWait:   maxWait,
+		buckets:   make(map[string]*providerBucket),
+	}, nil
+}
+
+// provider returns the bucket key and limit for a request URL: the most specific
+// configured base URL it is under, else its host with the default limit.
+func (l *ProviderLimiter) provider(u *url.URL) (string, RateLimit) {
+	key := providerKey(u)
+	for _, p := range l.providers {
+		if key == p.BaseURL || strings.HasPrefix(key, p.BaseURL+"/") {
+			return p.BaseURL, p.RateLimit
+		}
+	}
+	return strings.ToLower(u.Host), l.defaults
+}
+
+// wait blocks until the provider serving req has capacity for one request of tokens.
+func (l *ProviderLimiter) wait(ctx context.Context, req *http.Request, tokens int) error {
+	if l == nil {
+		return nil
+	}
+	provider, limit := l.provider(req.URL)
+	user := ""
+	if p, ok := authz.From(ctx); ok {
+		user = strconv.FormatUint(uint64(p.UserID), 10)
+	}
+
+	l.mu.Lock()
+	b := l.bucket(provider, limit)
+	if b == nil {
+		l.mu.Unlock()
+		return nil
+	}
+	// A request larger than the whole budget would never fit; it waits for a full bucket.
+	if tpm := b.limit.TokensPerMinute; tpm > 0 && tokens > tpm {
+		tokens = tpm
+	}
+	w := &rateWaiter{user: user, tokens: float64(tokens), ready: make(chan struct{})}
+	b.enqueue(w)
+	b.dispatch(l, time.Now())
+	l.mu.Unlock()
+
+	queued := time.Now()
+	timeout := time.NewTimer(l.maxWait)
+	defer timeout.Stop()
+	var err error
+	select {
+	case <-w.ready:
+		if waited := time.Since(queued); waited >= time.Millisecond {
+			opsflags.Debugf("provider limiter: user %q waited %s for %s", user, waited.Round(time.Millisecond), provider)
+		}
+		return nil
+	case <-ctx.Done():
+		err = ctx.Err()
+	case <-timeout.C:
+		err = ErrProviderBusy.Wrap(fmt.Errorf("waited %s for %s rate limit", l.maxWait, provider))
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if !b.remove(w) {
+		// Granted while giving up; the capacity is taken, so go ahead.
+		return nil
+	}
+	b.dispatch(l, time.Now())
+	return err
+}
+
+// bucket returns the provider's bucket, or nil when it is unlimited. l.mu must be held.
+func (l *ProviderLimiter) bucket(provider string, limit RateLimit) *providerBucket {
+	if b, ok := l.buckets[provider]; ok {
+		return b
+	}
+	if limit.unlimited() {
+		l.buckets[provider] = nil
+		return nil
+	}
+	b := &providerBucket{
+		limit:    limit,
+		requests: float64(limit.RequestsPerMinute),
+		tokens:   float64(limit.TokensPerMinute),
+		updated:  time.Now(),
+		queues:   make(map[string][]*rateWaiter),
+	}
+	l.buckets[provider] = b
+	return b
+}
+
+type rateWaiter struct {
+	user   string
+	tokens float64
+	ready  chan struct{}
+}
+
+// providerBucket refills a minute's worth of requests and tokens per minute. Its fields
+// are guarded by the limiter's mutex.
+type providerBucket struct {
+	limit    RateLimit
+	requests float64
+	tokens   float64
+	updated  time.Time
+
+	queues map[string][]*rateWaiter
+	// order lists the users with queued requests; the first one is served next.
+	order []string
+	timer *time.Timer
+}
+
+func (b *providerBucket) enqueue(w *rateWaiter) {
+	if len(b.queues[w.user]) == 0 {
+		b.order = append(b.order, w.user)
+	}
+	b.queues[w.user] = append(b.queues[w.user], w)
+}
+
+// remove takes w out of the queue and reports whether it was still waiting.
+func (b *providerBucket) remove(w *rateWaiter) bool {
+	queue := b.queues[w.user]
+	for i, q := range queue {
+		if q != w {
+			continue
+		}
+		queue = append(queue[:i], queue[i+1:]...)
+		if len(queue) > 0 {
+			b.queues[w.user] = queue
+			return true
+		}
+		delete(b.queues, w.user)
+		for j, u := range b.order {
+			if u == w.user {
+				b.order = append(b.order[:j], b.order[j+1:]...)
+				break
+			}
+		}
+		return true
+	}
+	return false
+}
+
+func (b *providerBucket) refill(now time.Time) {
+	elapsed := now.Sub(b.updated).Minutes()
+	b.updated = now
+	if rpm := float64(b.limit.RequestsPerMinute); rpm > 0 {
+		b.requests = math.Min(rpm, b.requests+elapsed*rpm)
+	}
+	if tpm := float64(b.limit.TokensPerMinute); tpm > 0 {
+		b.tokens = math.Min(tpm, b.tokens+elapsed*tpm)
+	}
+}
+
+// dispatch grants queued requests in turn while there is capacity, and otherwise sets a
+// timer for when the next one fits.
+func (b *providerBucket) dispatch(l *ProviderLimiter, now time.Time) {
+	b.refill(now)
+	for len(b.order) > 0 {
+		user := b.order[0]
+		w := b.queues[user][0]
+		if wait := b.shortfall(w.tokens); wait > 0 {
+			if b.timer != nil {
+				b.timer.Stop()
+			}
+			b.timer = time.AfterFunc(wait, func() {
+				l.mu.Lock()
+				defer l.mu.Unlock()
+				b.timer = nil
+				b.dispatch(l, time.Now())
+			})
+			return
+		}
+		if b.limit.RequestsPerMinute > 0 {
+			b.requests--
+		}
+		if b.limit.TokensPerMinute > 0 {
+			b.tokens -= w.tokens
+		}
+		close(w.ready)
+
+		// The user goes to the back of the line if they have more requests waiting.
+		b.order = b.order[1:]
+		if rest := b.queues[user][1:]; len(rest) > 0 {
+			b.queues[user] = rest
+			b.order = append(b.order, user)
+		} else {
+			delete(b.queues, user)
+		}
+	}
+}
+
+// shortfall returns how long until a request of tokens fits; 0 when it fits now.
+func (b *providerBucket) shortfall(tokens float64) time.Duration {
+	var wait float64 // minutes
+	if rpm := float64(b.limit.RequestsPerMinute); rpm > 0 && b.requests < 1 {
+		wait = math.Max(wait, (1-b.requests)/rpm)
+	}
+	if tpm := float64(b.limit.TokensPerMinute); tpm > 0 && b.tokens < tokens {
+		wait = math.Max(wait, (tokens-b.tokens)/tpm)
+	}
+	if wait == 0 {
+		return 0
+	}
+	return time.Duration(wait*float64(time.Minute)) + time.Millisecond
+}
+
+// providerKey is u without query or trailing slash, with the scheme and host in lower case.
+func providerKey(u *url.URL) string {
+	return strings.ToLower(u.Scheme) + "://" + strings.ToLower(u.Host) + strings.TrimRight(u.Path, "/")
+}
+
+// statusError describes an unsuccessful provider response; a 429 is ErrProviderBusy.
+func statusError(what string, status int, raw []byte) error {
+	err := fmt.Errorf("%s status %d: %s", what, status, string(raw))
+	if status == http.StatusTooManyRequests {
+		return ErrProviderBusy.Wrap(err)
+	}
+	return err
+}
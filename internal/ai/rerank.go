@@ -0,0 +1,99 @@
+package ai
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// RerankConfig holds settings for a DashScope-style text rerank API (e.g. gte-rerank).
+// URL is the full endpoint, not a base URL.
+type RerankConfig struct {
+	URL     string
+	APIKey  string
+	Model   string
+	Headers ProviderHeaders
+}
+
+// Rerank scores how relevant each document is to query. Scores are index-aligned with
+// documents; higher is more relevant.
+func (c *OpenAICompatibleClient) Rerank(ctx context.Context, cfg RerankConfig, query string, documents []string) ([]float64, error) {
+	if len(documents) == 0 {
+		return nil, nil
+	}
+	reqBody := map[string]interface{}{
+		"model": cfg.Model,
+		"input": map[string]interface{}{
+			"query":     query,
+			"documents": documents,
+		},
+		"parameters": map[string]interface{}{
+			"top_n":            len(documents),
+			"return_documents": false,
+		},
+	}
+	bodyBytes, err := json.Marshal(reqBody)
+	if err != nil {
+		return nil, fmt.Errorf("marshal rerank request failed: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, cfg.URL, bytes.NewReader(bodyBytes))
+	if err != nil {
+		return nil, fmt.Errorf("build rerank request failed: %w", err)
+	}
+	cfg.Headers.apply(ctx, req)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+cfg.APIKey)
+
+	client := c.httpClient
+	if client == nil {
+		client = &http.Client{Timeout: 30 * time.Second}
+	}
+	tokens := EstimateTokens(query)
+	for _, d := range documents {
+		tokens += EstimateTokens(d)
+	}
+	if err := c.limiter.wait(ctx, req, tokens); err != nil {
+		return nil, err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("rerank request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	raw, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("read rerank response failed: %w", err)
+	}
+	if resp.StatusCode >= 300 {
+		return nil, statusError("rerank response", resp.StatusCode, raw)
+	}
+
+	var parsed struct {
+		Output struct {
+			Results []struct {
+				Index          int     `json:"index"`
+				RelevanceScore float64 `json:"relevance_score"`
+			} `json:"results"`
+		} `json:"output"`
+	}
+	if err := json.Unmarshal(raw, &parsed); err != nil {
+		return nil, fmt.Errorf("parse rerank json failed: %w", err)
+	}
+	if len(parsed.Output.Results) == 0 {
+		return nil, fmt.Errorf("empty rerank results in response")
+	}
+	scores := make([]float64, len(documents))
+	for _, r := range parsed.Output.Results {
+		if r.Index < 0 || r.Index >= len(documents) {
+			return nil, fmt.Errorf("rerank result index %d out of range", r.Index)
+		}
+		scores[r.Index] = r.RelevanceScore
+	}
+	return scores, nil
+}
@@ -0,0 +1,56 @@
+package ai
+
+import (
+	"unicode"
+	"unicode/utf8"
+)
+
+// messageTokenOverhead is what chat formats add per message (role and separators).
+const messageTokenOverhead = 4
+
+// EstimateTokens approximates how many tokens a BPE tokenizer (cl100k-style) produces for
+// text, without shipping a vocabulary. Latin words cost about one token per four letters,
+// digits one per three, each punctuation mark and CJK character one, and other non-ASCII
+// runes about one per two. It tends to overestimate slightly, which is the safe side when
+// budgeting a context window.
+func EstimateTokens(text string) int {
+	tokens := 0
+	letters, digits, other := 0, 0, 0
+	flush := func() {
+		tokens += (letters+3)/4 + (digits+2)/3 + (other+1)/2
+		letters, digits, other = 0, 0, 0
+	}
+	for _, r := range text {
+		switch {
+		case r < utf8.RuneSelf && (unicode.IsLetter(r) || r == '\''):
+			if digits > 0 {
+				flush()
+			}
+			letters++
+		case unicode.IsDigit(r):
+			if letters > 0 {
+				flush()
+			}
+			digits++
+		case unicode.IsSpace(r):
+			// A space is merged into the following word's token.
+			flush()
+		case unicode.Is(unicode.Han, r) || unicode.Is(unicode.Hiragana, r) ||
+			unicode.Is(unicode.Katakana, r) || unicode.Is(unicode.Hangul, r):
+			flush()
+			tokens++
+		case r >= utf8.RuneSelf && unicode.IsLetter(r):
+			other++
+		default:
+			flush()
+			tokens++
+		}
+	}
+	flush()
+	return tokens
+}
+
+// EstimateMessageTokens approximates the prompt tokens of a chat message.
+func EstimateMessageTokens(msg ChatMessage) int {
+	return messageTokenOverhead + EstimateTokens(msg.Content)
+}
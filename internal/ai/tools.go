@@ -0,0 +1,113 @@
+package ai
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// ToolDefinition describes a function the model may call. Parameters is a JSON Schema object.
+type ToolDefinition struct {
+	Name        string
+	Description string
+	Parameters  map[string]interface{}
+}
+
+// ToolCall is a function call requested by the model.
+type ToolCall struct {
+	ID       string           `json:"id"`
+	Type     string           `json:"type"`
+	Function ToolCallFunction `json:"function"`
+}
+
+type ToolCallFunction struct {
+	Name      string `json:"name"`
+	Arguments string `json:"arguments"` // JSON-encoded arguments
+}
+
+// ToolCaller runs one chat-completion round with tools available. The returned assistant
+// message either has ToolCalls to execute or a final Content.
+type ToolCaller interface {
+	CompleteWithTools(ctx context.Context, cfg ChatConfig, messages []ChatMessage, tools []ToolDefinition) (ChatMessage, error)
+}
+
+var _ ToolCaller = (*OpenAICompatibleClient)(nil)
+
+func (c *OpenAICompatibleClient) CompleteWithTools(
+	ctx context.Context,
+	cfg ChatConfig,
+	messages []ChatMessage,
+	tools []ToolDefinition,
+) (ChatMessage, error) {
+	toolSpecs := make([]map[string]interface{}, len(tools))
+	for i, t := range tools {
+		toolSpecs[i] = map[string]interface{}{
+			"type": "function",
+			"function": map[string]interface{}{
+				"name":        t.Name,
+				"description": t.Description,
+				"parameters":  t.Parameters,
+			},
+		}
+	}
+	reqBody := map[string]interface{}{
+		"model":    cfg.Model,
+		"messages": messages,
+		"tools":    toolSpecs,
+		"stream":   false,
+	}
+	cfg.setSampling(reqBody)
+	bodyBytes, err := json.Marshal(reqBody)
+	if err != nil {
+		return ChatMessage{}, fmt.Errorf("marshal llm tool request failed: %w", err)
+	}
+
+	url := strings.TrimRight(cfg.BaseURL, "/") + "/chat/completions"
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(bodyBytes))
+	if err != nil {
+		return ChatMessage{}, fmt.Errorf("build llm tool request failed: %w", err)
+	}
+	cfg.Headers.apply(ctx, req)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+cfg.APIKey)
+
+	if err := c.limiter.wait(ctx, req, promptTokens(cfg, messages)); err != nil {
+		return ChatMessage{}, err
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return ChatMessage{}, fmt.Errorf("llm tool request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	raw, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return ChatMessage{}, fmt.Errorf("read llm tool response failed: %w", err)
+	}
+	if resp.StatusCode >= 300 {
+		return ChatMessage{}, statusError("llm tool response", resp.StatusCode, raw)
+	}
+
+	var parsed struct {
+		Choices []struct {
+			Message ChatMessage `json:"message"`
+		} `json:"choices"`
+		Usage *Usage `json:"usage"`
+	}
+	if err := json.Unmarshal(raw, &parsed); err != nil {
+		return ChatMessage{}, fmt.Errorf("parse llm tool json failed: %w", err)
+	}
+	recordUsage(ctx, parsed.Usage)
+	if len(parsed.Choices) == 0 {
+		return ChatMessage{}, fmt.Errorf("empty llm choices")
+	}
+	msg := parsed.Choices[0].Message
+	if msg.Role == "" {
+		msg.Role = "assistant"
+	}
+	return msg, nil
+}
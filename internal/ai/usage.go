@@ -0,0 +1,49 @@
+package ai
+
+import (
+	"context"
+	"sync"
+)
+
+// Usage is the token count an OpenAI-compatible API reports for a completion.
+type Usage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+}
+
+// UsageMeter sums the usage reported for the completions made with its context, so
+// callers can account for tokens without every Completer method returning them.
+type UsageMeter struct {
+	mu       sync.Mutex
+	usage    Usage
+	reported bool
+}
+
+type usageMeterKey struct{}
+
+// WithUsageMeter returns a context whose completions are counted by the returned meter.
+func WithUsageMeter(ctx context.Context) (context.Context, *UsageMeter) {
+	meter := &UsageMeter{}
+	return context.WithValue(ctx, usageMeterKey{}, meter), meter
+}
+
+// Usage returns the summed usage and whether the provider reported any.
+func (m *UsageMeter) Usage() (Usage, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.usage, m.reported
+}
+
+// recordUsage adds usage to the meter of ctx, if any. A nil usage (the provider sent
+// none) is ignored.
+func recordUsage(ctx context.Context, usage *Usage) {
+	meter, ok := ctx.Value(usageMeterKey{}).(*UsageMeter)
+	if !ok || usage == nil {
+		return
+	}
+	meter.mu.Lock()
+	defer meter.mu.Unlock()
+	meter.usage.PromptTokens += usage.PromptTokens
+	meter.usage.CompletionTokens += usage.CompletionTokens
+	meter.reported = true
+}
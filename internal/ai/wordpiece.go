@@ -0,0 +1,135 @@
+package ai
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+const (
+	wordPieceUnknown      = "[UNK]"
+	wordPieceCLS          = "[CLS]"
+	wordPieceSEP          = "[SEP]"
+	wordPieceMaxWordChars = 100
+)
+
+// wordPieceTokenizer implements the uncased BERT tokenizer used by sentence-transformers
+// models such as all-MiniLM-L6-v2: basic cleanup and splitting followed by greedy WordPiece.
+type wordPieceTokenizer struct {
+	vocab map[string]int64
+}
+
+func loadWordPieceTokenizer(vocabPath string) (*wordPieceTokenizer, error) {
+	f, err := os.Open(vocabPath)
+	if err != nil {
+		return nil, fmt.Errorf("open vocab failed: %w", err)
+	}
+	defer f.Close()
+
+	vocab := make(map[string]int64)
+	sc := bufio.NewScanner(f)
+	var id int64
+	for sc.Scan() {
+		vocab[strings.TrimRight(sc.Text(), "\r")] = id
+		id++
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("read vocab failed: %w", err)
+	}
+	for _, special := range []string{wordPieceUnknown, wordPieceCLS, wordPieceSEP} {
+		if _, ok := vocab[special]; !ok {
+			return nil, fmt.Errorf("vocab is missing %s", special)
+		}
+	}
+	return &wordPieceTokenizer{vocab: vocab}, nil
+}
+
+// Encode returns token IDs wrapped in [CLS] ... [SEP], truncated to maxTokens in total.
+func (t *wordPieceTokenizer) Encode(text string, maxTokens int) []int64 {
+	ids := []int64{t.vocab[wordPieceCLS]}
+	limit := maxTokens - 1 // reserve [SEP]
+	for _, word := range basicTokenize(text) {
+		for _, piece := range t.wordPiece(word) {
+			if len(ids) >= limit {
+				return append(ids, t.vocab[wordPieceSEP])
+			}
+			ids = append(ids, piece)
+		}
+	}
+	return append(ids, t.vocab[wordPieceSEP])
+}
+
+// wordPiece splits a word into the longest vocabulary pieces, left to right.
+func (t *wordPieceTokenizer) wordPiece(word string) []int64 {
+	runes := []rune(word)
+	if len(runes) > wordPieceMaxWordChars {
+		return []int64{t.vocab[wordPieceUnknown]}
+	}
+	var pieces []int64
+	for start := 0; start < len(runes); {
+		end := len(runes)
+		var found int64 = -1
+		for end > start {
+			sub := string(runes[start:end])
+			if start > 0 {
+				sub = "##" + sub
+			}
+			if id, ok := t.vocab[sub]; ok {
+				found = id
+				break
+			}
+			end--
+		}
+		if found < 0 {
+			return []int64{t.vocab[wordPieceUnknown]}
+		}
+		pieces = append(pieces, found)
+		start = end
+	}
+	return pieces
+}
+
+// basicTokenize lowercases, strips accents, and splits on whitespace, punctuation, and CJK characters.
+func basicTokenize(text string) []string {
+	var words []string
+	var cur strings.Builder
+	flush := func() {
+		if cur.Len() > 0 {
+			words = append(words, cur.String())
+			cur.Reset()
+		}
+	}
+	for _, r := range norm.NFD.String(strings.ToLower(text)) {
+		switch {
+		case r == 0 || r == unicode.ReplacementChar || (unicode.IsControl(r) && !unicode.IsSpace(r)):
+			continue
+		case unicode.Is(unicode.Mn, r):
+			continue // combining accent left over from NFD
+		case unicode.IsSpace(r):
+			flush()
+		case isWordPiecePunct(r) || isCJK(r):
+			flush()
+			words = append(words, string(r))
+		default:
+			cur.WriteRune(r)
+		}
+	}
+	flush()
+	return words
+}
+
+func isWordPiecePunct(r rune) bool {
+	if (r >= 33 && r <= 47) || (r >= 58 && r <= 64) || (r >= 91 && r <= 96) || (r >= 123 && r <= 126) {
+		return true
+	}
+	return unicode.IsPunct(r)
+}
+
+func isCJK(r rune) bool {
+	return (r >= 0x4E00 && r <= 0x9FFF) || (r >= 0x3400 && r <= 0x4DBF) ||
+		(r >= 0x20000 && r <= 0x2A6DF) || (r >= 0xF900 && r <= 0xFAFF)
+}
@@ -0,0 +1,146 @@
+package app
+
+import (
+	"context"
+	"log"
+	"strings"
+	"time"
+
+	"gopherai-resume/internal/model"
+	"gopherai-resume/internal/pkg/apperr"
+)
+
+// Activity types, as recorded by the services and accepted by the type filter.
+const (
+	ActivityChatSessionCreated       = "chat_session_created"
+	ActivityRAGSessionCreated        = "rag_session_created"
+	ActivityDocumentIngested         = "document_ingested"
+	ActivityRAGAsked                 = "rag_asked"
+	ActivityVisionClassified         = "vision_classified"
+	ActivityApplicationCreated       = "application_created"
+	ActivityApplicationStatusChanged = "application_status_changed"
+)
+
+var activityTypes = map[string]bool{
+	ActivityChatSessionCreated:       true,
+	ActivityRAGSessionCreated:        true,
+	ActivityDocumentIngested:         true,
+	ActivityRAGAsked:                 true,
+	ActivityVisionClassified:         true,
+	ActivityApplicationCreated:       true,
+	ActivityApplicationStatusChanged: true,
+}
+
+const (
+	defaultActivityLimit = 50
+	maxActivityLimit     = 200
+	maxActivitySummary   = 512
+	// activityPruneBatch is how many events Prune deletes per statement.
+	activityPruneBatch = 1000
+)
+
+var ErrInvalidActivityType = apperr.BadRequest("invalid activity type")
+
+// ActivityRecorder records an action for the user's activity timeline. Services hold it as
+// an interface so a nil recorder records nothing.
+type ActivityRecorder interface {
+	Record(ctx context.Context, userID uint, kind string, resourceID uint, summary string) error
+}
+
+// ActivityService records what users do across the modules and serves it back as their
+// activity timeline.
+type ActivityService struct {
+	repo ActivityRepository
+	// retention is how long events are kept; 0 keeps them forever.
+	retention time.Duration
+}
+
+func NewActivityService(repo ActivityRepository, retention time.Duration) *ActivityService {
+	return &ActivityService{repo: repo, retention: retention}
+}
+
+// Record stores one event. Summaries longer than the column are cut.
+func (s *ActivityService) Record(ctx context.Context, userID uint, kind string, resourceID uint, summary string) error {
+	if userID == 0 || !activityTypes[kind] {
+		return ErrInvalidInput
+	}
+	summary = truncateRunes(strings.TrimSpace(summary), maxActivitySummary)
+	return s.repo.Create(ctx, &model.ActivityEvent{
+		UserID:     userID,
+		Type:       kind,
+		ResourceID: resourceID,
+		Summary:    summary,
+	})
+}
+
+// ActivityQuery selects a page of the timeline. Types limits it to those event types (all
+// when empty); BeforeID continues from a previous page's NextBeforeID (0 for the newest).
+type ActivityQuery struct {
+	Types    []string
+	BeforeID uint
+	Limit    int
+}
+
+// ActivityPage is one page of the timeline, newest first. Pass NextBeforeID as before_id to
+// fetch the next, older page; it is 0 when HasMore is false.
+type ActivityPage struct {
+	Events       []model.ActivityEvent `json:"events"`
+	HasMore      bool                  `json:"has_more"`
+	NextBeforeID uint                  `json:"next_before_id"`
+}
+
+func (s *ActivityService) List(ctx context.Context, userID uint, query ActivityQuery) (*ActivityPage, error) {
+	if userID == 0 {
+		return nil, ErrInvalidInput
+	}
+	for _, kind := range query.Types {
+		if !activityTypes[kind] {
+			return nil, ErrInvalidActivityType
+		}
+	}
+	limit := query.Limit
+	if limit <= 0 || limit > maxActivityLimit {
+		limit = defaultActivityLimit
+	}
+	events, err := s.repo.ListByUserID(ctx, userID, query.Types, query.BeforeID, limit+1)
+	if err != nil {
+		return nil, err
+	}
+	page := &ActivityPage{Events: events, HasMore: len(events) > limit}
+	if page.HasMore {
+		page.Events = events[:limit]
+		page.NextBeforeID = page.Events[limit-1].ID
+	}
+	return page, nil
+}
+
+// Prune deletes the events older than the retention period, in batches so no statement
+// holds locks for long, and returns how many it deleted.
+func (s *ActivityService) Prune(ctx context.Context) (int64, error) {
+	if s.retention <= 0 {
+		return 0, nil
+	}
+	cutoff := time.Now().Add(-s.retention)
+	var deleted int64
+	for {
+		n, err := s.repo.DeleteBefore(ctx, cutoff, activityPruneBatch)
+		deleted += n
+		if err != nil {
+			return deleted, err
+		}
+		if n < activityPruneBatch {
+			return deleted, nil
+		}
+	}
+}
+
+// recordActivity records an event through r, if any. The action it describes has already
+// succeeded, so a failure is only logged.
+func recordActivity(ctx context.Context, r ActivityRecorder, userID uint, kind string, resourceID uint, summary string) {
+	if r == nil {
+		return
+	}
+	if err := r.Record(ctx, userID, kind, resourceID, summary); err != nil {
+		log.Printf("record %s activity for user %d failed: %v", kind, userID, err)
+	}
+}
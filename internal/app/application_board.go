@@ -0,0 +1,141 @@
+package app
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"gopherai-resume/internal/model"
+	"gopherai-resume/internal/pkg/authz"
+)
+
+// applicationBoardStatuses is the column order of the application board.
+var applicationBoardStatuses = []string{
+	ApplicationSaved,
+	ApplicationApplied,
+	ApplicationInterviewing,
+	ApplicationOffer,
+	ApplicationAccepted,
+	ApplicationRejected,
+	ApplicationWithdrawn,
+}
+
+// BoardColumn is one status column of the board. AllowedMoves lists the columns its cards
+// may be dragged to; Final columns accept no moves out.
+type BoardColumn struct {
+	Status       string              `json:"status"`
+	Final        bool                `json:"final"`
+	AllowedMoves []string            `json:"allowed_moves"`
+	Count        int                 `json:"count"`
+	Applications []model.Application `json:"applications"`
+}
+
+// ApplicationBoard is the application tracker as a kanban board, one column per status.
+type ApplicationBoard struct {
+	Columns []BoardColumn `json:"columns"`
+}
+
+// MoveApplicationInput drops an application into a column. An empty Status keeps the
+// current column; Position is 1-based, and 0 or past the end places it last. Note
+// annotates the history entry of a status change.
+type MoveApplicationInput struct {
+	UserID        uint
+	ApplicationID uint
+	Status        string
+	Position      int
+	Note          string
+}
+
+// Board returns every status column in workflow order, each with its cards in board order.
+func (s *ApplicationService) Board(ctx context.Context, userID uint) (*ApplicationBoard, error) {
+	if userID == 0 {
+		return nil, ErrInvalidInput
+	}
+	list, err := s.repo.ListForBoard(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	byStatus := make(map[string][]model.Application, len(applicationBoardStatuses))
+	for _, application := range list {
+		byStatus[application.Status] = append(byStatus[application.Status], application)
+	}
+
+	board := &ApplicationBoard{Columns: make([]BoardColumn, 0, len(applicationBoardStatuses))}
+	for _, status := range applicationBoardStatuses {
+		cards := byStatus[status]
+		if cards == nil {
+			cards = []model.Application{}
+		}
+		moves := applicationTransitions[status]
+		if moves == nil {
+			moves = []string{}
+		}
+		board.Columns = append(board.Columns, BoardColumn{
+			Status:       status,
+			Final:        len(applicationTransitions[status]) == 0,
+			AllowedMoves: moves,
+			Count:        len(cards),
+			Applications: cards,
+		})
+	}
+	return board, nil
+}
+
+// Move places an application at a position in a column, changing its status when the column
+// differs. Status changes follow the same transition rules as Update and are recorded in the
+// history. The target column is renumbered, so every card in it gets a stored position.
+// It returns the updated board.
+func (s *ApplicationService) Move(ctx context.Context, input MoveApplicationInput) (*ApplicationBoard, error) {
+	if input.Position < 0 {
+		return nil, ErrInvalidInput
+	}
+	application, err := s.getOwned(ctx, input.UserID, input.ApplicationID, authz.Write)
+	if err != nil {
+		return nil, err
+	}
+
+	target := strings.TrimSpace(input.Status)
+	if target == "" {
+		target = application.Status
+	}
+	var change *model.ApplicationStatusChange
+	if target != application.Status {
+		if err := checkStatusTransition(application.Status, target); err != nil {
+			return nil, err
+		}
+		change = &model.ApplicationStatusChange{
+			UserID:     input.UserID,
+			FromStatus: application.Status,
+			ToStatus:   target,
+			Note:       strings.TrimSpace(input.Note),
+		}
+		application.Status = target
+		if target == ApplicationApplied && application.AppliedAt == nil {
+			now := time.Now()
+			application.AppliedAt = &now
+		}
+	}
+
+	list, err := s.repo.ListForBoard(ctx, input.UserID)
+	if err != nil {
+		return nil, err
+	}
+	column := make([]uint, 0, len(list))
+	for _, card := range list {
+		if card.Status == target && card.ID != application.ID {
+			column = append(column, card.ID)
+		}
+	}
+	at := len(column)
+	if input.Position > 0 && input.Position-1 < at {
+		at = input.Position - 1
+	}
+	column = append(column[:at], append([]uint{application.ID}, column[at:]...)...)
+	application.BoardPosition = at + 1
+
+	if err := s.repo.Move(ctx, application, change, column); err != nil {
+		return nil, err
+	}
+	s.recordStatusChange(ctx, application, change)
+	return s.Board(ctx, input.UserID)
+}
@@ -0,0 +1,187 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"gopherai-resume/internal/ai"
+	"gopherai-resume/internal/model"
+	"gopherai-resume/internal/pkg/apperr"
+	"gopherai-resume/internal/pkg/authz"
+)
+
+var ErrEmailUnparseable = apperr.New(http.StatusBadGateway, apperr.CodeInternalServer, "could not classify email")
+
+// Recruiter email classifications.
+const (
+	EmailRejection       = "rejection"
+	EmailInterviewInvite = "interview_invite"
+	EmailOffer           = "offer"
+	EmailOther           = "other"
+)
+
+const (
+	maxEmailChars = 20000
+	// minEmailConfidence is the classifier confidence needed to change a status automatically.
+	minEmailConfidence = 0.6
+	// maxEmailCandidates bounds how many open applications are offered to the LLM for matching.
+	maxEmailCandidates = 50
+)
+
+var emailStatuses = map[string]string{
+	EmailRejection:       ApplicationRejected,
+	EmailInterviewInvite: ApplicationInterviewing,
+	EmailOffer:           ApplicationOffer,
+}
+
+// ParseEmailInput is a pasted recruiter email. ApplicationID pins the email to an application;
+// otherwise the LLM matches it against the user's open applications. DryRun classifies
+// without updating anything.
+type ParseEmailInput struct {
+	UserID        uint
+	Email         string
+	ApplicationID uint
+	DryRun        bool
+}
+
+// ParseEmailResult reports the classification and what was done with it. SkippedReason
+// explains why a matched application was not updated.
+type ParseEmailResult struct {
+	Classification string             `json:"classification"`
+	Confidence     float64            `json:"confidence"`
+	Summary        string             `json:"summary"`
+	Company        string             `json:"company"`
+	Role           string             `json:"role"`
+	ApplicationID  uint               `json:"application_id,omitempty"`
+	Updated        bool               `json:"updated"`
+	SkippedReason  string             `json:"skipped_reason,omitempty"`
+	Application    *ApplicationDetail `json:"application,omitempty"`
+}
+
+// ParseEmail classifies a recruiter email with the LLM and moves the matching application
+// to the corresponding status when the transition is allowed.
+func (s *ApplicationService) ParseEmail(ctx context.Context, input ParseEmailInput) (*ParseEmailResult, error) {
+	email := strings.TrimSpace(input.Email)
+	if input.UserID == 0 || email == "" {
+		return nil, ErrInvalidInput
+	}
+	if err := checkConfiguredModel(ctx, s.modelPolicy, input.UserID, s.chatConfig); err != nil {
+		return nil, err
+	}
+	if runes := []rune(email); len(runes) > maxEmailChars {
+		email = string(runes[:maxEmailChars])
+	}
+
+	var candidates []model.Application
+	if input.ApplicationID != 0 {
+		application, err := s.getOwned(ctx, input.UserID, input.ApplicationID, authz.Write)
+		if err != nil {
+			return nil, err
+		}
+		candidates = []model.Application{*application}
+	} else {
+		all, err := s.repo.ListByUserID(ctx, input.UserID, "")
+		if err != nil {
+			return nil, err
+		}
+		for _, a := range all {
+			if len(applicationTransitions[a.Status]) > 0 && len(candidates) < maxEmailCandidates {
+				candidates = append(candidates, a)
+			}
+		}
+	}
+
+	var list strings.Builder
+	for _, a := range candidates {
+		fmt.Fprintf(&list, "- id %d: %s at %s (status: %s)\n", a.ID, a.Role, a.Company, a.Status)
+	}
+	if list.Len() == 0 {
+		list.WriteString("(none)\n")
+	}
+	messages := []ai.ChatMessage{
+		{Role: "system", Content: "You classify emails a job seeker received from recruiters or employers. " +
+			"Respond with a JSON object: {\"classification\": \"rejection\" | \"interview_invite\" | \"offer\" | \"other\", " +
+			"\"confidence\": number between 0 and 1, \"company\": string, \"role\": string, \"summary\": string (one sentence), " +
+			"\"application_id\": number (id of the matching application from the list, or 0 if none matches)}. " +
+			"Use \"other\" for acknowledgements, newsletters and anything that does not change the application's outcome."},
+		{Role: "user", Content: "Tracked applications:\n" + list.String() + "\nEmail:\n" + email},
+	}
+	raw, err := s.completer.Complete(ctx, s.chatConfig, messages)
+	if err != nil {
+		return nil, err
+	}
+	var parsed struct {
+		Classification string  `json:"classification"`
+		Confidence     float64 `json:"confidence"`
+		Company        string  `json:"company"`
+		Role           string  `json:"role"`
+		Summary        string  `json:"summary"`
+		ApplicationID  uint    `json:"application_id"`
+	}
+	if err := decodeLLMJSON(raw, &parsed); err != nil {
+		return nil, ErrEmailUnparseable
+	}
+
+	result := &ParseEmailResult{
+		Classification: strings.ToLower(strings.TrimSpace(parsed.Classification)),
+		Confidence:     parsed.Confidence,
+		Summary:        strings.TrimSpace(parsed.Summary),
+		Company:        strings.TrimSpace(parsed.Company),
+		Role:           strings.TrimSpace(parsed.Role),
+	}
+	if _, ok := emailStatuses[result.Classification]; !ok && result.Classification != EmailOther {
+		result.Classification = EmailOther
+	}
+
+	var matched *model.Application
+	for i := range candidates {
+		if candidates[i].ID == parsed.ApplicationID || input.ApplicationID != 0 {
+			matched = &candidates[i]
+			break
+		}
+	}
+	if matched == nil {
+		result.SkippedReason = "no tracked application matches this email"
+		return result, nil
+	}
+	result.ApplicationID = matched.ID
+
+	next, ok := emailStatuses[result.Classification]
+	switch {
+	case !ok:
+		result.SkippedReason = "email does not change the application status"
+	case result.Confidence < minEmailConfidence:
+		result.SkippedReason = fmt.Sprintf("classification confidence %.2f is below %.2f", result.Confidence, minEmailConfidence)
+	case matched.Status == next:
+		result.SkippedReason = "application is already " + next
+	case checkStatusTransition(matched.Status, next) != nil:
+		result.SkippedReason = fmt.Sprintf("cannot move from %s to %s", matched.Status, next)
+	case input.DryRun:
+		result.SkippedReason = "dry run"
+	}
+	if result.SkippedReason != "" {
+		return result, nil
+	}
+
+	note := "From recruiter email"
+	if result.Summary != "" {
+		note += ": " + result.Summary
+	}
+	if runes := []rune(note); len(runes) > 512 {
+		note = string(runes[:512])
+	}
+	detail, err := s.Update(ctx, UpdateApplicationInput{
+		UserID:        input.UserID,
+		ApplicationID: matched.ID,
+		Status:        &next,
+		StatusNote:    note,
+	})
+	if err != nil {
+		return nil, err
+	}
+	result.Updated = true
+	result.Application = detail
+	return result, nil
+}
@@ -0,0 +1,372 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"gopherai-resume/internal/ai"
+	"gopherai-resume/internal/model"
+	"gopherai-resume/internal/pkg/apperr"
+	"gopherai-resume/internal/pkg/authz"
+)
+
+var (
+	ErrApplicationNotFound      = apperr.NotFound(apperr.CodeApplicationNotFound, "application not found")
+	ErrInvalidApplicationStatus = apperr.BadRequest("invalid application status")
+	ErrInvalidStatusTransition  = apperr.Conflict(apperr.CodeInvalidTransition, "invalid application status transition")
+	ErrResumeDocumentNotFound   = apperr.NotFound(apperr.CodeDocumentNotFound, "resume document not found")
+)
+
+// Application statuses.
+const (
+	ApplicationSaved        = "saved"
+	ApplicationApplied      = "applied"
+	ApplicationInterviewing = "interviewing"
+	ApplicationOffer        = "offer"
+	ApplicationAccepted     = "accepted"
+	ApplicationRejected     = "rejected"
+	ApplicationWithdrawn    = "withdrawn"
+)
+
+// applicationTransitions lists the statuses each status may move to. Accepted, rejected
+// and withdrawn are final.
+var applicationTransitions = map[string][]string{
+	ApplicationSaved:        {ApplicationApplied, ApplicationWithdrawn},
+	ApplicationApplied:      {ApplicationInterviewing, ApplicationOffer, ApplicationRejected, ApplicationWithdrawn},
+	ApplicationInterviewing: {ApplicationOffer, ApplicationRejected, ApplicationWithdrawn},
+	ApplicationOffer:        {ApplicationAccepted, ApplicationRejected, ApplicationWithdrawn},
+	ApplicationAccepted:     nil,
+	ApplicationRejected:     nil,
+	ApplicationWithdrawn:    nil,
+}
+
+// reminderBatchSize is how many due reminders one DispatchReminders call handles.
+const reminderBatchSize = 100
+
+// activeApplicationStatuses are the non-final statuses; only these produce reminders.
+var activeApplicationStatuses = []string{ApplicationSaved, ApplicationApplied, ApplicationInterviewing, ApplicationOffer}
+
+// ApplicationService tracks job applications and their status history.
+type ApplicationService struct {
+	repo        ApplicationRepository
+	docRepo     RAGDocumentRepository
+	completer   ai.Completer
+	chatConfig  ai.ChatConfig
+	modelPolicy ModelPolicy // nil allows every model
+	notifier    Notifier    // nil disables reminder emails
+	activity    ActivityRecorder
+}
+
+func NewApplicationService(
+	repo ApplicationRepository,
+	docRepo RAGDocumentRepository,
+	completer ai.Completer,
+	chatConfig ai.ChatConfig,
+	modelPolicy ModelPolicy,
+	notifier Notifier,
+	activity ActivityRecorder,
+) *ApplicationService {
+	return &ApplicationService{
+		repo:        repo,
+		docRepo:     docRepo,
+		completer:   completer,
+		chatConfig:  chatConfig,
+		modelPolicy: modelPolicy,
+		notifier:    notifier,
+		activity:    activity,
+	}
+}
+
+type CreateApplicationInput struct {
+	UserID           uint
+	Company          string
+	Role             string
+	Status           string // defaults to saved
+	JobURL           string
+	JobDescription   string
+	ResumeDocumentID *uint
+	JobPostingID     *uint
+	AppliedAt        *time.Time
+	RemindAt         *time.Time
+	ReminderNote     string
+	Notes            string
+}
+
+// UpdateApplicationInput changes only the non-nil fields. A status change is validated
+// against the allowed transitions and recorded in the history with StatusNote.
+type UpdateApplicationInput struct {
+	UserID           uint
+	ApplicationID    uint
+	Company          *string
+	Role             *string
+	Status           *string
+	StatusNote       string
+	JobURL           *string
+	JobDescription   *string
+	ResumeDocumentID *uint
+	AppliedAt        *time.Time
+	RemindAt         *time.Time
+	ClearReminder    bool
+	ReminderNote     *string
+	Notes            *string
+}
+
+// ApplicationDetail is an application with its status history, oldest first.
+type ApplicationDetail struct {
+	model.Application
+	History []model.ApplicationStatusChange `json:"history"`
+}
+
+func (s *ApplicationService) Create(ctx context.Context, input CreateApplicationInput) (*model.Application, error) {
+	company := strings.TrimSpace(input.Company)
+	role := strings.TrimSpace(input.Role)
+	if input.UserID == 0 || company == "" || role == "" {
+		return nil, ErrInvalidInput
+	}
+	status := strings.TrimSpace(input.Status)
+	if status == "" {
+		status = ApplicationSaved
+	}
+	if _, ok := applicationTransitions[status]; !ok {
+		return nil, ErrInvalidApplicationStatus
+	}
+	if err := s.checkResumeDocument(ctx, input.UserID, input.ResumeDocumentID); err != nil {
+		return nil, err
+	}
+
+	application := &model.Application{
+		UserID:           input.UserID,
+		Company:          company,
+		Role:             role,
+		Status:           status,
+		JobURL:           strings.TrimSpace(input.JobURL),
+		JobDescription:   input.JobDescription,
+		ResumeDocumentID: input.ResumeDocumentID,
+		JobPostingID:     input.JobPostingID,
+		AppliedAt:        input.AppliedAt,
+		RemindAt:         input.RemindAt,
+		ReminderNote:     strings.TrimSpace(input.ReminderNote),
+		Notes:            input.Notes,
+	}
+	if application.AppliedAt == nil && status != ApplicationSaved {
+		now := time.Now()
+		application.AppliedAt = &now
+	}
+	change := &model.ApplicationStatusChange{UserID: input.UserID, ToStatus: status}
+	if err := s.repo.Create(ctx, application, change); err != nil {
+		return nil, err
+	}
+	recordActivity(ctx, s.activity, input.UserID, ActivityApplicationCreated, application.ID,
+		fmt.Sprintf("Added %s at %s as %s", role, company, status))
+	return application, nil
+}
+
+func (s *ApplicationService) Get(ctx context.Context, userID, applicationID uint) (*ApplicationDetail, error) {
+	application, err := s.getOwned(ctx, userID, applicationID, authz.Read)
+	if err != nil {
+		return nil, err
+	}
+	history, err := s.repo.ListStatusChanges(ctx, application.ID)
+	if err != nil {
+		return nil, err
+	}
+	return &ApplicationDetail{Application: *application, History: history}, nil
+}
+
+func (s *ApplicationService) List(ctx context.Context, userID uint, status string) ([]model.Application, error) {
+	if userID == 0 {
+		return nil, ErrInvalidInput
+	}
+	status = strings.TrimSpace(status)
+	if status != "" {
+		if _, ok := applicationTransitions[status]; !ok {
+			return nil, ErrInvalidApplicationStatus
+		}
+	}
+	return s.repo.ListByUserID(ctx, userID, status)
+}
+
+func (s *ApplicationService) Update(ctx context.Context, input UpdateApplicationInput) (*ApplicationDetail, error) {
+	application, err := s.getOwned(ctx, input.UserID, input.ApplicationID, authz.Write)
+	if err != nil {
+		return nil, err
+	}
+
+	if input.Company != nil {
+		if application.Company = strings.TrimSpace(*input.Company); application.Company == "" {
+			return nil, ErrInvalidInput
+		}
+	}
+	if input.Role != nil {
+		if application.Role = strings.TrimSpace(*input.Role); application.Role == "" {
+			return nil, ErrInvalidInput
+		}
+	}
+	if input.JobURL != nil {
+		application.JobURL = strings.TrimSpace(*input.JobURL)
+	}
+	if input.JobDescription != nil {
+		application.JobDescription = *input.JobDescription
+	}
+	if input.ResumeDocumentID != nil {
+		if err := s.checkResumeDocument(ctx, input.UserID, input.ResumeDocumentID); err != nil {
+			return nil, err
+		}
+		application.ResumeDocumentID = input.ResumeDocumentID
+	}
+	if input.AppliedAt != nil {
+		application.AppliedAt = input.AppliedAt
+	}
+	if input.ClearReminder {
+		application.RemindAt = nil
+		application.ReminderNote = ""
+		application.ReminderSentAt = nil
+	}
+	if input.RemindAt != nil {
+		application.RemindAt = input.RemindAt
+		application.ReminderSentAt = nil
+	}
+	if input.ReminderNote != nil {
+		application.ReminderNote = strings.TrimSpace(*input.ReminderNote)
+	}
+	if input.Notes != nil {
+		application.Notes = *input.Notes
+	}
+
+	var change *model.ApplicationStatusChange
+	if input.Status != nil {
+		next := strings.TrimSpace(*input.Status)
+		if next != application.Status {
+			if err := checkStatusTransition(application.Status, next); err != nil {
+				return nil, err
+			}
+			change = &model.ApplicationStatusChange{
+				UserID:     input.UserID,
+				FromStatus: application.Status,
+				ToStatus:   next,
+				Note:       strings.TrimSpace(input.StatusNote),
+			}
+			application.Status = next
+			// A card changing column starts unplaced within it.
+			application.BoardPosition = 0
+			if next == ApplicationApplied && application.AppliedAt == nil {
+				now := time.Now()
+				application.AppliedAt = &now
+			}
+		}
+	}
+
+	if err := s.repo.Update(ctx, application, change); err != nil {
+		return nil, err
+	}
+	s.recordStatusChange(ctx, application, change)
+	return s.Get(ctx, input.UserID, application.ID)
+}
+
+// recordStatusChange adds a stored status change, if any, to the user's activity.
+func (s *ApplicationService) recordStatusChange(ctx context.Context, application *model.Application, change *model.ApplicationStatusChange) {
+	if change == nil {
+		return
+	}
+	recordActivity(ctx, s.activity, application.UserID, ActivityApplicationStatusChanged, application.ID,
+		fmt.Sprintf("Moved %s at %s from %s to %s", application.Role, application.Company, change.FromStatus, change.ToStatus))
+}
+
+func (s *ApplicationService) Delete(ctx context.Context, userID, applicationID uint) error {
+	if _, err := s.getOwned(ctx, userID, applicationID, authz.Write); err != nil {
+		return err
+	}
+	return s.repo.DeleteByIDAndUserID(ctx, applicationID, userID)
+}
+
+// DueReminders lists the user's open applications whose reminder time is at or before before.
+// DispatchReminders also notifies users of due reminders without their polling.
+func (s *ApplicationService) DueReminders(ctx context.Context, userID uint, before time.Time) ([]model.Application, error) {
+	if userID == 0 {
+		return nil, ErrInvalidInput
+	}
+	return s.repo.ListDueReminders(ctx, userID, before, activeApplicationStatuses)
+}
+
+// DispatchReminders notifies users of one batch of due reminders of open applications,
+// across users, and marks them sent. The notifier pushes each to the user's live
+// connections and emails it as their preferences allow. A reminder that cannot be queued
+// or marked is logged and left for the next run, so it does not hold up the rest of the
+// batch. It returns how many were sent.
+func (s *ApplicationService) DispatchReminders(ctx context.Context) (int, error) {
+	if s.notifier == nil {
+		return 0, nil
+	}
+	due, err := s.repo.ListUnsentReminders(ctx, time.Now(), activeApplicationStatuses, reminderBatchSize)
+	if err != nil {
+		return 0, err
+	}
+	sent := 0
+	for _, application := range due {
+		if ctx.Err() != nil {
+			return sent, ctx.Err()
+		}
+		body := fmt.Sprintf("Reminder for your application to %s as %s (status: %s), due %s.",
+			application.Company, application.Role, application.Status, formatEmailTime(*application.RemindAt))
+		if application.ReminderNote != "" {
+			body += "\n\nNote: " + application.ReminderNote
+		}
+		if application.JobURL != "" {
+			body += "\n\nPosting: " + application.JobURL
+		}
+		subject := fmt.Sprintf("Reminder: %s at %s", application.Role, application.Company)
+		if err := s.notifier.Notify(ctx, application.UserID, NotifyApplicationReminder, subject, body+notificationFooter); err != nil {
+			log.Printf("queue reminder for application %d failed: %v", application.ID, err)
+			continue
+		}
+		if err := s.repo.MarkReminderSent(ctx, application.ID, *application.RemindAt, time.Now()); err != nil {
+			log.Printf("mark reminder of application %d sent failed: %v", application.ID, err)
+			continue
+		}
+		sent++
+	}
+	return sent, nil
+}
+
+func (s *ApplicationService) getOwned(ctx context.Context, userID, applicationID uint, action authz.Action) (*model.Application, error) {
+	if userID == 0 || applicationID == 0 {
+		return nil, ErrInvalidInput
+	}
+	application, err := loadApplication(ctx, s.repo, applicationID, userID, action)
+	if err != nil {
+		return nil, err
+	}
+	if application == nil {
+		return nil, ErrApplicationNotFound
+	}
+	return application, nil
+}
+
+func (s *ApplicationService) checkResumeDocument(ctx context.Context, userID uint, docID *uint) error {
+	if docID == nil {
+		return nil
+	}
+	doc, err := loadRAGDocument(ctx, s.docRepo, *docID, userID, authz.Write)
+	if err != nil {
+		return err
+	}
+	if doc == nil {
+		return ErrResumeDocumentNotFound
+	}
+	return nil
+}
+
+func checkStatusTransition(from, to string) error {
+	if _, ok := applicationTransitions[to]; !ok {
+		return ErrInvalidApplicationStatus
+	}
+	for _, allowed := range applicationTransitions[from] {
+		if allowed == to {
+			return nil
+		}
+	}
+	return ErrInvalidStatusTransition
+}
@@ -1,29 +1,47 @@
 package app
 
 import (
-	"errors"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
+	"log"
+	"net/http"
+	"net/url"
 	"strings"
 	"time"
 
 	"golang.org/x/crypto/bcrypt"
 
 	"gopherai-resume/internal/model"
+	"gopherai-resume/internal/pkg/apperr"
 	"gopherai-resume/internal/pkg/jwtutil"
-	"gopherai-resume/internal/repository"
 )
 
 var (
-	ErrInvalidInput      = errors.New("invalid input")
-	ErrUsernameExists    = errors.New("username already exists")
-	ErrEmailExists       = errors.New("email already exists")
-	ErrInvalidCredential = errors.New("invalid username or password")
+	ErrInvalidInput      = apperr.BadRequest("invalid input")
+	ErrUsernameExists    = apperr.New(http.StatusBadRequest, apperr.CodeUsernameExists, "username already exists")
+	ErrEmailExists       = apperr.New(http.StatusBadRequest, apperr.CodeEmailExists, "email already exists")
+	ErrInvalidCredential = apperr.New(http.StatusUnauthorized, apperr.CodeInvalidCredentials, "invalid username or password")
+	ErrInvalidToken      = apperr.BadRequest("invalid or expired token")
+	ErrEmailVerified     = apperr.BadRequest("email already verified")
+)
+
+// Purposes of emailed user tokens.
+const (
+	tokenPasswordReset = "password_reset"
+	tokenVerifyEmail   = "verify_email"
 )
 
 type AuthService struct {
-	userRepo      *repository.UserRepository
+	userRepo      UserRepository
+	tokens        UserTokenRepository
+	notifications *NotificationService
 	jwtSecret     string
 	jwtExpiration time.Duration
+	resetTTL      time.Duration
+	verifyTTL     time.Duration
 }
 
 type RegisterInput struct {
@@ -42,15 +60,37 @@ type AuthResult struct {
 	User  *model.User
 }
 
-func NewAuthService(userRepo *repository.UserRepository, jwtSecret string, jwtExpiration time.Duration) *AuthService {
-	return &AuthService{
+func NewAuthService(
+	userRepo UserRepository,
+	tokens UserTokenRepository,
+	notifications *NotificationService,
+	jwtSecret string,
+	jwtExpiration time.Duration,
+	resetTTL time.Duration,
+	verifyTTL time.Duration,
+) *AuthService {
+	s := &AuthService{
 		userRepo:      userRepo,
+		tokens:        tokens,
+		notifications: notifications,
 		jwtSecret:     jwtSecret,
 		jwtExpiration: jwtExpiration,
+		resetTTL:      resetTTL,
+		verifyTTL:     verifyTTL,
 	}
+	// Tokens are issued when their email is sent, so the outbox never holds one.
+	notifications.RegisterLink(tokenPasswordReset, func(ctx context.Context, userID uint) (string, error) {
+		token, err := s.issueToken(ctx, userID, tokenPasswordReset, s.resetTTL)
+		return notifications.Link("/reset-password?token=" + url.QueryEscape(token)), err
+	})
+	notifications.RegisterLink(tokenVerifyEmail, func(ctx context.Context, userID uint) (string, error) {
+		token, err := s.issueToken(ctx, userID, tokenVerifyEmail, s.verifyTTL)
+		return notifications.Link("/api/v1/auth/verify-email?token=" + url.QueryEscape(token)), err
+	})
+	return s
 }
 
-func (s *AuthService) Register(input RegisterInput) (*AuthResult, error) {
+func (s *AuthService) Register(ctx context.Context, input RegisterInput) (*AuthResult, error) {
 	username := strings.TrimSpace(input.Username)
 	email := strings.TrimSpace(strings.ToLower(input.Email))
 	password := strings.TrimSpace(input.Password)
@@ -59,7 +99,7 @@ func (s *AuthService) Register(input RegisterInput) (*AuthResult, error) {
 		return nil, ErrInvalidInput
 	}
 
-	existingByName, err := s.userRepo.GetByUsername(username)
+	existingByName, err := s.userRepo.GetByUsername(ctx, username)
 	if err != nil {
 		return nil, err
 	}
@@ -67,7 +107,7 @@ func (s *AuthService) Register(input RegisterInput) (*AuthResult, error) {
 		return nil, ErrUsernameExists
 	}
 
-	existingByEmail, err := s.userRepo.GetByEmail(email)
+	existingByEmail, err := s.userRepo.GetByEmail(ctx, email)
 	if err != nil {
 		return nil, err
 	}
@@ -85,25 +125,29 @@ func (s *AuthService) Register(input RegisterInput) (*AuthResult, error) {
 		Email:        email,
 		PasswordHash: string(hash),
 	}
-	if err := s.userRepo.Create(user); err != nil {
+	if err := s.userRepo.Create(ctx, user); err != nil {
 		return nil, err
 	}
+	// Registration succeeds even if the email cannot be queued; the user can ask again.
+	if err := s.sendVerification(ctx, user); err != nil {
+		log.Printf("queue verification email for user %d failed: %v", user.ID, err)
+	}
 
-	token, err := jwtutil.GenerateToken(s.jwtSecret, s.jwtExpiration, user.ID, user.Username)
+	token, err := jwtutil.GenerateToken(s.jwtSecret, s.jwtExpiration, user.ID, user.Username, user.TokenVersion)
 	if err != nil {
 		return nil, err
 	}
 	return &AuthResult{Token: token, User: user}, nil
 }
 
-func (s *AuthService) Login(input LoginInput) (*AuthResult, error) {
+func (s *AuthService) Login(ctx context.Context, input LoginInput) (*AuthResult, error) {
 	username := strings.TrimSpace(input.Username)
 	password := strings.TrimSpace(input.Password)
 	if username == "" || password == "" {
 		return nil, ErrInvalidInput
 	}
 
-	user, err := s.userRepo.GetByUsername(username)
+	user, err := s.userRepo.GetByUsername(ctx, username)
 	if err != nil {
 		return nil, err
 	}
@@ -115,16 +159,130 @@ func (s *AuthService) Login(input LoginInput) (*AuthResult, error) {
 		return nil, ErrInvalidCredential
 	}
 
-	token, err := jwtutil.GenerateToken(s.jwtSecret, s.jwtExpiration, user.ID, user.Username)
+	token, err := jwtutil.GenerateToken(s.jwtSecret, s.jwtExpiration, user.ID, user.Username, user.TokenVersion)
 	if err != nil {
 		return nil, err
 	}
 	return &AuthResult{Token: token, User: user}, nil
 }
 
-func (s *AuthService) GetUserByID(id uint) (*model.User, error) {
+// TokenVersion returns the user's current token version; tokens carrying another one are
+// revoked. ok is false when the user no longer exists.
+func (s *AuthService) TokenVersion(ctx context.Context, userID uint) (uint, bool, error) {
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil || user == nil {
+		return 0, false, err
+	}
+	return user.TokenVersion, true, nil
+}
+
+func (s *AuthService) GetUserByID(ctx context.Context, id uint) (*model.User, error) {
 	if id == 0 {
 		return nil, ErrInvalidInput
 	}
-	return s.userRepo.GetByID(id)
+	return s.userRepo.GetByID(ctx, id)
+}
+
+// RequestPasswordReset emails a reset link to the account with this email. Unknown
+// addresses are ignored without an error, so the endpoint does not reveal who is registered.
+func (s *AuthService) RequestPasswordReset(ctx context.Context, email string) error {
+	email = strings.TrimSpace(strings.ToLower(email))
+	if email == "" {
+		return ErrInvalidInput
+	}
+	user, err := s.userRepo.GetByEmail(ctx, email)
+	if err != nil || user == nil {
+		return err
+	}
+	body := fmt.Sprintf("Hi %s,\n\nSomeone asked to reset the password of your GopherAI Resume account. "+
+		"Choose a new password here within %d minutes:\n\n%s\n\nIf this was not you, ignore this email; your password is unchanged.",
+		user.Username, int(s.resetTTL.Minutes()), emailLinkPlaceholder)
+	return s.notifications.NotifyWithLink(ctx, user.ID, NotifyPasswordReset, "Reset your GopherAI Resume password", body, tokenPasswordReset)
+}
+
+// ResetPassword sets a new password using an emailed reset token. Each token works once.
+// Every token issued before is revoked, signing the user out everywhere.
+func (s *AuthService) ResetPassword(ctx context.Context, token, password string) error {
+	password = strings.TrimSpace(password)
+	if strings.TrimSpace(token) == "" || len(password) < 8 {
+		return ErrInvalidInput
+	}
+	used, err := s.tokens.Consume(ctx, hashToken(token), tokenPasswordReset, time.Now())
+	if err != nil {
+		return err
+	}
+	if used == nil {
+		return ErrInvalidToken
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return fmt.Errorf("hash password failed: %w", err)
+	}
+	return s.userRepo.UpdatePasswordHash(ctx, used.UserID, string(hash))
+}
+
+// ResendVerification emails a new verification link to the user.
+func (s *AuthService) ResendVerification(ctx context.Context, userID uint) error {
+	user, err := s.GetUserByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if user == nil {
+		return ErrInvalidInput
+	}
+	if user.EmailVerifiedAt != nil {
+		return ErrEmailVerified
+	}
+	return s.sendVerification(ctx, user)
+}
+
+// VerifyEmail marks the email of the token's user as verified.
+func (s *AuthService) VerifyEmail(ctx context.Context, token string) (*model.User, error) {
+	if strings.TrimSpace(token) == "" {
+		return nil, ErrInvalidInput
+	}
+	now := time.Now()
+	used, err := s.tokens.Consume(ctx, hashToken(token), tokenVerifyEmail, now)
+	if err != nil {
+		return nil, err
+	}
+	if used == nil {
+		return nil, ErrInvalidToken
+	}
+	if err := s.userRepo.MarkEmailVerified(ctx, used.UserID, now); err != nil {
+		return nil, err
+	}
+	return s.userRepo.GetByID(ctx, used.UserID)
+}
+
+func (s *AuthService) sendVerification(ctx context.Context, user *model.User) error {
+	body := fmt.Sprintf("Hi %s,\n\nPlease confirm this email address for your GopherAI Resume account "+
+		"by opening this link within %d hours:\n\n%s",
+		user.Username, int(s.verifyTTL.Hours()), emailLinkPlaceholder)
+	return s.notifications.NotifyWithLink(ctx, user.ID, NotifyEmailVerification, "Confirm your email address", body, tokenVerifyEmail)
+}
+
+// issueToken stores a new random token for purpose, replacing the user's earlier ones, and
+// returns it. Only its hash is stored.
+func (s *AuthService) issueToken(ctx context.Context, userID uint, purpose string, ttl time.Duration) (string, error) {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", fmt.Errorf("generate token failed: %w", err)
+	}
+	token := hex.EncodeToString(raw)
+	err := s.tokens.Replace(ctx, &model.UserToken{
+		UserID:    userID,
+		Purpose:   purpose,
+		TokenHash: hashToken(token),
+		ExpiresAt: time.Now().Add(ttl),
+	})
+	if err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(strings.TrimSpace(token)))
+	return hex.EncodeToString(sum[:])
 }
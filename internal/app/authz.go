@@ -0,0 +1,168 @@
+package app
+
+import (
+	"context"
+	"fmt"
+
+	"gopherai-resume/internal/model"
+	"gopherai-resume/internal/pkg/authz"
+)
+
+// The access policy for user-owned and workspace resources:
+//   - a user may do anything with resources they own;
+//   - workspace resources need a membership role of at least the required one;
+//   - admins may read any resource, which is recorded as an override; changes stay with
+//     the owner or the workspace's members.
+//
+// Every denial and override is recorded with authz.Record. Callers turn a denial into
+// their not-found error so IDs of other users' resources are not disclosed.
+
+// Resource kinds named in authorization records.
+const (
+	ResourceApplication       = "application"
+	ResourceChatMessage       = "chat_message"
+	ResourceChatSession       = "chat_session"
+	ResourceDataExport        = "data_export"
+	ResourcePortfolioAnalysis = "portfolio_analysis"
+	ResourceRAGDocument       = "rag_document"
+	ResourceRAGSession        = "rag_session"
+	ResourceResumeBullet      = "resume_bullet"
+	ResourceScheduledMessage  = "scheduled_message"
+	ResourceVisionSample      = "vision_sample"
+	ResourceWorkspace         = "workspace"
+)
+
+// authorizeOwned reports whether userID may perform action on the resource kind/id owned by
+// ownerID.
+func authorizeOwned(ctx context.Context, userID uint, action authz.Action, kind string, id, ownerID uint) bool {
+	if ownerID == userID {
+		return true
+	}
+	if action == authz.Read && authz.IsAdmin(ctx, userID) {
+		authz.Record(ctx, authz.Override, userID, action, kind, id, fmt.Sprintf("owned by user %d", ownerID))
+		return true
+	}
+	authz.Record(ctx, authz.Deny, userID, action, kind, id, fmt.Sprintf("owned by user %d", ownerID))
+	return false
+}
+
+// loadChatSession returns the chat session if userID may perform action on it, or nil when
+// it does not exist or access is denied.
+func loadChatSession(ctx context.Context, repo SessionRepository, sessionID, userID uint, action authz.Action) (*model.Session, error) {
+	session, err := repo.GetByID(ctx, sessionID)
+	if err != nil || session == nil {
+		return nil, err
+	}
+	if !authorizeOwned(ctx, userID, action, ResourceChatSession, sessionID, session.UserID) {
+		return nil, nil
+	}
+	return session, nil
+}
+
+// loadRAGSession is loadChatSession for RAG sessions.
+func loadRAGSession(ctx context.Context, repo RAGSessionRepository, sessionID, userID uint, action authz.Action) (*model.RAGSession, error) {
+	session, err := repo.GetByID(ctx, sessionID)
+	if err != nil || session == nil {
+		return nil, err
+	}
+	if !authorizeOwned(ctx, userID, action, ResourceRAGSession, sessionID, session.UserID) {
+		return nil, nil
+	}
+	return session, nil
+}
+
+// loadRAGDocument is loadChatSession for RAG documents.
+func loadRAGDocument(ctx context.Context, repo RAGDocumentRepository, documentID, userID uint, action authz.Action) (*model.RAGDocument, error) {
+	doc, err := repo.GetByID(ctx, documentID)
+	if err != nil || doc == nil {
+		return nil, err
+	}
+	if !authorizeOwned(ctx, userID, action, ResourceRAGDocument, documentID, doc.UserID) {
+		return nil, nil
+	}
+	return doc, nil
+}
+
+// loadOwned is loadChatSession for any resource kind that get loads by ID and owner
+// reports the owning user of.
+func loadOwned[T any](ctx context.Context, get func(context.Context, uint) (*T, error), owner func(*T) uint, kind string, id, userID uint, action authz.Action) (*T, error) {
+	resource, err := get(ctx, id)
+	if err != nil || resource == nil {
+		return nil, err
+	}
+	if !authorizeOwned(ctx, userID, action, kind, id, owner(resource)) {
+		return nil, nil
+	}
+	return resource, nil
+}
+
+// loadApplication is loadChatSession for job applications.
+func loadApplication(ctx context.Context, repo ApplicationRepository, applicationID, userID uint, action authz.Action) (*model.Application, error) {
+	return loadOwned(ctx, repo.GetByID, func(a *model.Application) uint { return a.UserID }, ResourceApplication, applicationID, userID, action)
+}
+
+// loadChatMessage is loadChatSession for chat messages.
+func loadChatMessage(ctx context.Context, repo MessageRepository, messageID, userID uint, action authz.Action) (*model.Message, error) {
+	return loadOwned(ctx, repo.GetByID, func(m *model.Message) uint { return m.UserID }, ResourceChatMessage, messageID, userID, action)
+}
+
+// loadDataExport is loadChatSession for data exports.
+func loadDataExport(ctx context.Context, repo DataExportRepository, exportID, userID uint, action authz.Action) (*model.DataExport, error) {
+	return loadOwned(ctx, repo.GetByID, func(e *model.DataExport) uint { return e.UserID }, ResourceDataExport, exportID, userID, action)
+}
+
+// loadPortfolioAnalysis is loadChatSession for portfolio analyses.
+func loadPortfolioAnalysis(ctx context.Context, repo PortfolioAnalysisRepository, analysisID, userID uint, action authz.Action) (*model.PortfolioAnalysis, error) {
+	return loadOwned(ctx, repo.GetByID, func(a *model.PortfolioAnalysis) uint { return a.UserID }, ResourcePortfolioAnalysis, analysisID, userID, action)
+}
+
+// loadResumeBullet is loadChatSession for suggested resume bullets.
+func loadResumeBullet(ctx context.Context, repo ResumeBulletRepository, bulletID, userID uint, action authz.Action) (*model.ResumeBullet, error) {
+	return loadOwned(ctx, repo.GetByID, func(b *model.ResumeBullet) uint { return b.UserID }, ResourceResumeBullet, bulletID, userID, action)
+}
+
+// loadScheduledMessage is loadChatSession for scheduled messages.
+func loadScheduledMessage(ctx context.Context, repo ScheduledMessageRepository, messageID, userID uint, action authz.Action) (*model.ScheduledMessage, error) {
+	return loadOwned(ctx, repo.GetByID, func(m *model.ScheduledMessage) uint { return m.UserID }, ResourceScheduledMessage, messageID, userID, action)
+}
+
+// loadVisionSample is loadChatSession for stored vision samples.
+func loadVisionSample(ctx context.Context, repo VisionSampleRepository, sampleID, userID uint, action authz.Action) (*model.VisionSample, error) {
+	return loadOwned(ctx, repo.GetByID, func(v *model.VisionSample) uint { return v.UserID }, ResourceVisionSample, sampleID, userID, action)
+}
+
+// authorizeWorkspace returns the user's active membership when their role is at least
+// minRole. Admins who are not members may read: they get a synthetic member-role membership.
+func authorizeWorkspace(ctx context.Context, repo WorkspaceRepository, workspaceID, userID uint, minRole string) (*model.WorkspaceMember, error) {
+	action := authz.Write
+	if minRole == WorkspaceMember {
+		action = authz.Read
+	}
+	member, err := repo.GetMember(ctx, workspaceID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if member != nil && member.Status != MemberActive {
+		member = nil // an invitation grants nothing until it is accepted
+	}
+	if member == nil {
+		if action == authz.Read && authz.IsAdmin(ctx, userID) {
+			workspace, err := repo.GetByID(ctx, workspaceID)
+			if err != nil {
+				return nil, err
+			}
+			if workspace != nil {
+				authz.Record(ctx, authz.Override, userID, action, ResourceWorkspace, workspaceID, "not a member")
+				return &model.WorkspaceMember{WorkspaceID: workspaceID, UserID: userID, Role: WorkspaceMember, Status: MemberActive}, nil
+			}
+		}
+		authz.Record(ctx, authz.Deny, userID, action, ResourceWorkspace, workspaceID, "not a member")
+		return nil, ErrWorkspaceNotFound
+	}
+	if workspaceRoleRank[member.Role] < workspaceRoleRank[minRole] {
+		authz.Record(ctx, authz.Deny, userID, action, ResourceWorkspace, workspaceID,
+			fmt.Sprintf("role %s below %s", member.Role, minRole))
+		return nil, ErrWorkspaceForbidden
+	}
+	return member, nil
+}
@@ -0,0 +1,301 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopherai-resume/internal/ai"
+	"gopherai-resume/internal/model"
+	"gopherai-resume/internal/pkg/apperr"
+)
+
+const (
+	defaultCandidateSearchLimit = 10
+	maxCandidateSearchLimit     = 50
+	candidateEvidenceChunks     = 2
+	maxCandidateEvidenceChars   = 400
+	// maxCandidateRankPool caps the filtered candidates a query ranks by similarity.
+	maxCandidateRankPool = 500
+)
+
+var (
+	ErrCandidateNotFound = apperr.NotFound(apperr.CodeCandidateNotFound, "candidate not found")
+	ErrCandidateExists   = apperr.Conflict(apperr.CodeCandidateExists, "resume is already in the candidate pool")
+	minYearsPattern      = regexp.MustCompile(`(?i)(\d+(?:\.\d+)?)\s*\+?\s*(?:years?|yrs?)\b`)
+)
+
+// CandidateView is a pool candidate with its skills decoded.
+type CandidateView struct {
+	model.WorkspaceCandidate
+	Skills []string `json:"skills"`
+}
+
+// CandidateSearchInput is a recruiter query. Skills and MinYears are hard filters; skills
+// and "N+ years" written in Query are added to them.
+type CandidateSearchInput struct {
+	UserID      uint
+	WorkspaceID uint
+	Query       string
+	Skills      []string
+	MinYears    *float64
+	Limit       int
+}
+
+// CandidateEvidence is a resume passage that matches the query.
+type CandidateEvidence struct {
+	ChunkID uint    `json:"chunk_id"`
+	Content string  `json:"content"`
+	Score   float64 `json:"score"`
+}
+
+// CandidateMatch is a ranked search hit. Score is the best passage's similarity to the query.
+type CandidateMatch struct {
+	Candidate     CandidateView       `json:"candidate"`
+	Score         float64             `json:"score"`
+	MatchedSkills []string            `json:"matched_skills"`
+	Evidence      []CandidateEvidence `json:"evidence"`
+}
+
+// CandidateSearchResult echoes the filters applied, so clients can show how the query was read.
+type CandidateSearchResult struct {
+	Skills   []string         `json:"skills"`
+	MinYears *float64         `json:"min_years"`
+	Matches  []CandidateMatch `json:"matches"`
+}
+
+// CandidatePoolService keeps a workspace's pool of candidate resumes and searches it.
+type CandidatePoolService struct {
+	repo        WorkspaceCandidateRepository
+	workspaces  *WorkspaceService
+	rag         *RAGService
+	completer   ai.Completer
+	chatConfig  ai.ChatConfig
+	modelPolicy ModelPolicy // nil allows every model
+}
+
+func NewCandidatePoolService(
+	repo WorkspaceCandidateRepository,
+	workspaces *WorkspaceService,
+	rag *RAGService,
+	completer ai.Completer,
+	chatConfig ai.ChatConfig,
+	modelPolicy ModelPolicy,
+) *CandidatePoolService {
+	return &CandidatePoolService{repo: repo, workspaces: workspaces, rag: rag, completer: completer, chatConfig: chatConfig, modelPolicy: modelPolicy}
+}
+
+// Add puts one of the user's resume documents into the workspace pool, extracting the
+// candidate's name, skills and years of experience.
+func (s *CandidatePoolService) Add(ctx context.Context, userID, workspaceID, documentID uint) (*CandidateView, error) {
+	if _, err := s.workspaces.Authorize(ctx, workspaceID, userID, WorkspaceRecruiter); err != nil {
+		return nil, err
+	}
+	if documentID == 0 {
+		return nil, ErrInvalidInput
+	}
+	existing, err := s.repo.GetByDocumentID(ctx, workspaceID, documentID)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		return nil, ErrCandidateExists
+	}
+	text, err := s.rag.DocumentText(ctx, userID, documentID)
+	if errors.Is(err, ErrRAGDocumentNotFound) {
+		return nil, ErrResumeDocumentNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := checkConfiguredModel(ctx, s.modelPolicy, userID, s.chatConfig); err != nil {
+		return nil, err
+	}
+	profile, err := s.extractProfile(ctx, text)
+	if err != nil {
+		return nil, err
+	}
+	skills := canonicalSkills(append(profile.Skills, findSkills(text)...))
+	data, err := json.Marshal(skills)
+	if err != nil {
+		return nil, err
+	}
+	candidate := &model.WorkspaceCandidate{
+		WorkspaceID:     workspaceID,
+		DocumentID:      documentID,
+		AddedBy:         userID,
+		Name:            truncateRunes(strings.TrimSpace(profile.Name), 256),
+		Headline:        truncateRunes(strings.TrimSpace(profile.Headline), 512),
+		Skills:          string(data),
+		YearsExperience: profile.YearsExperience,
+	}
+	if err := s.repo.Create(ctx, candidate); err != nil {
+		return nil, err
+	}
+	return &CandidateView{WorkspaceCandidate: *candidate, Skills: skills}, nil
+}
+
+type candidateProfile struct {
+	Name            string   `json:"name"`
+	Headline        string   `json:"headline"`
+	YearsExperience *float64 `json:"years_experience"`
+	Skills          []string `json:"skills"`
+}
+
+// extractProfile asks the LLM for the searchable facts of a resume. An unparseable reply
+// leaves them empty; taxonomy skills are still found in the text.
+func (s *CandidatePoolService) extractProfile(ctx context.Context, text string) (*candidateProfile, error) {
+	raw, err := s.completer.Complete(ctx, s.chatConfig, []ai.ChatMessage{
+		{Role: "system", Content: "Extract from this resume: the candidate's name, a one-line headline (current or target " +
+			"role), total years of professional experience as a number (null if it cannot be worked out from the dates), " +
+			"and the technical skills and tools they have used, as short names. Respond with a JSON object: " +
+			"{\"name\": string, \"headline\": string, \"years_experience\": number|null, \"skills\": [string]}."},
+		{Role: "user", Content: truncateRunes(text, maxResumeChars)},
+	})
+	if err != nil {
+		return nil, err
+	}
+	var profile candidateProfile
+	if err := decodeLLMJSON(raw, &profile); err != nil {
+		return &candidateProfile{}, nil
+	}
+	if y := profile.YearsExperience; y != nil && (*y < 0 || *y > 60) {
+		profile.YearsExperience = nil
+	}
+	return &profile, nil
+}
+
+func (s *CandidatePoolService) List(ctx context.Context, userID, workspaceID uint) ([]CandidateView, error) {
+	if _, err := s.workspaces.Authorize(ctx, workspaceID, userID, WorkspaceRecruiter); err != nil {
+		return nil, err
+	}
+	list, err := s.repo.ListByWorkspaceID(ctx, workspaceID)
+	if err != nil {
+		return nil, err
+	}
+	views := make([]CandidateView, 0, len(list))
+	for _, c := range list {
+		views = append(views, candidateView(c))
+	}
+	return views, nil
+}
+
+// Remove takes a candidate out of the pool; the resume document itself is kept.
+func (s *CandidatePoolService) Remove(ctx context.Context, userID, workspaceID, candidateID uint) error {
+	if _, err := s.workspaces.Authorize(ctx, workspaceID, userID, WorkspaceRecruiter); err != nil {
+		return err
+	}
+	candidate, err := s.repo.GetByIDAndWorkspaceID(ctx, candidateID, workspaceID)
+	if err != nil {
+		return err
+	}
+	if candidate == nil {
+		return ErrCandidateNotFound
+	}
+	return s.repo.DeleteByIDAndWorkspaceID(ctx, candidate.ID, workspaceID)
+}
+
+// Search filters the pool by skills and experience in the database, then ranks up to
+// maxCandidateRankPool of the newest remaining candidates by embedding similarity between
+// the query and their resume chunks. Without query text the filtered candidates are
+// ordered by experience.
+func (s *CandidatePoolService) Search(ctx context.Context, input CandidateSearchInput) (*CandidateSearchResult, error) {
+	if _, err := s.workspaces.Authorize(ctx, input.WorkspaceID, input.UserID, WorkspaceRecruiter); err != nil {
+		return nil, err
+	}
+	query := strings.TrimSpace(input.Query)
+	required := canonicalSkills(append(input.Skills, findSkills(query)...))
+	minYears := input.MinYears
+	if minYears == nil {
+		if m := minYearsPattern.FindStringSubmatch(query); m != nil {
+			if years, err := strconv.ParseFloat(m[1], 64); err == nil {
+				minYears = &years
+			}
+		}
+	}
+	if query == "" && len(required) == 0 && minYears == nil {
+		return nil, ErrInvalidInput
+	}
+	limit := input.Limit
+	if limit <= 0 {
+		limit = defaultCandidateSearchLimit
+	}
+	limit = min(limit, maxCandidateSearchLimit)
+
+	fetch := limit
+	if query != "" {
+		fetch = maxCandidateRankPool
+	}
+	pool, err := s.repo.Search(ctx, input.WorkspaceID, required, minYears, query == "", fetch)
+	if err != nil {
+		return nil, err
+	}
+	result := &CandidateSearchResult{Skills: required, MinYears: minYears, Matches: []CandidateMatch{}}
+	if len(pool) == 0 {
+		return result, nil
+	}
+	matches := make([]CandidateMatch, 0, len(pool))
+	for _, c := range pool {
+		matches = append(matches, CandidateMatch{Candidate: candidateView(c), MatchedSkills: required, Evidence: []CandidateEvidence{}})
+	}
+	if query != "" {
+		if err := s.rankBySimilarity(ctx, query, matches); err != nil {
+			return nil, err
+		}
+	}
+	if len(matches) > limit {
+		matches = matches[:limit]
+	}
+	result.Matches = matches
+	return result, nil
+}
+
+// rankBySimilarity scores each match by its best chunks against the query and sorts them.
+func (s *CandidatePoolService) rankBySimilarity(ctx context.Context, query string, matches []CandidateMatch) error {
+	vectors, err := s.rag.embedTexts(ctx, []string{query})
+	if err != nil {
+		return err
+	}
+	docIDs := make([]uint, len(matches))
+	for i, m := range matches {
+		docIDs[i] = m.Candidate.DocumentID
+	}
+	chunks, err := s.rag.retrievableChunksPerDocument(ctx, docIDs, query, vectors[0], candidateEvidenceChunks)
+	if err != nil {
+		return err
+	}
+	byDoc := make(map[uint][]scoredChunk, len(docIDs))
+	for _, chunk := range chunks {
+		byDoc[chunk.DocumentID] = append(byDoc[chunk.DocumentID], scoredChunk{
+			chunk: chunk,
+			score: cosineSimilarity(vectors[0], chunk.EmbeddingVector()),
+		})
+	}
+	for i := range matches {
+		top := topKScored(byDoc[matches[i].Candidate.DocumentID], candidateEvidenceChunks)
+		for _, sc := range top {
+			matches[i].Evidence = append(matches[i].Evidence, CandidateEvidence{
+				ChunkID: sc.chunk.ID,
+				Content: truncateRunes(strings.TrimSpace(sc.chunk.Content), maxCandidateEvidenceChars),
+				Score:   math.Round(float64(sc.score)*1000) / 1000,
+			})
+		}
+		if len(matches[i].Evidence) > 0 {
+			matches[i].Score = matches[i].Evidence[0].Score
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool { return matches[i].Score > matches[j].Score })
+	return nil
+}
+
+func candidateView(c model.WorkspaceCandidate) CandidateView {
+	view := CandidateView{WorkspaceCandidate: c, Skills: []string{}}
+	_ = json.Unmarshal([]byte(c.Skills), &view.Skills)
+	return view
+}
@@ -0,0 +1,167 @@
+package app
+
+import (
+	"context"
+	"strings"
+
+	"gopherai-resume/internal/model"
+	"gopherai-resume/internal/pkg/apperr"
+	"gopherai-resume/internal/pkg/authz"
+)
+
+var (
+	ErrSessionHasForks = apperr.Conflict(apperr.CodeSessionHasForks, "session has forks; delete them first")
+	ErrMessageForked   = apperr.Conflict(apperr.CodeSessionHasForks, "a fork continues from this message or a later one; edit it with fork instead")
+	ErrForkTooDeep     = apperr.BadRequest("session is nested too deeply to fork")
+)
+
+const (
+	// maxForkDepth caps how many forks deep a session may sit below its root.
+	maxForkDepth = 16
+	// maxTreeMessages caps the messages returned for one session tree.
+	maxTreeMessages = 2000
+)
+
+// ForkSessionInput branches a new session off SessionID's conversation after MessageID,
+// which may be any message on that session's branch.
+type ForkSessionInput struct {
+	UserID    uint
+	SessionID uint
+	MessageID uint
+	Title     string
+}
+
+// ForkSession creates a session that shares the conversation up to and including the
+// message and continues separately from there. Nothing is copied: the fork reads the
+// earlier history from the session that holds it. The fork inherits the LLM settings and
+// RAG session of SessionID.
+func (s *ChatService) ForkSession(ctx context.Context, input ForkSessionInput) (*model.Session, error) {
+	if input.UserID == 0 || input.SessionID == 0 || input.MessageID == 0 {
+		return nil, ErrInvalidInput
+	}
+	session, err := loadChatSession(ctx, s.sessionRepo, input.SessionID, input.UserID, authz.Write)
+	if err != nil {
+		return nil, err
+	}
+	if session == nil {
+		return nil, ErrSessionNotFound
+	}
+	message, err := s.messageRepo.GetOnBranch(ctx, session.ID, input.MessageID)
+	if err != nil {
+		return nil, err
+	}
+	if message == nil {
+		return nil, ErrMessageNotFound
+	}
+	title := strings.TrimSpace(input.Title)
+	if title == "" {
+		title = forkTitle(session.Title, " (branch)")
+	}
+	fork, err := s.newFork(ctx, session, message, title)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.sessionRepo.Create(ctx, fork); err != nil {
+		return nil, err
+	}
+	return fork, nil
+}
+
+// newFork builds, without storing it, a session of source's user that continues from
+// point, or starts a new conversation when point is nil. It hangs off the session that
+// holds point, which is an ancestor of source when point was inherited, and inherits
+// source's LLM settings and RAG session.
+func (s *ChatService) newFork(ctx context.Context, source *model.Session, point *model.Message, title string) (*model.Session, error) {
+	fork := &model.Session{
+		UserID:       source.UserID,
+		Title:        title,
+		Model:        source.Model,
+		Temperature:  source.Temperature,
+		TopP:         source.TopP,
+		MaxTokens:    source.MaxTokens,
+		RAGSessionID: source.RAGSessionID,
+	}
+	if point == nil {
+		return fork, nil
+	}
+	lineage, err := s.sessionRepo.Lineage(ctx, point.SessionID)
+	if err != nil {
+		return nil, err
+	}
+	if len(lineage) == 0 {
+		return nil, ErrSessionNotFound
+	}
+	if len(lineage) > maxForkDepth {
+		return nil, ErrForkTooDeep
+	}
+	fork.ParentSessionID, fork.ForkMessageID = &point.SessionID, &point.ID
+	// A summary that stops at or before the fork point describes the fork's history too.
+	if owner := lineage[0]; owner.Summary != "" && owner.SummaryUntilID <= point.ID {
+		fork.Summary, fork.SummaryUntilID = owner.Summary, owner.SummaryUntilID
+	}
+	return fork, nil
+}
+
+// checkNotForked returns ErrMessageForked when a fork's history includes message, which
+// then must not be changed or removed in place.
+func (s *ChatService) checkNotForked(ctx context.Context, message *model.Message) error {
+	forked, err := s.sessionRepo.HasForksFrom(ctx, message.SessionID, message.ID)
+	if err != nil {
+		return err
+	}
+	if forked {
+		return ErrMessageForked
+	}
+	return nil
+}
+
+// SessionTree is a conversation and all of its forks. Each message links to the one it
+// follows through parent_message_id, and each fork names its parent session and fork
+// message, so clients can draw the tree.
+type SessionTree struct {
+	RootSessionID uint            `json:"root_session_id"`
+	Sessions      []model.Session `json:"sessions"`
+	Messages      []model.Message `json:"messages"`
+	// HasMore is set when the tree holds more than maxTreeMessages messages; the oldest
+	// are returned.
+	HasMore bool `json:"has_more"`
+}
+
+// GetSessionTree returns the tree the session belongs to, from its root session down.
+func (s *ChatService) GetSessionTree(ctx context.Context, userID, sessionID uint) (*SessionTree, error) {
+	if userID == 0 || sessionID == 0 {
+		return nil, ErrInvalidInput
+	}
+	session, err := loadChatSession(ctx, s.sessionRepo, sessionID, userID, authz.Read)
+	if err != nil {
+		return nil, err
+	}
+	if session == nil {
+		return nil, ErrSessionNotFound
+	}
+	lineage, err := s.sessionRepo.Lineage(ctx, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	root := session
+	if len(lineage) > 0 {
+		root = &lineage[len(lineage)-1]
+	}
+	sessions, err := s.sessionRepo.ListTree(ctx, root.ID, userID)
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]uint, len(sessions))
+	for i, item := range sessions {
+		ids[i] = item.ID
+	}
+	messages, err := s.messageRepo.ListBySessionIDs(ctx, ids, maxTreeMessages+1)
+	if err != nil {
+		return nil, err
+	}
+	tree := &SessionTree{RootSessionID: root.ID, Sessions: sessions, Messages: messages}
+	if len(messages) > maxTreeMessages {
+		tree.Messages, tree.HasMore = messages[:maxTreeMessages], true
+	}
+	return tree, nil
+}
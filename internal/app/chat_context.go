@@ -0,0 +1,247 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+
+	"gopherai-resume/internal/ai"
+	"gopherai-resume/internal/model"
+)
+
+const (
+	// summaryBudgetShare is the fraction (1/n) of the context budget set aside for the
+	// summary of earlier messages.
+	summaryBudgetShare = 4
+	// summaryBatchSize is how many messages one summarization call folds in.
+	summaryBatchSize       = 100
+	maxSummaryMessageChars = 2000
+	// summarySessionsPerRun bounds how many sessions one SummarizePending run catches up.
+	summarySessionsPerRun = 50
+	// chatRAGTopK is how many chunks of an attached RAG session are added to each prompt.
+	chatRAGTopK = 4
+	// ragBudgetShare is the fraction (1/n) of the context budget the excerpts may take.
+	ragBudgetShare = 3
+)
+
+const chatSystemPrompt = "You are a concise and helpful AI assistant."
+
+// buildPromptMessages assembles the system prompt, recent history and the current input.
+// History is capped at maxContext messages and trimmed, oldest first, to fit
+// maxContextTokens; the newest message is always kept. Messages that no longer fit are
+// marked for the summary worker to fold into the session's rolling summary, which is sent
+// as a second system message; until it catches up they are left out.
+// Sessions attached to a RAG session also get the most relevant document excerpts.
+func (s *ChatService) buildPromptMessages(ctx context.Context, session *model.Session, currentUserInput string) ([]ai.ChatMessage, error) {
+	recent, err := s.messageRepo.ListRecentBySessionID(ctx, session.ID, s.maxContext)
+	if err != nil {
+		return nil, err
+	}
+
+	system := ai.ChatMessage{Role: "system", Content: chatSystemPrompt}
+	conversation := make([]ai.ChatMessage, 0, len(recent)+1)
+	for _, item := range recent {
+		role := item.Role
+		if role == "" {
+			role = "user"
+		}
+		conversation = append(conversation, ai.ChatMessage{
+			Role:    role,
+			Content: item.Content,
+		})
+	}
+	if strings.TrimSpace(currentUserInput) != "" {
+		conversation = append(conversation, ai.ChatMessage{
+			Role:    "user",
+			Content: strings.TrimSpace(currentUserInput),
+		})
+	}
+
+	prefix := []ai.ChatMessage{system}
+	if excerpts := s.retrieveContext(ctx, session, conversation); excerpts != "" {
+		prefix = append(prefix, ai.ChatMessage{Role: "system", Content: excerpts})
+	}
+	budget := s.maxContextTokens
+	for _, m := range prefix {
+		budget -= ai.EstimateMessageTokens(m)
+	}
+	start := fitTokenBudget(conversation, budget)
+	// Older messages may exist beyond the loaded window when it is full.
+	dropped := start > 0 || len(recent) == s.maxContext
+	if !dropped && session.Summary == "" {
+		return append(prefix, conversation...), nil
+	}
+
+	start = fitTokenBudget(conversation, budget-s.summaryTokens())
+	summary := session.Summary
+	if len(recent) > 0 {
+		// Everything older than the first message kept verbatim belongs in the summary.
+		beforeID := recent[len(recent)-1].ID + 1
+		if start < len(recent) {
+			beforeID = recent[start].ID
+		}
+		if beforeID > session.SummaryUntilID+1 && beforeID > session.SummaryPendingID {
+			if err := s.sessionRepo.MarkSummaryPending(ctx, session.ID, beforeID); err != nil {
+				log.Printf("mark history of session %d for summary failed: %v", session.ID, err)
+			}
+		}
+	}
+
+	messages := prefix
+	if summary != "" {
+		messages = append(messages, ai.ChatMessage{
+			Role:    "system",
+			Content: "Summary of the earlier conversation:\n" + summary,
+		})
+	}
+	return append(messages, conversation[start:]...), nil
+}
+
+// retrieveContext returns the attached RAG session's chunks most relevant to the latest
+// user message, formatted as numbered excerpts within the excerpt budget. Retrieval
+// failures are logged and the reply goes ahead without excerpts.
+func (s *ChatService) retrieveContext(ctx context.Context, session *model.Session, conversation []ai.ChatMessage) string {
+	if s.retriever == nil || session.RAGSessionID == nil {
+		return ""
+	}
+	query := ""
+	for i := len(conversation) - 1; i >= 0; i-- {
+		if conversation[i].Role == "user" {
+			query = conversation[i].Content
+			break
+		}
+	}
+	if strings.TrimSpace(query) == "" {
+		return ""
+	}
+	chunks, err := s.retriever.RetrieveFromSession(ctx, session.UserID, *session.RAGSessionID, query, chatRAGTopK)
+	if err != nil {
+		log.Printf("retrieve context for session %d from rag session %d failed: %v", session.ID, *session.RAGSessionID, err)
+		return ""
+	}
+	if len(chunks) == 0 {
+		return ""
+	}
+
+	budget := s.maxContextTokens / ragBudgetShare
+	var b strings.Builder
+	b.WriteString("Use these excerpts from the user's documents when they are relevant to the question:")
+	for i, chunk := range chunks {
+		entry := fmt.Sprintf("\n\n[%d] %s", i+1, strings.TrimSpace(chunk.Content))
+		if i > 0 && ai.EstimateTokens(b.String()+entry) > budget {
+			break
+		}
+		b.WriteString(entry)
+	}
+	return truncateRunes(b.String(), budget*4)
+}
+
+// fitTokenBudget returns the index of the oldest message that, together with every newer
+// one, fits budget. The last message is always included.
+func fitTokenBudget(messages []ai.ChatMessage, budget int) int {
+	start := len(messages)
+	for start > 0 {
+		cost := ai.EstimateMessageTokens(messages[start-1])
+		if cost > budget && start < len(messages) {
+			break
+		}
+		budget -= cost
+		start--
+	}
+	return start
+}
+
+// summaryTokens is the share of the context budget the summary may take.
+func (s *ChatService) summaryTokens() int {
+	return s.maxContextTokens / summaryBudgetShare
+}
+
+// SummarizePending folds the messages that stopped fitting the prompt into their sessions'
+// summaries, with each session's own model settings, and returns how many sessions it
+// brought up to date. A session that fails is logged and retried on the next run.
+func (s *ChatService) SummarizePending(ctx context.Context) (int, error) {
+	sessions, err := s.sessionRepo.ListSummaryPending(ctx, summarySessionsPerRun)
+	if err != nil {
+		return 0, err
+	}
+	done := 0
+	for i := range sessions {
+		session := &sessions[i]
+		cfg, err := s.resolveLLM(ctx, session.UserID, session, LLMOverride{})
+		if err == nil {
+			_, err = s.refreshSummary(ctx, session, cfg, session.SummaryPendingID, s.summaryTokens())
+		}
+		if err != nil {
+			if ctx.Err() != nil {
+				return done, ctx.Err()
+			}
+			log.Printf("summarize history of session %d failed: %v", session.ID, err)
+			continue
+		}
+		if session.SummaryUntilID+1 < session.SummaryPendingID {
+			// The rest of the range was deleted meanwhile; mark it covered so the session
+			// is not listed again.
+			err := s.sessionRepo.UpdateSummary(ctx, session.ID, session.Summary, session.SummaryPendingID-1)
+			if err != nil {
+				log.Printf("advance summary of session %d failed: %v", session.ID, err)
+				continue
+			}
+		}
+		done++
+	}
+	return done, nil
+}
+
+// refreshSummary folds the session's messages between its summary and beforeID into the
+// summary, storing progress after each batch. It returns the newest summary, which is the
+// stored one when an error stops it early.
+func (s *ChatService) refreshSummary(ctx context.Context, session *model.Session, cfg ai.ChatConfig, beforeID uint, maxTokens int) (string, error) {
+	for {
+		if session.SummaryUntilID+1 >= beforeID {
+			return session.Summary, nil
+		}
+		batch, err := s.messageRepo.ListRangeBySessionID(ctx, session.ID, session.SummaryUntilID, beforeID, summaryBatchSize)
+		if err != nil || len(batch) == 0 {
+			return session.Summary, err
+		}
+		summary, err := s.summarize(ctx, cfg, session.Summary, batch, maxTokens)
+		if err != nil {
+			return session.Summary, err
+		}
+		untilID := batch[len(batch)-1].ID
+		if err := s.sessionRepo.UpdateSummary(ctx, session.ID, summary, untilID); err != nil {
+			return session.Summary, err
+		}
+		session.Summary, session.SummaryUntilID = summary, untilID
+		if len(batch) < summaryBatchSize {
+			return summary, nil
+		}
+	}
+}
+
+func (s *ChatService) summarize(ctx context.Context, cfg ai.ChatConfig, previous string, messages []model.Message, maxTokens int) (string, error) {
+	var transcript strings.Builder
+	if previous != "" {
+		fmt.Fprintf(&transcript, "Summary so far:\n%s\n\nNew messages:\n", previous)
+	}
+	for _, m := range messages {
+		fmt.Fprintf(&transcript, "%s: %s\n", m.Role, truncateRunes(m.Content, maxSummaryMessageChars))
+	}
+	cfg.MaxTokens = maxTokens
+	reply, err := s.completer.Complete(ctx, cfg, []ai.ChatMessage{
+		{Role: "system", Content: fmt.Sprintf("You maintain a running summary of a chat between a user and an assistant. "+
+			"Merge the new messages into the summary so far. Keep facts about the user, their goals and preferences, "+
+			"decisions, figures, names and open questions; drop pleasantries. Write plain prose or short bullets, "+
+			"at most %d words, and reply with the summary only.", maxTokens*3/4)},
+		{Role: "user", Content: transcript.String()},
+	})
+	if err != nil {
+		return "", err
+	}
+	summary := strings.TrimSpace(reply)
+	if summary == "" {
+		return "", fmt.Errorf("empty summary")
+	}
+	return truncateRunes(summary, maxTokens*4), nil
+}
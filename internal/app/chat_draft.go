@@ -0,0 +1,100 @@
+package app
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"gopherai-resume/internal/model"
+	"gopherai-resume/internal/pkg/apperr"
+	"gopherai-resume/internal/pkg/authz"
+)
+
+var (
+	ErrDraftTooLong      = apperr.BadRequest("draft exceeds 20000 characters")
+	ErrDraftsUnavailable = apperr.New(http.StatusServiceUnavailable, apperr.CodeFeatureUnavailable, "draft storage is not configured")
+)
+
+const maxDraftRunes = 20000
+
+// DraftStore keeps unsent drafts per user and session; GetDraft returns nil when there is none.
+type DraftStore interface {
+	GetDraft(ctx context.Context, userID, sessionID uint) (*model.ChatDraft, error)
+	SetDraft(ctx context.Context, userID uint, draft *model.ChatDraft) error
+	DeleteDraft(ctx context.Context, userID, sessionID uint) error
+}
+
+// ChatDraftService autosaves the prompt a user is still writing, so a reloaded tab can
+// restore it.
+type ChatDraftService struct {
+	drafts   DraftStore // nil when Redis is disabled
+	sessions SessionRepository
+	now      func() time.Time
+}
+
+func NewChatDraftService(drafts DraftStore, sessions SessionRepository) *ChatDraftService {
+	return &ChatDraftService{drafts: drafts, sessions: sessions, now: time.Now}
+}
+
+// Get returns the session's draft, or an empty one if nothing is saved.
+func (s *ChatDraftService) Get(ctx context.Context, userID, sessionID uint) (*model.ChatDraft, error) {
+	if err := s.authorize(ctx, userID, sessionID); err != nil {
+		return nil, err
+	}
+	draft, err := s.drafts.GetDraft(ctx, userID, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	if draft == nil {
+		draft = &model.ChatDraft{SessionID: sessionID}
+	}
+	return draft, nil
+}
+
+// Save stores content as the session's draft. Blank content clears it instead.
+func (s *ChatDraftService) Save(ctx context.Context, userID, sessionID uint, content string) (*model.ChatDraft, error) {
+	if utf8.RuneCountInString(content) > maxDraftRunes {
+		return nil, ErrDraftTooLong
+	}
+	if err := s.authorize(ctx, userID, sessionID); err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(content) == "" {
+		if err := s.drafts.DeleteDraft(ctx, userID, sessionID); err != nil {
+			return nil, err
+		}
+		return &model.ChatDraft{SessionID: sessionID}, nil
+	}
+	draft := &model.ChatDraft{SessionID: sessionID, Content: content, UpdatedAt: s.now()}
+	if err := s.drafts.SetDraft(ctx, userID, draft); err != nil {
+		return nil, err
+	}
+	return draft, nil
+}
+
+// Delete clears the session's draft, e.g. once the message has been sent.
+func (s *ChatDraftService) Delete(ctx context.Context, userID, sessionID uint) error {
+	if err := s.authorize(ctx, userID, sessionID); err != nil {
+		return err
+	}
+	return s.drafts.DeleteDraft(ctx, userID, sessionID)
+}
+
+func (s *ChatDraftService) authorize(ctx context.Context, userID, sessionID uint) error {
+	if s.drafts == nil {
+		return ErrDraftsUnavailable
+	}
+	if userID == 0 || sessionID == 0 {
+		return ErrInvalidInput
+	}
+	session, err := loadChatSession(ctx, s.sessions, sessionID, userID, authz.Write)
+	if err != nil {
+		return err
+	}
+	if session == nil {
+		return ErrSessionNotFound
+	}
+	return nil
+}
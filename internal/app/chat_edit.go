@@ -0,0 +1,261 @@
+package app
+
+import (
+	"context"
+	"log"
+	"slices"
+	"strings"
+	"time"
+
+	"gopherai-resume/internal/ai"
+	"gopherai-resume/internal/model"
+	"gopherai-resume/internal/pkg/apperr"
+	"gopherai-resume/internal/pkg/authz"
+)
+
+var (
+	ErrMessageNotFound    = apperr.NotFound(apperr.CodeMessageNotFound, "message not found")
+	ErrMessageNotEditable = apperr.BadRequest("only user messages can be edited")
+)
+
+// EditMessageInput replaces a user message's content. By default the messages after it are
+// deleted, which is refused once a fork shares them; with Fork the original session is left
+// untouched and the edited message starts a fork of the conversation before it. Regenerate
+// asks the model for a fresh reply to the edit.
+type EditMessageInput struct {
+	UserID     uint
+	MessageID  uint
+	Content    string
+	Fork       bool
+	Regenerate bool
+	LLM        LLMOverride
+}
+
+type EditMessageResult struct {
+	SessionID       uint           `json:"session_id"`
+	Forked          bool           `json:"forked"`
+	Message         model.Message  `json:"message"`
+	RemovedMessages int64          `json:"removed_messages"`
+	Reply           *model.Message `json:"reply,omitempty"`
+}
+
+func (s *ChatService) EditMessage(ctx context.Context, input EditMessageInput) (*EditMessageResult, error) {
+	if input.UserID == 0 || input.MessageID == 0 {
+		return nil, ErrInvalidInput
+	}
+	content := strings.TrimSpace(input.Content)
+	if content == "" {
+		return nil, ErrMessageEmpty
+	}
+
+	message, err := loadChatMessage(ctx, s.messageRepo, input.MessageID, input.UserID, authz.Write)
+	if err != nil {
+		return nil, err
+	}
+	if message == nil {
+		return nil, ErrMessageNotFound
+	}
+	if message.Role != "user" {
+		return nil, ErrMessageNotEditable
+	}
+	session, err := loadChatSession(ctx, s.sessionRepo, message.SessionID, input.UserID, authz.Write)
+	if err != nil {
+		return nil, err
+	}
+	if session == nil {
+		return nil, ErrSessionNotFound
+	}
+
+	// Resolve the LLM before changing anything so a bad override does not leave a half-done edit.
+	var cfg ai.ChatConfig
+	if input.Regenerate {
+		if cfg, err = s.resolveLLM(ctx, input.UserID, session, input.LLM); err != nil {
+			return nil, err
+		}
+		if s.publisher == nil {
+			return nil, ErrMessageEnqueue
+		}
+	}
+
+	if !input.Fork {
+		// Truncating and regenerating in place must not interleave with a send, and the
+		// message may have been removed by one that finished while we waited.
+		unlock, err := s.lockSession(ctx, session.ID)
+		if err != nil {
+			return nil, err
+		}
+		defer unlock()
+		if message, err = loadChatMessage(ctx, s.messageRepo, input.MessageID, input.UserID, authz.Write); err != nil {
+			return nil, err
+		}
+		if message == nil {
+			return nil, ErrMessageNotFound
+		}
+	}
+	if content, err = s.fitMessage(ctx, session, content); err != nil {
+		return nil, err
+	}
+
+	result := &EditMessageResult{Forked: input.Fork}
+	target := session
+	if input.Fork {
+		// The fork shares the conversation up to the message the edited one follows.
+		var point *model.Message
+		earlier, _, err := s.messageRepo.ListPageBySessionID(ctx, message.SessionID, message.ID, 1)
+		if err != nil {
+			return nil, err
+		}
+		if len(earlier) > 0 {
+			point = &earlier[0]
+		}
+		fork, err := s.newFork(ctx, session, point, forkTitle(session.Title, " (edited)"))
+		if err != nil {
+			return nil, err
+		}
+		edited := []model.Message{{
+			UserID:    input.UserID,
+			Role:      message.Role,
+			Content:   content,
+			CreatedAt: time.Now(),
+		}}
+		if point != nil {
+			edited[0].ParentMessageID = &point.ID
+		}
+		if err := s.messageRepo.Fork(ctx, fork, edited); err != nil {
+			return nil, err
+		}
+		result.SessionID = fork.ID
+		target = fork
+		result.Message = edited[0]
+	} else {
+		if err := s.checkNotForked(ctx, message); err != nil {
+			return nil, err
+		}
+		removed, err := s.messageRepo.ReplaceContentAndTruncate(ctx, message, content)
+		if err != nil {
+			return nil, err
+		}
+		if message.ID <= session.SummaryUntilID {
+			// The summary describes the conversation as it was before the edit.
+			if err := s.sessionRepo.UpdateSummary(ctx, session.ID, "", 0); err != nil {
+				return nil, err
+			}
+			session.Summary, session.SummaryUntilID = "", 0
+		}
+		result.SessionID = message.SessionID
+		result.Message = *message
+		result.RemovedMessages = removed
+	}
+	if s.historyCache != nil {
+		_ = s.historyCache.MarkDirty(ctx, result.SessionID)
+		_ = s.historyCache.DeleteHistory(ctx, result.SessionID)
+	}
+	// The edit dropped the message's old embedding, or the fork stored a new message.
+	if s.embedQueue != nil {
+		if err := s.embedQueue.Publish(ctx, result.Message); err != nil {
+			log.Printf("enqueue embedding of edited message %d failed: %v", result.Message.ID, err)
+		}
+	}
+
+	if !input.Regenerate {
+		return result, nil
+	}
+	promptMessages, err := s.buildPromptMessages(ctx, target, "")
+	if err != nil {
+		return nil, err
+	}
+	meterCtx, meter := ai.WithUsageMeter(ctx)
+	reply, err := s.completer.Complete(meterCtx, cfg, promptMessages)
+	if err != nil {
+		return nil, err
+	}
+	reply = strings.TrimSpace(reply)
+	if reply == "" {
+		reply = "The model returned an empty response."
+	}
+	assistantMessage := model.Message{
+		SessionID: result.SessionID,
+		UserID:    input.UserID,
+		Role:      "assistant",
+		Content:   reply,
+		CreatedAt: time.Now(),
+	}
+	applyUsage(&assistantMessage, cfg, meter, promptMessages)
+	if err := s.publisher.Publish(ctx, assistantMessage); err != nil {
+		return nil, ErrMessageEnqueue
+	}
+	result.Reply = &assistantMessage
+	return result, nil
+}
+
+// forkTitle marks a forked session's title with suffix, keeping it within the
+// 128-character column.
+func forkTitle(title, suffix string) string {
+	runes := []rune(title)
+	if limit := 128 - len(suffix); len(runes) > limit {
+		runes = runes[:limit]
+	}
+	return string(runes) + suffix
+}
+
+// maxBulkDeleteMessages caps how many messages one bulk delete may remove.
+const maxBulkDeleteMessages = 100
+
+// DeleteMessagesResult reports how many messages were deleted and from which sessions.
+type DeleteMessagesResult struct {
+	Deleted    int64  `json:"deleted"`
+	SessionIDs []uint `json:"session_ids"`
+}
+
+// DeleteMessages deletes the user's messages by ID, possibly across sessions. Either all of
+// them are deleted or, if any is missing or not the user's, none. Unlike an edit, later
+// messages are kept.
+func (s *ChatService) DeleteMessages(ctx context.Context, userID uint, messageIDs []uint) (*DeleteMessagesResult, error) {
+	ids := uniqueIDs(messageIDs)
+	if userID == 0 || len(ids) == 0 || len(ids) > maxBulkDeleteMessages {
+		return nil, ErrInvalidInput
+	}
+	messages, err := s.messageRepo.ListByIDsAndUserID(ctx, ids, userID)
+	if err != nil {
+		return nil, err
+	}
+	if len(messages) != len(ids) {
+		return nil, ErrMessageNotFound
+	}
+	for i := range messages {
+		if err := s.checkNotForked(ctx, &messages[i]); err != nil {
+			return nil, err
+		}
+	}
+	oldest := make(map[uint]uint) // session ID -> smallest deleted message ID
+	for _, m := range messages {
+		if first, ok := oldest[m.SessionID]; !ok || m.ID < first {
+			oldest[m.SessionID] = m.ID
+		}
+	}
+
+	removed, err := s.messageRepo.DeleteByIDs(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	result := &DeleteMessagesResult{Deleted: removed, SessionIDs: make([]uint, 0, len(oldest))}
+	for sessionID, first := range oldest {
+		result.SessionIDs = append(result.SessionIDs, sessionID)
+		session, err := loadChatSession(ctx, s.sessionRepo, sessionID, userID, authz.Write)
+		if err != nil {
+			return nil, err
+		}
+		if session != nil && first <= session.SummaryUntilID {
+			// The summary still describes the deleted messages.
+			if err := s.sessionRepo.UpdateSummary(ctx, sessionID, "", 0); err != nil {
+				return nil, err
+			}
+		}
+		if s.historyCache != nil {
+			_ = s.historyCache.MarkDirty(ctx, sessionID)
+			_ = s.historyCache.DeleteHistory(ctx, sessionID)
+		}
+	}
+	slices.Sort(result.SessionIDs)
+	return result, nil
+}
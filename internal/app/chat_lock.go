@@ -0,0 +1,55 @@
+package app
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"gopherai-resume/internal/pkg/apperr"
+)
+
+var ErrSessionBusy = apperr.Conflict(apperr.CodeSessionBusy, "another message is being processed in this session")
+
+// sessionLockPoll is how often a waiting send retries a held session lock.
+const sessionLockPoll = 100 * time.Millisecond
+
+// SessionLocker serializes sends within a chat session.
+type SessionLocker interface {
+	// TryLock takes the lock for sessionID if it is free. When ok, release must be called
+	// once the turn is recorded.
+	TryLock(ctx context.Context, sessionID uint) (release func(), ok bool, err error)
+}
+
+// lockSession waits up to s.lockWait for sessionID's lock and returns ErrSessionBusy when
+// it stays held. Without a locker, or when the locker fails, it returns a no-op release so
+// sends are not blocked by a lock backend outage.
+func (s *ChatService) lockSession(ctx context.Context, sessionID uint) (func(), error) {
+	if s.locks == nil {
+		return func() {}, nil
+	}
+	deadline := time.Now().Add(s.lockWait)
+	for {
+		release, ok, err := s.locks.TryLock(ctx, sessionID)
+		if err != nil {
+			log.Printf("lock session %d failed, sending unserialized: %v", sessionID, err)
+			return func() {}, nil
+		}
+		if ok {
+			return release, nil
+		}
+		wait := time.Until(deadline)
+		if wait <= 0 {
+			return nil, ErrSessionBusy
+		}
+		if wait > sessionLockPoll {
+			wait = sessionLockPoll
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
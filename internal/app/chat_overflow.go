@@ -0,0 +1,91 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+
+	"gopherai-resume/internal/model"
+	"gopherai-resume/internal/pkg/apperr"
+)
+
+var ErrMessageTooLong = apperr.New(http.StatusRequestEntityTooLarge, apperr.CodeMessageTooLong, "message content is too long")
+
+// overflowPreviewRunes is how much of the start and of the end of an oversized message is
+// kept in the chat; questions usually come before or after the pasted text.
+const overflowPreviewRunes = 1000
+
+// MessageOverflow stores oversized chat input as a RAG document, so the relevant parts
+// are retrieved as context instead of the whole text being sent with every turn.
+type MessageOverflow interface {
+	// StoreOverflow ingests content into the RAG session, creating one titled title when
+	// ragSessionID is 0, and returns the new document.
+	StoreOverflow(ctx context.Context, userID, ragSessionID uint, title, content string) (*model.RAGDocument, error)
+}
+
+// checkMessageSize rejects content over the size limit unless it can overflow to RAG.
+func (s *ChatService) checkMessageSize(content string) error {
+	if s.maxMessageRunes <= 0 || s.overflow != nil || utf8.RuneCountInString(content) <= s.maxMessageRunes {
+		return nil
+	}
+	return ErrMessageTooLong.Withf("at most %d characters", s.maxMessageRunes)
+}
+
+// fitMessage returns content as it should be stored and sent. Content over the size limit
+// is moved to a RAG document attached to the session, attaching a new RAG session if it
+// has none, and replaced by its start and end and a note naming the document.
+func (s *ChatService) fitMessage(ctx context.Context, session *model.Session, content string) (string, error) {
+	if err := s.checkMessageSize(content); err != nil {
+		return "", err
+	}
+	runes := []rune(content)
+	if s.maxMessageRunes <= 0 || len(runes) <= s.maxMessageRunes {
+		return content, nil
+	}
+
+	var ragSessionID uint
+	if session.RAGSessionID != nil {
+		ragSessionID = *session.RAGSessionID
+	}
+	name := fmt.Sprintf("Pasted in chat %q", session.Title)
+	doc, err := s.overflow.StoreOverflow(ctx, session.UserID, ragSessionID, name, content)
+	if err != nil {
+		return "", fmt.Errorf("store oversized message failed: %w", err)
+	}
+	if ragSessionID == 0 {
+		session.RAGSessionID = &doc.SessionID
+		if err := s.sessionRepo.Update(ctx, session); err != nil {
+			return "", err
+		}
+	}
+
+	preview := content
+	if len(runes) > 2*overflowPreviewRunes {
+		preview = string(runes[:overflowPreviewRunes]) + "\n[…]\n" + string(runes[len(runes)-overflowPreviewRunes:])
+	}
+	return fmt.Sprintf("%s\n\n[The full text, %d characters, was saved as document #%d %q in the attached RAG session; relevant parts are retrieved from it.]",
+		preview, len(runes), doc.ID, doc.Name), nil
+}
+
+// StoreOverflow implements MessageOverflow.
+func (s *RAGService) StoreOverflow(ctx context.Context, userID, ragSessionID uint, title, content string) (*model.RAGDocument, error) {
+	if ragSessionID == 0 {
+		session, err := s.CreateSession(ctx, RAGCreateSessionInput{UserID: userID, Title: title})
+		if err != nil {
+			return nil, err
+		}
+		ragSessionID = session.ID
+	}
+	result, err := s.Ingest(ctx, IngestInput{
+		UserID:    userID,
+		SessionID: ragSessionID,
+		Name:      strings.TrimSpace(title),
+		Content:   content,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &result.Document, nil
+}
@@ -0,0 +1,276 @@
+package app
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"gopherai-resume/internal/ai"
+	"gopherai-resume/internal/pkg/apperr"
+	"gopherai-resume/internal/pkg/requestid"
+)
+
+var (
+	ErrProxyModelNotAllowed = apperr.BadRequest("model is not available through the proxy")
+	ErrProxyMessages        = apperr.BadRequest("messages must be non-empty, with roles system, user, assistant or tool")
+	ErrProxyUnmetered       = apperr.New(http.StatusServiceUnavailable, apperr.CodeFeatureUnavailable, "the chat proxy needs a usage counter")
+)
+
+const (
+	// proxyPromptPreviewRunes is how much of the last user message ProxyLogPrompts logs.
+	proxyPromptPreviewRunes = 200
+	// proxyCompletionReserve is the completion reserved from the quota for a request that
+	// sets no max_tokens.
+	proxyCompletionReserve = 4096
+)
+
+var proxyRoles = map[string]bool{"system": true, "user": true, "assistant": true, "tool": true}
+
+// Patterns masked in logged prompt previews, in order: API keys and bearer tokens, email
+// addresses, then runs of 7 or more digits (phone, card and ID numbers).
+var proxyMasks = []struct {
+	pattern *regexp.Regexp
+	replace string
+}{
+	{regexp.MustCompile(`(?i)\b(sk-[a-z0-9_-]{8,}|bearer\s+[a-z0-9._-]{8,})`), "[key]"},
+	{regexp.MustCompile(`[\w.+-]+@[\w-]+(\.[\w-]+)+`), "[email]"},
+	{regexp.MustCompile(`\+?\d[\d -]{5,}\d`), "[number]"},
+}
+
+// ProxyChatInput is an OpenAI-style chat completions request. An empty Model is the
+// configured one.
+type ProxyChatInput struct {
+	UserID      uint
+	Model       string
+	Messages    []ai.ChatMessage
+	Temperature *float64
+	TopP        *float64
+	MaxTokens   int
+	Stop        []string
+}
+
+// ProxyCompletion is the answer to a proxied request. Usage is what the provider reported,
+// or an estimate when UsageEstimated is set.
+type ProxyCompletion struct {
+	ID             string
+	Model          string
+	Created        int64
+	Content        string
+	Usage          ai.Usage
+	UsageEstimated bool
+}
+
+// ChatProxyService forwards OpenAI-compatible chat completions to the configured provider
+// for authenticated users, so tools can use this server as a gateway without the provider
+// key. Tokens count against the user's daily proxy quota.
+type ChatProxyService struct {
+	completer  ai.Completer
+	defaultLLM ai.ChatConfig
+	models     map[string]bool
+	quota      *QuotaService
+	logPrompts bool
+	policy     ModelPolicy // nil allows every proxied model
+}
+
+func NewChatProxyService(
+	completer ai.Completer,
+	defaultLLM ai.ChatConfig,
+	models []string,
+	quota *QuotaService,
+	logPrompts bool,
+	policy ModelPolicy,
+) *ChatProxyService {
+	allowed := map[string]bool{defaultLLM.Model: true}
+	for _, name := range models {
+		if name = strings.TrimSpace(name); name != "" {
+			allowed[name] = true
+		}
+	}
+	return &ChatProxyService{
+		completer:  completer,
+		defaultLLM: defaultLLM,
+		models:     allowed,
+		quota:      quota,
+		logPrompts: logPrompts,
+		policy:     policy,
+	}
+}
+
+// Complete answers the request in one shot.
+func (s *ChatProxyService) Complete(ctx context.Context, input ProxyChatInput) (*ProxyCompletion, error) {
+	return s.run(ctx, input, false, func(ctx context.Context, cfg ai.ChatConfig, completion *ProxyCompletion) (string, error) {
+		return s.completer.Complete(ctx, cfg, input.Messages)
+	})
+}
+
+// Stream answers the request chunk by chunk. onChunk receives the completion being built,
+// whose ID, Model and Created are already set, with each piece of text.
+func (s *ChatProxyService) Stream(
+	ctx context.Context,
+	input ProxyChatInput,
+	onChunk func(completion *ProxyCompletion, chunk string) error,
+) (*ProxyCompletion, error) {
+	return s.run(ctx, input, true, func(ctx context.Context, cfg ai.ChatConfig, completion *ProxyCompletion) (string, error) {
+		return s.completer.StreamComplete(ctx, cfg, input.Messages, func(chunk string) error {
+			return onChunk(completion, chunk)
+		})
+	})
+}
+
+// run validates the request and reserves the estimated prompt tokens plus the most the
+// completion may use from the quota before calling the provider, then settles the quota
+// with the real usage. A failed call gives the reservation back. Without a usage counter
+// nothing could be metered, so every request is refused. Every call is logged, without its
+// content unless logPrompts is set.
+func (s *ChatProxyService) run(
+	ctx context.Context,
+	input ProxyChatInput,
+	stream bool,
+	call func(ctx context.Context, cfg ai.ChatConfig, completion *ProxyCompletion) (string, error),
+) (*ProxyCompletion, error) {
+	if input.UserID == 0 {
+		return nil, ErrInvalidInput
+	}
+	if !s.quota.Metered() {
+		return nil, ErrProxyUnmetered
+	}
+	cfg, err := s.resolve(ctx, input)
+	if err != nil {
+		return nil, err
+	}
+	prompt := int64(0)
+	for _, m := range input.Messages {
+		prompt += int64(ai.EstimateMessageTokens(m))
+	}
+	completionLimit := cfg.MaxTokens
+	if completionLimit <= 0 {
+		completionLimit = proxyCompletionReserve
+	}
+	estimate := prompt + int64(completionLimit)
+	if err := s.quota.Consume(ctx, input.UserID, QuotaProxyTokens, estimate); err != nil {
+		return nil, err
+	}
+
+	id, err := newCompletionID()
+	if err != nil {
+		_ = s.quota.Adjust(ctx, input.UserID, QuotaProxyTokens, -estimate)
+		return nil, err
+	}
+	completion := &ProxyCompletion{ID: id, Model: cfg.Model, Created: time.Now().Unix()}
+	started := time.Now()
+	meterCtx, meter := ai.WithUsageMeter(ctx)
+	content, err := call(meterCtx, cfg, completion)
+	completion.Content = content
+	if usage, ok := meter.Usage(); ok {
+		completion.Usage = usage
+	} else {
+		completion.Usage = ai.Usage{PromptTokens: int(prompt), CompletionTokens: ai.EstimateTokens(content)}
+		completion.UsageEstimated = true
+	}
+	s.logCall(ctx, input, completion, stream, time.Since(started), err)
+	if err != nil {
+		_ = s.quota.Adjust(ctx, input.UserID, QuotaProxyTokens, -estimate)
+		return nil, err
+	}
+	used := int64(completion.Usage.PromptTokens + completion.Usage.CompletionTokens)
+	if err := s.quota.Adjust(ctx, input.UserID, QuotaProxyTokens, used-estimate); err != nil {
+		log.Printf("settle proxy quota for user %d failed: %v", input.UserID, err)
+	}
+	return completion, nil
+}
+
+// resolve checks the messages and builds the provider config for the request, whose model
+// must also pass the user's model policy. The provider URL, key and headers always come
+// from the server configuration.
+func (s *ChatProxyService) resolve(ctx context.Context, input ProxyChatInput) (ai.ChatConfig, error) {
+	if len(input.Messages) == 0 {
+		return ai.ChatConfig{}, ErrProxyMessages
+	}
+	for _, m := range input.Messages {
+		if !proxyRoles[m.Role] {
+			return ai.ChatConfig{}, ErrProxyMessages
+		}
+	}
+	cfg := s.defaultLLM
+	if name := strings.TrimSpace(input.Model); name != "" {
+		if !s.models[name] {
+			return ai.ChatConfig{}, ErrProxyModelNotAllowed
+		}
+		cfg.Model = name
+	}
+	if err := validateSampling(input.Temperature, input.TopP, input.MaxTokens); err != nil {
+		return ai.ChatConfig{}, err
+	}
+	if err := validateStop(input.Stop); err != nil {
+		return ai.ChatConfig{}, err
+	}
+	cfg.Temperature, cfg.TopP, cfg.MaxTokens, cfg.Stop = input.Temperature, input.TopP, input.MaxTokens, input.Stop
+	if cfg.BaseURL == "" || cfg.APIKey == "" || cfg.Model == "" {
+		return ai.ChatConfig{}, ErrLLMConfig
+	}
+	if s.policy != nil {
+		if err := s.policy.CheckModel(ctx, input.UserID, "", cfg.Model); err != nil {
+			return ai.ChatConfig{}, err
+		}
+	}
+	return cfg, nil
+}
+
+func (s *ChatProxyService) logCall(
+	ctx context.Context,
+	input ProxyChatInput,
+	completion *ProxyCompletion,
+	stream bool,
+	elapsed time.Duration,
+	err error,
+) {
+	var line strings.Builder
+	fmt.Fprintf(&line, "proxy chat: user=%d model=%s stream=%t messages=%d prompt_tokens=%d completion_tokens=%d estimated=%t duration=%s request_id=%s",
+		input.UserID, completion.Model, stream, len(input.Messages),
+		completion.Usage.PromptTokens, completion.Usage.CompletionTokens, completion.UsageEstimated,
+		elapsed.Round(time.Millisecond), requestid.From(ctx))
+	if err != nil {
+		fmt.Fprintf(&line, " err=%v", err)
+	}
+	if s.logPrompts {
+		fmt.Fprintf(&line, " prompt=%q", maskPrompt(lastUserMessage(input.Messages)))
+	}
+	log.Print(line.String())
+}
+
+func lastUserMessage(messages []ai.ChatMessage) string {
+	for i := len(messages) - 1; i >= 0; i-- {
+		if messages[i].Role == "user" {
+			return messages[i].Content
+		}
+	}
+	return ""
+}
+
+// maskPrompt masks keys, email addresses and long numbers in text, then shortens it to a
+// preview. Masking first keeps a value cut by the preview from slipping through.
+func maskPrompt(text string) string {
+	for _, mask := range proxyMasks {
+		text = mask.pattern.ReplaceAllString(text, mask.replace)
+	}
+	if utf8.RuneCountInString(text) > proxyPromptPreviewRunes {
+		text = string([]rune(text)[:proxyPromptPreviewRunes]) + "..."
+	}
+	return text
+}
+
+// newCompletionID returns an ID in the "chatcmpl-..." form OpenAI clients expect.
+func newCompletionID() (string, error) {
+	raw := make([]byte, 12)
+	if _, err := rand.Read(raw); err != nil {
+		return "", fmt.Errorf("generate completion id failed: %w", err)
+	}
+	return "chatcmpl-" + hex.EncodeToString(raw), nil
+}
@@ -0,0 +1,225 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strings"
+	"time"
+
+	"gopherai-resume/internal/model"
+	"gopherai-resume/internal/pkg/apperr"
+	"gopherai-resume/internal/pkg/authz"
+)
+
+var (
+	ErrScheduledMessageNotFound = apperr.NotFound(apperr.CodeScheduledNotFound, "scheduled message not found")
+	ErrScheduleTime             = apperr.BadRequest("run_at must be in the future and at most 30 days ahead")
+	ErrTooManyScheduled         = apperr.Conflict(apperr.CodeScheduleLimit, "too many pending scheduled messages")
+	ErrScheduleNotPending       = apperr.Conflict(apperr.CodeScheduleNotPending, "scheduled message is no longer pending")
+)
+
+// Scheduled message statuses.
+const (
+	ScheduledPending   = "pending"
+	ScheduledRunning   = "running"
+	ScheduledSent      = "sent"
+	ScheduledFailed    = "failed"
+	ScheduledCancelled = "cancelled"
+)
+
+var scheduledStatuses = map[string]bool{
+	ScheduledPending: true, ScheduledRunning: true, ScheduledSent: true, ScheduledFailed: true, ScheduledCancelled: true,
+}
+
+const (
+	maxScheduleAhead    = 30 * 24 * time.Hour
+	maxPendingScheduled = 50
+	scheduleBatchSize   = 20
+	scheduleMaxAttempts = 3
+	scheduleMaxBackoff  = 15 * time.Minute
+	// scheduleStaleAfter is when a claimed message whose run never finished, e.g. because
+	// the server stopped mid-run, is picked up again.
+	scheduleStaleAfter  = 10 * time.Minute
+	maxScheduleErrChars = 512
+)
+
+// ScheduleMessageInput asks for Content to be sent to the session at RunAt.
+type ScheduleMessageInput struct {
+	UserID    uint
+	SessionID uint
+	Content   string
+	Model     string
+	RunAt     time.Time
+}
+
+// ChatScheduleService schedules chat prompts and sends them when due through ChatService,
+// so the reply is stored in the session like any other.
+type ChatScheduleService struct {
+	repo     ScheduledMessageRepository
+	sessions SessionRepository
+	chat     *ChatService
+	now      func() time.Time
+}
+
+func NewChatScheduleService(repo ScheduledMessageRepository, sessions SessionRepository, chat *ChatService) *ChatScheduleService {
+	return &ChatScheduleService{repo: repo, sessions: sessions, chat: chat, now: time.Now}
+}
+
+func (s *ChatScheduleService) Schedule(ctx context.Context, input ScheduleMessageInput) (*model.ScheduledMessage, error) {
+	content := strings.TrimSpace(input.Content)
+	if input.UserID == 0 || input.SessionID == 0 {
+		return nil, ErrInvalidInput
+	}
+	if content == "" {
+		return nil, ErrMessageEmpty
+	}
+	if err := s.chat.checkMessageSize(content); err != nil {
+		return nil, err
+	}
+	now := s.now()
+	if !input.RunAt.After(now) || input.RunAt.Sub(now) > maxScheduleAhead {
+		return nil, ErrScheduleTime
+	}
+	session, err := loadChatSession(ctx, s.sessions, input.SessionID, input.UserID, authz.Write)
+	if err != nil {
+		return nil, err
+	}
+	if session == nil {
+		return nil, ErrSessionNotFound
+	}
+	pending, err := s.repo.CountByUserIDAndStatus(ctx, input.UserID, ScheduledPending)
+	if err != nil {
+		return nil, err
+	}
+	if pending >= maxPendingScheduled {
+		return nil, ErrTooManyScheduled
+	}
+
+	msg := &model.ScheduledMessage{
+		UserID:    input.UserID,
+		SessionID: input.SessionID,
+		Content:   content,
+		Model:     strings.TrimSpace(input.Model),
+		Status:    ScheduledPending,
+		RunAt:     input.RunAt,
+	}
+	if err := s.repo.Create(ctx, msg); err != nil {
+		return nil, err
+	}
+	return msg, nil
+}
+
+// List returns the user's scheduled messages, optionally only those in status.
+func (s *ChatScheduleService) List(ctx context.Context, userID uint, status string) ([]model.ScheduledMessage, error) {
+	if userID == 0 || (status != "" && !scheduledStatuses[status]) {
+		return nil, ErrInvalidInput
+	}
+	list, err := s.repo.ListByUserID(ctx, userID, status)
+	if err != nil {
+		return nil, err
+	}
+	if list == nil {
+		list = []model.ScheduledMessage{}
+	}
+	return list, nil
+}
+
+// Cancel stops a pending message from being sent.
+func (s *ChatScheduleService) Cancel(ctx context.Context, userID, id uint) (*model.ScheduledMessage, error) {
+	if userID == 0 || id == 0 {
+		return nil, ErrInvalidInput
+	}
+	cancelled, err := s.repo.SetStatusIf(ctx, id, userID, ScheduledPending, ScheduledCancelled)
+	if err != nil {
+		return nil, err
+	}
+	msg, err := loadScheduledMessage(ctx, s.repo, id, userID, authz.Write)
+	if err != nil {
+		return nil, err
+	}
+	if msg == nil {
+		return nil, ErrScheduledMessageNotFound
+	}
+	if !cancelled {
+		return nil, ErrScheduleNotPending
+	}
+	return msg, nil
+}
+
+// RunDue sends the scheduled messages that are due and returns how many were sent. Each
+// message is claimed first, so several servers can run the scheduler at once.
+func (s *ChatScheduleService) RunDue(ctx context.Context) (int, error) {
+	now := s.now()
+	due, err := s.repo.ListDue(ctx, ScheduledPending, ScheduledRunning, now, now.Add(-scheduleStaleAfter), scheduleBatchSize)
+	if err != nil {
+		return 0, err
+	}
+	sent := 0
+	for i := range due {
+		if ctx.Err() != nil {
+			return sent, ctx.Err()
+		}
+		msg := &due[i]
+		claimed, err := s.repo.Claim(ctx, msg, ScheduledRunning, s.now())
+		if err != nil {
+			return sent, err
+		}
+		if !claimed {
+			continue
+		}
+		if msg.Attempts > scheduleMaxAttempts {
+			// Only a stale claim gets here: every attempt was interrupted.
+			msg.Status = ScheduledFailed
+			msg.LastError = "run interrupted too many times"
+			if err := s.repo.Update(ctx, msg); err != nil {
+				return sent, err
+			}
+			continue
+		}
+
+		_, sendErr := s.chat.SendMessage(ctx, SendMessageInput{
+			UserID:    msg.UserID,
+			SessionID: msg.SessionID,
+			Content:   msg.Content,
+			LLM:       LLMOverride{Model: msg.Model},
+		})
+		finishedAt := s.now()
+		switch {
+		case sendErr == nil:
+			msg.Status = ScheduledSent
+			msg.SentAt = &finishedAt
+			msg.LastError = ""
+			sent++
+		case !retryableScheduleError(sendErr) || msg.Attempts >= scheduleMaxAttempts:
+			msg.Status = ScheduledFailed
+			msg.LastError = truncateRunes(sendErr.Error(), maxScheduleErrChars)
+		default:
+			msg.Status = ScheduledPending
+			msg.LastError = truncateRunes(sendErr.Error(), maxScheduleErrChars)
+			backoff := time.Minute << (msg.Attempts - 1)
+			if backoff > scheduleMaxBackoff {
+				backoff = scheduleMaxBackoff
+			}
+			msg.RunAt = finishedAt.Add(backoff)
+		}
+		if sendErr != nil {
+			log.Printf("send scheduled message %d failed on attempt %d: %v", msg.ID, msg.Attempts, sendErr)
+		}
+		if err := s.repo.Update(ctx, msg); err != nil {
+			return sent, err
+		}
+	}
+	return sent, nil
+}
+
+// retryableScheduleError reports whether a failed send may succeed later; a deleted
+// session or an invalid model will not.
+func retryableScheduleError(err error) bool {
+	return !errors.Is(err, ErrSessionNotFound) &&
+		!errors.Is(err, ErrInvalidInput) &&
+		!errors.Is(err, ErrMessageEmpty) &&
+		!errors.Is(err, ErrMessageTooLong) &&
+		!errors.Is(err, ErrLLMConfig) &&
+		!errors.Is(err, ErrInvalidSampling)
+}
@@ -0,0 +1,81 @@
+package app
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	"gopherai-resume/internal/ai"
+	"gopherai-resume/internal/model"
+)
+
+const (
+	defaultChatSearchLimit = 10
+	maxChatSearchLimit     = 50
+)
+
+// ChatSearchService finds past chat messages by meaning rather than keywords, using the
+// embeddings written by the message embed worker.
+type ChatSearchService struct {
+	messageEmbRepo MessageEmbeddingRepository
+	embedder       ai.Embedder
+	embConfig      ai.EmbeddingConfig
+}
+
+func NewChatSearchService(
+	messageEmbRepo MessageEmbeddingRepository,
+	embedder ai.Embedder,
+	embConfig ai.EmbeddingConfig,
+) *ChatSearchService {
+	return &ChatSearchService{
+		messageEmbRepo: messageEmbRepo,
+		embedder:       embedder,
+		embConfig:      embConfig,
+	}
+}
+
+// ChatSearchHit is a matching message with its cosine similarity to the query.
+type ChatSearchHit struct {
+	Message model.Message `json:"message"`
+	Score   float32       `json:"score"`
+}
+
+// Search returns the user's messages most similar to query, best match first.
+func (s *ChatSearchService) Search(ctx context.Context, userID uint, query string, limit int) ([]ChatSearchHit, error) {
+	query = strings.TrimSpace(query)
+	if userID == 0 || query == "" {
+		return nil, ErrInvalidInput.Withf("q is required")
+	}
+	if limit <= 0 {
+		limit = defaultChatSearchLimit
+	}
+	if limit > maxChatSearchLimit {
+		limit = maxChatSearchLimit
+	}
+
+	history, err := s.messageEmbRepo.ListByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if len(history) == 0 {
+		return []ChatSearchHit{}, nil
+	}
+
+	queryEmb, err := s.embedder.Embed(ctx, s.embConfig, query)
+	if err != nil {
+		return nil, err
+	}
+
+	hits := make([]ChatSearchHit, len(history))
+	for i := range history {
+		hits[i] = ChatSearchHit{
+			Message: history[i].Message,
+			Score:   cosineSimilarity(queryEmb, history[i].EmbeddingVector()),
+		}
+	}
+	sort.SliceStable(hits, func(i, j int) bool { return hits[i].Score > hits[j].Score })
+	if limit > len(hits) {
+		limit = len(hits)
+	}
+	return hits[:limit], nil
+}
@@ -2,30 +2,64 @@ package app
 
 import (
 	"context"
-	"errors"
+	"fmt"
+	"log"
+	"net/http"
 	"strings"
 	"time"
+	"unicode/utf8"
 
 	"gopherai-resume/internal/ai"
 	"gopherai-resume/internal/model"
-	"gopherai-resume/internal/repository"
+	"gopherai-resume/internal/pkg/apperr"
+	"gopherai-resume/internal/pkg/authz"
 )
 
 var (
-	ErrSessionNotFound = errors.New("session not found")
-	ErrMessageEmpty    = errors.New("message content is empty")
-	ErrLLMConfig       = errors.New("llm config is invalid")
-	ErrMessageEnqueue  = errors.New("message enqueue failed")
+	ErrSessionNotFound = apperr.NotFound(apperr.CodeSessionNotFound, "session not found")
+	ErrMessageEmpty    = apperr.BadRequest("message content is empty")
+	ErrLLMConfig       = apperr.New(http.StatusServiceUnavailable, apperr.CodeFeatureUnavailable, "llm config is invalid")
+	ErrMessageEnqueue  = apperr.New(http.StatusServiceUnavailable, apperr.CodeInternalServer, "message enqueue failed")
+	ErrInvalidSampling = apperr.BadRequest("temperature must be in [0, 2], top_p in (0, 1], max_tokens positive and stop at most 4 non-empty sequences of up to 64 characters")
+	ErrOverrideAPIKey  = apperr.BadRequest("a base_url override requires an api_key")
+)
+
+// History page sizes; the history cache holds one page of the largest size.
+const (
+	defaultHistoryPage = 50
+	maxHistoryPage     = 200
 )
 
 type ChatService struct {
-	sessionRepo  *repository.SessionRepository
-	messageRepo  *repository.MessageRepository
+	sessionRepo  SessionRepository
+	messageRepo  MessageRepository
 	publisher    AsyncMessagePublisher
 	historyCache HistoryCache
-	llmClient    *ai.OpenAICompatibleClient
+	completer    ai.Completer
+	toolCaller   ai.ToolCaller
+	tools        []ChatTool
+	retriever    ChatRetriever // nil disables attaching RAG sessions
 	defaultLLM   ai.ChatConfig
 	maxContext   int
+	// maxContextTokens is the estimated token budget for the prompt built from history.
+	maxContextTokens int
+	streams          *streamRegistry
+	// maxMessageRunes caps user message size; 0 is unlimited.
+	maxMessageRunes int
+	overflow        MessageOverflow // nil rejects oversized messages
+	locks           SessionLocker   // nil lets sends to one session run concurrently
+	lockWait        time.Duration
+	activity        ActivityRecorder
+	modelPolicy     ModelPolicy // nil allows every model and provider
+	// embedQueue queues messages the service changes itself, such as edits, for
+	// embedding; nil when message embedding is off.
+	embedQueue AsyncMessagePublisher
+}
+
+// ChatRetriever supplies document excerpts to chat sessions attached to a RAG session.
+type ChatRetriever interface {
+	SessionExists(ctx context.Context, userID, ragSessionID uint) (bool, error)
+	RetrieveFromSession(ctx context.Context, userID, ragSessionID uint, query string, k int) ([]model.RAGChunk, error)
 }
 
 type AsyncMessagePublisher interface {
@@ -41,8 +75,9 @@ type HistoryCache interface {
 }
 
 type CreateSessionInput struct {
-	UserID uint
-	Title  string
+	UserID       uint
+	Title        string
+	RAGSessionID uint // 0 = none
 }
 
 type SendMessageInput struct {
@@ -50,49 +85,88 @@ type SendMessageInput struct {
 	SessionID uint
 	Content   string
 	LLM       LLMOverride
+	// Images are attachments the assistant can inspect through the registered tools.
+	Images []ChatImage
 }
 
 type LLMRequestLog struct {
 	BaseURL      string           `json:"base_url"`
 	Model        string           `json:"model"`
 	APIKeyMasked string           `json:"api_key_masked"`
+	Temperature  *float64         `json:"temperature,omitempty"`
+	TopP         *float64         `json:"top_p,omitempty"`
+	MaxTokens    int              `json:"max_tokens,omitempty"`
+	Stop         []string         `json:"stop,omitempty"`
 	Messages     []ai.ChatMessage `json:"messages"`
 }
 
 type SendMessageResult struct {
-	Messages   []model.Message `json:"messages"`
-	LLMRequest LLMRequestLog   `json:"llm_request"`
+	Messages   []model.Message  `json:"messages"`
+	LLMRequest LLMRequestLog    `json:"llm_request"`
+	ToolCalls  []ToolInvocation `json:"tool_calls,omitempty"`
 }
 
 type LLMOverride struct {
-	BaseURL string
-	APIKey  string
-	Model   string
+	BaseURL     string
+	APIKey      string
+	Model       string
+	Temperature *float64
+	TopP        *float64
+	MaxTokens   int
+	// Stop applies to this request only; sessions do not store stop sequences.
+	Stop []string
 }
 
 func NewChatService(
-	sessionRepo *repository.SessionRepository,
-	messageRepo *repository.MessageRepository,
+	sessionRepo SessionRepository,
+	messageRepo MessageRepository,
 	publisher AsyncMessagePublisher,
+	embedQueue AsyncMessagePublisher,
 	historyCache HistoryCache,
+	completer ai.Completer,
+	toolCaller ai.ToolCaller,
+	tools []ChatTool,
+	retriever ChatRetriever,
 	defaultLLM ai.ChatConfig,
 	maxContext int,
+	maxContextTokens int,
+	maxMessageRunes int,
+	overflow MessageOverflow,
+	locks SessionLocker,
+	lockWait time.Duration,
+	activity ActivityRecorder,
+	modelPolicy ModelPolicy,
 ) *ChatService {
 	if maxContext <= 0 {
 		maxContext = 20
 	}
+	if maxContextTokens <= 0 {
+		maxContextTokens = 6000
+	}
 	return &ChatService{
-		sessionRepo:  sessionRepo,
-		messageRepo:  messageRepo,
-		publisher:    publisher,
-		historyCache: historyCache,
-		llmClient:    ai.NewOpenAICompatibleClient(),
-		defaultLLM:   defaultLLM,
-		maxContext:   maxContext,
+		sessionRepo:      sessionRepo,
+		messageRepo:      messageRepo,
+		publisher:        publisher,
+		embedQueue:       embedQueue,
+		historyCache:     historyCache,
+		completer:        completer,
+		toolCaller:       toolCaller,
+		tools:            tools,
+		retriever:        retriever,
+		defaultLLM:       defaultLLM,
+		maxContext:       maxContext,
+		maxContextTokens: maxContextTokens,
+		streams:          newStreamRegistry(),
+		maxMessageRunes:  maxMessageRunes,
+		overflow:         overflow,
+		locks:            locks,
+		lockWait:         lockWait,
+		activity:         activity,
+		modelPolicy:      modelPolicy,
 	}
 }
 
-func (s *ChatService) CreateSession(input CreateSessionInput) (*model.Session, error) {
+func (s *ChatService) CreateSession(ctx context.Context, input CreateSessionInput) (*model.Session, error) {
 	if input.UserID == 0 {
 		return nil, ErrInvalidInput
 	}
@@ -106,43 +180,59 @@ func (s *ChatService) CreateSession(input CreateSessionInput) (*model.Session, e
 		UserID: input.UserID,
 		Title:  title,
 	}
-	if err := s.sessionRepo.Create(session); err != nil {
+	if input.RAGSessionID != 0 {
+		if err := s.checkRAGSession(ctx, input.UserID, input.RAGSessionID); err != nil {
+			return nil, err
+		}
+		session.RAGSessionID = &input.RAGSessionID
+	}
+	if err := s.sessionRepo.Create(ctx, session); err != nil {
 		return nil, err
 	}
+	recordActivity(ctx, s.activity, input.UserID, ActivityChatSessionCreated, session.ID,
+		fmt.Sprintf("Created chat session %q", title))
 	return session, nil
 }
 
-func (s *ChatService) ListSessions(userID uint) ([]model.Session, error) {
+func (s *ChatService) ListSessions(ctx context.Context, userID uint) ([]model.Session, error) {
 	if userID == 0 {
 		return nil, ErrInvalidInput
 	}
-	return s.sessionRepo.ListByUserID(userID)
+	return s.sessionRepo.ListByUserID(ctx, userID)
 }
 
-func (s *ChatService) DeleteSession(userID, sessionID uint) error {
+func (s *ChatService) DeleteSession(ctx context.Context, userID, sessionID uint) error {
 	if userID == 0 || sessionID == 0 {
 		return ErrInvalidInput
 	}
-	session, err := s.sessionRepo.GetByIDAndUserID(sessionID, userID)
+	session, err := loadChatSession(ctx, s.sessionRepo, sessionID, userID, authz.Write)
 	if err != nil {
 		return err
 	}
 	if session == nil {
 		return ErrSessionNotFound
 	}
-	if err := s.messageRepo.DeleteBySessionID(sessionID); err != nil {
+	// Forks read the history before their fork point from this session.
+	forks, err := s.sessionRepo.CountForks(ctx, sessionID)
+	if err != nil {
+		return err
+	}
+	if forks > 0 {
+		return ErrSessionHasForks
+	}
+	if err := s.messageRepo.DeleteBySessionID(ctx, sessionID); err != nil {
 		return err
 	}
-	if err := s.sessionRepo.DeleteByIDAndUserID(sessionID, userID); err != nil {
+	if err := s.sessionRepo.DeleteByIDAndUserID(ctx, sessionID, userID); err != nil {
 		return err
 	}
 	if s.historyCache != nil {
-		_ = s.historyCache.DeleteHistory(context.Background(), sessionID)
+		_ = s.historyCache.DeleteHistory(ctx, sessionID)
 	}
 	return nil
 }
 
-func (s *ChatService) SendMessage(input SendMessageInput) (*SendMessageResult, error) {
+func (s *ChatService) SendMessage(ctx context.Context, input SendMessageInput) (*SendMessageResult, error) {
 	if input.UserID == 0 || input.SessionID == 0 {
 		return nil, ErrInvalidInput
 	}
@@ -152,7 +242,7 @@ func (s *ChatService) SendMessage(input SendMessageInput) (*SendMessageResult, e
 		return nil, ErrMessageEmpty
 	}
 
-	session, err := s.sessionRepo.GetByIDAndUserID(input.SessionID, input.UserID)
+	session, err := loadChatSession(ctx, s.sessionRepo, input.SessionID, input.UserID, authz.Write)
 	if err != nil {
 		return nil, err
 	}
@@ -160,11 +250,19 @@ func (s *ChatService) SendMessage(input SendMessageInput) (*SendMessageResult, e
 		return nil, ErrSessionNotFound
 	}
 
-	cfg, err := s.resolveLLM(input.LLM)
+	cfg, err := s.resolveLLM(ctx, input.UserID, session, input.LLM)
 	if err != nil {
 		return nil, err
 	}
-	promptMessages, err := s.buildPromptMessages(input.SessionID, content)
+	unlock, err := s.lockSession(ctx, input.SessionID)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+	if content, err = s.fitMessage(ctx, session, content); err != nil {
+		return nil, err
+	}
+	promptMessages, err := s.buildPromptMessages(ctx, session, content)
 	if err != nil {
 		return nil, err
 	}
@@ -180,13 +278,24 @@ func (s *ChatService) SendMessage(input SendMessageInput) (*SendMessageResult, e
 		return nil, ErrMessageEnqueue
 	}
 	if s.historyCache != nil {
-		_ = s.historyCache.MarkDirty(context.Background(), input.SessionID)
-		_ = s.historyCache.DeleteHistory(context.Background(), input.SessionID)
+		_ = s.historyCache.MarkDirty(ctx, input.SessionID)
+		_ = s.historyCache.DeleteHistory(ctx, input.SessionID)
 	}
-	if err := s.publisher.Publish(context.Background(), *userMessage); err != nil {
+	if err := s.publisher.Publish(ctx, *userMessage); err != nil {
 		return nil, ErrMessageEnqueue
 	}
-	assistantContent, err := s.llmClient.Complete(context.Background(), cfg, promptMessages)
+	var assistantContent string
+	var invocations []ToolInvocation
+	ctx, meter := ai.WithUsageMeter(ctx)
+	if len(input.Images) > 0 && s.toolCaller != nil && len(s.tools) > 0 {
+		promptMessages[len(promptMessages)-1].Content += fmt.Sprintf(
+			"\n\n[%d image(s) attached; use the available tools with image_index 0-%d to inspect them]",
+			len(input.Images), len(input.Images)-1,
+		)
+		assistantContent, promptMessages, invocations, err = s.runToolLoop(ctx, cfg, input.UserID, promptMessages, input.Images)
+	} else {
+		assistantContent, err = s.completer.Complete(ctx, cfg, promptMessages)
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -202,7 +311,9 @@ func (s *ChatService) SendMessage(input SendMessageInput) (*SendMessageResult, e
 		Content:   assistantContent,
 		CreatedAt: time.Now(),
 	}
-	if err := s.publisher.Publish(context.Background(), *assistantMessage); err != nil {
+	applyUsage(assistantMessage, cfg, meter, promptMessages)
+	// The reply has been paid for, so store it even if the client has gone away.
+	if err := s.publisher.Publish(context.WithoutCancel(ctx), *assistantMessage); err != nil {
 		return nil, ErrMessageEnqueue
 	}
 
@@ -212,17 +323,35 @@ func (s *ChatService) SendMessage(input SendMessageInput) (*SendMessageResult, e
 			BaseURL:      cfg.BaseURL,
 			Model:        cfg.Model,
 			APIKeyMasked: maskSecret(cfg.APIKey),
+			Temperature:  cfg.Temperature,
+			TopP:         cfg.TopP,
+			MaxTokens:    cfg.MaxTokens,
+			Stop:         cfg.Stop,
 			Messages:     promptMessages,
 		},
+		ToolCalls: invocations,
 	}, nil
 }
 
-func (s *ChatService) GetHistory(userID, sessionID uint, limit int) ([]model.Message, error) {
+// HistoryPage is one page of a session's history, oldest first. Pass NextBeforeID as
+// before_id to fetch the previous page; it is 0 when HasMore is false.
+type HistoryPage struct {
+	Messages     []model.Message `json:"messages"`
+	HasMore      bool            `json:"has_more"`
+	NextBeforeID uint            `json:"next_before_id"`
+}
+
+// GetHistoryPage pages backwards through a session's history, in message ID order, from
+// the message beforeID (exclusive), or from the newest message when beforeID is 0. The
+// newest page is served from the history cache when it holds enough messages.
+func (s *ChatService) GetHistoryPage(ctx context.Context, userID, sessionID, beforeID uint, limit int) (*HistoryPage, error) {
 	if userID == 0 || sessionID == 0 {
 		return nil, ErrInvalidInput
 	}
-
-	session, err := s.sessionRepo.GetByIDAndUserID(sessionID, userID)
+	if limit <= 0 || limit > maxHistoryPage {
+		limit = defaultHistoryPage
+	}
+	session, err := loadChatSession(ctx, s.sessionRepo, sessionID, userID, authz.Read)
 	if err != nil {
 		return nil, err
 	}
@@ -230,31 +359,56 @@ func (s *ChatService) GetHistory(userID, sessionID uint, limit int) ([]model.Mes
 		return nil, ErrSessionNotFound
 	}
 
-	ctx := context.Background()
-	if s.historyCache != nil {
-		dirty, err := s.historyCache.IsDirty(ctx, sessionID)
-		if err == nil && !dirty {
-			if cached, hit, cacheErr := s.historyCache.GetHistory(ctx, sessionID); cacheErr == nil && hit {
-				return trimMessages(cached, limit), nil
-			}
+	if beforeID == 0 && s.historyCache != nil {
+		if page, ok := s.cachedHistoryPage(ctx, sessionID, limit); ok {
+			return page, nil
 		}
 	}
-
-	messages, err := s.messageRepo.ListBySessionID(sessionID, limit)
+	messages, hasMore, err := s.messageRepo.ListPageBySessionID(ctx, sessionID, beforeID, limit)
 	if err != nil {
 		return nil, err
 	}
-	if s.historyCache != nil {
+	if beforeID == 0 && s.historyCache != nil && limit == maxHistoryPage {
 		if dirty, dirtyErr := s.historyCache.IsDirty(ctx, sessionID); dirtyErr == nil && !dirty {
 			_ = s.historyCache.SetHistory(ctx, sessionID, messages)
 		}
 	}
-	return messages, nil
+	return newHistoryPage(messages, hasMore), nil
 }
 
+// cachedHistoryPage answers the newest page from the history cache, which holds up to
+// maxHistoryPage of the newest messages. A cache with fewer holds the whole history.
+func (s *ChatService) cachedHistoryPage(ctx context.Context, sessionID uint, limit int) (*HistoryPage, bool) {
+	if dirty, err := s.historyCache.IsDirty(ctx, sessionID); err != nil || dirty {
+		return nil, false
+	}
+	cached, hit, err := s.historyCache.GetHistory(ctx, sessionID)
+	if err != nil || !hit {
+		return nil, false
+	}
+	if len(cached) >= maxHistoryPage && limit >= len(cached) {
+		// Older messages may exist beyond what the cache holds.
+		return nil, false
+	}
+	return newHistoryPage(trimMessages(cached, limit), limit < len(cached)), true
+}
+
+func newHistoryPage(messages []model.Message, hasMore bool) *HistoryPage {
+	page := &HistoryPage{Messages: messages, HasMore: hasMore}
+	if hasMore && len(messages) > 0 {
+		page.NextBeforeID = messages[0].ID
+	}
+	return page
+}
+
+// StreamMessage streams the assistant reply through onChunk. onStart, if set, receives the
+// stream ID accepted by CancelStream before the first chunk. A cancelled stream keeps the
+// partial reply in history, flagged truncated, and returns ErrStreamCancelled; so does a
+// stream whose onChunk fails because the client went away, which returns that error.
 func (s *ChatService) StreamMessage(
 	ctx context.Context,
 	input SendMessageInput,
+	onStart func(streamID string) error,
 	onChunk func(string) error,
 ) (string, error) {
 	if input.UserID == 0 || input.SessionID == 0 {
@@ -265,7 +419,7 @@ func (s *ChatService) StreamMessage(
 		return "", ErrMessageEmpty
 	}
 
-	session, err := s.sessionRepo.GetByIDAndUserID(input.SessionID, input.UserID)
+	session, err := loadChatSession(ctx, s.sessionRepo, input.SessionID, input.UserID, authz.Write)
 	if err != nil {
 		return "", err
 	}
@@ -273,36 +427,85 @@ func (s *ChatService) StreamMessage(
 		return "", ErrSessionNotFound
 	}
 
-	cfg, err := s.resolveLLM(input.LLM)
+	cfg, err := s.resolveLLM(ctx, input.UserID, session, input.LLM)
 	if err != nil {
 		return "", err
 	}
-	promptMessages, err := s.buildPromptMessages(input.SessionID, content)
+	unlock, err := s.lockSession(ctx, input.SessionID)
 	if err != nil {
 		return "", err
 	}
+	defer unlock()
+	if content, err = s.fitMessage(ctx, session, content); err != nil {
+		return "", err
+	}
+	promptMessages, err := s.buildPromptMessages(ctx, session, content)
+	if err != nil {
+		return "", err
+	}
+	return s.streamReply(ctx, input.UserID, input.SessionID, content, cfg, promptMessages, onStart, onChunk)
+}
 
+// streamReply records userContent as the user's turn, streams the completion of
+// promptMessages, and records the reply. See StreamMessage for onStart and cancellation.
+func (s *ChatService) streamReply(
+	ctx context.Context,
+	userID, sessionID uint,
+	userContent string,
+	cfg ai.ChatConfig,
+	promptMessages []ai.ChatMessage,
+	onStart func(streamID string) error,
+	onChunk func(string) error,
+) (string, error) {
 	userMessage := &model.Message{
-		SessionID: input.SessionID,
-		UserID:    input.UserID,
+		SessionID: sessionID,
+		UserID:    userID,
 		Role:      "user",
-		Content:   content,
+		Content:   userContent,
 		CreatedAt: time.Now(),
 	}
 	if s.publisher == nil {
 		return "", ErrMessageEnqueue
 	}
 	if s.historyCache != nil {
-		_ = s.historyCache.MarkDirty(ctx, input.SessionID)
-		_ = s.historyCache.DeleteHistory(ctx, input.SessionID)
+		_ = s.historyCache.MarkDirty(ctx, sessionID)
+		_ = s.historyCache.DeleteHistory(ctx, sessionID)
 	}
 	if err := s.publisher.Publish(ctx, *userMessage); err != nil {
 		return "", ErrMessageEnqueue
 	}
 
-	full, err := s.llmClient.StreamComplete(ctx, cfg, promptMessages, onChunk)
+	streamID, streamCtx, done := s.streams.register(ctx, userID)
+	defer done()
+	if onStart != nil {
+		if err := onStart(streamID); err != nil {
+			return "", err
+		}
+	}
+
+	var partial strings.Builder
+	var sinkErr error
+	meterCtx, meter := ai.WithUsageMeter(streamCtx)
+	full, err := s.completer.StreamComplete(meterCtx, cfg, promptMessages, func(chunk string) error {
+		partial.WriteString(chunk)
+		if err := onChunk(chunk); err != nil {
+			sinkErr = err
+			return err
+		}
+		return nil
+	})
 	if err != nil {
-		return "", err
+		if streamCtx.Err() == nil && sinkErr == nil {
+			return "", err
+		}
+		// Cancelled by the user or cut off by the client going away: keep what was generated
+		// so the history does not end on an unanswered user message.
+		full = strings.TrimSpace(partial.String())
+		s.publishPartial(ctx, userID, sessionID, full, cfg, meter, promptMessages)
+		if streamCtx.Err() == nil {
+			return full, sinkErr
+		}
+		return full, ErrStreamCancelled
 	}
 	full = strings.TrimSpace(full)
 	if full == "" {
@@ -310,12 +513,13 @@ func (s *ChatService) StreamMessage(
 	}
 
 	assistantMessage := &model.Message{
-		SessionID: input.SessionID,
-		UserID:    input.UserID,
+		SessionID: sessionID,
+		UserID:    userID,
 		Role:      "assistant",
 		Content:   full,
 		CreatedAt: time.Now(),
 	}
+	applyUsage(assistantMessage, cfg, meter, promptMessages)
 	if err := s.publisher.Publish(ctx, *assistantMessage); err != nil {
 		return "", ErrMessageEnqueue
 	}
@@ -323,6 +527,51 @@ func (s *ChatService) StreamMessage(
 	return full, nil
 }
 
+// publishPartial records the part of a reply generated before its stream stopped, flagged
+// truncated. It uses a context detached from ctx, which is usually the cancelled one.
+func (s *ChatService) publishPartial(
+	ctx context.Context,
+	userID, sessionID uint,
+	content string,
+	cfg ai.ChatConfig,
+	meter *ai.UsageMeter,
+	promptMessages []ai.ChatMessage,
+) {
+	if content == "" {
+		return
+	}
+	message := model.Message{
+		SessionID: sessionID,
+		UserID:    userID,
+		Role:      "assistant",
+		Content:   content,
+		Truncated: true,
+		CreatedAt: time.Now(),
+	}
+	applyUsage(&message, cfg, meter, promptMessages)
+	if err := s.publisher.Publish(context.WithoutCancel(ctx), message); err != nil {
+		log.Printf("persist partial reply for session %d failed: %v", sessionID, err)
+	}
+}
+
+// appendMessages records messages produced outside SendMessage/StreamMessage in their
+// session's history.
+func (s *ChatService) appendMessages(ctx context.Context, messages ...model.Message) error {
+	if s.publisher == nil {
+		return ErrMessageEnqueue
+	}
+	for _, m := range messages {
+		if s.historyCache != nil {
+			_ = s.historyCache.MarkDirty(ctx, m.SessionID)
+			_ = s.historyCache.DeleteHistory(ctx, m.SessionID)
+		}
+		if err := s.publisher.Publish(ctx, m); err != nil {
+			return ErrMessageEnqueue
+		}
+	}
+	return nil
+}
+
 func trimMessages(messages []model.Message, limit int) []model.Message {
 	if limit <= 0 || limit >= len(messages) {
 		return messages
@@ -330,10 +579,51 @@ func trimMessages(messages []model.Message, limit int) []model.Message {
 	return messages[len(messages)-limit:]
 }
 
-func (s *ChatService) resolveLLM(override LLMOverride) (ai.ChatConfig, error) {
+// resolveLLM layers the server defaults, then session's settings (session may be nil), then
+// the per-request override, and checks the result against the user's model policy.
+func (s *ChatService) resolveLLM(ctx context.Context, userID uint, session *model.Session, override LLMOverride) (ai.ChatConfig, error) {
 	cfg := s.defaultLLM
-	if strings.TrimSpace(override.BaseURL) != "" {
-		cfg.BaseURL = strings.TrimSpace(override.BaseURL)
+	if session != nil {
+		if session.Model != "" {
+			cfg.Model = session.Model
+		}
+		if session.Temperature != nil {
+			cfg.Temperature = session.Temperature
+		}
+		if session.TopP != nil {
+			cfg.TopP = session.TopP
+		}
+		if session.MaxTokens != nil {
+			cfg.MaxTokens = *session.MaxTokens
+		}
+	}
+	if override.Temperature != nil {
+		cfg.Temperature = override.Temperature
+	}
+	if override.TopP != nil {
+		cfg.TopP = override.TopP
+	}
+	// A negative value is kept so validateSampling rejects it instead of ignoring it.
+	if override.MaxTokens != 0 {
+		cfg.MaxTokens = override.MaxTokens
+	}
+	if len(override.Stop) > 0 {
+		cfg.Stop = override.Stop
+	}
+	if err := validateSampling(cfg.Temperature, cfg.TopP, cfg.MaxTokens); err != nil {
+		return ai.ChatConfig{}, err
+	}
+	if err := validateStop(cfg.Stop); err != nil {
+		return ai.ChatConfig{}, err
+	}
+	if baseURL := strings.TrimSpace(override.BaseURL); baseURL != "" {
+		// The server key belongs to the configured provider and is never sent elsewhere,
+		// nor are its default headers.
+		if strings.TrimSpace(override.APIKey) == "" {
+			return ai.ChatConfig{}, ErrOverrideAPIKey
+		}
+		cfg.Headers = cfg.Headers.ProfilesOnly()
+		cfg.BaseURL = baseURL
 	}
 	if strings.TrimSpace(override.APIKey) != "" {
 		cfg.APIKey = strings.TrimSpace(override.APIKey)
@@ -344,6 +634,11 @@ func (s *ChatService) resolveLLM(override LLMOverride) (ai.ChatConfig, error) {
 	if cfg.BaseURL == "" || cfg.APIKey == "" || cfg.Model == "" {
 		return ai.ChatConfig{}, ErrLLMConfig
 	}
+	if s.modelPolicy != nil {
+		if err := s.modelPolicy.CheckModel(ctx, userID, strings.TrimSpace(override.BaseURL), cfg.Model); err != nil {
+			return ai.ChatConfig{}, err
+		}
+	}
 	return cfg, nil
 }
 
@@ -354,32 +649,153 @@ func maskSecret(secret string) string {
 	return secret[:4] + strings.Repeat("*", len(secret)-8) + secret[len(secret)-4:]
 }
 
-func (s *ChatService) buildPromptMessages(sessionID uint, currentUserInput string) ([]ai.ChatMessage, error) {
-	recent, err := s.messageRepo.ListRecentBySessionID(sessionID, s.maxContext)
+// UpdateSessionInput changes the non-nil fields of a session. ResetLLM clears the session's
+// LLM settings before the given ones are applied.
+type UpdateSessionInput struct {
+	UserID      uint
+	SessionID   uint
+	Title       *string
+	Pinned      *bool
+	Model       *string
+	Temperature *float64
+	TopP        *float64
+	MaxTokens   *int
+	ResetLLM    bool
+	// RAGSessionID attaches a RAG session; 0 detaches the current one.
+	RAGSessionID *uint
+}
+
+func (s *ChatService) UpdateSession(ctx context.Context, input UpdateSessionInput) (*model.Session, error) {
+	if input.UserID == 0 || input.SessionID == 0 {
+		return nil, ErrInvalidInput
+	}
+	session, err := loadChatSession(ctx, s.sessionRepo, input.SessionID, input.UserID, authz.Write)
 	if err != nil {
 		return nil, err
 	}
+	if session == nil {
+		return nil, ErrSessionNotFound
+	}
 
-	messages := make([]ai.ChatMessage, 0, len(recent)+1)
-	messages = append(messages, ai.ChatMessage{
-		Role:    "system",
-		Content: "You are a concise and helpful AI assistant.",
-	})
-	for _, item := range recent {
-		role := item.Role
-		if role == "" {
-			role = "user"
+	if input.Title != nil {
+		title := strings.TrimSpace(*input.Title)
+		if title == "" {
+			return nil, ErrInvalidInput
+		}
+		session.Title = title
+	}
+	if input.Pinned != nil && *input.Pinned != session.Pinned {
+		// A session changing group starts unordered within it.
+		session.Pinned = *input.Pinned
+		session.SortOrder = 0
+	}
+	if input.ResetLLM {
+		session.Model = ""
+		session.Temperature = nil
+		session.TopP = nil
+		session.MaxTokens = nil
+	}
+	if input.Model != nil {
+		session.Model = strings.TrimSpace(*input.Model)
+	}
+	if input.Temperature != nil {
+		session.Temperature = input.Temperature
+	}
+	if input.TopP != nil {
+		session.TopP = input.TopP
+	}
+	if input.MaxTokens != nil {
+		session.MaxTokens = input.MaxTokens
+	}
+	if input.RAGSessionID != nil {
+		session.RAGSessionID = nil
+		if id := *input.RAGSessionID; id != 0 {
+			if err := s.checkRAGSession(ctx, input.UserID, id); err != nil {
+				return nil, err
+			}
+			session.RAGSessionID = &id
+		}
+	}
+	maxTokens := 0
+	if session.MaxTokens != nil {
+		if *session.MaxTokens <= 0 {
+			return nil, ErrInvalidSampling
+		}
+		maxTokens = *session.MaxTokens
+	}
+	if err := validateSampling(session.Temperature, session.TopP, maxTokens); err != nil {
+		return nil, err
+	}
+
+	if err := s.sessionRepo.Update(ctx, session); err != nil {
+		return nil, err
+	}
+	return session, nil
+}
+
+func (s *ChatService) checkRAGSession(ctx context.Context, userID, ragSessionID uint) error {
+	if s.retriever == nil {
+		return ErrInvalidInput
+	}
+	ok, err := s.retriever.SessionExists(ctx, userID, ragSessionID)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrRAGSessionNotFound
+	}
+	return nil
+}
+
+// ReorderSessions gives the listed sessions positions 1..n in that order, so they come
+// before unordered sessions within their pinned or unpinned group. Sessions ordered earlier
+// but not listed keep their relative order after them. It returns the new listing.
+func (s *ChatService) ReorderSessions(ctx context.Context, userID uint, sessionIDs []uint) ([]model.Session, error) {
+	ids := uniqueIDs(sessionIDs)
+	if userID == 0 || len(ids) == 0 || len(ids) != len(sessionIDs) {
+		return nil, ErrInvalidInput
+	}
+	owned, err := s.sessionRepo.CountByIDsAndUserID(ctx, ids, userID)
+	if err != nil {
+		return nil, err
+	}
+	if owned != int64(len(ids)) {
+		return nil, ErrSessionNotFound
+	}
+	if err := s.sessionRepo.UpdateSortOrder(ctx, userID, ids); err != nil {
+		return nil, err
+	}
+	return s.sessionRepo.ListByUserID(ctx, userID)
+}
+
+// validateSampling checks the ranges accepted by OpenAI-compatible providers.
+func validateSampling(temperature, topP *float64, maxTokens int) error {
+	if temperature != nil && (*temperature < 0 || *temperature > 2) {
+		return ErrInvalidSampling
+	}
+	if topP != nil && (*topP <= 0 || *topP > 1) {
+		return ErrInvalidSampling
+	}
+	if maxTokens < 0 {
+		return ErrInvalidSampling
+	}
+	return nil
+}
+
+// Stop sequence limits, as OpenAI-compatible APIs accept at most 4.
+const (
+	maxStopSequences     = 4
+	maxStopSequenceRunes = 64
+)
+
+func validateStop(stop []string) error {
+	if len(stop) > maxStopSequences {
+		return ErrInvalidSampling
+	}
+	for _, seq := range stop {
+		if seq == "" || utf8.RuneCountInString(seq) > maxStopSequenceRunes {
+			return ErrInvalidSampling
 		}
-		messages = append(messages, ai.ChatMessage{
-			Role:    role,
-			Content: item.Content,
-		})
-	}
-	if strings.TrimSpace(currentUserInput) != "" {
-		messages = append(messages, ai.ChatMessage{
-			Role:    "user",
-			Content: strings.TrimSpace(currentUserInput),
-		})
-	}
-	return messages, nil
+	}
+	return nil
 }
@@ -0,0 +1,75 @@
+package app
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"sync"
+
+	"gopherai-resume/internal/pkg/apperr"
+)
+
+var (
+	ErrStreamNotFound  = apperr.NotFound(apperr.CodeStreamNotFound, "stream not found")
+	ErrStreamCancelled = errors.New("stream cancelled")
+)
+
+// streamRegistry tracks in-flight streamed completions so their owner can cancel them.
+// It is process-local: a cancel request must reach the instance serving the stream.
+type streamRegistry struct {
+	mu      sync.Mutex
+	streams map[string]activeStream
+}
+
+type activeStream struct {
+	userID uint
+	cancel context.CancelFunc
+}
+
+func newStreamRegistry() *streamRegistry {
+	return &streamRegistry{streams: make(map[string]activeStream)}
+}
+
+// register derives a cancellable context from ctx and returns it with the new stream ID.
+// done must be called when the stream finishes.
+func (r *streamRegistry) register(ctx context.Context, userID uint) (string, context.Context, func()) {
+	streamCtx, cancel := context.WithCancel(ctx)
+	id := newStreamID()
+
+	r.mu.Lock()
+	r.streams[id] = activeStream{userID: userID, cancel: cancel}
+	r.mu.Unlock()
+
+	return id, streamCtx, func() {
+		r.mu.Lock()
+		delete(r.streams, id)
+		r.mu.Unlock()
+		cancel()
+	}
+}
+
+func (r *streamRegistry) cancel(userID uint, id string) error {
+	r.mu.Lock()
+	stream, ok := r.streams[id]
+	r.mu.Unlock()
+	if !ok || stream.userID != userID {
+		return ErrStreamNotFound
+	}
+	stream.cancel()
+	return nil
+}
+
+func newStreamID() string {
+	buf := make([]byte, 16)
+	_, _ = rand.Read(buf)
+	return hex.EncodeToString(buf)
+}
+
+// CancelStream aborts an in-flight StreamMessage started by userID.
+func (s *ChatService) CancelStream(userID uint, streamID string) error {
+	if userID == 0 || streamID == "" {
+		return ErrInvalidInput
+	}
+	return s.streams.cancel(userID, streamID)
+}
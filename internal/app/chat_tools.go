@@ -0,0 +1,200 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"gopherai-resume/internal/ai"
+	"gopherai-resume/internal/vision"
+)
+
+const maxToolRounds = 4
+
+var errToolImageIndex = errors.New("image_index out of range")
+
+// ChatImage is an image attached to a chat message and made available to tools.
+type ChatImage struct {
+	Data     []byte
+	MIMEType string
+}
+
+// ChatTool is a function the assistant may call while answering a message for userID.
+type ChatTool interface {
+	Definition() ai.ToolDefinition
+	Call(ctx context.Context, userID uint, args json.RawMessage, images []ChatImage) (string, error)
+}
+
+// ToolInvocation records one tool call made while answering, for the API response.
+type ToolInvocation struct {
+	Name      string `json:"name"`
+	Arguments string `json:"arguments"`
+	Result    string `json:"result,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+var imageIndexSchema = map[string]interface{}{
+	"type":        "integer",
+	"description": "Zero-based index of the image attached to the user's message.",
+	"minimum":     0,
+}
+
+func pickImage(images []ChatImage, index int) (ChatImage, error) {
+	if index < 0 || index >= len(images) {
+		return ChatImage{}, errToolImageIndex
+	}
+	return images[index], nil
+}
+
+// ClassifyImageTool exposes the local ONNX classifier as the "classify_image" tool. Calls go
+// through the vision service, so they share the classify route's cache and quotas.
+type ClassifyImageTool struct {
+	vision *VisionService
+}
+
+func NewClassifyImageTool(vision *VisionService) *ClassifyImageTool {
+	return &ClassifyImageTool{vision: vision}
+}
+
+func (t *ClassifyImageTool) Definition() ai.ToolDefinition {
+	return ai.ToolDefinition{
+		Name:        "classify_image",
+		Description: "Classify an attached image with a local ImageNet model and return the most likely labels with probabilities.",
+		Parameters: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"image_index": imageIndexSchema,
+				"top_k":       map[string]interface{}{"type": "integer", "minimum": 1, "maximum": 10},
+			},
+			"required": []string{"image_index"},
+		},
+	}
+}
+
+func (t *ClassifyImageTool) Call(ctx context.Context, userID uint, args json.RawMessage, images []ChatImage) (string, error) {
+	var params struct {
+		ImageIndex int `json:"image_index"`
+		TopK       int `json:"top_k"`
+	}
+	if err := json.Unmarshal(args, &params); err != nil {
+		return "", fmt.Errorf("invalid arguments: %w", err)
+	}
+	image, err := pickImage(images, params.ImageIndex)
+	if err != nil {
+		return "", err
+	}
+	if params.TopK <= 0 || params.TopK > 10 {
+		params.TopK = 5
+	}
+	result, err := t.vision.Classify(ctx, ClassifyImageInput{
+		UserID: userID,
+		Image:  image.Data,
+		Options: vision.ClassifyOptions{
+			TopK:          params.TopK,
+			Probabilities: true,
+		},
+	})
+	if err != nil {
+		return "", err
+	}
+	out, err := json.Marshal(result.Predictions)
+	if err != nil {
+		return "", err
+	}
+	return string(out), nil
+}
+
+// OCRImageTool exposes OCR as the "ocr_image" tool.
+type OCRImageTool struct {
+	ocr    ai.OCR
+	config ai.ChatConfig
+}
+
+func NewOCRImageTool(ocr ai.OCR, config ai.ChatConfig) *OCRImageTool {
+	return &OCRImageTool{ocr: ocr, config: config}
+}
+
+func (t *OCRImageTool) Definition() ai.ToolDefinition {
+	return ai.ToolDefinition{
+		Name:        "ocr_image",
+		Description: "Read and return all text visible in an attached image (documents, screenshots, photos of printed pages).",
+		Parameters: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"image_index": imageIndexSchema,
+			},
+			"required": []string{"image_index"},
+		},
+	}
+}
+
+func (t *OCRImageTool) Call(ctx context.Context, _ uint, args json.RawMessage, images []ChatImage) (string, error) {
+	var params struct {
+		ImageIndex int `json:"image_index"`
+	}
+	if err := json.Unmarshal(args, &params); err != nil {
+		return "", fmt.Errorf("invalid arguments: %w", err)
+	}
+	image, err := pickImage(images, params.ImageIndex)
+	if err != nil {
+		return "", err
+	}
+	return t.ocr.RecognizeText(ctx, t.config, image.Data, image.MIMEType)
+}
+
+// runToolLoop lets the model call tools until it produces a final answer or maxToolRounds is
+// reached. It returns the final content, the full transcript, and the calls made.
+func (s *ChatService) runToolLoop(
+	ctx context.Context,
+	cfg ai.ChatConfig,
+	userID uint,
+	messages []ai.ChatMessage,
+	images []ChatImage,
+) (string, []ai.ChatMessage, []ToolInvocation, error) {
+	definitions := make([]ai.ToolDefinition, len(s.tools))
+	byName := make(map[string]ChatTool, len(s.tools))
+	for i, tool := range s.tools {
+		definitions[i] = tool.Definition()
+		byName[definitions[i].Name] = tool
+	}
+
+	var invocations []ToolInvocation
+	for round := 0; round < maxToolRounds; round++ {
+		reply, err := s.toolCaller.CompleteWithTools(ctx, cfg, messages, definitions)
+		if err != nil {
+			return "", messages, invocations, err
+		}
+		messages = append(messages, reply)
+		if len(reply.ToolCalls) == 0 {
+			return reply.Content, messages, invocations, nil
+		}
+
+		for _, call := range reply.ToolCalls {
+			invocation := ToolInvocation{Name: call.Function.Name, Arguments: call.Function.Arguments}
+			var result string
+			tool, ok := byName[call.Function.Name]
+			if !ok {
+				err = fmt.Errorf("unknown tool %q", call.Function.Name)
+			} else {
+				result, err = tool.Call(ctx, userID, json.RawMessage(call.Function.Arguments), images)
+			}
+			if err != nil {
+				invocation.Error = err.Error()
+				result = "error: " + err.Error()
+			} else {
+				invocation.Result = result
+			}
+			invocations = append(invocations, invocation)
+			messages = append(messages, ai.ChatMessage{
+				Role:       "tool",
+				ToolCallID: call.ID,
+				Content:    result,
+			})
+		}
+	}
+
+	// Out of rounds: ask for an answer without tools.
+	final, err := s.completer.Complete(ctx, cfg, messages)
+	return final, messages, invocations, err
+}
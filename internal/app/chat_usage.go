@@ -0,0 +1,137 @@
+package app
+
+import (
+	"context"
+	"math"
+	"time"
+
+	"gopherai-resume/internal/ai"
+	"gopherai-resume/internal/model"
+	"gopherai-resume/internal/pkg/apperr"
+	"gopherai-resume/internal/repository"
+)
+
+var ErrInvalidUsagePeriod = apperr.BadRequest("period must be day, week, month or all")
+
+// usagePeriods are the rolling windows GET /chat/usage reports on; "all" has no window.
+var usagePeriods = map[string]time.Duration{
+	"day":   24 * time.Hour,
+	"week":  7 * 24 * time.Hour,
+	"month": 30 * 24 * time.Hour,
+}
+
+// ModelPrice is what a model costs per million tokens, in the configured currency.
+type ModelPrice struct {
+	PromptPerMillion     float64
+	CompletionPerMillion float64
+}
+
+// ModelUsageCost is one model's usage and its cost; Cost is nil when the model has no price.
+type ModelUsageCost struct {
+	repository.ModelUsage
+	Cost *float64 `json:"cost"`
+}
+
+// ChatUsageReport sums a user's chat token usage over a period.
+type ChatUsageReport struct {
+	Period           string     `json:"period"`
+	Since            *time.Time `json:"since"`
+	Currency         string     `json:"currency"`
+	Messages         int64      `json:"messages"`
+	PromptTokens     int64      `json:"prompt_tokens"`
+	CompletionTokens int64      `json:"completion_tokens"`
+	TotalTokens      int64      `json:"total_tokens"`
+	// EstimatedCost covers the priced models only; UnpricedModels lists the others.
+	EstimatedCost  float64                 `json:"estimated_cost"`
+	UnpricedModels []string                `json:"unpriced_models"`
+	ByModel        []ModelUsageCost        `json:"by_model"`
+	ByDay          []repository.DailyUsage `json:"by_day"`
+}
+
+// ChatUsageService reports token usage and spend from the counts stored on assistant messages.
+type ChatUsageService struct {
+	messages MessageRepository
+	prices   map[string]ModelPrice
+	currency string
+}
+
+func NewChatUsageService(messages MessageRepository, prices map[string]ModelPrice, currency string) *ChatUsageService {
+	return &ChatUsageService{messages: messages, prices: prices, currency: currency}
+}
+
+// Usage reports the user's usage for period: "day", "week" and "month" are the last 1, 7
+// and 30 days; "all" (or empty, for "month") selects everything.
+func (s *ChatUsageService) Usage(ctx context.Context, userID uint, period string) (*ChatUsageReport, error) {
+	if userID == 0 {
+		return nil, ErrInvalidInput
+	}
+	if period == "" {
+		period = "month"
+	}
+	report := &ChatUsageReport{
+		Period:         period,
+		Currency:       s.currency,
+		UnpricedModels: []string{},
+		ByModel:        []ModelUsageCost{},
+	}
+	since := time.Unix(0, 0)
+	if period != "all" {
+		window, ok := usagePeriods[period]
+		if !ok {
+			return nil, ErrInvalidUsagePeriod
+		}
+		since = time.Now().Add(-window)
+		report.Since = &since
+	}
+
+	byModel, err := s.messages.UsageByModel(ctx, userID, since)
+	if err != nil {
+		return nil, err
+	}
+	for _, usage := range byModel {
+		row := ModelUsageCost{ModelUsage: usage}
+		if price, ok := s.prices[usage.Model]; ok {
+			cost := roundCost(float64(usage.PromptTokens)/1e6*price.PromptPerMillion +
+				float64(usage.CompletionTokens)/1e6*price.CompletionPerMillion)
+			row.Cost = &cost
+			report.EstimatedCost += cost
+		} else if usage.Model != "" {
+			report.UnpricedModels = append(report.UnpricedModels, usage.Model)
+		}
+		report.Messages += usage.Messages
+		report.PromptTokens += usage.PromptTokens
+		report.CompletionTokens += usage.CompletionTokens
+		report.ByModel = append(report.ByModel, row)
+	}
+	report.TotalTokens = report.PromptTokens + report.CompletionTokens
+	report.EstimatedCost = roundCost(report.EstimatedCost)
+
+	if report.ByDay, err = s.messages.UsageByDay(ctx, userID, since); err != nil {
+		return nil, err
+	}
+	if report.ByDay == nil {
+		report.ByDay = []repository.DailyUsage{}
+	}
+	return report, nil
+}
+
+func roundCost(cost float64) float64 {
+	return math.Round(cost*1e6) / 1e6
+}
+
+// applyUsage records on an assistant message the model and token counts of the completion
+// that produced it. Without provider-reported usage, such as after a cancelled stream, the
+// counts are estimated from the prompt and the reply.
+func applyUsage(message *model.Message, cfg ai.ChatConfig, meter *ai.UsageMeter, prompt []ai.ChatMessage) {
+	message.Model = cfg.Model
+	if usage, ok := meter.Usage(); ok {
+		message.PromptTokens, message.CompletionTokens = usage.PromptTokens, usage.CompletionTokens
+		return
+	}
+	message.PromptTokens = 0
+	for _, m := range prompt {
+		message.PromptTokens += ai.EstimateMessageTokens(m)
+	}
+	message.CompletionTokens = ai.EstimateTokens(message.Content)
+	message.UsageEstimated = true
+}
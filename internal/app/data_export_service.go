@@ -0,0 +1,686 @@
+package app
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"time"
+
+	"gopherai-resume/internal/model"
+	"gopherai-resume/internal/pkg/apperr"
+	"gopherai-resume/internal/pkg/authz"
+	"gopherai-resume/internal/repository"
+	"gopherai-resume/internal/storage"
+)
+
+var (
+	ErrDataExportNotFound   = apperr.NotFound(apperr.CodeExportNotFound, "data export not found")
+	ErrDataExportNotReady   = apperr.Conflict(apperr.CodeExportNotReady, "data export is not ready")
+	ErrDataExportInProgress = apperr.Conflict(apperr.CodeExportInProgress, "another data export is already being generated")
+)
+
+// Data export statuses.
+const (
+	ExportPending    = "pending"
+	ExportProcessing = "processing"
+	ExportReady      = "ready"
+	ExportFailed     = "failed"
+)
+
+const (
+	// dataExportReuse is how long a finished export is handed out again instead of
+	// starting a new one.
+	dataExportReuse = 24 * time.Hour
+	// dataExportTimeout bounds one generation; unfinished exports older than twice this
+	// are treated as abandoned, e.g. by a restart.
+	dataExportTimeout   = 10 * time.Minute
+	dataExportPageSize  = 500
+	dataExportKeyFormat = "exports/%d/%d.zip"
+)
+
+// DataExportService builds archives of everything a user owns for data portability.
+type DataExportService struct {
+	repo          DataExportRepository
+	users         UserRepository
+	sessions      SessionRepository
+	messages      MessageRepository
+	ragSessions   RAGSessionRepository
+	ragDocs       RAGDocumentRepository
+	ragChunks     RAGChunkRepository
+	ragMessages   RAGMessageRepository
+	vision        VisionSampleRepository
+	applications  ApplicationRepository
+	profiles      ResumeProfileRepository
+	bullets       ResumeBulletRepository
+	portfolios    PortfolioAnalysisRepository
+	workspaces    WorkspaceRepository
+	candidates    WorkspaceCandidateRepository
+	postings      JobPostingRepository
+	reports       ScreeningReportRepository
+	scheduled     ScheduledMessageRepository
+	notifications NotificationRepository
+	drafts        DraftStore // nil when Redis is disabled
+	store         storage.ObjectStore
+}
+
+func NewDataExportService(
+	repo DataExportRepository,
+	users UserRepository,
+	sessions SessionRepository,
+	messages MessageRepository,
+	ragSessions RAGSessionRepository,
+	ragDocs RAGDocumentRepository,
+	ragChunks RAGChunkRepository,
+	ragMessages RAGMessageRepository,
+	vision VisionSampleRepository,
+	applications ApplicationRepository,
+	profiles ResumeProfileRepository,
+	bullets ResumeBulletRepository,
+	portfolios PortfolioAnalysisRepository,
+	workspaces WorkspaceRepository,
+	candidates WorkspaceCandidateRepository,
+	postings JobPostingRepository,
+	reports ScreeningReportRepository,
+	scheduled ScheduledMessageRepository,
+	notifications NotificationRepository,
+	drafts DraftStore,
+	store storage.ObjectStore,
+) *DataExportService {
+	return &DataExportService{
+		repo:          repo,
+		users:         users,
+		sessions:      sessions,
+		messages:      messages,
+		ragSessions:   ragSessions,
+		ragDocs:       ragDocs,
+		ragChunks:     ragChunks,
+		ragMessages:   ragMessages,
+		vision:        vision,
+		applications:  applications,
+		profiles:      profiles,
+		bullets:       bullets,
+		portfolios:    portfolios,
+		workspaces:    workspaces,
+		candidates:    candidates,
+		postings:      postings,
+		reports:       reports,
+		scheduled:     scheduled,
+		notifications: notifications,
+		drafts:        drafts,
+		store:         store,
+	}
+}
+
+// Request returns the user's export in progress, or one finished within the last day, and
+// otherwise starts a new one in the background. Starting one deletes the user's older exports.
+// A user has at most one unfinished export: a request racing another that is starting one
+// gets ErrDataExportInProgress.
+func (s *DataExportService) Request(ctx context.Context, userID uint) (*model.DataExport, error) {
+	if userID == 0 {
+		return nil, ErrInvalidInput
+	}
+	previous, err := s.repo.ListByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if len(previous) > 0 {
+		latest := previous[0]
+		age := time.Since(latest.CreatedAt)
+		switch latest.Status {
+		case ExportPending, ExportProcessing:
+			if age < 2*dataExportTimeout {
+				return &latest, nil
+			}
+			// Abandoned: finish it as failed so it no longer holds the user's one
+			// unfinished export.
+			latest.Status = ExportFailed
+			latest.Error = "export was interrupted"
+			latest.ActiveUserID = nil
+			if err := s.repo.Update(ctx, &latest); err != nil {
+				return nil, err
+			}
+			previous[0] = latest
+		case ExportReady:
+			if age < dataExportReuse {
+				return &latest, nil
+			}
+		}
+	}
+
+	export := &model.DataExport{UserID: userID, Status: ExportPending}
+	created, err := s.repo.CreateActive(ctx, export)
+	if err != nil {
+		return nil, err
+	}
+	if !created {
+		return nil, ErrDataExportInProgress
+	}
+	for _, old := range previous {
+		s.delete(ctx, &old)
+	}
+	go func(id uint) {
+		genCtx, cancel := context.WithTimeout(context.Background(), dataExportTimeout)
+		defer cancel()
+		if err := s.Generate(genCtx, id); err != nil {
+			log.Printf("generate data export %d failed: %v", id, err)
+		}
+	}(export.ID)
+	return export, nil
+}
+
+// Download returns a ready export's archive and its file name.
+func (s *DataExportService) Download(ctx context.Context, userID, exportID uint) ([]byte, string, error) {
+	if userID == 0 || exportID == 0 {
+		return nil, "", ErrInvalidInput
+	}
+	// Archives hold everything a user stored, so admins get no read override here.
+	export, err := loadDataExport(ctx, s.repo, exportID, userID, authz.Write)
+	if err != nil {
+		return nil, "", err
+	}
+	if export == nil {
+		return nil, "", ErrDataExportNotFound
+	}
+	if export.Status != ExportReady {
+		return nil, "", ErrDataExportNotReady
+	}
+	data, err := s.store.Get(ctx, export.ObjectKey)
+	if errors.Is(err, storage.ErrObjectNotFound) {
+		return nil, "", ErrDataExportNotFound
+	}
+	if err != nil {
+		return nil, "", err
+	}
+	return data, fmt.Sprintf("gopherai-export-%s.zip", export.CreatedAt.UTC().Format("20060102")), nil
+}
+
+// delete removes an export and its archive. Failures are logged only.
+func (s *DataExportService) delete(ctx context.Context, export *model.DataExport) {
+	if export.ObjectKey != "" {
+		if err := s.store.Delete(ctx, export.ObjectKey); err != nil {
+			log.Printf("delete data export %d file failed: %v", export.ID, err)
+			return
+		}
+	}
+	if err := s.repo.DeleteByID(ctx, export.ID); err != nil {
+		log.Printf("delete data export %d failed: %v", export.ID, err)
+	}
+}
+
+// Generate builds and stores one export, recording failures on the export itself.
+func (s *DataExportService) Generate(ctx context.Context, exportID uint) error {
+	export, err := s.repo.GetByID(ctx, exportID)
+	if err != nil {
+		return err
+	}
+	if export == nil || export.Status == ExportReady {
+		return nil
+	}
+	export.Status = ExportProcessing
+	export.Error = ""
+	if err := s.repo.Update(ctx, export); err != nil {
+		return err
+	}
+
+	// The archive is written to the store as it is built rather than held in memory; a
+	// failed build aborts the write.
+	key := fmt.Sprintf(dataExportKeyFormat, export.UserID, export.ID)
+	pr, pw := io.Pipe()
+	go func() {
+		pw.CloseWithError(s.build(ctx, export.UserID, pw))
+	}()
+	size, genErr := s.store.PutStream(ctx, key, pr)
+	// Unblocks the build if the store gave up first.
+	pr.CloseWithError(errors.New("data export upload stopped"))
+	if genErr == nil {
+		export.ObjectKey = key
+		export.SizeBytes = size
+		export.Status = ExportReady
+	} else {
+		export.Status = ExportFailed
+		export.Error = truncateRunes(genErr.Error(), 512)
+	}
+	export.ActiveUserID = nil
+	// The generation context may have run out; the outcome must still be recorded.
+	if err := s.repo.Update(context.WithoutCancel(ctx), export); err != nil {
+		return err
+	}
+	return genErr
+}
+
+// exportedApplication is an application with its status history.
+type exportedApplication struct {
+	model.Application
+	StatusHistory []model.ApplicationStatusChange `json:"status_history"`
+}
+
+// exportedVisionSample is a vision sample with its stored result and image file.
+type exportedVisionSample struct {
+	model.VisionSample
+	Result json.RawMessage `json:"result,omitempty"`
+	Image  string          `json:"image,omitempty"`
+}
+
+// exportedResumeProfile is a resume profile with its structured data.
+type exportedResumeProfile struct {
+	model.ResumeProfile
+	Data json.RawMessage `json:"data,omitempty"`
+}
+
+// exportedResumeBullet is a resume bullet with its rewrite conversation.
+type exportedResumeBullet struct {
+	model.ResumeBullet
+	Transcript json.RawMessage `json:"transcript,omitempty"`
+}
+
+// exportedPortfolioAnalysis is a portfolio analysis with its projects and bullets.
+type exportedPortfolioAnalysis struct {
+	model.PortfolioAnalysis
+	Projects json.RawMessage `json:"projects,omitempty"`
+	Bullets  json.RawMessage `json:"bullets,omitempty"`
+}
+
+// exportedWorkspace is a workspace the user belongs to. Workspaces the user owns come with
+// their members, postings, candidates, screening results and reports; the others only with
+// the candidates the user added.
+type exportedWorkspace struct {
+	model.Workspace
+	Members          []model.WorkspaceMember   `json:"members,omitempty"`
+	Postings         []exportedJobPosting      `json:"postings,omitempty"`
+	Candidates       []exportedCandidate       `json:"candidates"`
+	ScreeningResults []exportedScreeningResult `json:"screening_results,omitempty"`
+	Reports          []exportedScreeningReport `json:"reports,omitempty"`
+}
+
+// exportedJobPosting is a job posting with its parsed fields and tags.
+type exportedJobPosting struct {
+	model.JobPosting
+	Parsed json.RawMessage `json:"parsed,omitempty"`
+	Tags   []string        `json:"tags"`
+}
+
+// exportedCandidate is a pool candidate with their skills.
+type exportedCandidate struct {
+	model.WorkspaceCandidate
+	Skills json.RawMessage `json:"skills,omitempty"`
+}
+
+// exportedScreeningResult is a screening result with its strengths and gaps.
+type exportedScreeningResult struct {
+	model.ScreeningResult
+	Strengths json.RawMessage `json:"strengths,omitempty"`
+	Gaps      json.RawMessage `json:"gaps,omitempty"`
+}
+
+// exportedScreeningReport is a screening report with its stored file.
+type exportedScreeningReport struct {
+	model.ScreeningReport
+	File string `json:"file,omitempty"`
+}
+
+// exportedNotifications are the user's email preferences and the emails sent or queued for
+// them.
+type exportedNotifications struct {
+	Preferences *model.NotificationPreference `json:"preferences"`
+	Emails      []model.EmailOutbox           `json:"emails"`
+}
+
+// rawJSON returns a stored JSON column for embedding in the export, or nil when it is not
+// valid JSON.
+func rawJSON(s string) json.RawMessage {
+	if !json.Valid([]byte(s)) {
+		return nil
+	}
+	return json.RawMessage(s)
+}
+
+// exportArchive writes the files of one export.
+type exportArchive struct {
+	zw *zip.Writer
+}
+
+func (a *exportArchive) writeJSON(name string, v interface{}) error {
+	w, err := a.zw.Create(name)
+	if err != nil {
+		return fmt.Errorf("create %s in export failed: %w", name, err)
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+// copyObject copies a stored object into the archive as name, reporting false when the
+// object no longer exists.
+func (a *exportArchive) copyObject(ctx context.Context, store storage.ObjectStore, key, name string) (bool, error) {
+	data, err := store.Get(ctx, key)
+	if errors.Is(err, storage.ErrObjectNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	w, err := a.zw.Create(name)
+	if err != nil {
+		return false, fmt.Errorf("create %s in export failed: %w", name, err)
+	}
+	if _, err := w.Write(data); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// build writes the user's data as JSON files, plus kept vision images and screening report
+// files, into a zip archive on w.
+func (s *DataExportService) build(ctx context.Context, userID uint, w io.Writer) error {
+	zw := zip.NewWriter(w)
+	archive := &exportArchive{zw: zw}
+
+	user, err := s.users.GetByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if user == nil {
+		return ErrUserNotFound
+	}
+	if err := archive.writeJSON("profile.json", user); err != nil {
+		return err
+	}
+	for _, part := range []func(context.Context, *exportArchive, uint) error{
+		s.buildChat,
+		s.buildRAG,
+		s.buildVision,
+		s.buildApplications,
+		s.buildPortfolios,
+		s.buildWorkspaces,
+		s.buildNotifications,
+	} {
+		if err := part(ctx, archive, userID); err != nil {
+			return err
+		}
+	}
+
+	if err := zw.Close(); err != nil {
+		return fmt.Errorf("finish export archive failed: %w", err)
+	}
+	return nil
+}
+
+// buildChat exports chat sessions, messages, unsent drafts and scheduled messages.
+func (s *DataExportService) buildChat(ctx context.Context, archive *exportArchive, userID uint) error {
+	sessions, err := s.sessions.ListByUserID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if err := archive.writeJSON("chat/sessions.json", sessions); err != nil {
+		return err
+	}
+	var messages []model.Message
+	for afterID := uint(0); ; {
+		page, err := s.messages.ListByUserIDAfterID(ctx, userID, afterID, dataExportPageSize)
+		if err != nil {
+			return err
+		}
+		messages = append(messages, page...)
+		if len(page) < dataExportPageSize {
+			break
+		}
+		afterID = page[len(page)-1].ID
+	}
+	if err := archive.writeJSON("chat/messages.json", messages); err != nil {
+		return err
+	}
+
+	drafts := []model.ChatDraft{}
+	if s.drafts != nil {
+		for _, session := range sessions {
+			draft, err := s.drafts.GetDraft(ctx, userID, session.ID)
+			if err != nil {
+				return err
+			}
+			if draft != nil {
+				drafts = append(drafts, *draft)
+			}
+		}
+	}
+	if err := archive.writeJSON("chat/drafts.json", drafts); err != nil {
+		return err
+	}
+	scheduled, err := s.scheduled.ListByUserID(ctx, userID, "")
+	if err != nil {
+		return err
+	}
+	return archive.writeJSON("chat/scheduled_messages.json", scheduled)
+}
+
+// buildRAG exports RAG sessions and questions, documents with their chunks, and the resume
+// profiles and bullets extracted from them.
+func (s *DataExportService) buildRAG(ctx context.Context, archive *exportArchive, userID uint) error {
+	ragSessions, err := s.ragSessions.ListByUserID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if err := archive.writeJSON("rag/sessions.json", ragSessions); err != nil {
+		return err
+	}
+	var questions []model.RAGMessage
+	for _, session := range ragSessions {
+		for beforeID := uint(0); ; {
+			page, err := s.ragMessages.ListRecentBySessionID(ctx, session.ID, beforeID, dataExportPageSize)
+			if err != nil {
+				return err
+			}
+			questions = append(questions, page...)
+			if len(page) < dataExportPageSize {
+				break
+			}
+			beforeID = page[len(page)-1].ID
+		}
+	}
+	if err := archive.writeJSON("rag/questions.json", questions); err != nil {
+		return err
+	}
+	docs, err := s.ragDocs.ListByUserID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if err := archive.writeJSON("rag/documents.json", docs); err != nil {
+		return err
+	}
+	profiles := []exportedResumeProfile{}
+	bullets := []exportedResumeBullet{}
+	for _, doc := range docs {
+		chunks, err := s.ragChunks.ListByDocumentIDs(ctx, []uint{doc.ID})
+		if err != nil {
+			return err
+		}
+		if err := archive.writeJSON(fmt.Sprintf("rag/chunks/%d.json", doc.ID), chunks); err != nil {
+			return err
+		}
+		profile, err := s.profiles.GetByDocumentIDAndUserID(ctx, doc.ID, userID)
+		if err != nil {
+			return err
+		}
+		if profile != nil {
+			profiles = append(profiles, exportedResumeProfile{ResumeProfile: *profile, Data: rawJSON(profile.Data)})
+		}
+		docBullets, err := s.bullets.ListByDocumentID(ctx, userID, doc.ID)
+		if err != nil {
+			return err
+		}
+		for _, b := range docBullets {
+			bullets = append(bullets, exportedResumeBullet{ResumeBullet: b, Transcript: rawJSON(b.Transcript)})
+		}
+	}
+	if err := archive.writeJSON("resume/profiles.json", profiles); err != nil {
+		return err
+	}
+	return archive.writeJSON("resume/bullets.json", bullets)
+}
+
+// buildVision exports vision samples with their kept images.
+func (s *DataExportService) buildVision(ctx context.Context, archive *exportArchive, userID uint) error {
+	var samples []exportedVisionSample
+	for afterID := uint(0); ; {
+		page, err := s.vision.ListByUserIDAfterID(ctx, userID, afterID, dataExportPageSize)
+		if err != nil {
+			return err
+		}
+		for _, sample := range page {
+			exported := exportedVisionSample{VisionSample: sample, Result: rawJSON(sample.Result)}
+			name := fmt.Sprintf("vision/images/%d", sample.ID)
+			copied, err := archive.copyObject(ctx, s.store, sample.ObjectKey, name)
+			if err != nil {
+				return err
+			}
+			if copied {
+				exported.Image = name
+			}
+			samples = append(samples, exported)
+		}
+		if len(page) < dataExportPageSize {
+			break
+		}
+		afterID = page[len(page)-1].ID
+	}
+	return archive.writeJSON("vision/samples.json", samples)
+}
+
+// buildApplications exports job applications with their status history.
+func (s *DataExportService) buildApplications(ctx context.Context, archive *exportArchive, userID uint) error {
+	apps, err := s.applications.ListByUserID(ctx, userID, "")
+	if err != nil {
+		return err
+	}
+	exportedApps := make([]exportedApplication, 0, len(apps))
+	for _, a := range apps {
+		history, err := s.applications.ListStatusChanges(ctx, a.ID)
+		if err != nil {
+			return err
+		}
+		exportedApps = append(exportedApps, exportedApplication{Application: a, StatusHistory: history})
+	}
+	return archive.writeJSON("applications.json", exportedApps)
+}
+
+// buildPortfolios exports portfolio analyses.
+func (s *DataExportService) buildPortfolios(ctx context.Context, archive *exportArchive, userID uint) error {
+	analyses, err := s.portfolios.ListByUserID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	exported := make([]exportedPortfolioAnalysis, 0, len(analyses))
+	for _, a := range analyses {
+		exported = append(exported, exportedPortfolioAnalysis{PortfolioAnalysis: a, Projects: rawJSON(a.Projects), Bullets: rawJSON(a.Bullets)})
+	}
+	return archive.writeJSON("portfolio_analyses.json", exported)
+}
+
+// buildWorkspaces exports the workspaces the user is an active member of; see
+// exportedWorkspace for what each includes.
+func (s *DataExportService) buildWorkspaces(ctx context.Context, archive *exportArchive, userID uint) error {
+	workspaces, err := s.workspaces.ListByUserID(ctx, userID, MemberActive)
+	if err != nil {
+		return err
+	}
+	exported := make([]exportedWorkspace, 0, len(workspaces))
+	for _, ws := range workspaces {
+		owned := ws.OwnerID == userID
+		out := exportedWorkspace{Workspace: ws, Candidates: []exportedCandidate{}}
+		candidates, err := s.candidates.ListByWorkspaceID(ctx, ws.ID)
+		if err != nil {
+			return err
+		}
+		for _, c := range candidates {
+			if owned || c.AddedBy == userID {
+				out.Candidates = append(out.Candidates, exportedCandidate{WorkspaceCandidate: c, Skills: rawJSON(c.Skills)})
+			}
+		}
+		if owned {
+			if err := s.buildOwnedWorkspace(ctx, archive, &out); err != nil {
+				return err
+			}
+		}
+		exported = append(exported, out)
+	}
+	return archive.writeJSON("workspaces.json", exported)
+}
+
+// buildOwnedWorkspace adds the members, postings, screening results and reports of a
+// workspace the user owns, copying report files into the archive.
+func (s *DataExportService) buildOwnedWorkspace(ctx context.Context, archive *exportArchive, out *exportedWorkspace) error {
+	members, err := s.workspaces.ListMembers(ctx, out.ID)
+	if err != nil {
+		return err
+	}
+	out.Members = members
+	postings, err := s.postings.ListByWorkspaceID(ctx, out.ID, repository.JobPostingFilter{IncludeArchived: true})
+	if err != nil {
+		return err
+	}
+	ids := make([]uint, 0, len(postings))
+	for _, p := range postings {
+		ids = append(ids, p.ID)
+	}
+	tags, err := s.postings.ListTags(ctx, ids)
+	if err != nil {
+		return err
+	}
+	for _, p := range postings {
+		postingTags := tags[p.ID]
+		if postingTags == nil {
+			postingTags = []string{}
+		}
+		out.Postings = append(out.Postings, exportedJobPosting{JobPosting: p, Parsed: rawJSON(p.Parsed), Tags: postingTags})
+		results, err := s.postings.ListScreeningResults(ctx, p.ID)
+		if err != nil {
+			return err
+		}
+		for _, r := range results {
+			out.ScreeningResults = append(out.ScreeningResults, exportedScreeningResult{ScreeningResult: r, Strengths: rawJSON(r.Strengths), Gaps: rawJSON(r.Gaps)})
+		}
+		reports, err := s.reports.ListByPostingID(ctx, p.ID)
+		if err != nil {
+			return err
+		}
+		for _, r := range reports {
+			exported := exportedScreeningReport{ScreeningReport: r}
+			if r.ObjectKey != "" {
+				name := fmt.Sprintf("workspaces/%d/reports/%d", out.ID, r.ID)
+				copied, err := archive.copyObject(ctx, s.store, r.ObjectKey, name)
+				if err != nil {
+					return err
+				}
+				if copied {
+					exported.File = name
+				}
+			}
+			out.Reports = append(out.Reports, exported)
+		}
+	}
+	return nil
+}
+
+// buildNotifications exports email preferences and the emails sent or queued for the user.
+func (s *DataExportService) buildNotifications(ctx context.Context, archive *exportArchive, userID uint) error {
+	pref, err := s.notifications.GetPreference(ctx, userID)
+	if err != nil {
+		return err
+	}
+	out := exportedNotifications{Preferences: pref, Emails: []model.EmailOutbox{}}
+	for afterID := uint(0); ; {
+		page, err := s.notifications.ListOutboxByUserIDAfterID(ctx, userID, afterID, dataExportPageSize)
+		if err != nil {
+			return err
+		}
+		out.Emails = append(out.Emails, page...)
+		if len(page) < dataExportPageSize {
+			break
+		}
+		afterID = page[len(page)-1].ID
+	}
+	return archive.writeJSON("notifications.json", out)
+}
@@ -0,0 +1,147 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strings"
+
+	"gopherai-resume/internal/ai"
+	"gopherai-resume/internal/pkg/apperr"
+)
+
+const maxEmbeddingInputs = 64
+
+var ErrEmbeddingTooManyInputs = apperr.BadRequest("too many embedding inputs")
+
+// EmbeddingCache caches vectors by model and input text.
+type EmbeddingCache interface {
+	GetEmbedding(ctx context.Context, model, text string) ([]float32, bool, error)
+	SetEmbedding(ctx context.Context, model, text string, vec []float32) error
+}
+
+// EmbeddingService proxies the configured embedding provider for authenticated users,
+// so clients never need the provider key.
+type EmbeddingService struct {
+	embedder  ai.Embedder
+	embConfig ai.EmbeddingConfig
+	cache     EmbeddingCache
+	quota     *QuotaService
+}
+
+func NewEmbeddingService(
+	embedder ai.Embedder,
+	embConfig ai.EmbeddingConfig,
+	cache EmbeddingCache,
+	quota *QuotaService,
+) *EmbeddingService {
+	return &EmbeddingService{
+		embedder:  embedder,
+		embConfig: embConfig,
+		cache:     cache,
+		quota:     quota,
+	}
+}
+
+// EmbedInput is the input for an embeddings request.
+type EmbedInput struct {
+	UserID uint
+	Inputs []string
+}
+
+// EmbeddingItem is one vector in an OpenAI-style embeddings response.
+type EmbeddingItem struct {
+	Object    string    `json:"object"`
+	Index     int       `json:"index"`
+	Embedding []float32 `json:"embedding"`
+}
+
+// EmbedResult mirrors the OpenAI embeddings response shape plus a cache-hit count.
+type EmbedResult struct {
+	Object string          `json:"object"`
+	Model  string          `json:"model"`
+	Data   []EmbeddingItem `json:"data"`
+	Cached int             `json:"cached"`
+}
+
+// Embed returns embeddings for the inputs. Cached vectors are served without calling the provider
+// and do not count towards the user's quota. The rest is reserved up front, and inputs the
+// provider failed to embed are refunded.
+func (s *EmbeddingService) Embed(ctx context.Context, input EmbedInput) (*EmbedResult, error) {
+	if input.UserID == 0 || len(input.Inputs) == 0 {
+		return nil, ErrInvalidInput
+	}
+	if len(input.Inputs) > maxEmbeddingInputs {
+		return nil, ErrEmbeddingTooManyInputs
+	}
+	texts := make([]string, len(input.Inputs))
+	for i, text := range input.Inputs {
+		texts[i] = strings.TrimSpace(text)
+		if texts[i] == "" {
+			return nil, ErrInvalidInput
+		}
+	}
+
+	vectors := make([][]float32, len(texts))
+	var missIdx []int
+	cached := 0
+	for i, text := range texts {
+		if s.cache != nil {
+			if vec, hit, err := s.cache.GetEmbedding(ctx, s.embConfig.Model, text); err == nil && hit {
+				vectors[i] = vec
+				cached++
+				continue
+			}
+		}
+		missIdx = append(missIdx, i)
+	}
+
+	if len(missIdx) > 0 {
+		if err := s.quota.Consume(ctx, input.UserID, QuotaEmbeddingInputs, int64(len(missIdx))); err != nil {
+			return nil, err
+		}
+		for start := 0; start < len(missIdx); start += embeddingBatchSize {
+			end := start + embeddingBatchSize
+			if end > len(missIdx) {
+				end = len(missIdx)
+			}
+			batch := make([]string, 0, end-start)
+			for _, idx := range missIdx[start:end] {
+				batch = append(batch, texts[idx])
+			}
+			embedded, err := s.embedder.EmbedBatch(ctx, s.embConfig, batch)
+			if err == nil && len(embedded) != len(batch) {
+				err = errors.New("embedding count mismatch")
+			}
+			if err != nil {
+				// Earlier batches were embedded and cached; only the rest is given back.
+				s.refundEmbeddings(ctx, input.UserID, len(missIdx)-start)
+				return nil, err
+			}
+			for j, idx := range missIdx[start:end] {
+				vectors[idx] = embedded[j]
+				if s.cache != nil {
+					_ = s.cache.SetEmbedding(ctx, s.embConfig.Model, texts[idx], embedded[j])
+				}
+			}
+		}
+	}
+
+	result := &EmbedResult{
+		Object: "list",
+		Model:  s.embConfig.Model,
+		Data:   make([]EmbeddingItem, len(vectors)),
+		Cached: cached,
+	}
+	for i, vec := range vectors {
+		result.Data[i] = EmbeddingItem{Object: "embedding", Index: i, Embedding: vec}
+	}
+	return result, nil
+}
+
+func (s *EmbeddingService) refundEmbeddings(ctx context.Context, userID uint, inputs int) {
+	err := s.quota.Refund(ctx, userID, map[string]int64{QuotaEmbeddingInputs: int64(inputs)})
+	if err != nil {
+		log.Printf("refund embedding quota for user %d failed: %v", userID, err)
+	}
+}
@@ -0,0 +1,150 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"gopherai-resume/internal/ai"
+	"gopherai-resume/internal/pkg/apperr"
+	"gopherai-resume/internal/pkg/authz"
+)
+
+var ErrEvaluationUnparseable = apperr.New(http.StatusBadGateway, apperr.CodeInternalServer, "could not parse evaluation from model output")
+
+// Interview question types.
+const (
+	QuestionBehavioral = "behavioral"
+	QuestionTechnical  = "technical"
+)
+
+const maxAnswerChars = 8000
+
+// RubricScore is one rubric criterion scored from 1 to 5.
+type RubricScore struct {
+	Criterion string `json:"criterion"`
+	Score     int    `json:"score"`
+	Feedback  string `json:"feedback"`
+}
+
+// STARBreakdown reports which STAR parts an answer covers, quoting or summarizing each.
+// Empty fields are missing from the answer.
+type STARBreakdown struct {
+	Situation string `json:"situation"`
+	Task      string `json:"task"`
+	Action    string `json:"action"`
+	Result    string `json:"result"`
+}
+
+// AnswerEvaluation is structured feedback on one interview answer.
+type AnswerEvaluation struct {
+	QuestionType     string         `json:"question_type"`
+	OverallScore     int            `json:"overall_score"` // 0-100
+	Rubric           []RubricScore  `json:"rubric"`
+	STAR             *STARBreakdown `json:"star,omitempty"` // behavioral questions only
+	Strengths        []string       `json:"strengths"`
+	Improvements     []string       `json:"improvements"`
+	RewrittenExample string         `json:"rewritten_example"`
+}
+
+// EvaluateAnswerInput is a question and the user's written answer. The job description
+// comes from JobDescription or, when empty, from the user's application ApplicationID.
+type EvaluateAnswerInput struct {
+	UserID         uint
+	Question       string
+	Answer         string
+	QuestionType   string // behavioral (default) or technical
+	JobDescription string
+	ApplicationID  uint
+}
+
+// rubrics lists the criteria scored for each question type.
+var rubrics = map[string][]string{
+	QuestionBehavioral: {"STAR structure", "Specificity", "Relevance to the role", "Impact and results", "Clarity"},
+	QuestionTechnical:  {"Correctness", "Depth", "Specificity", "Relevance to the role", "Clarity"},
+}
+
+// InterviewService gives feedback on interview practice answers.
+type InterviewService struct {
+	appRepo     ApplicationRepository
+	completer   ai.Completer
+	chatConfig  ai.ChatConfig
+	modelPolicy ModelPolicy // nil allows every model
+}
+
+func NewInterviewService(appRepo ApplicationRepository, completer ai.Completer, chatConfig ai.ChatConfig, modelPolicy ModelPolicy) *InterviewService {
+	return &InterviewService{appRepo: appRepo, completer: completer, chatConfig: chatConfig, modelPolicy: modelPolicy}
+}
+
+func (s *InterviewService) EvaluateAnswer(ctx context.Context, input EvaluateAnswerInput) (*AnswerEvaluation, error) {
+	question := strings.TrimSpace(input.Question)
+	answer := strings.TrimSpace(input.Answer)
+	questionType := strings.ToLower(strings.TrimSpace(input.QuestionType))
+	if questionType == "" {
+		questionType = QuestionBehavioral
+	}
+	criteria, ok := rubrics[questionType]
+	if input.UserID == 0 || question == "" || answer == "" || !ok || len([]rune(answer)) > maxAnswerChars {
+		return nil, ErrInvalidInput
+	}
+	if err := checkConfiguredModel(ctx, s.modelPolicy, input.UserID, s.chatConfig); err != nil {
+		return nil, err
+	}
+
+	jd := strings.TrimSpace(input.JobDescription)
+	if jd == "" && input.ApplicationID != 0 {
+		application, err := loadApplication(ctx, s.appRepo, input.ApplicationID, input.UserID, authz.Read)
+		if err != nil {
+			return nil, err
+		}
+		if application == nil {
+			return nil, ErrApplicationNotFound
+		}
+		jd = strings.TrimSpace(fmt.Sprintf("%s at %s\n%s", application.Role, application.Company, application.JobDescription))
+	}
+	jd = truncateRunes(jd, maxJobPostingChars)
+
+	system := "You are an interview coach. Evaluate the candidate's written answer to a " + questionType + " interview question. " +
+		"Score each rubric criterion from 1 (poor) to 5 (excellent) with one or two sentences of feedback. " +
+		"Respond with a JSON object: {\"overall_score\": integer 0-100, " +
+		"\"rubric\": [{\"criterion\": string, \"score\": integer, \"feedback\": string}], "
+	if questionType == QuestionBehavioral {
+		system += "\"star\": {\"situation\": string, \"task\": string, \"action\": string, \"result\": string} " +
+			"(summarize each part as the answer states it; use an empty string for parts that are missing), "
+	}
+	system += "\"strengths\": [string], \"improvements\": [string], " +
+		"\"rewritten_example\": string (a stronger version of the answer in the first person, keeping the candidate's facts; " +
+		"mark figures the candidate should fill in as [placeholder] instead of inventing them)}. " +
+		"Rubric criteria: " + strings.Join(criteria, "; ") + "."
+	if jd == "" {
+		system += " No job description was given; judge relevance to the question alone."
+	}
+
+	var prompt strings.Builder
+	if jd != "" {
+		fmt.Fprintf(&prompt, "Target job description:\n%s\n\n", jd)
+	}
+	fmt.Fprintf(&prompt, "Question: %s\n\nAnswer:\n%s", question, answer)
+
+	raw, err := s.completer.Complete(ctx, s.chatConfig, []ai.ChatMessage{
+		{Role: "system", Content: system},
+		{Role: "user", Content: prompt.String()},
+	})
+	if err != nil {
+		return nil, err
+	}
+	evaluation := &AnswerEvaluation{}
+	if err := decodeLLMJSON(raw, evaluation); err != nil {
+		return nil, ErrEvaluationUnparseable
+	}
+	evaluation.QuestionType = questionType
+	evaluation.OverallScore = max(0, min(100, evaluation.OverallScore))
+	for i := range evaluation.Rubric {
+		evaluation.Rubric[i].Score = max(1, min(5, evaluation.Rubric[i].Score))
+	}
+	if questionType != QuestionBehavioral {
+		evaluation.STAR = nil
+	}
+	return evaluation, nil
+}
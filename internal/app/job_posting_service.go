@@ -0,0 +1,521 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"gopherai-resume/internal/ai"
+	"gopherai-resume/internal/model"
+	"gopherai-resume/internal/pkg/apperr"
+	"gopherai-resume/internal/repository"
+)
+
+var ErrJobPostingNotFound = apperr.NotFound(apperr.CodeJobPostingNotFound, "job posting not found")
+
+const (
+	maxJobPostingTags   = 20
+	maxScreeningResumes = 20
+	maxJobPostingChars  = 20000
+)
+
+// ParsedJobPosting is the structured form of a job description extracted by the LLM.
+type ParsedJobPosting struct {
+	Title              string   `json:"title"`
+	Company            string   `json:"company"`
+	Location           string   `json:"location"`
+	Seniority          string   `json:"seniority"`
+	EmploymentType     string   `json:"employment_type"`
+	MinYearsExperience int      `json:"min_years_experience"`
+	RequiredSkills     []string `json:"required_skills"`
+	NiceToHaveSkills   []string `json:"nice_to_have_skills"`
+	Responsibilities   []string `json:"responsibilities"`
+	Summary            string   `json:"summary"`
+}
+
+// JobPostingLinks point from a posting into the workflows that use it.
+type JobPostingLinks struct {
+	Screen     string `json:"screen"`
+	Screenings string `json:"screenings"`
+	Reports    string `json:"reports"`
+	Track      string `json:"track"`
+}
+
+// JobPostingDetail is a posting with its tags, parsed fields and workflow links.
+type JobPostingDetail struct {
+	model.JobPosting
+	Tags   []string          `json:"tags"`
+	Parsed *ParsedJobPosting `json:"parsed"`
+	Links  JobPostingLinks   `json:"links"`
+}
+
+// ScreeningResultView is a screening result with its strengths and gaps decoded.
+type ScreeningResultView struct {
+	model.ScreeningResult
+	Strengths []string `json:"strengths"`
+	Gaps      []string `json:"gaps"`
+}
+
+type CreateJobPostingInput struct {
+	UserID      uint
+	WorkspaceID uint
+	Title       string // taken from the parsed description when empty
+	Company     string
+	Location    string
+	URL         string
+	Description string
+	Tags        []string
+}
+
+// UpdateJobPostingInput changes only the non-nil fields. A new description is re-parsed.
+type UpdateJobPostingInput struct {
+	UserID      uint
+	WorkspaceID uint
+	PostingID   uint
+	Title       *string
+	Company     *string
+	Location    *string
+	URL         *string
+	Description *string
+	Tags        []string // nil leaves tags unchanged; empty clears them
+	Archived    *bool
+}
+
+// JobPostingService maintains a workspace's shared library of job postings and screens
+// resumes against them.
+type JobPostingService struct {
+	repo         JobPostingRepository
+	workspaces   *WorkspaceService
+	rag          *RAGService
+	applications *ApplicationService
+	reports      *ScreeningReportService
+	completer    ai.Completer
+	chatConfig   ai.ChatConfig
+	modelPolicy  ModelPolicy // nil allows every model
+}
+
+func NewJobPostingService(
+	repo JobPostingRepository,
+	workspaces *WorkspaceService,
+	rag *RAGService,
+	applications *ApplicationService,
+	reports *ScreeningReportService,
+	completer ai.Completer,
+	chatConfig ai.ChatConfig,
+	modelPolicy ModelPolicy,
+) *JobPostingService {
+	return &JobPostingService{
+		repo:         repo,
+		workspaces:   workspaces,
+		rag:          rag,
+		applications: applications,
+		reports:      reports,
+		completer:    completer,
+		chatConfig:   chatConfig,
+		modelPolicy:  modelPolicy,
+	}
+}
+
+// Create parses the description and adds the posting to the library. Recruiters and above only.
+func (s *JobPostingService) Create(ctx context.Context, input CreateJobPostingInput) (*JobPostingDetail, error) {
+	description := strings.TrimSpace(input.Description)
+	if description == "" {
+		return nil, ErrInvalidInput
+	}
+	if _, err := s.workspaces.Authorize(ctx, input.WorkspaceID, input.UserID, WorkspaceRecruiter); err != nil {
+		return nil, err
+	}
+	tags, err := normalizeTags(input.Tags)
+	if err != nil {
+		return nil, err
+	}
+	parsed, parsedJSON, err := s.parse(ctx, input.UserID, description)
+	if err != nil {
+		return nil, err
+	}
+
+	posting := &model.JobPosting{
+		WorkspaceID: input.WorkspaceID,
+		CreatedBy:   input.UserID,
+		Title:       orDefault(strings.TrimSpace(input.Title), parsed.Title),
+		Company:     orDefault(strings.TrimSpace(input.Company), parsed.Company),
+		Location:    orDefault(strings.TrimSpace(input.Location), parsed.Location),
+		URL:         strings.TrimSpace(input.URL),
+		Description: description,
+		Parsed:      parsedJSON,
+	}
+	if posting.Title == "" {
+		return nil, ErrInvalidInput
+	}
+	if err := s.repo.Create(ctx, posting, tags); err != nil {
+		return nil, err
+	}
+	return s.detail(posting, tags), nil
+}
+
+// List returns the workspace's postings. archived is "" (active only), "only" or "all".
+func (s *JobPostingService) List(ctx context.Context, userID, workspaceID uint, tag, query, archived string) ([]JobPostingDetail, error) {
+	if _, err := s.workspaces.Authorize(ctx, workspaceID, userID, WorkspaceMember); err != nil {
+		return nil, err
+	}
+	filter := repository.JobPostingFilter{
+		Tag:   strings.ToLower(strings.TrimSpace(tag)),
+		Query: strings.TrimSpace(query),
+	}
+	switch archived {
+	case "":
+	case "only":
+		filter.OnlyArchived = true
+	case "all":
+		filter.IncludeArchived = true
+	default:
+		return nil, ErrInvalidInput
+	}
+	postings, err := s.repo.ListByWorkspaceID(ctx, workspaceID, filter)
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]uint, 0, len(postings))
+	for _, p := range postings {
+		ids = append(ids, p.ID)
+	}
+	tags, err := s.repo.ListTags(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	list := make([]JobPostingDetail, 0, len(postings))
+	for i := range postings {
+		list = append(list, *s.detail(&postings[i], tags[postings[i].ID]))
+	}
+	return list, nil
+}
+
+func (s *JobPostingService) Get(ctx context.Context, userID, workspaceID, postingID uint) (*JobPostingDetail, error) {
+	if _, err := s.workspaces.Authorize(ctx, workspaceID, userID, WorkspaceMember); err != nil {
+		return nil, err
+	}
+	posting, err := s.getPosting(ctx, workspaceID, postingID)
+	if err != nil {
+		return nil, err
+	}
+	tags, err := s.repo.ListTags(ctx, []uint{posting.ID})
+	if err != nil {
+		return nil, err
+	}
+	return s.detail(posting, tags[posting.ID]), nil
+}
+
+func (s *JobPostingService) Update(ctx context.Context, input UpdateJobPostingInput) (*JobPostingDetail, error) {
+	if _, err := s.workspaces.Authorize(ctx, input.WorkspaceID, input.UserID, WorkspaceRecruiter); err != nil {
+		return nil, err
+	}
+	posting, err := s.getPosting(ctx, input.WorkspaceID, input.PostingID)
+	if err != nil {
+		return nil, err
+	}
+	var tags []string
+	if input.Tags != nil {
+		if tags, err = normalizeTags(input.Tags); err != nil {
+			return nil, err
+		}
+		if tags == nil {
+			tags = []string{}
+		}
+	}
+
+	if input.Title != nil {
+		if posting.Title = strings.TrimSpace(*input.Title); posting.Title == "" {
+			return nil, ErrInvalidInput
+		}
+	}
+	if input.Company != nil {
+		posting.Company = strings.TrimSpace(*input.Company)
+	}
+	if input.Location != nil {
+		posting.Location = strings.TrimSpace(*input.Location)
+	}
+	if input.URL != nil {
+		posting.URL = strings.TrimSpace(*input.URL)
+	}
+	if input.Description != nil {
+		description := strings.TrimSpace(*input.Description)
+		if description == "" {
+			return nil, ErrInvalidInput
+		}
+		if description != posting.Description {
+			_, parsedJSON, err := s.parse(ctx, input.UserID, description)
+			if err != nil {
+				return nil, err
+			}
+			posting.Description = description
+			posting.Parsed = parsedJSON
+		}
+	}
+	if input.Archived != nil {
+		switch {
+		case *input.Archived && posting.ArchivedAt == nil:
+			now := time.Now()
+			posting.ArchivedAt = &now
+		case !*input.Archived:
+			posting.ArchivedAt = nil
+		}
+	}
+
+	if err := s.repo.Update(ctx, posting, tags); err != nil {
+		return nil, err
+	}
+	return s.Get(ctx, input.UserID, input.WorkspaceID, posting.ID)
+}
+
+// Delete removes a posting with its tags, screening results and reports. Admins and above
+// only; recruiters archive instead.
+func (s *JobPostingService) Delete(ctx context.Context, userID, workspaceID, postingID uint) error {
+	if _, err := s.workspaces.Authorize(ctx, workspaceID, userID, WorkspaceAdmin); err != nil {
+		return err
+	}
+	if _, err := s.getPosting(ctx, workspaceID, postingID); err != nil {
+		return err
+	}
+	s.reports.deleteFiles(ctx, postingID)
+	return s.repo.DeleteByIDAndWorkspaceID(ctx, postingID, workspaceID)
+}
+
+// Screen scores each of the recruiter's resume documents against the posting and stores the
+// results. They are returned best score first.
+func (s *JobPostingService) Screen(ctx context.Context, userID, workspaceID, postingID uint, documentIDs []uint) ([]ScreeningResultView, error) {
+	if len(documentIDs) == 0 || len(documentIDs) > maxScreeningResumes {
+		return nil, ErrInvalidInput
+	}
+	if _, err := s.workspaces.Authorize(ctx, workspaceID, userID, WorkspaceRecruiter); err != nil {
+		return nil, err
+	}
+	posting, err := s.getPosting(ctx, workspaceID, postingID)
+	if err != nil {
+		return nil, err
+	}
+	if posting.ArchivedAt != nil {
+		return nil, ErrJobPostingNotFound
+	}
+
+	resumes := make(map[uint]string, len(documentIDs))
+	for _, id := range documentIDs {
+		if _, ok := resumes[id]; ok {
+			continue
+		}
+		text, err := s.rag.DocumentText(ctx, userID, id)
+		if errors.Is(err, ErrRAGDocumentNotFound) {
+			return nil, ErrResumeDocumentNotFound
+		}
+		if err != nil {
+			return nil, err
+		}
+		if runes := []rune(text); len(runes) > maxResumeChars {
+			text = string(runes[:maxResumeChars])
+		}
+		resumes[id] = text
+	}
+
+	if err := checkConfiguredModel(ctx, s.modelPolicy, userID, s.chatConfig); err != nil {
+		return nil, err
+	}
+	results := make([]model.ScreeningResult, 0, len(resumes))
+	views := make([]ScreeningResultView, 0, len(resumes))
+	for _, id := range documentIDs {
+		text, ok := resumes[id]
+		if !ok {
+			continue
+		}
+		delete(resumes, id)
+		view, err := s.screenOne(ctx, posting, text)
+		if err != nil {
+			return nil, err
+		}
+		view.WorkspaceID = workspaceID
+		view.JobPostingID = posting.ID
+		view.DocumentID = id
+		view.ScreenedBy = userID
+		results = append(results, view.ScreeningResult)
+		views = append(views, *view)
+	}
+	if err := s.repo.CreateScreeningResults(ctx, results); err != nil {
+		return nil, err
+	}
+	for i := range views {
+		views[i].ID = results[i].ID
+		views[i].CreatedAt = results[i].CreatedAt
+	}
+	sort.SliceStable(views, func(i, j int) bool { return views[i].Score > views[j].Score })
+	return views, nil
+}
+
+// Screenings lists a posting's screening results, best score first.
+func (s *JobPostingService) Screenings(ctx context.Context, userID, workspaceID, postingID uint) ([]ScreeningResultView, error) {
+	if _, err := s.workspaces.Authorize(ctx, workspaceID, userID, WorkspaceRecruiter); err != nil {
+		return nil, err
+	}
+	if _, err := s.getPosting(ctx, workspaceID, postingID); err != nil {
+		return nil, err
+	}
+	results, err := s.repo.ListScreeningResults(ctx, postingID)
+	if err != nil {
+		return nil, err
+	}
+	views := make([]ScreeningResultView, 0, len(results))
+	for _, r := range results {
+		view := ScreeningResultView{ScreeningResult: r}
+		_ = json.Unmarshal([]byte(r.Strengths), &view.Strengths)
+		_ = json.Unmarshal([]byte(r.Gaps), &view.Gaps)
+		views = append(views, view)
+	}
+	return views, nil
+}
+
+// Track copies a posting into the user's application tracker as a saved application.
+func (s *JobPostingService) Track(ctx context.Context, userID, workspaceID, postingID uint, resumeDocumentID *uint) (*model.Application, error) {
+	if _, err := s.workspaces.Authorize(ctx, workspaceID, userID, WorkspaceMember); err != nil {
+		return nil, err
+	}
+	posting, err := s.getPosting(ctx, workspaceID, postingID)
+	if err != nil {
+		return nil, err
+	}
+	return s.applications.Create(ctx, CreateApplicationInput{
+		UserID:           userID,
+		Company:          orDefault(posting.Company, "Unknown company"),
+		Role:             posting.Title,
+		JobURL:           posting.URL,
+		JobDescription:   posting.Description,
+		ResumeDocumentID: resumeDocumentID,
+		JobPostingID:     &posting.ID,
+	})
+}
+
+func (s *JobPostingService) getPosting(ctx context.Context, workspaceID, postingID uint) (*model.JobPosting, error) {
+	if postingID == 0 {
+		return nil, ErrInvalidInput
+	}
+	posting, err := s.repo.GetByIDAndWorkspaceID(ctx, postingID, workspaceID)
+	if err != nil {
+		return nil, err
+	}
+	if posting == nil {
+		return nil, ErrJobPostingNotFound
+	}
+	return posting, nil
+}
+
+func (s *JobPostingService) detail(posting *model.JobPosting, tags []string) *JobPostingDetail {
+	detail := &JobPostingDetail{JobPosting: *posting, Tags: tags}
+	if detail.Tags == nil {
+		detail.Tags = []string{}
+	}
+	var parsed ParsedJobPosting
+	if posting.Parsed != "" && json.Unmarshal([]byte(posting.Parsed), &parsed) == nil {
+		detail.Parsed = &parsed
+	}
+	base := fmt.Sprintf("/api/v1/workspaces/%d/jobs/%d", posting.WorkspaceID, posting.ID)
+	detail.Links = JobPostingLinks{Screen: base + "/screen", Screenings: base + "/screenings", Reports: base + "/reports", Track: base + "/track"}
+	return detail
+}
+
+// parse extracts structured fields from a description. An unparseable reply is not an
+// error; the posting is stored without parsed fields.
+func (s *JobPostingService) parse(ctx context.Context, userID uint, description string) (*ParsedJobPosting, string, error) {
+	if err := checkConfiguredModel(ctx, s.modelPolicy, userID, s.chatConfig); err != nil {
+		return nil, "", err
+	}
+	if runes := []rune(description); len(runes) > maxJobPostingChars {
+		description = string(runes[:maxJobPostingChars])
+	}
+	messages := []ai.ChatMessage{
+		{Role: "system", Content: "Extract the job posting into a JSON object with keys: title, company, location, seniority " +
+			"(intern, junior, mid, senior, staff, principal or empty), employment_type, min_years_experience (integer, 0 if not stated), " +
+			"required_skills, nice_to_have_skills, responsibilities (arrays of short strings) and summary (one sentence). " +
+			"Use empty values for anything not stated. Respond with the JSON object only."},
+		{Role: "user", Content: description},
+	}
+	raw, err := s.completer.Complete(ctx, s.chatConfig, messages)
+	if err != nil {
+		return nil, "", err
+	}
+	var parsed ParsedJobPosting
+	if err := decodeLLMJSON(raw, &parsed); err != nil {
+		return &ParsedJobPosting{}, "", nil
+	}
+	parsed.Title = strings.TrimSpace(parsed.Title)
+	parsed.Company = strings.TrimSpace(parsed.Company)
+	parsed.Location = strings.TrimSpace(parsed.Location)
+	encoded, err := json.Marshal(parsed)
+	if err != nil {
+		return nil, "", fmt.Errorf("marshal parsed job posting failed: %w", err)
+	}
+	return &parsed, string(encoded), nil
+}
+
+func (s *JobPostingService) screenOne(ctx context.Context, posting *model.JobPosting, resume string) (*ScreeningResultView, error) {
+	requirements := posting.Description
+	if posting.Parsed != "" {
+		requirements = posting.Parsed + "\n\nFull description:\n" + posting.Description
+	}
+	if runes := []rune(requirements); len(runes) > maxJobPostingChars {
+		requirements = string(runes[:maxJobPostingChars])
+	}
+	messages := []ai.ChatMessage{
+		{Role: "system", Content: "You screen a resume against a job posting. Respond with a JSON object: " +
+			"{\"score\": integer 0-100 for overall fit, \"summary\": one or two sentences, " +
+			"\"strengths\": [requirements the resume clearly meets, with the evidence], \"gaps\": [requirements missing or unclear]}. " +
+			"Judge only from the resume text."},
+		{Role: "user", Content: fmt.Sprintf("Job posting: %s\n%s\n\nResume:\n%s", posting.Title, requirements, resume)},
+	}
+	raw, err := s.completer.Complete(ctx, s.chatConfig, messages)
+	if err != nil {
+		return nil, err
+	}
+	var parsed struct {
+		Score     int      `json:"score"`
+		Summary   string   `json:"summary"`
+		Strengths []string `json:"strengths"`
+		Gaps      []string `json:"gaps"`
+	}
+	if err := decodeLLMJSON(raw, &parsed); err != nil {
+		parsed.Summary = strings.TrimSpace(raw)
+	}
+	parsed.Score = max(0, min(100, parsed.Score))
+	strengths, _ := json.Marshal(parsed.Strengths)
+	gaps, _ := json.Marshal(parsed.Gaps)
+	return &ScreeningResultView{
+		ScreeningResult: model.ScreeningResult{
+			Score:     parsed.Score,
+			Summary:   strings.TrimSpace(parsed.Summary),
+			Strengths: string(strengths),
+			Gaps:      string(gaps),
+		},
+		Strengths: parsed.Strengths,
+		Gaps:      parsed.Gaps,
+	}, nil
+}
+
+// normalizeTags lower-cases, trims and de-duplicates tags.
+func normalizeTags(raw []string) ([]string, error) {
+	var tags []string
+	seen := make(map[string]bool, len(raw))
+	for _, tag := range raw {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" || seen[tag] {
+			continue
+		}
+		if len([]rune(tag)) > 64 {
+			return nil, ErrInvalidInput
+		}
+		seen[tag] = true
+		tags = append(tags, tag)
+	}
+	if len(tags) > maxJobPostingTags {
+		return nil, ErrInvalidInput
+	}
+	return tags, nil
+}
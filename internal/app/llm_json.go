@@ -0,0 +1,21 @@
+package app
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+)
+
+var errNoJSONObject = errors.New("no json object in llm output")
+
+// decodeLLMJSON extracts the first JSON object from an LLM reply (tolerating code fences and
+// surrounding prose) and unmarshals it into out.
+func decodeLLMJSON(raw string, out interface{}) error {
+	raw = strings.TrimSpace(raw)
+	start := strings.Index(raw, "{")
+	end := strings.LastIndex(raw, "}")
+	if start < 0 || end <= start {
+		return errNoJSONObject
+	}
+	return json.Unmarshal([]byte(raw[start:end+1]), out)
+}
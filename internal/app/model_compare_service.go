@@ -0,0 +1,158 @@
+package app
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"gopherai-resume/internal/ai"
+	"gopherai-resume/internal/pkg/apperr"
+)
+
+const (
+	minCompareModels = 2
+	maxCompareModels = 4
+)
+
+var ErrModelNotAllowed = apperr.BadRequest("model is not configured for comparison")
+
+// CompareModelsInput is one prompt to send to several models. Models defaults to the first
+// configured compare models; sampling settings apply to every model.
+type CompareModelsInput struct {
+	UserID       uint
+	Prompt       string
+	SystemPrompt string
+	Models       []string
+	Temperature  *float64
+	TopP         *float64
+	MaxTokens    int
+}
+
+// ModelCompletion is one model's answer. Error is set instead of Content when that model
+// failed; the other models' answers are still returned.
+type ModelCompletion struct {
+	Model           string `json:"model"`
+	Content         string `json:"content"`
+	Error           string `json:"error,omitempty"`
+	LatencyMS       int64  `json:"latency_ms"`
+	EstimatedTokens int    `json:"estimated_tokens"`
+}
+
+// ModelComparison holds the completions in the order the models were requested.
+type ModelComparison struct {
+	Prompt  string            `json:"prompt"`
+	Results []ModelCompletion `json:"results"`
+}
+
+// ModelCompareService sends one prompt to several configured models side by side.
+type ModelCompareService struct {
+	completer  ai.Completer
+	defaultLLM ai.ChatConfig
+	models     []string
+	policy     ModelPolicy // nil allows every configured model
+	// maxPromptRunes caps the prompt and the system prompt alike; 0 is unlimited.
+	maxPromptRunes int
+}
+
+func NewModelCompareService(completer ai.Completer, defaultLLM ai.ChatConfig, models []string, policy ModelPolicy, maxPromptRunes int) *ModelCompareService {
+	return &ModelCompareService{completer: completer, defaultLLM: defaultLLM, models: models, policy: policy, maxPromptRunes: maxPromptRunes}
+}
+
+// Models lists the models a comparison may use.
+func (s *ModelCompareService) Models() []string {
+	return s.models
+}
+
+// Compare runs the prompt on 2 to 4 models concurrently, one goroutine per model, and waits
+// for all of them. Only a request that fails as a whole returns an error.
+func (s *ModelCompareService) Compare(ctx context.Context, input CompareModelsInput) (*ModelComparison, error) {
+	prompt := strings.TrimSpace(input.Prompt)
+	if input.UserID == 0 || prompt == "" {
+		return nil, ErrInvalidInput
+	}
+	system := strings.TrimSpace(input.SystemPrompt)
+	if s.maxPromptRunes > 0 && (utf8.RuneCountInString(prompt) > s.maxPromptRunes || utf8.RuneCountInString(system) > s.maxPromptRunes) {
+		return nil, ErrMessageTooLong.Withf("at most %d characters", s.maxPromptRunes)
+	}
+	if err := validateSampling(input.Temperature, input.TopP, input.MaxTokens); err != nil {
+		return nil, err
+	}
+	models, err := s.selectModels(ctx, input.UserID, input.Models)
+	if err != nil {
+		return nil, err
+	}
+
+	messages := []ai.ChatMessage{{Role: "system", Content: chatSystemPrompt}}
+	if system != "" {
+		messages[0].Content = system
+	}
+	messages = append(messages, ai.ChatMessage{Role: "user", Content: prompt})
+
+	results := make([]ModelCompletion, len(models))
+	var wg sync.WaitGroup
+	for i, model := range models {
+		wg.Add(1)
+		go func(i int, model string) {
+			defer wg.Done()
+			cfg := s.defaultLLM
+			cfg.Model = model
+			cfg.Temperature = input.Temperature
+			cfg.TopP = input.TopP
+			cfg.MaxTokens = input.MaxTokens
+			started := time.Now()
+			content, err := s.completer.Complete(ctx, cfg, messages)
+			result := ModelCompletion{Model: model, LatencyMS: time.Since(started).Milliseconds()}
+			if err != nil {
+				result.Error = err.Error()
+			} else {
+				result.Content = content
+				result.EstimatedTokens = ai.EstimateTokens(content)
+			}
+			results[i] = result
+		}(i, model)
+	}
+	wg.Wait()
+	return &ModelComparison{Prompt: prompt, Results: results}, nil
+}
+
+// selectModels validates the requested models against the configured ones and the user's
+// model policy, defaulting to the first maxCompareModels of them.
+func (s *ModelCompareService) selectModels(ctx context.Context, userID uint, requested []string) ([]string, error) {
+	if s.defaultLLM.BaseURL == "" || s.defaultLLM.APIKey == "" {
+		return nil, ErrLLMConfig
+	}
+	if len(requested) == 0 {
+		requested = s.models
+		if len(requested) > maxCompareModels {
+			requested = requested[:maxCompareModels]
+		}
+	}
+	allowed := make(map[string]bool, len(s.models))
+	for _, m := range s.models {
+		allowed[m] = true
+	}
+	seen := make(map[string]bool, len(requested))
+	models := make([]string, 0, len(requested))
+	for _, m := range requested {
+		m = strings.TrimSpace(m)
+		if m == "" || seen[m] {
+			continue
+		}
+		if !allowed[m] {
+			return nil, ErrModelNotAllowed
+		}
+		if s.policy != nil {
+			if err := s.policy.CheckModel(ctx, userID, "", m); err != nil {
+				return nil, err
+			}
+		}
+		seen[m] = true
+		models = append(models, m)
+	}
+	if len(models) < minCompareModels || len(models) > maxCompareModels {
+		return nil, ErrInvalidInput
+	}
+	return models, nil
+}
@@ -0,0 +1,214 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"gopherai-resume/internal/ai"
+	"gopherai-resume/internal/pkg/authz"
+)
+
+// maxResumeChars bounds how much resume text is put into a prompt.
+const maxResumeChars = 12000
+
+// SalaryDataPoint is one compensation figure the user collected (a salary survey, a job
+// posting's range, an offer from a peer). Amounts are annual unless Period says otherwise.
+type SalaryDataPoint struct {
+	Source   string  `json:"source"`
+	Low      float64 `json:"low"`
+	High     float64 `json:"high"`
+	Currency string  `json:"currency"`
+	Period   string  `json:"period"`
+	Note     string  `json:"note"`
+}
+
+// SalaryEstimate summarizes data points that share a currency and period.
+type SalaryEstimate struct {
+	Low      float64 `json:"low"`
+	Median   float64 `json:"median"`
+	High     float64 `json:"high"`
+	Currency string  `json:"currency"`
+	Period   string  `json:"period"`
+	Sources  int     `json:"sources"`
+}
+
+// NegotiationBriefInput asks for a negotiation brief for Role in Location, grounded in the
+// resume stored as the RAG document ResumeDocumentID and the user's salary data.
+type NegotiationBriefInput struct {
+	UserID           uint
+	SessionID        uint
+	Role             string
+	Location         string
+	ResumeDocumentID uint
+	CurrentOffer     string
+	SalaryData       []SalaryDataPoint
+	LLM              LLMOverride
+}
+
+// NegotiationService writes salary negotiation briefs into a chat session.
+type NegotiationService struct {
+	chat      *ChatService
+	docRepo   RAGDocumentRepository
+	chunkRepo RAGChunkRepository
+}
+
+func NewNegotiationService(
+	chat *ChatService,
+	docRepo RAGDocumentRepository,
+	chunkRepo RAGChunkRepository,
+) *NegotiationService {
+	return &NegotiationService{chat: chat, docRepo: docRepo, chunkRepo: chunkRepo}
+}
+
+// StreamBrief streams the brief like ChatService.StreamMessage and stores the request and
+// the brief in the session's history.
+func (s *NegotiationService) StreamBrief(
+	ctx context.Context,
+	input NegotiationBriefInput,
+	onStart func(streamID string) error,
+	onChunk func(string) error,
+) (string, error) {
+	role := strings.TrimSpace(input.Role)
+	location := strings.TrimSpace(input.Location)
+	if input.UserID == 0 || input.SessionID == 0 || role == "" || input.ResumeDocumentID == 0 {
+		return "", ErrInvalidInput
+	}
+	session, err := loadChatSession(ctx, s.chat.sessionRepo, input.SessionID, input.UserID, authz.Write)
+	if err != nil {
+		return "", err
+	}
+	if session == nil {
+		return "", ErrSessionNotFound
+	}
+	resume, err := s.resumeText(ctx, input.UserID, input.ResumeDocumentID)
+	if err != nil {
+		return "", err
+	}
+	cfg, err := s.chat.resolveLLM(ctx, input.UserID, session, input.LLM)
+	if err != nil {
+		return "", err
+	}
+
+	var data strings.Builder
+	for i, p := range input.SalaryData {
+		fmt.Fprintf(&data, "%d. %s: %s", i+1, orDefault(strings.TrimSpace(p.Source), "unnamed source"), formatSalaryRange(p))
+		if note := strings.TrimSpace(p.Note); note != "" {
+			data.WriteString(" (" + note + ")")
+		}
+		data.WriteString("\n")
+	}
+	estimate := EstimateSalary(input.SalaryData)
+	estimateText := "No consistent estimate: either no data was provided or the sources use different currencies or periods."
+	if estimate != nil {
+		estimateText = fmt.Sprintf("%s %.0f - %.0f per %s (median %.0f, from %d sources)",
+			estimate.Currency, estimate.Low, estimate.High, estimate.Period, estimate.Median, estimate.Sources)
+	}
+
+	var prompt strings.Builder
+	fmt.Fprintf(&prompt, "Target role: %s\n", role)
+	if location != "" {
+		fmt.Fprintf(&prompt, "Location: %s\n", location)
+	}
+	if offer := strings.TrimSpace(input.CurrentOffer); offer != "" {
+		fmt.Fprintf(&prompt, "Current offer: %s\n", offer)
+	}
+	fmt.Fprintf(&prompt, "\nSalary data provided by the user:\n%s", orDefault(data.String(), "(none)\n"))
+	fmt.Fprintf(&prompt, "Computed range: %s\n", estimateText)
+	fmt.Fprintf(&prompt, "\nResume:\n%s\n", resume)
+
+	promptMessages := []ai.ChatMessage{
+		{Role: "system", Content: "You are a salary negotiation coach. Write a concise negotiation brief in Markdown with these sections: " +
+			"Estimated range, Target and walk-away numbers, Talking points, Responses to likely pushback. " +
+			"Base the range only on the salary data and computed range given; say so when the data is thin and do not invent market figures. " +
+			"Ground every talking point in a specific achievement from the resume and quote its numbers."},
+		{Role: "user", Content: prompt.String()},
+	}
+
+	request := "Negotiation brief: " + role
+	if location != "" {
+		request += " in " + location
+	}
+	return s.chat.streamReply(ctx, input.UserID, input.SessionID, request, cfg, promptMessages, onStart, onChunk)
+}
+
+func (s *NegotiationService) resumeText(ctx context.Context, userID, docID uint) (string, error) {
+	doc, err := loadRAGDocument(ctx, s.docRepo, docID, userID, authz.Write)
+	if err != nil {
+		return "", err
+	}
+	if doc == nil {
+		return "", ErrResumeDocumentNotFound
+	}
+	chunks, err := s.chunkRepo.ListByDocumentIDs(ctx, []uint{doc.ID})
+	if err != nil {
+		return "", err
+	}
+	text := strings.TrimSpace(joinChunks(chunks, documentChunking(doc).overlap))
+	if runes := []rune(text); len(runes) > maxResumeChars {
+		text = string(runes[:maxResumeChars])
+	}
+	return text, nil
+}
+
+// EstimateSalary returns the spread of data points when they all share a currency and period,
+// or nil otherwise. A point with only one bound counts as a single figure.
+func EstimateSalary(points []SalaryDataPoint) *SalaryEstimate {
+	var mids []float64
+	estimate := &SalaryEstimate{}
+	for _, p := range points {
+		low, high := p.Low, p.High
+		if low <= 0 {
+			low = high
+		}
+		if high <= 0 {
+			high = low
+		}
+		if low <= 0 {
+			continue
+		}
+		currency := strings.ToUpper(strings.TrimSpace(p.Currency))
+		period := orDefault(strings.ToLower(strings.TrimSpace(p.Period)), "year")
+		if len(mids) == 0 {
+			estimate.Currency, estimate.Period = currency, period
+			estimate.Low, estimate.High = low, high
+		} else if currency != estimate.Currency || period != estimate.Period {
+			return nil
+		}
+		estimate.Low = min(estimate.Low, low)
+		estimate.High = max(estimate.High, high)
+		mids = append(mids, (low+high)/2)
+	}
+	if len(mids) == 0 {
+		return nil
+	}
+	sort.Float64s(mids)
+	if n := len(mids); n%2 == 1 {
+		estimate.Median = mids[n/2]
+	} else {
+		estimate.Median = (mids[n/2-1] + mids[n/2]) / 2
+	}
+	estimate.Sources = len(mids)
+	return estimate
+}
+
+func formatSalaryRange(p SalaryDataPoint) string {
+	period := orDefault(strings.TrimSpace(p.Period), "year")
+	currency := strings.TrimSpace(p.Currency)
+	switch {
+	case p.Low > 0 && p.High > 0:
+		return fmt.Sprintf("%s %.0f - %.0f per %s", currency, p.Low, p.High, period)
+	case p.Low > 0 || p.High > 0:
+		return fmt.Sprintf("%s %.0f per %s", currency, max(p.Low, p.High), period)
+	default:
+		return "no figure"
+	}
+}
+
+func orDefault(value, fallback string) string {
+	if value == "" {
+		return fallback
+	}
+	return value
+}
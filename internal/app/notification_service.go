@@ -0,0 +1,312 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"gopherai-resume/internal/model"
+	"gopherai-resume/internal/platform/mailer"
+)
+
+// Notification kinds. Password reset and email verification are account emails and cannot
+// be turned off.
+const (
+	NotifyPasswordReset       = "password_reset"
+	NotifyEmailVerification   = "email_verification"
+	NotifyIngestionComplete   = "ingestion_complete"
+	NotifyScreeningReport     = "screening_report"
+	NotifyApplicationReminder = "application_reminder"
+)
+
+// Outbox statuses.
+const (
+	OutboxPending = "pending"
+	OutboxSent    = "sent"
+	OutboxFailed  = "failed"
+)
+
+// notificationFooter closes the emails users can turn off.
+const notificationFooter = "\n\nYou can choose which emails you receive in your notification preferences " +
+	"(PATCH /api/v1/notifications/preferences)."
+
+const (
+	outboxBatchSize   = 50
+	outboxMaxBackoff  = time.Hour
+	maxOutboxErrChars = 512
+	// outboxClaimLease is how long a claimed email is left to its sender before another
+	// may retry it.
+	outboxClaimLease = 5 * time.Minute
+	// emailLinkPlaceholder marks where an email's one-time link goes, see NotifyWithLink.
+	emailLinkPlaceholder = "{{link}}"
+)
+
+// defaultNotificationPreference applies to users who never saved preferences. Ingestion is
+// opt-in because uploads already report their result in the response.
+var defaultNotificationPreference = model.NotificationPreference{
+	IngestionComplete:    false,
+	ScreeningReports:     true,
+	ApplicationReminders: true,
+}
+
+// Notifier queues an email to a user. Services hold it as an interface so a nil notifier
+// disables notifications.
+type Notifier interface {
+	Notify(ctx context.Context, userID uint, kind, subject, body string) error
+}
+
+// UpdateNotificationPreferencesInput changes the non-nil preferences.
+type UpdateNotificationPreferencesInput struct {
+	UserID               uint
+	IngestionComplete    *bool
+	ScreeningReports     *bool
+	ApplicationReminders *bool
+}
+
+// EventPusher delivers an event to the user's open live connections, such as the chat
+// WebSocket, and returns how many took it.
+type EventPusher interface {
+	Push(userID uint, event string, payload interface{}) int
+}
+
+// EventNotification is pushed to a user's live connections for every optional
+// notification, whether or not it is also emailed.
+const EventNotification = "notification"
+
+// LinkIssuer issues a one-time link for the user, such as a password reset link, when an
+// email that carries it is sent.
+type LinkIssuer func(ctx context.Context, userID uint) (string, error)
+
+// NotificationService writes emails to the outbox, honouring user preferences, and delivers
+// the outbox through a mailer.
+type NotificationService struct {
+	repo        NotificationRepository
+	users       UserRepository
+	mailer      mailer.Mailer
+	baseURL     string
+	maxAttempts int
+	links       map[string]LinkIssuer
+	events      EventPusher // nil pushes nothing
+}
+
+func NewNotificationService(
+	repo NotificationRepository,
+	users UserRepository,
+	m mailer.Mailer,
+	baseURL string,
+	maxAttempts int,
+	events EventPusher,
+) *NotificationService {
+	if maxAttempts <= 0 {
+		maxAttempts = 5
+	}
+	return &NotificationService{
+		repo:        repo,
+		users:       users,
+		mailer:      m,
+		baseURL:     strings.TrimRight(baseURL, "/"),
+		maxAttempts: maxAttempts,
+		links:       make(map[string]LinkIssuer),
+		events:      events,
+	}
+}
+
+// RegisterLink sets how links of purpose are issued. It is called while the services are
+// built, before any email is delivered.
+func (s *NotificationService) RegisterLink(purpose string, issue LinkIssuer) {
+	s.links[purpose] = issue
+}
+
+// Link returns an absolute link into the app for use in email bodies.
+func (s *NotificationService) Link(path string) string {
+	return s.baseURL + path
+}
+
+// Notify queues an email of kind to the user unless their preferences turn it off. It only
+// writes to the outbox; delivery happens in DeliverDue. Optional notifications are also
+// pushed to the user's live connections as EventNotification.
+func (s *NotificationService) Notify(ctx context.Context, userID uint, kind, subject, body string) error {
+	return s.queue(ctx, userID, kind, subject, body, "")
+}
+
+// NotifyWithLink queues an email whose body holds {{link}} where a link of purpose, see
+// RegisterLink, is put when the email is sent. Each delivery attempt issues a new link.
+func (s *NotificationService) NotifyWithLink(ctx context.Context, userID uint, kind, subject, body, purpose string) error {
+	if _, ok := s.links[purpose]; !ok || !strings.Contains(body, emailLinkPlaceholder) {
+		return ErrInvalidInput
+	}
+	return s.queue(ctx, userID, kind, subject, body, purpose)
+}
+
+func (s *NotificationService) queue(ctx context.Context, userID uint, kind, subject, body, linkPurpose string) error {
+	if userID == 0 || strings.TrimSpace(subject) == "" {
+		return ErrInvalidInput
+	}
+	account := kind == NotifyPasswordReset || kind == NotifyEmailVerification
+	if !account && s.events != nil {
+		s.events.Push(userID, EventNotification, map[string]string{"kind": kind, "subject": subject})
+	}
+	user, err := s.users.GetByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if user == nil || user.Email == "" {
+		return nil
+	}
+	if !account {
+		pref, err := s.Preferences(ctx, userID)
+		if err != nil {
+			return err
+		}
+		if !notificationEnabled(pref, kind) {
+			return nil
+		}
+	}
+	return s.repo.CreateOutbox(ctx, &model.EmailOutbox{
+		UserID:        userID,
+		Kind:          kind,
+		ToAddress:     user.Email,
+		Subject:       truncateRunes(subject, 256),
+		Body:          body,
+		LinkPurpose:   linkPurpose,
+		Status:        OutboxPending,
+		NextAttemptAt: time.Now(),
+	})
+}
+
+func notificationEnabled(pref *model.NotificationPreference, kind string) bool {
+	switch kind {
+	case NotifyIngestionComplete:
+		return pref.IngestionComplete
+	case NotifyScreeningReport:
+		return pref.ScreeningReports
+	case NotifyApplicationReminder:
+		return pref.ApplicationReminders
+	}
+	return true
+}
+
+// Preferences returns the user's saved preferences, or the defaults.
+func (s *NotificationService) Preferences(ctx context.Context, userID uint) (*model.NotificationPreference, error) {
+	if userID == 0 {
+		return nil, ErrInvalidInput
+	}
+	pref, err := s.repo.GetPreference(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if pref == nil {
+		defaults := defaultNotificationPreference
+		defaults.UserID = userID
+		return &defaults, nil
+	}
+	return pref, nil
+}
+
+func (s *NotificationService) UpdatePreferences(ctx context.Context, input UpdateNotificationPreferencesInput) (*model.NotificationPreference, error) {
+	pref, err := s.Preferences(ctx, input.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if input.IngestionComplete != nil {
+		pref.IngestionComplete = *input.IngestionComplete
+	}
+	if input.ScreeningReports != nil {
+		pref.ScreeningReports = *input.ScreeningReports
+	}
+	if input.ApplicationReminders != nil {
+		pref.ApplicationReminders = *input.ApplicationReminders
+	}
+	if err := s.repo.SavePreference(ctx, pref); err != nil {
+		return nil, err
+	}
+	return pref, nil
+}
+
+// DeliverDue sends up to one batch of due outbox emails and returns how many were sent.
+// Each email is claimed first, so instances sharing the outbox never send it twice. A
+// failed send is retried with exponential backoff until maxAttempts, then marked failed.
+func (s *NotificationService) DeliverDue(ctx context.Context) (int, error) {
+	now := time.Now()
+	due, err := s.repo.ListDueOutbox(ctx, OutboxPending, now, outboxBatchSize)
+	if err != nil {
+		return 0, err
+	}
+	sent := 0
+	for i := range due {
+		if ctx.Err() != nil {
+			return sent, ctx.Err()
+		}
+		email := &due[i]
+		claimed, err := s.repo.ClaimOutbox(ctx, email.ID, OutboxPending, now, time.Now().Add(outboxClaimLease))
+		if err != nil {
+			return sent, err
+		}
+		if !claimed {
+			continue
+		}
+		email.Attempts++
+		sendErr := s.send(ctx, email)
+		now := time.Now()
+		switch {
+		case sendErr == nil:
+			email.Status = OutboxSent
+			email.SentAt = &now
+			email.LastError = ""
+			sent++
+		case email.Attempts >= s.maxAttempts:
+			email.Status = OutboxFailed
+			email.LastError = truncateRunes(sendErr.Error(), maxOutboxErrChars)
+		default:
+			email.LastError = truncateRunes(sendErr.Error(), maxOutboxErrChars)
+			backoff := time.Minute << (email.Attempts - 1)
+			if backoff > outboxMaxBackoff {
+				backoff = outboxMaxBackoff
+			}
+			email.NextAttemptAt = now.Add(backoff)
+		}
+		if sendErr != nil {
+			log.Printf("send email %d (%s) failed on attempt %d: %v", email.ID, email.Kind, email.Attempts, sendErr)
+		}
+		if err := s.repo.UpdateOutbox(ctx, email); err != nil {
+			return sent, err
+		}
+	}
+	return sent, nil
+}
+
+// send renders the email, issuing its link if it has one, and hands it to the mailer. The
+// rendered body is not stored.
+func (s *NotificationService) send(ctx context.Context, email *model.EmailOutbox) error {
+	body := email.Body
+	if email.LinkPurpose != "" {
+		issue, ok := s.links[email.LinkPurpose]
+		if !ok {
+			return fmt.Errorf("no link issuer for %q", email.LinkPurpose)
+		}
+		link, err := issue(ctx, email.UserID)
+		if err != nil {
+			return err
+		}
+		body = strings.ReplaceAll(body, emailLinkPlaceholder, link)
+	}
+	return s.mailer.Send(ctx, mailer.Message{To: email.ToAddress, Subject: email.Subject, Body: body})
+}
+
+// notify queues an email through n and logs a failure instead of returning it, for
+// notifications that must not fail the operation that triggered them.
+func notify(ctx context.Context, n Notifier, userID uint, kind, subject, body string) {
+	if n == nil {
+		return
+	}
+	if err := n.Notify(ctx, userID, kind, subject, body); err != nil {
+		log.Printf("queue %s notification for user %d failed: %v", kind, userID, err)
+	}
+}
+
+// formatEmailTime renders a time for email bodies.
+func formatEmailTime(t time.Time) string {
+	return t.UTC().Format("2006-01-02 15:04 UTC")
+}
@@ -0,0 +1,280 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+
+	"gopherai-resume/internal/ai"
+	"gopherai-resume/internal/model"
+	"gopherai-resume/internal/pkg/apperr"
+	"gopherai-resume/internal/pkg/authz"
+	"gopherai-resume/internal/platform/github"
+)
+
+var (
+	ErrPortfolioAnalysisNotFound = apperr.NotFound(apperr.CodePortfolioNotFound, "portfolio analysis not found")
+	ErrPortfolioNoRepos          = apperr.New(http.StatusUnprocessableEntity, apperr.CodeBadRequest, "no public repositories to analyze")
+	ErrGitHubNotFound            = apperr.NotFound(apperr.CodeRepoNotFound, "github resource not found")
+	ErrGitHubRateLimited         = apperr.New(http.StatusServiceUnavailable, apperr.CodeInternalServer, "github rate limit exceeded")
+)
+
+// githubError maps the GitHub client's errors to the ones clients are shown.
+func githubError(err error) error {
+	switch {
+	case errors.Is(err, github.ErrNotFound):
+		return ErrGitHubNotFound.Wrap(err)
+	case errors.Is(err, github.ErrRateLimited):
+		return ErrGitHubRateLimited.Wrap(err)
+	}
+	return err
+}
+
+const maxReadmeBytes = 6000
+
+// RepoSource lists and reads public repositories.
+type RepoSource interface {
+	ListUserRepos(ctx context.Context, username string, limit int) ([]github.Repo, error)
+	GetRepo(ctx context.Context, owner, name string) (*github.Repo, error)
+	GetReadme(ctx context.Context, owner, name string, maxBytes int64) (string, error)
+}
+
+// PortfolioProject is the LLM's summary of one repository.
+type PortfolioProject struct {
+	Name         string   `json:"name"`
+	URL          string   `json:"url"`
+	Summary      string   `json:"summary"`
+	Technologies []string `json:"technologies"`
+	Highlights   []string `json:"highlights"`
+}
+
+// PortfolioAnalysisResult is a stored analysis with its decoded projects and bullets.
+type PortfolioAnalysisResult struct {
+	model.PortfolioAnalysis
+	Projects []PortfolioProject `json:"projects"`
+	Bullets  []string           `json:"bullets"`
+}
+
+// AnalyzePortfolioInput names a GitHub user or a single repository URL. With Ingest the
+// analysis is also added to RAG (in RAGSessionID when set) for interview preparation.
+type AnalyzePortfolioInput struct {
+	UserID       uint
+	Username     string
+	RepoURL      string
+	Ingest       bool
+	RAGSessionID uint
+}
+
+// PortfolioService summarizes a user's public GitHub projects and suggests resume bullets.
+type PortfolioService struct {
+	repo        PortfolioAnalysisRepository
+	repos       RepoSource
+	rag         *RAGService
+	completer   ai.Completer
+	chatConfig  ai.ChatConfig
+	modelPolicy ModelPolicy // nil allows every model
+	maxRepos    int
+}
+
+func NewPortfolioService(
+	repo PortfolioAnalysisRepository,
+	repos RepoSource,
+	rag *RAGService,
+	completer ai.Completer,
+	chatConfig ai.ChatConfig,
+	modelPolicy ModelPolicy,
+	maxRepos int,
+) *PortfolioService {
+	if maxRepos <= 0 {
+		maxRepos = 8
+	}
+	return &PortfolioService{repo: repo, repos: repos, rag: rag, completer: completer, chatConfig: chatConfig, modelPolicy: modelPolicy, maxRepos: maxRepos}
+}
+
+func (s *PortfolioService) Analyze(ctx context.Context, input AnalyzePortfolioInput) (*PortfolioAnalysisResult, error) {
+	username := strings.TrimSpace(input.Username)
+	repoURL := strings.TrimSpace(input.RepoURL)
+	if input.UserID == 0 || (username == "") == (repoURL == "") {
+		return nil, ErrInvalidInput
+	}
+	if err := checkConfiguredModel(ctx, s.modelPolicy, input.UserID, s.chatConfig); err != nil {
+		return nil, err
+	}
+
+	repos, source, err := s.fetchRepos(ctx, username, repoURL)
+	if err != nil {
+		return nil, err
+	}
+	if len(repos) == 0 {
+		return nil, ErrPortfolioNoRepos
+	}
+
+	var repoData strings.Builder
+	for _, r := range repos {
+		owner, name, _ := github.ParseRepoURL(r.HTMLURL)
+		readme, err := s.repos.GetReadme(ctx, owner, name, maxReadmeBytes)
+		if err != nil {
+			return nil, githubError(err)
+		}
+		fmt.Fprintf(&repoData, "=== %s (%s) ===\nURL: %s\nLanguage: %s\nStars: %d, forks: %d\nTopics: %s\nDescription: %s\nREADME:\n%s\n\n",
+			r.FullName, r.PushedAt.Format("2006-01"), r.HTMLURL, r.Language, r.Stars, r.Forks,
+			strings.Join(r.Topics, ", "), r.Description, orDefault(strings.TrimSpace(readme), "(none)"))
+	}
+
+	messages := []ai.ChatMessage{
+		{Role: "system", Content: "You review a developer's GitHub projects for their resume. " +
+			"Respond with a JSON object: {\"summary\": string (2-3 sentences on the developer's strengths), " +
+			"\"projects\": [{\"name\": string, \"url\": string, \"summary\": string, \"technologies\": [string], \"highlights\": [string]}], " +
+			"\"bullets\": [string] (3-8 resume bullet points starting with a strong verb)}. " +
+			"Include only notable projects. Use only facts from the repository data; do not invent usage numbers."},
+		{Role: "user", Content: repoData.String()},
+	}
+	raw, err := s.completer.Complete(ctx, s.chatConfig, messages)
+	if err != nil {
+		return nil, err
+	}
+	var parsed struct {
+		Summary  string             `json:"summary"`
+		Projects []PortfolioProject `json:"projects"`
+		Bullets  []string           `json:"bullets"`
+	}
+	if err := decodeLLMJSON(raw, &parsed); err != nil {
+		parsed.Summary = strings.TrimSpace(raw)
+	}
+
+	projectsJSON, err := json.Marshal(parsed.Projects)
+	if err != nil {
+		return nil, fmt.Errorf("marshal portfolio projects failed: %w", err)
+	}
+	bulletsJSON, err := json.Marshal(parsed.Bullets)
+	if err != nil {
+		return nil, fmt.Errorf("marshal portfolio bullets failed: %w", err)
+	}
+	analysis := &model.PortfolioAnalysis{
+		UserID:   input.UserID,
+		Source:   source,
+		Summary:  strings.TrimSpace(parsed.Summary),
+		Projects: string(projectsJSON),
+		Bullets:  string(bulletsJSON),
+	}
+	result := &PortfolioAnalysisResult{PortfolioAnalysis: *analysis, Projects: parsed.Projects, Bullets: parsed.Bullets}
+
+	// The analysis is saved only once its document is ingested, so a failed ingest leaves
+	// nothing behind that claims to be in RAG.
+	if input.Ingest {
+		ingest, err := s.rag.Ingest(ctx, IngestInput{
+			UserID:    input.UserID,
+			SessionID: input.RAGSessionID,
+			Name:      "GitHub portfolio: " + source,
+			Content:   renderPortfolio(result),
+		})
+		if err != nil {
+			return nil, err
+		}
+		analysis.DocumentID = &ingest.Document.ID
+	}
+	if err := s.repo.Create(ctx, analysis); err != nil {
+		if analysis.DocumentID != nil {
+			if delErr := s.rag.DeleteDocument(context.WithoutCancel(ctx), input.UserID, *analysis.DocumentID); delErr != nil {
+				log.Printf("delete portfolio document %d after failed save: %v", *analysis.DocumentID, delErr)
+			}
+		}
+		return nil, err
+	}
+	result.PortfolioAnalysis = *analysis
+	return result, nil
+}
+
+// fetchRepos returns the repositories to analyze and a label for the source.
+func (s *PortfolioService) fetchRepos(ctx context.Context, username, repoURL string) ([]github.Repo, string, error) {
+	if repoURL != "" {
+		owner, name, err := github.ParseRepoURL(repoURL)
+		if err != nil {
+			return nil, "", ErrInvalidInput
+		}
+		repo, err := s.repos.GetRepo(ctx, owner, name)
+		if err != nil {
+			return nil, "", githubError(err)
+		}
+		return []github.Repo{*repo}, repo.HTMLURL, nil
+	}
+
+	all, err := s.repos.ListUserRepos(ctx, username, 100)
+	if err != nil {
+		return nil, "", githubError(err)
+	}
+	var repos []github.Repo
+	for _, r := range all {
+		if !r.Fork && !r.Archived {
+			repos = append(repos, r)
+		}
+	}
+	// Most starred first, ties broken by recent activity; the API already sorts by push date.
+	sort.SliceStable(repos, func(i, j int) bool { return repos[i].Stars > repos[j].Stars })
+	if len(repos) > s.maxRepos {
+		repos = repos[:s.maxRepos]
+	}
+	return repos, username, nil
+}
+
+func (s *PortfolioService) List(ctx context.Context, userID uint) ([]model.PortfolioAnalysis, error) {
+	if userID == 0 {
+		return nil, ErrInvalidInput
+	}
+	return s.repo.ListByUserID(ctx, userID)
+}
+
+func (s *PortfolioService) Get(ctx context.Context, userID, analysisID uint) (*PortfolioAnalysisResult, error) {
+	return s.get(ctx, userID, analysisID, authz.Read)
+}
+
+func (s *PortfolioService) get(ctx context.Context, userID, analysisID uint, action authz.Action) (*PortfolioAnalysisResult, error) {
+	if userID == 0 || analysisID == 0 {
+		return nil, ErrInvalidInput
+	}
+	analysis, err := loadPortfolioAnalysis(ctx, s.repo, analysisID, userID, action)
+	if err != nil {
+		return nil, err
+	}
+	if analysis == nil {
+		return nil, ErrPortfolioAnalysisNotFound
+	}
+	result := &PortfolioAnalysisResult{PortfolioAnalysis: *analysis}
+	_ = json.Unmarshal([]byte(analysis.Projects), &result.Projects)
+	_ = json.Unmarshal([]byte(analysis.Bullets), &result.Bullets)
+	return result, nil
+}
+
+// Delete removes the analysis; a RAG document ingested from it is kept.
+func (s *PortfolioService) Delete(ctx context.Context, userID, analysisID uint) error {
+	if _, err := s.get(ctx, userID, analysisID, authz.Write); err != nil {
+		return err
+	}
+	return s.repo.DeleteByIDAndUserID(ctx, analysisID, userID)
+}
+
+func renderPortfolio(result *PortfolioAnalysisResult) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "GitHub portfolio (%s)\n\n%s\n", result.Source, result.Summary)
+	for _, p := range result.Projects {
+		fmt.Fprintf(&b, "\nProject: %s (%s)\n%s\n", p.Name, p.URL, p.Summary)
+		if len(p.Technologies) > 0 {
+			fmt.Fprintf(&b, "Technologies: %s\n", strings.Join(p.Technologies, ", "))
+		}
+		for _, h := range p.Highlights {
+			fmt.Fprintf(&b, "- %s\n", h)
+		}
+	}
+	if len(result.Bullets) > 0 {
+		b.WriteString("\nSuggested resume bullets:\n")
+		for _, bullet := range result.Bullets {
+			fmt.Fprintf(&b, "- %s\n", bullet)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,146 @@
+package app
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"gopherai-resume/internal/pkg/apperr"
+	"gopherai-resume/internal/pkg/limitwarn"
+)
+
+var ErrQuotaExceeded = apperr.New(http.StatusTooManyRequests, apperr.CodeQuotaExceeded, "daily quota exceeded")
+
+// Quota metrics tracked per user per day.
+const (
+	QuotaEmbeddingInputs  = "embedding_inputs"
+	QuotaVisionInferences = "vision_inferences"
+	QuotaVisionPixels     = "vision_pixels"
+	QuotaProxyTokens      = "proxy_tokens"
+)
+
+// UsageCounter persists per-user daily counters.
+type UsageCounter interface {
+	Add(ctx context.Context, userID uint, metric string, day time.Time, amount int64) (int64, error)
+	Get(ctx context.Context, userID uint, metric string, day time.Time) (int64, error)
+}
+
+// QuotaService enforces per-user daily limits; a limit of 0 means unlimited.
+type QuotaService struct {
+	counter UsageCounter
+	limits  map[string]int64
+	now     func() time.Time
+}
+
+func NewQuotaService(counter UsageCounter, limits map[string]int64) *QuotaService {
+	return &QuotaService{
+		counter: counter,
+		limits:  limits,
+		now:     time.Now,
+	}
+}
+
+// MetricUsage is the current day's usage for one metric.
+type MetricUsage struct {
+	Used  int64 `json:"used"`
+	Limit int64 `json:"limit"` // 0 = unlimited
+}
+
+// Metered reports whether usage is recorded at all. Without a counter every limit is
+// unenforced.
+func (s *QuotaService) Metered() bool {
+	return s != nil && s.counter != nil
+}
+
+// Consume records amount against metric, failing with ErrQuotaExceeded if it would pass the limit.
+// Rejected amounts are not counted. Nearing the limit adds a limitwarn warning to ctx.
+func (s *QuotaService) Consume(ctx context.Context, userID uint, metric string, amount int64) error {
+	if s == nil || s.counter == nil || amount <= 0 {
+		return nil
+	}
+	day := s.now()
+	limit := s.limits[metric]
+	total, err := s.counter.Add(ctx, userID, metric, day, amount)
+	if err != nil {
+		return err
+	}
+	if limit > 0 && total > limit {
+		_, _ = s.counter.Add(ctx, userID, metric, day, -amount)
+		return ErrQuotaExceeded
+	}
+	limitwarn.Check(ctx, limitwarn.KindQuota, metric, total, limit)
+	return nil
+}
+
+// Adjust adds amount, which may be negative, to metric without checking the limit. It
+// settles an estimate passed to Consume once the real amount is known.
+func (s *QuotaService) Adjust(ctx context.Context, userID uint, metric string, amount int64) error {
+	if s == nil || s.counter == nil || amount == 0 {
+		return nil
+	}
+	total, err := s.counter.Add(ctx, userID, metric, s.now(), amount)
+	if err != nil {
+		return err
+	}
+	limitwarn.Check(ctx, limitwarn.KindQuota, metric, total, s.limits[metric])
+	return nil
+}
+
+// ConsumeAll consumes several metrics at once; if any is over its limit, the ones already
+// consumed are rolled back and ErrQuotaExceeded is returned.
+func (s *QuotaService) ConsumeAll(ctx context.Context, userID uint, amounts map[string]int64) error {
+	if s == nil || s.counter == nil {
+		return nil
+	}
+	consumed := make(map[string]int64, len(amounts))
+	for metric, amount := range amounts {
+		if err := s.Consume(ctx, userID, metric, amount); err != nil {
+			for done, doneAmount := range consumed {
+				_, _ = s.counter.Add(ctx, userID, done, s.now(), -doneAmount)
+			}
+			return err
+		}
+		consumed[metric] = amount
+	}
+	return nil
+}
+
+// Refund gives back amounts taken by ConsumeAll for work that then failed. It runs even
+// if ctx was cancelled, since the failure is often the cancellation itself.
+func (s *QuotaService) Refund(ctx context.Context, userID uint, amounts map[string]int64) error {
+	if s == nil || s.counter == nil {
+		return nil
+	}
+	ctx = context.WithoutCancel(ctx)
+	for metric, amount := range amounts {
+		if amount <= 0 {
+			continue
+		}
+		if _, err := s.counter.Add(ctx, userID, metric, s.now(), -amount); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Usage returns today's usage for every metric with a configured limit. Without a counter
+// nothing is recorded, so every metric reports zero.
+func (s *QuotaService) Usage(ctx context.Context, userID uint) (map[string]MetricUsage, error) {
+	if s == nil {
+		return map[string]MetricUsage{}, nil
+	}
+	out := make(map[string]MetricUsage, len(s.limits))
+	day := s.now()
+	for metric, limit := range s.limits {
+		if s.counter == nil {
+			out[metric] = MetricUsage{Limit: limit}
+			continue
+		}
+		used, err := s.counter.Get(ctx, userID, metric, day)
+		if err != nil {
+			return nil, err
+		}
+		out[metric] = MetricUsage{Used: used, Limit: limit}
+	}
+	return out, nil
+}
@@ -0,0 +1,216 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"gopherai-resume/internal/model"
+	"gopherai-resume/internal/pkg/apperr"
+)
+
+// defaultBatchMaxQuestions caps a batch ask when no limit is configured.
+const defaultBatchMaxQuestions = 20
+
+// AskBatchInput is a list of questions asked of the same documents. Options holds what
+// every question shares; its Question is ignored. Admit, when set, is called before each
+// question is answered, and a question it returns an error for fails with that error, so a
+// batch counts against the same rate and in-flight limits as single asks. The func it
+// returns is called once the question is answered.
+type AskBatchInput struct {
+	Options   AskInput
+	Questions []string
+	Admit     func(ctx context.Context) (func(), error)
+}
+
+// BatchAnswer is the outcome of one question of a batch. Error and Code are set instead of
+// Result when it failed; the other questions are still answered.
+type BatchAnswer struct {
+	Index     int        `json:"index"`
+	Question  string     `json:"question"`
+	Result    *AskResult `json:"result,omitempty"`
+	Error     string     `json:"error,omitempty"`
+	Code      int        `json:"code,omitempty"`
+	LatencyMS int64      `json:"latency_ms"`
+}
+
+// BatchDocumentUse counts the questions whose answer used a document's chunks.
+type BatchDocumentUse struct {
+	DocumentID   uint   `json:"document_id"`
+	DocumentName string `json:"document_name"`
+	Questions    int    `json:"questions"`
+}
+
+// AskBatchSummary describes how well the documents cover a batch. Answered counts the
+// questions answered from the documents, not those that failed or had insufficient
+// context. MeanTopScore is the mean similarity of each answered question's best chunk.
+type AskBatchSummary struct {
+	Questions           int                `json:"questions"`
+	Answered            int                `json:"answered"`
+	InsufficientContext int                `json:"insufficient_context"`
+	Failed              int                `json:"failed"`
+	MeanTopScore        float32            `json:"mean_top_score"`
+	Documents           []BatchDocumentUse `json:"documents"`
+}
+
+// AskBatchResult holds the answers in the order the questions were given.
+type AskBatchResult struct {
+	Answers []BatchAnswer   `json:"answers"`
+	Summary AskBatchSummary `json:"summary"`
+}
+
+// AskBatch answers up to the configured number of questions, a few at a time. Each is
+// answered like Ask but on its own: without the session's recent turns and without being
+// stored in the session's history. The documents' chunks are loaded once for the whole
+// batch. Only a batch that is invalid as a whole returns an error.
+func (s *RAGService) AskBatch(ctx context.Context, input AskBatchInput) (*AskBatchResult, error) {
+	if input.Options.UserID == 0 || len(input.Questions) == 0 {
+		return nil, ErrInvalidInput
+	}
+	if len(input.Questions) > s.batchMaxQuestions {
+		return nil, ErrInvalidInput.Withf("at most %d questions per batch", s.batchMaxQuestions)
+	}
+
+	ctx = withBatchChunks(ctx)
+	answers := make([]BatchAnswer, len(input.Questions))
+	slots := make(chan struct{}, s.batchConcurrency)
+	var wg sync.WaitGroup
+	for i, question := range input.Questions {
+		wg.Add(1)
+		go func(i int, question string) {
+			defer wg.Done()
+			slots <- struct{}{}
+			defer func() { <-slots }()
+			answers[i] = s.askBatchQuestion(ctx, input, i, question)
+		}(i, question)
+	}
+	wg.Wait()
+
+	recordActivity(context.WithoutCancel(ctx), s.activity, input.Options.UserID, ActivityRAGAsked, input.Options.SessionID,
+		fmt.Sprintf("Asked a batch of %d questions", len(input.Questions)))
+	return &AskBatchResult{Answers: answers, Summary: summarizeBatch(answers)}, nil
+}
+
+func (s *RAGService) askBatchQuestion(ctx context.Context, input AskBatchInput, index int, question string) BatchAnswer {
+	answer := BatchAnswer{Index: index, Question: question}
+	started := time.Now()
+	var err error
+	if input.Admit != nil {
+		var done func()
+		if done, err = input.Admit(ctx); err == nil {
+			defer done()
+		}
+	}
+	if err == nil {
+		ask := input.Options
+		ask.Question = question
+		ask.standalone = true
+		answer.Result, err = s.Ask(ctx, ask)
+	}
+	answer.LatencyMS = time.Since(started).Milliseconds()
+	if err != nil {
+		answer.Result = nil
+		e, ok := apperr.As(err)
+		if !ok {
+			e = apperr.Internal("ask failed", err)
+		}
+		if e.Status >= 500 {
+			log.Printf("batch ask question %d for user %d failed: %v", index, input.Options.UserID, err)
+		}
+		answer.Error, answer.Code = e.Message, e.Code
+	}
+	return answer
+}
+
+func summarizeBatch(answers []BatchAnswer) AskBatchSummary {
+	summary := AskBatchSummary{Questions: len(answers), Documents: []BatchDocumentUse{}}
+	var topScores float32
+	uses := make(map[uint]*BatchDocumentUse)
+	for _, a := range answers {
+		switch {
+		case a.Result == nil:
+			summary.Failed++
+			continue
+		case a.Result.InsufficientContext:
+			summary.InsufficientContext++
+			continue
+		}
+		summary.Answered++
+		if len(a.Result.Scores) > 0 {
+			top := a.Result.Scores[0]
+			for _, score := range a.Result.Scores[1:] {
+				top = max(top, score)
+			}
+			topScores += top
+		}
+		seen := make(map[uint]bool)
+		for _, c := range a.Result.Citations {
+			if seen[c.DocumentID] {
+				continue
+			}
+			seen[c.DocumentID] = true
+			use, ok := uses[c.DocumentID]
+			if !ok {
+				use = &BatchDocumentUse{DocumentID: c.DocumentID, DocumentName: c.DocumentName}
+				uses[c.DocumentID] = use
+			}
+			use.Questions++
+		}
+	}
+	if summary.Answered > 0 {
+		summary.MeanTopScore = topScores / float32(summary.Answered)
+	}
+	for _, use := range uses {
+		summary.Documents = append(summary.Documents, *use)
+	}
+	sort.Slice(summary.Documents, func(a, b int) bool {
+		da, db := summary.Documents[a], summary.Documents[b]
+		if da.Questions != db.Questions {
+			return da.Questions > db.Questions
+		}
+		return da.DocumentID < db.DocumentID
+	})
+	return summary
+}
+
+type batchChunksKey struct{}
+
+// batchChunks holds the chunks loaded for a batch ask by document set, so its questions
+// scan each set once between them.
+type batchChunks struct {
+	mu    sync.Mutex
+	loads map[string]*batchChunkLoad
+}
+
+type batchChunkLoad struct {
+	once   sync.Once
+	chunks []model.RAGChunk
+	err    error
+}
+
+func withBatchChunks(ctx context.Context) context.Context {
+	return context.WithValue(ctx, batchChunksKey{}, &batchChunks{loads: make(map[string]*batchChunkLoad)})
+}
+
+// load returns the chunks of docIDs, calling fetch the first time the set is asked for.
+// Callers get their own slice and must not change the chunks in it.
+func (b *batchChunks) load(docIDs []uint, fetch func() ([]model.RAGChunk, error)) ([]model.RAGChunk, error) {
+	ids := append([]uint(nil), docIDs...)
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	key := fmt.Sprint(ids)
+	b.mu.Lock()
+	entry, ok := b.loads[key]
+	if !ok {
+		entry = &batchChunkLoad{}
+		b.loads[key] = entry
+	}
+	b.mu.Unlock()
+	entry.once.Do(func() { entry.chunks, entry.err = fetch() })
+	if entry.err != nil {
+		return nil, entry.err
+	}
+	return append([]model.RAGChunk(nil), entry.chunks...), nil
+}
@@ -0,0 +1,284 @@
+package app
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"gopherai-resume/internal/model"
+	"gopherai-resume/internal/pkg/docextract"
+)
+
+// Chunking strategies for IngestInput.ChunkStrategy.
+const (
+	// ChunkStrategyFixed cuts windows of ChunkSize runes that overlap by ChunkOverlap.
+	ChunkStrategyFixed = "fixed"
+	// ChunkStrategyParagraph packs whole paragraphs into chunks of up to ChunkSize runes;
+	// a longer paragraph is cut like ChunkStrategyFixed.
+	ChunkStrategyParagraph = "paragraph"
+	// ChunkStrategySentence packs whole sentences the same way.
+	ChunkStrategySentence = "sentence"
+)
+
+// Bounds for IngestInput.ChunkSize.
+const (
+	minChunkSize = 64
+	maxChunkSize = 8192
+)
+
+var (
+	paragraphBreak = regexp.MustCompile(`\n\s*\n`)
+	sentenceEnd    = regexp.MustCompile(`[.!?。！？]+["'”’)]*\s+|[。！？]+`)
+)
+
+// chunking is a resolved, valid way of splitting a document's text.
+type chunking struct {
+	size     int
+	overlap  int
+	strategy string
+}
+
+var defaultChunking = chunking{size: defaultChunkSize, overlap: defaultChunkOverlap, strategy: ChunkStrategyFixed}
+
+// resolveChunking validates requested chunking parameters and fills in the defaults for
+// those left unset. Overlap only applies to the fixed strategy.
+func resolveChunking(size int, overlap *int, strategy string) (chunking, error) {
+	c := defaultChunking
+	if size != 0 {
+		if size < minChunkSize || size > maxChunkSize {
+			return c, ErrInvalidInput.Withf("chunk_size must be between %d and %d", minChunkSize, maxChunkSize)
+		}
+		c.size = size
+	}
+	if strategy = strings.ToLower(strings.TrimSpace(strategy)); strategy != "" {
+		switch strategy {
+		case ChunkStrategyFixed, ChunkStrategyParagraph, ChunkStrategySentence:
+			c.strategy = strategy
+		default:
+			return c, ErrInvalidInput.Withf("unknown chunk_strategy %q", strategy)
+		}
+	}
+	if overlap != nil {
+		if *overlap < 0 || *overlap >= c.size {
+			return c, ErrInvalidInput.Withf("chunk_overlap must be at least 0 and below chunk_size")
+		}
+		c.overlap = *overlap
+	} else if c.overlap >= c.size {
+		c.overlap = c.size / 8
+	}
+	if c.strategy != ChunkStrategyFixed {
+		c.overlap = 0
+	}
+	return c, nil
+}
+
+// documentChunking returns the chunking a document was ingested with; documents ingested
+// before it was stored used the defaults.
+func documentChunking(doc *model.RAGDocument) chunking {
+	if doc.ChunkSize == 0 {
+		return defaultChunking
+	}
+	c := chunking{size: doc.ChunkSize, overlap: doc.ChunkOverlap, strategy: doc.ChunkStrategy}
+	if c.strategy == "" {
+		c.strategy = ChunkStrategyFixed
+	}
+	return c
+}
+
+// rechunking resolves the chunking for new content of doc like resolveChunking, except
+// that parameters left unset keep the document's values rather than the defaults.
+func rechunking(doc *model.RAGDocument, size int, overlap *int, strategy string) (chunking, error) {
+	current := documentChunking(doc)
+	if size == 0 {
+		size = current.size
+	}
+	if strings.TrimSpace(strategy) == "" {
+		strategy = current.strategy
+	}
+	if overlap == nil && strategy == current.strategy && current.overlap < size {
+		overlap = &current.overlap
+	}
+	return resolveChunking(size, overlap, strategy)
+}
+
+// apply records the chunking on doc so later appends and replacements reuse it.
+func (c chunking) apply(doc *model.RAGDocument) {
+	doc.ChunkSize = c.size
+	doc.ChunkOverlap = c.overlap
+	doc.ChunkStrategy = c.strategy
+}
+
+// textChunk is a chunk of a document's text with where it was cut from. start and end
+// count runes from the start of the text; page is the 1-based page the chunk starts on,
+// or 0 for text without pages.
+type textChunk struct {
+	text       string
+	start, end int
+	page       int
+}
+
+// chunkTexts returns the text of each chunk.
+func chunkTexts(chunks []textChunk) []string {
+	texts := make([]string, len(chunks))
+	for i, c := range chunks {
+		texts[i] = c.text
+	}
+	return texts
+}
+
+// split cuts text into chunks. Page breaks inside a chunk become newlines.
+func (c chunking) split(text string) []textChunk {
+	var chunks []textChunk
+	switch c.strategy {
+	case ChunkStrategyParagraph:
+		chunks = packUnits(text, splitParagraphs(text), "\n\n", c.size)
+	case ChunkStrategySentence:
+		chunks = packUnits(text, splitSentences(text), " ", c.size)
+	default:
+		chunks = chunkSpans(text, 0, c.size, c.overlap)
+	}
+	for i := range chunks {
+		chunks[i].text = strings.ReplaceAll(chunks[i].text, docextract.PageBreak, "\n")
+	}
+	return chunks
+}
+
+// assignPages sets the page each chunk starts on from the page breaks in text, the text
+// the chunks were split from.
+func assignPages(text string, chunks []textChunk) {
+	var breaks []int
+	offset := 0
+	for _, r := range text {
+		if string(r) == docextract.PageBreak {
+			breaks = append(breaks, offset)
+		}
+		offset++
+	}
+	for i := range chunks {
+		// A chunk cut right at a break starts on the next page.
+		chunks[i].page = 1 + sort.SearchInts(breaks, chunks[i].start+1)
+	}
+}
+
+// carryPages gives each chunk of a paged document's edited text the page of the last old
+// chunk starting at or before the same offset, starts being where the old chunks start in
+// the text they join to. Only chunks between an edit and the next old chunk start can come
+// out a page off.
+func carryPages(old []model.RAGChunk, starts []int, chunks []textChunk) {
+	for i := range chunks {
+		j := sort.SearchInts(starts, chunks[i].start+1) - 1
+		if j < 0 {
+			j = 0
+		}
+		chunks[i].page = old[j].Page
+	}
+}
+
+// pageCount is the number of pages in text, or 0 unless it is paged.
+func pageCount(text string, paged bool) int {
+	if !paged {
+		return 0
+	}
+	return strings.Count(text, docextract.PageBreak) + 1
+}
+
+// trimContent trims the space around a document's text, except leading page breaks, so
+// that pages are still numbered from the first one when it is blank.
+func trimContent(content string) string {
+	content = strings.TrimRightFunc(content, unicode.IsSpace)
+	return strings.TrimLeftFunc(content, func(r rune) bool {
+		return unicode.IsSpace(r) && string(r) != docextract.PageBreak
+	})
+}
+
+// span is a byte range of a text.
+type span struct{ start, end int }
+
+// splitParagraphs cuts text at blank lines.
+func splitParagraphs(text string) []span {
+	var spans []span
+	last := 0
+	for _, loc := range paragraphBreak.FindAllStringIndex(text, -1) {
+		spans = append(spans, span{last, loc[0]})
+		last = loc[1]
+	}
+	return append(spans, span{last, len(text)})
+}
+
+// splitSentences cuts text after each sentence-ending punctuation mark.
+func splitSentences(text string) []span {
+	var spans []span
+	last := 0
+	for _, loc := range sentenceEnd.FindAllStringIndex(text, -1) {
+		spans = append(spans, span{last, loc[1]})
+		last = loc[1]
+	}
+	return append(spans, span{last, len(text)})
+}
+
+// packUnits joins consecutive units of text with sep into chunks of at most size runes. A
+// unit longer than size is cut into fixed windows of its own.
+func packUnits(text string, units []span, sep string, size int) []textChunk {
+	var chunks []textChunk
+	var current []string
+	var first, last int // byte range of the units in current
+	currentLen := 0
+	runes := runeCounter{text: text}
+	flush := func() {
+		if len(current) > 0 {
+			chunks = append(chunks, textChunk{
+				text:  strings.Join(current, sep),
+				start: runes.at(first),
+				end:   runes.at(last),
+			})
+			current, currentLen = nil, 0
+		}
+	}
+	sepLen := len([]rune(sep))
+	for _, u := range units {
+		unit := text[u.start:u.end]
+		trimmed := strings.TrimLeftFunc(unit, unicode.IsSpace)
+		start := u.start + len(unit) - len(trimmed)
+		unit = strings.TrimRightFunc(trimmed, unicode.IsSpace)
+		if unit == "" {
+			continue
+		}
+		n := len([]rune(unit))
+		if n > size {
+			flush()
+			chunks = append(chunks, chunkSpans(unit, runes.at(start), size, 0)...)
+			continue
+		}
+		if currentLen > 0 && currentLen+sepLen+n > size {
+			flush()
+		}
+		if currentLen > 0 {
+			currentLen += sepLen
+		} else {
+			first = start
+		}
+		current = append(current, unit)
+		last = start + len(unit)
+		currentLen += n
+	}
+	flush()
+	return chunks
+}
+
+// runeCounter turns byte offsets into text into rune offsets. Offsets asked for in
+// increasing order are counted incrementally.
+type runeCounter struct {
+	text           string
+	bytes, counted int
+}
+
+func (r *runeCounter) at(offset int) int {
+	if offset < r.bytes {
+		r.bytes, r.counted = 0, 0
+	}
+	r.counted += utf8.RuneCountInString(r.text[r.bytes:offset])
+	r.bytes = offset
+	return r.counted
+}
@@ -0,0 +1,161 @@
+package app
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"hash/fnv"
+	"math/bits"
+	"strings"
+
+	"gopherai-resume/internal/repository"
+)
+
+// ChunkDedupe counts the chunks an ingest did not store because the document already had
+// their text: DuplicateChunks the same text, NearDuplicateChunks nearly the same.
+type ChunkDedupe struct {
+	DuplicateChunks     int `json:"duplicate_chunks,omitempty"`
+	NearDuplicateChunks int `json:"near_duplicate_chunks,omitempty"`
+}
+
+// chunkFingerprint returns the SHA-256 of text with case and whitespace normalized, and
+// the simhash of its word pairs.
+func chunkFingerprint(text string) (string, uint64) {
+	words := strings.Fields(strings.ToLower(text))
+	sum := sha256.Sum256([]byte(strings.Join(words, " ")))
+	return hex.EncodeToString(sum[:]), simHash(words)
+}
+
+// simHash is Charikar's 64-bit simhash over adjacent word pairs, or the single word of a
+// one-word text. Texts that share most pairs differ in few bits.
+func simHash(words []string) uint64 {
+	var weights [64]int
+	add := func(feature string) {
+		h := fnv.New64a()
+		h.Write([]byte(feature))
+		v := h.Sum64()
+		for bit := range weights {
+			if v&(1<<bit) != 0 {
+				weights[bit]++
+			} else {
+				weights[bit]--
+			}
+		}
+	}
+	if len(words) == 1 {
+		add(words[0])
+	}
+	for i := 0; i+1 < len(words); i++ {
+		add(words[i] + " " + words[i+1])
+	}
+	var hash uint64
+	for bit, w := range weights {
+		if w > 0 {
+			hash |= 1 << bit
+		}
+	}
+	return hash
+}
+
+// dedupeChunks drops the chunks whose text repeats an earlier chunk of the list or, when
+// documentID is not 0, a chunk already stored for that document, as an append adds to it.
+// Other documents are never compared, so each document keeps all of its own text. With a
+// near-duplicate distance configured it also drops chunks whose simhash is that close to
+// one of those. It returns the chunks to store, which keep their offsets, and what it
+// dropped. Chunks stored before fingerprints were are not compared.
+func (s *RAGService) dedupeChunks(ctx context.Context, documentID uint, chunks []textChunk) ([]textChunk, ChunkDedupe, error) {
+	var counts ChunkDedupe
+	if !s.dedupeChunksEnabled || len(chunks) == 0 {
+		return chunks, counts, nil
+	}
+	hashes := make([]string, len(chunks))
+	simHashes := make([]uint64, len(chunks))
+	for i, c := range chunks {
+		hashes[i], simHashes[i] = chunkFingerprint(c.text)
+	}
+	// Exact matches only need the stored chunks with one of the new hashes.
+	var only []string
+	if s.nearDuplicateDistance <= 0 {
+		only = hashes
+	}
+	var stored []repository.RAGChunkFingerprint
+	if documentID != 0 {
+		var err error
+		if stored, err = s.chunkRepo.ListFingerprints(ctx, documentID, only); err != nil {
+			return nil, counts, err
+		}
+	}
+
+	seen := make(map[string]bool, len(stored)+len(chunks))
+	near := newSimHashIndex(s.nearDuplicateDistance)
+	add := func(fp repository.RAGChunkFingerprint) {
+		seen[fp.ContentHash] = true
+		near.add(fp.SimHash)
+	}
+	for _, fp := range stored {
+		add(fp)
+	}
+	kept := make([]textChunk, 0, len(chunks))
+	for i, c := range chunks {
+		if seen[hashes[i]] {
+			counts.DuplicateChunks++
+			continue
+		}
+		if near.match(simHashes[i]) {
+			counts.NearDuplicateChunks++
+			continue
+		}
+		add(repository.RAGChunkFingerprint{ContentHash: hashes[i], SimHash: simHashes[i]})
+		kept = append(kept, c)
+	}
+	return kept, counts, nil
+}
+
+// simHashIndex finds simhashes within distance bits of one another without comparing
+// every pair. The 64 bits are split into distance+1 bands; two hashes that differ in at
+// most distance bits agree on at least one band, so only hashes sharing a band are
+// compared. A distance of 0 or less matches nothing.
+type simHashIndex struct {
+	distance int
+	width    int
+	bands    []map[uint64][]uint64
+}
+
+func newSimHashIndex(distance int) *simHashIndex {
+	idx := &simHashIndex{distance: distance}
+	if distance <= 0 {
+		return idx
+	}
+	n := distance + 1
+	if n > 64 {
+		n = 64
+	}
+	idx.width = (64 + n - 1) / n
+	idx.bands = make([]map[uint64][]uint64, (64+idx.width-1)/idx.width)
+	for i := range idx.bands {
+		idx.bands[i] = make(map[uint64][]uint64)
+	}
+	return idx
+}
+
+func (idx *simHashIndex) band(hash uint64, i int) uint64 {
+	return (hash >> (i * idx.width)) & (1<<idx.width - 1)
+}
+
+func (idx *simHashIndex) add(hash uint64) {
+	for i, band := range idx.bands {
+		key := idx.band(hash, i)
+		band[key] = append(band[key], hash)
+	}
+}
+
+func (idx *simHashIndex) match(hash uint64) bool {
+	for i, band := range idx.bands {
+		for _, o := range band[idx.band(hash, i)] {
+			if bits.OnesCount64(hash^o) <= idx.distance {
+				return true
+			}
+		}
+	}
+	return false
+}
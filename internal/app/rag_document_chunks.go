@@ -0,0 +1,104 @@
+package app
+
+import (
+	"context"
+	"unicode/utf8"
+
+	"gopherai-resume/internal/pkg/authz"
+)
+
+const (
+	defaultChunkPageLimit = 50
+	maxChunkPageLimit     = 200
+)
+
+// DocumentChunk is a stored chunk as shown for inspection, with how its embedding is kept
+// instead of the embedding itself.
+type DocumentChunk struct {
+	ID          uint   `json:"id"`
+	ChunkIndex  int    `json:"chunk_index"`
+	Content     string `json:"content"`
+	Characters  int    `json:"characters"`
+	StartOffset int    `json:"start_offset"`
+	EndOffset   int    `json:"end_offset"`
+	Page        int    `json:"page,omitempty"`
+	// EmbeddingFormat is "float32", "int8", "json" or "none"; see model.RAGChunk.EmbeddingInfo.
+	EmbeddingFormat    string `json:"embedding_format"`
+	EmbeddingDimension int    `json:"embedding_dimension"`
+	Archived           bool   `json:"archived,omitempty"`
+}
+
+// DocumentChunkPage is one page of a document's chunks in index order, with the chunking
+// the document was split with.
+type DocumentChunkPage struct {
+	DocumentID    uint            `json:"document_id"`
+	DocumentName  string          `json:"document_name"`
+	ChunkSize     int             `json:"chunk_size"`
+	ChunkOverlap  int             `json:"chunk_overlap"`
+	ChunkStrategy string          `json:"chunk_strategy"`
+	Total         int64           `json:"total"`
+	Offset        int             `json:"offset"`
+	Limit         int             `json:"limit"`
+	HasMore       bool            `json:"has_more"`
+	Chunks        []DocumentChunk `json:"chunks"`
+}
+
+// DocumentChunks returns up to limit of the document's chunks after the first offset, so
+// what the chunker produced can be checked.
+func (s *RAGService) DocumentChunks(ctx context.Context, userID, documentID uint, offset, limit int) (*DocumentChunkPage, error) {
+	if userID == 0 || documentID == 0 || offset < 0 {
+		return nil, ErrInvalidInput
+	}
+	if limit <= 0 {
+		limit = defaultChunkPageLimit
+	}
+	if limit > maxChunkPageLimit {
+		limit = maxChunkPageLimit
+	}
+	doc, err := loadRAGDocument(ctx, s.docRepo, documentID, userID, authz.Read)
+	if err != nil {
+		return nil, err
+	}
+	if doc == nil {
+		return nil, ErrRAGDocumentNotFound
+	}
+	total, err := s.chunkRepo.CountByDocumentID(ctx, doc.ID)
+	if err != nil {
+		return nil, err
+	}
+	chunks, err := s.chunkRepo.ListPageByDocumentID(ctx, doc.ID, offset, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	chunking := documentChunking(doc)
+	page := &DocumentChunkPage{
+		DocumentID:    doc.ID,
+		DocumentName:  doc.Name,
+		ChunkSize:     chunking.size,
+		ChunkOverlap:  chunking.overlap,
+		ChunkStrategy: chunking.strategy,
+		Total:         total,
+		Offset:        offset,
+		Limit:         limit,
+		HasMore:       int64(offset+len(chunks)) < total,
+		Chunks:        make([]DocumentChunk, 0, len(chunks)),
+	}
+	for i := range chunks {
+		c := &chunks[i]
+		format, dimensions := c.EmbeddingInfo()
+		page.Chunks = append(page.Chunks, DocumentChunk{
+			ID:                 c.ID,
+			ChunkIndex:         c.ChunkIndex,
+			Content:            c.Content,
+			Characters:         utf8.RuneCountInString(c.Content),
+			StartOffset:        c.StartOffset,
+			EndOffset:          c.EndOffset,
+			Page:               c.Page,
+			EmbeddingFormat:    format,
+			EmbeddingDimension: dimensions,
+			Archived:           c.IsArchived(),
+		})
+	}
+	return page, nil
+}
@@ -0,0 +1,140 @@
+package app
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+const (
+	bm25K1 = 1.2
+	bm25B  = 0.75
+	// rrfK damps how much the very top ranks dominate reciprocal rank fusion; 60 is the
+	// value from the original RRF paper.
+	rrfK = 60
+)
+
+// keywordTokens lowercases text and splits it into runs of letters and digits. Han
+// characters become one token each, since Chinese text has no spaces between words.
+// Identifiers such as "ERR_CONN_42" split the same way in queries and chunks, so an exact
+// code still matches.
+func keywordTokens(text string) []string {
+	var tokens []string
+	var current strings.Builder
+	flush := func() {
+		if current.Len() > 0 {
+			tokens = append(tokens, current.String())
+			current.Reset()
+		}
+	}
+	for _, r := range strings.ToLower(text) {
+		switch {
+		case unicode.Is(unicode.Han, r):
+			flush()
+			tokens = append(tokens, string(r))
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			current.WriteRune(r)
+		default:
+			flush()
+		}
+	}
+	flush()
+	return tokens
+}
+
+// bm25Scores scores each document against query with Okapi BM25, using docs itself as the
+// corpus for document frequencies and average length.
+func bm25Scores(query string, docs []string) []float64 {
+	scores := make([]float64, len(docs))
+	terms := uniqueTokens(keywordTokens(query))
+	if len(terms) == 0 || len(docs) == 0 {
+		return scores
+	}
+
+	termFreqs := make([]map[string]int, len(docs))
+	docFreq := make(map[string]int, len(terms))
+	totalLen := 0
+	for i, doc := range docs {
+		tokens := keywordTokens(doc)
+		totalLen += len(tokens)
+		freqs := make(map[string]int)
+		for _, t := range tokens {
+			freqs[t]++
+		}
+		termFreqs[i] = freqs
+		for _, term := range terms {
+			if freqs[term] > 0 {
+				docFreq[term]++
+			}
+		}
+	}
+	avgLen := float64(totalLen) / float64(len(docs))
+	if avgLen == 0 {
+		return scores
+	}
+
+	n := float64(len(docs))
+	for i, freqs := range termFreqs {
+		docLen := 0
+		for _, f := range freqs {
+			docLen += f
+		}
+		for _, term := range terms {
+			tf := float64(freqs[term])
+			if tf == 0 {
+				continue
+			}
+			df := float64(docFreq[term])
+			idf := math.Log(1 + (n-df+0.5)/(df+0.5))
+			scores[i] += idf * tf * (bm25K1 + 1) / (tf + bm25K1*(1-bm25B+bm25B*float64(docLen)/avgLen))
+		}
+	}
+	return scores
+}
+
+func uniqueTokens(tokens []string) []string {
+	seen := make(map[string]bool, len(tokens))
+	out := tokens[:0:0]
+	for _, t := range tokens {
+		if !seen[t] {
+			seen[t] = true
+			out = append(out, t)
+		}
+	}
+	return out
+}
+
+// fuseRankings combines the vector and keyword rankings of the same items with reciprocal
+// rank fusion and returns item indexes, best first. Items without any keyword match get no
+// keyword rank, so a query with no matching terms falls back to vector order.
+func fuseRankings(vectorScores []float32, keywordScores []float64) []int {
+	fused := make([]float64, len(vectorScores))
+
+	byVector := make([]int, len(vectorScores))
+	for i := range byVector {
+		byVector[i] = i
+	}
+	sort.SliceStable(byVector, func(a, b int) bool { return vectorScores[byVector[a]] > vectorScores[byVector[b]] })
+	for rank, idx := range byVector {
+		fused[idx] += 1 / float64(rrfK+rank+1)
+	}
+
+	var byKeyword []int
+	for i, score := range keywordScores {
+		if score > 0 {
+			byKeyword = append(byKeyword, i)
+		}
+	}
+	sort.SliceStable(byKeyword, func(a, b int) bool { return keywordScores[byKeyword[a]] > keywordScores[byKeyword[b]] })
+	for rank, idx := range byKeyword {
+		fused[idx] += 1 / float64(rrfK+rank+1)
+	}
+
+	order := make([]int, len(fused))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool { return fused[order[a]] > fused[order[b]] })
+	return order
+}
@@ -0,0 +1,431 @@
+package app
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"gopherai-resume/internal/model"
+	"gopherai-resume/internal/pkg/apperr"
+	"gopherai-resume/internal/pkg/authz"
+	"gopherai-resume/internal/storage"
+)
+
+// Document ingest statuses, see model.RAGDocument.Status.
+const (
+	DocumentPending    = "pending"
+	DocumentProcessing = "processing"
+	DocumentReady      = "ready"
+	DocumentFailed     = "failed"
+)
+
+const (
+	ingestProcessTimeout = 30 * time.Minute
+	// ingestMaxAttempts is how often ProcessIngest tries a document before marking it
+	// failed, waiting ingestRetryDelay times the attempt number in between.
+	ingestMaxAttempts = 3
+	ingestRetryDelay  = 10 * time.Second
+	// ingestObjectKeyPattern holds the content of an asynchronous ingest until it is done.
+	ingestObjectKeyPattern = "rag-ingest/%d.txt"
+	// ingestLease is how long a claimed ingest is left to its worker, which gives up after
+	// ingestProcessTimeout, before another may take it over.
+	ingestLease = ingestProcessTimeout + time.Minute
+	// ingestMaxClaims is how often an ingest may be started, counting those interrupted by a
+	// shutdown or timeout, before the document is marked failed.
+	ingestMaxClaims = 5
+)
+
+// ErrIngestInterrupted is returned by ProcessIngest, along with ctx's error, when ctx
+// ended before the ingest did. The document is pending again, and the job should be retried.
+var ErrIngestInterrupted = errors.New("rag ingest interrupted")
+
+// RAGIngestQueue hands documents to a background ingest worker.
+type RAGIngestQueue interface {
+	EnqueueRAGIngest(ctx context.Context, documentID uint) error
+}
+
+// RAGDocumentStatus is how far a document's ingest has got.
+type RAGDocumentStatus struct {
+	ID         uint   `json:"id"`
+	Status     string `json:"status"`
+	Error      string `json:"error,omitempty"`
+	ChunkCount int    `json:"chunk_count"`
+	// EmbeddedChunks is how many chunks an unfinished ingest has embedded so far.
+	EmbeddedChunks int `json:"embedded_chunks,omitempty"`
+	// ChunkDedupe counts the chunks the ingest left out because their text repeated.
+	ChunkDedupe
+}
+
+// IngestAsync stores the document as pending and leaves chunking and embedding to the
+// ingest worker, or to a goroutine when there is no queue. Poll DocumentStatus until the
+// document is ready. Progress is not called. A duplicate upload (see IngestInput.FileHash)
+// returns the existing document, which may already be ready.
+func (s *RAGService) IngestAsync(ctx context.Context, input IngestInput) (*model.RAGDocument, error) {
+	if input.UserID == 0 {
+		return nil, ErrInvalidInput
+	}
+	content := trimContent(input.Content)
+	if strings.TrimSpace(content) == "" {
+		return nil, ErrInvalidInput
+	}
+	dup, err := s.findDuplicate(ctx, input.UserID, input.SessionID, input.FileHash, input.AllowDuplicate)
+	if err != nil || dup != nil {
+		return dup, err
+	}
+	name := strings.TrimSpace(input.Name)
+	if name == "" {
+		name = "Untitled"
+	}
+	chunker, err := resolveChunking(input.ChunkSize, input.ChunkOverlap, input.ChunkStrategy)
+	if err != nil {
+		return nil, err
+	}
+	chunks, _, err := s.dedupeChunks(ctx, 0, chunker.split(content))
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkStorage(ctx, input.UserID, 0, 1, len(chunks), chunkBytes(chunks)); err != nil {
+		return nil, err
+	}
+
+	doc := &model.RAGDocument{
+		UserID:    input.UserID,
+		SessionID: input.SessionID,
+		Name:      name,
+		PageCount: pageCount(content, input.Paged),
+		FileHash:  input.FileHash,
+		Status:    DocumentPending,
+	}
+	chunker.apply(doc)
+	if err := s.docRepo.Create(ctx, doc, s.storageCheck(input.UserID)); err != nil {
+		return nil, err
+	}
+	if err := s.store.Put(ctx, ingestObjectKey(doc.ID), []byte(content)); err != nil {
+		_ = s.docRepo.DeleteByIDAndUserID(ctx, doc.ID, doc.UserID)
+		return nil, err
+	}
+	s.enqueueIngest(ctx, doc.ID)
+	return doc, nil
+}
+
+// findDuplicate returns the newest document the user uploaded into the session from the
+// file with fileHash, unless it failed. It returns nil when there is none, when the content
+// did not come from a file, or when allow is set.
+func (s *RAGService) findDuplicate(ctx context.Context, userID, sessionID uint, fileHash string, allow bool) (*model.RAGDocument, error) {
+	if fileHash == "" || allow {
+		return nil, nil
+	}
+	docs, err := s.docRepo.ListByFileHash(ctx, userID, sessionID, fileHash)
+	if err != nil {
+		return nil, err
+	}
+	for i := range docs {
+		if docs[i].Status != DocumentFailed {
+			return &docs[i], nil
+		}
+	}
+	return nil, nil
+}
+
+// enqueueIngest hands the document to the worker, or ingests it in a goroutine when no
+// queue is configured or publishing fails.
+func (s *RAGService) enqueueIngest(ctx context.Context, documentID uint) {
+	if s.ingestQueue != nil {
+		err := s.ingestQueue.EnqueueRAGIngest(ctx, documentID)
+		if err == nil {
+			return
+		}
+		log.Printf("enqueue rag ingest %d failed, ingesting in-process: %v", documentID, err)
+	}
+	go func(id uint) {
+		ingestCtx, cancel := context.WithTimeout(context.Background(), ingestProcessTimeout)
+		defer cancel()
+		if err := s.ProcessIngest(ingestCtx, id); err != nil {
+			log.Printf("ingest rag document %d failed: %v", id, err)
+		}
+	}(documentID)
+}
+
+// ProcessIngest chunks, embeds and stores a pending document's content. It is called by
+// the ingest worker and records failures on the document itself. The document is claimed
+// first, so a job for a document that is ready, failed, deleted or being ingested by
+// another worker does nothing. Failures that may be temporary, such as a provider error,
+// are retried up to ingestMaxAttempts times. Each attempt, like a job redelivered after a
+// crash, reuses the embeddings earlier attempts saved. When ctx ends first the document
+// goes back to pending and ErrIngestInterrupted is returned.
+func (s *RAGService) ProcessIngest(ctx context.Context, documentID uint) error {
+	key := ingestObjectKey(documentID)
+	doc, err := s.docRepo.GetByID(ctx, documentID)
+	if err != nil {
+		return err
+	}
+	if doc == nil {
+		_ = s.store.Delete(ctx, key)
+		return nil
+	}
+	if !documentIngesting(doc) {
+		return nil
+	}
+	now := time.Now()
+	claimed, err := s.docRepo.ClaimIngest(ctx, doc.ID, now, now.Add(ingestLease))
+	if err != nil || !claimed {
+		return err
+	}
+	if doc.IngestClaims+1 > ingestMaxClaims {
+		return s.failIngest(context.WithoutCancel(ctx), doc, errors.New("the ingest was interrupted too often"))
+	}
+
+	count, ingestErr := s.ingestStored(ctx, doc, key)
+	for attempt := 1; ingestErr != nil && attempt < ingestMaxAttempts && retryableIngestError(ingestErr); attempt++ {
+		log.Printf("ingest rag document %d failed on attempt %d of %d, retrying: %v", doc.ID, attempt, ingestMaxAttempts, ingestErr)
+		select {
+		case <-ctx.Done():
+		case <-time.After(ingestRetryDelay * time.Duration(attempt)):
+		}
+		if ctx.Err() != nil {
+			break
+		}
+		count, ingestErr = s.ingestStored(ctx, doc, key)
+	}
+	// The outcome is recorded even when ctx ran out. An ingest cut short that way is not
+	// the document's fault, so it is left for the next claim instead of failing.
+	ctxErr := ctx.Err()
+	ctx = context.WithoutCancel(ctx)
+	if ingestErr != nil && ctxErr != nil {
+		if err := s.docRepo.ReleaseIngest(ctx, doc.ID); err != nil {
+			return err
+		}
+		return fmt.Errorf("%w: document %d: %w", ErrIngestInterrupted, doc.ID, ctxErr)
+	}
+	if ingestErr != nil {
+		return s.failIngest(ctx, doc, ingestErr)
+	}
+	if err := s.docRepo.UpdateStatus(ctx, doc.ID, DocumentReady, ""); err != nil {
+		return err
+	}
+	s.discardIngest(ctx, doc.ID)
+	recordActivity(ctx, s.activity, doc.UserID, ActivityDocumentIngested, doc.ID,
+		fmt.Sprintf("Ingested %q as %d chunks", doc.Name, count))
+	var skipped string
+	if doc.DuplicateChunks+doc.NearDuplicateChunks > 0 {
+		skipped = fmt.Sprintf(" %d chunks that repeated its own text were skipped.", doc.DuplicateChunks+doc.NearDuplicateChunks)
+	}
+	notify(ctx, s.notifier, doc.UserID, NotifyIngestionComplete,
+		fmt.Sprintf("Document ready: %s", doc.Name),
+		fmt.Sprintf("%q was ingested as %d chunks and can now be searched and asked about.%s%s", doc.Name, count, skipped, notificationFooter))
+	return nil
+}
+
+// failIngest marks the document failed with cause and emails its owner. It returns cause.
+func (s *RAGService) failIngest(ctx context.Context, doc *model.RAGDocument, cause error) error {
+	msg := truncateRunes(apperr.Message(cause, "ingest failed"), 512)
+	if err := s.docRepo.UpdateStatus(ctx, doc.ID, DocumentFailed, msg); err != nil {
+		return err
+	}
+	notify(ctx, s.notifier, doc.UserID, NotifyIngestionComplete,
+		fmt.Sprintf("Document failed: %s", doc.Name),
+		fmt.Sprintf("%q could not be ingested: %s\nYou can upload it again.%s", doc.Name, msg, notificationFooter))
+	return cause
+}
+
+// ingestStored embeds the content kept under key and replaces the document's chunks with
+// the result, so a retried job never duplicates chunks. It returns the chunk count.
+func (s *RAGService) ingestStored(ctx context.Context, doc *model.RAGDocument, key string) (int, error) {
+	content, err := s.store.Get(ctx, key)
+	if err != nil {
+		return 0, err
+	}
+	chunks := documentChunking(doc).split(string(content))
+	if len(chunks) == 0 {
+		return 0, ErrInvalidInput
+	}
+	if doc.PageCount > 0 {
+		assignPages(string(content), chunks)
+	}
+	// A retried job replaces the chunks, so only the content itself is deduplicated.
+	chunks, dedupe, err := s.dedupeChunks(ctx, 0, chunks)
+	if err != nil {
+		return 0, err
+	}
+	if err := s.docRepo.UpdateDedupe(ctx, doc.ID, dedupe.DuplicateChunks, dedupe.NearDuplicateChunks); err != nil {
+		return 0, err
+	}
+	doc.DuplicateChunks, doc.NearDuplicateChunks = dedupe.DuplicateChunks, dedupe.NearDuplicateChunks
+	if err := s.checkStorage(ctx, doc.UserID, doc.ID, 0, len(chunks), chunkBytes(chunks)); err != nil {
+		return 0, err
+	}
+	ragChunks, err := s.embedChunksResumable(ctx, doc.ID, chunks)
+	if err != nil {
+		return 0, err
+	}
+	if err := s.chunkRepo.ReplaceByDocumentID(ctx, doc.ID, ragChunks, s.storageCheck(doc.UserID)); err != nil {
+		return 0, err
+	}
+	s.indexDocument(ctx, doc, ragChunks, true)
+	if err := s.docRepo.UpdateChunkCount(ctx, doc.ID, len(ragChunks)); err != nil {
+		return 0, err
+	}
+	return len(ragChunks), nil
+}
+
+// DocumentStatus reports a document's ingest status.
+func (s *RAGService) DocumentStatus(ctx context.Context, userID, documentID uint) (*RAGDocumentStatus, error) {
+	if userID == 0 || documentID == 0 {
+		return nil, ErrInvalidInput
+	}
+	doc, err := loadRAGDocument(ctx, s.docRepo, documentID, userID, authz.Read)
+	if err != nil {
+		return nil, err
+	}
+	if doc == nil {
+		return nil, ErrRAGDocumentNotFound
+	}
+	status := &RAGDocumentStatus{
+		ID:         doc.ID,
+		Status:     doc.Status,
+		Error:      doc.Error,
+		ChunkCount: doc.ChunkCount,
+		ChunkDedupe: ChunkDedupe{
+			DuplicateChunks:     doc.DuplicateChunks,
+			NearDuplicateChunks: doc.NearDuplicateChunks,
+		},
+	}
+	if documentIngesting(doc) && s.ingestEmbeddings != nil {
+		embedded, err := s.ingestEmbeddings.CountByDocumentID(ctx, doc.ID)
+		if err != nil {
+			return nil, err
+		}
+		status.EmbeddedChunks = int(embedded)
+	}
+	return status, nil
+}
+
+// embedChunksResumable embeds chunks like embedChunks for an asynchronous ingest of the
+// document. The embeddings of each batch are saved as soon as they arrive, and those an
+// earlier attempt saved for the same chunk text and embedding model are reused, so an
+// interrupted ingest is not embedded, and billed, twice.
+func (s *RAGService) embedChunksResumable(ctx context.Context, documentID uint, chunks []textChunk) ([]model.RAGChunk, error) {
+	if s.ingestEmbeddings == nil {
+		return s.embedChunks(ctx, documentID, 0, chunks, nil)
+	}
+	saved, err := s.ingestEmbeddings.ListByDocumentID(ctx, documentID)
+	if err != nil {
+		return nil, err
+	}
+	savedByIndex := make(map[int]model.RAGIngestEmbedding, len(saved))
+	for _, e := range saved {
+		savedByIndex[e.ChunkIndex] = e
+	}
+
+	embeddings := make([][]float32, len(chunks))
+	fingerprints := make([]string, len(chunks))
+	var missing []int
+	for i, c := range chunks {
+		fingerprints[i] = s.ingestFingerprint(c.text)
+		if e, ok := savedByIndex[i]; ok && e.Fingerprint == fingerprints[i] {
+			if vec := e.EmbeddingVector(); len(vec) > 0 {
+				embeddings[i] = vec
+				continue
+			}
+		}
+		missing = append(missing, i)
+	}
+	if reused := len(chunks) - len(missing); reused > 0 {
+		log.Printf("ingest rag document %d resumes with %d of %d chunks already embedded", documentID, reused, len(chunks))
+	}
+
+	for start := 0; start < len(missing); start += embeddingBatchSize {
+		batch := missing[start:min(start+embeddingBatchSize, len(missing))]
+		texts := make([]string, len(batch))
+		for j, i := range batch {
+			texts[j] = chunks[i].text
+		}
+		embedded, err := s.embedder.EmbedBatch(ctx, s.embConfig, texts)
+		if err != nil {
+			return nil, err
+		}
+		if len(embedded) != len(batch) {
+			return nil, errors.New("embedding count mismatch")
+		}
+		rows := make([]model.RAGIngestEmbedding, len(batch))
+		for j, i := range batch {
+			embeddings[i] = embedded[j]
+			rows[j] = model.RAGIngestEmbedding{DocumentID: documentID, ChunkIndex: i, Fingerprint: fingerprints[i]}
+			rows[j].SetEmbedding(embedded[j])
+		}
+		if err := s.ingestEmbeddings.UpsertBatch(ctx, rows); err != nil {
+			return nil, err
+		}
+	}
+	return s.buildChunks(documentID, 0, chunks, embeddings), nil
+}
+
+// ingestFingerprint identifies a chunk text embedded with the configured model.
+func (s *RAGService) ingestFingerprint(text string) string {
+	sum := sha256.Sum256([]byte(s.embConfig.Model + "\x00" + text))
+	return hex.EncodeToString(sum[:])
+}
+
+// discardIngest deletes the content and saved embeddings of an asynchronous ingest that is
+// no longer needed. Failures are only logged.
+func (s *RAGService) discardIngest(ctx context.Context, documentID uint) {
+	if err := s.store.Delete(ctx, ingestObjectKey(documentID)); err != nil {
+		log.Printf("delete pending content of rag document %d failed: %v", documentID, err)
+	}
+	if s.ingestEmbeddings != nil {
+		if err := s.ingestEmbeddings.DeleteByDocumentID(ctx, documentID); err != nil {
+			log.Printf("delete saved embeddings of rag document %d failed: %v", documentID, err)
+		}
+	}
+}
+
+// retryableIngestError reports whether another attempt could succeed: invalid content,
+// exhausted quotas and lost content fail the same way again.
+func retryableIngestError(err error) bool {
+	if errors.Is(err, storage.ErrObjectNotFound) || errors.Is(err, context.Canceled) {
+		return false
+	}
+	if e, ok := apperr.As(err); ok && e.Status < 500 {
+		return false
+	}
+	return true
+}
+
+// ResumeIngests restarts the asynchronous ingests that an earlier process left pending or
+// processing, as happens when it stopped mid-ingest or a job was lost. They are queued
+// again, or run in the background without a queue; a job the broker also redelivers finds
+// the document claimed and does nothing. An ingest whose lease has not run out yet, which
+// may still have a live worker, is queued once the lease ends. It returns how many
+// ingests it restarted or scheduled.
+func (s *RAGService) ResumeIngests(ctx context.Context) (int, error) {
+	docs, err := s.docRepo.ListByStatus(ctx, []string{DocumentPending, DocumentProcessing})
+	if err != nil {
+		return 0, err
+	}
+	now := time.Now()
+	for _, doc := range docs {
+		if doc.Status == DocumentProcessing && doc.IngestLeaseUntil != nil && doc.IngestLeaseUntil.After(now) {
+			id := doc.ID
+			time.AfterFunc(doc.IngestLeaseUntil.Sub(now)+time.Second, func() {
+				s.enqueueIngest(context.Background(), id)
+			})
+			continue
+		}
+		s.enqueueIngest(ctx, doc.ID)
+	}
+	return len(docs), nil
+}
+
+// documentIngesting reports whether an asynchronous ingest of doc is still under way.
+func documentIngesting(doc *model.RAGDocument) bool {
+	return doc.Status == DocumentPending || doc.Status == DocumentProcessing
+}
+
+func ingestObjectKey(documentID uint) string {
+	return fmt.Sprintf(ingestObjectKeyPattern, documentID)
+}
@@ -0,0 +1,214 @@
+package app
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"gopherai-resume/internal/pkg/apperr"
+	"gopherai-resume/internal/rag"
+	"gopherai-resume/internal/repository"
+)
+
+var ErrNoVectorStore = apperr.New(http.StatusServiceUnavailable, apperr.CodeFeatureUnavailable, "no vector store configured")
+
+// maintenanceBatchSize is how many chunks Reindex and PackEmbeddings load at a time.
+const maintenanceBatchSize = 200
+
+// RAGMaintenanceService runs storage housekeeping for the RAG tables. It is shared by the
+// admin API and the ragadmin CLI.
+type RAGMaintenanceService struct {
+	docRepo   RAGDocumentRepository
+	chunkRepo RAGChunkRepository
+}
+
+func NewRAGMaintenanceService(
+	docRepo RAGDocumentRepository,
+	chunkRepo RAGChunkRepository,
+) *RAGMaintenanceService {
+	return &RAGMaintenanceService{
+		docRepo:   docRepo,
+		chunkRepo: chunkRepo,
+	}
+}
+
+// VacuumResult reports orphaned chunks found (and removed unless DryRun).
+type VacuumResult struct {
+	DryRun         bool  `json:"dry_run"`
+	OrphanedChunks int64 `json:"orphaned_chunks"`
+	DeletedChunks  int64 `json:"deleted_chunks"`
+}
+
+// Vacuum deletes chunks whose document was removed without cascading.
+func (s *RAGMaintenanceService) Vacuum(ctx context.Context, dryRun bool) (*VacuumResult, error) {
+	orphaned, err := s.chunkRepo.CountOrphaned(ctx)
+	if err != nil {
+		return nil, err
+	}
+	result := &VacuumResult{DryRun: dryRun, OrphanedChunks: orphaned}
+	if dryRun || orphaned == 0 {
+		return result, nil
+	}
+	deleted, err := s.chunkRepo.DeleteOrphaned(ctx)
+	if err != nil {
+		return nil, err
+	}
+	result.DeletedChunks = deleted
+	return result, nil
+}
+
+// RecountResult reports how many documents had a stale chunk count.
+type RecountResult struct {
+	Documents int `json:"documents"`
+	Updated   int `json:"updated"`
+}
+
+// RecountChunks recomputes RAGDocument.ChunkCount from the chunks table.
+func (s *RAGMaintenanceService) RecountChunks(ctx context.Context) (*RecountResult, error) {
+	docs, err := s.docRepo.ListAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+	counts, err := s.chunkRepo.CountByDocument(ctx)
+	if err != nil {
+		return nil, err
+	}
+	result := &RecountResult{Documents: len(docs)}
+	for _, doc := range docs {
+		actual := counts[doc.ID]
+		if doc.ChunkCount == actual {
+			continue
+		}
+		if err := s.docRepo.UpdateChunkCount(ctx, doc.ID, actual); err != nil {
+			return nil, err
+		}
+		result.Updated++
+	}
+	return result, nil
+}
+
+// StorageReport returns RAG storage usage per user, largest embedding footprint first.
+func (s *RAGMaintenanceService) StorageReport(ctx context.Context) ([]repository.RAGUserStorage, error) {
+	return s.chunkRepo.StorageByUser(ctx)
+}
+
+// ArchiveResult reports how many cold documents and chunks were archived.
+type ArchiveResult struct {
+	Cutoff    time.Time `json:"cutoff"`
+	Documents int       `json:"documents"`
+	Chunks    int       `json:"chunks"`
+}
+
+// ArchiveCold compresses the embeddings of documents not retrieved for olderThanDays days.
+// Archived embeddings are restored transparently the next time the document is searched.
+func (s *RAGMaintenanceService) ArchiveCold(ctx context.Context, olderThanDays int) (*ArchiveResult, error) {
+	if olderThanDays <= 0 {
+		return nil, ErrInvalidInput.Withf("days must be positive")
+	}
+	now := time.Now()
+	cutoff := now.AddDate(0, 0, -olderThanDays)
+	docIDs, err := s.chunkRepo.ListColdDocumentIDs(ctx, cutoff)
+	if err != nil {
+		return nil, err
+	}
+	result := &ArchiveResult{Cutoff: cutoff}
+	for _, docID := range docIDs {
+		chunks, err := s.chunkRepo.ListByDocumentIDs(ctx, []uint{docID})
+		if err != nil {
+			return nil, err
+		}
+		for i := range chunks {
+			if chunks[i].IsArchived() {
+				continue
+			}
+			if err := chunks[i].ArchiveEmbedding(now); err != nil {
+				return nil, err
+			}
+			if err := s.chunkRepo.SaveEmbeddingState(ctx, &chunks[i]); err != nil {
+				return nil, err
+			}
+			result.Chunks++
+		}
+		result.Documents++
+	}
+	return result, nil
+}
+
+// ReindexResult reports how many chunks were written to the vector store.
+type ReindexResult struct {
+	Chunks int `json:"chunks"`
+}
+
+// Reindex writes every stored chunk embedding to store, e.g. after switching to a vector
+// store backend or to rebuild a lost collection. Archived embeddings are decompressed for
+// indexing but stay archived in MySQL. The documents indexed are marked so retrieval
+// searches the store for them instead of reading their chunks from MySQL.
+func (s *RAGMaintenanceService) Reindex(ctx context.Context, store rag.VectorStore) (*ReindexResult, error) {
+	if store == nil {
+		return nil, ErrNoVectorStore
+	}
+	result := &ReindexResult{}
+	indexed := make(map[uint]bool)
+	var afterID uint
+	for {
+		chunks, err := s.chunkRepo.ListAfterID(ctx, afterID, maintenanceBatchSize)
+		if err != nil {
+			return nil, err
+		}
+		if len(chunks) == 0 {
+			docIDs := make([]uint, 0, len(indexed))
+			for id := range indexed {
+				docIDs = append(docIDs, id)
+			}
+			if err := s.docRepo.SetVectorIndexed(ctx, docIDs, true); err != nil {
+				return nil, err
+			}
+			return result, nil
+		}
+		points, err := rag.PointsFromChunks(chunks)
+		if err != nil {
+			return nil, err
+		}
+		if err := store.Upsert(ctx, points); err != nil {
+			return nil, err
+		}
+		result.Chunks += len(points)
+		for i := range chunks {
+			indexed[chunks[i].DocumentID] = true
+		}
+		afterID = chunks[len(chunks)-1].ID
+	}
+}
+
+// PackResult reports how many chunks had their JSON embedding packed.
+type PackResult struct {
+	Chunks    int  `json:"chunks"`
+	Quantized bool `json:"quantized"`
+}
+
+// PackEmbeddings converts the JSON embeddings of chunks stored before packed embeddings
+// existed, quantizing them to int8 if asked. Archived JSON embeddings are packed when
+// retrieval next restores them.
+func (s *RAGMaintenanceService) PackEmbeddings(ctx context.Context, quantize bool) (*PackResult, error) {
+	result := &PackResult{Quantized: quantize}
+	var afterID uint
+	for {
+		chunks, err := s.chunkRepo.ListJSONEmbeddedAfterID(ctx, afterID, maintenanceBatchSize)
+		if err != nil {
+			return nil, err
+		}
+		if len(chunks) == 0 {
+			return result, nil
+		}
+		for i := range chunks {
+			if !chunks[i].PackEmbedding(quantize) {
+				continue
+			}
+			if err := s.chunkRepo.SaveEmbeddingState(ctx, &chunks[i]); err != nil {
+				return nil, err
+			}
+			result.Chunks++
+		}
+		afterID = chunks[len(chunks)-1].ID
+	}
+}
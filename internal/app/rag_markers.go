@@ -0,0 +1,101 @@
+package app
+
+import (
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// citationMarkerInstruction asks the model to cite the numbered context entries.
+const citationMarkerInstruction = " Context entries are numbered like [1]. After each statement, cite the entries it is based on with their numbers in square brackets, e.g. [1] or [1][3]. Use only the numbers given and do not list the sources at the end."
+
+// maxMarkerNumber bounds what is read as a citation: bracketed numbers above it, such as
+// [2023], and [0] are left alone as ordinary text.
+const maxMarkerNumber = 99
+
+var (
+	// markerGroupPattern matches the forms models cite in: [1], [1, 2], [1-3], [^1], 【1】
+	// and [source 1], with any spaces before them.
+	markerGroupPattern = regexp.MustCompile(
+		`(?i)([ \t]*)[\[【]\^?\s*(?:(?:source|context|entry|chunk)\s*)?(\d+(?:\s*(?:,|;|-|–|and)\s*\d+)*)\s*[\]】]`)
+	markerNumberPattern = regexp.MustCompile(`\d+`)
+	// markerRunPattern matches adjacent [n] markers after normalization.
+	markerRunPattern = regexp.MustCompile(`\[\d+\](?:[ \t]*\[\d+\])*`)
+)
+
+// repairCitationMarkers rewrites the citation markers in answer as [n] markers that each
+// refer to one of sources numbered context entries. Lists and ranges are split into single
+// markers, markers for entries that do not exist are dropped, and repeated ones merged. It
+// returns the repaired answer and the entries it cites, in ascending order.
+func repairCitationMarkers(answer string, sources int) (string, []int) {
+	answer = markerGroupPattern.ReplaceAllStringFunc(answer, func(match string) string {
+		parts := markerGroupPattern.FindStringSubmatch(match)
+		numbers, ok := markerNumbers(parts[2])
+		if !ok {
+			return match
+		}
+		var b strings.Builder
+		for _, n := range numbers {
+			if n >= 1 && n <= sources {
+				b.WriteString("[" + strconv.Itoa(n) + "]")
+			}
+		}
+		if b.Len() == 0 {
+			return ""
+		}
+		return parts[1] + b.String()
+	})
+
+	seen := make(map[int]bool)
+	answer = markerRunPattern.ReplaceAllStringFunc(answer, func(run string) string {
+		if _, ok := markerNumbers(run); !ok {
+			return run
+		}
+		var b strings.Builder
+		inRun := make(map[int]bool)
+		for _, raw := range markerNumberPattern.FindAllString(run, -1) {
+			n, _ := strconv.Atoi(raw)
+			if n < 1 || n > sources || inRun[n] {
+				continue
+			}
+			inRun[n], seen[n] = true, true
+			b.WriteString("[" + raw + "]")
+		}
+		return b.String()
+	})
+
+	cited := make([]int, 0, len(seen))
+	for n := range seen {
+		cited = append(cited, n)
+	}
+	sort.Ints(cited)
+	return strings.TrimSpace(answer), cited
+}
+
+// markerNumbers expands a marker's list of numbers and ranges. ok is false when a number
+// cannot be a citation.
+func markerNumbers(list string) ([]int, bool) {
+	var numbers []int
+	raw := markerNumberPattern.FindAllStringIndex(list, -1)
+	for i := 0; i < len(raw); i++ {
+		n, err := strconv.Atoi(list[raw[i][0]:raw[i][1]])
+		if err != nil || n == 0 || n > maxMarkerNumber {
+			return nil, false
+		}
+		numbers = append(numbers, n)
+		if i+1 < len(raw) {
+			sep := strings.TrimSpace(list[raw[i][1]:raw[i+1][0]])
+			if sep == "-" || sep == "–" {
+				end, err := strconv.Atoi(list[raw[i+1][0]:raw[i+1][1]])
+				if err != nil || end > maxMarkerNumber {
+					return nil, false
+				}
+				for m := n + 1; m < end; m++ {
+					numbers = append(numbers, m)
+				}
+			}
+		}
+	}
+	return numbers, true
+}
@@ -0,0 +1,51 @@
+package app
+
+// mmrCandidateFactor is how many first-stage candidates per selected source a diversified
+// ask considers.
+const mmrCandidateFactor = 4
+
+// selectMMR picks k of sources by maximal marginal relevance: each pick maximizes
+// lambda*relevance - (1-lambda)*(highest similarity to a source already picked), where
+// similarity is the cosine between embeddings. sources must be in rank order, after
+// hybrid fusion or reranking, and relevance comes from that rank, falling from 1 for the
+// first source to 1/n for the last, so diversifying keeps the keyword and reranker
+// signals instead of falling back to embedding similarity. Ties go to the source ranked
+// first. lambda 1 keeps the ranked order; lower values trade relevance for skipping
+// near-duplicates.
+func selectMMR(sources []askSource, k int, lambda float64) []askSource {
+	if k >= len(sources) {
+		return sources
+	}
+	n := len(sources)
+	relevance := make([]float64, n)
+	for i := range sources {
+		relevance[i] = float64(n-i) / float64(n)
+	}
+	picked := make([]bool, n)
+	// maxSim[i] is source i's highest similarity to the picks so far.
+	maxSim := make([]float64, n)
+	selected := make([]askSource, 0, k)
+	for len(selected) < k {
+		best, bestScore := -1, 0.0
+		for i := range sources {
+			if picked[i] {
+				continue
+			}
+			score := lambda*relevance[i] - (1-lambda)*maxSim[i]
+			if best < 0 || score > bestScore {
+				best, bestScore = i, score
+			}
+		}
+		picked[best] = true
+		selected = append(selected, sources[best])
+		for i := range sources {
+			if picked[i] {
+				continue
+			}
+			if sim := float64(cosineSimilarity(sources[i].embedding, sources[best].embedding)); sim > maxSim[i] {
+				maxSim[i] = sim
+			}
+		}
+	}
+	return selected
+}
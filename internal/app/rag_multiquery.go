@@ -0,0 +1,158 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+
+	"gopherai-resume/internal/ai"
+	"gopherai-resume/internal/model"
+	"gopherai-resume/internal/repository"
+)
+
+// maxQueryParaphrases is how many paraphrases a multi-query ask searches for besides the
+// question itself.
+const maxQueryParaphrases = 3
+
+// paraphraseQuery asks the chat model for up to maxQueryParaphrases rewordings of query
+// that may match differently phrased passages, taking one call from userID's ask budget
+// and their paraphrases' embeddings from the embedding quota. If the budget or quota is
+// spent or the call fails, it returns none and the ask searches for the query alone.
+func (s *RAGService) paraphraseQuery(ctx context.Context, userID uint, query string) []string {
+	if s.askBudget != nil {
+		if err := s.askBudget(ctx, userID); err != nil {
+			log.Printf("paraphrase rag query for user %d skipped, searching for the question only: %v", userID, err)
+			return nil
+		}
+	}
+	messages := []ai.ChatMessage{
+		{Role: "system", Content: fmt.Sprintf(
+			`Rewrite the user's search query in %d different ways that could match relevant passages in their documents: use synonyms, expand abbreviations, and make implied terms explicit. If the query has a previous question before it, resolve references to it. Keep each rewrite to one line with the same meaning and language. Reply with JSON only: {"queries": [...]}.`,
+			maxQueryParaphrases,
+		)},
+		{Role: "user", Content: query},
+	}
+	raw, err := s.completer.Complete(ctx, s.chatConfig, messages)
+	if err != nil {
+		log.Printf("paraphrase rag query failed, searching for the question only: %v", err)
+		return nil
+	}
+	var parsed struct {
+		Queries []string `json:"queries"`
+	}
+	if err := decodeLLMJSON(raw, &parsed); err != nil {
+		log.Printf("paraphrase rag query returned no queries, searching for the question only: %v", err)
+		return nil
+	}
+	seen := map[string]bool{strings.ToLower(strings.TrimSpace(query)): true}
+	var paraphrases []string
+	for _, q := range parsed.Queries {
+		q = strings.TrimSpace(q)
+		key := strings.ToLower(q)
+		if q == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		paraphrases = append(paraphrases, truncateRunes(q, 500))
+		if len(paraphrases) == maxQueryParaphrases {
+			break
+		}
+	}
+	if err := s.quota.Consume(ctx, userID, QuotaEmbeddingInputs, int64(len(paraphrases))); err != nil {
+		log.Printf("embed rag query paraphrases for user %d skipped, searching for the question only: %v", userID, err)
+		return nil
+	}
+	return paraphrases
+}
+
+// retrieveParaphrases ranks the sources for each paraphrase and merges the rankings with
+// ranked, the question's own, by reciprocal rank fusion, so sources that several phrasings
+// find come first. A source's score is its best similarity to any of the queries. chunks
+// are the question's retrieved chunks; without a vector store they are every chunk of the
+// documents and are reused instead of loaded again. It returns the best k. The embedding
+// quota paraphraseQuery took for userID is refunded if embedding fails.
+func (s *RAGService) retrieveParaphrases(
+	ctx context.Context,
+	userID uint,
+	ranked []askSource,
+	paraphrases []string,
+	chunks []model.RAGChunk,
+	docIDs []uint,
+	history []repository.EmbeddedMessage,
+	k int,
+) ([]askSource, error) {
+	embeddings, err := s.embedder.EmbedBatch(ctx, s.embConfig, paraphrases)
+	if err == nil && len(embeddings) != len(paraphrases) {
+		err = fmt.Errorf("embedded %d of %d query paraphrases", len(embeddings), len(paraphrases))
+	}
+	if err != nil {
+		if refundErr := s.quota.Refund(ctx, userID, map[string]int64{QuotaEmbeddingInputs: int64(len(paraphrases))}); refundErr != nil {
+			log.Printf("refund embedding quota for user %d failed: %v", userID, refundErr)
+		}
+		return nil, err
+	}
+
+	rankings := [][]askSource{ranked}
+	for i, q := range paraphrases {
+		found := chunks
+		if s.vectors != nil && len(docIDs) > 0 {
+			found, err = s.retrievableChunks(ctx, docIDs, q, embeddings[i], k)
+			if err != nil {
+				return nil, err
+			}
+		}
+		rankings = append(rankings, rankSources(q, embeddings[i], found, history, k))
+	}
+	return fuseSourceRankings(rankings, k), nil
+}
+
+// fuseSourceRankings merges rankings of the same sources with reciprocal rank fusion and
+// returns the best k. Sources are matched by chunk or message ID.
+func fuseSourceRankings(rankings [][]askSource, k int) []askSource {
+	type fused struct {
+		source askSource
+		score  float64
+		first  int // order of first appearance, for stable ties
+	}
+	byKey := make(map[string]*fused)
+	for _, ranking := range rankings {
+		for rank, src := range ranking {
+			key := sourceKey(src)
+			f, ok := byKey[key]
+			if !ok {
+				f = &fused{source: src, first: len(byKey)}
+				byKey[key] = f
+			} else if src.score > f.source.score {
+				f.source.score = src.score
+			}
+			f.score += 1 / float64(rrfK+rank+1)
+		}
+	}
+	merged := make([]*fused, 0, len(byKey))
+	for _, f := range byKey {
+		merged = append(merged, f)
+	}
+	sort.Slice(merged, func(a, b int) bool {
+		if merged[a].score != merged[b].score {
+			return merged[a].score > merged[b].score
+		}
+		return merged[a].first < merged[b].first
+	})
+	if k > len(merged) {
+		k = len(merged)
+	}
+	out := make([]askSource, k)
+	for i := range out {
+		out[i] = merged[i].source
+	}
+	return out
+}
+
+func sourceKey(src askSource) string {
+	if src.chunk != nil {
+		return fmt.Sprintf("chunk:%d", src.chunk.ID)
+	}
+	return fmt.Sprintf("message:%d", src.message.ID)
+}
@@ -0,0 +1,127 @@
+package app
+
+import (
+	"context"
+	"net/http"
+
+	"gopherai-resume/internal/pkg/apperr"
+	"gopherai-resume/internal/pkg/limitwarn"
+	"gopherai-resume/internal/repository"
+)
+
+var ErrRAGStorageQuota = apperr.New(http.StatusTooManyRequests, apperr.CodeQuotaExceeded, "rag storage quota exceeded, delete documents to free space")
+
+// RAG storage quota metrics, as reported by StorageQuota and in limit warnings.
+const (
+	QuotaRAGDocuments = "rag_documents"
+	QuotaRAGChunks    = "rag_chunks"
+	QuotaRAGBytes     = "rag_bytes"
+)
+
+// RAGStorageLimits caps what each user keeps in RAG at any time; 0 is unlimited. Bytes
+// count the text of the stored chunks.
+type RAGStorageLimits struct {
+	Documents int
+	Chunks    int
+	Bytes     int64
+}
+
+// RAGStorageQuota is a user's RAG storage against the limits.
+type RAGStorageQuota struct {
+	Documents MetricUsage `json:"documents"`
+	Chunks    MetricUsage `json:"chunks"`
+	Bytes     MetricUsage `json:"bytes"`
+}
+
+// StorageQuota reports the user's RAG storage usage and limits.
+func (s *RAGService) StorageQuota(ctx context.Context, userID uint) (*RAGStorageQuota, error) {
+	if userID == 0 {
+		return nil, ErrInvalidInput
+	}
+	used, err := s.chunkRepo.StorageByUserID(ctx, userID, 0)
+	if err != nil {
+		return nil, err
+	}
+	return &RAGStorageQuota{
+		Documents: MetricUsage{Used: used.DocumentCount, Limit: int64(s.storageLimits.Documents)},
+		Chunks:    MetricUsage{Used: used.ChunkCount, Limit: int64(s.storageLimits.Chunks)},
+		Bytes:     MetricUsage{Used: used.ContentBytes, Limit: s.storageLimits.Bytes},
+	}, nil
+}
+
+// checkStorage fails with ErrRAGStorageQuota when adding documents, chunks and bytes to
+// the user's RAG storage would pass a limit. The current chunks of excludeDocumentID, whose
+// content is being replaced, are not counted. Nearing a limit adds a limitwarn warning.
+// It runs before the costly work of a write; storageCheck checks again as it is stored.
+func (s *RAGService) checkStorage(ctx context.Context, userID, excludeDocumentID uint, documents, chunks int, bytes int64) error {
+	if !s.storageLimited() {
+		return nil
+	}
+	used, err := s.chunkRepo.StorageByUserID(ctx, userID, excludeDocumentID)
+	if err != nil {
+		return err
+	}
+	totals := s.storageTotals(used, documents, chunks, bytes)
+	if err := overStorage(totals); err != nil {
+		return err
+	}
+	for _, t := range totals {
+		limitwarn.Check(ctx, limitwarn.KindStorage, t.metric, t.total, t.limit)
+	}
+	return nil
+}
+
+// storageCheck has a write check the user's RAG storage again inside its transaction,
+// so that writes running at once cannot pass a limit together; nil when there are none.
+func (s *RAGService) storageCheck(userID uint) *repository.StorageCheck {
+	if !s.storageLimited() {
+		return nil
+	}
+	return &repository.StorageCheck{
+		UserID: userID,
+		Check: func(used repository.RAGUserStorage) error {
+			return overStorage(s.storageTotals(used, 0, 0, 0))
+		},
+	}
+}
+
+func (s *RAGService) storageLimited() bool {
+	limits := s.storageLimits
+	return limits.Documents > 0 || limits.Chunks > 0 || limits.Bytes > 0
+}
+
+// storageTotal is one metric of a user's RAG storage against its limit.
+type storageTotal struct {
+	metric string
+	total  int64
+	limit  int64
+}
+
+// storageTotals adds documents, chunks and bytes to the storage used.
+func (s *RAGService) storageTotals(used repository.RAGUserStorage, documents, chunks int, bytes int64) []storageTotal {
+	limits := s.storageLimits
+	return []storageTotal{
+		{QuotaRAGDocuments, used.DocumentCount + int64(documents), int64(limits.Documents)},
+		{QuotaRAGChunks, used.ChunkCount + int64(chunks), int64(limits.Chunks)},
+		{QuotaRAGBytes, used.ContentBytes + bytes, limits.Bytes},
+	}
+}
+
+// overStorage returns ErrRAGStorageQuota for the first total over its limit.
+func overStorage(totals []storageTotal) error {
+	for _, t := range totals {
+		if t.limit > 0 && t.total > t.limit {
+			return ErrRAGStorageQuota.Withf("%s would reach %d of %d", t.metric, t.total, t.limit)
+		}
+	}
+	return nil
+}
+
+// chunkBytes is the size of the chunks' text.
+func chunkBytes(chunks []textChunk) int64 {
+	var n int64
+	for _, c := range chunks {
+		n += int64(len(c.text))
+	}
+	return n
+}
@@ -0,0 +1,85 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+
+	"gopherai-resume/internal/ai"
+)
+
+const (
+	// defaultRerankCandidates is how many first-stage candidates a reranked ask considers.
+	defaultRerankCandidates = 40
+	// rerankPassageRunes caps each passage sent to the chat model for scoring.
+	rerankPassageRunes = 600
+)
+
+// rerankSources reorders sources by rerank score. If scoring fails, the first-stage order
+// is kept so the question is still answered.
+func (s *RAGService) rerankSources(ctx context.Context, query string, sources []askSource) []askSource {
+	if len(sources) < 2 {
+		return sources
+	}
+	texts := make([]string, len(sources))
+	for i, src := range sources {
+		texts[i] = src.text()
+	}
+	var scores []float64
+	var err error
+	if s.reranker != nil {
+		scores, err = s.reranker.Rerank(ctx, s.rerankConfig, query, texts)
+	} else {
+		scores, err = s.scoreWithChatModel(ctx, query, texts)
+	}
+	if err != nil {
+		log.Printf("rerank %d candidates failed, keeping retrieval order: %v", len(sources), err)
+		return sources
+	}
+
+	order := make([]int, len(sources))
+	for i := range order {
+		order[i] = i
+	}
+	// Stable, so ties keep their first-stage order.
+	sort.SliceStable(order, func(a, b int) bool { return scores[order[a]] > scores[order[b]] })
+	reranked := make([]askSource, len(sources))
+	for i, idx := range order {
+		reranked[i] = sources[idx]
+	}
+	return reranked
+}
+
+// scoreWithChatModel asks the chat model to rate each passage's relevance from 0 to 10.
+func (s *RAGService) scoreWithChatModel(ctx context.Context, query string, texts []string) ([]float64, error) {
+	var b strings.Builder
+	b.WriteString("Query: ")
+	b.WriteString(query)
+	b.WriteString("\n\nPassages:")
+	for i, text := range texts {
+		fmt.Fprintf(&b, "\n[%d] %s", i, strings.Join(strings.Fields(truncateRunes(text, rerankPassageRunes)), " "))
+	}
+	messages := []ai.ChatMessage{
+		{Role: "system", Content: fmt.Sprintf(
+			`Rate how well each passage helps answer the query, from 0 (irrelevant) to 10 (answers it directly). Reply with JSON only: {"scores": [...]} with exactly %d numbers, one per passage in order.`,
+			len(texts),
+		)},
+		{Role: "user", Content: b.String()},
+	}
+	raw, err := s.completer.Complete(ctx, s.chatConfig, messages)
+	if err != nil {
+		return nil, err
+	}
+	var parsed struct {
+		Scores []float64 `json:"scores"`
+	}
+	if err := decodeLLMJSON(raw, &parsed); err != nil {
+		return nil, err
+	}
+	if len(parsed.Scores) != len(texts) {
+		return nil, fmt.Errorf("chat model returned %d scores for %d passages", len(parsed.Scores), len(texts))
+	}
+	return parsed.Scores, nil
+}
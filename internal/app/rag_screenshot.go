@@ -0,0 +1,137 @@
+package app
+
+import (
+	"context"
+	"log"
+	"strings"
+	"time"
+)
+
+// temporaryDocumentMaxAge is how old a temporary document must be before
+// SweepTemporaryDocuments takes it for left over by a request that never deleted it.
+const temporaryDocumentMaxAge = time.Hour
+
+// ScreenImageInput is a resume screenshot or scan and the screening questions to answer
+// about it. TopK is as for AskInput, and Admit as for AskBatchInput. Hold, when set, takes
+// one of the user's in-flight slots for the OCR and ingest, and the func it returns gives
+// it back before the questions are answered.
+type ScreenImageInput struct {
+	UserID    uint
+	Name      string
+	Image     []byte
+	MIMEType  string
+	Questions []string
+	TopK      int
+	Admit     func(ctx context.Context) (func(), error)
+	Hold      func(ctx context.Context) (func(), error)
+}
+
+// ScreenImageResult is the recognized text, how many chunks it was split into, and the
+// answers as AskBatch returns them.
+type ScreenImageResult struct {
+	OCRText    string          `json:"ocr_text"`
+	ChunkCount int             `json:"chunk_count"`
+	Answers    []BatchAnswer   `json:"answers"`
+	Summary    AskBatchSummary `json:"summary"`
+}
+
+// ScreenImage runs OCR on the image, ingests the text as a temporary document, answers
+// the questions about that document alone, and deletes it again, whether or not answering
+// succeeded. The document stays out of the user's listings and unscoped asks meanwhile,
+// and SweepTemporaryDocuments removes it if the process stops first. The questions and the document quota are checked first, so a request that
+// would fail anyway costs no OCR.
+func (s *RAGService) ScreenImage(ctx context.Context, input ScreenImageInput) (*ScreenImageResult, error) {
+	if input.UserID == 0 || len(input.Image) == 0 || len(input.Questions) == 0 {
+		return nil, ErrInvalidInput
+	}
+	if len(input.Questions) > s.batchMaxQuestions {
+		return nil, ErrInvalidInput.Withf("at most %d questions per batch", s.batchMaxQuestions)
+	}
+	if err := s.checkStorage(ctx, input.UserID, 0, 1, 0, 0); err != nil {
+		return nil, err
+	}
+
+	release := func() {}
+	if input.Hold != nil {
+		done, err := input.Hold(ctx)
+		if err != nil {
+			return nil, err
+		}
+		release = done
+	}
+	text, ingest, err := s.ingestScreenshot(ctx, input)
+	release()
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err := s.DeleteDocument(context.WithoutCancel(ctx), input.UserID, ingest.Document.ID); err != nil {
+			log.Printf("delete temporary rag document %d failed: %v", ingest.Document.ID, err)
+		}
+	}()
+
+	answers, err := s.AskBatch(ctx, AskBatchInput{
+		Options: AskInput{
+			UserID:      input.UserID,
+			DocumentIDs: []uint{ingest.Document.ID},
+			TopK:        input.TopK,
+		},
+		Questions: input.Questions,
+		Admit:     input.Admit,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &ScreenImageResult{
+		OCRText:    text,
+		ChunkCount: ingest.ChunkCount,
+		Answers:    answers.Answers,
+		Summary:    answers.Summary,
+	}, nil
+}
+
+// ingestScreenshot runs OCR on the image and ingests the text as a temporary document.
+func (s *RAGService) ingestScreenshot(ctx context.Context, input ScreenImageInput) (string, *IngestResult, error) {
+	text, err := s.ocr.RecognizeText(ctx, s.ocrConfig, input.Image, input.MIMEType)
+	if err != nil {
+		return "", nil, err
+	}
+	if strings.TrimSpace(text) == "" {
+		return "", nil, ErrOCRNoText
+	}
+	name := strings.TrimSpace(input.Name)
+	if name == "" {
+		name = "Screenshot"
+	}
+	ingest, err := s.Ingest(ctx, IngestInput{
+		UserID:         input.UserID,
+		Name:           name,
+		Content:        text,
+		AllowDuplicate: true,
+		temporary:      true,
+	})
+	if err != nil {
+		return "", nil, err
+	}
+	return text, ingest, nil
+}
+
+// SweepTemporaryDocuments deletes the temporary documents that requests left behind, as
+// happens when the process stops before one deletes its document. Documents younger than
+// temporaryDocumentMaxAge may still be in use and are kept. It returns how many documents
+// it deleted.
+func (s *RAGService) SweepTemporaryDocuments(ctx context.Context) (int, error) {
+	docs, err := s.docRepo.ListTemporaryBefore(ctx, time.Now().Add(-temporaryDocumentMaxAge))
+	if err != nil {
+		return 0, err
+	}
+	deleted := 0
+	for _, doc := range docs {
+		if err := s.DeleteDocument(ctx, doc.UserID, doc.ID); err != nil {
+			log.Printf("delete temporary rag document %d failed: %v", doc.ID, err)
+			continue
+		}
+		deleted++
+	}
+	return deleted, nil
+}
@@ -2,51 +2,216 @@ package app
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
+	"fmt"
+	"log"
+	"sort"
 	"strings"
+	"time"
 
 	"gopherai-resume/internal/ai"
 	"gopherai-resume/internal/model"
+	"gopherai-resume/internal/pkg/apperr"
+	"gopherai-resume/internal/pkg/authz"
+	"gopherai-resume/internal/rag"
 	"gopherai-resume/internal/repository"
+	"gopherai-resume/internal/storage"
 )
 
 const (
 	defaultChunkSize    = 512
 	defaultChunkOverlap = 64
 	defaultTopK         = 5
+	maxTopK             = 20 // caps per-request top_k for asks and comparisons
 	embeddingBatchSize  = 10 // DashScope and similar APIs often limit batch size
+	// vectorCandidateFactor is how many vector store matches are fetched per chunk to
+	// select, so keyword scores can still reorder them.
+	vectorCandidateFactor = 4
+
+	suggestionSampleSize = 8
+	suggestionCount      = 5
+
+	compareMinDocuments = 2
+	compareMaxDocuments = 5
+	compareTopKPerDoc   = 3
+
+	// ragFollowUpTurns is how many earlier questions and answers of a RAG session are sent
+	// with a new question, so follow-ups can refer to them.
+	ragFollowUpTurns   = 4
+	defaultRAGHistory  = 50
+	maxRAGHistoryLimit = 200
 )
 
 var (
-	ErrRAGNoDocuments   = errors.New("no documents to search")
-	ErrRAGNoChunks      = errors.New("no chunks found for retrieval")
-	ErrRAGSessionNotFound = errors.New("rag session not found")
+	ErrRAGNoDocuments      = apperr.BadRequest("no documents to search")
+	ErrRAGNoChunks         = apperr.BadRequest("no chunks found for retrieval")
+	ErrRAGSessionNotFound  = apperr.NotFound(apperr.CodeSessionNotFound, "rag session not found")
+	ErrRAGDocumentNotFound = apperr.NotFound(apperr.CodeDocumentNotFound, "rag document not found")
+	ErrRAGDocumentNotReady = apperr.Conflict(apperr.CodeDocumentNotReady, "rag document is still being ingested")
+	ErrRAGNothingToAppend  = apperr.BadRequest("the document already holds all of the appended text")
+
+	ErrRAGCompareDocumentCount = apperr.BadRequest("compare requires 2 to 5 distinct documents")
+	ErrOCRNoText               = apperr.BadRequest("no text recognized in image")
 )
 
 type RAGService struct {
-	sessionRepo *repository.RAGSessionRepository
-	docRepo     *repository.RAGDocumentRepository
-	chunkRepo   *repository.RAGChunkRepository
-	llmClient   *ai.OpenAICompatibleClient
-	embConfig   ai.EmbeddingConfig
-	chatConfig  ai.ChatConfig
+	sessionRepo     RAGSessionRepository
+	docRepo         RAGDocumentRepository
+	chunkRepo       RAGChunkRepository
+	messageRepo     RAGMessageRepository
+	messageEmbRepo  MessageEmbeddingRepository
+	embedder        ai.Embedder
+	completer       ai.Completer
+	ocr             ai.OCR
+	embConfig       ai.EmbeddingConfig
+	chatConfig      ai.ChatConfig
+	ocrConfig       ai.ChatConfig
+	suggestionCache SuggestionCache
+	notifier        Notifier // nil disables ingestion emails
+	activity        ActivityRecorder
+	// vectors, when set, indexes chunk embeddings and answers retrieval searches;
+	// nil scores every chunk of the searched documents in process.
+	vectors rag.VectorStore
+	// quantizeEmbeddings stores new chunk embeddings as int8 instead of float32.
+	quantizeEmbeddings bool
+	// reranker scores AskInput.Rerank candidates with rerankConfig's model; nil scores
+	// them with the chat model instead.
+	reranker         ai.Reranker
+	rerankConfig     ai.RerankConfig
+	rerankCandidates int
+	// mmrLambda weighs relevance against diversity for AskInput.Diversify, from 0 to 1.
+	mmrLambda float64
+	// citationMarkers and multiQuery are the defaults of AskInput.CitationMarkers and
+	// AskInput.MultiQuery.
+	citationMarkers bool
+	multiQuery      bool
+	// batchMaxQuestions and batchConcurrency bound AskBatch.
+	batchMaxQuestions int
+	batchConcurrency  int
+	storageLimits     RAGStorageLimits
+	// dedupeChunksEnabled and nearDuplicateDistance configure dedupeChunks.
+	dedupeChunksEnabled   bool
+	nearDuplicateDistance int
+	// shadowEmbedder embeds chunks and comparison queries with shadowConfig's model, which
+	// is empty when no shadow model is configured.
+	shadowRepo     RAGShadowEmbeddingRepository
+	shadowEmbedder ai.Embedder
+	shadowConfig   ai.EmbeddingConfig
+	shadow         *shadowBackfill
+	// store holds the content of asynchronous ingests until ingestQueue's worker (or, when
+	// ingestQueue is nil, a goroutine) has processed it.
+	store       storage.ObjectStore
+	ingestQueue RAGIngestQueue
+	// ingestEmbeddings saves the embeddings of unfinished asynchronous ingests so a retry
+	// resumes where the last attempt stopped; nil embeds everything again.
+	ingestEmbeddings RAGIngestEmbeddingRepository
+	// modelPolicy decides whether a user may be answered with chatConfig's model; nil
+	// allows everyone.
+	modelPolicy ModelPolicy
+	// quota and askBudget meter the paraphrasing of multi-query asks; either may be nil.
+	quota     *QuotaService
+	askBudget AskBudget
+}
+
+// AskBudget counts one extra model call of a RAG ask against the user's ask budget and
+// fails when it is spent.
+type AskBudget func(ctx context.Context, userID uint) error
+
+// SuggestionCache caches suggested questions per document-set hash.
+type SuggestionCache interface {
+	GetSuggestions(ctx context.Context, setHash string) ([]string, bool, error)
+	SetSuggestions(ctx context.Context, setHash string, questions []string) error
 }
 
 func NewRAGService(
-	sessionRepo *repository.RAGSessionRepository,
-	docRepo *repository.RAGDocumentRepository,
-	chunkRepo *repository.RAGChunkRepository,
-	llmClient *ai.OpenAICompatibleClient,
+	sessionRepo RAGSessionRepository,
+	docRepo RAGDocumentRepository,
+	chunkRepo RAGChunkRepository,
+	messageRepo RAGMessageRepository,
+	messageEmbRepo MessageEmbeddingRepository,
+	vectors rag.VectorStore,
+	embedder ai.Embedder,
+	completer ai.Completer,
+	ocr ai.OCR,
 	embConfig ai.EmbeddingConfig,
 	chatConfig ai.ChatConfig,
+	ocrConfig ai.ChatConfig,
+	suggestionCache SuggestionCache,
+	notifier Notifier,
+	activity ActivityRecorder,
+	quantizeEmbeddings bool,
+	reranker ai.Reranker,
+	rerankConfig ai.RerankConfig,
+	rerankCandidates int,
+	mmrLambda float64,
+	citationMarkers bool,
+	multiQuery bool,
+	batchMaxQuestions int,
+	batchConcurrency int,
+	storageLimits RAGStorageLimits,
+	dedupeChunks bool,
+	nearDuplicateDistance int,
+	shadowRepo RAGShadowEmbeddingRepository,
+	shadowEmbedder ai.Embedder,
+	shadowConfig ai.EmbeddingConfig,
+	store storage.ObjectStore,
+	ingestQueue RAGIngestQueue,
+	ingestEmbeddings RAGIngestEmbeddingRepository,
+	modelPolicy ModelPolicy,
+	quota *QuotaService,
+	askBudget AskBudget,
 ) *RAGService {
+	if rerankCandidates <= 0 {
+		rerankCandidates = defaultRerankCandidates
+	}
+	if batchMaxQuestions <= 0 {
+		batchMaxQuestions = defaultBatchMaxQuestions
+	}
+	if batchConcurrency <= 0 {
+		batchConcurrency = 1
+	}
 	return &RAGService{
-		sessionRepo: sessionRepo,
-		docRepo:     docRepo,
-		chunkRepo:   chunkRepo,
-		llmClient:   llmClient,
-		embConfig:   embConfig,
-		chatConfig:  chatConfig,
+		sessionRepo:     sessionRepo,
+		docRepo:         docRepo,
+		chunkRepo:       chunkRepo,
+		messageRepo:     messageRepo,
+		messageEmbRepo:  messageEmbRepo,
+		vectors:         vectors,
+		embedder:        embedder,
+		completer:       completer,
+		ocr:             ocr,
+		embConfig:       embConfig,
+		chatConfig:      chatConfig,
+		ocrConfig:       ocrConfig,
+		suggestionCache: suggestionCache,
+		notifier:        notifier,
+		activity:        activity,
+
+		quantizeEmbeddings: quantizeEmbeddings,
+		reranker:           reranker,
+		rerankConfig:       rerankConfig,
+		rerankCandidates:   rerankCandidates,
+		mmrLambda:          mmrLambda,
+		citationMarkers:    citationMarkers,
+		multiQuery:         multiQuery,
+		batchMaxQuestions:  batchMaxQuestions,
+		batchConcurrency:   batchConcurrency,
+		storageLimits:      storageLimits,
+		shadowRepo:         shadowRepo,
+		shadowEmbedder:     shadowEmbedder,
+		shadowConfig:       shadowConfig,
+		shadow:             &shadowBackfill{},
+		store:              store,
+		ingestQueue:        ingestQueue,
+		ingestEmbeddings:   ingestEmbeddings,
+		modelPolicy:        modelPolicy,
+		quota:              quota,
+		askBudget:          askBudget,
+
+		dedupeChunksEnabled:   dedupeChunks,
+		nearDuplicateDistance: nearDuplicateDistance,
 	}
 }
 
@@ -57,7 +222,7 @@ type RAGCreateSessionInput struct {
 }
 
 // CreateSession creates a new RAG session.
-func (s *RAGService) CreateSession(input RAGCreateSessionInput) (*model.RAGSession, error) {
+func (s *RAGService) CreateSession(ctx context.Context, input RAGCreateSessionInput) (*model.RAGSession, error) {
 	if input.UserID == 0 {
 		return nil, ErrInvalidInput
 	}
@@ -66,55 +231,71 @@ func (s *RAGService) CreateSession(input RAGCreateSessionInput) (*model.RAGSessi
 		title = "New RAG"
 	}
 	session := &model.RAGSession{UserID: input.UserID, Title: title}
-	if err := s.sessionRepo.Create(session); err != nil {
+	if err := s.sessionRepo.Create(ctx, session); err != nil {
 		return nil, err
 	}
+	recordActivity(ctx, s.activity, input.UserID, ActivityRAGSessionCreated, session.ID,
+		fmt.Sprintf("Created knowledge base session %q", title))
 	return session, nil
 }
 
 // ListSessions returns all RAG sessions for the user.
-func (s *RAGService) ListSessions(userID uint) ([]model.RAGSession, error) {
+func (s *RAGService) ListSessions(ctx context.Context, userID uint) ([]model.RAGSession, error) {
 	if userID == 0 {
 		return nil, ErrInvalidInput
 	}
-	return s.sessionRepo.ListByUserID(userID)
+	return s.sessionRepo.ListByUserID(ctx, userID)
 }
 
 // DeleteSession deletes a RAG session and all its documents (and chunks).
-func (s *RAGService) DeleteSession(userID, sessionID uint) error {
+func (s *RAGService) DeleteSession(ctx context.Context, userID, sessionID uint) error {
 	if userID == 0 || sessionID == 0 {
 		return ErrInvalidInput
 	}
-	session, err := s.sessionRepo.GetByIDAndUserID(sessionID, userID)
+	session, err := loadRAGSession(ctx, s.sessionRepo, sessionID, userID, authz.Write)
 	if err != nil || session == nil {
 		return ErrRAGSessionNotFound
 	}
-	docIDs, err := s.docRepo.ListBySessionID(sessionID)
+	docIDs, err := s.docRepo.ListBySessionID(ctx, sessionID)
 	if err != nil {
 		return err
 	}
 	for _, docID := range docIDs {
-		_ = s.chunkRepo.DeleteByDocumentID(docID)
+		if err := s.chunkRepo.DeleteByDocumentID(ctx, docID); err != nil {
+			return err
+		}
+		if err := s.unindexDocument(ctx, docID); err != nil {
+			return err
+		}
+	}
+	if err := s.docRepo.DeleteBySessionID(ctx, sessionID); err != nil {
+		return err
 	}
-	if err := s.docRepo.DeleteBySessionID(sessionID); err != nil {
+	if err := s.messageRepo.DeleteBySessionID(ctx, sessionID); err != nil {
 		return err
 	}
-	return s.sessionRepo.DeleteByIDAndUserID(sessionID, userID)
+	return s.sessionRepo.DeleteByIDAndUserID(ctx, sessionID, userID)
 }
 
 // DeleteDocument deletes a document and its chunks.
-func (s *RAGService) DeleteDocument(userID, documentID uint) error {
+func (s *RAGService) DeleteDocument(ctx context.Context, userID, documentID uint) error {
 	if userID == 0 || documentID == 0 {
 		return ErrInvalidInput
 	}
-	doc, err := s.docRepo.GetByIDAndUserID(documentID, userID)
+	doc, err := loadRAGDocument(ctx, s.docRepo, documentID, userID, authz.Write)
 	if err != nil || doc == nil {
 		return ErrInvalidInput
 	}
-	if err := s.chunkRepo.DeleteByDocumentID(doc.ID); err != nil {
+	if err := s.chunkRepo.DeleteByDocumentID(ctx, doc.ID); err != nil {
 		return err
 	}
-	return s.docRepo.DeleteByIDAndUserID(doc.ID, userID)
+	if err := s.unindexDocument(ctx, doc.ID); err != nil {
+		return err
+	}
+	if doc.Status != DocumentReady {
+		s.discardIngest(ctx, doc.ID)
+	}
+	return s.docRepo.DeleteByIDAndUserID(ctx, doc.ID, userID)
 }
 
 // IngestInput is the input for adding a document.
@@ -123,20 +304,58 @@ type IngestInput struct {
 	SessionID uint // 0 = no session
 	Name      string
 	Content   string
+	// Paged marks Content as the text of a PDF, with docextract.PageBreak between pages;
+	// each chunk then records the page it starts on.
+	Paged bool
+	// ChunkSize, ChunkOverlap and ChunkStrategy choose how Content is split; zero values
+	// use 512 runes, a 64-rune overlap and ChunkStrategyFixed. The document keeps them for
+	// later appends and replacements.
+	ChunkSize     int
+	ChunkOverlap  *int
+	ChunkStrategy string
+	// FileHash is the hex SHA-256 of the uploaded file Content was extracted from. Unless
+	// AllowDuplicate is set, uploading a file already ingested into the same session returns
+	// the existing document instead of embedding it again.
+	FileHash       string
+	AllowDuplicate bool
+	// Progress, if set, is called as ingestion moves through its stages.
+	Progress func(IngestProgress)
+	// temporary marks a document deleted again within the request, which is not worth
+	// an activity entry or a notification and is kept out of listings meanwhile.
+	temporary bool
+}
+
+// Ingest progress stages.
+const (
+	IngestStageChunked  = "chunked"
+	IngestStageEmbedded = "embedded"
+)
+
+// IngestProgress reports how far an ingest has got. For IngestStageChunked, Total is the
+// chunk count; for IngestStageEmbedded, Done of Total embedding batches are finished.
+type IngestProgress struct {
+	Stage string `json:"stage"`
+	Done  int    `json:"done"`
+	Total int    `json:"total"`
 }
 
 // IngestResult is the result of document ingest.
 type IngestResult struct {
 	Document   model.RAGDocument `json:"document"`
-	ChunkCount int              `json:"chunk_count"`
+	ChunkCount int               `json:"chunk_count"`
+	// Duplicate is set when Document is an earlier upload of the same file and nothing was
+	// ingested.
+	Duplicate bool `json:"duplicate,omitempty"`
+	// ChunkDedupe counts the chunks left out because the document already had their text.
+	ChunkDedupe
 }
 
 // ListDocuments returns RAG documents for the user; if sessionID is 0, returns all.
-func (s *RAGService) ListDocuments(userID, sessionID uint) ([]model.RAGDocument, error) {
+func (s *RAGService) ListDocuments(ctx context.Context, userID, sessionID uint) ([]model.RAGDocument, error) {
 	if userID == 0 {
 		return nil, ErrInvalidInput
 	}
-	return s.docRepo.ListByUserIDAndSessionID(userID, sessionID)
+	return s.docRepo.ListByUserIDAndSessionID(ctx, userID, sessionID)
 }
 
 // Ingest chunks the content, embeds each chunk, and persists document + chunks.
@@ -144,245 +363,1669 @@ func (s *RAGService) Ingest(ctx context.Context, input IngestInput) (*IngestResu
 	if input.UserID == 0 {
 		return nil, ErrInvalidInput
 	}
-	content := strings.TrimSpace(input.Content)
-	if content == "" {
+	content := trimContent(input.Content)
+	if strings.TrimSpace(content) == "" {
 		return nil, ErrInvalidInput
 	}
+	dup, err := s.findDuplicate(ctx, input.UserID, input.SessionID, input.FileHash, input.AllowDuplicate)
+	if err != nil {
+		return nil, err
+	}
+	if dup != nil {
+		return &IngestResult{Document: *dup, ChunkCount: dup.ChunkCount, Duplicate: true}, nil
+	}
 	name := strings.TrimSpace(input.Name)
 	if name == "" {
 		name = "Untitled"
 	}
+	chunker, err := resolveChunking(input.ChunkSize, input.ChunkOverlap, input.ChunkStrategy)
+	if err != nil {
+		return nil, err
+	}
 
-	chunks := chunkText(content, defaultChunkSize, defaultChunkOverlap)
+	chunks := chunker.split(content)
 	if len(chunks) == 0 {
 		return nil, ErrInvalidInput
 	}
-
-	doc := &model.RAGDocument{
-		UserID:    input.UserID,
-		SessionID: input.SessionID,
-		Name:      name,
+	if input.Paged {
+		assignPages(content, chunks)
+	}
+	chunks, dedupe, err := s.dedupeChunks(ctx, 0, chunks)
+	if err != nil {
+		return nil, err
 	}
-	if err := s.docRepo.Create(doc); err != nil {
+	if err := s.checkStorage(ctx, input.UserID, 0, 1, len(chunks), chunkBytes(chunks)); err != nil {
 		return nil, err
 	}
+	progress := input.Progress
+	if progress == nil {
+		progress = func(IngestProgress) {}
+	}
+	progress(IngestProgress{Stage: IngestStageChunked, Done: len(chunks), Total: len(chunks)})
 
-	// Call embedding API in batches to avoid provider limits.
-	var embeddings [][]float32
-	for i := 0; i < len(chunks); i += embeddingBatchSize {
-		end := i + embeddingBatchSize
-		if end > len(chunks) {
-			end = len(chunks)
-		}
-		batch := chunks[i:end]
-		batched, err := s.llmClient.EmbedBatch(ctx, s.embConfig, batch)
-		if err != nil {
-			return nil, err
-		}
-		embeddings = append(embeddings, batched...)
+	doc := &model.RAGDocument{
+		UserID:     input.UserID,
+		SessionID:  input.SessionID,
+		Name:       name,
+		ChunkCount: len(chunks),
+		PageCount:  pageCount(content, input.Paged),
+		FileHash:   input.FileHash,
+		Status:     DocumentReady,
+		Temporary:  input.temporary,
 	}
-	if len(embeddings) != len(chunks) {
-		return nil, errors.New("embedding count mismatch")
+	chunker.apply(doc)
+	if err := s.docRepo.Create(ctx, doc, s.storageCheck(input.UserID)); err != nil {
+		return nil, err
 	}
 
-	ragChunks := make([]model.RAGChunk, len(chunks))
-	for i := range chunks {
-		ragChunks[i] = model.RAGChunk{
-			DocumentID: doc.ID,
-			Content:    chunks[i],
-		}
-		ragChunks[i].SetEmbedding(embeddings[i])
+	ragChunks, err := s.embedChunks(ctx, doc.ID, 0, chunks, func(done, total int) {
+		progress(IngestProgress{Stage: IngestStageEmbedded, Done: done, Total: total})
+	})
+	if err == nil {
+		err = s.chunkRepo.CreateBatch(ctx, ragChunks, s.storageCheck(input.UserID))
 	}
-	if err := s.chunkRepo.CreateBatch(ragChunks); err != nil {
+	if err != nil {
+		// The document has no chunks, so it is removed again rather than left empty.
+		if delErr := s.docRepo.DeleteByIDAndUserID(context.WithoutCancel(ctx), doc.ID, doc.UserID); delErr != nil {
+			log.Printf("delete rag document %d after failed ingest failed: %v", doc.ID, delErr)
+		}
 		return nil, err
 	}
+	s.indexDocument(ctx, doc, ragChunks, true)
+	if !input.temporary {
+		recordActivity(ctx, s.activity, input.UserID, ActivityDocumentIngested, doc.ID,
+			fmt.Sprintf("Ingested %q as %d chunks", name, len(ragChunks)))
+		notify(ctx, s.notifier, input.UserID, NotifyIngestionComplete,
+			fmt.Sprintf("Document ready: %s", name),
+			fmt.Sprintf("%q was ingested as %d chunks and can now be searched and asked about.%s", name, len(ragChunks), notificationFooter))
+	}
 
 	return &IngestResult{
-		Document:   *doc,
-		ChunkCount: len(ragChunks),
+		Document:    *doc,
+		ChunkCount:  len(ragChunks),
+		ChunkDedupe: dedupe,
 	}, nil
 }
 
-// AskInput is the input for RAG ask.
-type AskInput struct {
-	UserID      uint
-	SessionID   uint   // if non-zero, search only docs in this session
-	Question    string
-	DocumentIDs []uint // empty = search by session or all user's documents
-	TopK        int
+// IngestImageInput is the input for ingesting a photographed or scanned document.
+// AllowDuplicate is as for IngestInput.
+type IngestImageInput struct {
+	UserID         uint
+	SessionID      uint // 0 = no session
+	Name           string
+	Image          []byte
+	MIMEType       string
+	AllowDuplicate bool
 }
 
-// AskResult is the result of RAG ask (answer + used chunks).
-type AskResult struct {
-	Answer   string           `json:"answer"`
-	Chunks   []model.RAGChunk `json:"chunks"`
+// IngestImageResult returns the recognized text alongside the ingest result. OCRText is
+// empty when the image was a duplicate and no OCR ran.
+type IngestImageResult struct {
+	OCRText string        `json:"ocr_text,omitempty"`
+	Ingest  *IngestResult `json:"ingest"`
 }
 
-// Ask retrieves top-k relevant chunks, builds a prompt with them, and calls the LLM.
-func (s *RAGService) Ask(ctx context.Context, input AskInput) (*AskResult, error) {
-	if input.UserID == 0 {
-		return nil, ErrInvalidInput
-	}
-	question := strings.TrimSpace(input.Question)
-	if question == "" {
+// IngestImage runs OCR on the image and ingests the recognized text as a new document.
+func (s *RAGService) IngestImage(ctx context.Context, input IngestImageInput) (*IngestImageResult, error) {
+	if input.UserID == 0 || len(input.Image) == 0 {
 		return nil, ErrInvalidInput
 	}
-
-	topK := input.TopK
-	if topK <= 0 {
-		topK = defaultTopK
-	}
-
-	var docIDs []uint
-	if len(input.DocumentIDs) > 0 {
-		for _, id := range input.DocumentIDs {
-			doc, err := s.docRepo.GetByIDAndUserID(id, input.UserID)
-			if err != nil || doc == nil {
-				continue
-			}
-			docIDs = append(docIDs, id)
-		}
-	} else {
-		var docs []model.RAGDocument
-		var err error
-		if input.SessionID != 0 {
-			docs, err = s.docRepo.ListByUserIDAndSessionID(input.UserID, input.SessionID)
-		} else {
-			docs, err = s.docRepo.ListByUserID(input.UserID)
-		}
+	if input.SessionID != 0 {
+		session, err := loadRAGSession(ctx, s.sessionRepo, input.SessionID, input.UserID, authz.Write)
 		if err != nil {
 			return nil, err
 		}
-		if len(docs) == 0 {
-			return nil, ErrRAGNoDocuments
-		}
-		for _, d := range docs {
-			docIDs = append(docIDs, d.ID)
+		if session == nil {
+			return nil, ErrRAGSessionNotFound
 		}
 	}
-	if len(docIDs) == 0 {
-		return nil, ErrRAGNoDocuments
-	}
 
-	allChunks, err := s.chunkRepo.ListByDocumentIDs(docIDs)
+	sum := sha256.Sum256(input.Image)
+	hash := hex.EncodeToString(sum[:])
+	dup, err := s.findDuplicate(ctx, input.UserID, input.SessionID, hash, input.AllowDuplicate)
 	if err != nil {
 		return nil, err
 	}
-	if len(allChunks) == 0 {
-		return nil, ErrRAGNoChunks
+	if dup != nil {
+		return &IngestImageResult{Ingest: &IngestResult{Document: *dup, ChunkCount: dup.ChunkCount, Duplicate: true}}, nil
+	}
+	// The chunks are checked once the text is known; a full document quota need not wait for OCR.
+	if err := s.checkStorage(ctx, input.UserID, 0, 1, 0, 0); err != nil {
+		return nil, err
 	}
 
-	queryEmb, err := s.llmClient.Embed(ctx, s.embConfig, question)
+	text, err := s.ocr.RecognizeText(ctx, s.ocrConfig, input.Image, input.MIMEType)
 	if err != nil {
 		return nil, err
 	}
-
-	scored := make([]struct {
-		chunk model.RAGChunk
-		score float32
-	}, len(allChunks))
-	for i := range allChunks {
-		vec := allChunks[i].EmbeddingVector()
-		scored[i].chunk = allChunks[i]
-		scored[i].score = cosineSimilarity(queryEmb, vec)
+	if strings.TrimSpace(text) == "" {
+		return nil, ErrOCRNoText
 	}
-	top := topKScored(scored, topK)
 
-	selectedChunks := make([]model.RAGChunk, len(top))
-	for i := range top {
-		selectedChunks[i] = top[i].chunk
+	ingest, err := s.Ingest(ctx, IngestInput{
+		UserID:         input.UserID,
+		SessionID:      input.SessionID,
+		Name:           input.Name,
+		Content:        text,
+		FileHash:       hash,
+		AllowDuplicate: true,
+	})
+	if err != nil {
+		return nil, err
 	}
+	return &IngestImageResult{OCRText: text, Ingest: ingest}, nil
+}
 
-	contextBlock := ""
-	for i, c := range selectedChunks {
-		contextBlock += "\n---\n" + c.Content
-		if i == len(selectedChunks)-1 {
-			contextBlock += "\n---"
-		}
-	}
+// AppendInput is the input for appending content to an existing document.
+type AppendInput struct {
+	UserID     uint
+	DocumentID uint
+	Content    string
+}
 
-	systemContent := "You are a helpful assistant. Answer the user's question based only on the following context. If the context does not contain enough information, say so. Do not make up facts."
-	userContent := "Context:" + contextBlock + "\n\nQuestion: " + question + "\n\nAnswer:"
+// AppendResult reports the chunks added by an append.
+type AppendResult struct {
+	Document        model.RAGDocument `json:"document"`
+	ChunkCount      int               `json:"chunk_count"`
+	FirstChunkIndex int               `json:"first_chunk_index"`
+	ChunkDedupe
+}
 
-	messages := []ai.ChatMessage{
-		{Role: "system", Content: systemContent},
-		{Role: "user", Content: userContent},
+// Append chunks and embeds only the new content and links it after the document's existing chunks.
+func (s *RAGService) Append(ctx context.Context, input AppendInput) (*AppendResult, error) {
+	if input.UserID == 0 || input.DocumentID == 0 {
+		return nil, ErrInvalidInput
 	}
-	answer, err := s.llmClient.Complete(ctx, s.chatConfig, messages)
+	content := strings.TrimSpace(input.Content)
+	if content == "" {
+		return nil, ErrInvalidInput
+	}
+	doc, err := loadRAGDocument(ctx, s.docRepo, input.DocumentID, input.UserID, authz.Write)
 	if err != nil {
 		return nil, err
 	}
+	if doc == nil {
+		return nil, ErrRAGDocumentNotFound
+	}
+	if documentIngesting(doc) {
+		return nil, ErrRAGDocumentNotReady
+	}
 
-	return &AskResult{
-		Answer: strings.TrimSpace(answer),
-		Chunks: selectedChunks,
+	chunks := documentChunking(doc).split(content)
+	if len(chunks) == 0 {
+		return nil, ErrInvalidInput
+	}
+	chunks, dedupe, err := s.dedupeChunks(ctx, doc.ID, chunks)
+	if err != nil {
+		return nil, err
+	}
+	if len(chunks) == 0 {
+		return nil, ErrRAGNothingToAppend
+	}
+	if err := s.checkStorage(ctx, input.UserID, 0, 0, len(chunks), chunkBytes(chunks)); err != nil {
+		return nil, err
+	}
+	ragChunks, err := s.embedChunks(ctx, doc.ID, 0, chunks, nil)
+	if err != nil {
+		return nil, err
+	}
+	// The indexes and offsets are allocated when the chunks are stored, so appends that
+	// run at once do not collide.
+	startIndex, err := s.chunkRepo.AppendBatch(ctx, doc.ID, ragChunks, s.storageCheck(doc.UserID))
+	if err != nil {
+		return nil, err
+	}
+	s.indexDocument(ctx, doc, ragChunks, false)
+	doc.ChunkCount = startIndex + len(ragChunks)
+
+	return &AppendResult{
+		Document:        *doc,
+		ChunkCount:      len(ragChunks),
+		FirstChunkIndex: startIndex,
+		ChunkDedupe:     dedupe,
 	}, nil
 }
 
-// chunkText splits text into overlapping chunks by rune count.
-func chunkText(text string, size, overlap int) []string {
-	if size <= 0 {
-		size = defaultChunkSize
+// DocumentText returns the full text of a document, reassembled from its chunks.
+func (s *RAGService) DocumentText(ctx context.Context, userID, documentID uint) (string, error) {
+	if userID == 0 || documentID == 0 {
+		return "", ErrInvalidInput
 	}
-	if overlap >= size {
-		overlap = size / 2
+	doc, err := loadRAGDocument(ctx, s.docRepo, documentID, userID, authz.Read)
+	if err != nil {
+		return "", err
 	}
-	var chunks []string
-	runes := []rune(text)
-	for i := 0; i < len(runes); {
-		end := i + size
-		if end > len(runes) {
-			end = len(runes)
-		}
-		chunk := string(runes[i:end])
-		chunks = append(chunks, chunk)
-		i += size - overlap
-		if i >= len(runes) {
-			break
-		}
+	if doc == nil {
+		return "", ErrRAGDocumentNotFound
 	}
-	return chunks
+	chunks, err := s.chunkRepo.ListByDocumentIDs(ctx, []uint{doc.ID})
+	if err != nil {
+		return "", err
+	}
+	return joinChunks(chunks, documentChunking(doc).overlap), nil
 }
 
-func cosineSimilarity(a, b []float32) float32 {
-	if len(a) == 0 || len(a) != len(b) {
-		return 0
+// RetrieveFromSession returns the k chunks of a RAG session's documents most similar to
+// query. A session without documents yields no chunks rather than an error.
+func (s *RAGService) RetrieveFromSession(ctx context.Context, userID, sessionID uint, query string, k int) ([]model.RAGChunk, error) {
+	query = strings.TrimSpace(query)
+	if userID == 0 || sessionID == 0 || query == "" {
+		return nil, ErrInvalidInput
 	}
-	var dot, normA, normB float32
-	for i := range a {
-		dot += a[i] * b[i]
-		normA += a[i] * a[i]
-		normB += b[i] * b[i]
+	docs, err := s.docRepo.ListByUserIDAndSessionID(ctx, userID, sessionID)
+	if err != nil || len(docs) == 0 {
+		return nil, err
 	}
-	if normA <= 0 || normB <= 0 {
-		return 0
+	docIDs := make([]uint, len(docs))
+	for i, d := range docs {
+		docIDs[i] = d.ID
 	}
-	return dot / (float32(mathSqrt(float64(normA))) * float32(mathSqrt(float64(normB))))
+	queryEmb, err := s.embedder.Embed(ctx, s.embConfig, query)
+	if err != nil {
+		return nil, err
+	}
+	chunks, err := s.retrievableChunks(ctx, docIDs, query, queryEmb, k)
+	if err != nil || len(chunks) == 0 {
+		return nil, err
+	}
+	top := selectTopChunks(queryEmb, chunks, k)
+	s.touchChunks(ctx, top)
+	return top, nil
 }
 
-func mathSqrt(x float64) float64 {
-	if x <= 0 {
-		return 0
+// SessionExists reports whether the user owns the RAG session.
+func (s *RAGService) SessionExists(ctx context.Context, userID, sessionID uint) (bool, error) {
+	session, err := loadRAGSession(ctx, s.sessionRepo, sessionID, userID, authz.Read)
+	if err != nil {
+		return false, err
 	}
-	// Newton's method for sqrt
-	t := x
-	for i := 0; i < 20; i++ {
-		next := 0.5 * (t + x/t)
-		if next == t {
-			return t
+	return session != nil, nil
+}
+
+// RetrieveFromDocument returns the k chunks of one document most similar to query.
+func (s *RAGService) RetrieveFromDocument(ctx context.Context, userID, documentID uint, query string, k int) ([]model.RAGChunk, error) {
+	query = strings.TrimSpace(query)
+	if userID == 0 || documentID == 0 || query == "" {
+		return nil, ErrInvalidInput
+	}
+	doc, err := loadRAGDocument(ctx, s.docRepo, documentID, userID, authz.Read)
+	if err != nil {
+		return nil, err
+	}
+	if doc == nil {
+		return nil, ErrRAGDocumentNotFound
+	}
+	queryEmb, err := s.embedder.Embed(ctx, s.embConfig, query)
+	if err != nil {
+		return nil, err
+	}
+	chunks, err := s.retrievableChunks(ctx, []uint{doc.ID}, query, queryEmb, k)
+	if err != nil {
+		return nil, err
+	}
+	top := selectTopChunks(queryEmb, chunks, k)
+	s.touchChunks(ctx, top)
+	return top, nil
+}
+
+// ReplaceContent re-chunks and re-embeds a document with new content, an edit of the text
+// DocumentText returned, keeping its ID, name, chunking and pages. The document's content
+// version changes, so question suggestions for sessions holding it are generated afresh.
+func (s *RAGService) ReplaceContent(ctx context.Context, userID, documentID uint, content string) (*IngestResult, error) {
+	return s.Reingest(ctx, ReingestInput{UserID: userID, DocumentID: documentID, Content: content, keepPages: true})
+}
+
+// ReingestInput is the input for replacing a document's content. Name and the chunking
+// fields are optional; those left unset keep the document's current values.
+type ReingestInput struct {
+	UserID     uint
+	DocumentID uint
+	Name       string
+	Content    string
+	// Paged marks Content as the text of a PDF, as for IngestInput.
+	Paged bool
+	// FileHash is the SHA-256 of the uploaded file, as for IngestInput; empty for text.
+	FileHash string
+
+	ChunkSize     int
+	ChunkOverlap  *int
+	ChunkStrategy string
+	// keepPages carries the pages of a paged document over to Content, which has lost its
+	// page breaks to DocumentText.
+	keepPages bool
+}
+
+// Reingest replaces a document's content in place: its chunks are deleted and the new
+// content is chunked and embedded under the same document ID, so sessions and other
+// references to the document keep working.
+func (s *RAGService) Reingest(ctx context.Context, input ReingestInput) (*IngestResult, error) {
+	if input.UserID == 0 || input.DocumentID == 0 {
+		return nil, ErrInvalidInput
+	}
+	content := trimContent(input.Content)
+	if strings.TrimSpace(content) == "" {
+		return nil, ErrInvalidInput
+	}
+	doc, err := loadRAGDocument(ctx, s.docRepo, input.DocumentID, input.UserID, authz.Write)
+	if err != nil {
+		return nil, err
+	}
+	if doc == nil {
+		return nil, ErrRAGDocumentNotFound
+	}
+	if documentIngesting(doc) {
+		return nil, ErrRAGDocumentNotReady
+	}
+	chunker, err := rechunking(doc, input.ChunkSize, input.ChunkOverlap, input.ChunkStrategy)
+	if err != nil {
+		return nil, err
+	}
+
+	chunks := chunker.split(content)
+	if len(chunks) == 0 {
+		return nil, ErrInvalidInput
+	}
+	pages := pageCount(content, input.Paged)
+	if input.Paged {
+		assignPages(content, chunks)
+	} else if input.keepPages && doc.PageCount > 0 {
+		old, err := s.chunkRepo.ListByDocumentIDs(ctx, []uint{doc.ID})
+		if err != nil {
+			return nil, err
+		}
+		if len(old) > 0 {
+			_, starts := joinChunkStarts(old, documentChunking(doc).overlap)
+			carryPages(old, starts, chunks)
+			pages = doc.PageCount
 		}
-		t = next
 	}
-	return t
+	// The old chunks are replaced, so only the new content is deduplicated.
+	chunks, dedupe, err := s.dedupeChunks(ctx, 0, chunks)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkStorage(ctx, input.UserID, doc.ID, 0, len(chunks), chunkBytes(chunks)); err != nil {
+		return nil, err
+	}
+	ragChunks, err := s.embedChunks(ctx, doc.ID, 0, chunks, nil)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.chunkRepo.ReplaceByDocumentID(ctx, doc.ID, ragChunks, s.storageCheck(doc.UserID)); err != nil {
+		return nil, err
+	}
+	s.indexDocument(ctx, doc, ragChunks, true)
+	if name := strings.TrimSpace(input.Name); name != "" {
+		doc.Name = name
+	}
+	doc.ChunkCount = len(ragChunks)
+	doc.PageCount = pages
+	doc.FileHash = input.FileHash
+	chunker.apply(doc)
+	if err := s.docRepo.UpdateContent(ctx, doc); err != nil {
+		return nil, err
+	}
+	// Replacing the content of a failed asynchronous ingest completes it.
+	if doc.Status == DocumentFailed {
+		if err := s.docRepo.UpdateStatus(ctx, doc.ID, DocumentReady, ""); err != nil {
+			return nil, err
+		}
+		doc.Status, doc.Error = DocumentReady, ""
+		s.discardIngest(ctx, doc.ID)
+	}
+	return &IngestResult{Document: *doc, ChunkCount: len(ragChunks), ChunkDedupe: dedupe}, nil
 }
 
-func topKScored(scored []struct {
-	chunk model.RAGChunk
-	score float32
-}, k int) []struct {
-	chunk model.RAGChunk
-	score float32
-} {
+// embedChunks embeds chunk texts in batches and builds chunk rows indexed from startIndex.
+// onBatch, if non-nil, is called after each embedding batch.
+func (s *RAGService) embedChunks(ctx context.Context, documentID uint, startIndex int, chunks []textChunk, onBatch func(done, total int)) ([]model.RAGChunk, error) {
+	embeddings, err := s.embedTextsWithProgress(ctx, chunkTexts(chunks), onBatch)
+	if err != nil {
+		return nil, err
+	}
+	return s.buildChunks(documentID, startIndex, chunks, embeddings), nil
+}
+
+// buildChunks makes chunk rows indexed from startIndex with their embeddings.
+func (s *RAGService) buildChunks(documentID uint, startIndex int, chunks []textChunk, embeddings [][]float32) []model.RAGChunk {
+	ragChunks := make([]model.RAGChunk, len(chunks))
+	for i, c := range chunks {
+		ragChunks[i] = model.RAGChunk{
+			DocumentID:  documentID,
+			ChunkIndex:  startIndex + i,
+			Content:     c.text,
+			StartOffset: c.start,
+			EndOffset:   c.end,
+			Page:        c.page,
+		}
+		ragChunks[i].ContentHash, ragChunks[i].SimHash = chunkFingerprint(c.text)
+		if s.quantizeEmbeddings {
+			ragChunks[i].SetQuantizedEmbedding(embeddings[i])
+		} else {
+			ragChunks[i].SetEmbedding(embeddings[i])
+		}
+	}
+	return ragChunks
+}
+
+// embedTexts embeds texts in batches to stay under provider batch limits.
+func (s *RAGService) embedTexts(ctx context.Context, texts []string) ([][]float32, error) {
+	return s.embedTextsWithProgress(ctx, texts, nil)
+}
+
+func (s *RAGService) embedTextsWithProgress(ctx context.Context, texts []string, onBatch func(done, total int)) ([][]float32, error) {
+	var embeddings [][]float32
+	batches := (len(texts) + embeddingBatchSize - 1) / embeddingBatchSize
+	for i := 0; i < len(texts); i += embeddingBatchSize {
+		end := i + embeddingBatchSize
+		if end > len(texts) {
+			end = len(texts)
+		}
+		batched, err := s.embedder.EmbedBatch(ctx, s.embConfig, texts[i:end])
+		if err != nil {
+			return nil, err
+		}
+		embeddings = append(embeddings, batched...)
+		if onBatch != nil {
+			onBatch(i/embeddingBatchSize+1, batches)
+		}
+	}
+	if len(embeddings) != len(texts) {
+		return nil, errors.New("embedding count mismatch")
+	}
+	return embeddings, nil
+}
+
+// AskInput is the input for RAG ask.
+type AskInput struct {
+	UserID uint
+	// SessionID, if non-zero, searches only docs in this session, stores the question and
+	// answer in its history and sends its recent turns along for follow-up questions.
+	SessionID   uint
+	Question    string
+	DocumentIDs []uint // empty = search by session or all user's documents
+	TopK        int
+	// IncludeChatHistory also retrieves from the user's own persisted chat messages.
+	IncludeChatHistory bool
+	// Rerank retrieves a wider candidate set and has a rerank model (or the chat model)
+	// pick the TopK most relevant from it.
+	Rerank bool
+	// Diversify selects the TopK with maximal marginal relevance, so near-duplicate chunks
+	// do not crowd out other relevant ones.
+	Diversify bool
+	// MinScore is the embedding similarity a source needs to be used. When none reaches
+	// it, the model is not called and the result is marked InsufficientContext. 0 keeps
+	// every source.
+	MinScore float32
+	// CompareShadow also ranks the documents with the shadow embedding model and reports
+	// how its top-k overlaps the chunks used; the answer still uses the primary model.
+	CompareShadow bool
+	// CitationMarkers has the answer cite the chunks inline as [1], [2], ..., numbered in
+	// Chunks order; nil uses the configured default.
+	CitationMarkers *bool
+	// MultiQuery has the chat model paraphrase the question and retrieves for each
+	// paraphrase too, merging the results by reciprocal rank fusion. It finds passages
+	// worded differently from the question at the cost of one more model call; nil uses
+	// the configured default.
+	MultiQuery *bool
+	// standalone answers the question without the session's recent turns and does not store
+	// it in the history; SessionID then only selects the documents. AskBatch sets it so its
+	// questions do not depend on each other.
+	standalone bool
+}
+
+// AskResult is the result of RAG ask (answer + used chunks and chat messages).
+type AskResult struct {
+	// MessageID is the stored history entry; 0 without a session.
+	MessageID uint             `json:"message_id,omitempty"`
+	Answer    string           `json:"answer"`
+	Chunks    []model.RAGChunk `json:"chunks"`
+	// Scores holds each chunk's embedding similarity to the question, in Chunks order.
+	Scores []float32 `json:"scores"`
+	// Citations says where each chunk comes from, in Chunks order.
+	Citations []Citation `json:"citations"`
+	// CitedMarkers lists the chunk numbers the answer cites with citation markers.
+	CitedMarkers []int           `json:"cited_markers,omitempty"`
+	Messages     []model.Message `json:"messages,omitempty"`
+	// Queries are the paraphrases searched for besides the question, for AskInput.MultiQuery.
+	Queries []string `json:"queries,omitempty"`
+	// InsufficientContext is set when no source reached AskInput.MinScore; Answer is then
+	// insufficientContextAnswer and no chunks are returned.
+	InsufficientContext bool `json:"insufficient_context,omitempty"`
+	// ShadowComparison is set for AskInput.CompareShadow.
+	ShadowComparison *ShadowComparison `json:"shadow_comparison,omitempty"`
+}
+
+// Citation says where a source chunk comes from, for display as e.g. "resume, page 3".
+// Offsets count characters in the document's text; both are 0 for chunks stored before
+// offsets were.
+type Citation struct {
+	ChunkID      uint   `json:"chunk_id"`
+	DocumentID   uint   `json:"document_id"`
+	DocumentName string `json:"document_name"`
+	ChunkIndex   int    `json:"chunk_index"`
+	StartOffset  int    `json:"start_offset"`
+	EndOffset    int    `json:"end_offset"`
+	// Page is the 1-based PDF page the chunk starts on; 0 for documents without pages.
+	Page int `json:"page,omitempty"`
+	// Label names the document and, when known, the page.
+	Label string `json:"label"`
+	// Marker is the number the answer cites the chunk by, as [Marker], when citation
+	// markers were requested.
+	Marker int `json:"marker,omitempty"`
+}
+
+// citeChunks returns the citation of each chunk, naming documents from names.
+func citeChunks(chunks []model.RAGChunk, names map[uint]string) []Citation {
+	citations := make([]Citation, len(chunks))
+	for i, c := range chunks {
+		name := names[c.DocumentID]
+		label := name
+		if c.Page > 0 {
+			label = fmt.Sprintf("%s, page %d", name, c.Page)
+		}
+		citations[i] = Citation{
+			ChunkID:      c.ID,
+			DocumentID:   c.DocumentID,
+			DocumentName: name,
+			ChunkIndex:   c.ChunkIndex,
+			StartOffset:  c.StartOffset,
+			EndOffset:    c.EndOffset,
+			Page:         c.Page,
+			Label:        label,
+		}
+	}
+	return citations
+}
+
+// insufficientContextAnswer answers a question whose sources all scored below MinScore.
+const insufficientContextAnswer = "The documents do not contain enough relevant information to answer this question."
+
+// RAGHistoryEntry is a stored question and answer with its chunk IDs decoded.
+type RAGHistoryEntry struct {
+	model.RAGMessage
+	ChunkIDs []uint `json:"chunk_ids"`
+}
+
+// History returns up to limit of the session's questions and answers before beforeID
+// (0 = the newest), oldest first.
+func (s *RAGService) History(ctx context.Context, userID, sessionID, beforeID uint, limit int) ([]RAGHistoryEntry, error) {
+	if userID == 0 || sessionID == 0 {
+		return nil, ErrInvalidInput
+	}
+	if limit <= 0 {
+		limit = defaultRAGHistory
+	}
+	if limit > maxRAGHistoryLimit {
+		limit = maxRAGHistoryLimit
+	}
+	session, err := loadRAGSession(ctx, s.sessionRepo, sessionID, userID, authz.Read)
+	if err != nil {
+		return nil, err
+	}
+	if session == nil {
+		return nil, ErrRAGSessionNotFound
+	}
+	recent, err := s.messageRepo.ListRecentBySessionID(ctx, sessionID, beforeID, limit)
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]RAGHistoryEntry, 0, len(recent))
+	for i := len(recent) - 1; i >= 0; i-- {
+		entries = append(entries, RAGHistoryEntry{RAGMessage: recent[i], ChunkIDs: recent[i].ChunkIDList()})
+	}
+	return entries, nil
+}
+
+// AskSources are the chunks and chat messages an answer is grounded in, sent by AskStream
+// before the answer.
+type AskSources struct {
+	Chunks    []model.RAGChunk `json:"chunks"`
+	Scores    []float32        `json:"scores"`
+	Citations []Citation       `json:"citations"`
+	Messages  []model.Message  `json:"messages,omitempty"`
+	Queries   []string         `json:"queries,omitempty"`
+	// InsufficientContext is set when no source reached AskInput.MinScore.
+	InsufficientContext bool              `json:"insufficient_context,omitempty"`
+	ShadowComparison    *ShadowComparison `json:"shadow_comparison,omitempty"`
+}
+
+// Ask retrieves top-k relevant chunks, builds a prompt with them, and calls the LLM.
+// With IncludeChatHistory, prior chat messages compete for the same top-k slots and are
+// cited with their date so the answer can refer back to earlier conversations.
+func (s *RAGService) Ask(ctx context.Context, input AskInput) (*AskResult, error) {
+	prompt, err := s.prepareAsk(ctx, input)
+	if err != nil {
+		return nil, err
+	}
+	answer := insufficientContextAnswer
+	var cited []int
+	if !prompt.sources.InsufficientContext {
+		answer, err = s.completer.Complete(ctx, s.chatConfig, prompt.messages)
+		if err != nil {
+			return nil, err
+		}
+		answer, cited = prompt.finishAnswer(answer)
+	}
+
+	return &AskResult{
+		MessageID:           s.recordAsk(ctx, input, prompt, answer),
+		Answer:              answer,
+		CitedMarkers:        cited,
+		Chunks:              prompt.sources.Chunks,
+		Scores:              prompt.sources.Scores,
+		Citations:           prompt.sources.Citations,
+		Messages:            prompt.sources.Messages,
+		Queries:             prompt.sources.Queries,
+		InsufficientContext: prompt.sources.InsufficientContext,
+		ShadowComparison:    prompt.sources.ShadowComparison,
+	}, nil
+}
+
+// AskStream is Ask with the answer streamed through onChunk. onSources receives the retrieved
+// sources before the first chunk; errors returned before it is called mean nothing was sent.
+// The chunks are the model's raw output; the returned answer has its citation markers
+// repaired like Ask's.
+func (s *RAGService) AskStream(ctx context.Context, input AskInput, onSources func(AskSources) error, onChunk func(string) error) (string, error) {
+	prompt, err := s.prepareAsk(ctx, input)
+	if err != nil {
+		return "", err
+	}
+	if err := onSources(prompt.sources); err != nil {
+		return "", err
+	}
+	if prompt.sources.InsufficientContext {
+		if err := onChunk(insufficientContextAnswer); err != nil {
+			return "", err
+		}
+		s.recordAsk(ctx, input, prompt, insufficientContextAnswer)
+		return insufficientContextAnswer, nil
+	}
+	answer, err := s.completer.StreamComplete(ctx, s.chatConfig, prompt.messages, onChunk)
+	if err != nil {
+		return answer, err
+	}
+	answer, _ = prompt.finishAnswer(answer)
+	s.recordAsk(ctx, input, prompt, answer)
+	return answer, nil
+}
+
+// askPrompt is a question ready to send: its retrieved sources and the prompt built on them.
+type askPrompt struct {
+	question string
+	sources  AskSources
+	messages []ai.ChatMessage
+	// markers is set when the model was asked to cite the chunks with [n] markers.
+	markers bool
+}
+
+// finishAnswer repairs the answer's citation markers, if the model was asked for them, and
+// returns it with the chunk numbers it cites.
+func (p *askPrompt) finishAnswer(answer string) (string, []int) {
+	answer = strings.TrimSpace(answer)
+	if !p.markers {
+		return answer, nil
+	}
+	return repairCitationMarkers(answer, len(p.sources.Chunks))
+}
+
+// recordAsk stores the answered question in the session's history and returns its ID. A
+// failure is only logged, since the answer has already been produced.
+func (s *RAGService) recordAsk(ctx context.Context, input AskInput, prompt *askPrompt, answer string) uint {
+	if input.standalone {
+		return 0
+	}
+	recordActivity(context.WithoutCancel(ctx), s.activity, input.UserID, ActivityRAGAsked, input.SessionID,
+		fmt.Sprintf("Asked %q", truncateRunes(prompt.question, 200)))
+	if input.SessionID == 0 {
+		return 0
+	}
+	chunkIDs := make([]uint, 0, len(prompt.sources.Chunks))
+	for _, c := range prompt.sources.Chunks {
+		chunkIDs = append(chunkIDs, c.ID)
+	}
+	msg := &model.RAGMessage{
+		SessionID: input.SessionID,
+		UserID:    input.UserID,
+		Question:  prompt.question,
+		Answer:    answer,
+	}
+	msg.SetChunkIDs(chunkIDs)
+	if err := s.messageRepo.Create(context.WithoutCancel(ctx), msg); err != nil {
+		log.Printf("store rag message for session %d failed: %v", input.SessionID, err)
+		return 0
+	}
+	return msg.ID
+}
+
+// prepareAsk retrieves the sources for input's question and builds the prompt grounded in
+// them, after the session's recent turns.
+func (s *RAGService) prepareAsk(ctx context.Context, input AskInput) (*askPrompt, error) {
+	if input.UserID == 0 {
+		return nil, ErrInvalidInput
+	}
+	question := strings.TrimSpace(input.Question)
+	if question == "" {
+		return nil, ErrInvalidInput
+	}
+	if input.CompareShadow && s.shadowConfig.Model == "" {
+		return nil, ErrInvalidInput.Withf("no shadow embedding model is configured")
+	}
+	if err := s.checkChatModel(ctx, input.UserID); err != nil {
+		return nil, err
+	}
+
+	topK := input.TopK
+	if topK <= 0 {
+		topK = defaultTopK
+	}
+	topK = min(topK, maxTopK)
+
+	var turns []model.RAGMessage
+	if input.SessionID != 0 {
+		// Only a session the answer is appended to needs write access.
+		action := authz.Write
+		if input.standalone {
+			action = authz.Read
+		}
+		session, err := loadRAGSession(ctx, s.sessionRepo, input.SessionID, input.UserID, action)
+		if err != nil {
+			return nil, err
+		}
+		if session == nil {
+			return nil, ErrRAGSessionNotFound
+		}
+	}
+	if input.SessionID != 0 && !input.standalone {
+		recent, err := s.messageRepo.ListRecentBySessionID(ctx, input.SessionID, 0, ragFollowUpTurns)
+		if err != nil {
+			return nil, err
+		}
+		for i := len(recent) - 1; i >= 0; i-- {
+			turns = append(turns, recent[i])
+		}
+	}
+
+	var docIDs []uint
+	docNames := make(map[uint]string)
+	if len(input.DocumentIDs) > 0 {
+		for _, id := range input.DocumentIDs {
+			doc, err := loadRAGDocument(ctx, s.docRepo, id, input.UserID, authz.Read)
+			if err != nil || doc == nil {
+				continue
+			}
+			docIDs = append(docIDs, id)
+			docNames[id] = doc.Name
+		}
+	} else {
+		var docs []model.RAGDocument
+		var err error
+		if input.SessionID != 0 {
+			docs, err = s.docRepo.ListByUserIDAndSessionID(ctx, input.UserID, input.SessionID)
+		} else {
+			docs, err = s.docRepo.ListByUserID(ctx, input.UserID)
+		}
+		if err != nil {
+			return nil, err
+		}
+		for _, d := range docs {
+			docIDs = append(docIDs, d.ID)
+			docNames[d.ID] = d.Name
+		}
+	}
+	if len(docIDs) == 0 && !input.IncludeChatHistory {
+		return nil, ErrRAGNoDocuments
+	}
+
+	// A follow-up such as "and the second one?" retrieves poorly alone, so the previous
+	// question is searched for along with it.
+	query := question
+	if len(turns) > 0 {
+		query = turns[len(turns)-1].Question + "\n" + question
+	}
+	queryEmb, err := s.embedder.Embed(ctx, s.embConfig, query)
+	if err != nil {
+		return nil, err
+	}
+
+	// With reranking, the first stage keeps a wider candidate set for the reranker to
+	// choose topK from.
+	candidates := topK
+	if input.Rerank && s.rerankCandidates > candidates {
+		candidates = s.rerankCandidates
+	}
+	if input.Diversify && topK*mmrCandidateFactor > candidates {
+		candidates = topK * mmrCandidateFactor
+	}
+	var allChunks []model.RAGChunk
+	if len(docIDs) > 0 {
+		allChunks, err = s.retrievableChunks(ctx, docIDs, query, queryEmb, candidates)
+		if err != nil {
+			return nil, err
+		}
+	}
+	var history []repository.EmbeddedMessage
+	if input.IncludeChatHistory && s.messageEmbRepo != nil {
+		var err error
+		history, err = s.messageEmbRepo.ListByUserID(ctx, input.UserID)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if len(allChunks) == 0 && len(history) == 0 {
+		if len(docIDs) == 0 {
+			return nil, ErrRAGNoDocuments
+		}
+		return nil, ErrRAGNoChunks
+	}
+
+	ranked := rankSources(query, queryEmb, allChunks, history, candidates)
+	multiQuery := s.multiQuery
+	if input.MultiQuery != nil {
+		multiQuery = *input.MultiQuery
+	}
+	var paraphrases []string
+	if multiQuery {
+		paraphrases = s.paraphraseQuery(ctx, input.UserID, query)
+	}
+	if len(paraphrases) > 0 {
+		ranked, err = s.retrieveParaphrases(ctx, input.UserID, ranked, paraphrases, allChunks, docIDs, history, candidates)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if input.MinScore > 0 {
+		ranked = aboveScore(ranked, input.MinScore)
+		if len(ranked) == 0 {
+			return &askPrompt{
+				question: question,
+				sources: AskSources{
+					Chunks:              []model.RAGChunk{},
+					Scores:              []float32{},
+					Citations:           []Citation{},
+					Queries:             paraphrases,
+					InsufficientContext: true,
+				},
+			}, nil
+		}
+	}
+	if input.Rerank {
+		ranked = s.rerankSources(ctx, query, ranked)
+	}
+	if input.Diversify {
+		// MMR chooses from the best few candidates per slot, so a reranker still decides
+		// which candidates are in the running.
+		if pool := topK * mmrCandidateFactor; len(ranked) > pool {
+			ranked = ranked[:pool]
+		}
+		ranked = selectMMR(ranked, topK, s.mmrLambda)
+	}
+	if len(ranked) > topK {
+		ranked = ranked[:topK]
+	}
+	selectedChunks, selectedMessages := splitSources(ranked)
+	s.touchChunks(ctx, selectedChunks)
+	scores := make([]float32, 0, len(selectedChunks))
+	for _, src := range ranked {
+		if src.chunk != nil {
+			scores = append(scores, src.score)
+		}
+	}
+	var comparison *ShadowComparison
+	if input.CompareShadow && len(docIDs) > 0 {
+		// The comparison only informs the switch to the shadow model, so it never fails the ask.
+		comparison, err = s.compareShadow(ctx, query, docIDs, topK, selectedChunks)
+		if err != nil {
+			log.Printf("compare shadow retrieval with %s failed: %v", s.shadowConfig.Model, err)
+		}
+	}
+
+	markers := s.citationMarkers
+	if input.CitationMarkers != nil {
+		markers = *input.CitationMarkers
+	}
+	citations := citeChunks(selectedChunks, docNames)
+	contextBlock := ""
+	for i, c := range selectedChunks {
+		if markers {
+			citations[i].Marker = i + 1
+			contextBlock += fmt.Sprintf("\n---\n[%d]\n", i+1) + c.Content
+		} else {
+			contextBlock += "\n---\n" + c.Content
+		}
+	}
+	for _, m := range selectedMessages {
+		contextBlock += fmt.Sprintf("\n---\n[Chat on %s, %s said]\n%s", m.CreatedAt.Format("2006-01-02"), m.Role, m.Content)
+	}
+	contextBlock += "\n---"
+
+	systemContent := "You are a helpful assistant. Answer the user's question based only on the following context. If the context does not contain enough information, say so. Do not make up facts."
+	if len(selectedMessages) > 0 {
+		systemContent += " Context entries marked [Chat on <date>, ...] come from the user's earlier conversations; when you use one, cite it by its date."
+	}
+	if markers && len(selectedChunks) > 0 {
+		systemContent += citationMarkerInstruction
+	}
+	userContent := "Context:" + contextBlock + "\n\nQuestion: " + question + "\n\nAnswer:"
+
+	messages := []ai.ChatMessage{{Role: "system", Content: systemContent}}
+	for _, turn := range turns {
+		messages = append(messages,
+			ai.ChatMessage{Role: "user", Content: turn.Question},
+			ai.ChatMessage{Role: "assistant", Content: turn.Answer},
+		)
+	}
+	messages = append(messages, ai.ChatMessage{Role: "user", Content: userContent})
+	return &askPrompt{
+		question: question,
+		sources: AskSources{
+			Chunks:           selectedChunks,
+			Scores:           scores,
+			Citations:        citations,
+			Messages:         selectedMessages,
+			Queries:          paraphrases,
+			ShadowComparison: comparison,
+		},
+		messages: messages,
+		markers:  markers && len(selectedChunks) > 0,
+	}, nil
+}
+
+// selectTopSources ranks document chunks and chat messages together and returns the k
+// best sources overall, split by kind in rank order, so one kind may fill all k. Ranking
+// is hybrid: the cosine similarity ranking to queryEmb and the BM25 keyword ranking for
+// query are merged by reciprocal rank fusion, so exact identifiers like error codes and
+// names are found even when their embeddings are not close.
+func selectTopSources(query string, queryEmb []float32, chunks []model.RAGChunk, history []repository.EmbeddedMessage, k int) ([]model.RAGChunk, []model.Message) {
+	return splitSources(rankSources(query, queryEmb, chunks, history, k))
+}
+
+// askSource is a retrieved document chunk or chat message; exactly one of chunk and
+// message is set.
+type askSource struct {
+	chunk     *model.RAGChunk
+	message   *model.Message
+	embedding []float32
+	// score is the cosine similarity of embedding to the question.
+	score float32
+}
+
+func (src askSource) text() string {
+	if src.chunk != nil {
+		return src.chunk.Content
+	}
+	return src.message.Content
+}
+
+// rankSources orders chunks and chat messages by hybrid relevance (see selectTopSources)
+// and returns the best k.
+func rankSources(query string, queryEmb []float32, chunks []model.RAGChunk, history []repository.EmbeddedMessage, k int) []askSource {
+	n := len(chunks) + len(history)
+	sources := make([]askSource, 0, n)
+	texts := make([]string, 0, n)
+	vectorScores := make([]float32, 0, n)
+	for i := range chunks {
+		emb := chunks[i].EmbeddingVector()
+		score := cosineSimilarity(queryEmb, emb)
+		sources = append(sources, askSource{chunk: &chunks[i], embedding: emb, score: score})
+		texts = append(texts, chunks[i].Content)
+		vectorScores = append(vectorScores, score)
+	}
+	for i := range history {
+		emb := history[i].EmbeddingVector()
+		score := cosineSimilarity(queryEmb, emb)
+		sources = append(sources, askSource{message: &history[i].Message, embedding: emb, score: score})
+		texts = append(texts, history[i].Content)
+		vectorScores = append(vectorScores, score)
+	}
+	order := fuseRankings(vectorScores, bm25Scores(query, texts))
+	if k > len(order) {
+		k = len(order)
+	}
+	ranked := make([]askSource, 0, k)
+	for _, idx := range order[:k] {
+		ranked = append(ranked, sources[idx])
+	}
+	return ranked
+}
+
+// aboveScore keeps the sources whose similarity reaches minScore, in order.
+func aboveScore(sources []askSource, minScore float32) []askSource {
+	kept := sources[:0]
+	for _, src := range sources {
+		if src.score >= minScore {
+			kept = append(kept, src)
+		}
+	}
+	return kept
+}
+
+// splitSources separates ranked sources by kind, preserving their order.
+func splitSources(sources []askSource) ([]model.RAGChunk, []model.Message) {
+	var chunks []model.RAGChunk
+	var messages []model.Message
+	for _, src := range sources {
+		if src.chunk != nil {
+			chunks = append(chunks, *src.chunk)
+		} else {
+			messages = append(messages, *src.message)
+		}
+	}
+	return chunks, messages
+}
+
+// CompareInput is the input for a cross-document comparison.
+type CompareInput struct {
+	UserID      uint
+	Question    string
+	DocumentIDs []uint // 2-5 documents owned by the user
+	TopK        int    // evidence chunks per document
+}
+
+// CompareDocument holds the evidence retrieved from one compared document.
+type CompareDocument struct {
+	Document model.RAGDocument `json:"document"`
+	Evidence []model.RAGChunk  `json:"evidence"`
+}
+
+// CompareAspect is one row of the side-by-side comparison; Values is keyed by document ID.
+type CompareAspect struct {
+	Aspect string            `json:"aspect"`
+	Values map[string]string `json:"values"`
+}
+
+// CompareResult is the structured comparison grounded in per-document evidence.
+type CompareResult struct {
+	Question  string            `json:"question"`
+	Summary   string            `json:"summary"`
+	Aspects   []CompareAspect   `json:"aspects"`
+	Documents []CompareDocument `json:"documents"`
+}
+
+// Compare retrieves evidence from each selected document separately and asks the LLM for a
+// side-by-side comparison, so every document contributes context even when one dominates similarity.
+func (s *RAGService) Compare(ctx context.Context, input CompareInput) (*CompareResult, error) {
+	if input.UserID == 0 {
+		return nil, ErrInvalidInput
+	}
+	question := strings.TrimSpace(input.Question)
+	if question == "" {
+		return nil, ErrInvalidInput
+	}
+	docIDs := uniqueIDs(input.DocumentIDs)
+	if len(docIDs) < compareMinDocuments || len(docIDs) > compareMaxDocuments {
+		return nil, ErrRAGCompareDocumentCount
+	}
+	if err := s.checkChatModel(ctx, input.UserID); err != nil {
+		return nil, err
+	}
+	topK := input.TopK
+	if topK <= 0 {
+		topK = compareTopKPerDoc
+	}
+	topK = min(topK, maxTopK)
+
+	docs := make([]model.RAGDocument, 0, len(docIDs))
+	for _, id := range docIDs {
+		doc, err := loadRAGDocument(ctx, s.docRepo, id, input.UserID, authz.Read)
+		if err != nil {
+			return nil, err
+		}
+		if doc == nil {
+			return nil, ErrRAGDocumentNotFound
+		}
+		docs = append(docs, *doc)
+	}
+
+	queryEmb, err := s.embedder.Embed(ctx, s.embConfig, question)
+	if err != nil {
+		return nil, err
+	}
+
+	compared := make([]CompareDocument, 0, len(docs))
+	var contextBlock strings.Builder
+	for _, doc := range docs {
+		chunks, err := s.loadRetrievableChunks(ctx, []uint{doc.ID})
+		if err != nil {
+			return nil, err
+		}
+		evidence := selectTopChunks(queryEmb, chunks, topK)
+		s.touchChunks(ctx, evidence)
+		compared = append(compared, CompareDocument{Document: doc, Evidence: evidence})
+
+		fmt.Fprintf(&contextBlock, "\n=== Document %d: %s ===", doc.ID, doc.Name)
+		for _, c := range evidence {
+			contextBlock.WriteString("\n---\n" + c.Content)
+		}
+		if len(evidence) == 0 {
+			contextBlock.WriteString("\n(no content)")
+		}
+	}
+
+	systemContent := "You compare documents using only the evidence provided for each one. " +
+		"Respond with a JSON object: {\"summary\": string, \"aspects\": [{\"aspect\": string, \"values\": {\"<document id>\": string}}]}. " +
+		"Include every document id in each aspect's values; write \"not mentioned\" when the evidence is silent. Do not make up facts."
+	userContent := "Evidence:" + contextBlock.String() + "\n\nComparison question: " + question
+	messages := []ai.ChatMessage{
+		{Role: "system", Content: systemContent},
+		{Role: "user", Content: userContent},
+	}
+	raw, err := s.completer.Complete(ctx, s.chatConfig, messages)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &CompareResult{Question: question, Documents: compared}
+	var parsed struct {
+		Summary string          `json:"summary"`
+		Aspects []CompareAspect `json:"aspects"`
+	}
+	if err := decodeLLMJSON(raw, &parsed); err != nil {
+		// Fall back to the free-text answer rather than failing the whole request.
+		result.Summary = strings.TrimSpace(raw)
+		return result, nil
+	}
+	result.Summary = strings.TrimSpace(parsed.Summary)
+	result.Aspects = parsed.Aspects
+	return result, nil
+}
+
+// checkChatModel applies the user's model policy to the chat model RAG answers with.
+func (s *RAGService) checkChatModel(ctx context.Context, userID uint) error {
+	return checkConfiguredModel(ctx, s.modelPolicy, userID, s.chatConfig)
+}
+
+func uniqueIDs(ids []uint) []uint {
+	seen := make(map[uint]bool, len(ids))
+	out := make([]uint, 0, len(ids))
+	for _, id := range ids {
+		if id == 0 || seen[id] {
+			continue
+		}
+		seen[id] = true
+		out = append(out, id)
+	}
+	return out
+}
+
+// SuggestQuestionsResult lists questions the LLM believes the session's documents can answer.
+type SuggestQuestionsResult struct {
+	Questions []string `json:"questions"`
+	Cached    bool     `json:"cached"`
+}
+
+// SuggestQuestions samples chunks from the session's documents and asks the LLM for answerable questions.
+// Results are cached per document-set hash, so adding, removing, appending to or re-ingesting
+// a document yields fresh suggestions.
+func (s *RAGService) SuggestQuestions(ctx context.Context, userID, sessionID uint) (*SuggestQuestionsResult, error) {
+	if userID == 0 || sessionID == 0 {
+		return nil, ErrInvalidInput
+	}
+	session, err := loadRAGSession(ctx, s.sessionRepo, sessionID, userID, authz.Read)
+	if err != nil {
+		return nil, err
+	}
+	if session == nil {
+		return nil, ErrRAGSessionNotFound
+	}
+
+	docs, err := s.docRepo.ListByUserIDAndSessionID(ctx, userID, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	if len(docs) == 0 {
+		return nil, ErrRAGNoDocuments
+	}
+	docIDs := make([]uint, len(docs))
+	for i, d := range docs {
+		docIDs[i] = d.ID
+	}
+	setHash := documentSetHash(docs)
+
+	if s.suggestionCache != nil {
+		if cached, hit, cacheErr := s.suggestionCache.GetSuggestions(ctx, setHash); cacheErr == nil && hit {
+			return &SuggestQuestionsResult{Questions: cached, Cached: true}, nil
+		}
+	}
+
+	chunks, err := s.chunkRepo.ListByDocumentIDs(ctx, docIDs)
+	if err != nil {
+		return nil, err
+	}
+	if len(chunks) == 0 {
+		return nil, ErrRAGNoChunks
+	}
+	sample := sampleChunks(chunks, suggestionSampleSize)
+
+	contextBlock := ""
+	for _, c := range sample {
+		contextBlock += "\n---\n" + c.Content
+	}
+	contextBlock += "\n---"
+
+	if err := s.checkChatModel(ctx, userID); err != nil {
+		return nil, err
+	}
+	systemContent := fmt.Sprintf("You help users explore their uploaded documents. Propose exactly %d distinct questions that can be answered using only the given excerpts. Output one question per line with no numbering or extra text.", suggestionCount)
+	messages := []ai.ChatMessage{
+		{Role: "system", Content: systemContent},
+		{Role: "user", Content: "Excerpts:" + contextBlock},
+	}
+	raw, err := s.completer.Complete(ctx, s.chatConfig, messages)
+	if err != nil {
+		return nil, err
+	}
+	questions := parseQuestionLines(raw, suggestionCount)
+
+	if s.suggestionCache != nil && len(questions) > 0 {
+		_ = s.suggestionCache.SetSuggestions(ctx, setHash, questions)
+	}
+	return &SuggestQuestionsResult{Questions: questions}, nil
+}
+
+// documentSetHash returns a stable hash of the given documents regardless of order. It
+// covers each document's chunk count and content version, so appending to or re-ingesting
+// a document also changes it.
+func documentSetHash(docs []model.RAGDocument) string {
+	sorted := append([]model.RAGDocument(nil), docs...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].ID < sorted[j].ID })
+	h := sha256.New()
+	for _, d := range sorted {
+		fmt.Fprintf(h, "%d:%d:%d,", d.ID, d.ChunkCount, d.ContentVersion)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// sampleChunks picks up to n chunks spread evenly across the slice, so the sample is deterministic.
+func sampleChunks(chunks []model.RAGChunk, n int) []model.RAGChunk {
+	if len(chunks) <= n {
+		return chunks
+	}
+	out := make([]model.RAGChunk, 0, n)
+	step := float64(len(chunks)) / float64(n)
+	for i := 0; i < n; i++ {
+		out = append(out, chunks[int(float64(i)*step)])
+	}
+	return out
+}
+
+// parseQuestionLines extracts up to max questions from LLM output, stripping list markers.
+func parseQuestionLines(raw string, max int) []string {
+	var questions []string
+	for _, line := range strings.Split(raw, "\n") {
+		line = strings.TrimSpace(line)
+		line = strings.TrimLeft(line, "-*•0123456789.) ")
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		questions = append(questions, line)
+		if len(questions) == max {
+			break
+		}
+	}
+	return questions
+}
+
+// chunkSpans splits text into overlapping chunks by rune count. Offsets start at base.
+func chunkSpans(text string, base, size, overlap int) []textChunk {
+	if size <= 0 {
+		size = defaultChunkSize
+	}
+	if overlap >= size {
+		overlap = size / 2
+	}
+	var chunks []textChunk
+	runes := []rune(text)
+	for i := 0; i < len(runes); {
+		end := i + size
+		if end > len(runes) {
+			end = len(runes)
+		}
+		chunks = append(chunks, textChunk{text: string(runes[i:end]), start: base + i, end: base + end})
+		i += size - overlap
+		if i >= len(runes) {
+			break
+		}
+	}
+	return chunks
+}
+
+// joinChunks reassembles a document's text from its ordered chunks, dropping the overlap
+// runes chunkText repeats between consecutive chunks.
+func joinChunks(chunks []model.RAGChunk, overlap int) string {
+	text, _ := joinChunkStarts(chunks, overlap)
+	return text
+}
+
+// joinChunkStarts is joinChunks that also returns the rune offset each chunk starts at in
+// the text.
+func joinChunkStarts(chunks []model.RAGChunk, overlap int) (string, []int) {
+	var b strings.Builder
+	var prev []rune
+	starts := make([]int, len(chunks))
+	written := 0
+	for i, c := range chunks {
+		runes := []rune(c.Content)
+		if overlap > 0 && len(prev) >= overlap && len(runes) >= overlap &&
+			string(prev[len(prev)-overlap:]) == string(runes[:overlap]) {
+			runes = runes[overlap:]
+			starts[i] = written - overlap
+		} else {
+			if b.Len() > 0 {
+				b.WriteString("\n")
+				written++
+			}
+			starts[i] = written
+		}
+		b.WriteString(string(runes))
+		written += len(runes)
+		prev = []rune(c.Content)
+	}
+	return b.String(), starts
+}
+
+func cosineSimilarity(a, b []float32) float32 {
+	if len(a) == 0 || len(a) != len(b) {
+		return 0
+	}
+	var dot, normA, normB float32
+	for i := range a {
+		dot += a[i] * b[i]
+		normA += a[i] * a[i]
+		normB += b[i] * b[i]
+	}
+	if normA <= 0 || normB <= 0 {
+		return 0
+	}
+	return dot / (float32(mathSqrt(float64(normA))) * float32(mathSqrt(float64(normB))))
+}
+
+func mathSqrt(x float64) float64 {
+	if x <= 0 {
+		return 0
+	}
+	// Newton's method for sqrt
+	t := x
+	for i := 0; i < 20; i++ {
+		next := 0.5 * (t + x/t)
+		if next == t {
+			return t
+		}
+		t = next
+	}
+	return t
+}
+
+// retrievableChunks returns the chunks of docIDs to score against query when selecting k:
+// all of them, or with a vector store its nearest matches to queryEmb together with the
+// best keyword matches for query, so hybrid ranking still sees chunks that share the
+// query's terms but are not close in embedding space. Documents whose chunks are not all in
+// the vector store, because indexing failed or the store is new, are read in full from
+// MySQL and indexed again.
+func (s *RAGService) retrievableChunks(ctx context.Context, docIDs []uint, query string, queryEmb []float32, k int) ([]model.RAGChunk, error) {
+	if s.vectors == nil {
+		return s.loadRetrievableChunks(ctx, docIDs)
+	}
+	unindexed, err := s.docRepo.ListUnindexedIDs(ctx, docIDs)
+	if err != nil {
+		return nil, err
+	}
+	var chunks []model.RAGChunk
+	if len(unindexed) > 0 {
+		chunks, err = s.loadRetrievableChunks(ctx, unindexed)
+		if err != nil {
+			return nil, err
+		}
+		s.repairIndex(ctx, unindexed, chunks)
+	}
+	indexed := excludeIDs(docIDs, unindexed)
+	if len(indexed) == 0 {
+		return chunks, nil
+	}
+
+	limit := k * vectorCandidateFactor
+	matches, err := s.vectors.Search(ctx, queryEmb, indexed, limit)
+	if err != nil {
+		log.Printf("vector search for %d rag documents failed, reading chunks from mysql: %v", len(indexed), err)
+		rest, err := s.loadRetrievableChunks(ctx, indexed)
+		if err != nil {
+			return nil, err
+		}
+		return append(chunks, rest...), nil
+	}
+	ids := make([]uint, 0, len(matches)+limit)
+	seen := make(map[uint]bool, len(matches)+limit)
+	for _, m := range matches {
+		ids = append(ids, m.ChunkID)
+		seen[m.ChunkID] = true
+	}
+	keywordIDs, err := s.keywordChunkIDs(ctx, indexed, query, limit)
+	if err != nil {
+		return nil, err
+	}
+	for _, id := range keywordIDs {
+		if !seen[id] {
+			ids = append(ids, id)
+			seen[id] = true
+		}
+	}
+	found, err := s.chunkRepo.ListByIDs(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	found, err = s.restoreArchived(ctx, found)
+	if err != nil {
+		return nil, err
+	}
+	return append(chunks, found...), nil
+}
+
+// retrievableChunksPerDocument is retrievableChunks for selecting the best k chunks of each
+// document rather than of all of them together.
+func (s *RAGService) retrievableChunksPerDocument(ctx context.Context, docIDs []uint, query string, queryEmb []float32, k int) ([]model.RAGChunk, error) {
+	if s.vectors == nil {
+		return s.loadRetrievableChunks(ctx, docIDs)
+	}
+	var chunks []model.RAGChunk
+	for _, docID := range docIDs {
+		found, err := s.retrievableChunks(ctx, []uint{docID}, query, queryEmb, k)
+		if err != nil {
+			return nil, err
+		}
+		chunks = append(chunks, found...)
+	}
+	return chunks, nil
+}
+
+// keywordChunkIDs returns the IDs of the chunks of docIDs that best match query by BM25, at
+// most limit of them and only those sharing a term with it.
+func (s *RAGService) keywordChunkIDs(ctx context.Context, docIDs []uint, query string, limit int) ([]uint, error) {
+	if strings.TrimSpace(query) == "" {
+		return nil, nil
+	}
+	chunks, err := s.chunkRepo.ListContentByDocumentIDs(ctx, docIDs)
+	if err != nil || len(chunks) == 0 {
+		return nil, err
+	}
+	texts := make([]string, len(chunks))
+	for i := range chunks {
+		texts[i] = chunks[i].Content
+	}
+	scores := bm25Scores(query, texts)
+	order := make([]int, len(chunks))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool { return scores[order[a]] > scores[order[b]] })
+	ids := make([]uint, 0, limit)
+	for _, i := range order {
+		if len(ids) >= limit || scores[i] <= 0 {
+			break
+		}
+		ids = append(ids, chunks[i].ID)
+	}
+	return ids, nil
+}
+
+// excludeIDs returns ids without those in excluded, keeping their order.
+func excludeIDs(ids, excluded []uint) []uint {
+	if len(excluded) == 0 {
+		return ids
+	}
+	skip := make(map[uint]bool, len(excluded))
+	for _, id := range excluded {
+		skip[id] = true
+	}
+	kept := make([]uint, 0, len(ids))
+	for _, id := range ids {
+		if !skip[id] {
+			kept = append(kept, id)
+		}
+	}
+	return kept
+}
+
+// repairIndex adds the chunks of documents missing from the vector store and marks the
+// documents indexed. Failures are only logged; the documents are read from MySQL and tried
+// again on the next retrieval.
+func (s *RAGService) repairIndex(ctx context.Context, docIDs []uint, chunks []model.RAGChunk) {
+	if err := s.indexChunks(ctx, chunks); err != nil {
+		log.Printf("index %d rag documents failed: %v", len(docIDs), err)
+		return
+	}
+	if err := s.docRepo.SetVectorIndexed(ctx, docIDs, true); err != nil {
+		log.Printf("mark %d rag documents indexed failed: %v", len(docIDs), err)
+	}
+}
+
+// indexDocument puts a document's chunks in the vector store, if there is one, and records
+// whether that worked. With replace the document's existing points are removed first;
+// otherwise chunks are added to them, which only completes the index if the document was
+// already indexed. Failures are only logged: until the document is indexed, retrieval reads
+// its chunks from MySQL and indexes them again.
+func (s *RAGService) indexDocument(ctx context.Context, doc *model.RAGDocument, chunks []model.RAGChunk, replace bool) {
+	if s.vectors == nil {
+		return
+	}
+	if !replace && !doc.VectorIndexed {
+		return
+	}
+	// The document is marked unindexed while its points change, so a failure part way
+	// leaves it read from MySQL.
+	if doc.VectorIndexed {
+		if err := s.docRepo.SetVectorIndexed(ctx, []uint{doc.ID}, false); err != nil {
+			log.Printf("mark rag document %d unindexed failed: %v", doc.ID, err)
+			return
+		}
+		doc.VectorIndexed = false
+	}
+	if replace {
+		if err := s.unindexDocument(ctx, doc.ID); err != nil {
+			log.Printf("remove rag document %d from vector store failed: %v", doc.ID, err)
+			return
+		}
+	}
+	if err := s.indexChunks(ctx, chunks); err != nil {
+		log.Printf("index rag document %d failed: %v", doc.ID, err)
+		return
+	}
+	if err := s.docRepo.SetVectorIndexed(ctx, []uint{doc.ID}, true); err != nil {
+		log.Printf("mark rag document %d indexed failed: %v", doc.ID, err)
+		return
+	}
+	doc.VectorIndexed = true
+}
+
+// indexChunks adds stored chunks to the vector store, if there is one.
+func (s *RAGService) indexChunks(ctx context.Context, chunks []model.RAGChunk) error {
+	if s.vectors == nil {
+		return nil
+	}
+	points, err := rag.PointsFromChunks(chunks)
+	if err != nil {
+		return err
+	}
+	return s.vectors.Upsert(ctx, points)
+}
+
+// unindexDocument removes a document's chunks from the vector store, if there is one.
+func (s *RAGService) unindexDocument(ctx context.Context, documentID uint) error {
+	if s.vectors == nil {
+		return nil
+	}
+	return s.vectors.DeleteByDocument(ctx, documentID)
+}
+
+// loadRetrievableChunks lists chunks for retrieval, restoring any archived embeddings on
+// demand. Within a batch ask each set of documents is only read once.
+func (s *RAGService) loadRetrievableChunks(ctx context.Context, docIDs []uint) ([]model.RAGChunk, error) {
+	if batch, ok := ctx.Value(batchChunksKey{}).(*batchChunks); ok {
+		return batch.load(docIDs, func() ([]model.RAGChunk, error) {
+			return s.listRetrievableChunks(ctx, docIDs)
+		})
+	}
+	return s.listRetrievableChunks(ctx, docIDs)
+}
+
+func (s *RAGService) listRetrievableChunks(ctx context.Context, docIDs []uint) ([]model.RAGChunk, error) {
+	chunks, err := s.chunkRepo.ListByDocumentIDs(ctx, docIDs)
+	if err != nil {
+		return nil, err
+	}
+	return s.restoreArchived(ctx, chunks)
+}
+
+// restoreArchived restores the archived embeddings among chunks in place and saves them,
+// packing those archived as JSON.
+func (s *RAGService) restoreArchived(ctx context.Context, chunks []model.RAGChunk) ([]model.RAGChunk, error) {
+	for i := range chunks {
+		if !chunks[i].IsArchived() {
+			continue
+		}
+		if err := chunks[i].RestoreEmbedding(); err != nil {
+			return nil, fmt.Errorf("restore archived embedding failed: %w", err)
+		}
+		chunks[i].PackEmbedding(s.quantizeEmbeddings)
+		if err := s.chunkRepo.SaveEmbeddingState(ctx, &chunks[i]); err != nil {
+			return nil, err
+		}
+	}
+	return chunks, nil
+}
+
+// touchChunks records retrieval time; failures only affect archiving, so they are ignored.
+func (s *RAGService) touchChunks(ctx context.Context, chunks []model.RAGChunk) {
+	ids := make([]uint, len(chunks))
+	for i := range chunks {
+		ids[i] = chunks[i].ID
+	}
+	_ = s.chunkRepo.TouchAccessed(ctx, ids, time.Now())
+}
+
+type scoredChunk struct {
+	chunk model.RAGChunk
+	score float32
+}
+
+// selectTopChunks scores chunks by cosine similarity to the query embedding and returns the best k.
+func selectTopChunks(queryEmb []float32, chunks []model.RAGChunk, k int) []model.RAGChunk {
+	scored := make([]scoredChunk, len(chunks))
+	for i := range chunks {
+		vec := chunks[i].EmbeddingVector()
+		scored[i].chunk = chunks[i]
+		scored[i].score = cosineSimilarity(queryEmb, vec)
+	}
+	top := topKScored(scored, k)
+
+	selected := make([]model.RAGChunk, len(top))
+	for i := range top {
+		selected[i] = top[i].chunk
+	}
+	return selected
+}
+
+func topKScored(scored []scoredChunk, k int) []scoredChunk {
 	if k <= 0 || len(scored) == 0 {
 		return nil
 	}
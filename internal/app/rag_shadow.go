@@ -0,0 +1,255 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"gopherai-resume/internal/model"
+)
+
+const (
+	// shadowBackfillBatch is how many chunks a shadow backfill embeds per request.
+	shadowBackfillBatch = 64
+	// shadowMaxAttempts is how many times a chunk may fail to embed before the backfill
+	// skips it.
+	shadowMaxAttempts = 3
+	// shadowFullPassEvery is how often a backfill starts from the first chunk again, to
+	// retry failed chunks and drop the embeddings of deleted ones. Other runs only look at
+	// chunks added since the last.
+	shadowFullPassEvery = time.Hour
+	// shadowOrphanBatch is how many embeddings a full pass checks for a chunk at a time.
+	shadowOrphanBatch = 1000
+	// maxShadowErrorChars bounds the failure message kept per chunk.
+	maxShadowErrorChars = 512
+)
+
+// ShadowComparison compares an ask's retrieval under the primary embedding model with the
+// top-k the shadow model ranks highest among the same documents.
+type ShadowComparison struct {
+	Model string `json:"model"`
+	// PrimaryChunkIDs are the chunks the answer used, in rank order; ShadowChunkIDs are the
+	// shadow model's top-k by embedding similarity alone.
+	PrimaryChunkIDs []uint `json:"primary_chunk_ids"`
+	ShadowChunkIDs  []uint `json:"shadow_chunk_ids"`
+	// Overlap is how many chunks both lists share and Jaccard that count over their union.
+	Overlap int     `json:"overlap"`
+	Jaccard float64 `json:"jaccard"`
+	// Unembedded counts primary chunks the shadow model has not embedded yet; while it is
+	// non-zero the backfill has not caught up and the comparison understates the overlap.
+	Unembedded int `json:"unembedded,omitempty"`
+}
+
+// ShadowStats summarizes the comparison asks run against the shadow model by every
+// server instance.
+type ShadowStats struct {
+	Model string `json:"model"`
+	// EmbeddedChunks is how many chunks have a shadow embedding so far; FailedChunks how
+	// many failed to embed too often and are skipped.
+	EmbeddedChunks int64 `json:"embedded_chunks"`
+	FailedChunks   int64 `json:"failed_chunks"`
+	Comparisons    int64 `json:"comparisons"`
+	// MeanOverlap is the average share of the primary top-k the shadow top-k also found;
+	// MeanJaccard the average Jaccard similarity of the two.
+	MeanOverlap float64 `json:"mean_overlap"`
+	MeanJaccard float64 `json:"mean_jaccard"`
+}
+
+// shadowBackfill is where this instance's backfill left off.
+type shadowBackfill struct {
+	mu sync.Mutex
+	// cursor is the highest chunk ID examined since the last full pass, at fullPassAt.
+	cursor     uint
+	fullPassAt time.Time
+}
+
+// ShadowStats reports the shadow model's embedding progress and comparison averages.
+func (s *RAGService) ShadowStats(ctx context.Context) (*ShadowStats, error) {
+	if s.shadowConfig.Model == "" {
+		return nil, ErrInvalidInput.Withf("no shadow embedding model is configured")
+	}
+	embedded, err := s.shadowRepo.CountByModel(ctx, s.shadowConfig.Model)
+	if err != nil {
+		return nil, err
+	}
+	failed, err := s.shadowRepo.CountFailedByModel(ctx, s.shadowConfig.Model, shadowMaxAttempts)
+	if err != nil {
+		return nil, err
+	}
+	stats := &ShadowStats{Model: s.shadowConfig.Model, EmbeddedChunks: embedded, FailedChunks: failed}
+	stat, err := s.shadowRepo.GetStat(ctx, s.shadowConfig.Model)
+	if err != nil {
+		return nil, err
+	}
+	if stat != nil && stat.Comparisons > 0 {
+		stats.Comparisons = stat.Comparisons
+		stats.MeanOverlap = stat.OverlapSum / float64(stat.Comparisons)
+		stats.MeanJaccard = stat.JaccardSum / float64(stat.Comparisons)
+	}
+	return stats, nil
+}
+
+// BackfillShadowEmbeddings embeds the chunks that have no shadow embedding yet, new
+// ingests included. It returns how many chunks were embedded and does nothing without a
+// shadow model. Runs continue after the last chunk the previous run reached; once per
+// shadowFullPassEvery a run starts over, retrying chunks that failed and dropping the
+// embeddings of deleted chunks.
+func (s *RAGService) BackfillShadowEmbeddings(ctx context.Context) (int, error) {
+	if s.shadowConfig.Model == "" {
+		return 0, nil
+	}
+	s.shadow.mu.Lock()
+	defer s.shadow.mu.Unlock()
+	if time.Since(s.shadow.fullPassAt) >= shadowFullPassEvery {
+		if err := s.deleteOrphanedShadowEmbeddings(ctx); err != nil {
+			return 0, err
+		}
+		s.shadow.cursor = 0
+		s.shadow.fullPassAt = time.Now()
+	}
+	embedded := 0
+	for {
+		chunks, err := s.shadowRepo.ListChunksMissing(ctx, s.shadowConfig.Model, s.shadow.cursor, shadowBackfillBatch, shadowMaxAttempts)
+		if err != nil {
+			return embedded, err
+		}
+		if len(chunks) == 0 {
+			return embedded, nil
+		}
+		n, err := s.embedShadowBatch(ctx, chunks)
+		embedded += n
+		if err != nil {
+			return embedded, err
+		}
+		s.shadow.cursor = chunks[len(chunks)-1].ID
+	}
+}
+
+// embedShadowBatch embeds chunks with the shadow model and stores them. When the batch
+// fails, each chunk is embedded on its own so one the provider rejects does not hold back
+// the rest; the failures are recorded and a chunk is skipped after shadowMaxAttempts. If
+// every chunk fails the provider is likely down, so the error is returned to end the run.
+func (s *RAGService) embedShadowBatch(ctx context.Context, chunks []model.RAGChunk) (int, error) {
+	texts := make([]string, len(chunks))
+	for i, c := range chunks {
+		texts[i] = c.Content
+	}
+	vecs, err := s.shadowEmbedder.EmbedBatch(ctx, s.shadowConfig, texts)
+	if err == nil && len(vecs) != len(chunks) {
+		err = fmt.Errorf("shadow embedding returned %d vectors for %d chunks", len(vecs), len(chunks))
+	}
+	if err == nil {
+		return len(chunks), s.storeShadowEmbeddings(ctx, chunks, vecs)
+	}
+	if ctx.Err() != nil {
+		return 0, err
+	}
+
+	embedded := 0
+	var lastErr error
+	for i := range chunks {
+		vec, err := s.shadowEmbedder.Embed(ctx, s.shadowConfig, chunks[i].Content)
+		if err != nil {
+			if ctx.Err() != nil {
+				return embedded, err
+			}
+			lastErr = err
+			if err := s.shadowRepo.RecordFailures(ctx, s.shadowConfig.Model, chunks[i:i+1], truncateRunes(err.Error(), maxShadowErrorChars)); err != nil {
+				return embedded, err
+			}
+			continue
+		}
+		if err := s.storeShadowEmbeddings(ctx, chunks[i:i+1], [][]float32{vec}); err != nil {
+			return embedded, err
+		}
+		embedded++
+	}
+	if embedded == 0 {
+		return 0, lastErr
+	}
+	log.Printf("shadow embedding with %s failed for %d of %d chunks: %v", s.shadowConfig.Model, len(chunks)-embedded, len(chunks), lastErr)
+	return embedded, nil
+}
+
+func (s *RAGService) storeShadowEmbeddings(ctx context.Context, chunks []model.RAGChunk, vecs [][]float32) error {
+	rows := make([]model.RAGShadowEmbedding, len(chunks))
+	for i, c := range chunks {
+		rows[i] = model.RAGShadowEmbedding{ChunkID: c.ID, DocumentID: c.DocumentID, Model: s.shadowConfig.Model}
+		rows[i].SetEmbedding(vecs[i])
+	}
+	return s.shadowRepo.UpsertBatch(ctx, rows)
+}
+
+// deleteOrphanedShadowEmbeddings drops the shadow embeddings of deleted chunks, a batch at
+// a time.
+func (s *RAGService) deleteOrphanedShadowEmbeddings(ctx context.Context) error {
+	var afterID uint
+	for {
+		last, _, err := s.shadowRepo.DeleteOrphaned(ctx, afterID, shadowOrphanBatch)
+		if err != nil || last == 0 {
+			return err
+		}
+		afterID = last
+	}
+}
+
+// compareShadow ranks the documents' chunks by the shadow model's embeddings and compares
+// its top-k with the primary chunks that were selected.
+func (s *RAGService) compareShadow(ctx context.Context, query string, docIDs []uint, topK int, primary []model.RAGChunk) (*ShadowComparison, error) {
+	queryEmb, err := s.shadowEmbedder.Embed(ctx, s.shadowConfig, query)
+	if err != nil {
+		return nil, err
+	}
+	embeddings, err := s.shadowRepo.ListByDocumentIDs(ctx, s.shadowConfig.Model, docIDs)
+	if err != nil {
+		return nil, err
+	}
+	scores := make([]float32, len(embeddings))
+	embedded := make(map[uint]bool, len(embeddings))
+	for i := range embeddings {
+		scores[i] = cosineSimilarity(queryEmb, embeddings[i].EmbeddingVector())
+		embedded[embeddings[i].ChunkID] = true
+	}
+	order := make([]int, len(embeddings))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool { return scores[order[a]] > scores[order[b]] })
+	if len(order) > topK {
+		order = order[:topK]
+	}
+
+	cmp := &ShadowComparison{
+		Model:           s.shadowConfig.Model,
+		PrimaryChunkIDs: make([]uint, 0, len(primary)),
+		ShadowChunkIDs:  make([]uint, 0, len(order)),
+	}
+	inShadow := make(map[uint]bool, len(order))
+	for _, idx := range order {
+		id := embeddings[idx].ChunkID
+		cmp.ShadowChunkIDs = append(cmp.ShadowChunkIDs, id)
+		inShadow[id] = true
+	}
+	for _, c := range primary {
+		cmp.PrimaryChunkIDs = append(cmp.PrimaryChunkIDs, c.ID)
+		if inShadow[c.ID] {
+			cmp.Overlap++
+		}
+		if !embedded[c.ID] {
+			cmp.Unembedded++
+		}
+	}
+	if union := len(cmp.PrimaryChunkIDs) + len(cmp.ShadowChunkIDs) - cmp.Overlap; union > 0 {
+		cmp.Jaccard = float64(cmp.Overlap) / float64(union)
+	}
+	if len(cmp.PrimaryChunkIDs) > 0 {
+		overlap := float64(cmp.Overlap) / float64(len(cmp.PrimaryChunkIDs))
+		if err := s.shadowRepo.RecordComparison(ctx, s.shadowConfig.Model, overlap, cmp.Jaccard); err != nil {
+			log.Printf("record shadow comparison with %s failed: %v", s.shadowConfig.Model, err)
+		}
+	}
+	return cmp, nil
+}
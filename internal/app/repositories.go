@@ -0,0 +1,486 @@
+package app
+
+import (
+	"context"
+	"time"
+
+	"gopherai-resume/internal/model"
+	"gopherai-resume/internal/repository"
+)
+
+// The services depend on these interfaces rather than on the GORM repositories, so they
+// can be unit tested with fakes and backed by other stores. The GORM implementations in
+// package repository satisfy them; bootstrap wires them in.
+//
+// Lookups return (nil, nil) when the record does not exist, and write methods that touch
+// several tables do so atomically.
+
+type ActivityRepository interface {
+	Create(ctx context.Context, event *model.ActivityEvent) error
+	// ListByUserID returns up to limit of the user's events with an ID below beforeID (0 for
+	// no bound), newest first, optionally only those of the given types.
+	ListByUserID(ctx context.Context, userID uint, types []string, beforeID uint, limit int) ([]model.ActivityEvent, error)
+	// DeleteBefore deletes up to limit events created before cutoff and returns how many it
+	// deleted.
+	DeleteBefore(ctx context.Context, cutoff time.Time, limit int) (int64, error)
+}
+
+type ApplicationRepository interface {
+	// Create inserts the application and its initial status entry.
+	Create(ctx context.Context, application *model.Application, change *model.ApplicationStatusChange) error
+	// Update saves the application and, when change is non-nil, records the status transition.
+	Update(ctx context.Context, application *model.Application, change *model.ApplicationStatusChange) error
+	GetByID(ctx context.Context, id uint) (*model.Application, error)
+	// ListByUserID lists the user's applications, most recently updated first. An empty status lists all.
+	ListByUserID(ctx context.Context, userID uint, status string) ([]model.Application, error)
+	// ListForBoard lists the user's applications in board order: placed cards by position,
+	// then the rest most recently updated first. Callers group them by status.
+	ListForBoard(ctx context.Context, userID uint) ([]model.Application, error)
+	// Move records a status change of application, when change is non-nil, and sets the board
+	// position of the user's applications to their place in columnIDs, starting at 1.
+	// UpdatedAt is only touched by the status change, so reordering does not count as activity.
+	Move(ctx context.Context, application *model.Application, change *model.ApplicationStatusChange, columnIDs []uint) error
+	ListStatusChanges(ctx context.Context, applicationID uint) ([]model.ApplicationStatusChange, error)
+	// ListDueReminders returns the user's applications in one of statuses whose reminder is at or before before.
+	ListDueReminders(ctx context.Context, userID uint, before time.Time, statuses []string) ([]model.Application, error)
+	// ListUnsentReminders returns up to limit applications, across users, in one of statuses
+	// whose reminder is due at before and has not been emailed yet, earliest first.
+	ListUnsentReminders(ctx context.Context, before time.Time, statuses []string, limit int) ([]model.Application, error)
+	// MarkReminderSent records that the reminder due at remindAt was emailed. It does nothing
+	// when the reminder was rescheduled in the meantime. UpdatedAt is left alone.
+	MarkReminderSent(ctx context.Context, id uint, remindAt, sentAt time.Time) error
+	// DeleteByIDAndUserID deletes the application and its status history.
+	DeleteByIDAndUserID(ctx context.Context, id, userID uint) error
+}
+
+type DataExportRepository interface {
+	Create(ctx context.Context, export *model.DataExport) error
+	// CreateActive inserts export as the user's one unfinished export, reporting false when
+	// the user already has one.
+	CreateActive(ctx context.Context, export *model.DataExport) (bool, error)
+	Update(ctx context.Context, export *model.DataExport) error
+	GetByID(ctx context.Context, id uint) (*model.DataExport, error)
+	// ListByUserID lists the user's exports, newest first.
+	ListByUserID(ctx context.Context, userID uint) ([]model.DataExport, error)
+	DeleteByID(ctx context.Context, id uint) error
+}
+
+type JobPostingRepository interface {
+	// Create inserts the posting with its tags.
+	Create(ctx context.Context, posting *model.JobPosting, tags []string) error
+	// Update saves the posting and, when tags is non-nil, replaces its tags.
+	Update(ctx context.Context, posting *model.JobPosting, tags []string) error
+	GetByIDAndWorkspaceID(ctx context.Context, id, workspaceID uint) (*model.JobPosting, error)
+	// ListByWorkspaceID lists the workspace's postings, most recently updated first.
+	ListByWorkspaceID(ctx context.Context, workspaceID uint, filter repository.JobPostingFilter) ([]model.JobPosting, error)
+	// ListTags returns the tags of the given postings keyed by posting ID.
+	ListTags(ctx context.Context, postingIDs []uint) (map[uint][]string, error)
+	// DeleteByIDAndWorkspaceID deletes the posting, its tags, screening results and report records.
+	// Stored report files are left to the caller.
+	DeleteByIDAndWorkspaceID(ctx context.Context, id, workspaceID uint) error
+	CreateScreeningResults(ctx context.Context, results []model.ScreeningResult) error
+	// ListScreeningResults lists a posting's screening results, best score first.
+	ListScreeningResults(ctx context.Context, postingID uint) ([]model.ScreeningResult, error)
+	GetScreeningResult(ctx context.Context, id, postingID uint) (*model.ScreeningResult, error)
+}
+
+type MessageEmbeddingRepository interface {
+	// Create stores the embedding, replacing any earlier one for the same message so
+	// redelivered queue messages stay idempotent.
+	Create(ctx context.Context, embedding *model.MessageEmbedding) error
+	// ListByUserID returns the user's most recent embedded messages, newest first.
+	ListByUserID(ctx context.Context, userID uint) ([]repository.EmbeddedMessage, error)
+}
+
+type MessageRepository interface {
+	// Create stores message. Unless it names its parent, the message follows the latest
+	// message on its session's branch.
+	Create(ctx context.Context, message *model.Message) error
+	// ListPageBySessionID returns up to limit messages of the session's branch with IDs below
+	// beforeID (the newest when beforeID is 0), oldest first, and whether older ones remain.
+	ListPageBySessionID(ctx context.Context, sessionID, beforeID uint, limit int) ([]model.Message, bool, error)
+	ListRecentBySessionID(ctx context.Context, sessionID uint, limit int) ([]model.Message, error)
+	// ListRangeBySessionID returns up to limit messages of the session's branch with
+	// afterID < id < beforeID, oldest first.
+	ListRangeBySessionID(ctx context.Context, sessionID, afterID, beforeID uint, limit int) ([]model.Message, error)
+	// ListBranch returns up to limit messages of the session's branch in conversation order.
+	ListBranch(ctx context.Context, sessionID uint, limit int) ([]model.Message, error)
+	// UsageByModel sums the usage of the user's assistant messages created since since, per model.
+	UsageByModel(ctx context.Context, userID uint, since time.Time) ([]repository.ModelUsage, error)
+	// UsageByDay sums the usage of the user's assistant messages created since since, per day.
+	UsageByDay(ctx context.Context, userID uint, since time.Time) ([]repository.DailyUsage, error)
+	// GetOnBranch returns the message if it is on the session's branch, or nil.
+	GetOnBranch(ctx context.Context, sessionID, messageID uint) (*model.Message, error)
+	// ListBySessionIDs returns up to limit messages of the sessions, in ID order.
+	ListBySessionIDs(ctx context.Context, sessionIDs []uint, limit int) ([]model.Message, error)
+	DeleteBySessionID(ctx context.Context, sessionID uint) error
+	GetByID(ctx context.Context, id uint) (*model.Message, error)
+	ListByIDsAndUserID(ctx context.Context, ids []uint, userID uint) ([]model.Message, error)
+	// ListByUserIDAfterID returns up to limit of the user's messages with IDs above afterID,
+	// in ID order.
+	ListByUserIDAfterID(ctx context.Context, userID, afterID uint, limit int) ([]model.Message, error)
+	// DeleteByIDs deletes messages and their embeddings.
+	DeleteByIDs(ctx context.Context, ids []uint) (int64, error)
+	// ReplaceContentAndTruncate sets message's content and deletes every later message in its
+	// session, along with the embeddings of the edited and deleted messages. It returns the
+	// number of messages deleted.
+	ReplaceContentAndTruncate(ctx context.Context, message *model.Message, content string) (int64, error)
+	// Fork creates session and stores messages in it, each following the one before; the
+	// first keeps its ParentMessageID, the fork point.
+	Fork(ctx context.Context, session *model.Session, messages []model.Message) error
+}
+
+type NotificationRepository interface {
+	GetPreference(ctx context.Context, userID uint) (*model.NotificationPreference, error)
+	// SavePreference inserts or updates the user's preferences.
+	SavePreference(ctx context.Context, pref *model.NotificationPreference) error
+	CreateOutbox(ctx context.Context, email *model.EmailOutbox) error
+	// ListDueOutbox returns up to limit pending emails whose next attempt is due, oldest first.
+	ListDueOutbox(ctx context.Context, status string, now time.Time, limit int) ([]model.EmailOutbox, error)
+	// ClaimOutbox takes a due email for one attempt, reporting false when another sender
+	// already has it.
+	ClaimOutbox(ctx context.Context, id uint, status string, now, leaseUntil time.Time) (bool, error)
+	UpdateOutbox(ctx context.Context, email *model.EmailOutbox) error
+	// ListOutboxByUserIDAfterID pages through the emails sent or queued for the user by ID.
+	ListOutboxByUserIDAfterID(ctx context.Context, userID, afterID uint, limit int) ([]model.EmailOutbox, error)
+}
+
+type PortfolioAnalysisRepository interface {
+	Create(ctx context.Context, analysis *model.PortfolioAnalysis) error
+	GetByID(ctx context.Context, id uint) (*model.PortfolioAnalysis, error)
+	ListByUserID(ctx context.Context, userID uint) ([]model.PortfolioAnalysis, error)
+	DeleteByIDAndUserID(ctx context.Context, id, userID uint) error
+}
+
+type RAGChunkRepository interface {
+	Create(ctx context.Context, chunk *model.RAGChunk) error
+	// CreateBatch stores chunks; with a check, the user's storage is checked again before
+	// they commit.
+	CreateBatch(ctx context.Context, chunks []model.RAGChunk, check *repository.StorageCheck) error
+	// ListByDocumentIDs returns all chunks for the given document IDs (for a user's docs).
+	// Caller should filter document IDs by user ownership.
+	ListByDocumentIDs(ctx context.Context, documentIDs []uint) ([]model.RAGChunk, error)
+	// ListByIDs returns the chunks with the given IDs; missing IDs are skipped.
+	ListByIDs(ctx context.Context, ids []uint) ([]model.RAGChunk, error)
+	// ListContentByDocumentIDs returns the ID, document and content of the documents'
+	// chunks, without embeddings.
+	ListContentByDocumentIDs(ctx context.Context, documentIDs []uint) ([]model.RAGChunk, error)
+	// ListPageByDocumentID returns up to limit of the document's chunks in index order,
+	// skipping the first offset.
+	ListPageByDocumentID(ctx context.Context, documentID uint, offset, limit int) ([]model.RAGChunk, error)
+	CountByDocumentID(ctx context.Context, documentID uint) (int64, error)
+	// ListAfterID returns up to limit chunks with IDs above afterID in ID order, for walking
+	// the whole table.
+	ListAfterID(ctx context.Context, afterID uint, limit int) ([]model.RAGChunk, error)
+	// ListJSONEmbeddedAfterID returns up to limit chunks with IDs above afterID, in ID order,
+	// that still store their embedding as JSON.
+	ListJSONEmbeddedAfterID(ctx context.Context, afterID uint, limit int) ([]model.RAGChunk, error)
+	// AppendBatch stores chunks after a document's existing ones, setting their indexes
+	// and moving their offsets past the existing text, updates the document's chunk count
+	// and returns the first chunk's index. Concurrent appends get distinct indexes. With a
+	// check, the user's storage is checked again before the append commits.
+	AppendBatch(ctx context.Context, documentID uint, chunks []model.RAGChunk, check *repository.StorageCheck) (int, error)
+	DeleteByDocumentID(ctx context.Context, documentID uint) error
+	// ReplaceByDocumentID swaps all chunks of a document for chunks in one transaction;
+	// with a check, the user's storage is checked again before it commits.
+	ReplaceByDocumentID(ctx context.Context, documentID uint, chunks []model.RAGChunk, check *repository.StorageCheck) error
+	// CountOrphaned returns the number of chunks whose document no longer exists.
+	CountOrphaned(ctx context.Context) (int64, error)
+	// DeleteOrphaned deletes chunks whose document no longer exists and returns how many were removed.
+	DeleteOrphaned(ctx context.Context) (int64, error)
+	// CountByDocument returns chunk counts keyed by document ID.
+	CountByDocument(ctx context.Context) (map[uint]int, error)
+	// StorageByUser reports document/chunk counts and byte sizes per user.
+	StorageByUser(ctx context.Context) ([]repository.RAGUserStorage, error)
+	// StorageByUserID reports the user's document count, and the chunk count and content
+	// bytes of their documents other than excludeDocumentID (0 = none).
+	StorageByUserID(ctx context.Context, userID, excludeDocumentID uint) (repository.RAGUserStorage, error)
+	// ListFingerprints returns the content fingerprints of the document's chunks,
+	// optionally only those with hashes.
+	ListFingerprints(ctx context.Context, documentID uint, hashes []string) ([]repository.RAGChunkFingerprint, error)
+	// TouchAccessed sets last_accessed_at for the given chunks.
+	TouchAccessed(ctx context.Context, ids []uint, at time.Time) error
+	// ListColdDocumentIDs returns documents created before cutoff that still have hot embeddings
+	// and none of whose chunks were retrieved since cutoff.
+	ListColdDocumentIDs(ctx context.Context, cutoff time.Time) ([]uint, error)
+	// SaveEmbeddingState persists the embedding columns after archiving or restoring a chunk.
+	SaveEmbeddingState(ctx context.Context, chunk *model.RAGChunk) error
+}
+
+// RAGShadowEmbeddingRepository stores chunk embeddings under a shadow embedding model.
+type RAGShadowEmbeddingRepository interface {
+	// UpsertBatch stores the embeddings, replacing any earlier one for the same chunk and model.
+	UpsertBatch(ctx context.Context, embeddings []model.RAGShadowEmbedding) error
+	// ListByDocumentIDs returns the model's embeddings of the given documents' chunks.
+	ListByDocumentIDs(ctx context.Context, modelName string, documentIDs []uint) ([]model.RAGShadowEmbedding, error)
+	// RecordFailures counts a failed attempt to embed each chunk under the model and keeps
+	// message as the last error.
+	RecordFailures(ctx context.Context, modelName string, chunks []model.RAGChunk, message string) error
+	// ListChunksMissing returns up to limit chunks with IDs above afterID, in ID order, that
+	// have no embedding under the model yet and have failed fewer than maxAttempts times.
+	ListChunksMissing(ctx context.Context, modelName string, afterID uint, limit, maxAttempts int) ([]model.RAGChunk, error)
+	CountByModel(ctx context.Context, modelName string) (int64, error)
+	// CountFailedByModel returns how many chunks failed maxAttempts times and are no longer tried.
+	CountFailedByModel(ctx context.Context, modelName string, maxAttempts int) (int64, error)
+	// DeleteOrphaned deletes the embeddings among the first limit with IDs above afterID
+	// whose chunk no longer exists. It returns the last ID examined, 0 once there are none
+	// left, and how many were removed.
+	DeleteOrphaned(ctx context.Context, afterID uint, limit int) (uint, int64, error)
+	// RecordComparison adds one comparison ask's overlap share and Jaccard similarity to
+	// the model's totals.
+	RecordComparison(ctx context.Context, modelName string, overlap, jaccard float64) error
+	// GetStat returns the model's comparison totals, or nil before the first comparison.
+	GetStat(ctx context.Context, modelName string) (*model.RAGShadowStat, error)
+}
+
+type RAGDocumentRepository interface {
+	// Create stores doc; with a check, the user's storage is checked again before it commits.
+	Create(ctx context.Context, doc *model.RAGDocument, check *repository.StorageCheck) error
+	// ListByUserID lists the user's documents, newest first, leaving out temporary ones.
+	ListByUserID(ctx context.Context, userID uint) ([]model.RAGDocument, error)
+	// ListByUserIDAndSessionID lists documents for user; if sessionID is 0, lists all user's docs.
+	// Temporary documents are left out.
+	ListByUserIDAndSessionID(ctx context.Context, userID, sessionID uint) ([]model.RAGDocument, error)
+	// ListBySessionID returns document IDs for a session (for cascade delete).
+	ListBySessionID(ctx context.Context, sessionID uint) ([]uint, error)
+	// DeleteBySessionID deletes all documents in a session (caller must delete chunks first).
+	DeleteBySessionID(ctx context.Context, sessionID uint) error
+	GetByID(ctx context.Context, id uint) (*model.RAGDocument, error)
+	DeleteByIDAndUserID(ctx context.Context, id, userID uint) error
+	// ListAll returns every document; used by maintenance jobs.
+	ListAll(ctx context.Context) ([]model.RAGDocument, error)
+	// ListByFileHash returns the user's documents in the session (0 = no session) uploaded
+	// from the file with the given hash, newest first.
+	ListByFileHash(ctx context.Context, userID, sessionID uint, fileHash string) ([]model.RAGDocument, error)
+	UpdateChunkCount(ctx context.Context, id uint, count int) error
+	// UpdateDedupe records the chunks an asynchronous ingest left out as duplicates.
+	UpdateDedupe(ctx context.Context, id uint, duplicate, nearDuplicate int) error
+	// UpdateContent saves the name, chunking, chunk and page counts and file hash of a
+	// document whose content was replaced.
+	UpdateContent(ctx context.Context, doc *model.RAGDocument) error
+	// UpdateStatus sets the document's ingest status and error message.
+	UpdateStatus(ctx context.Context, id uint, status, errMsg string) error
+	// ClaimIngest takes a pending document, or a processing one whose lease ran out, for
+	// one ingest attempt, reporting false when another worker holds it.
+	ClaimIngest(ctx context.Context, id uint, now, leaseUntil time.Time) (bool, error)
+	// ReleaseIngest puts a processing document back to pending.
+	ReleaseIngest(ctx context.Context, id uint) error
+	// ListByStatus returns every document in one of the statuses, oldest first.
+	ListByStatus(ctx context.Context, statuses []string) ([]model.RAGDocument, error)
+	// ListTemporaryBefore returns the temporary documents created before cutoff, oldest first.
+	ListTemporaryBefore(ctx context.Context, cutoff time.Time) ([]model.RAGDocument, error)
+	// ListUnindexedIDs returns those of ids whose chunks are not all in the vector store.
+	ListUnindexedIDs(ctx context.Context, ids []uint) ([]uint, error)
+	// SetVectorIndexed records whether the documents' chunks are all in the vector store.
+	SetVectorIndexed(ctx context.Context, ids []uint, indexed bool) error
+}
+
+// RAGIngestEmbeddingRepository saves the embeddings of unfinished asynchronous ingests.
+type RAGIngestEmbeddingRepository interface {
+	// UpsertBatch stores the embeddings, replacing any earlier one for the same chunk.
+	UpsertBatch(ctx context.Context, embeddings []model.RAGIngestEmbedding) error
+	ListByDocumentID(ctx context.Context, documentID uint) ([]model.RAGIngestEmbedding, error)
+	CountByDocumentID(ctx context.Context, documentID uint) (int64, error)
+	DeleteByDocumentID(ctx context.Context, documentID uint) error
+}
+
+type RAGMessageRepository interface {
+	Create(ctx context.Context, message *model.RAGMessage) error
+	// ListRecentBySessionID returns up to limit of the session's newest messages with an ID below
+	// beforeID (0 = no bound), newest first.
+	ListRecentBySessionID(ctx context.Context, sessionID, beforeID uint, limit int) ([]model.RAGMessage, error)
+	DeleteBySessionID(ctx context.Context, sessionID uint) error
+}
+
+type RAGSessionRepository interface {
+	Create(ctx context.Context, session *model.RAGSession) error
+	ListByUserID(ctx context.Context, userID uint) ([]model.RAGSession, error)
+	GetByID(ctx context.Context, id uint) (*model.RAGSession, error)
+	DeleteByIDAndUserID(ctx context.Context, id, userID uint) error
+}
+
+type ResumeBulletRepository interface {
+	// ReplaceOpen makes bullets the document's open bullets: open bullets no longer flagged are
+	// deleted, and bullets matching an existing one (or an accepted rewrite) are not inserted.
+	ReplaceOpen(ctx context.Context, documentID uint, closedStatuses []string, bullets []model.ResumeBullet) error
+	Update(ctx context.Context, bullet *model.ResumeBullet) error
+	GetByID(ctx context.Context, id uint) (*model.ResumeBullet, error)
+	ListByDocumentID(ctx context.Context, userID, documentID uint) ([]model.ResumeBullet, error)
+}
+
+type ResumeProfileRepository interface {
+	// Save inserts a new profile or updates an existing one (ID set).
+	Save(ctx context.Context, profile *model.ResumeProfile) error
+	GetByDocumentIDAndUserID(ctx context.Context, documentID, userID uint) (*model.ResumeProfile, error)
+}
+
+type ResumeSchemaRepository interface {
+	// Create stores schema as the next version, activating it when schema.Active is set.
+	Create(ctx context.Context, schema *model.ResumeSchema) error
+	List(ctx context.Context) ([]model.ResumeSchema, error)
+	GetByVersion(ctx context.Context, version int) (*model.ResumeSchema, error)
+	GetActive(ctx context.Context) (*model.ResumeSchema, error)
+	// Activate makes version the only active schema; version 0 deactivates all of them.
+	Activate(ctx context.Context, version int) error
+}
+
+type ScheduledMessageRepository interface {
+	Create(ctx context.Context, msg *model.ScheduledMessage) error
+	GetByID(ctx context.Context, id uint) (*model.ScheduledMessage, error)
+	// ListByUserID lists the user's scheduled messages, soonest first. An empty status lists all.
+	ListByUserID(ctx context.Context, userID uint, status string) ([]model.ScheduledMessage, error)
+	// CountByUserIDAndStatus counts the user's scheduled messages in status.
+	CountByUserIDAndStatus(ctx context.Context, userID uint, status string) (int64, error)
+	// ListDue returns up to limit messages that are pending and due at now, or were claimed
+	// at or before staleBefore and never finished, earliest first.
+	ListDue(ctx context.Context, pending, running string, now, staleBefore time.Time, limit int) ([]model.ScheduledMessage, error)
+	// Claim moves msg to status and counts an attempt, unless another worker changed it since
+	// it was read; Attempts serves as the version. It reports whether the claim won and
+	// updates msg to match.
+	Claim(ctx context.Context, msg *model.ScheduledMessage, status string, at time.Time) (bool, error)
+	Update(ctx context.Context, msg *model.ScheduledMessage) error
+	// SetStatusIf moves the user's message from one status to another and reports whether it
+	// was in from.
+	SetStatusIf(ctx context.Context, id, userID uint, from, to string) (bool, error)
+}
+
+type ScreeningReportRepository interface {
+	CreateBatch(ctx context.Context, reports []model.ScreeningReport) error
+	Update(ctx context.Context, report *model.ScreeningReport) error
+	GetByID(ctx context.Context, id uint) (*model.ScreeningReport, error)
+	GetByIDAndPostingID(ctx context.Context, id, postingID uint) (*model.ScreeningReport, error)
+	// ListByPostingID lists a posting's reports, newest first.
+	ListByPostingID(ctx context.Context, postingID uint) ([]model.ScreeningReport, error)
+}
+
+type SessionRepository interface {
+	Create(ctx context.Context, session *model.Session) error
+	// ListByUserID lists pinned sessions first, then by custom order, then most recently updated.
+	ListByUserID(ctx context.Context, userID uint) ([]model.Session, error)
+	GetByID(ctx context.Context, id uint) (*model.Session, error)
+	Update(ctx context.Context, session *model.Session) error
+	// CountByIDsAndUserID counts how many of the IDs are sessions owned by the user.
+	CountByIDsAndUserID(ctx context.Context, ids []uint, userID uint) (int64, error)
+	// UpdateSortOrder gives the user's sessions in ids positions 1..n in that order and renumbers
+	// their other ordered sessions after them. UpdatedAt is left alone so reordering does not
+	// count as activity.
+	UpdateSortOrder(ctx context.Context, userID uint, ids []uint) error
+	// UpdateSummary stores a session's rolling summary. UpdatedAt is left alone so it does not
+	// count as activity, and other fields are untouched so concurrent setting changes survive.
+	UpdateSummary(ctx context.Context, sessionID uint, summary string, untilID uint) error
+	// MarkSummaryPending records that the session's messages before beforeID should be
+	// summarized. It never lowers an earlier mark.
+	MarkSummaryPending(ctx context.Context, sessionID, beforeID uint) error
+	// ListSummaryPending lists up to limit sessions with messages waiting to be summarized.
+	ListSummaryPending(ctx context.Context, limit int) ([]model.Session, error)
+	// Lineage returns the session followed by the sessions it was forked from, nearest first.
+	// A deleted ancestor ends the chain early.
+	Lineage(ctx context.Context, sessionID uint) ([]model.Session, error)
+	// ListTree returns the user's session rootID followed by every session forked from it,
+	// directly or not, level by level.
+	ListTree(ctx context.Context, rootID, userID uint) ([]model.Session, error)
+	// CountForks counts the sessions forked directly from sessionID.
+	CountForks(ctx context.Context, sessionID uint) (int64, error)
+	// HasForksFrom reports whether a session was forked from sessionID at messageID or
+	// later, so that its history includes the message.
+	HasForksFrom(ctx context.Context, sessionID, messageID uint) (bool, error)
+	// DeleteByIDAndUserID deletes the session and revokes its share link.
+	DeleteByIDAndUserID(ctx context.Context, sessionID, userID uint) error
+}
+
+type SessionShareRepository interface {
+	// Replace stores share and deletes the session's earlier share, so only the newest link works.
+	Replace(ctx context.Context, share *model.SessionShare) error
+	GetByTokenHash(ctx context.Context, hash string) (*model.SessionShare, error)
+	// DeleteBySessionID revokes the session's share and reports whether there was one.
+	DeleteBySessionID(ctx context.Context, sessionID uint) (bool, error)
+}
+
+type UserRepository interface {
+	Create(ctx context.Context, user *model.User) error
+	GetByUsername(ctx context.Context, username string) (*model.User, error)
+	GetByEmail(ctx context.Context, email string) (*model.User, error)
+	GetByID(ctx context.Context, id uint) (*model.User, error)
+	// UpdatePasswordHash sets the password hash and bumps the token version, revoking every
+	// token issued before.
+	UpdatePasswordHash(ctx context.Context, id uint, hash string) error
+	MarkEmailVerified(ctx context.Context, id uint, at time.Time) error
+}
+
+type UserTokenRepository interface {
+	// Replace stores token and deletes the user's earlier tokens for the same purpose, so only
+	// the most recently emailed one works.
+	Replace(ctx context.Context, token *model.UserToken) error
+	// Consume marks an unused, unexpired token as used and returns it. It returns nil when no
+	// such token exists or another request used it first.
+	Consume(ctx context.Context, hash, purpose string, now time.Time) (*model.UserToken, error)
+}
+
+type VisionSampleRepository interface {
+	Create(ctx context.Context, sample *model.VisionSample) error
+	GetByID(ctx context.Context, id uint) (*model.VisionSample, error)
+	ListByUserID(ctx context.Context, userID uint, limit int) ([]model.VisionSample, error)
+	// ListByUserIDAfterID returns up to limit of the user's samples with IDs above afterID,
+	// in ID order.
+	ListByUserIDAfterID(ctx context.Context, userID, afterID uint, limit int) ([]model.VisionSample, error)
+	// ListByImageHash returns the user's samples of the image with the given SHA-256,
+	// newest first.
+	ListByImageHash(ctx context.Context, userID uint, sha256 string) ([]model.VisionSample, error)
+	// ListRecent returns the most recent samples across all users, for model evaluation.
+	ListRecent(ctx context.Context, limit int) ([]model.VisionSample, error)
+	DeleteByIDAndUserID(ctx context.Context, id, userID uint) error
+}
+
+type WorkspaceCandidateRepository interface {
+	Create(ctx context.Context, candidate *model.WorkspaceCandidate) error
+	GetByIDAndWorkspaceID(ctx context.Context, id, workspaceID uint) (*model.WorkspaceCandidate, error)
+	GetByDocumentID(ctx context.Context, workspaceID, documentID uint) (*model.WorkspaceCandidate, error)
+	ListByWorkspaceID(ctx context.Context, workspaceID uint) ([]model.WorkspaceCandidate, error)
+	// Search lists up to limit candidates having every skill and at least minYears of
+	// experience, most experienced first when byExperience is set and newest first otherwise.
+	Search(ctx context.Context, workspaceID uint, skills []string, minYears *float64, byExperience bool, limit int) ([]model.WorkspaceCandidate, error)
+	DeleteByIDAndWorkspaceID(ctx context.Context, id, workspaceID uint) error
+}
+
+type WorkspaceRepository interface {
+	// Create inserts the workspace and its owner's membership.
+	Create(ctx context.Context, workspace *model.Workspace, owner *model.WorkspaceMember) error
+	GetByID(ctx context.Context, id uint) (*model.Workspace, error)
+	// ListByUserID lists the workspaces the user is a member of with the given membership
+	// status.
+	ListByUserID(ctx context.Context, userID uint, status string) ([]model.Workspace, error)
+	// UpdateModelAllowlist stores the workspace's allowed models and base URLs, as JSON.
+	UpdateModelAllowlist(ctx context.Context, id uint, models, baseURLs string) error
+	GetMember(ctx context.Context, workspaceID, userID uint) (*model.WorkspaceMember, error)
+	ListMembers(ctx context.Context, workspaceID uint) ([]model.WorkspaceMember, error)
+	AddMember(ctx context.Context, member *model.WorkspaceMember) error
+	UpdateMember(ctx context.Context, member *model.WorkspaceMember) error
+	RemoveMember(ctx context.Context, workspaceID, userID uint) error
+}
+
+var (
+	_ ActivityRepository           = (*repository.ActivityRepository)(nil)
+	_ ApplicationRepository        = (*repository.ApplicationRepository)(nil)
+	_ JobPostingRepository         = (*repository.JobPostingRepository)(nil)
+	_ MessageEmbeddingRepository   = (*repository.MessageEmbeddingRepository)(nil)
+	_ MessageRepository            = (*repository.MessageRepository)(nil)
+	_ NotificationRepository       = (*repository.NotificationRepository)(nil)
+	_ PortfolioAnalysisRepository  = (*repository.PortfolioAnalysisRepository)(nil)
+	_ RAGChunkRepository           = (*repository.RAGChunkRepository)(nil)
+	_ RAGDocumentRepository        = (*repository.RAGDocumentRepository)(nil)
+	_ RAGIngestEmbeddingRepository = (*repository.RAGIngestEmbeddingRepository)(nil)
+	_ RAGMessageRepository         = (*repository.RAGMessageRepository)(nil)
+	_ RAGSessionRepository         = (*repository.RAGSessionRepository)(nil)
+	_ ResumeBulletRepository       = (*repository.ResumeBulletRepository)(nil)
+	_ ResumeProfileRepository      = (*repository.ResumeProfileRepository)(nil)
+	_ ResumeSchemaRepository       = (*repository.ResumeSchemaRepository)(nil)
+	_ ScheduledMessageRepository   = (*repository.ScheduledMessageRepository)(nil)
+	_ ScreeningReportRepository    = (*repository.ScreeningReportRepository)(nil)
+	_ SessionRepository            = (*repository.SessionRepository)(nil)
+	_ SessionShareRepository       = (*repository.SessionShareRepository)(nil)
+	_ UserRepository               = (*repository.UserRepository)(nil)
+	_ UserTokenRepository          = (*repository.UserTokenRepository)(nil)
+	_ VisionSampleRepository       = (*repository.VisionSampleRepository)(nil)
+	_ WorkspaceCandidateRepository = (*repository.WorkspaceCandidateRepository)(nil)
+	_ WorkspaceRepository          = (*repository.WorkspaceRepository)(nil)
+)
@@ -0,0 +1,279 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"gopherai-resume/internal/ai"
+	"gopherai-resume/internal/model"
+	"gopherai-resume/internal/pkg/apperr"
+	"gopherai-resume/internal/pkg/authz"
+)
+
+var (
+	ErrBulletNotFound  = apperr.NotFound(apperr.CodeBulletNotFound, "resume bullet not found")
+	ErrBulletClosed    = apperr.Conflict(apperr.CodeBulletConflict, "resume bullet is already accepted or skipped")
+	ErrBulletNoRewrite = apperr.BadRequest("no rewrite to accept")
+	ErrBulletOutdated  = apperr.Conflict(apperr.CodeBulletConflict, "bullet text no longer appears in the resume")
+)
+
+// Resume bullet statuses.
+const (
+	BulletFlagged  = "flagged"  // lacks measurable impact; conversation not finished
+	BulletProposed = "proposed" // the assistant has proposed a rewrite
+	BulletAccepted = "accepted"
+	BulletSkipped  = "skipped"
+)
+
+var (
+	bulletLinePattern = regexp.MustCompile(`^\s*(?:[-*•·▪‣–]|\d{1,2}[.)])\s+(.+)$`)
+	// metricPattern matches text that already states a measurable result.
+	metricPattern = regexp.MustCompile(`(?i)\d|percent|\b(?:doubled|tripled|quadrupled|halved)\b`)
+)
+
+const (
+	minBulletChars = 20
+	maxBulletTurns = 12
+)
+
+const bulletCoachPrompt = "You help a job seeker add measurable impact to one resume bullet point. " +
+	"Ask one short question at a time to find numbers: scale (users, revenue, team size, data volume), " +
+	"improvement (percent faster, cost saved, errors reduced) or frequency. " +
+	"Do not invent figures; if the user has no exact number, accept a reasonable estimate they give and mark it with \"~\". " +
+	"Respond with a JSON object: {\"reply\": string (your next question or comment), " +
+	"\"rewrite\": string (a rewritten bullet once you have at least one figure, otherwise empty)}. " +
+	"A rewrite starts with a strong verb, keeps the original facts and stays under 30 words."
+
+// ResumeBulletService finds resume bullets without measurable impact and rewrites them with
+// the user in a guided conversation.
+type ResumeBulletService struct {
+	repo        ResumeBulletRepository
+	rag         *RAGService
+	chat        *ChatService
+	completer   ai.Completer
+	chatConfig  ai.ChatConfig
+	modelPolicy ModelPolicy // nil allows every model
+}
+
+func NewResumeBulletService(
+	repo ResumeBulletRepository,
+	rag *RAGService,
+	chat *ChatService,
+	completer ai.Completer,
+	chatConfig ai.ChatConfig,
+	modelPolicy ModelPolicy,
+) *ResumeBulletService {
+	return &ResumeBulletService{repo: repo, rag: rag, chat: chat, completer: completer, chatConfig: chatConfig, modelPolicy: modelPolicy}
+}
+
+// ScanBulletsInput selects the resume to scan. Turns are mirrored to ChatSessionID when set.
+type ScanBulletsInput struct {
+	UserID        uint
+	DocumentID    uint
+	ChatSessionID uint
+}
+
+// BulletReply is one turn of the guided conversation.
+type BulletReply struct {
+	Bullet model.ResumeBullet `json:"bullet"`
+	Reply  string             `json:"reply"`
+}
+
+// Scan flags the resume's bullets that state no measurable result. Bullets already flagged keep
+// their conversation, open ones no longer in the resume are dropped, and bullets the user
+// accepted or skipped are not flagged again.
+func (s *ResumeBulletService) Scan(ctx context.Context, input ScanBulletsInput) ([]model.ResumeBullet, error) {
+	if input.UserID == 0 || input.DocumentID == 0 {
+		return nil, ErrInvalidInput
+	}
+	if input.ChatSessionID != 0 {
+		session, err := loadChatSession(ctx, s.chat.sessionRepo, input.ChatSessionID, input.UserID, authz.Write)
+		if err != nil {
+			return nil, err
+		}
+		if session == nil {
+			return nil, ErrSessionNotFound
+		}
+	}
+	text, err := s.rag.DocumentText(ctx, input.UserID, input.DocumentID)
+	if err != nil {
+		return nil, err
+	}
+
+	var bullets []model.ResumeBullet
+	for _, line := range FindUnquantifiedBullets(text) {
+		bullets = append(bullets, model.ResumeBullet{
+			UserID:        input.UserID,
+			DocumentID:    input.DocumentID,
+			ChatSessionID: input.ChatSessionID,
+			Original:      line,
+			Status:        BulletFlagged,
+		})
+	}
+	if err := s.repo.ReplaceOpen(ctx, input.DocumentID, []string{BulletAccepted, BulletSkipped}, bullets); err != nil {
+		return nil, err
+	}
+	return s.repo.ListByDocumentID(ctx, input.UserID, input.DocumentID)
+}
+
+// FindUnquantifiedBullets returns the text of bullet lines that contain no figures.
+func FindUnquantifiedBullets(text string) []string {
+	var out []string
+	seen := make(map[string]bool)
+	for _, line := range strings.Split(text, "\n") {
+		m := bulletLinePattern.FindStringSubmatch(line)
+		if m == nil {
+			continue
+		}
+		bullet := strings.TrimSpace(m[1])
+		if len([]rune(bullet)) < minBulletChars || metricPattern.MatchString(bullet) || seen[bullet] {
+			continue
+		}
+		seen[bullet] = true
+		out = append(out, bullet)
+	}
+	return out
+}
+
+func (s *ResumeBulletService) List(ctx context.Context, userID, documentID uint) ([]model.ResumeBullet, error) {
+	if userID == 0 || documentID == 0 {
+		return nil, ErrInvalidInput
+	}
+	return s.repo.ListByDocumentID(ctx, userID, documentID)
+}
+
+// Reply sends the user's answer (empty to start the conversation) and returns the assistant's
+// next question, plus a proposed rewrite once it has enough figures.
+func (s *ResumeBulletService) Reply(ctx context.Context, userID, bulletID uint, message string) (*BulletReply, error) {
+	bullet, err := s.getOpen(ctx, userID, bulletID)
+	if err != nil {
+		return nil, err
+	}
+	var transcript []ai.ChatMessage
+	if bullet.Transcript != "" {
+		if err := json.Unmarshal([]byte(bullet.Transcript), &transcript); err != nil {
+			return nil, fmt.Errorf("decode bullet transcript failed: %w", err)
+		}
+	}
+	message = strings.TrimSpace(message)
+	if message == "" && len(transcript) > 0 {
+		return nil, ErrMessageEmpty
+	}
+	if len(transcript) >= maxBulletTurns*2 {
+		return nil, ErrInvalidInput
+	}
+	if err := checkConfiguredModel(ctx, s.modelPolicy, userID, s.chatConfig); err != nil {
+		return nil, err
+	}
+	if message == "" {
+		message = "Help me quantify this bullet: " + bullet.Original
+	}
+	transcript = append(transcript, ai.ChatMessage{Role: "user", Content: message})
+
+	prompt := append([]ai.ChatMessage{
+		{Role: "system", Content: bulletCoachPrompt + "\n\nBullet: " + bullet.Original},
+	}, transcript...)
+	raw, err := s.completer.Complete(ctx, s.chatConfig, prompt)
+	if err != nil {
+		return nil, err
+	}
+	var parsed struct {
+		Reply   string `json:"reply"`
+		Rewrite string `json:"rewrite"`
+	}
+	if err := decodeLLMJSON(raw, &parsed); err != nil {
+		parsed.Reply = strings.TrimSpace(raw)
+	}
+	reply := strings.TrimSpace(parsed.Reply)
+	if rewrite := strings.TrimSpace(parsed.Rewrite); rewrite != "" {
+		bullet.Proposed = rewrite
+		bullet.Status = BulletProposed
+		reply = strings.TrimSpace(reply + "\n\nProposed rewrite: " + rewrite)
+	}
+	transcript = append(transcript, ai.ChatMessage{Role: "assistant", Content: reply})
+
+	encoded, err := json.Marshal(transcript)
+	if err != nil {
+		return nil, fmt.Errorf("encode bullet transcript failed: %w", err)
+	}
+	bullet.Transcript = string(encoded)
+	if err := s.repo.Update(ctx, bullet); err != nil {
+		return nil, err
+	}
+
+	if bullet.ChatSessionID != 0 {
+		now := time.Now()
+		_ = s.chat.appendMessages(ctx,
+			model.Message{SessionID: bullet.ChatSessionID, UserID: userID, Role: "user", Content: message, CreatedAt: now},
+			model.Message{SessionID: bullet.ChatSessionID, UserID: userID, Role: "assistant", Content: reply, CreatedAt: now.Add(time.Millisecond)},
+		)
+	}
+	return &BulletReply{Bullet: *bullet, Reply: reply}, nil
+}
+
+// Accept writes the rewrite (text, or the proposed rewrite when text is empty) into the
+// stored resume in place of the original bullet and re-indexes the document.
+func (s *ResumeBulletService) Accept(ctx context.Context, userID, bulletID uint, text string) (*model.ResumeBullet, error) {
+	bullet, err := s.getOpen(ctx, userID, bulletID)
+	if err != nil {
+		return nil, err
+	}
+	rewrite := strings.TrimSpace(text)
+	if rewrite == "" {
+		rewrite = bullet.Proposed
+	}
+	if rewrite == "" {
+		return nil, ErrBulletNoRewrite
+	}
+
+	content, err := s.rag.DocumentText(ctx, userID, bullet.DocumentID)
+	if err != nil {
+		return nil, err
+	}
+	if !strings.Contains(content, bullet.Original) {
+		return nil, ErrBulletOutdated
+	}
+	if _, err := s.rag.ReplaceContent(ctx, userID, bullet.DocumentID, strings.Replace(content, bullet.Original, rewrite, 1)); err != nil {
+		return nil, err
+	}
+
+	bullet.Proposed = rewrite
+	bullet.Status = BulletAccepted
+	if err := s.repo.Update(ctx, bullet); err != nil {
+		return nil, err
+	}
+	return bullet, nil
+}
+
+func (s *ResumeBulletService) Skip(ctx context.Context, userID, bulletID uint) (*model.ResumeBullet, error) {
+	bullet, err := s.getOpen(ctx, userID, bulletID)
+	if err != nil {
+		return nil, err
+	}
+	bullet.Status = BulletSkipped
+	if err := s.repo.Update(ctx, bullet); err != nil {
+		return nil, err
+	}
+	return bullet, nil
+}
+
+func (s *ResumeBulletService) getOpen(ctx context.Context, userID, bulletID uint) (*model.ResumeBullet, error) {
+	if userID == 0 || bulletID == 0 {
+		return nil, ErrInvalidInput
+	}
+	bullet, err := loadResumeBullet(ctx, s.repo, bulletID, userID, authz.Write)
+	if err != nil {
+		return nil, err
+	}
+	if bullet == nil {
+		return nil, ErrBulletNotFound
+	}
+	if bullet.Status == BulletAccepted || bullet.Status == BulletSkipped {
+		return nil, ErrBulletClosed
+	}
+	return bullet, nil
+}
@@ -0,0 +1,364 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopherai-resume/internal/ai"
+)
+
+// Consistency issue types.
+const (
+	IssueInvalidDateRange = "invalid_date_range"
+	IssueOverlappingDates = "overlapping_dates"
+	IssueTitleMismatch    = "title_mismatch"
+	IssueUnevidencedSkill = "unevidenced_skill"
+)
+
+// Consistency issue severities.
+const (
+	SeverityHigh   = "high"
+	SeverityMedium = "medium"
+	SeverityLow    = "low"
+)
+
+const (
+	// overlapGraceMonths is how much two roles may overlap before it is reported, to allow
+	// for notice periods and handovers.
+	overlapGraceMonths = 1
+	// longOverlapMonths is the overlap from which it is reported as medium severity.
+	longOverlapMonths   = 4
+	maxTitleIssues      = 10
+	maxConsistencyChars = 12000
+)
+
+var monthNames = map[string]int{
+	"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+	"jul": 7, "aug": 8, "sep": 9, "sept": 9, "oct": 10, "nov": 11, "dec": 12,
+}
+
+// dateToken matches "Jan 2020", "January 2020", "2020-01", "01/2020" or "2020".
+const (
+	monthToken = `\b(?:jan(?:uary)?|feb(?:ruary)?|mar(?:ch)?|apr(?:il)?|may|june?|july?|aug(?:ust)?|sep(?:t(?:ember)?)?|oct(?:ober)?|nov(?:ember)?|dec(?:ember)?)\b\.?`
+	dateToken  = `(?:` + monthToken + `\s+\d{4}|\b\d{4}[-/.]\d{1,2}\b|\b\d{1,2}[-/.]\d{4}\b|\b\d{4}\b)`
+)
+
+var (
+	dateRangePattern = regexp.MustCompile(`(?i)(` + dateToken + `)\s*(?:-|–|—|to|until)\s*(` + dateToken + `|present|current|now|today)`)
+	monthYearPattern = regexp.MustCompile(`^([A-Za-z]{3,9})\.?\s+(\d{4})$`)
+	yearMonthPattern = regexp.MustCompile(`^(\d{4})[-/.](\d{1,2})$`)
+	monthYearNumeric = regexp.MustCompile(`^(\d{1,2})[-/.](\d{4})$`)
+	yearOnlyPattern  = regexp.MustCompile(`^(\d{4})$`)
+)
+
+// ConsistencyIssue is one problem found in a resume. Evidence quotes the lines involved.
+type ConsistencyIssue struct {
+	Type     string   `json:"type"`
+	Severity string   `json:"severity"`
+	Message  string   `json:"message"`
+	Section  string   `json:"section,omitempty"`
+	Evidence []string `json:"evidence,omitempty"`
+}
+
+// ResumeConsistencyReport lists the issues, most severe first, with a count per severity.
+// TitleCheckSkipped is set when the LLM check for mismatched titles could not run, including
+// when the user's model policy does not allow the configured model.
+type ResumeConsistencyReport struct {
+	Issues            []ConsistencyIssue `json:"issues"`
+	Counts            map[string]int     `json:"counts"`
+	TitleCheckSkipped bool               `json:"title_check_skipped,omitempty"`
+}
+
+// ResumeConsistencyService cross-checks a resume against itself: date ranges, job titles
+// mentioned in different sections and skills never backed by experience.
+type ResumeConsistencyService struct {
+	rag         *RAGService
+	completer   ai.Completer
+	chatConfig  ai.ChatConfig
+	modelPolicy ModelPolicy // nil allows every model
+}
+
+func NewResumeConsistencyService(rag *RAGService, completer ai.Completer, chatConfig ai.ChatConfig, modelPolicy ModelPolicy) *ResumeConsistencyService {
+	return &ResumeConsistencyService{rag: rag, completer: completer, chatConfig: chatConfig, modelPolicy: modelPolicy}
+}
+
+// Check analyses one resume document. Date and skill checks are rule-based; titles are
+// compared by the LLM, and a failure there is reported through TitleCheckSkipped.
+func (s *ResumeConsistencyService) Check(ctx context.Context, userID, resumeDocumentID uint) (*ResumeConsistencyReport, error) {
+	if userID == 0 || resumeDocumentID == 0 {
+		return nil, ErrInvalidInput
+	}
+	text, err := s.rag.DocumentText(ctx, userID, resumeDocumentID)
+	if errors.Is(err, ErrRAGDocumentNotFound) {
+		return nil, ErrResumeDocumentNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	sections := SplitResumeSections(text)
+	if len(sections) == 0 {
+		return nil, ErrInvalidInput
+	}
+
+	report := &ResumeConsistencyReport{}
+	report.Issues = append(report.Issues, checkDateRanges(sections, time.Now())...)
+	report.Issues = append(report.Issues, checkSkillEvidence(sections)...)
+	titleIssues, err := s.checkTitles(ctx, userID, text)
+	if err != nil {
+		log.Printf("resume consistency title check for document %d failed: %v", resumeDocumentID, err)
+		report.TitleCheckSkipped = true
+	}
+	report.Issues = append(report.Issues, titleIssues...)
+
+	rank := map[string]int{SeverityHigh: 0, SeverityMedium: 1, SeverityLow: 2}
+	sort.SliceStable(report.Issues, func(i, j int) bool {
+		return rank[report.Issues[i].Severity] < rank[report.Issues[j].Severity]
+	})
+	report.Counts = map[string]int{SeverityHigh: 0, SeverityMedium: 0, SeverityLow: 0}
+	for _, issue := range report.Issues {
+		report.Counts[issue.Severity]++
+	}
+	if report.Issues == nil {
+		report.Issues = []ConsistencyIssue{}
+	}
+	return report, nil
+}
+
+// datedEntry is a resume line carrying a date range, as months since year 0. A bare year
+// could mean any of its months, so surely is the part of the range that holds however
+// the years are read: from the last month of a start year to the first of an end year.
+type datedEntry struct {
+	section string
+	line    string
+	start   int
+	end     int
+	surely  [2]int
+}
+
+// checkDateRanges reports ranges that end before they start or start in the future, and
+// roles in experience sections that overlap by more than overlapGraceMonths.
+func checkDateRanges(sections []ResumeSection, now time.Time) []ConsistencyIssue {
+	current := now.Year()*12 + int(now.Month()) - 1
+	var issues []ConsistencyIssue
+	var roles []datedEntry
+	for _, section := range sections {
+		experience := isExperienceSection(section.Title)
+		for _, line := range strings.Split(section.Text, "\n") {
+			line = strings.TrimSpace(line)
+			m := dateRangePattern.FindStringSubmatch(line)
+			if m == nil {
+				continue
+			}
+			start, ok := parseResumeDate(m[1], false)
+			if !ok {
+				continue
+			}
+			end, ok := current, true
+			if !isOngoing(m[2]) {
+				end, ok = parseResumeDate(m[2], true)
+			}
+			if !ok {
+				continue
+			}
+			switch {
+			case end < start:
+				issues = append(issues, ConsistencyIssue{
+					Type:     IssueInvalidDateRange,
+					Severity: SeverityHigh,
+					Message:  fmt.Sprintf("%q ends before it starts.", m[0]),
+					Section:  section.Title,
+					Evidence: []string{line},
+				})
+				continue
+			case start > current:
+				issues = append(issues, ConsistencyIssue{
+					Type:     IssueInvalidDateRange,
+					Severity: SeverityHigh,
+					Message:  fmt.Sprintf("%q starts in the future.", m[0]),
+					Section:  section.Title,
+					Evidence: []string{line},
+				})
+				continue
+			}
+			if experience {
+				entry := datedEntry{section: section.Title, line: line, start: start, end: end, surely: [2]int{start, end}}
+				if yearOnlyPattern.MatchString(strings.TrimSpace(m[1])) {
+					entry.surely[0] = start + 11
+				}
+				if yearOnlyPattern.MatchString(strings.TrimSpace(m[2])) {
+					entry.surely[1] = end - 11
+				}
+				roles = append(roles, entry)
+			}
+		}
+	}
+
+	for i := range roles {
+		for j := i + 1; j < len(roles); j++ {
+			a, b := roles[i], roles[j]
+			// Only the overlap that holds however bare years are read is reported, so
+			// "2019 - 2020" and "2020 - 2021" do not count as a year of overlap.
+			overlap := min(a.surely[1], b.surely[1]) - max(a.surely[0], b.surely[0])
+			if overlap <= overlapGraceMonths {
+				continue
+			}
+			severity := SeverityLow
+			if overlap >= longOverlapMonths {
+				severity = SeverityMedium
+			}
+			issues = append(issues, ConsistencyIssue{
+				Type:     IssueOverlappingDates,
+				Severity: severity,
+				Message:  fmt.Sprintf("Two roles overlap by %d months; mark part-time or concurrent work explicitly if intended.", overlap),
+				Section:  a.section,
+				Evidence: []string{a.line, b.line},
+			})
+		}
+	}
+	return issues
+}
+
+// parseResumeDate converts a date token to months since year 0. A bare year is taken as
+// January, or December when it ends a range.
+func parseResumeDate(token string, isEnd bool) (int, bool) {
+	token = strings.TrimSpace(token)
+	year, month := 0, 0
+	if m := monthYearPattern.FindStringSubmatch(token); m != nil {
+		name := strings.ToLower(m[1])
+		month = monthNames[name]
+		if month == 0 && len(name) > 3 {
+			month = monthNames[name[:3]]
+		}
+		year, _ = strconv.Atoi(m[2])
+	} else if m := yearMonthPattern.FindStringSubmatch(token); m != nil {
+		year, _ = strconv.Atoi(m[1])
+		month, _ = strconv.Atoi(m[2])
+	} else if m := monthYearNumeric.FindStringSubmatch(token); m != nil {
+		month, _ = strconv.Atoi(m[1])
+		year, _ = strconv.Atoi(m[2])
+	} else if m := yearOnlyPattern.FindStringSubmatch(token); m != nil {
+		year, _ = strconv.Atoi(m[1])
+		month = 1
+		if isEnd {
+			month = 12
+		}
+	}
+	if year < 1950 || year > 2100 || month < 1 || month > 12 {
+		return 0, false
+	}
+	return year*12 + month - 1, true
+}
+
+func isOngoing(token string) bool {
+	switch strings.ToLower(strings.TrimSpace(token)) {
+	case "present", "current", "now", "today":
+		return true
+	}
+	return false
+}
+
+func isExperienceSection(title string) bool {
+	t := strings.ToLower(title)
+	return strings.Contains(t, "experience") || strings.Contains(t, "employment") || strings.Contains(t, "work history")
+}
+
+// checkSkillEvidence reports skills listed in a skills section that no experience or
+// project section mentions.
+func checkSkillEvidence(sections []ResumeSection) []ConsistencyIssue {
+	var claimed []string
+	var skillSection string
+	var evidence strings.Builder
+	for _, section := range sections {
+		t := strings.ToLower(section.Title)
+		switch {
+		case strings.Contains(t, "skill"):
+			claimed = append(claimed, findSkills(section.Text)...)
+			if skillSection == "" {
+				skillSection = section.Title
+			}
+		case isExperienceSection(section.Title) || strings.Contains(t, "project"):
+			evidence.WriteString(section.Text)
+			evidence.WriteString("\n")
+		}
+	}
+	if len(claimed) == 0 || evidence.Len() == 0 {
+		return nil
+	}
+
+	evidenced := make(map[string]bool)
+	for _, skill := range findSkills(evidence.String()) {
+		evidenced[skill] = true
+	}
+	var issues []ConsistencyIssue
+	seen := make(map[string]bool)
+	for _, skill := range claimed {
+		if evidenced[skill] || seen[skill] {
+			continue
+		}
+		seen[skill] = true
+		issues = append(issues, ConsistencyIssue{
+			Type:     IssueUnevidencedSkill,
+			Severity: SeverityLow,
+			Message:  fmt.Sprintf("%s is listed as a skill but no experience or project mentions it.", skill),
+			Section:  skillSection,
+		})
+	}
+	return issues
+}
+
+// checkTitles asks the LLM for job titles, employers or seniority that disagree between
+// sections, e.g. a summary claiming "Senior Engineer" for a role listed as "Engineer".
+func (s *ResumeConsistencyService) checkTitles(ctx context.Context, userID uint, text string) ([]ConsistencyIssue, error) {
+	if err := checkConfiguredModel(ctx, s.modelPolicy, userID, s.chatConfig); err != nil {
+		return nil, err
+	}
+	raw, err := s.completer.Complete(ctx, s.chatConfig, []ai.ChatMessage{
+		{Role: "system", Content: fmt.Sprintf("You check a resume for internal inconsistencies in job titles, seniority, "+
+			"employer names and years of experience between its sections (summary, experience, projects, cover text). "+
+			"Only report real contradictions between two places in the resume, not style or missing information. "+
+			"Respond with a JSON object: {\"issues\": [{\"message\": string, \"evidence\": [string], \"severity\": \"high\"|\"medium\"|\"low\"}]}, "+
+			"quoting the conflicting lines verbatim in evidence, at most %d issues. Return an empty list if there are none.", maxTitleIssues)},
+		{Role: "user", Content: truncateRunes(text, maxConsistencyChars)},
+	})
+	if err != nil {
+		return nil, err
+	}
+	var parsed struct {
+		Issues []struct {
+			Message  string   `json:"message"`
+			Evidence []string `json:"evidence"`
+			Severity string   `json:"severity"`
+		} `json:"issues"`
+	}
+	if err := decodeLLMJSON(raw, &parsed); err != nil {
+		return nil, err
+	}
+	var issues []ConsistencyIssue
+	for _, item := range parsed.Issues {
+		message := strings.TrimSpace(item.Message)
+		if message == "" {
+			continue
+		}
+		severity := strings.ToLower(strings.TrimSpace(item.Severity))
+		if severity != SeverityHigh && severity != SeverityLow {
+			severity = SeverityMedium
+		}
+		issues = append(issues, ConsistencyIssue{
+			Type:     IssueTitleMismatch,
+			Severity: severity,
+			Message:  message,
+			Evidence: item.Evidence,
+		})
+		if len(issues) == maxTitleIssues {
+			break
+		}
+	}
+	return issues, nil
+}
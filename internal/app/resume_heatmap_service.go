@@ -0,0 +1,567 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"gopherai-resume/internal/ai"
+	"gopherai-resume/internal/pkg/authz"
+)
+
+const (
+	maxHeatmapRequirements = 25
+	maxHeatmapPhrases      = 80
+	maxHeatmapPhraseChars  = 400
+	minRequirementChars    = 3
+	compareTieMargin       = 0.01 // similarity gap below which a requirement is a tie
+)
+
+// resumeHeadingWords are section titles recognised even when not written in capitals.
+var resumeHeadingWords = map[string]bool{
+	"summary": true, "profile": true, "objective": true, "about": true, "about me": true,
+	"experience": true, "work experience": true, "professional experience": true, "employment": true,
+	"employment history": true, "education": true, "skills": true, "technical skills": true,
+	"projects": true, "certifications": true, "awards": true, "publications": true,
+	"languages": true, "volunteering": true, "interests": true, "achievements": true,
+}
+
+var markdownHeadingPattern = regexp.MustCompile(`^#{1,6}\s+(.+)$`)
+
+// ResumeSection is a titled part of a resume with the phrases (bullets or sentences) in it.
+type ResumeSection struct {
+	Title   string   `json:"title"`
+	Text    string   `json:"text"`
+	Phrases []string `json:"phrases"`
+}
+
+// HeatmapPhrase scores one resume phrase against every requirement.
+type HeatmapPhrase struct {
+	Section int       `json:"section"` // index into Sections
+	Text    string    `json:"text"`
+	Scores  []float64 `json:"scores"`
+	Best    int       `json:"best_requirement"` // index into Requirements, -1 if none
+}
+
+// RequirementCoverage is the best evidence for one requirement.
+type RequirementCoverage struct {
+	Requirement string  `json:"requirement"`
+	BestSection int     `json:"best_section"`
+	BestPhrase  int     `json:"best_phrase"` // index into Phrases, -1 if none
+	Score       float64 `json:"score"`
+}
+
+// ResumeHeatmap is the similarity data behind a resume-vs-requirements heatmap. Matrix rows
+// are sections and columns are requirements. Values are cosine similarities; Normalized
+// rescales them to 0-1 across the whole matrix for colouring.
+type ResumeHeatmap struct {
+	Requirements []string              `json:"requirements"`
+	Sections     []ResumeSection       `json:"sections"`
+	Matrix       [][]float64           `json:"matrix"`
+	Normalized   [][]float64           `json:"normalized"`
+	Phrases      []HeatmapPhrase       `json:"phrases"`
+	Coverage     []RequirementCoverage `json:"coverage"`
+}
+
+// CompareResumesInput names the resume versions to compare and the target job, given the
+// same way as in ResumeHeatmapInput.
+type CompareResumesInput struct {
+	UserID            uint
+	ResumeDocumentIDs []uint
+	Requirements      []string
+	JobDescription    string
+	ApplicationID     uint
+}
+
+// VersionRequirementScore is how well one resume version covers one requirement. Evidence
+// is the version's closest phrase.
+type VersionRequirementScore struct {
+	ResumeDocumentID uint    `json:"resume_document_id"`
+	Score            float64 `json:"score"`
+	Evidence         string  `json:"evidence,omitempty"`
+}
+
+// RequirementWinner compares the versions on one requirement. Winner is an index into
+// Scores, or -1 when the top two are within compareTieMargin.
+type RequirementWinner struct {
+	Requirement      string                    `json:"requirement"`
+	Scores           []VersionRequirementScore `json:"scores"`
+	Winner           int                       `json:"winner"`
+	WinnerDocumentID uint                      `json:"winner_document_id,omitempty"`
+	Margin           float64                   `json:"margin"`
+}
+
+// ResumeVersionScore summarises one version: its mean requirement score and how many
+// requirements it won outright.
+type ResumeVersionScore struct {
+	ResumeDocumentID uint    `json:"resume_document_id"`
+	Name             string  `json:"name"`
+	Score            float64 `json:"score"`
+	Wins             int     `json:"wins"`
+}
+
+// ResumeComparison is the result of comparing resume versions against one job.
+type ResumeComparison struct {
+	Versions              []ResumeVersionScore `json:"versions"`
+	Requirements          []RequirementWinner  `json:"requirements"`
+	RecommendedDocumentID uint                 `json:"recommended_document_id"`
+}
+
+// ResumeHeatmapInput names a resume document and the target job. Requirements wins over
+// JobDescription, which wins over ApplicationID.
+type ResumeHeatmapInput struct {
+	UserID           uint
+	ResumeDocumentID uint
+	Requirements     []string
+	JobDescription   string
+	ApplicationID    uint
+}
+
+// ResumeHeatmapService maps resume sections to job requirements by embedding similarity.
+type ResumeHeatmapService struct {
+	rag         *RAGService
+	appRepo     ApplicationRepository
+	completer   ai.Completer
+	chatConfig  ai.ChatConfig
+	modelPolicy ModelPolicy // nil allows every model
+}
+
+func NewResumeHeatmapService(
+	rag *RAGService,
+	appRepo ApplicationRepository,
+	completer ai.Completer,
+	chatConfig ai.ChatConfig,
+	modelPolicy ModelPolicy,
+) *ResumeHeatmapService {
+	return &ResumeHeatmapService{rag: rag, appRepo: appRepo, completer: completer, chatConfig: chatConfig, modelPolicy: modelPolicy}
+}
+
+func (s *ResumeHeatmapService) Build(ctx context.Context, input ResumeHeatmapInput) (*ResumeHeatmap, error) {
+	if input.UserID == 0 {
+		return nil, ErrInvalidInput
+	}
+	sections, err := s.resumeSections(ctx, input.UserID, input.ResumeDocumentID)
+	if err != nil {
+		return nil, err
+	}
+	requirements, err := s.requirements(ctx, input.UserID, input.Requirements, input.JobDescription, input.ApplicationID)
+	if err != nil {
+		return nil, err
+	}
+	reqVecs, err := s.rag.embedTexts(ctx, requirements)
+	if err != nil {
+		return nil, err
+	}
+	embedded, err := s.embedResume(ctx, sections)
+	if err != nil {
+		return nil, err
+	}
+
+	heatmap := &ResumeHeatmap{
+		Requirements: requirements,
+		Sections:     sections,
+		Matrix:       similarityMatrix(embedded.sectionVecs, reqVecs),
+	}
+	heatmap.Normalized = normalizeMatrix(heatmap.Matrix)
+
+	phraseScores := similarityMatrix(embedded.phraseVecs, reqVecs)
+	heatmap.Phrases = make([]HeatmapPhrase, len(embedded.phrases))
+	for i, p := range embedded.phrases {
+		heatmap.Phrases[i] = HeatmapPhrase{Section: p.section, Text: p.text, Scores: phraseScores[i], Best: argmax(phraseScores[i])}
+	}
+	heatmap.Coverage = requirementCoverage(requirements, heatmap.Matrix, phraseScores)
+	return heatmap, nil
+}
+
+// CompareVersions scores several resume versions against the same requirements and picks a
+// winner per requirement and overall.
+func (s *ResumeHeatmapService) CompareVersions(ctx context.Context, input CompareResumesInput) (*ResumeComparison, error) {
+	if input.UserID == 0 {
+		return nil, ErrInvalidInput
+	}
+	docIDs := uniqueIDs(input.ResumeDocumentIDs)
+	if len(docIDs) < compareMinDocuments || len(docIDs) > compareMaxDocuments {
+		return nil, ErrRAGCompareDocumentCount
+	}
+	// Load every version before spending an LLM call on the requirements.
+	versions := make([]ResumeVersionScore, len(docIDs))
+	sections := make([][]ResumeSection, len(docIDs))
+	for i, id := range docIDs {
+		doc, err := loadRAGDocument(ctx, s.rag.docRepo, id, input.UserID, authz.Write)
+		if err != nil {
+			return nil, err
+		}
+		if doc == nil {
+			return nil, ErrResumeDocumentNotFound
+		}
+		if sections[i], err = s.resumeSections(ctx, input.UserID, id); err != nil {
+			return nil, err
+		}
+		versions[i] = ResumeVersionScore{ResumeDocumentID: id, Name: doc.Name}
+	}
+	requirements, err := s.requirements(ctx, input.UserID, input.Requirements, input.JobDescription, input.ApplicationID)
+	if err != nil {
+		return nil, err
+	}
+	reqVecs, err := s.rag.embedTexts(ctx, requirements)
+	if err != nil {
+		return nil, err
+	}
+
+	coverage := make([][]RequirementCoverage, len(docIDs))
+	for i := range docIDs {
+		embedded, err := s.embedResume(ctx, sections[i])
+		if err != nil {
+			return nil, err
+		}
+		coverage[i] = requirementCoverage(
+			requirements,
+			similarityMatrix(embedded.sectionVecs, reqVecs),
+			similarityMatrix(embedded.phraseVecs, reqVecs),
+		)
+		total := 0.0
+		for _, cov := range coverage[i] {
+			total += cov.Score
+		}
+		versions[i].Score = math.Round(total/float64(len(requirements))*1000) / 1000
+	}
+
+	result := &ResumeComparison{Requirements: make([]RequirementWinner, len(requirements))}
+	for j, req := range requirements {
+		winner := RequirementWinner{Requirement: req, Scores: make([]VersionRequirementScore, len(docIDs)), Winner: -1}
+		best, second := math.Inf(-1), math.Inf(-1)
+		for i := range docIDs {
+			cov := coverage[i][j]
+			score := VersionRequirementScore{ResumeDocumentID: docIDs[i], Score: cov.Score}
+			if cov.BestPhrase >= 0 {
+				score.Evidence = s.phraseText(sections[i], cov.BestPhrase)
+			}
+			winner.Scores[i] = score
+			switch {
+			case cov.Score > best:
+				second, best, winner.Winner = best, cov.Score, i
+			case cov.Score > second:
+				second = cov.Score
+			}
+		}
+		winner.Margin = math.Round((best-second)*1000) / 1000
+		if winner.Margin < compareTieMargin {
+			winner.Winner, winner.WinnerDocumentID = -1, 0
+		} else {
+			winner.WinnerDocumentID = docIDs[winner.Winner]
+			versions[winner.Winner].Wins++
+		}
+		result.Requirements[j] = winner
+	}
+
+	// Requirement wins decide; the mean score breaks ties.
+	bestVersion := 0
+	for i, v := range versions {
+		top := versions[bestVersion]
+		if v.Wins > top.Wins || (v.Wins == top.Wins && v.Score > top.Score) {
+			bestVersion = i
+		}
+	}
+	result.Versions = versions
+	result.RecommendedDocumentID = versions[bestVersion].ResumeDocumentID
+	return result, nil
+}
+
+// resumeSections loads a resume document and splits it into sections.
+func (s *ResumeHeatmapService) resumeSections(ctx context.Context, userID, documentID uint) ([]ResumeSection, error) {
+	if documentID == 0 {
+		return nil, ErrInvalidInput
+	}
+	text, err := s.rag.DocumentText(ctx, userID, documentID)
+	if errors.Is(err, ErrRAGDocumentNotFound) {
+		return nil, ErrResumeDocumentNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	sections := SplitResumeSections(text)
+	if len(sections) == 0 {
+		return nil, ErrInvalidInput
+	}
+	return sections, nil
+}
+
+type phraseRef struct {
+	section int
+	text    string
+}
+
+type embeddedResume struct {
+	sectionVecs [][]float32
+	phrases     []phraseRef
+	phraseVecs  [][]float32
+}
+
+// embedResume embeds sections and their phrases (up to maxHeatmapPhrases) in one call.
+func (s *ResumeHeatmapService) embedResume(ctx context.Context, sections []ResumeSection) (*embeddedResume, error) {
+	texts := make([]string, 0, len(sections)+maxHeatmapPhrases)
+	for _, sec := range sections {
+		texts = append(texts, truncateRunes(sec.Title+"\n"+sec.Text, maxResumeChars))
+	}
+	var phrases []phraseRef
+	for i, sec := range sections {
+		for _, p := range sec.Phrases {
+			if len(phrases) < maxHeatmapPhrases {
+				phrases = append(phrases, phraseRef{section: i, text: p})
+				texts = append(texts, p)
+			}
+		}
+	}
+	vectors, err := s.rag.embedTexts(ctx, texts)
+	if err != nil {
+		return nil, err
+	}
+	return &embeddedResume{
+		sectionVecs: vectors[:len(sections)],
+		phrases:     phrases,
+		phraseVecs:  vectors[len(sections):],
+	}, nil
+}
+
+// phraseText returns the n-th phrase in the order embedResume numbers them.
+func (s *ResumeHeatmapService) phraseText(sections []ResumeSection, n int) string {
+	for _, sec := range sections {
+		if n < len(sec.Phrases) {
+			return sec.Phrases[n]
+		}
+		n -= len(sec.Phrases)
+	}
+	return ""
+}
+
+// requirementCoverage finds, per requirement, the best section and phrase. The score is the
+// higher of the two similarities.
+func requirementCoverage(requirements []string, sectionScores, phraseScores [][]float64) []RequirementCoverage {
+	coverage := make([]RequirementCoverage, len(requirements))
+	for j, req := range requirements {
+		cov := RequirementCoverage{Requirement: req, BestSection: -1, BestPhrase: -1, Score: math.Inf(-1)}
+		for i := range sectionScores {
+			if v := sectionScores[i][j]; v > cov.Score {
+				cov.Score, cov.BestSection = v, i
+			}
+		}
+		best := math.Inf(-1)
+		for i := range phraseScores {
+			if v := phraseScores[i][j]; v > best {
+				best, cov.BestPhrase = v, i
+			}
+		}
+		if cov.BestPhrase >= 0 && best > cov.Score {
+			cov.Score = best
+		}
+		coverage[j] = cov
+	}
+	return coverage
+}
+
+// requirements returns the explicit requirements, or extracts them from the job description.
+func (s *ResumeHeatmapService) requirements(ctx context.Context, userID uint, explicit []string, jobDescription string, applicationID uint) ([]string, error) {
+	if len(explicit) > 0 {
+		reqs := cleanRequirements(explicit)
+		if len(reqs) == 0 || len(reqs) > maxHeatmapRequirements {
+			return nil, ErrInvalidInput
+		}
+		return reqs, nil
+	}
+	jd := strings.TrimSpace(jobDescription)
+	if jd == "" && applicationID != 0 {
+		application, err := loadApplication(ctx, s.appRepo, applicationID, userID, authz.Read)
+		if err != nil {
+			return nil, err
+		}
+		if application == nil {
+			return nil, ErrApplicationNotFound
+		}
+		jd = strings.TrimSpace(application.JobDescription)
+	}
+	if jd == "" {
+		return nil, ErrInvalidInput
+	}
+	if err := checkConfiguredModel(ctx, s.modelPolicy, userID, s.chatConfig); err != nil {
+		return nil, err
+	}
+	reqs, err := extractRequirements(ctx, s.completer, s.chatConfig, jd)
+	if err != nil {
+		return nil, err
+	}
+	if len(reqs) == 0 {
+		return nil, ErrInvalidInput
+	}
+	return reqs, nil
+}
+
+// extractRequirements asks the LLM for the distinct requirements of a job description. When
+// the reply cannot be parsed, the description's bullet lines are used instead.
+func extractRequirements(ctx context.Context, completer ai.Completer, cfg ai.ChatConfig, jd string) ([]string, error) {
+	raw, err := completer.Complete(ctx, cfg, []ai.ChatMessage{
+		{Role: "system", Content: fmt.Sprintf("List the distinct requirements of this job description (skills, experience, "+
+			"qualifications, responsibilities the candidate must be able to do), most important first, at most %d. "+
+			"Each is a short phrase of 2-8 words. Respond with a JSON object: {\"requirements\": [string]}.", maxHeatmapRequirements)},
+		{Role: "user", Content: truncateRunes(jd, maxJobPostingChars)},
+	})
+	if err != nil {
+		return nil, err
+	}
+	var parsed struct {
+		Requirements []string `json:"requirements"`
+	}
+	if err := decodeLLMJSON(raw, &parsed); err == nil && len(parsed.Requirements) > 0 {
+		reqs := cleanRequirements(parsed.Requirements)
+		if len(reqs) > maxHeatmapRequirements {
+			reqs = reqs[:maxHeatmapRequirements]
+		}
+		return reqs, nil
+	}
+	var bullets []string
+	for _, line := range strings.Split(jd, "\n") {
+		if m := bulletLinePattern.FindStringSubmatch(line); m != nil {
+			bullets = append(bullets, m[1])
+		}
+	}
+	reqs := cleanRequirements(bullets)
+	if len(reqs) > maxHeatmapRequirements {
+		reqs = reqs[:maxHeatmapRequirements]
+	}
+	return reqs, nil
+}
+
+func cleanRequirements(raw []string) []string {
+	seen := make(map[string]bool, len(raw))
+	var out []string
+	for _, r := range raw {
+		r = truncateRunes(strings.Join(strings.Fields(r), " "), 200)
+		key := strings.ToLower(r)
+		if len([]rune(r)) < minRequirementChars || seen[key] {
+			continue
+		}
+		seen[key] = true
+		out = append(out, r)
+	}
+	return out
+}
+
+// SplitResumeSections splits resume text at heading lines: Markdown headings, short lines
+// in capitals or ending with a colon, and common section names. Text before the first
+// heading becomes a "Header" section. Phrases are bullet lines, or sentences when a section
+// has no bullets.
+func SplitResumeSections(text string) []ResumeSection {
+	var sections []ResumeSection
+	current := ResumeSection{Title: "Header"}
+	var body []string
+	flush := func() {
+		current.Text = strings.TrimSpace(strings.Join(body, "\n"))
+		if current.Text != "" {
+			current.Phrases = sectionPhrases(body)
+			sections = append(sections, current)
+		}
+		body = nil
+	}
+	for _, line := range strings.Split(text, "\n") {
+		if title, ok := resumeHeading(line); ok {
+			flush()
+			current = ResumeSection{Title: title}
+			continue
+		}
+		body = append(body, line)
+	}
+	flush()
+	return sections
+}
+
+func resumeHeading(line string) (string, bool) {
+	trimmed := strings.TrimSpace(line)
+	if trimmed == "" || bulletLinePattern.MatchString(line) {
+		return "", false
+	}
+	if m := markdownHeadingPattern.FindStringSubmatch(trimmed); m != nil {
+		return strings.TrimSpace(m[1]), true
+	}
+	if len([]rune(trimmed)) > 40 {
+		return "", false
+	}
+	title := strings.TrimSpace(strings.TrimSuffix(trimmed, ":"))
+	if resumeHeadingWords[strings.ToLower(title)] {
+		return title, true
+	}
+	if strings.HasSuffix(trimmed, ":") && len(strings.Fields(title)) <= 4 {
+		return title, true
+	}
+	hasLetter := false
+	for _, r := range title {
+		if unicode.IsLetter(r) {
+			hasLetter = true
+			if !unicode.IsUpper(r) {
+				return "", false
+			}
+		}
+	}
+	return title, hasLetter && len(strings.Fields(title)) <= 4
+}
+
+func sectionPhrases(lines []string) []string {
+	var bullets, sentences []string
+	for _, line := range lines {
+		if m := bulletLinePattern.FindStringSubmatch(line); m != nil {
+			bullets = append(bullets, truncateRunes(strings.TrimSpace(m[1]), maxHeatmapPhraseChars))
+		}
+	}
+	if len(bullets) > 0 {
+		return bullets
+	}
+	joined := strings.Join(strings.Fields(strings.Join(lines, " ")), " ")
+	for _, sentence := range strings.SplitAfter(joined, ". ") {
+		if sentence = strings.TrimSpace(sentence); len([]rune(sentence)) >= 12 {
+			sentences = append(sentences, truncateRunes(sentence, maxHeatmapPhraseChars))
+		}
+	}
+	return sentences
+}
+
+func similarityMatrix(rows, cols [][]float32) [][]float64 {
+	matrix := make([][]float64, len(rows))
+	for i, r := range rows {
+		matrix[i] = make([]float64, len(cols))
+		for j, c := range cols {
+			matrix[i][j] = math.Round(float64(cosineSimilarity(r, c))*1000) / 1000
+		}
+	}
+	return matrix
+}
+
+func normalizeMatrix(matrix [][]float64) [][]float64 {
+	lo, hi := math.Inf(1), math.Inf(-1)
+	for _, row := range matrix {
+		for _, v := range row {
+			lo, hi = math.Min(lo, v), math.Max(hi, v)
+		}
+	}
+	out := make([][]float64, len(matrix))
+	for i, row := range matrix {
+		out[i] = make([]float64, len(row))
+		for j, v := range row {
+			if hi > lo {
+				out[i][j] = math.Round((v-lo)/(hi-lo)*1000) / 1000
+			}
+		}
+	}
+	return out
+}
+
+func argmax(values []float64) int {
+	best := -1
+	for i, v := range values {
+		if best < 0 || v > values[best] {
+			best = i
+		}
+	}
+	return best
+}
@@ -0,0 +1,254 @@
+package app
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"gopherai-resume/internal/ai"
+	"gopherai-resume/internal/model"
+	"gopherai-resume/internal/pkg/apperr"
+	"gopherai-resume/internal/pkg/jsonresume"
+)
+
+const (
+	ResumeProfileSourceImported = "imported"
+	ResumeProfileSourceParsed   = "parsed"
+)
+
+var (
+	ErrInvalidJSONResume = apperr.BadRequest("invalid json resume")
+	ErrResumeUnparseable = apperr.New(http.StatusBadGateway, apperr.CodeInternalServer, "could not structure the resume text")
+)
+
+// ImportJSONResumeInput is a JSON Resume file to store as a resume document.
+type ImportJSONResumeInput struct {
+	UserID    uint
+	SessionID uint // 0 = no session
+	Name      string
+	Data      []byte
+}
+
+// ImportJSONResumeResult is the ingested document and the resume as imported. Custom
+// fields that fail the active schema are dropped and reported in Warnings.
+type ImportJSONResumeResult struct {
+	IngestResult
+	Resume        *jsonresume.Resume `json:"resume"`
+	SchemaVersion int                `json:"schema_version"`
+	Warnings      []string           `json:"warnings,omitempty"`
+}
+
+// ExportedResume is a resume in the JSON Resume schema and the custom field schema version
+// it follows.
+type ExportedResume struct {
+	Resume        *jsonresume.Resume
+	SchemaVersion int
+}
+
+// ResumeProfileService converts between resume documents and the JSON Resume schema.
+// Imported files are rendered to text and ingested like any other resume; the structured
+// form is kept in a ResumeProfile so exports round-trip until the text is edited.
+type ResumeProfileService struct {
+	repo        ResumeProfileRepository
+	schemas     *ResumeSchemaService
+	rag         *RAGService
+	completer   ai.Completer
+	chatConfig  ai.ChatConfig
+	modelPolicy ModelPolicy // nil allows every model
+}
+
+func NewResumeProfileService(
+	repo ResumeProfileRepository,
+	schemas *ResumeSchemaService,
+	rag *RAGService,
+	completer ai.Completer,
+	chatConfig ai.ChatConfig,
+	modelPolicy ModelPolicy,
+) *ResumeProfileService {
+	return &ResumeProfileService{repo: repo, schemas: schemas, rag: rag, completer: completer, chatConfig: chatConfig, modelPolicy: modelPolicy}
+}
+
+func (s *ResumeProfileService) Import(ctx context.Context, input ImportJSONResumeInput) (*ImportJSONResumeResult, error) {
+	if input.UserID == 0 {
+		return nil, ErrInvalidInput
+	}
+	resume, err := jsonresume.Parse(input.Data)
+	if err != nil {
+		return nil, ErrInvalidJSONResume.Withf("%v", err)
+	}
+	schema, err := s.schemas.Active(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var warnings []string
+	resume.Custom, warnings = applyResumeSchema(schema, resume.Custom)
+	name := strings.TrimSpace(input.Name)
+	if name == "" {
+		name = strings.TrimSpace(resume.Basics.Name + " resume")
+	}
+	result, err := s.rag.Ingest(ctx, IngestInput{
+		UserID:    input.UserID,
+		SessionID: input.SessionID,
+		Name:      name,
+		Content:   resume.Markdown(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := s.saveProfile(ctx, nil, input.UserID, result.Document.ID, ResumeProfileSourceImported, schemaVersion(schema), resume); err != nil {
+		return nil, err
+	}
+	return &ImportJSONResumeResult{
+		IngestResult:  *result,
+		Resume:        resume,
+		SchemaVersion: schemaVersion(schema),
+		Warnings:      warnings,
+	}, nil
+}
+
+// Export returns a resume document in the JSON Resume schema. The stored profile is used
+// while the document text is unchanged and, for parsed profiles, the active custom field
+// schema is the one it was parsed with; otherwise the text is structured by the LLM and the
+// profile is refreshed.
+func (s *ResumeProfileService) Export(ctx context.Context, userID, documentID uint) (*ExportedResume, error) {
+	text, err := s.rag.DocumentText(ctx, userID, documentID)
+	if errors.Is(err, ErrRAGDocumentNotFound) {
+		return nil, ErrResumeDocumentNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	profile, err := s.repo.GetByDocumentIDAndUserID(ctx, documentID, userID)
+	if err != nil {
+		return nil, err
+	}
+	schema, err := s.schemas.Active(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if profile != nil && profile.ContentHash == contentHash(text) &&
+		(profile.Source == ResumeProfileSourceImported || profile.SchemaVersion == schemaVersion(schema)) {
+		var resume jsonresume.Resume
+		if err := json.Unmarshal([]byte(profile.Data), &resume); err == nil && !resume.IsEmpty() {
+			resume.Schema = jsonresume.SchemaURL
+			return &ExportedResume{Resume: &resume, SchemaVersion: profile.SchemaVersion}, nil
+		}
+	}
+
+	if err := checkConfiguredModel(ctx, s.modelPolicy, userID, s.chatConfig); err != nil {
+		return nil, err
+	}
+	resume, err := s.parse(ctx, text, schema)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.saveProfile(ctx, profile, userID, documentID, ResumeProfileSourceParsed, schemaVersion(schema), resume); err != nil {
+		return nil, err
+	}
+	resume.Schema = jsonresume.SchemaURL
+	return &ExportedResume{Resume: resume, SchemaVersion: schemaVersion(schema)}, nil
+}
+
+// parse structures resume text with the LLM. With a custom field schema, the reply's custom
+// fields are validated and, if any fail, the LLM is asked once to correct them; values that
+// still fail are dropped.
+func (s *ResumeProfileService) parse(ctx context.Context, text string, schema *ResumeSchemaView) (*jsonresume.Resume, error) {
+	instructions := "Convert this resume into the JSON Resume schema (jsonresume.org). Use the sections basics " +
+		"(name, label, email, phone, url, summary, location {city, region, countryCode}, profiles [{network, username, url}]), " +
+		"work [{name, position, location, url, startDate, endDate, summary, highlights}], volunteer, " +
+		"education [{institution, area, studyType, startDate, endDate, score, courses}], awards, certificates, " +
+		"publications, skills [{name, level, keywords}], languages [{language, fluency}], interests and projects " +
+		"[{name, description, highlights, keywords, startDate, endDate, url}]. Dates are YYYY-MM-DD, YYYY-MM or YYYY; " +
+		"leave endDate empty for current roles. Copy bullet points into highlights word for word. Only use facts in " +
+		"the resume and omit empty fields. Respond with the JSON object only."
+	if schema != nil {
+		instructions += "\n\nAlso add a \"custom\" object with these fields, using null when the resume does not " +
+			"state the value:\n" + describeCustomFields(schema.Fields)
+	}
+	messages := []ai.ChatMessage{
+		{Role: "system", Content: instructions},
+		{Role: "user", Content: truncateRunes(text, maxResumeChars)},
+	}
+	raw, err := s.completer.Complete(ctx, s.chatConfig, messages)
+	if err != nil {
+		return nil, err
+	}
+	var resume jsonresume.Resume
+	if err := decodeLLMJSON(raw, &resume); err != nil || resume.IsEmpty() {
+		return nil, ErrResumeUnparseable
+	}
+	custom, problems := applyResumeSchema(schema, resume.Custom)
+	if len(problems) > 0 && schema != nil {
+		messages = append(messages,
+			ai.ChatMessage{Role: "assistant", Content: raw},
+			ai.ChatMessage{Role: "user", Content: "These custom fields are invalid:\n- " + strings.Join(problems, "\n- ") +
+				"\nReply with the corrected \"custom\" object only, as {\"custom\": {...}}."},
+		)
+		if retry, err := s.completer.Complete(ctx, s.chatConfig, messages); err == nil {
+			var fixed struct {
+				Custom map[string]interface{} `json:"custom"`
+			}
+			if decodeLLMJSON(retry, &fixed) == nil && fixed.Custom != nil {
+				custom, _ = applyResumeSchema(schema, fixed.Custom)
+			}
+		}
+	}
+	resume.Custom = custom
+	return &resume, nil
+}
+
+// applyResumeSchema validates custom field values against schema. Without an active schema
+// no custom fields are kept.
+func applyResumeSchema(schema *ResumeSchemaView, values map[string]interface{}) (map[string]interface{}, []string) {
+	if schema == nil {
+		if len(values) > 0 {
+			return nil, []string{"custom fields ignored: no resume schema is active"}
+		}
+		return nil, nil
+	}
+	clean, problems := validateCustomFields(schema.Fields, values)
+	if len(clean) == 0 {
+		clean = nil
+	}
+	return clean, problems
+}
+
+func schemaVersion(schema *ResumeSchemaView) int {
+	if schema == nil {
+		return 0
+	}
+	return schema.Version
+}
+
+// saveProfile stores resume as the profile of a document, hashing the document's current
+// text. existing is updated in place when given.
+func (s *ResumeProfileService) saveProfile(ctx context.Context, existing *model.ResumeProfile, userID, documentID uint, source string, version int, resume *jsonresume.Resume) error {
+	text, err := s.rag.DocumentText(ctx, userID, documentID)
+	if err != nil {
+		return err
+	}
+	stored := *resume
+	stored.Schema = ""
+	data, err := json.Marshal(stored)
+	if err != nil {
+		return err
+	}
+	profile := existing
+	if profile == nil {
+		profile = &model.ResumeProfile{UserID: userID, DocumentID: documentID}
+	}
+	profile.Source = source
+	profile.Data = string(data)
+	profile.ContentHash = contentHash(text)
+	profile.SchemaVersion = version
+	return s.repo.Save(ctx, profile)
+}
+
+func contentHash(text string) string {
+	sum := sha256.Sum256([]byte(text))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,282 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gopherai-resume/internal/model"
+	"gopherai-resume/internal/pkg/apperr"
+)
+
+// Custom resume field types.
+const (
+	ResumeFieldString     = "string"
+	ResumeFieldNumber     = "number"
+	ResumeFieldInteger    = "integer"
+	ResumeFieldBoolean    = "boolean"
+	ResumeFieldDate       = "date" // YYYY, YYYY-MM or YYYY-MM-DD
+	ResumeFieldEnum       = "enum"
+	ResumeFieldStringList = "string_list"
+)
+
+const (
+	maxResumeSchemaFields  = 30
+	maxResumeFieldDescLen  = 300
+	maxResumeEnumOptions   = 50
+	maxResumeFieldValueLen = 500
+)
+
+var (
+	ErrInvalidResumeSchema  = apperr.BadRequest("invalid resume schema")
+	ErrResumeSchemaNotFound = apperr.NotFound(apperr.CodeSchemaNotFound, "resume schema not found")
+	resumeFieldNamePattern  = regexp.MustCompile(`^[a-z][a-z0-9_]{0,63}$`)
+	resumeFieldDatePattern  = regexp.MustCompile(`^\d{4}(-\d{2}(-\d{2})?)?$`)
+	resumeFieldTypeSet      = map[string]bool{ResumeFieldString: true, ResumeFieldNumber: true, ResumeFieldInteger: true, ResumeFieldBoolean: true, ResumeFieldDate: true, ResumeFieldEnum: true, ResumeFieldStringList: true}
+)
+
+// ResumeSchemaField is a custom field the resume parser extracts, e.g. security_clearance
+// or visa_status. Enum fields list their allowed values in Options.
+type ResumeSchemaField struct {
+	Name        string   `json:"name"`
+	Type        string   `json:"type"`
+	Description string   `json:"description"`
+	Options     []string `json:"options,omitempty"`
+}
+
+// ResumeSchemaView is a schema version with its fields decoded.
+type ResumeSchemaView struct {
+	model.ResumeSchema
+	Fields []ResumeSchemaField `json:"fields"`
+}
+
+// CreateResumeSchemaInput registers a new schema version.
+type CreateResumeSchemaInput struct {
+	UserID      uint
+	Description string
+	Fields      []ResumeSchemaField
+	Activate    bool
+}
+
+// ResumeSchemaService is the registry of custom resume field schemas. Versions are
+// immutable; changing the fields means registering a new version and activating it.
+type ResumeSchemaService struct {
+	repo ResumeSchemaRepository
+}
+
+func NewResumeSchemaService(repo ResumeSchemaRepository) *ResumeSchemaService {
+	return &ResumeSchemaService{repo: repo}
+}
+
+func (s *ResumeSchemaService) Create(ctx context.Context, input CreateResumeSchemaInput) (*ResumeSchemaView, error) {
+	fields, err := normalizeResumeSchemaFields(input.Fields)
+	if err != nil {
+		return nil, err
+	}
+	data, err := json.Marshal(fields)
+	if err != nil {
+		return nil, err
+	}
+	schema := &model.ResumeSchema{
+		Description: truncateRunes(strings.TrimSpace(input.Description), 512),
+		Fields:      string(data),
+		Active:      input.Activate,
+		CreatedBy:   input.UserID,
+	}
+	if err := s.repo.Create(ctx, schema); err != nil {
+		return nil, err
+	}
+	return &ResumeSchemaView{ResumeSchema: *schema, Fields: fields}, nil
+}
+
+func (s *ResumeSchemaService) List(ctx context.Context) ([]ResumeSchemaView, error) {
+	list, err := s.repo.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	views := make([]ResumeSchemaView, 0, len(list))
+	for _, schema := range list {
+		views = append(views, resumeSchemaView(schema))
+	}
+	return views, nil
+}
+
+// Activate makes version the schema used by the parser; 0 switches custom fields off.
+func (s *ResumeSchemaService) Activate(ctx context.Context, version int) error {
+	if version < 0 {
+		return ErrInvalidInput.Withf("invalid version")
+	}
+	if version > 0 {
+		schema, err := s.repo.GetByVersion(ctx, version)
+		if err != nil {
+			return err
+		}
+		if schema == nil {
+			return ErrResumeSchemaNotFound
+		}
+	}
+	return s.repo.Activate(ctx, version)
+}
+
+// Active returns the active schema, or nil when none is active.
+func (s *ResumeSchemaService) Active(ctx context.Context) (*ResumeSchemaView, error) {
+	schema, err := s.repo.GetActive(ctx)
+	if err != nil || schema == nil {
+		return nil, err
+	}
+	view := resumeSchemaView(*schema)
+	return &view, nil
+}
+
+func resumeSchemaView(schema model.ResumeSchema) ResumeSchemaView {
+	view := ResumeSchemaView{ResumeSchema: schema, Fields: []ResumeSchemaField{}}
+	_ = json.Unmarshal([]byte(schema.Fields), &view.Fields)
+	return view
+}
+
+func normalizeResumeSchemaFields(fields []ResumeSchemaField) ([]ResumeSchemaField, error) {
+	if len(fields) == 0 || len(fields) > maxResumeSchemaFields {
+		return nil, ErrInvalidResumeSchema.Withf("1 to %d fields are required", maxResumeSchemaFields)
+	}
+	seen := make(map[string]bool, len(fields))
+	out := make([]ResumeSchemaField, 0, len(fields))
+	for _, f := range fields {
+		f.Name = strings.TrimSpace(f.Name)
+		f.Type = strings.ToLower(strings.TrimSpace(f.Type))
+		f.Description = strings.TrimSpace(f.Description)
+		switch {
+		case !resumeFieldNamePattern.MatchString(f.Name):
+			return nil, ErrInvalidResumeSchema.Withf("field name %q must be snake_case", f.Name)
+		case seen[f.Name]:
+			return nil, ErrInvalidResumeSchema.Withf("field name %q is repeated", f.Name)
+		case !resumeFieldTypeSet[f.Type]:
+			return nil, ErrInvalidResumeSchema.Withf("field %q has unknown type %q", f.Name, f.Type)
+		case len([]rune(f.Description)) > maxResumeFieldDescLen:
+			return nil, ErrInvalidResumeSchema.Withf("description of %q is too long", f.Name)
+		}
+		seen[f.Name] = true
+		if f.Type != ResumeFieldEnum {
+			f.Options = nil
+		} else {
+			options := cleanRequirements(f.Options)
+			if len(options) == 0 || len(options) > maxResumeEnumOptions {
+				return nil, ErrInvalidResumeSchema.Withf("enum field %q needs 1 to %d options", f.Name, maxResumeEnumOptions)
+			}
+			f.Options = options
+		}
+		out = append(out, f)
+	}
+	return out, nil
+}
+
+// validateCustomFields checks values against fields. It returns the valid values, with enum
+// values in their canonical spelling, and a problem description per rejected value. Nulls
+// are dropped silently: a resume need not state every field.
+func validateCustomFields(fields []ResumeSchemaField, values map[string]interface{}) (map[string]interface{}, []string) {
+	byName := make(map[string]ResumeSchemaField, len(fields))
+	for _, f := range fields {
+		byName[f.Name] = f
+	}
+	clean := make(map[string]interface{}, len(values))
+	var problems []string
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		value := values[name]
+		field, ok := byName[name]
+		if !ok {
+			problems = append(problems, fmt.Sprintf("%s: not a field of the schema", name))
+			continue
+		}
+		if value == nil {
+			continue
+		}
+		v, problem := validateCustomValue(field, value)
+		if problem != "" {
+			problems = append(problems, fmt.Sprintf("%s: %s", name, problem))
+			continue
+		}
+		clean[name] = v
+	}
+	return clean, problems
+}
+
+func validateCustomValue(field ResumeSchemaField, value interface{}) (interface{}, string) {
+	switch field.Type {
+	case ResumeFieldString:
+		if s, ok := value.(string); ok && strings.TrimSpace(s) != "" {
+			return truncateRunes(strings.TrimSpace(s), maxResumeFieldValueLen), ""
+		}
+		return nil, "expected a non-empty string"
+	case ResumeFieldNumber:
+		if n, ok := value.(float64); ok {
+			return n, ""
+		}
+		return nil, "expected a number"
+	case ResumeFieldInteger:
+		if n, ok := value.(float64); ok && n == math.Trunc(n) {
+			return int64(n), ""
+		}
+		return nil, "expected an integer"
+	case ResumeFieldBoolean:
+		if b, ok := value.(bool); ok {
+			return b, ""
+		}
+		return nil, "expected true or false"
+	case ResumeFieldDate:
+		if s, ok := value.(string); ok && resumeFieldDatePattern.MatchString(strings.TrimSpace(s)) {
+			return strings.TrimSpace(s), ""
+		}
+		return nil, "expected a date as YYYY, YYYY-MM or YYYY-MM-DD"
+	case ResumeFieldEnum:
+		if s, ok := value.(string); ok {
+			for _, option := range field.Options {
+				if strings.EqualFold(strings.TrimSpace(s), option) {
+					return option, ""
+				}
+			}
+		}
+		return nil, fmt.Sprintf("expected one of %s", strings.Join(field.Options, ", "))
+	case ResumeFieldStringList:
+		items, ok := value.([]interface{})
+		if !ok {
+			return nil, "expected a list of strings"
+		}
+		list := make([]string, 0, len(items))
+		for _, item := range items {
+			s, ok := item.(string)
+			if !ok {
+				return nil, "expected a list of strings"
+			}
+			if s = strings.TrimSpace(s); s != "" {
+				list = append(list, truncateRunes(s, maxResumeFieldValueLen))
+			}
+		}
+		return list, ""
+	}
+	return nil, "unknown field type"
+}
+
+// describeCustomFields renders fields for an LLM prompt, one per line.
+func describeCustomFields(fields []ResumeSchemaField) string {
+	var b strings.Builder
+	for _, f := range fields {
+		fmt.Fprintf(&b, "- %s (%s", f.Name, f.Type)
+		if f.Type == ResumeFieldEnum {
+			fmt.Fprintf(&b, ": one of %s", strings.Join(f.Options, " | "))
+		}
+		b.WriteString(")")
+		if f.Description != "" {
+			fmt.Fprintf(&b, ": %s", f.Description)
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
@@ -0,0 +1,595 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"gopherai-resume/internal/ai"
+	"gopherai-resume/internal/model"
+	"gopherai-resume/internal/pkg/apperr"
+	"gopherai-resume/internal/pkg/authz"
+	"gopherai-resume/internal/pkg/pdfwrite"
+	"gopherai-resume/internal/storage"
+)
+
+var (
+	ErrScreeningReportNotFound = apperr.NotFound(apperr.CodeReportNotFound, "screening report not found")
+	ErrScreeningResultNotFound = apperr.NotFound(apperr.CodeReportNotFound, "screening result not found")
+	ErrReportNotReady          = apperr.Conflict(apperr.CodeReportNotReady, "screening report is not ready")
+	ErrReportPDFUnsupported    = apperr.BadRequest("the report has text the PDF format cannot show, such as CJK; request it as markdown")
+)
+
+// Report formats and statuses.
+const (
+	ReportFormatMarkdown = "markdown"
+	ReportFormatPDF      = "pdf"
+
+	ReportPending    = "pending"
+	ReportProcessing = "processing"
+	ReportReady      = "ready"
+	ReportFailed     = "failed"
+)
+
+const (
+	reportEvidenceQueries = 8
+	reportChunksPerQuery  = 2
+	reportMaxEvidence     = 10
+	reportEvidenceExcerpt = 600
+	reportGenerateTimeout = 5 * time.Minute
+	maxReportsPerRequest  = 50
+	// reportLocalWorkers generate the reports queued in-process, from a backlog of at most
+	// reportLocalBacklog.
+	reportLocalWorkers     = 2
+	reportLocalBacklog     = 4 * maxReportsPerRequest
+	reportObjectKeyPattern = "screening-reports/%d/%d/%d.%s"
+)
+
+// ScreeningReportQueue hands report IDs to a background worker.
+type ScreeningReportQueue interface {
+	EnqueueScreeningReport(ctx context.Context, reportID uint) error
+}
+
+// ReportCitation is one evidence chunk quoted in a report.
+type ReportCitation struct {
+	Number     int    `json:"number"`
+	ChunkID    uint   `json:"chunk_id"`
+	ChunkIndex int    `json:"chunk_index"`
+	Excerpt    string `json:"excerpt"`
+}
+
+// ReportPoint is a strength or gap with the numbers of the citations supporting it.
+type ReportPoint struct {
+	Text      string `json:"text"`
+	Citations []int  `json:"citations"`
+}
+
+// ScreeningReportService produces per-candidate explanation documents for screening results.
+type ScreeningReportService struct {
+	repo        ScreeningReportRepository
+	postings    JobPostingRepository
+	docRepo     RAGDocumentRepository
+	workspaces  *WorkspaceService
+	rag         *RAGService
+	store       storage.ObjectStore
+	queue       ScreeningReportQueue // nil generates in-process
+	completer   ai.Completer
+	chatConfig  ai.ChatConfig
+	modelPolicy ModelPolicy // nil allows every model
+	notifier    Notifier    // nil disables report emails
+
+	localOnce sync.Once
+	local     chan uint // reports generated in-process
+}
+
+func NewScreeningReportService(
+	repo ScreeningReportRepository,
+	postings JobPostingRepository,
+	docRepo RAGDocumentRepository,
+	workspaces *WorkspaceService,
+	rag *RAGService,
+	store storage.ObjectStore,
+	queue ScreeningReportQueue,
+	completer ai.Completer,
+	chatConfig ai.ChatConfig,
+	modelPolicy ModelPolicy,
+	notifier Notifier,
+) *ScreeningReportService {
+	return &ScreeningReportService{
+		repo:        repo,
+		postings:    postings,
+		docRepo:     docRepo,
+		workspaces:  workspaces,
+		rag:         rag,
+		store:       store,
+		queue:       queue,
+		completer:   completer,
+		chatConfig:  chatConfig,
+		modelPolicy: modelPolicy,
+		notifier:    notifier,
+	}
+}
+
+// Request queues one report per screening result. With no result IDs every result of the
+// posting is covered. Recruiters and above only.
+func (s *ScreeningReportService) Request(ctx context.Context, userID, workspaceID, postingID uint, resultIDs []uint, format string) ([]model.ScreeningReport, error) {
+	format = strings.ToLower(strings.TrimSpace(format))
+	if format == "" {
+		format = ReportFormatMarkdown
+	}
+	if format != ReportFormatMarkdown && format != ReportFormatPDF {
+		return nil, ErrInvalidInput
+	}
+	if _, err := s.authorizePosting(ctx, userID, workspaceID, postingID); err != nil {
+		return nil, err
+	}
+	if err := checkConfiguredModel(ctx, s.modelPolicy, userID, s.chatConfig); err != nil {
+		return nil, err
+	}
+
+	var results []model.ScreeningResult
+	if ids := uniqueIDs(resultIDs); len(ids) > 0 {
+		for _, id := range ids {
+			result, err := s.postings.GetScreeningResult(ctx, id, postingID)
+			if err != nil {
+				return nil, err
+			}
+			if result == nil {
+				return nil, ErrScreeningResultNotFound
+			}
+			results = append(results, *result)
+		}
+	} else {
+		all, err := s.postings.ListScreeningResults(ctx, postingID)
+		if err != nil {
+			return nil, err
+		}
+		results = all
+	}
+	// Reports quote the resume, so they are only made for resumes the requester may read.
+	readable := results[:0]
+	for _, r := range results {
+		doc, err := loadRAGDocument(ctx, s.docRepo, r.DocumentID, userID, authz.Read)
+		if err != nil {
+			return nil, err
+		}
+		if doc != nil {
+			readable = append(readable, r)
+		}
+	}
+	results = readable
+	if len(results) == 0 {
+		return nil, ErrScreeningResultNotFound
+	}
+	if len(results) > maxReportsPerRequest {
+		return nil, ErrInvalidInput
+	}
+
+	admin := authz.IsAdmin(ctx, userID)
+	reports := make([]model.ScreeningReport, 0, len(results))
+	for _, r := range results {
+		reports = append(reports, model.ScreeningReport{
+			WorkspaceID:       workspaceID,
+			JobPostingID:      postingID,
+			ScreeningResultID: r.ID,
+			RequestedBy:       userID,
+			RequestedByAdmin:  admin,
+			Format:            format,
+			Status:            ReportPending,
+		})
+	}
+	if err := s.repo.CreateBatch(ctx, reports); err != nil {
+		return nil, err
+	}
+	for i := range reports {
+		s.enqueue(ctx, &reports[i])
+	}
+	return reports, nil
+}
+
+// enqueue hands the report to the worker, or to the in-process workers when no queue is
+// configured or publishing fails. A report that finds the in-process backlog full fails.
+func (s *ScreeningReportService) enqueue(ctx context.Context, report *model.ScreeningReport) {
+	if s.queue != nil {
+		err := s.queue.EnqueueScreeningReport(ctx, report.ID)
+		if err == nil {
+			return
+		}
+		log.Printf("enqueue screening report %d failed, generating in-process: %v", report.ID, err)
+	}
+	s.localOnce.Do(s.startLocal)
+	select {
+	case s.local <- report.ID:
+	default:
+		report.Status = ReportFailed
+		report.Error = "too many reports are being generated; request it again later"
+		if err := s.repo.Update(ctx, report); err != nil {
+			log.Printf("fail screening report %d failed: %v", report.ID, err)
+		}
+	}
+}
+
+// startLocal starts the workers that generate reports in-process.
+func (s *ScreeningReportService) startLocal() {
+	s.local = make(chan uint, reportLocalBacklog)
+	for i := 0; i < reportLocalWorkers; i++ {
+		go func() {
+			for id := range s.local {
+				genCtx, cancel := context.WithTimeout(context.Background(), reportGenerateTimeout)
+				if err := s.GenerateReport(genCtx, id); err != nil {
+					log.Printf("generate screening report %d failed: %v", id, err)
+				}
+				cancel()
+			}
+		}()
+	}
+}
+
+func (s *ScreeningReportService) List(ctx context.Context, userID, workspaceID, postingID uint) ([]model.ScreeningReport, error) {
+	if _, err := s.authorizePosting(ctx, userID, workspaceID, postingID); err != nil {
+		return nil, err
+	}
+	return s.repo.ListByPostingID(ctx, postingID)
+}
+
+func (s *ScreeningReportService) Get(ctx context.Context, userID, workspaceID, postingID, reportID uint) (*model.ScreeningReport, error) {
+	if _, err := s.authorizePosting(ctx, userID, workspaceID, postingID); err != nil {
+		return nil, err
+	}
+	if reportID == 0 {
+		return nil, ErrInvalidInput
+	}
+	report, err := s.repo.GetByIDAndPostingID(ctx, reportID, postingID)
+	if err != nil {
+		return nil, err
+	}
+	if report == nil {
+		return nil, ErrScreeningReportNotFound
+	}
+	return report, nil
+}
+
+// Download returns a ready report's file with its content type and file name.
+func (s *ScreeningReportService) Download(ctx context.Context, userID, workspaceID, postingID, reportID uint) ([]byte, string, string, error) {
+	report, err := s.Get(ctx, userID, workspaceID, postingID, reportID)
+	if err != nil {
+		return nil, "", "", err
+	}
+	if report.Status != ReportReady {
+		return nil, "", "", ErrReportNotReady
+	}
+	data, err := s.store.Get(ctx, report.ObjectKey)
+	if errors.Is(err, storage.ErrObjectNotFound) {
+		return nil, "", "", ErrScreeningReportNotFound
+	}
+	if err != nil {
+		return nil, "", "", err
+	}
+	contentType, ext := "text/markdown; charset=utf-8", "md"
+	if report.Format == ReportFormatPDF {
+		contentType, ext = "application/pdf", "pdf"
+	}
+	return data, contentType, fmt.Sprintf("screening-report-%d.%s", report.ID, ext), nil
+}
+
+// deleteFiles removes the stored files of a posting's reports. Failures are logged only;
+// the records are deleted with the posting.
+func (s *ScreeningReportService) deleteFiles(ctx context.Context, postingID uint) {
+	reports, err := s.repo.ListByPostingID(ctx, postingID)
+	if err != nil {
+		log.Printf("list screening reports of posting %d failed: %v", postingID, err)
+		return
+	}
+	for _, r := range reports {
+		if r.ObjectKey == "" {
+			continue
+		}
+		if err := s.store.Delete(ctx, r.ObjectKey); err != nil {
+			log.Printf("delete screening report %d file failed: %v", r.ID, err)
+		}
+	}
+}
+
+// GenerateReport builds and stores one report. It is called by the report worker and
+// records failures on the report itself.
+func (s *ScreeningReportService) GenerateReport(ctx context.Context, reportID uint) error {
+	report, err := s.repo.GetByID(ctx, reportID)
+	if err != nil {
+		return err
+	}
+	if report == nil || report.Status == ReportReady {
+		return nil
+	}
+	report.Status = ReportProcessing
+	report.Error = ""
+	if err := s.repo.Update(ctx, report); err != nil {
+		return err
+	}
+
+	data, genErr := s.generate(ctx, report)
+	if genErr == nil {
+		key := fmt.Sprintf(reportObjectKeyPattern, report.WorkspaceID, report.JobPostingID, report.ID, report.Format)
+		if genErr = s.store.Put(ctx, key, data); genErr == nil {
+			report.ObjectKey = key
+			report.Status = ReportReady
+		}
+	}
+	if genErr != nil {
+		report.Status = ReportFailed
+		report.Error = truncateRunes(genErr.Error(), 512)
+	}
+	if err := s.repo.Update(ctx, report); err != nil {
+		return err
+	}
+	s.notifyFinished(ctx, report)
+	return genErr
+}
+
+// notifyFinished emails the requester that a report is ready or has failed.
+func (s *ScreeningReportService) notifyFinished(ctx context.Context, report *model.ScreeningReport) {
+	download := fmt.Sprintf("GET /api/v1/workspaces/%d/jobs/%d/reports/%d/download", report.WorkspaceID, report.JobPostingID, report.ID)
+	subject := fmt.Sprintf("Screening report %d is ready", report.ID)
+	body := fmt.Sprintf("The %s screening report %d for job posting %d is ready. Download it with %s.",
+		report.Format, report.ID, report.JobPostingID, download)
+	if report.Status == ReportFailed {
+		subject = fmt.Sprintf("Screening report %d failed", report.ID)
+		body = fmt.Sprintf("The screening report %d for job posting %d could not be generated: %s\nYou can request it again.",
+			report.ID, report.JobPostingID, report.Error)
+	}
+	notify(ctx, s.notifier, report.RequestedBy, NotifyScreeningReport, subject, body+notificationFooter)
+}
+
+func (s *ScreeningReportService) generate(ctx context.Context, report *model.ScreeningReport) ([]byte, error) {
+	// Workers have no request principal, so the requester's is restored for the resume
+	// loads; the access checks are applied again as when the report was requested.
+	ctx = authz.With(ctx, authz.Principal{UserID: report.RequestedBy, Admin: report.RequestedByAdmin})
+	posting, err := s.postings.GetByIDAndWorkspaceID(ctx, report.JobPostingID, report.WorkspaceID)
+	if err != nil {
+		return nil, err
+	}
+	if posting == nil {
+		return nil, ErrJobPostingNotFound
+	}
+	result, err := s.postings.GetScreeningResult(ctx, report.ScreeningResultID, posting.ID)
+	if err != nil {
+		return nil, err
+	}
+	if result == nil {
+		return nil, ErrScreeningResultNotFound
+	}
+	doc, err := loadRAGDocument(ctx, s.docRepo, result.DocumentID, report.RequestedBy, authz.Read)
+	if err != nil {
+		return nil, err
+	}
+	if doc == nil {
+		return nil, ErrResumeDocumentNotFound
+	}
+	ranked, err := s.postings.ListScreeningResults(ctx, posting.ID)
+	if err != nil {
+		return nil, err
+	}
+	rank := 0
+	for i := range ranked {
+		if ranked[i].ID == result.ID {
+			rank = i + 1
+			break
+		}
+	}
+
+	var strengths, gaps []string
+	_ = json.Unmarshal([]byte(result.Strengths), &strengths)
+	_ = json.Unmarshal([]byte(result.Gaps), &gaps)
+	var parsed ParsedJobPosting
+	_ = json.Unmarshal([]byte(posting.Parsed), &parsed)
+
+	citations, err := s.collectEvidence(ctx, report.RequestedBy, result, evidenceQueries(posting, &parsed, strengths, gaps))
+	if err != nil {
+		return nil, err
+	}
+
+	var evidence strings.Builder
+	for _, c := range citations {
+		fmt.Fprintf(&evidence, "[%d] %s\n\n", c.Number, c.Excerpt)
+	}
+	messages := []ai.ChatMessage{
+		{Role: "system", Content: "You explain a candidate's screening result to a recruiter. Respond with a JSON object: " +
+			"{\"overview\": string (2-3 sentences on why the candidate ranks where they do), " +
+			"\"strengths\": [{\"text\": string, \"citations\": [evidence numbers]}], " +
+			"\"gaps\": [{\"text\": string, \"citations\": [evidence numbers]}], " +
+			"\"probes\": [interview questions that would confirm strengths or close gaps]}. " +
+			"Cite only the numbered evidence given; a gap may have no citations. Do not invent facts."},
+		{Role: "user", Content: fmt.Sprintf("Job: %s\nRequirements: %s\n\nScreening score: %d/100 (rank %d of %d)\nScreening summary: %s\nStrengths noted: %s\nGaps noted: %s\n\nEvidence from the resume:\n%s",
+			posting.Title, orDefault(strings.Join(parsed.RequiredSkills, "; "), truncateRunes(posting.Description, 3000)),
+			result.Score, rank, len(ranked), result.Summary,
+			strings.Join(strengths, "; "), strings.Join(gaps, "; "), orDefault(evidence.String(), "(none)"))},
+	}
+	// The policy is checked again in case the requester's allowlist changed since queueing.
+	if err := checkConfiguredModel(ctx, s.modelPolicy, report.RequestedBy, s.chatConfig); err != nil {
+		return nil, err
+	}
+	raw, err := s.completer.Complete(ctx, s.chatConfig, messages)
+	if err != nil {
+		return nil, err
+	}
+	var explanation struct {
+		Overview  string        `json:"overview"`
+		Strengths []ReportPoint `json:"strengths"`
+		Gaps      []ReportPoint `json:"gaps"`
+		Probes    []string      `json:"probes"`
+	}
+	if err := decodeLLMJSON(raw, &explanation); err != nil {
+		explanation.Overview = strings.TrimSpace(raw)
+	}
+
+	md := renderScreeningReport(screeningReportContent{
+		Posting:   posting,
+		Result:    result,
+		Document:  doc,
+		Rank:      rank,
+		Total:     len(ranked),
+		Overview:  explanation.Overview,
+		Strengths: validCitations(explanation.Strengths, len(citations)),
+		Gaps:      validCitations(explanation.Gaps, len(citations)),
+		Probes:    explanation.Probes,
+		Citations: citations,
+	})
+	if report.Format == ReportFormatPDF {
+		data, err := pdfwrite.FromMarkdown(md)
+		if errors.Is(err, pdfwrite.ErrUnsupportedText) {
+			return nil, ErrReportPDFUnsupported
+		}
+		return data, err
+	}
+	return []byte(md), nil
+}
+
+// collectEvidence retrieves, as userID, the resume chunks most relevant to each query and
+// numbers them.
+func (s *ScreeningReportService) collectEvidence(ctx context.Context, userID uint, result *model.ScreeningResult, queries []string) ([]ReportCitation, error) {
+	var citations []ReportCitation
+	seen := make(map[uint]bool)
+	for _, q := range queries {
+		chunks, err := s.rag.RetrieveFromDocument(ctx, userID, result.DocumentID, q, reportChunksPerQuery)
+		if err != nil {
+			return nil, err
+		}
+		for _, c := range chunks {
+			if seen[c.ID] || len(citations) >= reportMaxEvidence {
+				continue
+			}
+			seen[c.ID] = true
+			citations = append(citations, ReportCitation{
+				Number:     len(citations) + 1,
+				ChunkID:    c.ID,
+				ChunkIndex: c.ChunkIndex,
+				Excerpt:    truncateRunes(strings.Join(strings.Fields(c.Content), " "), reportEvidenceExcerpt),
+			})
+		}
+	}
+	return citations, nil
+}
+
+func (s *ScreeningReportService) authorizePosting(ctx context.Context, userID, workspaceID, postingID uint) (*model.JobPosting, error) {
+	if _, err := s.workspaces.Authorize(ctx, workspaceID, userID, WorkspaceRecruiter); err != nil {
+		return nil, err
+	}
+	if postingID == 0 {
+		return nil, ErrInvalidInput
+	}
+	posting, err := s.postings.GetByIDAndWorkspaceID(ctx, postingID, workspaceID)
+	if err != nil {
+		return nil, err
+	}
+	if posting == nil {
+		return nil, ErrJobPostingNotFound
+	}
+	return posting, nil
+}
+
+// evidenceQueries picks what to search the resume for: the posting's required skills and the
+// strengths and gaps noted during screening.
+func evidenceQueries(posting *model.JobPosting, parsed *ParsedJobPosting, strengths, gaps []string) []string {
+	var queries []string
+	for _, group := range [][]string{parsed.RequiredSkills, strengths, gaps} {
+		for _, q := range group {
+			if q = strings.TrimSpace(q); q != "" && len(queries) < reportEvidenceQueries {
+				queries = append(queries, q)
+			}
+		}
+	}
+	if len(queries) == 0 {
+		queries = append(queries, posting.Title)
+	}
+	return queries
+}
+
+// validCitations drops citation numbers outside 1..n.
+func validCitations(points []ReportPoint, n int) []ReportPoint {
+	out := make([]ReportPoint, 0, len(points))
+	for _, p := range points {
+		if strings.TrimSpace(p.Text) == "" {
+			continue
+		}
+		valid := p.Citations[:0]
+		for _, c := range p.Citations {
+			if c >= 1 && c <= n {
+				valid = append(valid, c)
+			}
+		}
+		p.Citations = valid
+		out = append(out, p)
+	}
+	return out
+}
+
+type screeningReportContent struct {
+	Posting   *model.JobPosting
+	Result    *model.ScreeningResult
+	Document  *model.RAGDocument
+	Rank      int
+	Total     int
+	Overview  string
+	Strengths []ReportPoint
+	Gaps      []ReportPoint
+	Probes    []string
+	Citations []ReportCitation
+}
+
+func renderScreeningReport(c screeningReportContent) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# Candidate report: %s\n\n", c.Document.Name)
+	fmt.Fprintf(&b, "Position: %s", c.Posting.Title)
+	if c.Posting.Company != "" {
+		fmt.Fprintf(&b, " at %s", c.Posting.Company)
+	}
+	fmt.Fprintf(&b, "\n\nScore: %d/100, rank %d of %d\n\n", c.Result.Score, c.Rank, c.Total)
+	if overview := strings.TrimSpace(c.Overview); overview != "" {
+		fmt.Fprintf(&b, "## Overview\n\n%s\n\n", overview)
+	}
+	writePoints := func(title string, points []ReportPoint) {
+		fmt.Fprintf(&b, "## %s\n\n", title)
+		if len(points) == 0 {
+			b.WriteString("None identified.\n\n")
+			return
+		}
+		for _, p := range points {
+			b.WriteString("- " + strings.TrimSpace(p.Text))
+			for _, n := range p.Citations {
+				fmt.Fprintf(&b, " [%d]", n)
+			}
+			b.WriteString("\n")
+		}
+		b.WriteString("\n")
+	}
+	writePoints("Strengths", c.Strengths)
+	writePoints("Gaps", c.Gaps)
+	if len(c.Probes) > 0 {
+		b.WriteString("## Suggested interview probes\n\n")
+		for _, p := range c.Probes {
+			if p = strings.TrimSpace(p); p != "" {
+				b.WriteString("- " + p + "\n")
+			}
+		}
+		b.WriteString("\n")
+	}
+	if len(c.Citations) > 0 {
+		b.WriteString("## Evidence\n\n")
+		for _, cit := range c.Citations {
+			fmt.Fprintf(&b, "[%d] %s, chunk %d: \"%s\"\n\n", cit.Number, c.Document.Name, cit.ChunkIndex, cit.Excerpt)
+		}
+	}
+	fmt.Fprintf(&b, "Generated %s from screening result %d.\n", time.Now().UTC().Format("2006-01-02 15:04 UTC"), c.Result.ID)
+	return b.String()
+}
+
+func truncateRunes(s string, n int) string {
+	if runes := []rune(s); len(runes) > n {
+		return string(runes[:n])
+	}
+	return s
+}
@@ -0,0 +1,151 @@
+package app
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"gopherai-resume/internal/model"
+	"gopherai-resume/internal/pkg/apperr"
+	"gopherai-resume/internal/pkg/authz"
+)
+
+var ErrShareNotFound = apperr.NotFound(apperr.CodeShareNotFound, "share link not found")
+
+// maxSharedMessages caps the transcript a share link shows; the oldest messages are kept.
+const maxSharedMessages = 1000
+
+// SessionShareLink is a newly created share link. Token is only available here.
+type SessionShareLink struct {
+	SessionID uint      `json:"session_id"`
+	Token     string    `json:"token"`
+	Path      string    `json:"path"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// SharedMessage is a message as shown to anyone holding a share link.
+type SharedMessage struct {
+	Role      string    `json:"role"`
+	Content   string    `json:"content"`
+	Truncated bool      `json:"truncated"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// SharedTranscript is the read-only view of a shared session. It leaves out user and
+// message IDs, and tool and system messages.
+type SharedTranscript struct {
+	Title    string          `json:"title"`
+	SharedAt time.Time       `json:"shared_at"`
+	Messages []SharedMessage `json:"messages"`
+	HasMore  bool            `json:"has_more"`
+}
+
+// SessionShareService manages public read-only links to chat sessions.
+type SessionShareService struct {
+	shares   SessionShareRepository
+	sessions SessionRepository
+	messages MessageRepository
+}
+
+func NewSessionShareService(
+	shares SessionShareRepository,
+	sessions SessionRepository,
+	messages MessageRepository,
+) *SessionShareService {
+	return &SessionShareService{shares: shares, sessions: sessions, messages: messages}
+}
+
+// Share creates a share link for the session, revoking any earlier one.
+func (s *SessionShareService) Share(ctx context.Context, userID, sessionID uint) (*SessionShareLink, error) {
+	if userID == 0 || sessionID == 0 {
+		return nil, ErrInvalidInput
+	}
+	session, err := loadChatSession(ctx, s.sessions, sessionID, userID, authz.Write)
+	if err != nil {
+		return nil, err
+	}
+	if session == nil {
+		return nil, ErrSessionNotFound
+	}
+	raw := make([]byte, 24)
+	if _, err := rand.Read(raw); err != nil {
+		return nil, fmt.Errorf("generate share token failed: %w", err)
+	}
+	token := hex.EncodeToString(raw)
+	share := &model.SessionShare{SessionID: sessionID, UserID: userID, TokenHash: hashToken(token)}
+	if err := s.shares.Replace(ctx, share); err != nil {
+		return nil, err
+	}
+	return &SessionShareLink{
+		SessionID: sessionID,
+		Token:     token,
+		Path:      "/share/" + token,
+		CreatedAt: share.CreatedAt,
+	}, nil
+}
+
+// Revoke deletes the session's share link.
+func (s *SessionShareService) Revoke(ctx context.Context, userID, sessionID uint) error {
+	if userID == 0 || sessionID == 0 {
+		return ErrInvalidInput
+	}
+	session, err := loadChatSession(ctx, s.sessions, sessionID, userID, authz.Write)
+	if err != nil {
+		return err
+	}
+	if session == nil {
+		return ErrSessionNotFound
+	}
+	revoked, err := s.shares.DeleteBySessionID(ctx, sessionID)
+	if err != nil {
+		return err
+	}
+	if !revoked {
+		return ErrShareNotFound
+	}
+	return nil
+}
+
+// Transcript returns the conversation behind a share token. It reads the session as it is
+// now, so messages added after sharing are included.
+func (s *SessionShareService) Transcript(ctx context.Context, token string) (*SharedTranscript, error) {
+	if token == "" {
+		return nil, ErrShareNotFound
+	}
+	share, err := s.shares.GetByTokenHash(ctx, hashToken(token))
+	if err != nil {
+		return nil, err
+	}
+	if share == nil {
+		return nil, ErrShareNotFound
+	}
+	session, err := loadChatSession(ctx, s.sessions, share.SessionID, share.UserID, authz.Read)
+	if err != nil {
+		return nil, err
+	}
+	if session == nil {
+		return nil, ErrShareNotFound
+	}
+	messages, err := s.messages.ListBranch(ctx, session.ID, maxSharedMessages+1)
+	if err != nil {
+		return nil, err
+	}
+	transcript := &SharedTranscript{Title: session.Title, SharedAt: share.CreatedAt, Messages: []SharedMessage{}}
+	if len(messages) > maxSharedMessages {
+		messages, transcript.HasMore = messages[:maxSharedMessages], true
+	}
+	for _, m := range messages {
+		if m.Role != "user" && m.Role != "assistant" {
+			continue
+		}
+		transcript.Messages = append(transcript.Messages, SharedMessage{
+			Role:      m.Role,
+			Content:   m.Content,
+			Truncated: m.Truncated,
+			CreatedAt: m.CreatedAt,
+		})
+	}
+	return transcript, nil
+}
@@ -0,0 +1,150 @@
+package app
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// skillTaxonomy maps canonical skill names to the spellings that mean the same thing. It is
+// deliberately small: it covers the skills recruiters filter on most, and anything else is
+// still found by embedding search.
+var skillTaxonomy = map[string][]string{
+	"go":               {"Go", "golang"},
+	"python":           {"python"},
+	"java":             {"java"},
+	"kotlin":           {"kotlin"},
+	"scala":            {"scala"},
+	"javascript":       {"javascript", "js", "ecmascript"},
+	"typescript":       {"typescript"},
+	"c++":              {"c++", "cpp"},
+	"c#":               {"c#", "csharp"},
+	".net":             {".net", "dotnet", "asp.net"},
+	"rust":             {"rust"},
+	"ruby":             {"ruby"},
+	"rails":            {"Rails", "ruby on rails"},
+	"php":              {"php"},
+	"swift":            {"Swift"},
+	"sql":              {"sql"},
+	"node.js":          {"node.js", "nodejs", "Node"},
+	"react":            {"React", "react.js", "reactjs"},
+	"vue":              {"vue", "vue.js", "vuejs"},
+	"angular":          {"angular", "angularjs"},
+	"django":           {"django"},
+	"flask":            {"flask"},
+	"fastapi":          {"fastapi"},
+	"spring":           {"Spring", "spring boot", "springboot"},
+	"gin":              {"gin"},
+	"grpc":             {"grpc"},
+	"graphql":          {"graphql"},
+	"rest":             {"REST", "restful", "rest api"},
+	"microservices":    {"microservices", "microservice"},
+	"kafka":            {"kafka", "apache kafka"},
+	"rabbitmq":         {"rabbitmq"},
+	"redis":            {"redis"},
+	"mysql":            {"mysql"},
+	"postgresql":       {"postgresql", "postgres", "psql"},
+	"mongodb":          {"mongodb", "mongo"},
+	"elasticsearch":    {"elasticsearch", "elastic search", "opensearch"},
+	"cassandra":        {"cassandra"},
+	"docker":           {"docker"},
+	"kubernetes":       {"kubernetes", "k8s"},
+	"terraform":        {"terraform"},
+	"ansible":          {"ansible"},
+	"aws":              {"aws", "amazon web services"},
+	"gcp":              {"gcp", "google cloud"},
+	"azure":            {"azure"},
+	"linux":            {"linux"},
+	"ci/cd":            {"ci/cd", "cicd", "continuous integration"},
+	"git":              {"git"},
+	"spark":            {"Spark", "apache spark", "pyspark"},
+	"hadoop":           {"hadoop"},
+	"airflow":          {"airflow"},
+	"pytorch":          {"pytorch"},
+	"tensorflow":       {"tensorflow"},
+	"machine learning": {"machine learning", "ML"},
+	"deep learning":    {"deep learning"},
+	"nlp":              {"nlp", "natural language processing"},
+	"llm":              {"llm", "llms", "large language models"},
+	"data analysis":    {"data analysis", "data analytics"},
+	"html":             {"html", "html5"},
+	"css":              {"css", "css3"},
+	"ios":              {"iOS"},
+	"android":          {"android"},
+	"figma":            {"figma"},
+	"agile":            {"agile", "scrum"},
+}
+
+type skillPattern struct {
+	skill   string
+	pattern *regexp.Regexp
+}
+
+// skillPatterns match aliases as whole words. Aliases that are also everyday words are
+// written with capitals ("Go", "React", "REST") and matched case-sensitively.
+var skillPatterns = buildSkillPatterns()
+
+func buildSkillPatterns() []skillPattern {
+	var patterns []skillPattern
+	for skill, aliases := range skillTaxonomy {
+		for _, alias := range aliases {
+			flags := "(?i)"
+			if alias != strings.ToLower(alias) {
+				flags = ""
+			}
+			expr := flags + `(?:^|[^\pL\pN+#.])` + regexp.QuoteMeta(alias) + `(?:$|[^\pL\pN+#])`
+			patterns = append(patterns, skillPattern{skill: skill, pattern: regexp.MustCompile(expr)})
+		}
+	}
+	return patterns
+}
+
+// findSkills returns the canonical skills mentioned in text, sorted.
+func findSkills(text string) []string {
+	found := make(map[string]bool)
+	for _, p := range skillPatterns {
+		if !found[p.skill] && p.pattern.MatchString(text) {
+			found[p.skill] = true
+		}
+	}
+	skills := make([]string, 0, len(found))
+	for skill := range found {
+		skills = append(skills, skill)
+	}
+	sort.Strings(skills)
+	return skills
+}
+
+// canonicalSkill maps a skill name to its taxonomy entry, or its trimmed lower-case form
+// when the taxonomy does not know it.
+func canonicalSkill(name string) string {
+	name = strings.TrimSpace(name)
+	lower := strings.ToLower(name)
+	if _, ok := skillTaxonomy[lower]; ok {
+		return lower
+	}
+	for skill, aliases := range skillTaxonomy {
+		for _, alias := range aliases {
+			if strings.EqualFold(alias, name) {
+				return skill
+			}
+		}
+	}
+	return lower
+}
+
+// canonicalSkills canonicalizes and de-duplicates skill names, sorted.
+func canonicalSkills(names []string) []string {
+	seen := make(map[string]bool, len(names))
+	out := make([]string, 0, len(names))
+	for _, name := range names {
+		skill := canonicalSkill(name)
+		if skill == "" || seen[skill] {
+			continue
+		}
+		seen[skill] = true
+		out = append(out, skill)
+	}
+	sort.Strings(out)
+	return out
+}
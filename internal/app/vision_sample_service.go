@@ -0,0 +1,271 @@
+package app
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"gopherai-resume/internal/model"
+	"gopherai-resume/internal/pkg/apperr"
+	"gopherai-resume/internal/pkg/authz"
+	"gopherai-resume/internal/storage"
+	"gopherai-resume/internal/vision"
+)
+
+var (
+	ErrVisionSampleNotFound = apperr.NotFound(apperr.CodeSampleNotFound, "vision sample not found")
+	ErrVisionModelNotFound  = apperr.BadRequest("vision model not found")
+)
+
+// VisionSampleService keeps user-consented classified images and re-runs them against other
+// models, so model upgrades can be evaluated on real traffic. Re-runs count against the
+// vision quotas of whoever asked for them.
+type VisionSampleService struct {
+	repo   VisionSampleRepository
+	store  storage.ObjectStore
+	models *vision.Registry
+	quota  *QuotaService
+}
+
+func NewVisionSampleService(
+	repo VisionSampleRepository,
+	store storage.ObjectStore,
+	models *vision.Registry,
+	quota *QuotaService,
+) *VisionSampleService {
+	return &VisionSampleService{
+		repo:   repo,
+		store:  store,
+		models: models,
+		quota:  quota,
+	}
+}
+
+// SaveVisionSampleInput is a classified image and the result returned to the user.
+type SaveVisionSampleInput struct {
+	UserID  uint
+	Image   []byte
+	Options vision.ClassifyOptions
+	Result  *vision.ClassifyResult
+}
+
+// Save stores the image in the object store and records the sample with its result.
+func (s *VisionSampleService) Save(ctx context.Context, input SaveVisionSampleInput) (*model.VisionSample, error) {
+	if input.UserID == 0 || len(input.Image) == 0 || input.Result == nil {
+		return nil, ErrInvalidInput
+	}
+	resultJSON, err := json.Marshal(input.Result)
+	if err != nil {
+		return nil, fmt.Errorf("marshal vision result failed: %w", err)
+	}
+
+	sum := sha256.Sum256(input.Image)
+	hash := hex.EncodeToString(sum[:])
+	key := fmt.Sprintf("vision/%d/%d-%s", input.UserID, time.Now().UnixNano(), hash[:16])
+	if err := s.store.Put(ctx, key, input.Image); err != nil {
+		return nil, err
+	}
+
+	sample := &model.VisionSample{
+		UserID:        input.UserID,
+		ObjectKey:     key,
+		SHA256:        hash,
+		SizeBytes:     len(input.Image),
+		Model:         input.Result.Model.Name,
+		ModelVersion:  input.Result.Model.Version,
+		TopK:          input.Options.TopK,
+		Probabilities: input.Options.Probabilities,
+		Result:        string(resultJSON),
+	}
+	if err := s.repo.Create(ctx, sample); err != nil {
+		_ = s.store.Delete(ctx, key)
+		return nil, err
+	}
+	return sample, nil
+}
+
+// FindClassified returns the stored result of the user's newest sample of the same image
+// classified with the same model version and options, with SampleID set and marked
+// Duplicate, or nil when there is none.
+func (s *VisionSampleService) FindClassified(ctx context.Context, userID uint, image []byte, info vision.ModelInfo, opts vision.ClassifyOptions) (*vision.ClassifyResult, error) {
+	if userID == 0 || len(image) == 0 {
+		return nil, ErrInvalidInput
+	}
+	sum := sha256.Sum256(image)
+	samples, err := s.repo.ListByImageHash(ctx, userID, hex.EncodeToString(sum[:]))
+	if err != nil {
+		return nil, err
+	}
+	for _, sample := range samples {
+		if sample.Model != info.Name || sample.ModelVersion != info.Version ||
+			sample.TopK != opts.TopK || sample.Probabilities != opts.Probabilities {
+			continue
+		}
+		var result vision.ClassifyResult
+		if err := json.Unmarshal([]byte(sample.Result), &result); err != nil {
+			return nil, fmt.Errorf("unmarshal stored vision result failed: %w", err)
+		}
+		result.SampleID = sample.ID
+		result.Duplicate = true
+		result.Cached = false
+		return &result, nil
+	}
+	return nil, nil
+}
+
+func (s *VisionSampleService) List(ctx context.Context, userID uint, limit int) ([]model.VisionSample, error) {
+	if userID == 0 {
+		return nil, ErrInvalidInput
+	}
+	return s.repo.ListByUserID(ctx, userID, limit)
+}
+
+// Delete removes a sample and its stored image (consent withdrawal).
+func (s *VisionSampleService) Delete(ctx context.Context, userID, sampleID uint) error {
+	sample, err := loadVisionSample(ctx, s.repo, sampleID, userID, authz.Write)
+	if err != nil {
+		return err
+	}
+	if sample == nil {
+		return ErrVisionSampleNotFound
+	}
+	if err := s.store.Delete(ctx, sample.ObjectKey); err != nil {
+		return err
+	}
+	return s.repo.DeleteByIDAndUserID(ctx, sampleID, userID)
+}
+
+// VisionComparison summarises how a re-run differs from the stored result.
+type VisionComparison struct {
+	Top1Before  string `json:"top1_before"`
+	Top1After   string `json:"top1_after"`
+	Top1Changed bool   `json:"top1_changed"`
+	// Overlap is the number of labels present in both top-k lists.
+	Overlap int `json:"overlap"`
+}
+
+// VisionRerunResult is a stored sample re-classified with another model.
+type VisionRerunResult struct {
+	Sample     model.VisionSample     `json:"sample"`
+	Original   *vision.ClassifyResult `json:"original"`
+	Rerun      *vision.ClassifyResult `json:"rerun"`
+	Comparison VisionComparison       `json:"comparison"`
+}
+
+// Rerun re-classifies one of the user's samples with modelName (empty = default model).
+func (s *VisionSampleService) Rerun(ctx context.Context, userID, sampleID uint, modelName string) (*VisionRerunResult, error) {
+	sample, err := loadVisionSample(ctx, s.repo, sampleID, userID, authz.Read)
+	if err != nil {
+		return nil, err
+	}
+	if sample == nil {
+		return nil, ErrVisionSampleNotFound
+	}
+	return s.rerun(ctx, userID, sample, modelName)
+}
+
+// rerun re-classifies sample, charging the inference to userID.
+func (s *VisionSampleService) rerun(ctx context.Context, userID uint, sample *model.VisionSample, modelName string) (*VisionRerunResult, error) {
+	classifier, ok := s.models.Get(modelName)
+	if !ok {
+		return nil, ErrVisionModelNotFound
+	}
+	var original vision.ClassifyResult
+	if err := json.Unmarshal([]byte(sample.Result), &original); err != nil {
+		return nil, fmt.Errorf("unmarshal stored vision result failed: %w", err)
+	}
+	image, err := s.store.Get(ctx, sample.ObjectKey)
+	if err != nil {
+		return nil, err
+	}
+	charge, err := chargeVision(ctx, s.quota, userID, image)
+	if err != nil {
+		return nil, err
+	}
+	rerun, err := classifier.ClassifyWithOptions(image, vision.ClassifyOptions{
+		TopK:          sample.TopK,
+		Probabilities: sample.Probabilities,
+	})
+	if err != nil {
+		charge.refund(ctx)
+		return nil, err
+	}
+	return &VisionRerunResult{
+		Sample:     *sample,
+		Original:   &original,
+		Rerun:      rerun,
+		Comparison: compareVisionResults(original.Predictions, rerun.Predictions),
+	}, nil
+}
+
+// VisionEvaluation aggregates re-runs of recent samples against one model.
+type VisionEvaluation struct {
+	Model         string  `json:"model"`
+	Samples       int     `json:"samples"`
+	Failed        int     `json:"failed"`
+	Top1Agreement float64 `json:"top1_agreement"` // fraction of re-runs with unchanged top-1 label
+	MeanOverlap   float64 `json:"mean_overlap"`
+}
+
+// Evaluate re-runs up to limit of the most recent samples (all users) against modelName,
+// charging the inferences to adminID. It stops with ErrQuotaExceeded once that quota runs out.
+func (s *VisionSampleService) Evaluate(ctx context.Context, adminID uint, modelName string, limit int) (*VisionEvaluation, error) {
+	if _, ok := s.models.Get(modelName); !ok {
+		return nil, ErrVisionModelNotFound
+	}
+	samples, err := s.repo.ListRecent(ctx, limit)
+	if err != nil {
+		return nil, err
+	}
+	if modelName == "" {
+		modelName = s.models.DefaultModel()
+	}
+	eval := &VisionEvaluation{Model: modelName}
+	agreed, overlap := 0, 0
+	for i := range samples {
+		result, err := s.rerun(ctx, adminID, &samples[i], modelName)
+		if errors.Is(err, ErrQuotaExceeded) {
+			return nil, err
+		}
+		if err != nil {
+			eval.Failed++
+			continue
+		}
+		eval.Samples++
+		if !result.Comparison.Top1Changed {
+			agreed++
+		}
+		overlap += result.Comparison.Overlap
+	}
+	if eval.Samples > 0 {
+		eval.Top1Agreement = float64(agreed) / float64(eval.Samples)
+		eval.MeanOverlap = float64(overlap) / float64(eval.Samples)
+	}
+	return eval, nil
+}
+
+func compareVisionResults(before, after []vision.LabelScore) VisionComparison {
+	var cmp VisionComparison
+	if len(before) > 0 {
+		cmp.Top1Before = before[0].Label
+	}
+	if len(after) > 0 {
+		cmp.Top1After = after[0].Label
+	}
+	cmp.Top1Changed = cmp.Top1Before != cmp.Top1After
+
+	seen := make(map[int]struct{}, len(before))
+	for _, p := range before {
+		seen[p.Index] = struct{}{}
+	}
+	for _, p := range after {
+		if _, ok := seen[p.Index]; ok {
+			cmp.Overlap++
+		}
+	}
+	return cmp
+}
@@ -0,0 +1,193 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"gopherai-resume/internal/pkg/apperr"
+	"gopherai-resume/internal/vision"
+)
+
+var ErrVisionImageInvalid = apperr.BadRequest("unsupported or corrupt image")
+
+// VisionService classifies users' images for the classify route and the classify_image
+// chat tool alike: it answers from the result cache or the user's stored samples when it
+// can, meters the inferences it runs against the vision quotas and records classifications
+// in the user's activity timeline.
+type VisionService struct {
+	models   *vision.Registry
+	cache    vision.ResultCache
+	samples  *VisionSampleService
+	quota    *QuotaService
+	photos   *vision.PhotoQualityChecker
+	activity ActivityRecorder
+}
+
+// NewVisionService serves the models in registry; cache, samples and activity may be nil.
+func NewVisionService(
+	models *vision.Registry,
+	cache vision.ResultCache,
+	samples *VisionSampleService,
+	quota *QuotaService,
+	photos *vision.PhotoQualityChecker,
+	activity ActivityRecorder,
+) *VisionService {
+	return &VisionService{
+		models:   models,
+		cache:    cache,
+		samples:  samples,
+		quota:    quota,
+		photos:   photos,
+		activity: activity,
+	}
+}
+
+// ClassifyImageInput is one image to classify for a user.
+type ClassifyImageInput struct {
+	UserID uint
+	Image  []byte
+	// Model is the registered model to use; empty for the default.
+	Model   string
+	Options vision.ClassifyOptions
+	// NoCache skips the cached result and stored sample lookups; the fresh result still
+	// refreshes the cache.
+	NoCache bool
+	// Store keeps the image and result as a vision sample, with the user's consent, unless
+	// the result came from a stored sample. A failure to store it is only logged.
+	Store bool
+}
+
+// Classify returns the image's labels. A result found in the cache or among the user's
+// stored samples is returned as is; otherwise the inference and the image's pixels are
+// charged to the user's quota before the model runs and refunded if it fails. Failures
+// of the model itself are returned unwrapped. Each classification is recorded in the
+// user's activity timeline.
+func (s *VisionService) Classify(ctx context.Context, input ClassifyImageInput) (*vision.ClassifyResult, error) {
+	result, err := s.classify(ctx, input)
+	if err != nil {
+		return nil, err
+	}
+	if input.UserID == 0 {
+		return result, nil
+	}
+	if input.Store && s.samples != nil && !result.Duplicate {
+		sample, err := s.samples.Save(ctx, SaveVisionSampleInput{
+			UserID:  input.UserID,
+			Image:   input.Image,
+			Options: input.Options,
+			Result:  result,
+		})
+		if err != nil {
+			log.Printf("store vision sample failed: %v", err)
+		} else {
+			result.SampleID = sample.ID
+		}
+	}
+	summary := fmt.Sprintf("Classified an image with %s", result.Model.Name)
+	if len(result.Predictions) > 0 {
+		summary += fmt.Sprintf(": %s", result.Predictions[0].Label)
+	}
+	recordActivity(ctx, s.activity, input.UserID, ActivityVisionClassified, result.SampleID, summary)
+	return result, nil
+}
+
+// classify is Classify without storing the sample or recording the activity.
+func (s *VisionService) classify(ctx context.Context, input ClassifyImageInput) (*vision.ClassifyResult, error) {
+	classifier, ok := s.models.Get(input.Model)
+	if !ok {
+		return nil, ErrVisionModelNotFound
+	}
+	modelName := input.Model
+	if modelName == "" {
+		modelName = s.models.DefaultModel()
+	}
+	cacheKey := ""
+	if s.cache != nil {
+		cacheKey = vision.ResultCacheKey(input.Image, modelName, input.Options)
+		if !input.NoCache {
+			if cached, ok, err := s.cache.GetResult(ctx, cacheKey); err == nil && ok {
+				cached.Cached = true
+				return cached, nil
+			}
+		}
+	}
+	// The user's own stored sample of the image answers even without a result cache.
+	if !input.NoCache && s.samples != nil && input.UserID != 0 {
+		if info, err := classifier.Info(); err == nil {
+			stored, err := s.samples.FindClassified(ctx, input.UserID, input.Image, info, input.Options)
+			if err != nil {
+				log.Printf("look up stored vision sample failed: %v", err)
+			}
+			if stored != nil {
+				return stored, nil
+			}
+		}
+	}
+
+	charge, err := chargeVision(ctx, s.quota, input.UserID, input.Image)
+	if err != nil {
+		return nil, err
+	}
+	result, err := classifier.ClassifyWithOptions(input.Image, input.Options)
+	if err != nil {
+		charge.refund(ctx)
+		return nil, err
+	}
+	if s.cache != nil {
+		_ = s.cache.SetResult(ctx, cacheKey, result)
+	}
+	return result, nil
+}
+
+// CheckPhoto evaluates image as a resume headshot. It is metered like a classification.
+func (s *VisionService) CheckPhoto(ctx context.Context, userID uint, image []byte) (*vision.PhotoQualityReport, error) {
+	charge, err := chargeVision(ctx, s.quota, userID, image)
+	if err != nil {
+		return nil, err
+	}
+	report, err := s.photos.Check(image)
+	if err != nil {
+		charge.refund(ctx)
+		return nil, ErrVisionImageInvalid
+	}
+	return report, nil
+}
+
+// visionCharge is one inference taken from a user's vision quotas.
+type visionCharge struct {
+	quota   *QuotaService
+	userID  uint
+	amounts map[string]int64
+}
+
+// chargeVision takes one inference and the image's pixels from userID's daily vision
+// quotas, reading only the image header. A zero userID is not metered.
+func chargeVision(ctx context.Context, quota *QuotaService, userID uint, image []byte) (*visionCharge, error) {
+	width, height, err := vision.ImageDimensions(image)
+	if err != nil {
+		return nil, ErrVisionImageInvalid
+	}
+	charge := &visionCharge{quota: quota, userID: userID}
+	if userID == 0 {
+		return charge, nil
+	}
+	charge.amounts = map[string]int64{
+		QuotaVisionInferences: 1,
+		QuotaVisionPixels:     int64(width) * int64(height),
+	}
+	if err := quota.ConsumeAll(ctx, userID, charge.amounts); err != nil {
+		return nil, err
+	}
+	return charge, nil
+}
+
+// refund gives the charge back after the inference failed.
+func (c *visionCharge) refund(ctx context.Context) {
+	if len(c.amounts) == 0 {
+		return
+	}
+	if err := c.quota.Refund(ctx, c.userID, c.amounts); err != nil {
+		log.Printf("refund vision quota for user %d failed: %v", c.userID, err)
+	}
+}
@@ -0,0 +1,159 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+
+	"gopherai-resume/internal/ai"
+	"gopherai-resume/internal/model"
+	"gopherai-resume/internal/pkg/apperr"
+)
+
+// maxAllowlistEntries bounds each list of a workspace's model allowlist.
+const maxAllowlistEntries = 50
+
+var (
+	ErrWorkspaceModelNotAllowed = apperr.New(http.StatusForbidden, apperr.CodeForbidden, "model not allowed")
+	ErrInvalidAllowlistURL      = apperr.BadRequest("invalid base url")
+)
+
+// ModelPolicy decides which models and providers a user may chat with, in chat, RAG asks,
+// model comparisons, the chat proxy and every other feature that completes on a user's behalf.
+type ModelPolicy interface {
+	// CheckModel returns an error when the user may not use modelName at baseURL. baseURL
+	// is empty for the configured provider; callers pass a foreign baseURL only with the
+	// user's own key, never the server's.
+	CheckModel(ctx context.Context, userID uint, baseURL, modelName string) error
+}
+
+var _ ModelPolicy = (*WorkspaceService)(nil)
+
+// ModelAllowlist is what a workspace's members may chat with. An empty list does not
+// restrict: no Models allows every model, no BaseURLs every provider.
+type ModelAllowlist struct {
+	Models   []string `json:"models"`
+	BaseURLs []string `json:"base_urls"`
+}
+
+// ModelAllowlist returns the workspace's allowlist to any member.
+func (s *WorkspaceService) ModelAllowlist(ctx context.Context, userID, workspaceID uint) (*ModelAllowlist, error) {
+	if _, err := s.Authorize(ctx, workspaceID, userID, WorkspaceMember); err != nil {
+		return nil, err
+	}
+	workspace, err := s.repo.GetByID(ctx, workspaceID)
+	if err != nil {
+		return nil, err
+	}
+	if workspace == nil {
+		return nil, ErrWorkspaceNotFound
+	}
+	allowlist, err := workspaceAllowlist(workspace)
+	if err != nil {
+		return nil, err
+	}
+	return &allowlist, nil
+}
+
+// SetModelAllowlist replaces the workspace's allowlist. Only owners and admins may set it.
+// Entries are trimmed and deduplicated; base URLs must be http or https and are compared
+// without a trailing slash.
+func (s *WorkspaceService) SetModelAllowlist(ctx context.Context, userID, workspaceID uint, allowlist ModelAllowlist) (*ModelAllowlist, error) {
+	if _, err := s.Authorize(ctx, workspaceID, userID, WorkspaceAdmin); err != nil {
+		return nil, err
+	}
+	models := normalizeAllowlist(allowlist.Models, func(m string) string { return m })
+	baseURLs := normalizeAllowlist(allowlist.BaseURLs, normalizeBaseURL)
+	if len(models) > maxAllowlistEntries || len(baseURLs) > maxAllowlistEntries {
+		return nil, ErrInvalidInput.Withf("at most %d models and %d base urls", maxAllowlistEntries, maxAllowlistEntries)
+	}
+	for _, raw := range baseURLs {
+		u, err := url.Parse(raw)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return nil, ErrInvalidAllowlistURL.Withf("%s", raw)
+		}
+	}
+	encodedModels, err := json.Marshal(models)
+	if err != nil {
+		return nil, err
+	}
+	encodedURLs, err := json.Marshal(baseURLs)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.UpdateModelAllowlist(ctx, workspaceID, string(encodedModels), string(encodedURLs)); err != nil {
+		return nil, err
+	}
+	return &ModelAllowlist{Models: models, BaseURLs: baseURLs}, nil
+}
+
+// CheckModel enforces the allowlists of every workspace the user has joined, so a member
+// of several must stay within all of them; invitations not yet accepted do not count. The
+// configured provider is always allowed; its models are not. An allowlist that cannot be
+// read denies everything rather than lifting the restriction.
+func (s *WorkspaceService) CheckModel(ctx context.Context, userID uint, baseURL, modelName string) error {
+	workspaces, err := s.repo.ListByUserID(ctx, userID, MemberActive)
+	if err != nil {
+		return err
+	}
+	baseURL = normalizeBaseURL(baseURL)
+	for i := range workspaces {
+		allowlist, err := workspaceAllowlist(&workspaces[i])
+		if err != nil {
+			return err
+		}
+		if len(allowlist.Models) > 0 && !slices.Contains(allowlist.Models, modelName) {
+			return ErrWorkspaceModelNotAllowed.Withf("workspace %q does not allow model %q", workspaces[i].Name, modelName)
+		}
+		if baseURL != "" && len(allowlist.BaseURLs) > 0 && !slices.ContainsFunc(allowlist.BaseURLs, func(u string) bool {
+			return strings.EqualFold(u, baseURL)
+		}) {
+			return ErrWorkspaceModelNotAllowed.Withf("workspace %q does not allow base url %s", workspaces[i].Name, baseURL)
+		}
+	}
+	return nil
+}
+
+// checkConfiguredModel applies policy to the configured chat model cfg, for the services
+// that complete with it on userID's behalf. A nil policy allows every model.
+func checkConfiguredModel(ctx context.Context, policy ModelPolicy, userID uint, cfg ai.ChatConfig) error {
+	if policy == nil {
+		return nil
+	}
+	return policy.CheckModel(ctx, userID, "", cfg.Model)
+}
+
+// workspaceAllowlist decodes the workspace's stored allowlist.
+func workspaceAllowlist(workspace *model.Workspace) (ModelAllowlist, error) {
+	allowlist := ModelAllowlist{Models: []string{}, BaseURLs: []string{}}
+	if workspace.AllowedModels != "" {
+		if err := json.Unmarshal([]byte(workspace.AllowedModels), &allowlist.Models); err != nil {
+			return ModelAllowlist{}, fmt.Errorf("decode allowed models of workspace %d failed: %w", workspace.ID, err)
+		}
+	}
+	if workspace.AllowedBaseURLs != "" {
+		if err := json.Unmarshal([]byte(workspace.AllowedBaseURLs), &allowlist.BaseURLs); err != nil {
+			return ModelAllowlist{}, fmt.Errorf("decode allowed base urls of workspace %d failed: %w", workspace.ID, err)
+		}
+	}
+	return allowlist, nil
+}
+
+func normalizeAllowlist(entries []string, normalize func(string) string) []string {
+	out := []string{}
+	for _, e := range entries {
+		e = normalize(strings.TrimSpace(e))
+		if e != "" && !slices.Contains(out, e) {
+			out = append(out, e)
+		}
+	}
+	return out
+}
+
+func normalizeBaseURL(baseURL string) string {
+	return strings.TrimRight(strings.TrimSpace(baseURL), "/")
+}
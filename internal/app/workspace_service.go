@@ -0,0 +1,270 @@
+package app
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"gopherai-resume/internal/model"
+	"gopherai-resume/internal/pkg/apperr"
+)
+
+var (
+	ErrWorkspaceNotFound     = apperr.NotFound(apperr.CodeWorkspaceNotFound, "workspace not found")
+	ErrWorkspaceForbidden    = apperr.New(http.StatusForbidden, apperr.CodeForbidden, "insufficient workspace role")
+	ErrInvalidWorkspaceRole  = apperr.BadRequest("invalid workspace role")
+	ErrWorkspaceMemberExists = apperr.Conflict(apperr.CodeMemberExists, "user is already a workspace member")
+	ErrUserNotFound          = apperr.NotFound(apperr.CodeUserNotFound, "user not found")
+	ErrInvitationNotFound    = apperr.NotFound(apperr.CodeWorkspaceNotFound, "workspace invitation not found")
+	ErrWorkspaceLeaveManaged = apperr.New(http.StatusForbidden, apperr.CodeForbidden,
+		"the workspace restricts its members' models; ask an owner or admin to remove you")
+)
+
+// Workspace roles, from most to least privileged. Owners and admins manage members;
+// recruiters also maintain shared resources; members have read access.
+const (
+	WorkspaceOwner     = "owner"
+	WorkspaceAdmin     = "admin"
+	WorkspaceRecruiter = "recruiter"
+	WorkspaceMember    = "member"
+)
+
+// Membership statuses. Members someone else adds are invited and must accept before they
+// get access or the workspace's model allowlist applies to them.
+const (
+	MemberInvited = "invited"
+	MemberActive  = "active"
+)
+
+var workspaceRoleRank = map[string]int{
+	WorkspaceOwner:     4,
+	WorkspaceAdmin:     3,
+	WorkspaceRecruiter: 2,
+	WorkspaceMember:    1,
+}
+
+// WorkspaceService manages workspaces and their membership.
+type WorkspaceService struct {
+	repo     WorkspaceRepository
+	userRepo UserRepository
+}
+
+func NewWorkspaceService(repo WorkspaceRepository, userRepo UserRepository) *WorkspaceService {
+	return &WorkspaceService{repo: repo, userRepo: userRepo}
+}
+
+// WorkspaceMemberView is a membership with the member's username.
+type WorkspaceMemberView struct {
+	model.WorkspaceMember
+	Username string `json:"username"`
+}
+
+// Authorize returns the user's membership when their role is at least minRole. Non-members
+// get ErrWorkspaceNotFound so workspace IDs are not disclosed. Admins are let in at member
+// level (read access) without a membership; see authorizeWorkspace.
+func (s *WorkspaceService) Authorize(ctx context.Context, workspaceID, userID uint, minRole string) (*model.WorkspaceMember, error) {
+	if workspaceID == 0 || userID == 0 {
+		return nil, ErrInvalidInput
+	}
+	return authorizeWorkspace(ctx, s.repo, workspaceID, userID, minRole)
+}
+
+// Create makes a workspace with the user as its owner.
+func (s *WorkspaceService) Create(ctx context.Context, userID uint, name string) (*model.Workspace, error) {
+	name = strings.TrimSpace(name)
+	if userID == 0 || name == "" {
+		return nil, ErrInvalidInput
+	}
+	workspace := &model.Workspace{Name: name, OwnerID: userID}
+	if err := s.repo.Create(ctx, workspace, &model.WorkspaceMember{UserID: userID, Role: WorkspaceOwner, Status: MemberActive}); err != nil {
+		return nil, err
+	}
+	return workspace, nil
+}
+
+func (s *WorkspaceService) List(ctx context.Context, userID uint) ([]model.Workspace, error) {
+	if userID == 0 {
+		return nil, ErrInvalidInput
+	}
+	return s.repo.ListByUserID(ctx, userID, MemberActive)
+}
+
+// Invitations lists the workspaces the user has been invited to and not yet joined.
+func (s *WorkspaceService) Invitations(ctx context.Context, userID uint) ([]model.Workspace, error) {
+	if userID == 0 {
+		return nil, ErrInvalidInput
+	}
+	return s.repo.ListByUserID(ctx, userID, MemberInvited)
+}
+
+// AcceptInvitation makes the user's invitation to the workspace an active membership. An
+// invitation is declined by the user removing themselves with RemoveMember.
+func (s *WorkspaceService) AcceptInvitation(ctx context.Context, userID, workspaceID uint) (*model.WorkspaceMember, error) {
+	if userID == 0 || workspaceID == 0 {
+		return nil, ErrInvalidInput
+	}
+	member, err := s.repo.GetMember(ctx, workspaceID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if member == nil || member.Status != MemberInvited {
+		return nil, ErrInvitationNotFound
+	}
+	member.Status = MemberActive
+	if err := s.repo.UpdateMember(ctx, member); err != nil {
+		return nil, err
+	}
+	return member, nil
+}
+
+func (s *WorkspaceService) ListMembers(ctx context.Context, userID, workspaceID uint) ([]WorkspaceMemberView, error) {
+	if _, err := s.Authorize(ctx, workspaceID, userID, WorkspaceMember); err != nil {
+		return nil, err
+	}
+	members, err := s.repo.ListMembers(ctx, workspaceID)
+	if err != nil {
+		return nil, err
+	}
+	views := make([]WorkspaceMemberView, 0, len(members))
+	for _, m := range members {
+		view := WorkspaceMemberView{WorkspaceMember: m}
+		if user, err := s.userRepo.GetByID(ctx, m.UserID); err != nil {
+			return nil, err
+		} else if user != nil {
+			view.Username = user.Username
+		}
+		views = append(views, view)
+	}
+	return views, nil
+}
+
+// AddMember invites a user by username; they become a member once they accept. Only
+// owners and admins may invite, and only the owner may grant the admin role. The owner
+// role cannot be granted.
+func (s *WorkspaceService) AddMember(ctx context.Context, userID, workspaceID uint, username, role string) (*model.WorkspaceMember, error) {
+	username = strings.TrimSpace(username)
+	if username == "" {
+		return nil, ErrInvalidInput
+	}
+	actor, err := s.Authorize(ctx, workspaceID, userID, WorkspaceAdmin)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkGrantableRole(actor, role); err != nil {
+		return nil, err
+	}
+	user, err := s.userRepo.GetByUsername(ctx, username)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, ErrUserNotFound
+	}
+	existing, err := s.repo.GetMember(ctx, workspaceID, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		return nil, ErrWorkspaceMemberExists
+	}
+	member := &model.WorkspaceMember{WorkspaceID: workspaceID, UserID: user.ID, Role: role, Status: MemberInvited}
+	if err := s.repo.AddMember(ctx, member); err != nil {
+		return nil, err
+	}
+	return member, nil
+}
+
+// UpdateMemberRole changes a member's role under the same rules as AddMember.
+// The owner's role cannot be changed.
+func (s *WorkspaceService) UpdateMemberRole(ctx context.Context, userID, workspaceID, memberUserID uint, role string) (*model.WorkspaceMember, error) {
+	actor, err := s.Authorize(ctx, workspaceID, userID, WorkspaceAdmin)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkGrantableRole(actor, role); err != nil {
+		return nil, err
+	}
+	member, err := s.repo.GetMember(ctx, workspaceID, memberUserID)
+	if err != nil {
+		return nil, err
+	}
+	if member == nil {
+		return nil, ErrUserNotFound
+	}
+	if member.Role == WorkspaceOwner || (member.Role == WorkspaceAdmin && actor.Role != WorkspaceOwner) {
+		return nil, ErrWorkspaceForbidden
+	}
+	member.Role = role
+	if err := s.repo.UpdateMember(ctx, member); err != nil {
+		return nil, err
+	}
+	return member, nil
+}
+
+// RemoveMember removes a member or withdraws an invitation. Invited users may decline, and
+// members may leave unless the workspace restricts their models, which binds them until
+// an owner or admin removes them. Otherwise the rules of UpdateMemberRole apply. The owner
+// cannot be removed.
+func (s *WorkspaceService) RemoveMember(ctx context.Context, userID, workspaceID, memberUserID uint) error {
+	if userID == memberUserID {
+		return s.leave(ctx, userID, workspaceID)
+	}
+	actor, err := s.Authorize(ctx, workspaceID, userID, WorkspaceAdmin)
+	if err != nil {
+		return err
+	}
+	member, err := s.repo.GetMember(ctx, workspaceID, memberUserID)
+	if err != nil {
+		return err
+	}
+	if member == nil {
+		return ErrUserNotFound
+	}
+	if member.Role == WorkspaceOwner || (member.Role == WorkspaceAdmin && actor.Role != WorkspaceOwner) {
+		return ErrWorkspaceForbidden
+	}
+	return s.repo.RemoveMember(ctx, workspaceID, memberUserID)
+}
+
+// leave removes the user's own membership or invitation; see RemoveMember.
+func (s *WorkspaceService) leave(ctx context.Context, userID, workspaceID uint) error {
+	if userID == 0 || workspaceID == 0 {
+		return ErrInvalidInput
+	}
+	member, err := s.repo.GetMember(ctx, workspaceID, userID)
+	if err != nil {
+		return err
+	}
+	if member == nil {
+		return ErrWorkspaceNotFound
+	}
+	if member.Role == WorkspaceOwner {
+		return ErrWorkspaceForbidden
+	}
+	if member.Status == MemberActive {
+		workspace, err := s.repo.GetByID(ctx, workspaceID)
+		if err != nil {
+			return err
+		}
+		if workspace != nil {
+			allowlist, err := workspaceAllowlist(workspace)
+			if err != nil {
+				return err
+			}
+			if len(allowlist.Models) > 0 || len(allowlist.BaseURLs) > 0 {
+				return ErrWorkspaceLeaveManaged
+			}
+		}
+	}
+	return s.repo.RemoveMember(ctx, workspaceID, userID)
+}
+
+func checkGrantableRole(actor *model.WorkspaceMember, role string) error {
+	if _, ok := workspaceRoleRank[role]; !ok || role == WorkspaceOwner {
+		return ErrInvalidWorkspaceRole
+	}
+	if role == WorkspaceAdmin && actor.Role != WorkspaceOwner {
+		return ErrWorkspaceForbidden
+	}
+	return nil
+}
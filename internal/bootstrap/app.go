@@ -3,27 +3,78 @@ package bootstrap
 import (
 	"context"
 	"fmt"
+	"log"
+	"path/filepath"
+	"strings"
 	"time"
 
-	amqp "github.com/rabbitmq/amqp091-go"
 	"github.com/redis/go-redis/v9"
 	"gorm.io/gorm"
 
+	"gopherai-resume/internal/ai"
+	"gopherai-resume/internal/cache"
 	"gopherai-resume/internal/config"
 	"gopherai-resume/internal/model"
+	"gopherai-resume/internal/pkg/opsflags"
+	"gopherai-resume/internal/platform/mailer"
 	mysqlClient "gopherai-resume/internal/platform/mysql"
 	rabbitmqClient "gopherai-resume/internal/platform/rabbitmq"
 	redisClient "gopherai-resume/internal/platform/redis"
+	"gopherai-resume/internal/rag"
 	"gopherai-resume/internal/repository"
+	"gopherai-resume/internal/storage"
 	"gopherai-resume/internal/worker"
 )
 
 type App struct {
-	Config        *config.Config
-	MySQL         *gorm.DB
+	Config *config.Config
+	MySQL  *gorm.DB
+	Repos  *Repositories
+	// Redis is nil when disabled by config; MQ and the queue workers likewise. Either may
+	// be unavailable at any time, see Dependencies.
 	Redis         *redis.Client
-	MQConn        *amqp.Connection
+	MQ            *rabbitmqClient.Connector
 	MessageWorker *worker.MessagePersistWorker
+	EmbedWorker   *worker.MessageEmbedWorker
+	// ReportWorker is started by the HTTP layer once the report service exists; nil when
+	// no report queue is configured, in which case ReportPublisher is nil too.
+	ReportWorker    *worker.ScreeningReportWorker
+	ReportPublisher *rabbitmqClient.ReportPublisher
+	// IngestWorker and IngestPublisher are the same for asynchronous RAG ingests.
+	IngestWorker    *worker.RAGIngestWorker
+	IngestPublisher *rabbitmqClient.IngestPublisher
+	// NotificationWorker delivers the email outbox; the HTTP layer starts it with the services.
+	NotificationWorker *worker.NotificationWorker
+	// ScheduleWorker sends scheduled chat messages; the HTTP layer starts it with the chat service.
+	ScheduleWorker *worker.ChatScheduleWorker
+	// SummaryWorker summarizes chat history that outgrew the prompt; the HTTP layer starts it
+	// with the chat service.
+	SummaryWorker *worker.ChatSummaryWorker
+	// ActivityPruneWorker deletes activity events past their retention; nil when they are
+	// kept forever. The HTTP layer starts it with the activity service.
+	ActivityPruneWorker *worker.ActivityPruneWorker
+	// ShadowWorker embeds chunks with the shadow embedding model; nil when none is configured.
+	ShadowWorker *worker.ShadowEmbedWorker
+	// Ops holds the runtime-adjustable log level and debug toggles.
+	Ops *opsflags.Store
+
+	// Embedder and EmbeddingConfig are shared by the HTTP services and the message worker.
+	Embedder        ai.Embedder
+	EmbeddingConfig ai.EmbeddingConfig
+	LLMClient       *ai.OpenAICompatibleClient
+	// ProviderHeaders go with every request to the configured LLM provider.
+	ProviderHeaders ai.ProviderHeaders
+	// WriteRetry retries hot-path writes, such as chat messages, on transient MySQL errors.
+	WriteRetry  mysqlClient.RetryPolicy
+	ObjectStore storage.ObjectStore
+	// VectorStore searches chunk embeddings; nil with the default "mysql" backend.
+	VectorStore rag.VectorStore
+	Mailer      mailer.Mailer
+
+	// Dependencies tracks which external services are reachable; a background monitor
+	// keeps it current and reconnects RabbitMQ.
+	Dependencies *Dependencies
+	stopMonitor  context.CancelFunc
 
 	StartedAt time.Time
 }
@@ -33,46 +84,292 @@ func New(ctx context.Context) (*App, error) {
 	if err != nil {
 		return nil, fmt.Errorf("load config failed: %w", err)
 	}
+	files := "none, defaults and environment only"
+	if len(cfg.Files()) > 0 {
+		files = strings.Join(cfg.Files(), ", ")
+	}
+	for _, problem := range cfg.SecretProblems() {
+		log.Printf("config warning: %s", problem)
+	}
+	log.Printf("effective config (env %q, files: %s):\n%s", cfg.App.Env, files, cfg.Redacted())
+
+	deps := newDependencies()
+	deps.register(DependencyMySQL, true, true)
+	deps.register(DependencyRedis, false, cfg.Redis.Enabled)
+	deps.register(DependencyRabbitMQ, false, cfg.RabbitMQ.Enabled)
+	deps.register(DependencyVision, false, cfg.Vision.Enabled)
+
+	var redisCli *redis.Client
+	if cfg.Redis.Enabled {
+		redisCli = redisClient.NewClient(cfg.Redis.Addr, cfg.Redis.Password, cfg.Redis.DB)
+	}
+	// The ops flags come first so that SQL logging covers the migrations too.
+	var opsBackend opsflags.Backend
+	if redisCli != nil {
+		opsBackend = cache.NewOpsFlagsBackend(redisCli)
+	}
+	ops := opsflags.NewStore(opsflags.Flags{
+		LogLevel:            cfg.Ops.LogLevel,
+		SQLLogging:          cfg.Ops.SQLLogging,
+		LLMPayloadLogging:   cfg.Ops.LLMPayloadLogging,
+		SSEHeartbeatSeconds: cfg.Ops.SSEHeartbeatSeconds,
+	}, opsBackend)
 
 	mysqlDB, err := mysqlClient.New(ctx, cfg.MySQLDSN())
 	if err != nil {
 		return nil, err
 	}
+	// Chunks must have distinct positions before their unique index is created.
+	if err := repository.RenumberChunkIndexes(ctx, mysqlDB); err != nil {
+		return nil, err
+	}
 	if err := mysqlDB.AutoMigrate(
-		&model.User{}, &model.Session{}, &model.Message{},
-		&model.RAGSession{}, &model.RAGDocument{}, &model.RAGChunk{},
+		&model.User{}, &model.Session{}, &model.Message{}, &model.MessageEmbedding{},
+		&model.RAGSession{}, &model.RAGDocument{}, &model.RAGChunk{}, &model.RAGMessage{}, &model.RAGShadowEmbedding{},
+		&model.RAGIngestEmbedding{}, &model.RAGShadowStat{},
+		&model.VisionSample{},
+		&model.Application{}, &model.ApplicationStatusChange{},
+		&model.ResumeBullet{}, &model.PortfolioAnalysis{}, &model.ResumeProfile{}, &model.ResumeSchema{},
+		&model.Workspace{}, &model.WorkspaceMember{},
+		&model.JobPosting{}, &model.JobPostingTag{}, &model.ScreeningResult{}, &model.ScreeningReport{},
+		&model.WorkspaceCandidate{},
+		&model.NotificationPreference{}, &model.EmailOutbox{}, &model.UserToken{},
+		&model.SessionShare{}, &model.ScheduledMessage{},
+		&model.DataExport{}, &model.ActivityEvent{},
 	); err != nil {
 		return nil, fmt.Errorf("auto migrate tables failed: %w", err)
 	}
 
-	redisCli, err := redisClient.New(ctx, cfg.Redis.Addr, cfg.Redis.Password, cfg.Redis.DB)
+	// Redis and RabbitMQ are probed below, once everything depending on them exists.
+	var mq *rabbitmqClient.Connector
+	if cfg.RabbitMQ.Enabled {
+		mq = rabbitmqClient.NewConnector(cfg.RabbitMQ.URL)
+	}
+
+	objectStore, err := storage.NewLocalStore(cfg.Storage.LocalDir)
 	if err != nil {
 		return nil, err
 	}
 
-	mqConn, err := rabbitmqClient.New(ctx, cfg.RabbitMQ.URL)
+	mail, err := mailer.New(
+		cfg.Mail.Provider,
+		cfg.Mail.SMTPHost,
+		cfg.Mail.SMTPPort,
+		cfg.Mail.SMTPUsername,
+		cfg.Mail.SMTPPassword,
+		cfg.Mail.From,
+		cfg.Mail.SESRegion,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("create mailer failed: %w", err)
+	}
+
+	limiter, err := providerLimiter(cfg)
 	if err != nil {
 		return nil, err
 	}
+	headers, err := providerHeaders(cfg)
+	if err != nil {
+		return nil, err
+	}
+	llmClient := ai.NewOpenAICompatibleClient(limiter)
+	embedder, embConfig := newEmbedder(cfg, llmClient, headers)
+
+	writeRetry := mysqlClient.RetryPolicy{
+		Attempts:  cfg.MySQL.WriteRetries,
+		BaseDelay: time.Duration(cfg.MySQL.RetryBackoffMs) * time.Millisecond,
+		MaxDelay:  5 * time.Second,
+	}
+	repos := newRepositories(mysqlDB, writeRetry)
+	vectorStore, err := rag.NewStore(rag.StoreConfig{
+		Backend:          cfg.RAG.VectorStore,
+		QdrantURL:        cfg.RAG.QdrantURL,
+		QdrantAPIKey:     cfg.RAG.QdrantAPIKey,
+		QdrantCollection: cfg.RAG.QdrantCollection,
+		Loader:           chunkLoader(repos.RAGChunks),
+		MemoryMaxPoints:  cfg.RAG.MemoryMaxChunks,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("create vector store failed: %w", err)
+	}
+	var messageWorker *worker.MessagePersistWorker
+	var embedWorker *worker.MessageEmbedWorker
+	var reportWorker *worker.ScreeningReportWorker
+	var reportPublisher *rabbitmqClient.ReportPublisher
+	var ingestWorker *worker.RAGIngestWorker
+	var ingestPublisher *rabbitmqClient.IngestPublisher
+	if mq != nil {
+		var embedPublisher worker.MessagePublisher
+		if cfg.RabbitMQ.MessageEmbedQueue != "" {
+			embedWorker = worker.NewMessageEmbedWorker(
+				mq,
+				worker.NewRetryingEmbeddingStore(repos.MessageEmbeddings, writeRetry),
+				embedder,
+				embConfig,
+				cfg.RabbitMQ.MessageEmbedQueue,
+			)
+			embedPublisher = rabbitmqClient.NewMessagePublisher(mq, cfg.RabbitMQ.MessageEmbedQueue)
+		}
+		messageWorker = worker.NewMessagePersistWorker(
+			mq,
+			worker.NewRetryingMessageStore(repos.Messages, writeRetry),
+			cfg.RabbitMQ.MessagePersistQueue,
+			embedPublisher,
+		)
+		if cfg.RabbitMQ.ScreeningReportQueue != "" {
+			reportWorker = worker.NewScreeningReportWorker(mq, cfg.RabbitMQ.ScreeningReportQueue)
+			reportPublisher = rabbitmqClient.NewReportPublisher(mq, cfg.RabbitMQ.ScreeningReportQueue)
+		}
+		if cfg.RabbitMQ.RAGIngestQueue != "" {
+			ingestWorker = worker.NewRAGIngestWorker(mq, cfg.RabbitMQ.RAGIngestQueue)
+			ingestPublisher = rabbitmqClient.NewIngestPublisher(mq, cfg.RabbitMQ.RAGIngestQueue)
+		}
+		// Every (re)connection starts the consumers on the new connection.
+		mq.OnConnect(func() {
+			if err := messageWorker.Restart(ctx); err != nil {
+				log.Printf("start message worker failed: %v", err)
+			}
+			if embedWorker != nil {
+				if err := embedWorker.Restart(ctx); err != nil {
+					log.Printf("start embed worker failed: %v", err)
+				}
+			}
+			if reportWorker != nil {
+				if err := reportWorker.Restart(ctx); err != nil {
+					log.Printf("start screening report worker failed: %v", err)
+				}
+			}
+			if ingestWorker != nil {
+				if err := ingestWorker.Restart(ctx); err != nil {
+					log.Printf("start rag ingest worker failed: %v", err)
+				}
+			}
+		})
+	}
 
-	messageRepo := repository.NewMessageRepository(mysqlDB)
-	messageWorker := worker.NewMessagePersistWorker(mqConn, messageRepo, cfg.RabbitMQ.MessagePersistQueue)
-	if err := messageWorker.Start(ctx); err != nil {
-		return nil, fmt.Errorf("start message worker failed: %w", err)
+	app := &App{
+		Config:          cfg,
+		MySQL:           mysqlDB,
+		Repos:           repos,
+		Redis:           redisCli,
+		MQ:              mq,
+		MessageWorker:   messageWorker,
+		EmbedWorker:     embedWorker,
+		ReportWorker:    reportWorker,
+		ReportPublisher: reportPublisher,
+		IngestWorker:    ingestWorker,
+		IngestPublisher: ingestPublisher,
+		NotificationWorker: worker.NewNotificationWorker(
+			time.Duration(cfg.Mail.OutboxPollSeconds) * time.Second,
+		),
+		ScheduleWorker: worker.NewChatScheduleWorker(
+			time.Duration(cfg.LLM.SchedulePollSeconds) * time.Second,
+		),
+		SummaryWorker: worker.NewChatSummaryWorker(10 * time.Second),
+
+		Embedder:        embedder,
+		EmbeddingConfig: embConfig,
+		LLMClient:       llmClient,
+		ProviderHeaders: headers,
+		WriteRetry:      writeRetry,
+		ObjectStore:     objectStore,
+		VectorStore:     vectorStore,
+		Mailer:          mail,
+		Dependencies:    deps,
+		Ops:             ops,
+		StartedAt:       time.Now(),
+	}
+
+	if cfg.RAG.ShadowEmbeddingModel != "" {
+		app.ShadowWorker = worker.NewShadowEmbedWorker(time.Duration(cfg.RAG.ShadowEmbedIntervalSeconds) * time.Second)
+	}
+	if cfg.App.ActivityRetentionDays > 0 {
+		app.ActivityPruneWorker = worker.NewActivityPruneWorker(time.Hour)
+	}
+
+	app.probe(ctx)
+	if app.Dependencies.Degraded() {
+		var down []string
+		for _, status := range app.Dependencies.Statuses() {
+			if status.Enabled && !status.Available {
+				down = append(down, fmt.Sprintf("%s (%s)", status.Name, status.Error))
+			}
+		}
+		if !cfg.App.DegradedStart {
+			_ = app.Close()
+			return nil, fmt.Errorf("optional dependencies unavailable and degraded start is off: %s", strings.Join(down, ", "))
+		}
+		log.Printf("starting degraded, retrying every %ds: %s", cfg.App.DependencyRetrySeconds, strings.Join(down, ", "))
+	}
+	monitorCtx, stopMonitor := context.WithCancel(context.Background())
+	app.stopMonitor = stopMonitor
+	go app.monitorDependencies(monitorCtx, dependencyRetryInterval(cfg.App.DependencyRetrySeconds))
+	go ops.Watch(monitorCtx, time.Duration(cfg.Ops.SyncSeconds)*time.Second)
+	return app, nil
+}
+
+func dependencyRetryInterval(seconds int) time.Duration {
+	if seconds <= 0 {
+		return 15 * time.Second
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+// providerLimiter builds the client-side provider rate limits from config.
+func providerLimiter(cfg *config.Config) (*ai.ProviderLimiter, error) {
+	providers := make([]ai.ProviderRateLimit, 0, len(cfg.LLM.ProviderLimits))
+	for _, p := range cfg.LLM.ProviderLimits {
+		providers = append(providers, ai.ProviderRateLimit{
+			BaseURL:   p.BaseURL,
+			RateLimit: ai.RateLimit{RequestsPerMinute: p.RequestsPerMinute, TokensPerMinute: p.TokensPerMinute},
+		})
 	}
+	return ai.NewProviderLimiter(
+		ai.RateLimit{RequestsPerMinute: cfg.LLM.ProviderRequestsPerMinute, TokensPerMinute: cfg.LLM.ProviderTokensPerMinute},
+		providers,
+		time.Duration(cfg.LLM.ProviderQueueWaitSeconds)*time.Second,
+	)
+}
+
+func providerHeaders(cfg *config.Config) (ai.ProviderHeaders, error) {
+	profiles := make([]ai.ProviderHeaderProfile, 0, len(cfg.LLM.ProviderHeaders))
+	for _, p := range cfg.LLM.ProviderHeaders {
+		profiles = append(profiles, ai.ProviderHeaderProfile{
+			BaseURL:   p.BaseURL,
+			RequestID: p.RequestIDHeader,
+			Extra:     p.ExtraHeaders,
+		})
+	}
+	return ai.NewProviderHeaders(cfg.LLM.RequestIDHeader, cfg.LLM.ExtraHeaders, profiles)
+}
 
-	return &App{
-		Config:        cfg,
-		MySQL:         mysqlDB,
-		Redis:         redisCli,
-		MQConn:        mqConn,
-		MessageWorker: messageWorker,
-		StartedAt:     time.Now(),
-	}, nil
+// newEmbedder selects the embedding provider from config.
+func newEmbedder(cfg *config.Config, llmClient *ai.OpenAICompatibleClient, headers ai.ProviderHeaders) (ai.Embedder, ai.EmbeddingConfig) {
+	embConfig := ai.EmbeddingConfig{
+		BaseURL: cfg.LLM.BaseURL,
+		APIKey:  cfg.LLM.APIKey,
+		Model:   cfg.LLM.EmbeddingModel,
+		Headers: headers,
+	}
+	if cfg.Embedding.Provider != "onnx" {
+		return llmClient, embConfig
+	}
+	embedder := ai.NewONNXEmbedder(
+		cfg.Embedding.ONNXModelPath,
+		cfg.Embedding.ONNXVocabPath,
+		cfg.Vision.ONNXSharedLibPath,
+		cfg.Embedding.ONNXMaxTokens,
+	)
+	// Distinct model name keeps cached vectors from different providers apart.
+	embConfig.Model = "onnx:" + filepath.Base(cfg.Embedding.ONNXModelPath)
+	return embedder, embConfig
 }
 
 func (a *App) Close() error {
 	var closeErr error
+	if a.stopMonitor != nil {
+		a.stopMonitor()
+	}
 	if a.Redis != nil {
 		if err := a.Redis.Close(); err != nil {
 			closeErr = err
@@ -81,8 +378,32 @@ func (a *App) Close() error {
 	if a.MessageWorker != nil {
 		a.MessageWorker.Close()
 	}
-	if a.MQConn != nil {
-		if err := a.MQConn.Close(); err != nil {
+	if a.EmbedWorker != nil {
+		a.EmbedWorker.Close()
+	}
+	if a.ReportWorker != nil {
+		a.ReportWorker.Close()
+	}
+	if a.IngestWorker != nil {
+		a.IngestWorker.Close()
+	}
+	if a.NotificationWorker != nil {
+		a.NotificationWorker.Close()
+	}
+	if a.ScheduleWorker != nil {
+		a.ScheduleWorker.Close()
+	}
+	if a.SummaryWorker != nil {
+		a.SummaryWorker.Close()
+	}
+	if a.ShadowWorker != nil {
+		a.ShadowWorker.Close()
+	}
+	if a.ActivityPruneWorker != nil {
+		a.ActivityPruneWorker.Close()
+	}
+	if a.MQ != nil {
+		if err := a.MQ.Close(); err != nil {
 			closeErr = err
 		}
 	}
@@ -0,0 +1,178 @@
+package bootstrap
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	redisClient "gopherai-resume/internal/platform/redis"
+)
+
+// Dependency names used in the registry and in /healthz and /readyz.
+const (
+	DependencyMySQL    = "mysql"
+	DependencyRedis    = "redis"
+	DependencyRabbitMQ = "rabbitmq"
+	DependencyVision   = "vision"
+)
+
+var errDependencyDisabled = errors.New("disabled by config")
+
+// DependencyStatus is the last known state of one external dependency.
+type DependencyStatus struct {
+	Name string `json:"name"`
+	// Required dependencies make the server unready when down; optional ones only turn
+	// off the features that need them.
+	Required  bool      `json:"required"`
+	Enabled   bool      `json:"enabled"`
+	Available bool      `json:"available"`
+	Error     string    `json:"error,omitempty"`
+	CheckedAt time.Time `json:"checked_at"`
+}
+
+// Dependencies records dependency states as the startup probes and the background monitor
+// see them. It is safe for concurrent use.
+type Dependencies struct {
+	mu       sync.RWMutex
+	order    []string
+	statuses map[string]*DependencyStatus
+}
+
+func newDependencies() *Dependencies {
+	return &Dependencies{statuses: make(map[string]*DependencyStatus)}
+}
+
+func (d *Dependencies) register(name string, required, enabled bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.order = append(d.order, name)
+	status := &DependencyStatus{Name: name, Required: required, Enabled: enabled, CheckedAt: time.Now()}
+	if !enabled {
+		status.Error = errDependencyDisabled.Error()
+	}
+	d.statuses[name] = status
+}
+
+// set records a probe result and logs when the dependency goes down or comes back.
+func (d *Dependencies) set(name string, err error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	status, ok := d.statuses[name]
+	if !ok || !status.Enabled {
+		return
+	}
+	wasAvailable, lastError := status.Available, status.Error
+	status.Available = err == nil
+	status.Error = ""
+	if err != nil {
+		status.Error = err.Error()
+	}
+	status.CheckedAt = time.Now()
+
+	switch {
+	case err != nil && status.Error != lastError:
+		log.Printf("dependency %s unavailable: %v", name, err)
+	case err == nil && !wasAvailable:
+		log.Printf("dependency %s available", name)
+	}
+}
+
+// Available reports whether name is enabled and its last probe succeeded.
+func (d *Dependencies) Available(name string) bool {
+	if d == nil {
+		return false
+	}
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	status, ok := d.statuses[name]
+	return ok && status.Enabled && status.Available
+}
+
+// Statuses returns a copy of every dependency's state in registration order.
+func (d *Dependencies) Statuses() []DependencyStatus {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	out := make([]DependencyStatus, 0, len(d.order))
+	for _, name := range d.order {
+		out = append(out, *d.statuses[name])
+	}
+	return out
+}
+
+// Ready reports whether every required dependency is available.
+func (d *Dependencies) Ready() bool {
+	for _, status := range d.Statuses() {
+		if status.Required && !status.Available {
+			return false
+		}
+	}
+	return true
+}
+
+// Degraded reports whether an enabled optional dependency is down.
+func (d *Dependencies) Degraded() bool {
+	for _, status := range d.Statuses() {
+		if !status.Required && status.Enabled && !status.Available {
+			return true
+		}
+	}
+	return false
+}
+
+// probe checks every enabled dependency once. A RabbitMQ reconnect restarts the queue
+// workers through the connector's OnConnect callbacks.
+func (a *App) probe(ctx context.Context) {
+	if sqlDB, err := a.MySQL.DB(); err != nil {
+		a.Dependencies.set(DependencyMySQL, err)
+	} else {
+		pingCtx, cancel := context.WithTimeout(ctx, 3*time.Second)
+		a.Dependencies.set(DependencyMySQL, sqlDB.PingContext(pingCtx))
+		cancel()
+	}
+	if a.Redis != nil {
+		a.Dependencies.set(DependencyRedis, redisClient.Ping(ctx, a.Redis))
+	}
+	if a.MQ != nil {
+		a.Dependencies.set(DependencyRabbitMQ, a.MQ.Connect(ctx))
+	}
+	if a.Config.Vision.Enabled {
+		a.Dependencies.set(DependencyVision, a.checkVisionModels())
+	}
+}
+
+// checkVisionModels checks that the configured model, label and ONNX Runtime files exist.
+// Loading them stays lazy, on the first classification.
+func (a *App) checkVisionModels() error {
+	cfg := a.Config.Vision
+	paths := []string{cfg.ModelPath, cfg.LabelsPath}
+	for _, m := range cfg.Models {
+		paths = append(paths, m.ModelPath, m.LabelsPath)
+	}
+	if cfg.ONNXSharedLibPath != "" {
+		paths = append(paths, cfg.ONNXSharedLibPath)
+	}
+	for _, path := range paths {
+		if _, err := os.Stat(path); err != nil {
+			return fmt.Errorf("vision file %s: %w", path, err)
+		}
+	}
+	return nil
+}
+
+// monitorDependencies re-probes the dependencies every interval until ctx is done.
+func (a *App) monitorDependencies(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			a.probe(ctx)
+		}
+	}
+}
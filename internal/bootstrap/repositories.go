@@ -0,0 +1,87 @@
+package bootstrap
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+
+	appsvc "gopherai-resume/internal/app"
+	"gopherai-resume/internal/platform/mysql"
+	"gopherai-resume/internal/rag"
+	"gopherai-resume/internal/repository"
+)
+
+// Repositories holds the stores the services and workers use, typed as the app interfaces
+// so a different backend can be swapped in here.
+type Repositories struct {
+	Users               appsvc.UserRepository
+	UserTokens          appsvc.UserTokenRepository
+	Notifications       appsvc.NotificationRepository
+	Activity            appsvc.ActivityRepository
+	Sessions            appsvc.SessionRepository
+	SessionShares       appsvc.SessionShareRepository
+	Messages            appsvc.MessageRepository
+	MessageEmbeddings   appsvc.MessageEmbeddingRepository
+	RAGSessions         appsvc.RAGSessionRepository
+	RAGDocuments        appsvc.RAGDocumentRepository
+	RAGChunks           appsvc.RAGChunkRepository
+	RAGMessages         appsvc.RAGMessageRepository
+	RAGShadowEmbeddings appsvc.RAGShadowEmbeddingRepository
+	RAGIngestEmbeddings appsvc.RAGIngestEmbeddingRepository
+	ResumeBullets       appsvc.ResumeBulletRepository
+	ResumeProfiles      appsvc.ResumeProfileRepository
+	ResumeSchemas       appsvc.ResumeSchemaRepository
+	Applications        appsvc.ApplicationRepository
+	Workspaces          appsvc.WorkspaceRepository
+	WorkspaceCandidates appsvc.WorkspaceCandidateRepository
+	JobPostings         appsvc.JobPostingRepository
+	ScreeningReports    appsvc.ScreeningReportRepository
+	ScheduledMessages   appsvc.ScheduledMessageRepository
+	PortfolioAnalyses   appsvc.PortfolioAnalysisRepository
+	VisionSamples       appsvc.VisionSampleRepository
+	DataExports         appsvc.DataExportRepository
+}
+
+// newRepositories builds the GORM-backed repositories on db, retrying the hot-path writes
+// under retry.
+func newRepositories(db *gorm.DB, retry mysql.RetryPolicy) *Repositories {
+	return &Repositories{
+		Users:               repository.NewUserRepository(db),
+		UserTokens:          repository.NewUserTokenRepository(db),
+		Notifications:       repository.NewNotificationRepository(db),
+		Activity:            retryingActivityRepository{repository.NewActivityRepository(db), retry},
+		Sessions:            retryingSessionRepository{repository.NewSessionRepository(db), retry},
+		SessionShares:       repository.NewSessionShareRepository(db),
+		Messages:            repository.NewMessageRepository(db),
+		MessageEmbeddings:   repository.NewMessageEmbeddingRepository(db),
+		RAGSessions:         repository.NewRAGSessionRepository(db),
+		RAGDocuments:        retryingRAGDocumentRepository{repository.NewRAGDocumentRepository(db), retry},
+		RAGChunks:           retryingRAGChunkRepository{repository.NewRAGChunkRepository(db), retry},
+		RAGMessages:         retryingRAGMessageRepository{repository.NewRAGMessageRepository(db), retry},
+		RAGShadowEmbeddings: repository.NewRAGShadowEmbeddingRepository(db),
+		RAGIngestEmbeddings: repository.NewRAGIngestEmbeddingRepository(db),
+		ResumeBullets:       repository.NewResumeBulletRepository(db),
+		ResumeProfiles:      repository.NewResumeProfileRepository(db),
+		ResumeSchemas:       repository.NewResumeSchemaRepository(db),
+		Applications:        repository.NewApplicationRepository(db),
+		Workspaces:          repository.NewWorkspaceRepository(db),
+		WorkspaceCandidates: repository.NewWorkspaceCandidateRepository(db),
+		JobPostings:         repository.NewJobPostingRepository(db),
+		ScreeningReports:    repository.NewScreeningReportRepository(db),
+		ScheduledMessages:   repository.NewScheduledMessageRepository(db),
+		PortfolioAnalyses:   repository.NewPortfolioAnalysisRepository(db),
+		VisionSamples:       repository.NewVisionSampleRepository(db),
+		DataExports:         repository.NewDataExportRepository(db),
+	}
+}
+
+// chunkLoader loads the memory vector index from the chunks table.
+func chunkLoader(chunks appsvc.RAGChunkRepository) rag.Loader {
+	return func(ctx context.Context, documentIDs []uint) ([]rag.Point, error) {
+		list, err := chunks.ListByDocumentIDs(ctx, documentIDs)
+		if err != nil {
+			return nil, err
+		}
+		return rag.PointsFromChunks(list)
+	}
+}
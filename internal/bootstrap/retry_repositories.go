@@ -0,0 +1,87 @@
+package bootstrap
+
+import (
+	"context"
+	"time"
+
+	appsvc "gopherai-resume/internal/app"
+	"gopherai-resume/internal/model"
+	"gopherai-resume/internal/platform/mysql"
+	"gopherai-resume/internal/repository"
+)
+
+// The retrying repositories below retry the writes made while serving chat sends, RAG asks
+// and ingests when they fail with a transient MySQL error. Other methods pass through.
+
+type retryingSessionRepository struct {
+	appsvc.SessionRepository
+	retry mysql.RetryPolicy
+}
+
+func (r retryingSessionRepository) Create(ctx context.Context, session *model.Session) error {
+	return r.retry.Do(ctx, func() error { return r.SessionRepository.Create(ctx, session) })
+}
+
+func (r retryingSessionRepository) Update(ctx context.Context, session *model.Session) error {
+	return r.retry.Do(ctx, func() error { return r.SessionRepository.Update(ctx, session) })
+}
+
+func (r retryingSessionRepository) UpdateSummary(ctx context.Context, sessionID uint, summary string, untilID uint) error {
+	return r.retry.Do(ctx, func() error { return r.SessionRepository.UpdateSummary(ctx, sessionID, summary, untilID) })
+}
+
+func (r retryingSessionRepository) MarkSummaryPending(ctx context.Context, sessionID, beforeID uint) error {
+	return r.retry.Do(ctx, func() error { return r.SessionRepository.MarkSummaryPending(ctx, sessionID, beforeID) })
+}
+
+type retryingRAGMessageRepository struct {
+	appsvc.RAGMessageRepository
+	retry mysql.RetryPolicy
+}
+
+func (r retryingRAGMessageRepository) Create(ctx context.Context, message *model.RAGMessage) error {
+	return r.retry.Do(ctx, func() error { return r.RAGMessageRepository.Create(ctx, message) })
+}
+
+type retryingRAGDocumentRepository struct {
+	appsvc.RAGDocumentRepository
+	retry mysql.RetryPolicy
+}
+
+func (r retryingRAGDocumentRepository) Create(ctx context.Context, doc *model.RAGDocument, check *repository.StorageCheck) error {
+	return r.retry.Do(ctx, func() error { return r.RAGDocumentRepository.Create(ctx, doc, check) })
+}
+
+func (r retryingRAGDocumentRepository) UpdateChunkCount(ctx context.Context, id uint, count int) error {
+	return r.retry.Do(ctx, func() error { return r.RAGDocumentRepository.UpdateChunkCount(ctx, id, count) })
+}
+
+func (r retryingRAGDocumentRepository) UpdateStatus(ctx context.Context, id uint, status, errMsg string) error {
+	return r.retry.Do(ctx, func() error { return r.RAGDocumentRepository.UpdateStatus(ctx, id, status, errMsg) })
+}
+
+type retryingRAGChunkRepository struct {
+	appsvc.RAGChunkRepository
+	retry mysql.RetryPolicy
+}
+
+func (r retryingRAGChunkRepository) Create(ctx context.Context, chunk *model.RAGChunk) error {
+	return r.retry.Do(ctx, func() error { return r.RAGChunkRepository.Create(ctx, chunk) })
+}
+
+func (r retryingRAGChunkRepository) CreateBatch(ctx context.Context, chunks []model.RAGChunk, check *repository.StorageCheck) error {
+	return r.retry.Do(ctx, func() error { return r.RAGChunkRepository.CreateBatch(ctx, chunks, check) })
+}
+
+func (r retryingRAGChunkRepository) TouchAccessed(ctx context.Context, ids []uint, at time.Time) error {
+	return r.retry.Do(ctx, func() error { return r.RAGChunkRepository.TouchAccessed(ctx, ids, at) })
+}
+
+type retryingActivityRepository struct {
+	appsvc.ActivityRepository
+	retry mysql.RetryPolicy
+}
+
+func (r retryingActivityRepository) Create(ctx context.Context, event *model.ActivityEvent) error {
+	return r.retry.Do(ctx, func() error { return r.ActivityRepository.Create(ctx, event) })
+}
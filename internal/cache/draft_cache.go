@@ -0,0 +1,68 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	redisv9 "github.com/redis/go-redis/v9"
+
+	"gopherai-resume/internal/model"
+)
+
+// DraftCache stores one unsent chat draft per user and session. Saving a draft refreshes
+// its TTL, so only abandoned drafts expire.
+type DraftCache struct {
+	client *redisv9.Client
+	ttl    time.Duration
+}
+
+func NewDraftCache(client *redisv9.Client, ttl time.Duration) *DraftCache {
+	if ttl <= 0 {
+		ttl = 7 * 24 * time.Hour
+	}
+	return &DraftCache{
+		client: client,
+		ttl:    ttl,
+	}
+}
+
+// GetDraft returns nil when the session has no saved draft.
+func (c *DraftCache) GetDraft(ctx context.Context, userID, sessionID uint) (*model.ChatDraft, error) {
+	raw, err := c.client.Get(ctx, c.key(userID, sessionID)).Result()
+	if err == redisv9.Nil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("redis get draft failed: %w", err)
+	}
+
+	var draft model.ChatDraft
+	if err := json.Unmarshal([]byte(raw), &draft); err != nil {
+		return nil, fmt.Errorf("unmarshal cached draft failed: %w", err)
+	}
+	return &draft, nil
+}
+
+func (c *DraftCache) SetDraft(ctx context.Context, userID uint, draft *model.ChatDraft) error {
+	payload, err := json.Marshal(draft)
+	if err != nil {
+		return fmt.Errorf("marshal draft failed: %w", err)
+	}
+	if err := c.client.Set(ctx, c.key(userID, draft.SessionID), payload, c.ttl).Err(); err != nil {
+		return fmt.Errorf("redis set draft failed: %w", err)
+	}
+	return nil
+}
+
+func (c *DraftCache) DeleteDraft(ctx context.Context, userID, sessionID uint) error {
+	if err := c.client.Del(ctx, c.key(userID, sessionID)).Err(); err != nil {
+		return fmt.Errorf("redis delete draft failed: %w", err)
+	}
+	return nil
+}
+
+func (c *DraftCache) key(userID, sessionID uint) string {
+	return fmt.Sprintf("chat:draft:%d:%d", userID, sessionID)
+}
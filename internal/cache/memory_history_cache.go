@@ -0,0 +1,126 @@
+package cache
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"gopherai-resume/internal/model"
+)
+
+// memoryHistoryMaxSessions bounds how many session histories the in-memory cache keeps.
+const memoryHistoryMaxSessions = 1024
+
+// MemoryHistoryCache is an in-process HistoryCache for lite mode. It has the same TTL and
+// dirty-marker semantics as the Redis cache but is private to one server process, so it is
+// only correct with a single instance.
+type MemoryHistoryCache struct {
+	historyTTL     time.Duration
+	dirtyMarkerTTL time.Duration
+	now            func() time.Time
+
+	mu      sync.Mutex
+	history map[uint]memoryHistoryEntry
+	dirty   map[uint]time.Time // expiry per session
+}
+
+type memoryHistoryEntry struct {
+	messages  []model.Message
+	expiresAt time.Time
+}
+
+func NewMemoryHistoryCache(historyTTL, dirtyMarkerTTL time.Duration) *MemoryHistoryCache {
+	if historyTTL <= 0 {
+		historyTTL = 60 * time.Second
+	}
+	if dirtyMarkerTTL <= 0 {
+		dirtyMarkerTTL = 5 * time.Second
+	}
+	return &MemoryHistoryCache{
+		historyTTL:     historyTTL,
+		dirtyMarkerTTL: dirtyMarkerTTL,
+		now:            time.Now,
+		history:        make(map[uint]memoryHistoryEntry),
+		dirty:          make(map[uint]time.Time),
+	}
+}
+
+func (c *MemoryHistoryCache) GetHistory(ctx context.Context, sessionID uint) ([]model.Message, bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.history[sessionID]
+	if !ok {
+		return nil, false, nil
+	}
+	if !c.now().Before(entry.expiresAt) {
+		delete(c.history, sessionID)
+		return nil, false, nil
+	}
+	return append([]model.Message(nil), entry.messages...), true, nil
+}
+
+func (c *MemoryHistoryCache) SetHistory(ctx context.Context, sessionID uint, messages []model.Message) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := c.now()
+	if _, ok := c.history[sessionID]; !ok && len(c.history) >= memoryHistoryMaxSessions {
+		c.evict(now)
+	}
+	c.history[sessionID] = memoryHistoryEntry{
+		messages:  append([]model.Message(nil), messages...),
+		expiresAt: now.Add(c.historyTTL),
+	}
+	return nil
+}
+
+func (c *MemoryHistoryCache) DeleteHistory(ctx context.Context, sessionID uint) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.history, sessionID)
+	return nil
+}
+
+func (c *MemoryHistoryCache) MarkDirty(ctx context.Context, sessionID uint) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.dirty[sessionID] = c.now().Add(c.dirtyMarkerTTL)
+	return nil
+}
+
+func (c *MemoryHistoryCache) IsDirty(ctx context.Context, sessionID uint) (bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	expiresAt, ok := c.dirty[sessionID]
+	if !ok {
+		return false, nil
+	}
+	if !c.now().Before(expiresAt) {
+		delete(c.dirty, sessionID)
+		return false, nil
+	}
+	return true, nil
+}
+
+// evict drops expired entries and markers, then the entry closest to expiry if the cache
+// is still full. The caller holds mu.
+func (c *MemoryHistoryCache) evict(now time.Time) {
+	for id, expiresAt := range c.dirty {
+		if !now.Before(expiresAt) {
+			delete(c.dirty, id)
+		}
+	}
+	var oldestID uint
+	var oldest time.Time
+	for id, entry := range c.history {
+		if !now.Before(entry.expiresAt) {
+			delete(c.history, id)
+			continue
+		}
+		if oldest.IsZero() || entry.expiresAt.Before(oldest) {
+			oldestID, oldest = id, entry.expiresAt
+		}
+	}
+	if len(c.history) >= memoryHistoryMaxSessions {
+		delete(c.history, oldestID)
+	}
+}
@@ -0,0 +1,56 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	redisv9 "github.com/redis/go-redis/v9"
+
+	"gopherai-resume/internal/pkg/opsflags"
+)
+
+const opsFlagsKey = "ops:flags"
+
+// OpsFlagsBackend shares runtime ops flag changes between instances. The record has no TTL;
+// a reset deletes it.
+type OpsFlagsBackend struct {
+	client *redisv9.Client
+}
+
+func NewOpsFlagsBackend(client *redisv9.Client) *OpsFlagsBackend {
+	return &OpsFlagsBackend{client: client}
+}
+
+func (b *OpsFlagsBackend) Load(ctx context.Context) (*opsflags.Record, error) {
+	raw, err := b.client.Get(ctx, opsFlagsKey).Result()
+	if err == redisv9.Nil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("redis get ops flags failed: %w", err)
+	}
+	var record opsflags.Record
+	if err := json.Unmarshal([]byte(raw), &record); err != nil {
+		return nil, fmt.Errorf("unmarshal ops flags failed: %w", err)
+	}
+	return &record, nil
+}
+
+func (b *OpsFlagsBackend) Save(ctx context.Context, record opsflags.Record) error {
+	payload, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("marshal ops flags failed: %w", err)
+	}
+	if err := b.client.Set(ctx, opsFlagsKey, payload, 0).Err(); err != nil {
+		return fmt.Errorf("redis set ops flags failed: %w", err)
+	}
+	return nil
+}
+
+func (b *OpsFlagsBackend) Clear(ctx context.Context) error {
+	if err := b.client.Del(ctx, opsFlagsKey).Err(); err != nil {
+		return fmt.Errorf("redis delete ops flags failed: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,94 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	redisv9 "github.com/redis/go-redis/v9"
+)
+
+// RateLimiter counts requests per key in fixed windows in Redis, so the budget is shared by
+// every server instance.
+type RateLimiter struct {
+	client *redisv9.Client
+	now    func() time.Time
+}
+
+func NewRateLimiter(client *redisv9.Client) *RateLimiter {
+	return &RateLimiter{client: client, now: time.Now}
+}
+
+// Allow counts one request for key and reports whether it is within limit for the current
+// window, and the window's count so far. When it is not, the duration until the window
+// resets is returned.
+func (l *RateLimiter) Allow(ctx context.Context, key string, limit int, window time.Duration) (bool, int, time.Duration, error) {
+	now := l.now()
+	start := now.Truncate(window)
+	redisKey := fmt.Sprintf("ratelimit:%s:%d", key, start.Unix())
+	pipe := l.client.TxPipeline()
+	incr := pipe.Incr(ctx, redisKey)
+	pipe.Expire(ctx, redisKey, window)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return false, 0, 0, fmt.Errorf("redis incr rate limit failed: %w", err)
+	}
+	count := int(incr.Val())
+	if count > limit {
+		return false, count, start.Add(window).Sub(now), nil
+	}
+	return true, count, 0, nil
+}
+
+// memoryRateLimiterMaxKeys bounds how many windows the in-memory limiter tracks before it
+// drops expired ones.
+const memoryRateLimiterMaxKeys = 4096
+
+// MemoryRateLimiter is the in-process RateLimiter used without Redis. Each server instance
+// keeps its own counts.
+type MemoryRateLimiter struct {
+	now func() time.Time
+
+	mu      sync.Mutex
+	windows map[string]memoryRateWindow
+}
+
+type memoryRateWindow struct {
+	start time.Time
+	end   time.Time
+	count int
+}
+
+func NewMemoryRateLimiter() *MemoryRateLimiter {
+	return &MemoryRateLimiter{now: time.Now, windows: make(map[string]memoryRateWindow)}
+}
+
+func (l *MemoryRateLimiter) Allow(_ context.Context, key string, limit int, window time.Duration) (bool, int, time.Duration, error) {
+	now := l.now()
+	start := now.Truncate(window)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	w, ok := l.windows[key]
+	if !ok || !w.start.Equal(start) {
+		if !ok && len(l.windows) >= memoryRateLimiterMaxKeys {
+			l.evictLocked(now)
+		}
+		w = memoryRateWindow{start: start, end: start.Add(window)}
+	}
+	w.count++
+	l.windows[key] = w
+	if w.count > limit {
+		return false, w.count, w.end.Sub(now), nil
+	}
+	return true, w.count, 0, nil
+}
+
+// evictLocked drops the windows that have ended.
+func (l *MemoryRateLimiter) evictLocked(now time.Time) {
+	for key, w := range l.windows {
+		if !now.Before(w.end) {
+			delete(l.windows, key)
+		}
+	}
+}
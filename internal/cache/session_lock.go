@@ -0,0 +1,145 @@
+package cache
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	redisv9 "github.com/redis/go-redis/v9"
+)
+
+// sessionLockLease is how long a Redis session or job lock survives its holder dying.
+// Holders extend it while they run, so long completions keep the lock.
+const sessionLockLease = 30 * time.Second
+
+// Only the holder's token may extend or delete the lock.
+var (
+	extendSessionLock = redisv9.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("PEXPIRE", KEYS[1], ARGV[2])
+end
+return 0`)
+	releaseSessionLock = redisv9.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0`)
+)
+
+// SessionLock is a per-chat-session mutex in Redis, shared by every server instance.
+type SessionLock struct {
+	client *redisv9.Client
+}
+
+func NewSessionLock(client *redisv9.Client) *SessionLock {
+	return &SessionLock{client: client}
+}
+
+// TryLock takes the lock for sessionID if it is free. When ok, release must be called once
+// the holder is done.
+func (l *SessionLock) TryLock(ctx context.Context, sessionID uint) (func(), bool, error) {
+	return tryLock(ctx, l.client, fmt.Sprintf("chat:lock:%d", sessionID))
+}
+
+// JobLock is a named mutex in Redis for background jobs that only one server instance
+// should run at a time.
+type JobLock struct {
+	client *redisv9.Client
+}
+
+func NewJobLock(client *redisv9.Client) *JobLock {
+	return &JobLock{client: client}
+}
+
+// TryLock takes the lock for the job name if it is free. When ok, release must be called
+// once the run is done.
+func (l *JobLock) TryLock(ctx context.Context, name string) (func(), bool, error) {
+	return tryLock(ctx, l.client, "job:lock:"+name)
+}
+
+// tryLock takes the lock at key if it is free and extends it until released.
+func tryLock(ctx context.Context, client *redisv9.Client, key string) (func(), bool, error) {
+	token, err := lockToken()
+	if err != nil {
+		return nil, false, err
+	}
+	ok, err := client.SetNX(ctx, key, token, sessionLockLease).Result()
+	if err != nil {
+		return nil, false, fmt.Errorf("redis take lock %s failed: %w", key, err)
+	}
+	if !ok {
+		return nil, false, nil
+	}
+
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(sessionLockLease / 3)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				err := extendSessionLock.Run(context.Background(), client, []string{key}, token, sessionLockLease.Milliseconds()).Err()
+				if err != nil {
+					log.Printf("extend lock %s failed: %v", key, err)
+				}
+			}
+		}
+	}()
+	var once sync.Once
+	release := func() {
+		once.Do(func() {
+			close(stop)
+			<-done
+			ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+			defer cancel()
+			if err := releaseSessionLock.Run(ctx, client, []string{key}, token).Err(); err != nil {
+				log.Printf("release lock %s failed: %v", key, err)
+			}
+		})
+	}
+	return release, true, nil
+}
+
+func lockToken() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("generate lock token failed: %w", err)
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// MemorySessionLock is the in-process SessionLock used without Redis. It only serializes
+// requests handled by the same server instance.
+type MemorySessionLock struct {
+	mu   sync.Mutex
+	held map[uint]struct{}
+}
+
+func NewMemorySessionLock() *MemorySessionLock {
+	return &MemorySessionLock{held: make(map[uint]struct{})}
+}
+
+func (l *MemorySessionLock) TryLock(_ context.Context, sessionID uint) (func(), bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if _, ok := l.held[sessionID]; ok {
+		return nil, false, nil
+	}
+	l.held[sessionID] = struct{}{}
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			l.mu.Lock()
+			delete(l.held, sessionID)
+			l.mu.Unlock()
+		})
+	}, true, nil
+}
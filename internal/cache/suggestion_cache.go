@@ -0,0 +1,57 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	redisv9 "github.com/redis/go-redis/v9"
+)
+
+// SuggestionCache stores LLM-generated question suggestions keyed by a document-set hash.
+type SuggestionCache struct {
+	client *redisv9.Client
+	ttl    time.Duration
+}
+
+func NewSuggestionCache(client *redisv9.Client, ttl time.Duration) *SuggestionCache {
+	if ttl <= 0 {
+		ttl = 24 * time.Hour
+	}
+	return &SuggestionCache{
+		client: client,
+		ttl:    ttl,
+	}
+}
+
+func (c *SuggestionCache) GetSuggestions(ctx context.Context, setHash string) ([]string, bool, error) {
+	raw, err := c.client.Get(ctx, c.key(setHash)).Result()
+	if err == redisv9.Nil {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("redis get suggestions failed: %w", err)
+	}
+
+	var questions []string
+	if err := json.Unmarshal([]byte(raw), &questions); err != nil {
+		return nil, false, fmt.Errorf("unmarshal cached suggestions failed: %w", err)
+	}
+	return questions, true, nil
+}
+
+func (c *SuggestionCache) SetSuggestions(ctx context.Context, setHash string, questions []string) error {
+	payload, err := json.Marshal(questions)
+	if err != nil {
+		return fmt.Errorf("marshal suggestions cache failed: %w", err)
+	}
+	if err := c.client.Set(ctx, c.key(setHash), payload, c.ttl).Err(); err != nil {
+		return fmt.Errorf("redis set suggestions failed: %w", err)
+	}
+	return nil
+}
+
+func (c *SuggestionCache) key(setHash string) string {
+	return fmt.Sprintf("rag:suggestions:%s", setHash)
+}
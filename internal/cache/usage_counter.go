@@ -0,0 +1,46 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	redisv9 "github.com/redis/go-redis/v9"
+)
+
+// UsageCounter keeps per-user daily usage counters in Redis. Keys expire shortly after the day ends.
+type UsageCounter struct {
+	client *redisv9.Client
+}
+
+func NewUsageCounter(client *redisv9.Client) *UsageCounter {
+	return &UsageCounter{client: client}
+}
+
+// Add increments the counter for metric on the given day and returns the new total.
+func (c *UsageCounter) Add(ctx context.Context, userID uint, metric string, day time.Time, amount int64) (int64, error) {
+	key := c.key(userID, metric, day)
+	pipe := c.client.TxPipeline()
+	incr := pipe.IncrBy(ctx, key, amount)
+	pipe.Expire(ctx, key, 48*time.Hour)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return 0, fmt.Errorf("redis incr usage failed: %w", err)
+	}
+	return incr.Val(), nil
+}
+
+// Get returns the counter for metric on the given day (0 if unset).
+func (c *UsageCounter) Get(ctx context.Context, userID uint, metric string, day time.Time) (int64, error) {
+	val, err := c.client.Get(ctx, c.key(userID, metric, day)).Int64()
+	if err == redisv9.Nil {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("redis get usage failed: %w", err)
+	}
+	return val, nil
+}
+
+func (c *UsageCounter) key(userID uint, metric string, day time.Time) string {
+	return fmt.Sprintf("usage:%s:%d:%s", metric, userID, day.UTC().Format("20060102"))
+}
@@ -0,0 +1,59 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	redisv9 "github.com/redis/go-redis/v9"
+
+	"gopherai-resume/internal/vision"
+)
+
+// VisionCache stores classification results keyed by image hash, model and output options.
+type VisionCache struct {
+	client *redisv9.Client
+	ttl    time.Duration
+}
+
+func NewVisionCache(client *redisv9.Client, ttl time.Duration) *VisionCache {
+	if ttl <= 0 {
+		ttl = time.Hour
+	}
+	return &VisionCache{
+		client: client,
+		ttl:    ttl,
+	}
+}
+
+func (c *VisionCache) GetResult(ctx context.Context, key string) (*vision.ClassifyResult, bool, error) {
+	raw, err := c.client.Get(ctx, c.key(key)).Result()
+	if err == redisv9.Nil {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("redis get vision result failed: %w", err)
+	}
+
+	var result vision.ClassifyResult
+	if err := json.Unmarshal([]byte(raw), &result); err != nil {
+		return nil, false, fmt.Errorf("unmarshal cached vision result failed: %w", err)
+	}
+	return &result, true, nil
+}
+
+func (c *VisionCache) SetResult(ctx context.Context, key string, result *vision.ClassifyResult) error {
+	payload, err := json.Marshal(result)
+	if err != nil {
+		return fmt.Errorf("marshal vision result cache failed: %w", err)
+	}
+	if err := c.client.Set(ctx, c.key(key), payload, c.ttl).Err(); err != nil {
+		return fmt.Errorf("redis set vision result failed: %w", err)
+	}
+	return nil
+}
+
+func (c *VisionCache) key(key string) string {
+	return "vision:result:" + key
+}
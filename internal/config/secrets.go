@@ -0,0 +1,124 @@
+package config
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// minJWTSecretLength is the shortest JWT secret accepted in production.
+const minJWTSecretLength = 32
+
+// placeholderSecrets are sample values that must never sign production tokens.
+var placeholderSecrets = map[string]bool{
+	"change-me-in-production": true,
+	"change-me":               true,
+	"changeme":                true,
+	"secret":                  true,
+	"your-secret":             true,
+}
+
+// leakedKeyHashes are SHA-256 hashes of API keys that were published with the sample
+// config. They are hashed so the keys themselves stay out of the tree.
+var leakedKeyHashes = map[string]bool{
+	"b9a856e33942a0d6969f8d402d84ef1d3a757bc644391186628bf3ed8e4c8593": true,
+}
+
+// secret is a sensitive setting. Production deployments must supply it through the
+// environment: either the variable env or env+"_FILE" naming a file that holds the value,
+// as with Docker and Kubernetes secrets.
+type secret struct {
+	key      string
+	env      string
+	value    *string
+	required bool
+}
+
+func (c *Config) secrets() []secret {
+	return []secret{
+		{key: "auth.jwt_secret", env: "JWT_SECRET", value: &c.Auth.JWTSecret, required: true},
+		{key: "llm.api_key", env: "LLM_API_KEY", value: &c.LLM.APIKey, required: true},
+		{key: "mysql.password", env: "MYSQL_PASSWORD", value: &c.MySQL.Password},
+		{key: "redis.password", env: "REDIS_PASSWORD", value: &c.Redis.Password},
+		{key: "github.token", env: "GITHUB_TOKEN", value: &c.GitHub.Token},
+		{key: "rag.qdrant_api_key", env: "QDRANT_API_KEY", value: &c.RAG.QdrantAPIKey},
+		{key: "mail.smtp_password", env: "MAIL_SMTP_PASSWORD", value: &c.Mail.SMTPPassword},
+	}
+}
+
+// overrideSecretsByFiles reads secrets from the files named by *_FILE variables, which
+// take precedence over the plain variables, and records which secrets the environment
+// supplied.
+func overrideSecretsByFiles(cfg *Config) error {
+	cfg.envSecrets = make(map[string]bool)
+	for _, s := range cfg.secrets() {
+		if _, ok := os.LookupEnv(s.env); ok {
+			cfg.envSecrets[s.env] = true
+		}
+		path := os.Getenv(s.env + "_FILE")
+		if path == "" {
+			continue
+		}
+		raw, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("read %s_FILE failed: %w", s.env, err)
+		}
+		*s.value = strings.TrimSpace(string(raw))
+		cfg.envSecrets[s.env] = true
+	}
+	return nil
+}
+
+// IsProduction reports whether app.env names a production deployment.
+func (c *Config) IsProduction() bool {
+	switch strings.ToLower(c.App.Env) {
+	case "prod", "production":
+		return true
+	}
+	return false
+}
+
+// SecretProblems lists unsafe secrets with how to fix each. Placeholder, weak and
+// leaked values are always reported. In production, missing required secrets and secrets
+// read from a config file are reported too. Load refuses to start production with any.
+func (c *Config) SecretProblems() []string {
+	prod := c.IsProduction()
+	var problems []string
+	flagged := make(map[string]bool) // keys already reported
+	jwt := c.Auth.JWTSecret
+	switch {
+	case placeholderSecrets[strings.ToLower(jwt)]:
+		problems = append(problems, "auth.jwt_secret is a sample placeholder; set JWT_SECRET (or JWT_SECRET_FILE) "+
+			"to a random value, e.g. the output of `openssl rand -hex 32`")
+		flagged["auth.jwt_secret"] = true
+	case jwt != "" && len(jwt) < minJWTSecretLength:
+		problems = append(problems, fmt.Sprintf("auth.jwt_secret is shorter than %d characters; set JWT_SECRET "+
+			"(or JWT_SECRET_FILE) to a longer random value", minJWTSecretLength))
+		flagged["auth.jwt_secret"] = true
+	}
+	if c.LLM.APIKey != "" && leakedKeyHashes[hashSecret(c.LLM.APIKey)] {
+		problems = append(problems, "llm.api_key is a key that was published with the sample config; revoke it "+
+			"with the provider and set LLM_API_KEY (or LLM_API_KEY_FILE) to a new key")
+		flagged["llm.api_key"] = true
+	}
+	if !prod {
+		return problems
+	}
+	for _, s := range c.secrets() {
+		switch {
+		case *s.value == "" && s.required:
+			problems = append(problems, fmt.Sprintf("%s is not set; set %s or %s_FILE", s.key, s.env, s.env))
+		case *s.value != "" && !c.envSecrets[s.env] && !flagged[s.key]:
+			problems = append(problems, fmt.Sprintf("%s is set in a config file; remove it there and set %s or %s_FILE instead",
+				s.key, s.env, s.env))
+		}
+	}
+	return problems
+}
+
+func hashSecret(value string) string {
+	sum := sha256.Sum256([]byte(value))
+	return hex.EncodeToString(sum[:])
+}
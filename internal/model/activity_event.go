@@ -0,0 +1,15 @@
+package model
+
+import "time"
+
+// ActivityEvent is one action a user took, such as creating a session or asking a question,
+// recorded for their activity timeline. ResourceID points at the session, document or
+// application the action was on, where there is one.
+type ActivityEvent struct {
+	ID         uint      `gorm:"primaryKey" json:"id"`
+	UserID     uint      `gorm:"not null;index" json:"-"`
+	Type       string    `gorm:"size:32;not null;index" json:"type"`
+	ResourceID uint      `json:"resource_id,omitempty"`
+	Summary    string    `gorm:"size:512" json:"summary"`
+	CreatedAt  time.Time `gorm:"index" json:"created_at"`
+}
@@ -0,0 +1,39 @@
+package model
+
+import "time"
+
+// Application is a job the user is tracking, from saving the posting through to an outcome.
+type Application struct {
+	ID      uint   `gorm:"primaryKey" json:"id"`
+	UserID  uint   `gorm:"not null;index" json:"user_id"`
+	Company string `gorm:"size:256;not null" json:"company"`
+	Role    string `gorm:"size:256;not null" json:"role"`
+	Status  string `gorm:"size:32;not null;index" json:"status"`
+	// BoardPosition is the card's place in its status column on the board (1 = top);
+	// 0 means not placed, and such cards follow the placed ones by recency.
+	BoardPosition    int        `gorm:"not null;default:0" json:"board_position"`
+	JobURL           string     `gorm:"size:1024" json:"job_url"`
+	JobDescription   string     `gorm:"type:text" json:"job_description"`
+	ResumeDocumentID *uint      `gorm:"index" json:"resume_document_id"` // RAG document holding the resume version sent
+	JobPostingID     *uint      `gorm:"index" json:"job_posting_id"`     // workspace library posting it was created from
+	AppliedAt        *time.Time `json:"applied_at"`
+	RemindAt         *time.Time `gorm:"index" json:"remind_at"`
+	ReminderNote     string     `gorm:"size:512" json:"reminder_note"`
+	// ReminderSentAt is when the reminder for RemindAt was emailed; nil until then.
+	ReminderSentAt *time.Time `json:"reminder_sent_at"`
+	Notes          string     `gorm:"type:text" json:"notes"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+}
+
+// ApplicationStatusChange records one status transition of an Application.
+// The first entry of each application has an empty FromStatus.
+type ApplicationStatusChange struct {
+	ID            uint      `gorm:"primaryKey" json:"id"`
+	ApplicationID uint      `gorm:"not null;index" json:"application_id"`
+	UserID        uint      `gorm:"not null;index" json:"user_id"`
+	FromStatus    string    `gorm:"size:32" json:"from_status"`
+	ToStatus      string    `gorm:"size:32;not null" json:"to_status"`
+	Note          string    `gorm:"size:512" json:"note"`
+	CreatedAt     time.Time `json:"created_at"`
+}
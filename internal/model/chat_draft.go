@@ -0,0 +1,11 @@
+package model
+
+import "time"
+
+// ChatDraft is an unsent prompt autosaved for a chat session. Drafts live in Redis with a
+// TTL rather than in MySQL.
+type ChatDraft struct {
+	SessionID uint      `json:"session_id"`
+	Content   string    `json:"content"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
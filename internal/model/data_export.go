@@ -0,0 +1,19 @@
+package model
+
+import "time"
+
+// DataExport is an archive of everything a user owns, generated in the background and kept
+// in the object store.
+type DataExport struct {
+	ID        uint   `gorm:"primaryKey" json:"id"`
+	UserID    uint   `gorm:"not null;index" json:"user_id"`
+	Status    string `gorm:"size:16;not null" json:"status"`
+	Error     string `gorm:"size:512" json:"error,omitempty"`
+	ObjectKey string `gorm:"size:256" json:"-"`
+	SizeBytes int64  `gorm:"not null;default:0" json:"size_bytes"`
+	// ActiveUserID is UserID while the export is pending or processing and nil once it has
+	// finished, so its unique index allows one unfinished export per user.
+	ActiveUserID *uint     `gorm:"uniqueIndex" json:"-"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
@@ -0,0 +1,41 @@
+package model
+
+import "time"
+
+// JobPosting is a job description kept in a workspace's shared library.
+type JobPosting struct {
+	ID          uint       `gorm:"primaryKey" json:"id"`
+	WorkspaceID uint       `gorm:"not null;index" json:"workspace_id"`
+	CreatedBy   uint       `gorm:"not null" json:"created_by"`
+	Title       string     `gorm:"size:256;not null" json:"title"`
+	Company     string     `gorm:"size:256" json:"company"`
+	Location    string     `gorm:"size:256" json:"location"`
+	URL         string     `gorm:"size:1024" json:"url"`
+	Description string     `gorm:"type:mediumtext" json:"description"`
+	Parsed      string     `gorm:"type:text" json:"-"` // JSON app.ParsedJobPosting
+	ArchivedAt  *time.Time `gorm:"index" json:"archived_at"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+// JobPostingTag is one tag on a JobPosting. Tags are stored lower-case.
+type JobPostingTag struct {
+	ID           uint   `gorm:"primaryKey" json:"id"`
+	JobPostingID uint   `gorm:"not null;uniqueIndex:idx_job_posting_tag" json:"job_posting_id"`
+	WorkspaceID  uint   `gorm:"not null;index" json:"workspace_id"`
+	Tag          string `gorm:"size:64;not null;uniqueIndex:idx_job_posting_tag;index" json:"tag"`
+}
+
+// ScreeningResult is the LLM's assessment of one resume against a JobPosting.
+type ScreeningResult struct {
+	ID           uint      `gorm:"primaryKey" json:"id"`
+	WorkspaceID  uint      `gorm:"not null;index" json:"workspace_id"`
+	JobPostingID uint      `gorm:"not null;index" json:"job_posting_id"`
+	DocumentID   uint      `gorm:"not null;index" json:"document_id"` // RAG document holding the resume
+	ScreenedBy   uint      `gorm:"not null" json:"screened_by"`
+	Score        int       `gorm:"not null" json:"score"` // 0-100
+	Summary      string    `gorm:"type:text" json:"summary"`
+	Strengths    string    `gorm:"type:text" json:"-"` // JSON []string
+	Gaps         string    `gorm:"type:text" json:"-"` // JSON []string
+	CreatedAt    time.Time `json:"created_at"`
+}
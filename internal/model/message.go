@@ -3,10 +3,21 @@ package model
 import "time"
 
 type Message struct {
-	ID        uint      `gorm:"primaryKey" json:"id"`
-	SessionID uint      `gorm:"not null;index" json:"session_id"`
-	UserID    uint      `gorm:"not null;index" json:"user_id"`
-	Role      string    `gorm:"size:16;not null;index" json:"role"`
-	Content   string    `gorm:"type:text;not null" json:"content"`
-	CreatedAt time.Time `json:"created_at"`
+	ID        uint `gorm:"primaryKey" json:"id"`
+	SessionID uint `gorm:"not null;index" json:"session_id"`
+	UserID    uint `gorm:"not null;index" json:"user_id"`
+	// ParentMessageID is the message this one follows on its branch; nil for the first
+	// message of a conversation and for messages stored before branching existed.
+	ParentMessageID *uint  `gorm:"index" json:"parent_message_id"`
+	Role            string `gorm:"size:16;not null;index" json:"role"`
+	Content         string `gorm:"type:text;not null" json:"content"`
+	// Truncated marks an assistant reply whose stream was cancelled before it finished.
+	Truncated bool `gorm:"not null;default:false" json:"truncated"`
+	// Model and token counts of the completion behind an assistant message. The counts are
+	// the provider's, or estimated when UsageEstimated is set.
+	Model            string    `gorm:"size:128" json:"model,omitempty"`
+	PromptTokens     int       `gorm:"not null;default:0" json:"prompt_tokens"`
+	CompletionTokens int       `gorm:"not null;default:0" json:"completion_tokens"`
+	UsageEstimated   bool      `gorm:"not null;default:false" json:"usage_estimated"`
+	CreatedAt        time.Time `json:"created_at"`
 }
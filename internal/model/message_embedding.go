@@ -0,0 +1,26 @@
+package model
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// MessageEmbedding stores the embedding of a persisted chat message so RAG ask can
+// retrieve from the user's own conversation history.
+type MessageEmbedding struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	MessageID uint      `gorm:"not null;uniqueIndex" json:"message_id"`
+	UserID    uint      `gorm:"not null;index" json:"user_id"`
+	Embedding string    `gorm:"type:text" json:"-"` // JSON array of float32
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// SetEmbedding stores the embedding as JSON.
+func (e *MessageEmbedding) SetEmbedding(vec []float32) {
+	if len(vec) == 0 {
+		e.Embedding = "[]"
+		return
+	}
+	b, _ := json.Marshal(vec)
+	e.Embedding = string(b)
+}
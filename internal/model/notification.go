@@ -0,0 +1,48 @@
+package model
+
+import "time"
+
+// NotificationPreference holds a user's opt-ins for optional emails. Users without a row
+// get the defaults. Account emails (password reset, verification) are always sent.
+type NotificationPreference struct {
+	ID                   uint      `gorm:"primaryKey" json:"-"`
+	UserID               uint      `gorm:"not null;uniqueIndex" json:"user_id"`
+	IngestionComplete    bool      `gorm:"not null" json:"ingestion_complete"`
+	ScreeningReports     bool      `gorm:"not null" json:"screening_reports"`
+	ApplicationReminders bool      `gorm:"not null" json:"application_reminders"`
+	CreatedAt            time.Time `json:"-"`
+	UpdatedAt            time.Time `json:"updated_at"`
+}
+
+// EmailOutbox is an email waiting to be sent, or the record of one. Rows are written in the
+// same request that triggers them and delivered by the outbox worker, which retries
+// failures with backoff until MaxAttempts. An email with a LinkPurpose carries a one-time
+// link that is only issued when it is sent, so no usable token is ever stored in Body.
+type EmailOutbox struct {
+	ID            uint       `gorm:"primaryKey" json:"id"`
+	UserID        uint       `gorm:"not null;index" json:"user_id"`
+	Kind          string     `gorm:"size:32;not null" json:"kind"`
+	ToAddress     string     `gorm:"size:128;not null" json:"to_address"`
+	Subject       string     `gorm:"size:256;not null" json:"subject"`
+	Body          string     `gorm:"type:text" json:"body"`
+	LinkPurpose   string     `gorm:"size:32" json:"-"`
+	Status        string     `gorm:"size:16;not null;index:idx_outbox_due,priority:1" json:"status"` // pending, sent or failed
+	Attempts      int        `gorm:"not null;default:0" json:"attempts"`
+	LastError     string     `gorm:"size:512" json:"last_error,omitempty"`
+	NextAttemptAt time.Time  `gorm:"not null;index:idx_outbox_due,priority:2" json:"next_attempt_at"`
+	SentAt        *time.Time `json:"sent_at"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+}
+
+// UserToken is a single-use emailed token, such as a password reset. Only its SHA-256 hash
+// is stored.
+type UserToken struct {
+	ID        uint      `gorm:"primaryKey"`
+	UserID    uint      `gorm:"not null;index"`
+	Purpose   string    `gorm:"size:32;not null"`
+	TokenHash string    `gorm:"size:64;not null;uniqueIndex"`
+	ExpiresAt time.Time `gorm:"not null"`
+	UsedAt    *time.Time
+	CreatedAt time.Time
+}
@@ -0,0 +1,15 @@
+package model
+
+import "time"
+
+// PortfolioAnalysis is an LLM review of a user's GitHub repositories with suggested resume bullets.
+type PortfolioAnalysis struct {
+	ID         uint      `gorm:"primaryKey" json:"id"`
+	UserID     uint      `gorm:"not null;index" json:"user_id"`
+	Source     string    `gorm:"size:512;not null" json:"source"` // GitHub username or repository URL
+	Summary    string    `gorm:"type:text" json:"summary"`
+	Projects   string    `gorm:"type:mediumtext" json:"-"` // JSON []app.PortfolioProject
+	Bullets    string    `gorm:"type:text" json:"-"`       // JSON []string
+	DocumentID *uint     `json:"document_id"`              // RAG document when ingested
+	CreatedAt  time.Time `json:"created_at"`
+}
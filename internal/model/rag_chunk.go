@@ -1,22 +1,59 @@
 package model
 
 import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
 	"encoding/json"
+	"io"
+	"math"
 	"time"
 )
 
+// Embedding blob formats, stored in the blob's first byte.
+const (
+	embeddingFloat32 byte = 1 // little-endian float32 values
+	embeddingInt8    byte = 2 // little-endian float32 scale, then one int8 per value
+)
+
+// RAGChunkIndexName names the unique index on a chunk's document and position.
+const RAGChunkIndexName = "idx_rag_chunk_position"
+
 // RAGChunk stores a text chunk and its embedding for retrieval.
-// Embedding is stored as JSON array of float32 for portability.
 type RAGChunk struct {
-	ID         uint      `gorm:"primaryKey" json:"id"`
-	DocumentID uint      `gorm:"not null;index" json:"document_id"`
-	Content    string    `gorm:"type:text;not null" json:"content"`
-	Embedding  string    `gorm:"type:text" json:"-"` // JSON array of float32
-	CreatedAt  time.Time `json:"created_at"`
+	ID         uint   `gorm:"primaryKey" json:"id"`
+	DocumentID uint   `gorm:"not null;index;uniqueIndex:idx_rag_chunk_position" json:"document_id"`
+	ChunkIndex int    `gorm:"not null;default:0;uniqueIndex:idx_rag_chunk_position" json:"chunk_index"` // position within the document, monotonically increasing
+	Content    string `gorm:"type:text;not null" json:"content"`
+	// StartOffset and EndOffset locate the chunk in the document's text, in characters;
+	// both are 0 for chunks stored before offsets were.
+	StartOffset int `gorm:"not null;default:0" json:"start_offset"`
+	EndOffset   int `gorm:"not null;default:0" json:"end_offset"`
+	// Page is the 1-based PDF page the chunk starts on; 0 for text without pages.
+	Page int `gorm:"not null;default:0" json:"page,omitempty"`
+	// ContentHash is the SHA-256 of the chunk's normalized text and SimHash its simhash,
+	// for skipping duplicates at ingest; empty for chunks stored before they were.
+	ContentHash string `gorm:"size:64;index" json:"-"`
+	SimHash     uint64 `gorm:"not null;default:0" json:"-"`
+	// EmbeddingBlob is the packed embedding, see SetEmbedding and SetQuantizedEmbedding.
+	EmbeddingBlob []byte `gorm:"type:mediumblob" json:"-"`
+	// Embedding is the JSON array of float32 that chunks stored before EmbeddingBlob
+	// existed still carry until PackEmbedding converts them; empty otherwise.
+	Embedding string    `gorm:"type:text" json:"-"`
+	CreatedAt time.Time `json:"created_at"`
+
+	// LastAccessedAt is updated whenever the chunk is returned by retrieval.
+	LastAccessedAt *time.Time `gorm:"index" json:"last_accessed_at,omitempty"`
+	// EmbeddingArchive holds the gzip-compressed embedding of a cold chunk; Embedding is empty meanwhile.
+	EmbeddingArchive []byte     `gorm:"type:mediumblob" json:"-"`
+	ArchivedAt       *time.Time `json:"archived_at,omitempty"`
 }
 
-// EmbeddingVector returns the parsed embedding slice; empty on parse error.
+// EmbeddingVector returns the embedding; empty when there is none or it is malformed.
 func (c *RAGChunk) EmbeddingVector() []float32 {
+	if len(c.EmbeddingBlob) > 0 {
+		return unpackEmbedding(c.EmbeddingBlob)
+	}
 	if c.Embedding == "" {
 		return nil
 	}
@@ -25,12 +62,156 @@ func (c *RAGChunk) EmbeddingVector() []float32 {
 	return v
 }
 
-// SetEmbedding stores the embedding as JSON.
+// SetEmbedding stores the embedding as packed float32, 4 bytes per value.
 func (c *RAGChunk) SetEmbedding(vec []float32) {
-	if len(vec) == 0 {
-		c.Embedding = "[]"
-		return
+	c.EmbeddingBlob = packEmbedding(vec)
+	c.Embedding = ""
+}
+
+func packEmbedding(vec []float32) []byte {
+	blob := make([]byte, 1+4*len(vec))
+	blob[0] = embeddingFloat32
+	for i, v := range vec {
+		binary.LittleEndian.PutUint32(blob[1+4*i:], math.Float32bits(v))
+	}
+	return blob
+}
+
+// SetQuantizedEmbedding stores the embedding as one int8 per value plus a scale, a
+// quarter of SetEmbedding's size at a small loss of precision.
+func (c *RAGChunk) SetQuantizedEmbedding(vec []float32) {
+	var peak float32
+	for _, v := range vec {
+		if a := float32(math.Abs(float64(v))); a > peak {
+			peak = a
+		}
+	}
+	scale := peak / 127
+	blob := make([]byte, 5+len(vec))
+	blob[0] = embeddingInt8
+	binary.LittleEndian.PutUint32(blob[1:], math.Float32bits(scale))
+	for i, v := range vec {
+		var q int8
+		if scale > 0 {
+			q = int8(math.Round(float64(v / scale)))
+		}
+		blob[5+i] = byte(q)
+	}
+	c.EmbeddingBlob = blob
+	c.Embedding = ""
+}
+
+// PackEmbedding moves a JSON embedding into EmbeddingBlob, quantized if asked, and reports
+// whether there was one to move. Archived embeddings are left alone.
+func (c *RAGChunk) PackEmbedding(quantize bool) bool {
+	if c.Embedding == "" || c.IsArchived() {
+		return false
+	}
+	vec := c.EmbeddingVector()
+	if quantize {
+		c.SetQuantizedEmbedding(vec)
+	} else {
+		c.SetEmbedding(vec)
+	}
+	return true
+}
+
+func unpackEmbedding(blob []byte) []float32 {
+	switch blob[0] {
+	case embeddingFloat32:
+		n := (len(blob) - 1) / 4
+		vec := make([]float32, n)
+		for i := range vec {
+			vec[i] = math.Float32frombits(binary.LittleEndian.Uint32(blob[1+4*i:]))
+		}
+		return vec
+	case embeddingInt8:
+		if len(blob) < 5 {
+			return nil
+		}
+		scale := math.Float32frombits(binary.LittleEndian.Uint32(blob[1:]))
+		vec := make([]float32, len(blob)-5)
+		for i := range vec {
+			vec[i] = float32(int8(blob[5+i])) * scale
+		}
+		return vec
+	default:
+		return nil
+	}
+}
+
+// EmbeddingInfo reports how the embedding is stored, "float32", "int8", "json" or "none",
+// and its dimension. An archived embedding is reported as it was stored before archiving.
+func (c *RAGChunk) EmbeddingInfo() (format string, dimensions int) {
+	if c.IsArchived() {
+		restored := RAGChunk{EmbeddingArchive: c.EmbeddingArchive}
+		if err := restored.RestoreEmbedding(); err != nil {
+			return "none", 0
+		}
+		return restored.EmbeddingInfo()
+	}
+	switch {
+	case len(c.EmbeddingBlob) > 0 && c.EmbeddingBlob[0] == embeddingFloat32:
+		return "float32", (len(c.EmbeddingBlob) - 1) / 4
+	case len(c.EmbeddingBlob) > 0 && c.EmbeddingBlob[0] == embeddingInt8:
+		return "int8", max(len(c.EmbeddingBlob)-5, 0)
+	case c.Embedding != "":
+		return "json", len(c.EmbeddingVector())
+	}
+	return "none", 0
+}
+
+// IsArchived reports whether the embedding has been moved to cold storage.
+func (c *RAGChunk) IsArchived() bool {
+	return len(c.EmbeddingArchive) > 0
+}
+
+// ArchiveEmbedding compresses the embedding into EmbeddingArchive and clears the
+// embedding columns.
+func (c *RAGChunk) ArchiveEmbedding(now time.Time) error {
+	if c.IsArchived() || (c.Embedding == "" && len(c.EmbeddingBlob) == 0) {
+		return nil
+	}
+	raw := c.EmbeddingBlob
+	if len(raw) == 0 {
+		raw = []byte(c.Embedding)
+	}
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write(raw); err != nil {
+		return err
+	}
+	if err := zw.Close(); err != nil {
+		return err
+	}
+	c.EmbeddingArchive = buf.Bytes()
+	c.Embedding = ""
+	c.EmbeddingBlob = nil
+	c.ArchivedAt = &now
+	return nil
+}
+
+// RestoreEmbedding decompresses EmbeddingArchive back into the column it came from; JSON
+// archives start with '[', packed ones with their format byte.
+func (c *RAGChunk) RestoreEmbedding() error {
+	if !c.IsArchived() {
+		return nil
+	}
+	zr, err := gzip.NewReader(bytes.NewReader(c.EmbeddingArchive))
+	if err != nil {
+		return err
+	}
+	defer zr.Close()
+	raw, err := io.ReadAll(zr)
+	if err != nil {
+		return err
+	}
+	if len(raw) > 0 && raw[0] == '[' {
+		c.Embedding = string(raw)
+	} else {
+		c.EmbeddingBlob = raw
 	}
-	b, _ := json.Marshal(vec)
-	c.Embedding = string(b)
+	c.EmbeddingArchive = nil
+	c.ArchivedAt = nil
+	return nil
 }
@@ -7,5 +7,39 @@ type RAGDocument struct {
 	UserID     uint      `gorm:"not null;index" json:"user_id"`
 	SessionID  uint      `gorm:"index" json:"session_id"` // 0 = no session
 	Name       string    `gorm:"size:256;not null" json:"name"`
+	ChunkCount int       `gorm:"not null;default:0" json:"chunk_count"`
 	CreatedAt  time.Time `json:"created_at"`
+
+	// ChunkSize, ChunkOverlap and ChunkStrategy are how the content was split; a zero
+	// ChunkSize marks documents ingested with the defaults before they were stored.
+	ChunkSize     int    `gorm:"not null;default:0" json:"chunk_size"`
+	ChunkOverlap  int    `gorm:"not null;default:0" json:"chunk_overlap"`
+	ChunkStrategy string `gorm:"size:16" json:"chunk_strategy"`
+	// PageCount is the number of pages of a PDF; 0 for formats without pages.
+	PageCount int `gorm:"not null;default:0" json:"page_count,omitempty"`
+	// FileHash is the hex SHA-256 of the uploaded file the content came from; empty for
+	// content sent as text.
+	FileHash string `gorm:"size:64;index" json:"file_hash,omitempty"`
+	// ContentVersion counts the times the content was replaced, so caches keyed on the
+	// document notice a re-ingest that keeps the chunk count.
+	ContentVersion int `gorm:"not null;default:0" json:"-"`
+
+	// Status is "ready" once the chunks are stored. Asynchronous ingests are "pending" and
+	// then "processing" until then, or end "failed" with Error set.
+	Status string `gorm:"size:16;not null;default:ready;index" json:"status"`
+	Error  string `gorm:"size:512" json:"error,omitempty"`
+	// IngestLeaseUntil is when a processing ingest's worker is presumed gone, and
+	// IngestClaims how often an ingest was started.
+	IngestLeaseUntil *time.Time `json:"-"`
+	IngestClaims     int        `gorm:"not null;default:0" json:"-"`
+	// DuplicateChunks and NearDuplicateChunks count the chunks an asynchronous ingest left
+	// out because they repeated the document's own text.
+	DuplicateChunks     int `gorm:"not null;default:0" json:"duplicate_chunks,omitempty"`
+	NearDuplicateChunks int `gorm:"not null;default:0" json:"near_duplicate_chunks,omitempty"`
+	// VectorIndexed is set once every chunk is in the vector store. Until then retrieval
+	// scores the document's chunks from MySQL and indexes them again.
+	VectorIndexed bool `gorm:"not null;default:false" json:"-"`
+	// Temporary marks a document ingested for one request and deleted again at its end,
+	// such as a screenshot's OCR text. It is left out of listings and unscoped retrieval.
+	Temporary bool `gorm:"not null;default:false;index" json:"-"`
 }
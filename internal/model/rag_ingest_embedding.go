@@ -0,0 +1,30 @@
+package model
+
+import "time"
+
+// RAGIngestEmbedding is a chunk embedding saved by an asynchronous ingest that has not
+// completed, so a retried or resumed ingest of the document only embeds the chunks that
+// are left. Fingerprint identifies the chunk text and embedding model it was computed
+// for; the rows are deleted once the document is ready.
+type RAGIngestEmbedding struct {
+	ID          uint   `gorm:"primaryKey" json:"id"`
+	DocumentID  uint   `gorm:"not null;uniqueIndex:idx_ingest_doc_chunk" json:"document_id"`
+	ChunkIndex  int    `gorm:"not null;uniqueIndex:idx_ingest_doc_chunk" json:"chunk_index"`
+	Fingerprint string `gorm:"size:64;not null" json:"fingerprint"`
+	// EmbeddingBlob is packed like RAGChunk.EmbeddingBlob.
+	EmbeddingBlob []byte    `gorm:"type:mediumblob" json:"-"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// EmbeddingVector returns the embedding; empty when there is none or it is malformed.
+func (e *RAGIngestEmbedding) EmbeddingVector() []float32 {
+	if len(e.EmbeddingBlob) == 0 {
+		return nil
+	}
+	return unpackEmbedding(e.EmbeddingBlob)
+}
+
+// SetEmbedding stores the embedding as packed float32.
+func (e *RAGIngestEmbedding) SetEmbedding(vec []float32) {
+	e.EmbeddingBlob = packEmbedding(vec)
+}
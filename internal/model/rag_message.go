@@ -0,0 +1,38 @@
+package model
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// RAGMessage is one question asked in a RAG session, with its answer and the chunks the
+// answer was grounded in.
+type RAGMessage struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	SessionID uint      `gorm:"not null;index" json:"session_id"`
+	UserID    uint      `gorm:"not null;index" json:"user_id"`
+	Question  string    `gorm:"type:text;not null" json:"question"`
+	Answer    string    `gorm:"type:mediumtext" json:"answer"`
+	ChunkIDs  string    `gorm:"type:text" json:"-"` // JSON []uint of the retrieved chunks
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// ChunkIDList returns the parsed chunk IDs; empty on parse error.
+func (m *RAGMessage) ChunkIDList() []uint {
+	if m.ChunkIDs == "" {
+		return nil
+	}
+	var ids []uint
+	_ = json.Unmarshal([]byte(m.ChunkIDs), &ids)
+	return ids
+}
+
+// SetChunkIDs stores the chunk IDs as JSON.
+func (m *RAGMessage) SetChunkIDs(ids []uint) {
+	if len(ids) == 0 {
+		m.ChunkIDs = "[]"
+		return
+	}
+	b, _ := json.Marshal(ids)
+	m.ChunkIDs = string(b)
+}
@@ -0,0 +1,42 @@
+package model
+
+import "time"
+
+// RAGShadowEmbedding is a chunk's embedding under a shadow model, stored beside the
+// primary one so the two models' retrieval can be compared before switching.
+type RAGShadowEmbedding struct {
+	ID         uint   `gorm:"primaryKey" json:"id"`
+	ChunkID    uint   `gorm:"not null;uniqueIndex:idx_shadow_chunk_model" json:"chunk_id"`
+	DocumentID uint   `gorm:"not null;index" json:"document_id"`
+	Model      string `gorm:"size:128;not null;uniqueIndex:idx_shadow_chunk_model" json:"model"`
+	// EmbeddingBlob is packed like RAGChunk.EmbeddingBlob. It is empty while the chunk has
+	// only failed to embed; Attempts counts those failures and Error holds the last one.
+	EmbeddingBlob []byte    `gorm:"type:mediumblob" json:"-"`
+	Attempts      int       `gorm:"not null;default:0" json:"attempts,omitempty"`
+	Error         string    `gorm:"size:512" json:"error,omitempty"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// RAGShadowStat accumulates the comparison asks run against a shadow model, shared by
+// every server instance.
+type RAGShadowStat struct {
+	ID          uint      `gorm:"primaryKey" json:"id"`
+	Model       string    `gorm:"size:128;not null;uniqueIndex" json:"model"`
+	Comparisons int64     `gorm:"not null;default:0" json:"comparisons"`
+	OverlapSum  float64   `gorm:"not null;default:0" json:"overlap_sum"`
+	JaccardSum  float64   `gorm:"not null;default:0" json:"jaccard_sum"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// EmbeddingVector returns the embedding; empty when there is none or it is malformed.
+func (e *RAGShadowEmbedding) EmbeddingVector() []float32 {
+	if len(e.EmbeddingBlob) == 0 {
+		return nil
+	}
+	return unpackEmbedding(e.EmbeddingBlob)
+}
+
+// SetEmbedding stores the embedding as packed float32.
+func (e *RAGShadowEmbedding) SetEmbedding(vec []float32) {
+	e.EmbeddingBlob = packEmbedding(vec)
+}
@@ -0,0 +1,18 @@
+package model
+
+import "time"
+
+// ResumeBullet is a resume bullet point flagged for lacking measurable impact, and the
+// guided conversation that rewrites it. The resume is the RAG document DocumentID.
+type ResumeBullet struct {
+	ID            uint      `gorm:"primaryKey" json:"id"`
+	UserID        uint      `gorm:"not null;index" json:"user_id"`
+	DocumentID    uint      `gorm:"not null;index" json:"document_id"`
+	ChatSessionID uint      `gorm:"index" json:"chat_session_id"` // 0 = turns are not mirrored to a chat session
+	Original      string    `gorm:"type:text;not null" json:"original"`
+	Proposed      string    `gorm:"type:text" json:"proposed"`
+	Status        string    `gorm:"size:16;not null;index" json:"status"`
+	Transcript    string    `gorm:"type:mediumtext" json:"-"` // JSON []ai.ChatMessage, without the system prompt
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
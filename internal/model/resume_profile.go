@@ -0,0 +1,18 @@
+package model
+
+import "time"
+
+// ResumeProfile is the structured (JSON Resume) form of a resume document. ContentHash is
+// the SHA-256 of the document text it was built from, so edits to the document are noticed.
+type ResumeProfile struct {
+	ID          uint   `gorm:"primaryKey" json:"id"`
+	UserID      uint   `gorm:"not null;index" json:"user_id"`
+	DocumentID  uint   `gorm:"not null;uniqueIndex" json:"document_id"`
+	Source      string `gorm:"size:16;not null" json:"source"` // imported or parsed
+	Data        string `gorm:"type:mediumtext" json:"-"`       // JSON jsonresume.Resume
+	ContentHash string `gorm:"size:64;not null" json:"content_hash"`
+	// SchemaVersion is the custom field schema (ResumeSchema) in force when it was built; 0 = none.
+	SchemaVersion int       `gorm:"not null;default:0" json:"schema_version"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
@@ -0,0 +1,15 @@
+package model
+
+import "time"
+
+// ResumeSchema is one version of the deployment's custom resume fields, which the resume
+// parser extracts on top of the JSON Resume sections. At most one version is active.
+type ResumeSchema struct {
+	ID          uint      `gorm:"primaryKey" json:"id"`
+	Version     int       `gorm:"not null;uniqueIndex" json:"version"`
+	Description string    `gorm:"size:512" json:"description"`
+	Fields      string    `gorm:"type:text" json:"-"` // JSON []app.ResumeSchemaField
+	Active      bool      `gorm:"not null;default:false;index" json:"active"`
+	CreatedBy   uint      `json:"created_by"`
+	CreatedAt   time.Time `json:"created_at"`
+}
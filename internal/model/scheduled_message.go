@@ -0,0 +1,23 @@
+package model
+
+import "time"
+
+// ScheduledMessage is a chat prompt a user asked to send later. The schedule worker sends
+// it through the normal chat flow once RunAt passes, so the prompt and the reply land in
+// the session. Failed runs are retried with backoff up to a few attempts.
+type ScheduledMessage struct {
+	ID        uint   `gorm:"primaryKey" json:"id"`
+	UserID    uint   `gorm:"not null;index" json:"user_id"`
+	SessionID uint   `gorm:"not null;index" json:"session_id"`
+	Content   string `gorm:"type:text;not null" json:"content"`
+	// Model overrides the session's model for this message; empty keeps it.
+	Model     string     `gorm:"size:64" json:"model,omitempty"`
+	Status    string     `gorm:"size:16;not null;index:idx_scheduled_due,priority:1" json:"status"` // pending, running, sent, failed or cancelled
+	RunAt     time.Time  `gorm:"not null;index:idx_scheduled_due,priority:2" json:"run_at"`
+	Attempts  int        `gorm:"not null;default:0" json:"attempts"`
+	LastError string     `gorm:"size:512" json:"last_error,omitempty"`
+	ClaimedAt *time.Time `json:"-"`
+	SentAt    *time.Time `json:"sent_at"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+}
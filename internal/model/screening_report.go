@@ -0,0 +1,22 @@
+package model
+
+import "time"
+
+// ScreeningReport is an explanation document for one ScreeningResult, generated in the
+// background and kept in the object store.
+type ScreeningReport struct {
+	ID                uint      `gorm:"primaryKey" json:"id"`
+	WorkspaceID       uint      `gorm:"not null;index" json:"workspace_id"`
+	JobPostingID      uint      `gorm:"not null;index" json:"job_posting_id"`
+	ScreeningResultID uint      `gorm:"not null;index" json:"screening_result_id"`
+	RequestedBy       uint      `gorm:"not null" json:"requested_by"`
+	Format            string    `gorm:"size:16;not null" json:"format"` // markdown or pdf
+	Status            string    `gorm:"size:16;not null;index" json:"status"`
+	Error             string    `gorm:"size:512" json:"error,omitempty"`
+	ObjectKey         string    `gorm:"size:256" json:"-"`
+	CreatedAt         time.Time `json:"created_at"`
+	UpdatedAt         time.Time `json:"updated_at"`
+	// RequestedByAdmin is set when the requester was an admin, whose read override may have
+	// let them request reports on resumes they do not own. The report is generated as them.
+	RequestedByAdmin bool `gorm:"not null;default:false" json:"-"`
+}
@@ -3,9 +3,30 @@ package model
 import "time"
 
 type Session struct {
-	ID        uint      `gorm:"primaryKey" json:"id"`
-	UserID    uint      `gorm:"not null;index" json:"user_id"`
-	Title     string    `gorm:"size:128;not null" json:"title"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID     uint   `gorm:"primaryKey" json:"id"`
+	UserID uint   `gorm:"not null;index" json:"user_id"`
+	Title  string `gorm:"size:128;not null" json:"title"`
+	// Pinned sessions are listed first. SortOrder is a user-chosen position (1 = top);
+	// 0 means not reordered, and such sessions follow the ordered ones by recency.
+	Pinned    bool `gorm:"not null;default:false" json:"pinned"`
+	SortOrder int  `gorm:"not null;default:0" json:"sort_order"`
+	// LLM settings for this session; empty or nil falls back to the server defaults.
+	Model       string   `gorm:"size:128" json:"model"`
+	Temperature *float64 `json:"temperature"`
+	TopP        *float64 `json:"top_p"`
+	MaxTokens   *int     `json:"max_tokens"`
+	// RAGSessionID attaches a RAG session whose documents ground every reply.
+	RAGSessionID *uint `gorm:"index" json:"rag_session_id"`
+	// A fork continues the conversation of ParentSessionID from ForkMessageID without
+	// copying it: its history is the parent's up to that message, then its own messages.
+	ParentSessionID *uint `gorm:"index" json:"parent_session_id"`
+	ForkMessageID   *uint `json:"fork_message_id"`
+	// Summary condenses the messages up to SummaryUntilID that no longer fit the prompt.
+	// SummaryPendingID is set when messages before it stopped fitting; the summary worker
+	// folds them into Summary.
+	Summary          string    `gorm:"type:text" json:"-"`
+	SummaryUntilID   uint      `gorm:"not null;default:0" json:"-"`
+	SummaryPendingID uint      `gorm:"not null;default:0" json:"-"`
+	CreatedAt        time.Time `json:"created_at"`
+	UpdatedAt        time.Time `json:"updated_at"`
 }
@@ -0,0 +1,14 @@
+package model
+
+import "time"
+
+// SessionShare is a public read-only link to a chat session. Only the SHA-256 hash of its
+// token is stored, so the link can be shown only when it is created. A session has at most
+// one share; revoking deletes it.
+type SessionShare struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	SessionID uint      `gorm:"not null;uniqueIndex" json:"session_id"`
+	UserID    uint      `gorm:"not null;index" json:"user_id"`
+	TokenHash string    `gorm:"size:64;not null;uniqueIndex" json:"-"`
+	CreatedAt time.Time `json:"created_at"`
+}
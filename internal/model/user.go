@@ -3,10 +3,14 @@ package model
 import "time"
 
 type User struct {
-	ID           uint      `gorm:"primaryKey" json:"id"`
-	Username     string    `gorm:"size:64;not null;uniqueIndex" json:"username"`
-	Email        string    `gorm:"size:128;not null;uniqueIndex" json:"email"`
-	PasswordHash string    `gorm:"size:255;not null" json:"-"`
+	ID           uint   `gorm:"primaryKey" json:"id"`
+	Username     string `gorm:"size:64;not null;uniqueIndex" json:"username"`
+	Email        string `gorm:"size:128;not null;uniqueIndex" json:"email"`
+	PasswordHash string `gorm:"size:255;not null" json:"-"`
+	// EmailVerifiedAt is set once the user follows the emailed verification link.
+	EmailVerifiedAt *time.Time `json:"email_verified_at"`
+	// TokenVersion is carried in every token issued to the user. Bumping it revokes them all.
+	TokenVersion uint      `gorm:"not null;default:0" json:"-"`
 	CreatedAt    time.Time `json:"created_at"`
 	UpdatedAt    time.Time `json:"updated_at"`
 }
@@ -0,0 +1,19 @@
+package model
+
+import "time"
+
+// VisionSample is a classified image kept (with the user's consent) for re-running against
+// newer models. The image bytes live in the object store under ObjectKey.
+type VisionSample struct {
+	ID            uint      `gorm:"primaryKey" json:"id"`
+	UserID        uint      `gorm:"not null;index" json:"user_id"`
+	ObjectKey     string    `gorm:"size:255;not null" json:"-"`
+	SHA256        string    `gorm:"size:64;not null;index" json:"sha256"`
+	SizeBytes     int       `gorm:"not null" json:"size_bytes"`
+	Model         string    `gorm:"size:128;not null" json:"model"`
+	ModelVersion  int64     `gorm:"not null;default:0" json:"model_version"`
+	TopK          int       `gorm:"not null;default:0" json:"top_k"`
+	Probabilities bool      `gorm:"not null;default:false" json:"probabilities"`
+	Result        string    `gorm:"type:mediumtext" json:"-"` // JSON vision.ClassifyResult
+	CreatedAt     time.Time `json:"created_at"`
+}
@@ -0,0 +1,27 @@
+package model
+
+import "time"
+
+// Workspace is a team sharing resources such as the job posting library.
+type Workspace struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	Name      string    `gorm:"size:128;not null" json:"name"`
+	OwnerID   uint      `gorm:"not null;index" json:"owner_id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	// AllowedModels and AllowedBaseURLs restrict what members may chat with; empty allows
+	// everything. See app.WorkspaceService.CheckModel.
+	AllowedModels   string `gorm:"type:text" json:"-"` // JSON []string
+	AllowedBaseURLs string `gorm:"type:text" json:"-"` // JSON []string
+}
+
+// WorkspaceMember grants a user a role in a workspace. A member added by someone else is
+// "invited" until they accept; only "active" members have access.
+type WorkspaceMember struct {
+	ID          uint      `gorm:"primaryKey" json:"id"`
+	WorkspaceID uint      `gorm:"not null;uniqueIndex:idx_workspace_member" json:"workspace_id"`
+	UserID      uint      `gorm:"not null;uniqueIndex:idx_workspace_member;index" json:"user_id"`
+	Role        string    `gorm:"size:32;not null" json:"role"`
+	Status      string    `gorm:"size:16;not null;default:active" json:"status"`
+	CreatedAt   time.Time `json:"created_at"`
+}
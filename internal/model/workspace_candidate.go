@@ -0,0 +1,18 @@
+package model
+
+import "time"
+
+// WorkspaceCandidate is a resume added to a workspace's candidate pool. The resume stays a
+// RAG document of the member who added it; Skills and YearsExperience are extracted when it
+// is added and drive structured search.
+type WorkspaceCandidate struct {
+	ID              uint      `gorm:"primaryKey" json:"id"`
+	WorkspaceID     uint      `gorm:"not null;uniqueIndex:idx_workspace_candidate_doc" json:"workspace_id"`
+	DocumentID      uint      `gorm:"not null;uniqueIndex:idx_workspace_candidate_doc" json:"document_id"`
+	AddedBy         uint      `gorm:"not null" json:"added_by"`
+	Name            string    `gorm:"size:256" json:"name"`
+	Headline        string    `gorm:"size:512" json:"headline"`
+	Skills          string    `gorm:"type:text" json:"-"` // JSON []string of canonical skills
+	YearsExperience *float64  `json:"years_experience"`   // nil when the resume does not show it
+	CreatedAt       time.Time `json:"created_at"`
+}
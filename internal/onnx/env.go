@@ -0,0 +1,30 @@
+// Package onnx wraps the process-wide ONNX Runtime environment shared by every local model
+// (vision classifier, local embedder, ...).
+package onnx
+
+import (
+	"fmt"
+	"sync"
+
+	ort "github.com/yalue/onnxruntime_go"
+)
+
+var envMu sync.Mutex
+
+// InitEnvironment loads the ONNX Runtime shared library and initializes the global environment.
+// It is safe to call from several models: only the first call does work, and the library path
+// of later callers is ignored once the environment exists.
+func InitEnvironment(libPath string) error {
+	envMu.Lock()
+	defer envMu.Unlock()
+	if ort.IsInitialized() {
+		return nil
+	}
+	if libPath != "" {
+		ort.SetSharedLibraryPath(libPath)
+	}
+	if err := ort.InitializeEnvironment(); err != nil {
+		return fmt.Errorf("onnx init environment: %w", err)
+	}
+	return nil
+}
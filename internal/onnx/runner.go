@@ -0,0 +1,237 @@
+package onnx
+
+import (
+	"fmt"
+	"path/filepath"
+	"sync"
+
+	ort "github.com/yalue/onnxruntime_go"
+)
+
+// RunnerConfig configures a Runner.
+type RunnerConfig struct {
+	ModelPath string
+	LibPath   string
+	// PoolSize is the number of sessions (each with its own tensors) that may run concurrently.
+	PoolSize int
+}
+
+// ModelInfo describes the model loaded by a Runner.
+type ModelInfo struct {
+	File         string    `json:"file"`
+	Version      int64     `json:"version"`
+	Producer     string    `json:"producer,omitempty"`
+	GraphName    string    `json:"graph_name,omitempty"`
+	InputNames   []string  `json:"input_names"`
+	InputShapes  [][]int64 `json:"input_shapes"`
+	OutputNames  []string  `json:"output_names"`
+	OutputShapes [][]int64 `json:"output_shapes"`
+}
+
+// Runner owns a pool of fixed-shape float32 sessions for one model. It loads lazily, hands
+// each Run an idle session, and keeps tensor allocation out of model code. Models with
+// dynamic shapes or integer inputs (such as the local text embedder) manage their own sessions.
+type Runner struct {
+	cfg RunnerConfig
+
+	mu     sync.Mutex
+	inited bool
+	info   ModelInfo
+	pool   chan *pooledSession
+}
+
+type pooledSession struct {
+	session *ort.AdvancedSession
+	inputs  []*ort.Tensor[float32]
+	outputs []*ort.Tensor[float32]
+}
+
+// NewRunner creates a runner; the model is loaded on first Init, Info or Run.
+func NewRunner(cfg RunnerConfig) *Runner {
+	if cfg.PoolSize <= 0 {
+		cfg.PoolSize = 1
+	}
+	return &Runner{cfg: cfg}
+}
+
+// Init loads the runtime and creates the session pool. It is safe to call repeatedly.
+func (r *Runner) Init() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.inited {
+		return nil
+	}
+
+	if err := InitEnvironment(r.cfg.LibPath); err != nil {
+		return err
+	}
+
+	inputs, outputs, err := ort.GetInputOutputInfo(r.cfg.ModelPath)
+	if err != nil {
+		return fmt.Errorf("onnx get input/output info: %w", err)
+	}
+	if len(inputs) == 0 || len(outputs) == 0 {
+		return fmt.Errorf("onnx model has no inputs or outputs")
+	}
+
+	info := ModelInfo{File: filepath.Base(r.cfg.ModelPath)}
+	for _, in := range inputs {
+		info.InputNames = append(info.InputNames, in.Name)
+		info.InputShapes = append(info.InputShapes, fixedShape(in.Dimensions))
+	}
+	for _, out := range outputs {
+		info.OutputNames = append(info.OutputNames, out.Name)
+		info.OutputShapes = append(info.OutputShapes, fixedShape(out.Dimensions))
+	}
+
+	pool := make(chan *pooledSession, r.cfg.PoolSize)
+	for i := 0; i < r.cfg.PoolSize; i++ {
+		ps, err := r.newSession(info)
+		if err != nil {
+			close(pool)
+			for created := range pool {
+				created.destroy()
+			}
+			return err
+		}
+		if i == 0 {
+			if meta, err := ps.session.GetModelMetadata(); err == nil {
+				info.Version, _ = meta.GetVersion()
+				info.Producer, _ = meta.GetProducerName()
+				info.GraphName, _ = meta.GetGraphName()
+				_ = meta.Destroy()
+			}
+		}
+		pool <- ps
+	}
+
+	r.info = info
+	r.pool = pool
+	r.inited = true
+	return nil
+}
+
+func (r *Runner) newSession(info ModelInfo) (*pooledSession, error) {
+	ps := &pooledSession{}
+	for _, shape := range info.InputShapes {
+		t, err := ort.NewEmptyTensor[float32](ort.NewShape(shape...))
+		if err != nil {
+			ps.destroy()
+			return nil, fmt.Errorf("onnx new input tensor: %w", err)
+		}
+		ps.inputs = append(ps.inputs, t)
+	}
+	for _, shape := range info.OutputShapes {
+		t, err := ort.NewEmptyTensor[float32](ort.NewShape(shape...))
+		if err != nil {
+			ps.destroy()
+			return nil, fmt.Errorf("onnx new output tensor: %w", err)
+		}
+		ps.outputs = append(ps.outputs, t)
+	}
+
+	inputValues := make([]ort.Value, len(ps.inputs))
+	for i := range ps.inputs {
+		inputValues[i] = ps.inputs[i]
+	}
+	outputValues := make([]ort.Value, len(ps.outputs))
+	for i := range ps.outputs {
+		outputValues[i] = ps.outputs[i]
+	}
+	session, err := ort.NewAdvancedSession(r.cfg.ModelPath, info.InputNames, info.OutputNames,
+		inputValues, outputValues, nil)
+	if err != nil {
+		ps.destroy()
+		return nil, fmt.Errorf("onnx new session: %w", err)
+	}
+	ps.session = session
+	return ps, nil
+}
+
+func (ps *pooledSession) destroy() {
+	if ps.session != nil {
+		_ = ps.session.Destroy()
+	}
+	for _, t := range ps.inputs {
+		_ = t.Destroy()
+	}
+	for _, t := range ps.outputs {
+		_ = t.Destroy()
+	}
+}
+
+// Info loads the model if needed and returns its metadata and tensor shapes.
+func (r *Runner) Info() (ModelInfo, error) {
+	if err := r.Init(); err != nil {
+		return ModelInfo{File: filepath.Base(r.cfg.ModelPath)}, err
+	}
+	return r.info, nil
+}
+
+// Run copies inputs (one flat slice per model input, in model order) into an idle session,
+// runs it, and passes the output buffers to postprocess. The buffers are only valid until
+// postprocess returns; copy anything that must outlive the call.
+func (r *Runner) Run(inputs [][]float32, postprocess func(outputs [][]float32) error) error {
+	if err := r.Init(); err != nil {
+		return err
+	}
+	if len(inputs) != len(r.info.InputNames) {
+		return fmt.Errorf("onnx model expects %d inputs, got %d", len(r.info.InputNames), len(inputs))
+	}
+
+	ps := <-r.pool
+	defer func() { r.pool <- ps }()
+
+	for i, data := range inputs {
+		dst := ps.inputs[i].GetData()
+		if len(dst) < len(data) {
+			return fmt.Errorf("input tensor %s size %d < provided %d", r.info.InputNames[i], len(dst), len(data))
+		}
+		copy(dst, data)
+	}
+	if err := ps.session.Run(); err != nil {
+		return fmt.Errorf("onnx run: %w", err)
+	}
+
+	outputs := make([][]float32, len(ps.outputs))
+	for i := range ps.outputs {
+		outputs[i] = ps.outputs[i].GetData()
+	}
+	return postprocess(outputs)
+}
+
+// Model pairs a Runner with pre- and post-processing hooks, so adding a new model is
+// mostly a matter of writing those two functions.
+type Model[In, Out any] struct {
+	Runner      *Runner
+	Preprocess  func(In) ([][]float32, error)
+	Postprocess func(outputs [][]float32) (Out, error)
+}
+
+// Predict runs Preprocess, the model, and Postprocess.
+func (m *Model[In, Out]) Predict(in In) (Out, error) {
+	var out Out
+	inputs, err := m.Preprocess(in)
+	if err != nil {
+		return out, err
+	}
+	err = m.Runner.Run(inputs, func(outputs [][]float32) error {
+		var postErr error
+		out, postErr = m.Postprocess(outputs)
+		return postErr
+	})
+	return out, err
+}
+
+// fixedShape replaces dynamic (negative) dimensions with 1 so a static tensor can be
+// allocated; models served by Runner are expected to use batch size 1.
+func fixedShape(dims ort.Shape) []int64 {
+	shape := make([]int64, len(dims))
+	for i, d := range dims {
+		if d <= 0 {
+			d = 1
+		}
+		shape[i] = d
+	}
+	return shape
+}
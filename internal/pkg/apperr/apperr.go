@@ -0,0 +1,151 @@
+// Package apperr is the error type services return for failures a client should see: each
+// carries the HTTP status and API code to answer with and a message that is safe to show,
+// plus an optional internal cause that is only logged.
+package apperr
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// API error codes, returned in the code field of error responses.
+const (
+	CodeOK                  = 0
+	CodeBadRequest          = 40000
+	CodeUnauthorized        = 40100
+	CodeForbidden           = 40300
+	CodeInternalServer      = 50000
+	CodeFeatureUnavailable  = 50300
+	CodeProviderBusy        = 50301
+	CodeUsernameExists      = 40001
+	CodeEmailExists         = 40002
+	CodeInvalidCredentials  = 40101
+	CodeRouteNotFound       = 40400
+	CodeSessionNotFound     = 40401
+	CodeQuotaExceeded       = 42900
+	CodeRateLimited         = 42901
+	CodeTooManyInFlight     = 42902
+	CodeDocumentNotFound    = 40402
+	CodeSampleNotFound      = 40403
+	CodeStreamNotFound      = 40404
+	CodeApplicationNotFound = 40405
+	CodeMessageNotFound     = 40406
+	CodeBulletNotFound      = 40407
+	CodePortfolioNotFound   = 40408
+	CodeRepoNotFound        = 40409
+	CodeWorkspaceNotFound   = 40410
+	CodeJobPostingNotFound  = 40411
+	CodeUserNotFound        = 40412
+	CodeReportNotFound      = 40413
+	CodeSchemaNotFound      = 40414
+	CodeCandidateNotFound   = 40415
+	CodeShareNotFound       = 40416
+	CodeScheduledNotFound   = 40417
+	CodeExportNotFound      = 40418
+	CodeInvalidTransition   = 40900
+	CodeBulletConflict      = 40901
+	CodeMemberExists        = 40902
+	CodeReportNotReady      = 40903
+	CodeCandidateExists     = 40904
+	CodeSessionHasForks     = 40905
+	CodeScheduleNotPending  = 40906
+	CodeScheduleLimit       = 40907
+	CodeSessionBusy         = 40908
+	CodeExportNotReady      = 40909
+	CodeDocumentNotReady    = 40910
+	CodeExportInProgress    = 40911
+	CodeMessageTooLong      = 41300
+)
+
+// Error is a failure with the response it maps to. Services declare sentinel errors with
+// New and return them directly, with Withf for extra detail or with Wrap for an internal
+// cause; errors.Is matches every variant against its sentinel.
+type Error struct {
+	Status  int
+	Code    int
+	Message string // shown to clients
+	Cause   error  // logged only
+
+	kind *Error // the sentinel this error derives from
+}
+
+// New returns a sentinel error.
+func New(status, code int, message string) *Error {
+	e := &Error{Status: status, Code: code, Message: message}
+	e.kind = e
+	return e
+}
+
+// BadRequest returns a sentinel for invalid input answered with 400.
+func BadRequest(message string) *Error {
+	return New(http.StatusBadRequest, CodeBadRequest, message)
+}
+
+// NotFound returns a sentinel answered with 404 and code.
+func NotFound(code int, message string) *Error {
+	return New(http.StatusNotFound, code, message)
+}
+
+// Conflict returns a sentinel answered with 409 and code.
+func Conflict(code int, message string) *Error {
+	return New(http.StatusConflict, code, message)
+}
+
+// Internal is an unexpected failure answered with 500 and message; cause is only logged.
+func Internal(message string, cause error) *Error {
+	return &Error{Status: http.StatusInternalServerError, Code: CodeInternalServer, Message: message, Cause: cause}
+}
+
+// Upstream is a failure of a model or another upstream service answered with 502 and
+// message; cause is only logged.
+func Upstream(message string, cause error) *Error {
+	return &Error{Status: http.StatusBadGateway, Code: CodeInternalServer, Message: message, Cause: cause}
+}
+
+func (e *Error) Error() string {
+	if e.Cause != nil {
+		return e.Message + ": " + e.Cause.Error()
+	}
+	return e.Message
+}
+
+func (e *Error) Unwrap() error { return e.Cause }
+
+// Is reports whether target is the sentinel e derives from.
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && e.kind != nil && t.kind == e.kind
+}
+
+// Withf returns a copy of e whose public message has the formatted detail appended.
+func (e *Error) Withf(format string, args ...any) *Error {
+	c := *e
+	c.Message = e.Message + ": " + fmt.Sprintf(format, args...)
+	return &c
+}
+
+// Wrap returns a copy of e with cause attached for logging.
+func (e *Error) Wrap(cause error) *Error {
+	c := *e
+	c.Cause = cause
+	return &c
+}
+
+// As returns the first *Error in err's chain.
+func As(err error) (*Error, bool) {
+	var e *Error
+	if errors.As(err, &e) {
+		return e, true
+	}
+	return nil, false
+}
+
+// Message returns the message to show a client for err: the public message of an *Error,
+// or fallback for anything else.
+func Message(err error, fallback string) string {
+	if e, ok := As(err); ok {
+		return e.Message
+	}
+	return fallback
+}
@@ -0,0 +1,61 @@
+// Package authz carries the authenticated principal through a context and records access
+// decisions in one log format, so checks made in middleware and in the services can be
+// audited together.
+package authz
+
+import (
+	"context"
+	"log"
+
+	"gopherai-resume/internal/pkg/requestid"
+)
+
+// Principal is the authenticated caller of a request.
+type Principal struct {
+	UserID   uint
+	Username string
+	// Admin is set for usernames listed in auth.admin_usernames.
+	Admin bool
+}
+
+// Action is what a caller wants to do with a resource.
+type Action string
+
+const (
+	Read  Action = "read"
+	Write Action = "write"
+	// Administer is access to admin-only endpoints.
+	Administer Action = "admin"
+)
+
+// Decision outcomes that are recorded. Ordinary grants are not logged.
+const (
+	Deny     = "deny"
+	Override = "override" // granted only because the caller is an admin
+)
+
+type ctxKey struct{}
+
+// With returns a copy of ctx carrying p.
+func With(ctx context.Context, p Principal) context.Context {
+	return context.WithValue(ctx, ctxKey{}, p)
+}
+
+// From returns the principal in ctx; ok is false outside an authenticated request, e.g.
+// in background workers.
+func From(ctx context.Context) (Principal, bool) {
+	p, ok := ctx.Value(ctxKey{}).(Principal)
+	return p, ok
+}
+
+// IsAdmin reports whether ctx carries an admin principal for userID.
+func IsAdmin(ctx context.Context, userID uint) bool {
+	p, ok := From(ctx)
+	return ok && p.Admin && p.UserID == userID
+}
+
+// Record logs a denied or admin-overridden access to the resource kind/id by userID.
+func Record(ctx context.Context, decision string, userID uint, action Action, kind string, id uint, reason string) {
+	log.Printf("authz %s: user=%d action=%s resource=%s/%d reason=%q request_id=%s",
+		decision, userID, action, kind, id, reason, requestid.From(ctx))
+}
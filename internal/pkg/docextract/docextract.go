@@ -0,0 +1,139 @@
+// Package docextract extracts plain text from uploaded documents: PDF, DOCX, HTML,
+// Markdown and plain text.
+package docextract
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+)
+
+// Format is a supported document format.
+type Format string
+
+const (
+	FormatPDF      Format = "pdf"
+	FormatDOCX     Format = "docx"
+	FormatHTML     Format = "html"
+	FormatMarkdown Format = "markdown"
+	FormatText     Format = "text"
+)
+
+// PageBreak separates the pages in the text of a PDF, as a form feed does in pdftotext's
+// output, so that positions in the text can be mapped back to pages.
+const PageBreak = "\f"
+
+var (
+	ErrUnsupported = errors.New("unsupported document format")
+	ErrNotUTF8     = errors.New("text is not valid UTF-8")
+)
+
+var extensions = map[string]Format{
+	".pdf":      FormatPDF,
+	".docx":     FormatDOCX,
+	".html":     FormatHTML,
+	".htm":      FormatHTML,
+	".md":       FormatMarkdown,
+	".markdown": FormatMarkdown,
+	".txt":      FormatText,
+}
+
+// Extensions lists the file extensions Detect recognizes, for messages to users.
+func Extensions() []string {
+	return []string{".pdf", ".docx", ".html", ".htm", ".md", ".markdown", ".txt"}
+}
+
+// Detect picks the format of a file from its name, or from its content when the extension
+// is missing or unknown. The content wins when the two disagree on a binary format, so a
+// PDF renamed to .txt is still read as a PDF.
+func Detect(filename string, data []byte) (Format, error) {
+	sniffed := sniff(data)
+	byName, named := extensions[strings.ToLower(filepath.Ext(filename))]
+	switch {
+	case sniffed == FormatPDF || sniffed == FormatDOCX:
+		return sniffed, nil
+	case named && byName != FormatPDF && byName != FormatDOCX:
+		return byName, nil
+	case !named && sniffed != "":
+		return sniffed, nil
+	}
+	return "", ErrUnsupported
+}
+
+// sniff recognizes a format from the content, or returns "".
+func sniff(data []byte) Format {
+	switch contentType := http.DetectContentType(data); {
+	case contentType == "application/pdf":
+		return FormatPDF
+	case contentType == "application/zip":
+		if isDOCX(data) {
+			return FormatDOCX
+		}
+	case strings.HasPrefix(contentType, "text/html"):
+		return FormatHTML
+	case strings.HasPrefix(contentType, "text/plain"):
+		return FormatText
+	}
+	return ""
+}
+
+func isDOCX(data []byte) bool {
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return false
+	}
+	for _, f := range archive.File {
+		if f.Name == docxBody {
+			return true
+		}
+	}
+	return false
+}
+
+// ExtractText reads all of r and extracts its text, detecting the format with Detect. It
+// returns the format used. A document without text gives an empty string and no error.
+func ExtractText(r io.Reader, filename string) (string, Format, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return "", "", err
+	}
+	if len(data) == 0 {
+		return "", "", nil
+	}
+	format, err := Detect(filename, data)
+	if err != nil {
+		return "", "", err
+	}
+	var text string
+	switch format {
+	case FormatPDF:
+		text, err = extractPDF(data)
+	case FormatDOCX:
+		text, err = extractDOCX(data)
+	case FormatHTML:
+		text, err = extractHTML(data)
+	case FormatMarkdown:
+		text, err = extractMarkdown(data)
+	default:
+		text, err = decodeText(data)
+	}
+	if err != nil {
+		return "", format, err
+	}
+	return text, format, nil
+}
+
+// decodeText returns data as a string without a UTF-8 byte order mark, and with Windows
+// line endings turned into \n.
+func decodeText(data []byte) (string, error) {
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+	if !utf8.Valid(data) {
+		return "", ErrNotUTF8
+	}
+	return strings.ReplaceAll(string(data), "\r\n", "\n"), nil
+}
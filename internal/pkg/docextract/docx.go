@@ -0,0 +1,98 @@
+package docextract
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"io"
+	"strings"
+)
+
+// docxBody is the part of a DOCX archive that holds the main document.
+const docxBody = "word/document.xml"
+
+// maxDOCXBody caps the uncompressed document part, as a guard against zip bombs.
+const maxDOCXBody = 50 << 20
+
+var errDOCXTooLarge = errors.New("docx document is too large")
+
+// extractDOCX reads the text of the main document part: one line per paragraph and per
+// table row, with the row's cells separated by tabs. Headers, footers, comments and images
+// are skipped.
+func extractDOCX(data []byte) (string, error) {
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return "", err
+	}
+	var body *zip.File
+	for _, f := range archive.File {
+		if f.Name == docxBody {
+			body = f
+			break
+		}
+	}
+	if body == nil {
+		return "", ErrUnsupported
+	}
+	rc, err := body.Open()
+	if err != nil {
+		return "", err
+	}
+	defer rc.Close()
+	limited := &io.LimitedReader{R: rc, N: maxDOCXBody + 1}
+
+	var out strings.Builder
+	decoder := xml.NewDecoder(limited)
+	inText := false
+	cellDepth := 0
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			if limited.N <= 0 {
+				return "", errDOCXTooLarge
+			}
+			return "", err
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "t":
+				inText = true
+			case "tc":
+				cellDepth++
+			case "tab":
+				out.WriteByte('\t')
+			case "br", "cr":
+				out.WriteByte('\n')
+			}
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "t":
+				inText = false
+			case "p":
+				if cellDepth > 0 {
+					out.WriteByte(' ')
+				} else {
+					out.WriteByte('\n')
+				}
+			case "tc":
+				cellDepth--
+				out.WriteByte('\t')
+			case "tr":
+				out.WriteByte('\n')
+			}
+		case xml.CharData:
+			if inText {
+				out.Write(t)
+			}
+		}
+	}
+	if limited.N <= 0 {
+		return "", errDOCXTooLarge
+	}
+	return out.String(), nil
+}
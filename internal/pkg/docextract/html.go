@@ -0,0 +1,121 @@
+package docextract
+
+import (
+	"bytes"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// htmlSkipped are elements whose content is never text a reader sees.
+var htmlSkipped = map[atom.Atom]bool{
+	atom.Script:   true,
+	atom.Style:    true,
+	atom.Noscript: true,
+	atom.Template: true,
+	atom.Svg:      true,
+	atom.Iframe:   true,
+	atom.Head:     true,
+}
+
+// htmlBlocks are elements that start a new line, so paragraphs and list items stay apart.
+var htmlBlocks = map[atom.Atom]bool{
+	atom.P: true, atom.Div: true, atom.Br: true, atom.Li: true, atom.Tr: true,
+	atom.H1: true, atom.H2: true, atom.H3: true, atom.H4: true, atom.H5: true, atom.H6: true,
+	atom.Section: true, atom.Article: true, atom.Header: true, atom.Footer: true,
+	atom.Blockquote: true, atom.Pre: true, atom.Table: true, atom.Ul: true, atom.Ol: true,
+	atom.Dt: true, atom.Dd: true, atom.Hr: true, atom.Title: true,
+}
+
+// extractHTML returns the visible text of an HTML page, the title first. Whitespace is
+// collapsed outside <pre>, and block elements end lines.
+func extractHTML(data []byte) (string, error) {
+	doc, err := html.Parse(bytes.NewReader(data))
+	if err != nil {
+		return "", err
+	}
+	var out strings.Builder
+	if title := findElement(doc, atom.Title); title != nil {
+		writeHTMLText(&out, title, false)
+		out.WriteString("\n\n")
+	}
+	writeHTMLText(&out, doc, false)
+	return collapseBlankLines(out.String()), nil
+}
+
+func findElement(n *html.Node, a atom.Atom) *html.Node {
+	if n.Type == html.ElementNode && n.DataAtom == a {
+		return n
+	}
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		if found := findElement(child, a); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+func writeHTMLText(out *strings.Builder, n *html.Node, pre bool) {
+	switch n.Type {
+	case html.TextNode:
+		if pre {
+			out.WriteString(n.Data)
+			return
+		}
+		written := out.String()
+		if startsWithSpace(n.Data) && written != "" && !strings.HasSuffix(written, "\n") && !strings.HasSuffix(written, " ") {
+			out.WriteByte(' ')
+		}
+		if text := strings.Join(strings.Fields(n.Data), " "); text != "" {
+			out.WriteString(text)
+			if endsWithSpace(n.Data) {
+				out.WriteByte(' ')
+			}
+		}
+		return
+	case html.ElementNode:
+		if htmlSkipped[n.DataAtom] {
+			return
+		}
+		pre = pre || n.DataAtom == atom.Pre
+	}
+	block := n.Type == html.ElementNode && htmlBlocks[n.DataAtom]
+	if block {
+		out.WriteByte('\n')
+	}
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		writeHTMLText(out, child, pre)
+	}
+	if block {
+		out.WriteByte('\n')
+	}
+}
+
+func startsWithSpace(s string) bool {
+	return s != "" && strings.TrimLeft(s, " \t\r\n") != s
+}
+
+func endsWithSpace(s string) bool {
+	return s != "" && strings.TrimRight(s, " \t\r\n") != s
+}
+
+// collapseBlankLines trims trailing spaces and keeps at most one blank line between others.
+func collapseBlankLines(text string) string {
+	lines := strings.Split(text, "\n")
+	out := make([]string, 0, len(lines))
+	blank := true
+	for _, line := range lines {
+		line = strings.TrimRight(line, " \t\r")
+		if strings.TrimSpace(line) == "" {
+			if !blank {
+				out = append(out, "")
+			}
+			blank = true
+			continue
+		}
+		out = append(out, line)
+		blank = false
+	}
+	return strings.TrimSpace(strings.Join(out, "\n"))
+}
@@ -0,0 +1,64 @@
+package docextract
+
+import (
+	"regexp"
+	"strings"
+)
+
+var (
+	markdownImage    = regexp.MustCompile(`!\[([^\]]*)\]\([^)]*\)`)
+	markdownLink     = regexp.MustCompile(`\[([^\]]+)\]\([^)]*\)`)
+	markdownRefDef   = regexp.MustCompile(`^\s{0,3}\[[^\]]+\]:\s+\S+`)
+	markdownHeading  = regexp.MustCompile(`^\s{0,3}#{1,6}\s+`)
+	markdownEmphasis = regexp.MustCompile(`(\*\*|__|~~)(\S(?:.*?\S)?)(\*\*|__|~~)`)
+	markdownComment  = regexp.MustCompile(`(?s)<!--.*?-->`)
+)
+
+// extractMarkdown turns Markdown into plain text for retrieval. Front matter, comments,
+// link targets and reference definitions are dropped, and heading marks and bold or
+// strikethrough markers are removed. Code blocks keep their content without the fences,
+// and list markers and paragraphs are kept as they are.
+func extractMarkdown(data []byte) (string, error) {
+	text, err := decodeText(data)
+	if err != nil {
+		return "", err
+	}
+	text = stripFrontMatter(text)
+	text = markdownComment.ReplaceAllString(text, "")
+
+	lines := strings.Split(text, "\n")
+	out := make([]string, 0, len(lines))
+	inCode := false
+	for _, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~") {
+			inCode = !inCode
+			continue
+		}
+		if inCode {
+			out = append(out, line)
+			continue
+		}
+		if markdownRefDef.MatchString(line) {
+			continue
+		}
+		line = markdownHeading.ReplaceAllString(line, "")
+		line = markdownImage.ReplaceAllString(line, "$1")
+		line = markdownLink.ReplaceAllString(line, "$1")
+		line = markdownEmphasis.ReplaceAllString(line, "$2")
+		out = append(out, line)
+	}
+	return strings.Join(out, "\n"), nil
+}
+
+// stripFrontMatter removes a leading YAML front matter block delimited by "---" lines.
+func stripFrontMatter(text string) string {
+	if !strings.HasPrefix(text, "---\n") {
+		return text
+	}
+	end := strings.Index(text[4:], "\n---\n")
+	if end < 0 {
+		return text
+	}
+	return text[4+end+5:]
+}
@@ -0,0 +1,38 @@
+package docextract
+
+import (
+	"bytes"
+	"strings"
+
+	"github.com/ledongthuc/pdf"
+)
+
+// extractPDF extracts the plain text of a PDF, with PageBreak between pages. A PDF without
+// extractable text, such as a scan, gives an empty string and no error.
+func extractPDF(data []byte) (string, error) {
+	pdfReader, err := pdf.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return "", err
+	}
+	// Fonts are shared by pages, so their character maps are parsed once.
+	fonts := make(map[string]*pdf.Font)
+	pages := make([]string, 0, pdfReader.NumPage())
+	for i := 1; i <= pdfReader.NumPage(); i++ {
+		page := pdfReader.Page(i)
+		for _, name := range page.Fonts() {
+			if _, ok := fonts[name]; !ok {
+				font := page.Font(name)
+				fonts[name] = &font
+			}
+		}
+		text, err := page.GetPlainText(fonts)
+		if err != nil {
+			return "", err
+		}
+		pages = append(pages, strings.ReplaceAll(text, PageBreak, "\n"))
+	}
+	if strings.TrimSpace(strings.Join(pages, "")) == "" {
+		return "", nil
+	}
+	return strings.Join(pages, PageBreak), nil
+}
@@ -0,0 +1,390 @@
+// Package jsonresume implements the JSON Resume schema (https://jsonresume.org/schema) and
+// renders resumes as the Markdown-style text the rest of the app stores.
+package jsonresume
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// SchemaURL is written to exported documents' "$schema" field.
+const SchemaURL = "https://raw.githubusercontent.com/jsonresume/resume-schema/v1.0.0/schema.json"
+
+var ErrEmptyResume = errors.New("resume has no content")
+
+type Resume struct {
+	Schema       string        `json:"$schema,omitempty"`
+	Basics       Basics        `json:"basics"`
+	Work         []Work        `json:"work,omitempty"`
+	Volunteer    []Volunteer   `json:"volunteer,omitempty"`
+	Education    []Education   `json:"education,omitempty"`
+	Awards       []Award       `json:"awards,omitempty"`
+	Certificates []Certificate `json:"certificates,omitempty"`
+	Publications []Publication `json:"publications,omitempty"`
+	Skills       []Skill       `json:"skills,omitempty"`
+	Languages    []Language    `json:"languages,omitempty"`
+	Interests    []Interest    `json:"interests,omitempty"`
+	References   []Reference   `json:"references,omitempty"`
+	Projects     []Project     `json:"projects,omitempty"`
+	// Custom holds deployment-defined fields (e.g. security_clearance) outside the standard
+	// schema, keyed by field name.
+	Custom map[string]interface{} `json:"custom,omitempty"`
+}
+
+type Basics struct {
+	Name     string    `json:"name,omitempty"`
+	Label    string    `json:"label,omitempty"`
+	Image    string    `json:"image,omitempty"`
+	Email    string    `json:"email,omitempty"`
+	Phone    string    `json:"phone,omitempty"`
+	URL      string    `json:"url,omitempty"`
+	Summary  string    `json:"summary,omitempty"`
+	Location *Location `json:"location,omitempty"`
+	Profiles []Profile `json:"profiles,omitempty"`
+}
+
+type Location struct {
+	Address     string `json:"address,omitempty"`
+	PostalCode  string `json:"postalCode,omitempty"`
+	City        string `json:"city,omitempty"`
+	CountryCode string `json:"countryCode,omitempty"`
+	Region      string `json:"region,omitempty"`
+}
+
+type Profile struct {
+	Network  string `json:"network,omitempty"`
+	Username string `json:"username,omitempty"`
+	URL      string `json:"url,omitempty"`
+}
+
+type Work struct {
+	Name       string   `json:"name,omitempty"`
+	Location   string   `json:"location,omitempty"`
+	Position   string   `json:"position,omitempty"`
+	URL        string   `json:"url,omitempty"`
+	StartDate  string   `json:"startDate,omitempty"`
+	EndDate    string   `json:"endDate,omitempty"`
+	Summary    string   `json:"summary,omitempty"`
+	Highlights []string `json:"highlights,omitempty"`
+}
+
+type Volunteer struct {
+	Organization string   `json:"organization,omitempty"`
+	Position     string   `json:"position,omitempty"`
+	URL          string   `json:"url,omitempty"`
+	StartDate    string   `json:"startDate,omitempty"`
+	EndDate      string   `json:"endDate,omitempty"`
+	Summary      string   `json:"summary,omitempty"`
+	Highlights   []string `json:"highlights,omitempty"`
+}
+
+type Education struct {
+	Institution string   `json:"institution,omitempty"`
+	URL         string   `json:"url,omitempty"`
+	Area        string   `json:"area,omitempty"`
+	StudyType   string   `json:"studyType,omitempty"`
+	StartDate   string   `json:"startDate,omitempty"`
+	EndDate     string   `json:"endDate,omitempty"`
+	Score       string   `json:"score,omitempty"`
+	Courses     []string `json:"courses,omitempty"`
+}
+
+type Award struct {
+	Title   string `json:"title,omitempty"`
+	Date    string `json:"date,omitempty"`
+	Awarder string `json:"awarder,omitempty"`
+	Summary string `json:"summary,omitempty"`
+}
+
+type Certificate struct {
+	Name   string `json:"name,omitempty"`
+	Date   string `json:"date,omitempty"`
+	Issuer string `json:"issuer,omitempty"`
+	URL    string `json:"url,omitempty"`
+}
+
+type Publication struct {
+	Name        string `json:"name,omitempty"`
+	Publisher   string `json:"publisher,omitempty"`
+	ReleaseDate string `json:"releaseDate,omitempty"`
+	URL         string `json:"url,omitempty"`
+	Summary     string `json:"summary,omitempty"`
+}
+
+type Skill struct {
+	Name     string   `json:"name,omitempty"`
+	Level    string   `json:"level,omitempty"`
+	Keywords []string `json:"keywords,omitempty"`
+}
+
+type Language struct {
+	Language string `json:"language,omitempty"`
+	Fluency  string `json:"fluency,omitempty"`
+}
+
+type Interest struct {
+	Name     string   `json:"name,omitempty"`
+	Keywords []string `json:"keywords,omitempty"`
+}
+
+type Reference struct {
+	Name      string `json:"name,omitempty"`
+	Reference string `json:"reference,omitempty"`
+}
+
+type Project struct {
+	Name        string   `json:"name,omitempty"`
+	Description string   `json:"description,omitempty"`
+	Highlights  []string `json:"highlights,omitempty"`
+	Keywords    []string `json:"keywords,omitempty"`
+	StartDate   string   `json:"startDate,omitempty"`
+	EndDate     string   `json:"endDate,omitempty"`
+	URL         string   `json:"url,omitempty"`
+	Roles       []string `json:"roles,omitempty"`
+	Entity      string   `json:"entity,omitempty"`
+	Type        string   `json:"type,omitempty"`
+}
+
+// Parse decodes a JSON Resume document. Unknown fields are ignored, as the schema allows
+// extensions; a document without a name or any section is rejected.
+func Parse(data []byte) (*Resume, error) {
+	var r Resume
+	if err := json.Unmarshal(data, &r); err != nil {
+		return nil, fmt.Errorf("decode json resume failed: %w", err)
+	}
+	if r.IsEmpty() {
+		return nil, ErrEmptyResume
+	}
+	return &r, nil
+}
+
+// IsEmpty reports whether the resume has neither a name nor any section entries. Custom
+// fields alone do not count.
+func (r *Resume) IsEmpty() bool {
+	return strings.TrimSpace(r.Basics.Name) == "" && strings.TrimSpace(r.Basics.Summary) == "" &&
+		len(r.Work)+len(r.Volunteer)+len(r.Education)+len(r.Awards)+len(r.Certificates)+
+			len(r.Publications)+len(r.Skills)+len(r.Languages)+len(r.Interests)+len(r.Projects) == 0
+}
+
+// Markdown renders the resume as text: the name as a "#" heading, contact details, then one
+// "##" section per schema section with entries as "###" headings and highlights as "-"
+// bullets. References are left out, as they rarely belong in the resume text itself.
+func (r *Resume) Markdown() string {
+	var b strings.Builder
+	basics := r.Basics
+	if basics.Name != "" {
+		fmt.Fprintf(&b, "# %s\n", basics.Name)
+	}
+	if basics.Label != "" {
+		fmt.Fprintf(&b, "%s\n", basics.Label)
+	}
+	var contact []string
+	for _, v := range []string{basics.Email, basics.Phone, basics.URL, formatLocation(basics.Location)} {
+		if v != "" {
+			contact = append(contact, v)
+		}
+	}
+	for _, p := range basics.Profiles {
+		switch {
+		case p.URL != "":
+			contact = append(contact, p.URL)
+		case p.Network != "" && p.Username != "":
+			contact = append(contact, p.Network+": "+p.Username)
+		}
+	}
+	if len(contact) > 0 {
+		fmt.Fprintf(&b, "%s\n", strings.Join(contact, " | "))
+	}
+	if basics.Summary != "" {
+		section(&b, "Summary")
+		fmt.Fprintf(&b, "%s\n", basics.Summary)
+	}
+
+	if len(r.Work) > 0 {
+		section(&b, "Experience")
+		for _, w := range r.Work {
+			entry(&b, join(" - ", w.Position, w.Name), dates(w.StartDate, w.EndDate), w.Location)
+			paragraph(&b, w.Summary)
+			bullets(&b, w.Highlights)
+		}
+	}
+	if len(r.Projects) > 0 {
+		section(&b, "Projects")
+		for _, p := range r.Projects {
+			entry(&b, p.Name, dates(p.StartDate, p.EndDate), p.URL)
+			paragraph(&b, p.Description)
+			bullets(&b, p.Highlights)
+			if len(p.Keywords) > 0 {
+				fmt.Fprintf(&b, "Technologies: %s\n", strings.Join(p.Keywords, ", "))
+			}
+		}
+	}
+	if len(r.Education) > 0 {
+		section(&b, "Education")
+		for _, e := range r.Education {
+			entry(&b, join(", ", join(" in ", e.StudyType, e.Area), e.Institution), dates(e.StartDate, e.EndDate), "")
+			if e.Score != "" {
+				fmt.Fprintf(&b, "Score: %s\n", e.Score)
+			}
+			if len(e.Courses) > 0 {
+				fmt.Fprintf(&b, "Courses: %s\n", strings.Join(e.Courses, ", "))
+			}
+		}
+	}
+	if len(r.Skills) > 0 {
+		section(&b, "Skills")
+		for _, s := range r.Skills {
+			line := join(" (", s.Name, s.Level)
+			if s.Level != "" && s.Name != "" {
+				line += ")"
+			}
+			if len(s.Keywords) > 0 {
+				line = join(": ", line, strings.Join(s.Keywords, ", "))
+			}
+			fmt.Fprintf(&b, "- %s\n", line)
+		}
+	}
+	if len(r.Volunteer) > 0 {
+		section(&b, "Volunteering")
+		for _, v := range r.Volunteer {
+			entry(&b, join(" - ", v.Position, v.Organization), dates(v.StartDate, v.EndDate), "")
+			paragraph(&b, v.Summary)
+			bullets(&b, v.Highlights)
+		}
+	}
+	if len(r.Awards) > 0 {
+		section(&b, "Awards")
+		for _, a := range r.Awards {
+			fmt.Fprintf(&b, "- %s\n", join(" - ", join(", ", a.Title, a.Awarder, a.Date), a.Summary))
+		}
+	}
+	if len(r.Certificates) > 0 {
+		section(&b, "Certifications")
+		for _, c := range r.Certificates {
+			fmt.Fprintf(&b, "- %s\n", join(", ", c.Name, c.Issuer, c.Date))
+		}
+	}
+	if len(r.Publications) > 0 {
+		section(&b, "Publications")
+		for _, p := range r.Publications {
+			fmt.Fprintf(&b, "- %s\n", join(" - ", join(", ", p.Name, p.Publisher, p.ReleaseDate), p.Summary))
+		}
+	}
+	if len(r.Languages) > 0 {
+		section(&b, "Languages")
+		for _, l := range r.Languages {
+			fmt.Fprintf(&b, "- %s\n", join(": ", l.Language, l.Fluency))
+		}
+	}
+	if len(r.Interests) > 0 {
+		section(&b, "Interests")
+		for _, i := range r.Interests {
+			fmt.Fprintf(&b, "- %s\n", join(": ", i.Name, strings.Join(i.Keywords, ", ")))
+		}
+	}
+	if len(r.Custom) > 0 {
+		section(&b, "Additional Information")
+		names := make([]string, 0, len(r.Custom))
+		for name := range r.Custom {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if value := formatCustomValue(r.Custom[name]); value != "" {
+				fmt.Fprintf(&b, "- %s: %s\n", fieldLabel(name), value)
+			}
+		}
+	}
+	return strings.TrimSpace(b.String()) + "\n"
+}
+
+// fieldLabel turns a snake_case field name into a label: "visa_status" -> "Visa status".
+func fieldLabel(name string) string {
+	label := strings.ReplaceAll(name, "_", " ")
+	if label == "" {
+		return label
+	}
+	return strings.ToUpper(label[:1]) + label[1:]
+}
+
+func formatCustomValue(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case bool:
+		if v {
+			return "Yes"
+		}
+		return "No"
+	case []interface{}:
+		parts := make([]string, 0, len(v))
+		for _, item := range v {
+			parts = append(parts, formatCustomValue(item))
+		}
+		return join(", ", parts...)
+	case []string:
+		return join(", ", v...)
+	}
+	return strings.TrimSpace(fmt.Sprint(value))
+}
+
+func section(b *strings.Builder, title string) {
+	fmt.Fprintf(b, "\n## %s\n", title)
+}
+
+func entry(b *strings.Builder, title, period, extra string) {
+	if title == "" {
+		title = "Untitled"
+	}
+	fmt.Fprintf(b, "### %s\n", title)
+	if meta := join(" | ", period, extra); meta != "" {
+		fmt.Fprintf(b, "%s\n", meta)
+	}
+}
+
+func paragraph(b *strings.Builder, text string) {
+	if text = strings.TrimSpace(text); text != "" {
+		fmt.Fprintf(b, "%s\n", text)
+	}
+}
+
+func bullets(b *strings.Builder, items []string) {
+	for _, item := range items {
+		if item = strings.TrimSpace(item); item != "" {
+			fmt.Fprintf(b, "- %s\n", item)
+		}
+	}
+}
+
+func dates(start, end string) string {
+	switch {
+	case start == "" && end == "":
+		return ""
+	case end == "":
+		return start + " - Present"
+	case start == "":
+		return end
+	}
+	return start + " - " + end
+}
+
+func formatLocation(l *Location) string {
+	if l == nil {
+		return ""
+	}
+	return join(", ", l.City, l.Region, l.CountryCode)
+}
+
+// join joins the non-empty parts with sep.
+func join(sep string, parts ...string) string {
+	var kept []string
+	for _, p := range parts {
+		if p = strings.TrimSpace(p); p != "" {
+			kept = append(kept, p)
+		}
+	}
+	return strings.Join(kept, sep)
+}
@@ -10,18 +10,48 @@ import (
 type Claims struct {
 	UserID   uint   `json:"user_id"`
 	Username string `json:"username"`
+	// OrigIssuedAt is when the user signed in. Renewed tokens keep it, so a sliding session
+	// can be capped however often it is renewed.
+	OrigIssuedAt *jwt.NumericDate `json:"orig_iat,omitempty"`
+	// TokenVersion is the user's token version when the token was issued. A token whose
+	// version no longer matches the user's has been revoked.
+	TokenVersion uint `json:"tv,omitempty"`
 	jwt.RegisteredClaims
 }
 
-func GenerateToken(secret string, expiresIn time.Duration, userID uint, username string) (string, error) {
+// SessionStart is when the session of the token began: its orig_iat, or its iat for tokens
+// issued before orig_iat was added.
+func (c *Claims) SessionStart() time.Time {
+	if c.OrigIssuedAt != nil {
+		return c.OrigIssuedAt.Time
+	}
+	if c.IssuedAt != nil {
+		return c.IssuedAt.Time
+	}
+	return time.Time{}
+}
+
+func GenerateToken(secret string, expiresIn time.Duration, userID uint, username string, tokenVersion uint) (string, error) {
 	now := time.Now()
+	return signToken(secret, now, now.Add(expiresIn), userID, username, tokenVersion)
+}
+
+// RenewToken issues a token for the same user and session as claims that expires at
+// expiresAt. The session start and token version are carried over from claims.
+func RenewToken(secret string, claims *Claims, expiresAt time.Time) (string, error) {
+	return signToken(secret, claims.SessionStart(), expiresAt, claims.UserID, claims.Username, claims.TokenVersion)
+}
+
+func signToken(secret string, sessionStart, expiresAt time.Time, userID uint, username string, tokenVersion uint) (string, error) {
 	claims := Claims{
-		UserID:   userID,
-		Username: username,
+		UserID:       userID,
+		Username:     username,
+		OrigIssuedAt: jwt.NewNumericDate(sessionStart),
+		TokenVersion: tokenVersion,
 		RegisteredClaims: jwt.RegisteredClaims{
 			Subject:   fmt.Sprintf("%d", userID),
-			IssuedAt:  jwt.NewNumericDate(now),
-			ExpiresAt: jwt.NewNumericDate(now.Add(expiresIn)),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
 		},
 	}
 
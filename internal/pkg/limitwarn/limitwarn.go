@@ -0,0 +1,98 @@
+// Package limitwarn collects warnings about users approaching their quotas and rate limits
+// while a request is served, so the response can pass them on before requests start
+// failing with 429.
+package limitwarn
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// Kinds of limits warned about.
+const (
+	KindQuota     = "quota"      // daily quotas, per metric
+	KindRateLimit = "rate_limit" // per-minute budgets, per route
+	KindStorage   = "storage"    // totals a user may keep, per metric
+)
+
+// Thresholds are the shares of a limit, in percent, from which a warning is given.
+var Thresholds = []int{95, 80}
+
+// Warning says that a limit is nearly used up.
+type Warning struct {
+	Kind   string `json:"kind"`
+	Metric string `json:"metric"`
+	Used   int64  `json:"used"`
+	Limit  int64  `json:"limit"`
+	// Percent is the highest threshold reached.
+	Percent int    `json:"percent"`
+	Message string `json:"message"`
+}
+
+type list struct {
+	mu       sync.Mutex
+	warnings []Warning
+}
+
+type ctxKey struct{}
+
+// With returns a copy of ctx that collects warnings.
+func With(ctx context.Context) context.Context {
+	return context.WithValue(ctx, ctxKey{}, &list{})
+}
+
+// Check records a warning in ctx when used has reached a threshold of limit. A limit of 0
+// is unlimited, and contexts without With are ignored. A later check of the same limit
+// replaces the earlier warning.
+func Check(ctx context.Context, kind, metric string, used, limit int64) {
+	l, ok := ctx.Value(ctxKey{}).(*list)
+	if !ok || limit <= 0 {
+		return
+	}
+	percent := 0
+	for _, t := range Thresholds {
+		if used*100 >= limit*int64(t) {
+			percent = t
+			break
+		}
+	}
+	if percent == 0 {
+		return
+	}
+	w := Warning{Kind: kind, Metric: metric, Used: used, Limit: limit, Percent: percent, Message: message(kind, metric, used, limit)}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for i, existing := range l.warnings {
+		if existing.Kind == kind && existing.Metric == metric {
+			l.warnings[i] = w
+			return
+		}
+	}
+	l.warnings = append(l.warnings, w)
+}
+
+// From returns the warnings recorded in ctx, or nil.
+func From(ctx context.Context) []Warning {
+	l, ok := ctx.Value(ctxKey{}).(*list)
+	if !ok {
+		return nil
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if len(l.warnings) == 0 {
+		return nil
+	}
+	return append([]Warning(nil), l.warnings...)
+}
+
+func message(kind, metric string, used, limit int64) string {
+	if kind == KindRateLimit {
+		return fmt.Sprintf("%s: %d of %d requests allowed per minute used", metric, used, limit)
+	}
+	if kind == KindStorage {
+		return fmt.Sprintf("%s: %d of the storage limit of %d used", metric, used, limit)
+	}
+	return fmt.Sprintf("%s: %d of the daily limit of %d used", metric, used, limit)
+}
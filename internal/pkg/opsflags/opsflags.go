@@ -0,0 +1,249 @@
+// Package opsflags holds the operational toggles that admins can change at runtime, such as
+// the log level and debug logging, so an incident can be investigated without a restart.
+// The current flags are process-wide, like the standard logger, and read with Get.
+package opsflags
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"gopherai-resume/internal/pkg/apperr"
+)
+
+// Log levels, from the most verbose.
+const (
+	LevelDebug = "debug"
+	LevelInfo  = "info"
+	LevelWarn  = "warn"
+)
+
+// maxHeartbeatSeconds bounds SSEHeartbeatSeconds; proxies close idle streams well before.
+const maxHeartbeatSeconds = 300
+
+var ErrInvalidFlags = apperr.BadRequest(fmt.Sprintf(
+	"log_level must be debug, info or warn and sse_heartbeat_seconds between 0 and %d", maxHeartbeatSeconds))
+
+var levelRanks = map[string]int{LevelDebug: 0, LevelInfo: 1, LevelWarn: 2}
+
+// Flags are the toggles. LogLevel gates Debugf lines (debug) and the per-request access log
+// (info and below); warnings and errors are always logged. SQLLogging logs every SQL
+// statement, LLMPayloadLogging the bodies sent to and received from the model provider.
+// SSEHeartbeatSeconds is how long a server-sent event stream may stay silent before a
+// comment line is sent to keep proxies from closing it; 0 sends none.
+type Flags struct {
+	LogLevel            string `json:"log_level"`
+	SQLLogging          bool   `json:"sql_logging"`
+	LLMPayloadLogging   bool   `json:"llm_payload_logging"`
+	SSEHeartbeatSeconds int    `json:"sse_heartbeat_seconds"`
+}
+
+// Enabled reports whether lines at level are logged.
+func (f Flags) Enabled(level string) bool {
+	current, ok := levelRanks[f.LogLevel]
+	if !ok {
+		current = levelRanks[LevelInfo]
+	}
+	return levelRanks[level] >= current
+}
+
+// SSEHeartbeat is SSEHeartbeatSeconds as a duration.
+func (f Flags) SSEHeartbeat() time.Duration {
+	return time.Duration(f.SSEHeartbeatSeconds) * time.Second
+}
+
+func (f Flags) validate() error {
+	if _, ok := levelRanks[f.LogLevel]; !ok {
+		return ErrInvalidFlags
+	}
+	if f.SSEHeartbeatSeconds < 0 || f.SSEHeartbeatSeconds > maxHeartbeatSeconds {
+		return ErrInvalidFlags
+	}
+	return nil
+}
+
+var current atomic.Pointer[Flags]
+
+// Get returns the flags in effect. Before a Store is created they are the zero Flags, which
+// log at info level with every toggle off.
+func Get() Flags {
+	if f := current.Load(); f != nil {
+		return *f
+	}
+	return Flags{LogLevel: LevelInfo}
+}
+
+// Debugf logs like log.Printf when the log level is debug.
+func Debugf(format string, args ...any) {
+	if Get().Enabled(LevelDebug) {
+		log.Printf("debug: "+format, args...)
+	}
+}
+
+// Patch changes the non-nil flags.
+type Patch struct {
+	LogLevel            *string `json:"log_level"`
+	SQLLogging          *bool   `json:"sql_logging"`
+	LLMPayloadLogging   *bool   `json:"llm_payload_logging"`
+	SSEHeartbeatSeconds *int    `json:"sse_heartbeat_seconds"`
+}
+
+func (p Patch) apply(f Flags) Flags {
+	if p.LogLevel != nil {
+		f.LogLevel = strings.ToLower(strings.TrimSpace(*p.LogLevel))
+	}
+	if p.SQLLogging != nil {
+		f.SQLLogging = *p.SQLLogging
+	}
+	if p.LLMPayloadLogging != nil {
+		f.LLMPayloadLogging = *p.LLMPayloadLogging
+	}
+	if p.SSEHeartbeatSeconds != nil {
+		f.SSEHeartbeatSeconds = *p.SSEHeartbeatSeconds
+	}
+	return f
+}
+
+// Record is a change of the flags, as shared between instances.
+type Record struct {
+	Flags     Flags     `json:"flags"`
+	UpdatedAt time.Time `json:"updated_at"`
+	UpdatedBy string    `json:"updated_by"`
+}
+
+// Backend shares changes between instances. Load returns nil when the flags have not been
+// changed since the last reset.
+type Backend interface {
+	Load(ctx context.Context) (*Record, error)
+	Save(ctx context.Context, record Record) error
+	Clear(ctx context.Context) error
+}
+
+// State is what the admin API reports: the flags in effect, the configured defaults they
+// reset to, and the last change, if any.
+type State struct {
+	Flags     Flags      `json:"flags"`
+	Defaults  Flags      `json:"defaults"`
+	UpdatedAt *time.Time `json:"updated_at"`
+	UpdatedBy string     `json:"updated_by,omitempty"`
+	// Shared is false when changes apply to this instance only.
+	Shared bool `json:"shared"`
+}
+
+// Store changes the flags. With a Backend, changes are saved there and Watch picks up the
+// ones made through other instances; without one they apply to this instance only.
+type Store struct {
+	defaults Flags
+	backend  Backend
+
+	mu   sync.Mutex
+	last *Record
+}
+
+// NewStore makes defaults, the configured flags, current. An invalid log level in the
+// configuration falls back to info.
+func NewStore(defaults Flags, backend Backend) *Store {
+	defaults.LogLevel = strings.ToLower(strings.TrimSpace(defaults.LogLevel))
+	if _, ok := levelRanks[defaults.LogLevel]; !ok {
+		log.Printf("ops flags: unknown log level %q, using %s", defaults.LogLevel, LevelInfo)
+		defaults.LogLevel = LevelInfo
+	}
+	s := &Store{defaults: defaults, backend: backend}
+	current.Store(&defaults)
+	return s
+}
+
+func (s *Store) State() State {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	state := State{Flags: Get(), Defaults: s.defaults, Shared: s.backend != nil}
+	if s.last != nil {
+		at := s.last.UpdatedAt
+		state.UpdatedAt, state.UpdatedBy = &at, s.last.UpdatedBy
+	}
+	return state
+}
+
+// Update applies patch on top of the flags in effect. Every change is logged with who made it.
+func (s *Store) Update(ctx context.Context, patch Patch, by string) (State, error) {
+	flags := patch.apply(Get())
+	if err := flags.validate(); err != nil {
+		return State{}, err
+	}
+	record := Record{Flags: flags, UpdatedAt: time.Now(), UpdatedBy: by}
+	if s.backend != nil {
+		if err := s.backend.Save(ctx, record); err != nil {
+			return State{}, err
+		}
+	}
+	s.apply(&record)
+	log.Printf("ops flags changed by %s: %+v", by, flags)
+	return s.State(), nil
+}
+
+// Reset goes back to the configured defaults.
+func (s *Store) Reset(ctx context.Context, by string) (State, error) {
+	if s.backend != nil {
+		if err := s.backend.Clear(ctx); err != nil {
+			return State{}, err
+		}
+	}
+	s.apply(nil)
+	log.Printf("ops flags reset to defaults by %s", by)
+	return s.State(), nil
+}
+
+// Refresh loads the flags saved by any instance.
+func (s *Store) Refresh(ctx context.Context) error {
+	if s.backend == nil {
+		return nil
+	}
+	record, err := s.backend.Load(ctx)
+	if err != nil {
+		return err
+	}
+	if record != nil && record.Flags.validate() != nil {
+		return ErrInvalidFlags
+	}
+	s.apply(record)
+	return nil
+}
+
+// Watch refreshes the flags at once and then every interval until ctx is done. Failures
+// keep the flags in effect and are logged once until a refresh succeeds again.
+func (s *Store) Watch(ctx context.Context, interval time.Duration) {
+	if s.backend == nil || interval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	failing := false
+	for {
+		err := s.Refresh(ctx)
+		if err != nil && !failing && ctx.Err() == nil {
+			log.Printf("refresh ops flags failed, keeping the current ones: %v", err)
+		}
+		failing = err != nil
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// apply makes record's flags current, or the defaults for nil.
+func (s *Store) apply(record *Record) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	flags := s.defaults
+	if record != nil {
+		flags = record.Flags
+	}
+	s.last = record
+	current.Store(&flags)
+}
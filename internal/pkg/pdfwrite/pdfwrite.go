@@ -0,0 +1,222 @@
+// Package pdfwrite renders simple text documents (headings, paragraphs, bullets) as PDF
+// using the standard Helvetica fonts, so no font files are needed.
+package pdfwrite
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrUnsupportedText is returned for text the standard fonts cannot show, such as CJK.
+var ErrUnsupportedText = errors.New("pdfwrite: text has characters outside the WinAnsi character set")
+
+const (
+	pageWidth    = 595.0 // A4 in points
+	pageHeight   = 842.0
+	margin       = 50.0
+	bodySize     = 10.0
+	headingSize  = 14.0
+	titleSize    = 18.0
+	lineSpacing  = 1.4
+	avgCharWidth = 0.5 // Helvetica's average glyph width as a fraction of the font size
+)
+
+type line struct {
+	text   string
+	size   float64
+	bold   bool
+	indent float64
+	gap    float64 // extra space above the line
+}
+
+// FromMarkdown renders a small subset of Markdown: "#", "##" and "###" headings, "-" and
+// "*" bullets, and paragraphs. Other markup is printed as is. Text with characters outside
+// WinAnsi fails with ErrUnsupportedText rather than printing them as garbage.
+func FromMarkdown(md string) ([]byte, error) {
+	if !Supported(md) {
+		return nil, ErrUnsupportedText
+	}
+	var lines []line
+	for _, raw := range strings.Split(strings.ReplaceAll(md, "\r\n", "\n"), "\n") {
+		text := strings.TrimRight(raw, " \t")
+		trimmed := strings.TrimSpace(text)
+		switch {
+		case trimmed == "":
+			lines = append(lines, line{size: bodySize})
+		case strings.HasPrefix(trimmed, "# "):
+			lines = append(lines, wrap(strings.TrimPrefix(trimmed, "# "), titleSize, true, 0, 6)...)
+		case strings.HasPrefix(trimmed, "## "), strings.HasPrefix(trimmed, "### "):
+			lines = append(lines, wrap(strings.TrimLeft(trimmed, "# "), headingSize, true, 0, 8)...)
+		case strings.HasPrefix(trimmed, "- "), strings.HasPrefix(trimmed, "* "):
+			wrapped := wrap(trimmed[2:], bodySize, false, 14, 0)
+			if len(wrapped) > 0 {
+				wrapped[0].text = "\u0095 " + wrapped[0].text // WinAnsi bullet
+				wrapped[0].indent = 6
+			}
+			lines = append(lines, wrapped...)
+		default:
+			lines = append(lines, wrap(trimmed, bodySize, false, 0, 0)...)
+		}
+	}
+	return render(paginate(lines)), nil
+}
+
+// Supported reports whether FromMarkdown can show every character of text.
+func Supported(text string) bool {
+	for _, r := range text {
+		if _, ok := winAnsi(r); !ok && r >= 0x20 {
+			return false
+		}
+	}
+	return true
+}
+
+// wrap splits text into lines that fit the page width at the given size.
+func wrap(text string, size float64, bold bool, indent, gap float64) []line {
+	maxChars := int((pageWidth - 2*margin - indent) / (size * avgCharWidth))
+	var out []line
+	var current strings.Builder
+	flush := func() {
+		out = append(out, line{text: current.String(), size: size, bold: bold, indent: indent})
+		current.Reset()
+	}
+	for _, word := range strings.Fields(text) {
+		for len([]rune(word)) > maxChars {
+			if current.Len() > 0 {
+				flush()
+			}
+			runes := []rune(word)
+			current.WriteString(string(runes[:maxChars]))
+			flush()
+			word = string(runes[maxChars:])
+		}
+		if current.Len() > 0 && len([]rune(current.String()))+1+len([]rune(word)) > maxChars {
+			flush()
+		}
+		if current.Len() > 0 {
+			current.WriteByte(' ')
+		}
+		current.WriteString(word)
+	}
+	if current.Len() > 0 || len(out) == 0 {
+		flush()
+	}
+	if len(out) > 0 {
+		out[0].gap = gap
+	}
+	return out
+}
+
+// paginate groups lines into pages.
+func paginate(lines []line) [][]line {
+	var pages [][]line
+	var page []line
+	used := 0.0
+	for _, l := range lines {
+		height := l.size*lineSpacing + l.gap
+		if used+height > pageHeight-2*margin && len(page) > 0 {
+			pages = append(pages, page)
+			page, used = nil, 0
+		}
+		if len(page) == 0 && l.text == "" {
+			continue // no blank lines at the top of a page
+		}
+		page = append(page, l)
+		used += height
+	}
+	if len(page) > 0 || len(pages) == 0 {
+		pages = append(pages, page)
+	}
+	return pages
+}
+
+func render(pages [][]line) []byte {
+	var buf bytes.Buffer
+	var offsets []int
+	object := func(body string) {
+		offsets = append(offsets, buf.Len())
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", len(offsets), body)
+	}
+
+	buf.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+	// Objects 1-4: catalog, page tree, regular and bold fonts. Each page then takes two
+	// objects: the page and its content stream.
+	kids := make([]string, len(pages))
+	for i := range pages {
+		kids[i] = fmt.Sprintf("%d 0 R", 5+2*i)
+	}
+	object("<< /Type /Catalog /Pages 2 0 R >>")
+	object(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(pages)))
+	object("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>")
+	object("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica-Bold /Encoding /WinAnsiEncoding >>")
+
+	for i, page := range pages {
+		var content bytes.Buffer
+		y := pageHeight - margin
+		for _, l := range page {
+			y -= l.gap + l.size*lineSpacing
+			if l.text == "" {
+				continue
+			}
+			font := "F1"
+			if l.bold {
+				font = "F2"
+			}
+			fmt.Fprintf(&content, "BT /%s %.1f Tf %.1f %.1f Td (%s) Tj ET\n", font, l.size, margin+l.indent, y, escape(l.text))
+		}
+		object(fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %.0f %.0f] "+
+			"/Resources << /Font << /F1 3 0 R /F2 4 0 R >> >> /Contents %d 0 R >>", pageWidth, pageHeight, 6+2*i))
+		object(fmt.Sprintf("<< /Length %d >>\nstream\n%sendstream", content.Len(), content.String()))
+	}
+
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, off := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", off)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, xref)
+	return buf.Bytes()
+}
+
+// winAnsiPunctuation maps the characters WinAnsi places in 0x80-0x9f to their codes.
+var winAnsiPunctuation = map[rune]byte{
+	'€': 0x80, '‚': 0x82, 'ƒ': 0x83, '„': 0x84, '…': 0x85, '†': 0x86, '‡': 0x87, 'ˆ': 0x88,
+	'‰': 0x89, 'Š': 0x8a, '‹': 0x8b, 'Œ': 0x8c, 'Ž': 0x8e, '‘': 0x91, '’': 0x92, '“': 0x93,
+	'”': 0x94, '•': 0x95, '–': 0x96, '—': 0x97, '˜': 0x98, '™': 0x99, 'š': 0x9a, '›': 0x9b,
+	'œ': 0x9c, 'ž': 0x9e, 'Ÿ': 0x9f,
+	'\u0095': 0x95, // the bullet FromMarkdown inserts
+}
+
+// winAnsi returns the WinAnsi code of a printable character.
+func winAnsi(r rune) (byte, bool) {
+	if b, ok := winAnsiPunctuation[r]; ok {
+		return b, true
+	}
+	if (r >= 0x20 && r < 0x7f) || (r >= 0xa0 && r <= 0xff) {
+		return byte(r), true
+	}
+	return 0, false
+}
+
+// escape encodes text as a PDF literal string in WinAnsi. Control characters become spaces;
+// FromMarkdown has already rejected anything else WinAnsi lacks.
+func escape(text string) string {
+	var b strings.Builder
+	for _, r := range text {
+		code, ok := winAnsi(r)
+		switch {
+		case r == '\\' || r == '(' || r == ')':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case !ok:
+			b.WriteByte(' ')
+		case code < 0x80:
+			b.WriteByte(code)
+		default:
+			fmt.Fprintf(&b, "\\%03o", code)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,172 @@
+// Package github is a minimal client for the public parts of the GitHub REST API.
+package github
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+)
+
+var (
+	ErrNotFound     = errors.New("github resource not found")
+	ErrRateLimited  = errors.New("github rate limit exceeded")
+	ErrInvalidRepo  = errors.New("invalid github repository url")
+	ownerRepoRegexp = regexp.MustCompile(`^[A-Za-z0-9_.-]+$`)
+)
+
+// Repo is the subset of repository fields used for portfolio analysis.
+type Repo struct {
+	Name        string    `json:"name"`
+	FullName    string    `json:"full_name"`
+	Description string    `json:"description"`
+	HTMLURL     string    `json:"html_url"`
+	Language    string    `json:"language"`
+	Topics      []string  `json:"topics"`
+	Stars       int       `json:"stargazers_count"`
+	Forks       int       `json:"forks_count"`
+	Fork        bool      `json:"fork"`
+	Archived    bool      `json:"archived"`
+	PushedAt    time.Time `json:"pushed_at"`
+}
+
+type Client struct {
+	httpClient *http.Client
+	baseURL    string
+	token      string
+}
+
+// NewClient creates a client for baseURL (https://api.github.com or a GitHub Enterprise API
+// root). token may be empty.
+func NewClient(baseURL, token string) *Client {
+	return &Client{
+		httpClient: &http.Client{Timeout: 20 * time.Second},
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		token:      token,
+	}
+}
+
+// ListUserRepos returns up to limit of the user's public repositories, most recently pushed first.
+func (c *Client) ListUserRepos(ctx context.Context, username string, limit int) ([]Repo, error) {
+	if !ownerRepoRegexp.MatchString(username) {
+		return nil, ErrNotFound
+	}
+	if limit <= 0 || limit > 100 {
+		limit = 30
+	}
+	var repos []Repo
+	path := fmt.Sprintf("/users/%s/repos?type=owner&sort=pushed&per_page=%d", url.PathEscape(username), limit)
+	if err := c.getJSON(ctx, path, &repos); err != nil {
+		return nil, err
+	}
+	return repos, nil
+}
+
+func (c *Client) GetRepo(ctx context.Context, owner, name string) (*Repo, error) {
+	var repo Repo
+	if err := c.getJSON(ctx, "/repos/"+url.PathEscape(owner)+"/"+url.PathEscape(name), &repo); err != nil {
+		return nil, err
+	}
+	return &repo, nil
+}
+
+// GetReadme returns the raw README of a repository, truncated to maxBytes. A repository
+// without a README returns "" and no error.
+func (c *Client) GetReadme(ctx context.Context, owner, name string, maxBytes int64) (string, error) {
+	resp, err := c.get(ctx, "/repos/"+url.PathEscape(owner)+"/"+url.PathEscape(name)+"/readme", "application/vnd.github.raw")
+	if errors.Is(err, ErrNotFound) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	raw, err := io.ReadAll(io.LimitReader(resp.Body, maxBytes))
+	if err != nil {
+		return "", fmt.Errorf("read github readme failed: %w", err)
+	}
+	return string(raw), nil
+}
+
+// ParseRepoURL extracts owner and name from https://github.com/<owner>/<name>[...] or "<owner>/<name>".
+// URLs on any other host are rejected.
+func ParseRepoURL(raw string) (string, string, error) {
+	raw = strings.TrimSpace(raw)
+	lower := strings.ToLower(raw)
+	if !strings.Contains(raw, "://") && (strings.HasPrefix(lower, "github.com/") || strings.HasPrefix(lower, "www.github.com/")) {
+		raw = "https://" + raw
+	}
+	path := raw
+	if strings.Contains(raw, "://") {
+		u, err := url.Parse(raw)
+		if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.User != nil {
+			return "", "", ErrInvalidRepo
+		}
+		if host := strings.ToLower(u.Host); host != "github.com" && host != "www.github.com" {
+			return "", "", ErrInvalidRepo
+		}
+		path = u.Path
+	} else if strings.Count(strings.Trim(raw, "/"), "/") != 1 {
+		// Without a host only the "<owner>/<name>" shorthand is accepted.
+		return "", "", ErrInvalidRepo
+	}
+	parts := strings.Split(strings.Trim(path, "/"), "/")
+	if len(parts) < 2 {
+		return "", "", ErrInvalidRepo
+	}
+	owner, name := parts[0], strings.TrimSuffix(parts[1], ".git")
+	// GitHub owners never contain dots, which also rules out shorthand like "gitlab.com/name".
+	if strings.Contains(owner, ".") || !ownerRepoRegexp.MatchString(owner) || !ownerRepoRegexp.MatchString(name) {
+		return "", "", ErrInvalidRepo
+	}
+	return owner, name, nil
+}
+
+func (c *Client) getJSON(ctx context.Context, path string, out interface{}) error {
+	resp, err := c.get(ctx, path, "application/vnd.github+json")
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("decode github response failed: %w", err)
+	}
+	return nil
+}
+
+func (c *Client) get(ctx context.Context, path, accept string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+path, nil)
+	if err != nil {
+		return nil, fmt.Errorf("build github request failed: %w", err)
+	}
+	req.Header.Set("Accept", accept)
+	req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("github request failed: %w", err)
+	}
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		resp.Body.Close()
+		return nil, ErrNotFound
+	case resp.StatusCode == http.StatusTooManyRequests,
+		resp.StatusCode == http.StatusForbidden && resp.Header.Get("X-RateLimit-Remaining") == "0":
+		resp.Body.Close()
+		return nil, ErrRateLimited
+	case resp.StatusCode >= 300:
+		raw, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		resp.Body.Close()
+		return nil, fmt.Errorf("github status %d: %s", resp.StatusCode, string(raw))
+	}
+	return resp, nil
+}
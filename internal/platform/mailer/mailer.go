@@ -0,0 +1,170 @@
+// Package mailer sends plain-text email through SMTP, Amazon SES or the log.
+package mailer
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"log"
+	"mime"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"regexp"
+	"strconv"
+	"time"
+)
+
+var ErrInvalidMessage = errors.New("invalid email message")
+
+// Message is one plain-text email.
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer delivers a message or returns why it could not.
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// New selects the mailer for provider: "smtp", "ses" (through its SMTP interface, where
+// host is ignored and region picks the endpoint) or "log", which only logs messages.
+func New(provider, host string, port int, username, password, from, region string) (Mailer, error) {
+	switch provider {
+	case "", "log":
+		return LogMailer{}, nil
+	case "smtp":
+		if host == "" {
+			return nil, fmt.Errorf("smtp mailer needs a host")
+		}
+		return NewSMTP(host, port, username, password, from)
+	case "ses":
+		if region == "" {
+			return nil, fmt.Errorf("ses mailer needs a region")
+		}
+		return NewSMTP("email-smtp."+region+".amazonaws.com", port, username, password, from)
+	}
+	return nil, fmt.Errorf("unknown mail provider %q", provider)
+}
+
+// LogMailer writes messages to the log instead of sending them; for development. Link
+// tokens are masked, since anyone who can read the log could use them.
+type LogMailer struct{}
+
+var linkTokenPattern = regexp.MustCompile(`([?&]token=)[^\s&]+`)
+
+func (LogMailer) Send(_ context.Context, msg Message) error {
+	log.Printf("mail to %s: %s\n%s", msg.To, msg.Subject, linkTokenPattern.ReplaceAllString(msg.Body, "${1}[masked]"))
+	return nil
+}
+
+// SMTPMailer sends through an SMTP server, upgrading to TLS when the server offers STARTTLS.
+type SMTPMailer struct {
+	host     string
+	addr     string
+	username string
+	password string
+	from     *mail.Address
+	timeout  time.Duration
+}
+
+// NewSMTP creates an SMTP mailer. from may include a display name ("Name <addr>").
+func NewSMTP(host string, port int, username, password, from string) (*SMTPMailer, error) {
+	sender, err := mail.ParseAddress(from)
+	if err != nil {
+		return nil, fmt.Errorf("parse mail from address failed: %w", err)
+	}
+	if port <= 0 {
+		port = 587
+	}
+	return &SMTPMailer{
+		host:     host,
+		addr:     net.JoinHostPort(host, strconv.Itoa(port)),
+		username: username,
+		password: password,
+		from:     sender,
+		timeout:  30 * time.Second,
+	}, nil
+}
+
+func (m *SMTPMailer) Send(ctx context.Context, msg Message) error {
+	to, err := mail.ParseAddress(msg.To)
+	if err != nil || msg.Subject == "" {
+		return ErrInvalidMessage
+	}
+	data, err := m.compose(to, msg)
+	if err != nil {
+		return err
+	}
+
+	dialer := net.Dialer{Timeout: m.timeout}
+	conn, err := dialer.DialContext(ctx, "tcp", m.addr)
+	if err != nil {
+		return fmt.Errorf("connect smtp server failed: %w", err)
+	}
+	deadline := time.Now().Add(m.timeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	_ = conn.SetDeadline(deadline)
+
+	client, err := smtp.NewClient(conn, m.host)
+	if err != nil {
+		_ = conn.Close()
+		return fmt.Errorf("start smtp session failed: %w", err)
+	}
+	defer client.Close()
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err := client.StartTLS(&tls.Config{ServerName: m.host}); err != nil {
+			return fmt.Errorf("smtp starttls failed: %w", err)
+		}
+	}
+	if m.username != "" {
+		if err := client.Auth(smtp.PlainAuth("", m.username, m.password, m.host)); err != nil {
+			return fmt.Errorf("smtp auth failed: %w", err)
+		}
+	}
+	if err := client.Mail(m.from.Address); err != nil {
+		return fmt.Errorf("smtp mail from failed: %w", err)
+	}
+	if err := client.Rcpt(to.Address); err != nil {
+		return fmt.Errorf("smtp rcpt to failed: %w", err)
+	}
+	w, err := client.Data()
+	if err != nil {
+		return fmt.Errorf("smtp data failed: %w", err)
+	}
+	if _, err := w.Write(data); err != nil {
+		_ = w.Close()
+		return fmt.Errorf("write smtp message failed: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("send smtp message failed: %w", err)
+	}
+	return client.Quit()
+}
+
+// compose renders the headers and a quoted-printable UTF-8 body.
+func (m *SMTPMailer) compose(to *mail.Address, msg Message) ([]byte, error) {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "From: %s\r\n", m.from.String())
+	fmt.Fprintf(&buf, "To: %s\r\n", to.String())
+	fmt.Fprintf(&buf, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", msg.Subject))
+	fmt.Fprintf(&buf, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	buf.WriteString("MIME-Version: 1.0\r\n")
+	buf.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	buf.WriteString("Content-Transfer-Encoding: quoted-printable\r\n\r\n")
+	qp := quotedprintable.NewWriter(&buf)
+	if _, err := qp.Write([]byte(msg.Body)); err != nil {
+		return nil, fmt.Errorf("encode mail body failed: %w", err)
+	}
+	if err := qp.Close(); err != nil {
+		return nil, fmt.Errorf("encode mail body failed: %w", err)
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,50 @@
+package mysql
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm/logger"
+
+	"gopherai-resume/internal/pkg/opsflags"
+)
+
+// toggledLogger is gorm's default logger, which reports slow queries and errors, switched
+// to logging every statement while the sql_logging ops flag is on.
+type toggledLogger struct {
+	quiet   logger.Interface
+	verbose logger.Interface
+}
+
+func newToggledLogger() logger.Interface {
+	return toggledLogger{quiet: logger.Default, verbose: logger.Default.LogMode(logger.Info)}
+}
+
+func (l toggledLogger) current() logger.Interface {
+	if opsflags.Get().SQLLogging {
+		return l.verbose
+	}
+	return l.quiet
+}
+
+// LogMode sets the level used while SQL logging is off; statements are still all logged
+// while it is on.
+func (l toggledLogger) LogMode(level logger.LogLevel) logger.Interface {
+	return toggledLogger{quiet: l.quiet.LogMode(level), verbose: l.verbose}
+}
+
+func (l toggledLogger) Info(ctx context.Context, msg string, args ...interface{}) {
+	l.current().Info(ctx, msg, args...)
+}
+
+func (l toggledLogger) Warn(ctx context.Context, msg string, args ...interface{}) {
+	l.current().Warn(ctx, msg, args...)
+}
+
+func (l toggledLogger) Error(ctx context.Context, msg string, args ...interface{}) {
+	l.current().Error(ctx, msg, args...)
+}
+
+func (l toggledLogger) Trace(ctx context.Context, begin time.Time, fc func() (string, int64), err error) {
+	l.current().Trace(ctx, begin, fc, err)
+}
@@ -10,7 +10,7 @@ import (
 )
 
 func New(ctx context.Context, dsn string) (*gorm.DB, error) {
-	db, err := gorm.Open(mysql.Open(dsn), &gorm.Config{})
+	db, err := gorm.Open(mysql.Open(dsn), &gorm.Config{Logger: newToggledLogger()})
 	if err != nil {
 		return nil, fmt.Errorf("open mysql failed: %w", err)
 	}
@@ -0,0 +1,67 @@
+package mysql
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"time"
+
+	mysqldriver "github.com/go-sql-driver/mysql"
+)
+
+// MySQL server error numbers that are safe to retry: the statement or its transaction
+// was rolled back.
+const (
+	errLockWaitTimeout = 1205
+	errDeadlock        = 1213
+)
+
+// RetryPolicy retries operations that fail with a transient MySQL error, waiting
+// BaseDelay, then twice as long after each further failure, up to MaxDelay.
+// Attempts <= 1 runs the operation once.
+type RetryPolicy struct {
+	Attempts  int
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+}
+
+// Do runs op until it succeeds, fails with a non-transient error, the attempts run out or
+// ctx is done, and returns op's last error.
+func (p RetryPolicy) Do(ctx context.Context, op func() error) error {
+	delay := p.BaseDelay
+	for attempt := 1; ; attempt++ {
+		err := op()
+		if err == nil || attempt >= p.Attempts || !IsTransient(err) {
+			return err
+		}
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+		delay *= 2
+		if p.MaxDelay > 0 && delay > p.MaxDelay {
+			delay = p.MaxDelay
+		}
+	}
+}
+
+// IsTransient reports whether err is a dropped connection, a deadlock or a lock wait
+// timeout, which a brief failover or contention causes and a retry usually fixes.
+// mysqldriver.ErrInvalidConn means the connection broke mid-statement, so a retried
+// insert may in rare cases be stored twice.
+func IsTransient(err error) bool {
+	if err == nil {
+		return false
+	}
+	if errors.Is(err, driver.ErrBadConn) || errors.Is(err, mysqldriver.ErrInvalidConn) {
+		return true
+	}
+	var mysqlErr *mysqldriver.MySQLError
+	if errors.As(err, &mysqlErr) {
+		return mysqlErr.Number == errDeadlock || mysqlErr.Number == errLockWaitTimeout
+	}
+	return false
+}
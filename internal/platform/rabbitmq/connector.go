@@ -0,0 +1,88 @@
+package rabbitmq
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// ErrUnavailable is returned by publishers while there is no broker connection.
+var ErrUnavailable = errors.New("rabbitmq unavailable")
+
+// Connector holds the current broker connection. The connection may be missing, when the
+// broker was down at startup or disabled, or closed after the broker went away; Connect
+// dials a new one and runs the OnConnect callbacks, so consumers can restart.
+type Connector struct {
+	url string
+
+	mu        sync.RWMutex
+	conn      *amqp.Connection
+	onConnect []func()
+}
+
+func NewConnector(url string) *Connector {
+	return &Connector{url: url}
+}
+
+// Conn returns the open connection, or nil.
+func (c *Connector) Conn() *amqp.Connection {
+	if c == nil {
+		return nil
+	}
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if c.conn == nil || c.conn.IsClosed() {
+		return nil
+	}
+	return c.conn
+}
+
+// Channel opens a channel on the current connection.
+func (c *Connector) Channel() (*amqp.Channel, error) {
+	conn := c.Conn()
+	if conn == nil {
+		return nil, ErrUnavailable
+	}
+	return conn.Channel()
+}
+
+// OnConnect registers fn to run after each new connection is established.
+func (c *Connector) OnConnect(fn func()) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.onConnect = append(c.onConnect, fn)
+}
+
+// Connect dials the broker unless a connection is already open.
+func (c *Connector) Connect(ctx context.Context) error {
+	if c.Conn() != nil {
+		return nil
+	}
+	conn, err := New(ctx, c.url)
+	if err != nil {
+		return err
+	}
+	c.mu.Lock()
+	if c.conn != nil {
+		_ = c.conn.Close()
+	}
+	c.conn = conn
+	callbacks := append([]func(){}, c.onConnect...)
+	c.mu.Unlock()
+
+	for _, fn := range callbacks {
+		fn()
+	}
+	return nil
+}
+
+func (c *Connector) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.conn == nil || c.conn.IsClosed() {
+		return nil
+	}
+	return c.conn.Close()
+}
@@ -0,0 +1,63 @@
+package rabbitmq
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// RAGIngestJob is the payload of an asynchronous RAG document ingest job.
+type RAGIngestJob struct {
+	DocumentID uint `json:"document_id"`
+}
+
+// IngestPublisher queues RAG document ingest jobs.
+type IngestPublisher struct {
+	conns     *Connector
+	queueName string
+}
+
+func NewIngestPublisher(conns *Connector, queueName string) *IngestPublisher {
+	return &IngestPublisher{
+		conns:     conns,
+		queueName: queueName,
+	}
+}
+
+func (p *IngestPublisher) EnqueueRAGIngest(ctx context.Context, documentID uint) error {
+	ch, err := p.conns.Channel()
+	if errors.Is(err, ErrUnavailable) {
+		return err
+	}
+	if err != nil {
+		return fmt.Errorf("open rabbitmq channel failed: %w", err)
+	}
+	defer ch.Close()
+
+	if _, err := ch.QueueDeclare(p.queueName, true, false, false, false, nil); err != nil {
+		return fmt.Errorf("declare queue failed: %w", err)
+	}
+
+	payload, err := json.Marshal(RAGIngestJob{DocumentID: documentID})
+	if err != nil {
+		return fmt.Errorf("marshal ingest job failed: %w", err)
+	}
+	if err := ch.PublishWithContext(
+		ctx,
+		"",
+		p.queueName,
+		false,
+		false,
+		amqp.Publishing{
+			ContentType:  "application/json",
+			Body:         payload,
+			DeliveryMode: amqp.Persistent,
+		},
+	); err != nil {
+		return fmt.Errorf("publish ingest job failed: %w", err)
+	}
+	return nil
+}
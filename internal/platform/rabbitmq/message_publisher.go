@@ -3,6 +3,7 @@ package rabbitmq
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 
 	amqp "github.com/rabbitmq/amqp091-go"
@@ -11,19 +12,22 @@ import (
 )
 
 type MessagePublisher struct {
-	conn      *amqp.Connection
+	conns     *Connector
 	queueName string
 }
 
-func NewMessagePublisher(conn *amqp.Connection, queueName string) *MessagePublisher {
+func NewMessagePublisher(conns *Connector, queueName string) *MessagePublisher {
 	return &MessagePublisher{
-		conn:      conn,
+		conns:     conns,
 		queueName: queueName,
 	}
 }
 
 func (p *MessagePublisher) Publish(ctx context.Context, msg model.Message) error {
-	ch, err := p.conn.Channel()
+	ch, err := p.conns.Channel()
+	if errors.Is(err, ErrUnavailable) {
+		return err
+	}
 	if err != nil {
 		return fmt.Errorf("open rabbitmq channel failed: %w", err)
 	}
@@ -0,0 +1,63 @@
+package rabbitmq
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// ScreeningReportJob is the payload of a screening report generation job.
+type ScreeningReportJob struct {
+	ReportID uint `json:"report_id"`
+}
+
+// ReportPublisher queues screening report generation jobs.
+type ReportPublisher struct {
+	conns     *Connector
+	queueName string
+}
+
+func NewReportPublisher(conns *Connector, queueName string) *ReportPublisher {
+	return &ReportPublisher{
+		conns:     conns,
+		queueName: queueName,
+	}
+}
+
+func (p *ReportPublisher) EnqueueScreeningReport(ctx context.Context, reportID uint) error {
+	ch, err := p.conns.Channel()
+	if errors.Is(err, ErrUnavailable) {
+		return err
+	}
+	if err != nil {
+		return fmt.Errorf("open rabbitmq channel failed: %w", err)
+	}
+	defer ch.Close()
+
+	if _, err := ch.QueueDeclare(p.queueName, true, false, false, false, nil); err != nil {
+		return fmt.Errorf("declare queue failed: %w", err)
+	}
+
+	payload, err := json.Marshal(ScreeningReportJob{ReportID: reportID})
+	if err != nil {
+		return fmt.Errorf("marshal report job failed: %w", err)
+	}
+	if err := ch.PublishWithContext(
+		ctx,
+		"",
+		p.queueName,
+		false,
+		false,
+		amqp.Publishing{
+			ContentType:  "application/json",
+			Body:         payload,
+			DeliveryMode: amqp.Persistent,
+		},
+	); err != nil {
+		return fmt.Errorf("publish report job failed: %w", err)
+	}
+	return nil
+}
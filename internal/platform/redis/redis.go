@@ -9,7 +9,18 @@ import (
 )
 
 func New(ctx context.Context, addr, password string, db int) (*redis.Client, error) {
-	client := redis.NewClient(&redis.Options{
+	client := NewClient(addr, password, db)
+	if err := Ping(ctx, client); err != nil {
+		_ = client.Close()
+		return nil, err
+	}
+	return client, nil
+}
+
+// NewClient builds a client without contacting the server; it connects on first use and
+// reconnects by itself, so it can be created while Redis is down.
+func NewClient(addr, password string, db int) *redis.Client {
+	return redis.NewClient(&redis.Options{
 		Addr:         addr,
 		Password:     password,
 		DB:           db,
@@ -17,12 +28,13 @@ func New(ctx context.Context, addr, password string, db int) (*redis.Client, err
 		ReadTimeout:  2 * time.Second,
 		WriteTimeout: 2 * time.Second,
 	})
+}
 
+func Ping(ctx context.Context, client *redis.Client) error {
 	pingCtx, cancel := context.WithTimeout(ctx, 3*time.Second)
 	defer cancel()
 	if err := client.Ping(pingCtx).Err(); err != nil {
-		return nil, fmt.Errorf("ping redis failed: %w", err)
+		return fmt.Errorf("ping redis failed: %w", err)
 	}
-
-	return client, nil
+	return nil
 }
@@ -0,0 +1,232 @@
+package rag
+
+import (
+	"container/heap"
+	"math"
+	"math/rand"
+	"sort"
+)
+
+// HNSW parameters: links per node (twice that on the bottom layer) and the candidate list
+// sizes used while building and searching.
+const (
+	hnswM              = 16
+	hnswEfConstruction = 100
+	hnswEfSearch       = 64
+)
+
+type hnswNode struct {
+	id     uint
+	vec    []float32 // normalized, so similarity is a dot product
+	levels [][]int32 // neighbour node indexes per layer
+}
+
+// hnsw is a hierarchical navigable small world graph over unit vectors for approximate
+// nearest-neighbour search by cosine similarity. Nodes can be added but not deleted; a
+// graph is rebuilt to drop any (see MemoryStore). It is not safe for concurrent use.
+type hnsw struct {
+	nodes    []hnswNode
+	byID     map[uint]int32
+	entry    int32
+	maxLevel int
+	rng      *rand.Rand
+}
+
+func newHNSW(seed int64) *hnsw {
+	return &hnsw{byID: make(map[uint]int32), entry: -1, rng: rand.New(rand.NewSource(seed))}
+}
+
+func (h *hnsw) len() int {
+	return len(h.nodes)
+}
+
+// insert adds a vector under id. An existing id gets the new vector but keeps its links,
+// which is fine for the rare re-embedded chunk.
+func (h *hnsw) insert(id uint, vec []float32) {
+	vec = normalize(vec)
+	if idx, ok := h.byID[id]; ok {
+		h.nodes[idx].vec = vec
+		return
+	}
+	level := int(-math.Log(1-h.rng.Float64()) / math.Log(hnswM))
+	idx := int32(len(h.nodes))
+	h.nodes = append(h.nodes, hnswNode{id: id, vec: vec, levels: make([][]int32, level+1)})
+	h.byID[id] = idx
+	if h.entry < 0 {
+		h.entry, h.maxLevel = idx, level
+		return
+	}
+
+	ep := h.entry
+	for l := h.maxLevel; l > level; l-- {
+		ep = h.greedy(vec, ep, l)
+	}
+	for l := min(level, h.maxLevel); l >= 0; l-- {
+		found := h.searchLayer(vec, ep, hnswEfConstruction, l)
+		neighbours := found
+		if len(neighbours) > maxLinks(l) {
+			neighbours = neighbours[:maxLinks(l)]
+		}
+		links := make([]int32, len(neighbours))
+		for i, n := range neighbours {
+			links[i] = n.idx
+			h.link(n.idx, idx, l)
+		}
+		h.nodes[idx].levels[l] = links
+		ep = found[0].idx
+	}
+	if level > h.maxLevel {
+		h.entry, h.maxLevel = idx, level
+	}
+}
+
+// search returns up to k nearest ids with their similarity, best first.
+func (h *hnsw) search(vec []float32, k int) []hnswResult {
+	if h.entry < 0 || k <= 0 {
+		return nil
+	}
+	vec = normalize(vec)
+	ep := h.entry
+	for l := h.maxLevel; l > 0; l-- {
+		ep = h.greedy(vec, ep, l)
+	}
+	found := h.searchLayer(vec, ep, max(hnswEfSearch, k), 0)
+	if len(found) > k {
+		found = found[:k]
+	}
+	out := make([]hnswResult, len(found))
+	for i, c := range found {
+		out[i] = hnswResult{id: h.nodes[c.idx].id, score: c.sim}
+	}
+	return out
+}
+
+type hnswResult struct {
+	id    uint
+	score float32
+}
+
+// link adds to as a neighbour of from on layer l, dropping from's least similar
+// neighbour when it has too many.
+func (h *hnsw) link(from, to int32, l int) {
+	node := &h.nodes[from]
+	node.levels[l] = append(node.levels[l], to)
+	if len(node.levels[l]) <= maxLinks(l) {
+		return
+	}
+	cands := make([]hnswCandidate, len(node.levels[l]))
+	for i, n := range node.levels[l] {
+		cands[i] = hnswCandidate{idx: n, sim: dot(node.vec, h.nodes[n].vec)}
+	}
+	sort.Slice(cands, func(i, j int) bool { return cands[i].sim > cands[j].sim })
+	kept := node.levels[l][:0]
+	for _, c := range cands[:maxLinks(l)] {
+		kept = append(kept, c.idx)
+	}
+	node.levels[l] = kept
+}
+
+// greedy walks layer l from ep to the node most similar to vec.
+func (h *hnsw) greedy(vec []float32, ep int32, l int) int32 {
+	best := dot(vec, h.nodes[ep].vec)
+	for changed := true; changed; {
+		changed = false
+		for _, n := range h.nodes[ep].levels[l] {
+			if sim := dot(vec, h.nodes[n].vec); sim > best {
+				best, ep, changed = sim, n, true
+			}
+		}
+	}
+	return ep
+}
+
+// searchLayer returns up to ef nodes of layer l most similar to vec, best first.
+func (h *hnsw) searchLayer(vec []float32, ep int32, ef int, l int) []hnswCandidate {
+	visited := map[int32]bool{ep: true}
+	start := hnswCandidate{idx: ep, sim: dot(vec, h.nodes[ep].vec)}
+	frontier := &candidateHeap{best: true, items: []hnswCandidate{start}}
+	results := &candidateHeap{items: []hnswCandidate{start}}
+	for frontier.Len() > 0 {
+		c := heap.Pop(frontier).(hnswCandidate)
+		if results.Len() >= ef && c.sim < results.items[0].sim {
+			break
+		}
+		for _, n := range h.nodes[c.idx].levels[l] {
+			if visited[n] {
+				continue
+			}
+			visited[n] = true
+			sim := dot(vec, h.nodes[n].vec)
+			if results.Len() < ef || sim > results.items[0].sim {
+				heap.Push(frontier, hnswCandidate{idx: n, sim: sim})
+				heap.Push(results, hnswCandidate{idx: n, sim: sim})
+				if results.Len() > ef {
+					heap.Pop(results)
+				}
+			}
+		}
+	}
+	out := results.items
+	sort.Slice(out, func(i, j int) bool { return out[i].sim > out[j].sim })
+	return out
+}
+
+func maxLinks(l int) int {
+	if l == 0 {
+		return 2 * hnswM
+	}
+	return hnswM
+}
+
+type hnswCandidate struct {
+	idx int32
+	sim float32
+}
+
+// candidateHeap pops the most similar candidate first when best is set, else the least.
+type candidateHeap struct {
+	best  bool
+	items []hnswCandidate
+}
+
+func (h *candidateHeap) Len() int { return len(h.items) }
+func (h *candidateHeap) Less(i, j int) bool {
+	if h.best {
+		return h.items[i].sim > h.items[j].sim
+	}
+	return h.items[i].sim < h.items[j].sim
+}
+func (h *candidateHeap) Swap(i, j int)      { h.items[i], h.items[j] = h.items[j], h.items[i] }
+func (h *candidateHeap) Push(x interface{}) { h.items = append(h.items, x.(hnswCandidate)) }
+func (h *candidateHeap) Pop() interface{} {
+	last := h.items[len(h.items)-1]
+	h.items = h.items[:len(h.items)-1]
+	return last
+}
+
+func normalize(vec []float32) []float32 {
+	var norm float64
+	for _, v := range vec {
+		norm += float64(v) * float64(v)
+	}
+	out := make([]float32, len(vec))
+	if norm == 0 {
+		return out
+	}
+	scale := float32(1 / math.Sqrt(norm))
+	for i, v := range vec {
+		out[i] = v * scale
+	}
+	return out
+}
+
+func dot(a, b []float32) float32 {
+	if len(a) != len(b) {
+		return 0
+	}
+	var sum float32
+	for i := range a {
+		sum += a[i] * b[i]
+	}
+	return sum
+}
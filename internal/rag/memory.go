@@ -0,0 +1,204 @@
+package rag
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Loader returns the stored points of the given documents; the memory store calls it the
+// first time a document is searched.
+type Loader func(ctx context.Context, documentIDs []uint) ([]Point, error)
+
+// MemoryStore keeps an HNSW graph per document in process memory, so a search costs a
+// few graph walks instead of parsing and scoring every chunk. Documents are loaded on
+// their first search and then kept in step by Upsert and DeleteByDocument; when more than
+// maxPoints are held, the documents searched longest ago are dropped and reloaded when
+// next needed. Each process has its own index, so an append or replace handled by another
+// instance is only seen here after that document is dropped.
+//
+// The graphs cannot remove single points: DeleteByDocument drops a document's whole graph,
+// which the loader rebuilds on its next search. Callers that remove some of a document's
+// chunks must delete the document and upsert the chunks it keeps, as a re-ingest does;
+// otherwise the removed chunks stay searchable here until the document is evicted.
+type MemoryStore struct {
+	load      Loader
+	maxPoints int
+
+	mu     sync.RWMutex
+	docs   map[uint]*memoryDocument
+	points int
+	// loading counts the loads in flight per document and gen the changes made to it
+	// meanwhile, so a load that raced with a change is not kept.
+	loading map[uint]int
+	gen     map[uint]uint64
+}
+
+type memoryDocument struct {
+	graph    *hnsw
+	lastUsed atomic.Int64 // unix nanoseconds of the last search
+}
+
+// NewMemoryStore creates the store; maxPoints <= 0 keeps every loaded document.
+func NewMemoryStore(load Loader, maxPoints int) *MemoryStore {
+	return &MemoryStore{
+		load:      load,
+		maxPoints: maxPoints,
+		docs:      make(map[uint]*memoryDocument),
+		loading:   make(map[uint]int),
+		gen:       make(map[uint]uint64),
+	}
+}
+
+// Upsert adds points to documents that are loaded. Points of other documents are left to
+// the loader, which reads them from the database on the next search.
+func (s *MemoryStore) Upsert(_ context.Context, points []Point) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, p := range points {
+		doc, ok := s.docs[p.DocumentID]
+		if !ok {
+			s.changed(p.DocumentID)
+			continue
+		}
+		before := doc.graph.len()
+		doc.graph.insert(p.ChunkID, p.Vector)
+		s.points += doc.graph.len() - before
+	}
+	s.evict()
+	return nil
+}
+
+func (s *MemoryStore) Search(ctx context.Context, vector []float32, documentIDs []uint, k int) ([]Match, error) {
+	if len(vector) == 0 || len(documentIDs) == 0 || k <= 0 {
+		return nil, nil
+	}
+	loaded, err := s.loadMissing(ctx, documentIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	var matches []Match
+	now := time.Now().UnixNano()
+	s.mu.RLock()
+	for _, id := range documentIDs {
+		doc, ok := s.docs[id]
+		if !ok {
+			doc, ok = loaded[id]
+		}
+		if !ok {
+			continue
+		}
+		doc.lastUsed.Store(now)
+		for _, r := range doc.graph.search(vector, k) {
+			matches = append(matches, Match{ChunkID: r.id, DocumentID: id, Score: r.score})
+		}
+	}
+	s.mu.RUnlock()
+
+	sort.Slice(matches, func(i, j int) bool { return matches[i].Score > matches[j].Score })
+	if len(matches) > k {
+		matches = matches[:k]
+	}
+	return matches, nil
+}
+
+func (s *MemoryStore) DeleteByDocument(_ context.Context, documentID uint) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if doc, ok := s.docs[documentID]; ok {
+		s.points -= doc.graph.len()
+		delete(s.docs, documentID)
+	}
+	s.changed(documentID)
+	return nil
+}
+
+// changed records a change to a document that is not loaded. The caller holds mu.
+func (s *MemoryStore) changed(documentID uint) {
+	if s.loading[documentID] > 0 {
+		s.gen[documentID]++
+	}
+}
+
+// loadMissing builds graphs for the documents not yet held and keeps them, unless one
+// changed while loading; those are returned for this search only.
+func (s *MemoryStore) loadMissing(ctx context.Context, documentIDs []uint) (map[uint]*memoryDocument, error) {
+	if s.load == nil {
+		return nil, nil
+	}
+	s.mu.Lock()
+	var missing []uint
+	gens := make(map[uint]uint64)
+	for _, id := range documentIDs {
+		if _, ok := s.docs[id]; !ok {
+			missing = append(missing, id)
+			gens[id] = s.gen[id]
+			s.loading[id]++
+		}
+	}
+	s.mu.Unlock()
+	if len(missing) == 0 {
+		return nil, nil
+	}
+	defer func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		for _, id := range missing {
+			if s.loading[id]--; s.loading[id] == 0 {
+				delete(s.loading, id)
+				delete(s.gen, id)
+			}
+		}
+	}()
+
+	points, err := s.load(ctx, missing)
+	if err != nil {
+		return nil, err
+	}
+	loaded := make(map[uint]*memoryDocument, len(missing))
+	now := time.Now().UnixNano()
+	for _, id := range missing {
+		loaded[id] = &memoryDocument{graph: newHNSW(int64(id))}
+		loaded[id].lastUsed.Store(now)
+	}
+	for _, p := range points {
+		if doc, ok := loaded[p.DocumentID]; ok {
+			doc.graph.insert(p.ChunkID, p.Vector)
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for id, doc := range loaded {
+		if _, ok := s.docs[id]; ok || s.gen[id] != gens[id] {
+			continue
+		}
+		s.docs[id] = doc
+		s.points += doc.graph.len()
+	}
+	s.evict()
+	return loaded, nil
+}
+
+// evict drops the least recently searched documents while over maxPoints. The caller
+// holds mu.
+func (s *MemoryStore) evict() {
+	if s.maxPoints <= 0 || s.points <= s.maxPoints {
+		return
+	}
+	ids := make([]uint, 0, len(s.docs))
+	for id := range s.docs {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return s.docs[ids[i]].lastUsed.Load() < s.docs[ids[j]].lastUsed.Load() })
+	for _, id := range ids {
+		if s.points <= s.maxPoints {
+			return
+		}
+		s.points -= s.docs[id].graph.len()
+		delete(s.docs, id)
+	}
+}
@@ -0,0 +1,196 @@
+package rag
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+var errQdrantNotFound = errors.New("qdrant resource not found")
+
+// QdrantStore keeps chunk embeddings in a Qdrant collection through its REST API. Point IDs
+// are chunk IDs and each point carries its document_id as payload for filtering. The
+// collection is created with cosine distance on the first upsert, sized to its vectors.
+type QdrantStore struct {
+	httpClient *http.Client
+	baseURL    string
+	apiKey     string
+	collection string
+
+	mu    sync.Mutex
+	ready bool
+}
+
+func NewQdrantStore(baseURL, apiKey, collection string) *QdrantStore {
+	return &QdrantStore{
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		apiKey:     apiKey,
+		collection: collection,
+	}
+}
+
+type qdrantPoint struct {
+	ID      uint                   `json:"id"`
+	Vector  []float32              `json:"vector"`
+	Payload map[string]interface{} `json:"payload"`
+}
+
+type qdrantMatch struct {
+	ID      uint    `json:"id"`
+	Score   float32 `json:"score"`
+	Payload struct {
+		DocumentID uint `json:"document_id"`
+	} `json:"payload"`
+}
+
+type qdrantCondition struct {
+	Key   string                 `json:"key"`
+	Match map[string]interface{} `json:"match"`
+}
+
+type qdrantFilter struct {
+	Must []qdrantCondition `json:"must"`
+}
+
+func (s *QdrantStore) Upsert(ctx context.Context, points []Point) error {
+	if len(points) == 0 {
+		return nil
+	}
+	if err := s.ensureCollection(ctx, len(points[0].Vector)); err != nil {
+		return err
+	}
+	body := struct {
+		Points []qdrantPoint `json:"points"`
+	}{Points: make([]qdrantPoint, len(points))}
+	for i, p := range points {
+		body.Points[i] = qdrantPoint{
+			ID:      p.ChunkID,
+			Vector:  p.Vector,
+			Payload: map[string]interface{}{"document_id": p.DocumentID},
+		}
+	}
+	return s.do(ctx, http.MethodPut, s.collectionPath()+"/points?wait=true", body, nil)
+}
+
+func (s *QdrantStore) Search(ctx context.Context, vector []float32, documentIDs []uint, k int) ([]Match, error) {
+	if len(vector) == 0 || len(documentIDs) == 0 || k <= 0 {
+		return nil, nil
+	}
+	body := struct {
+		Vector      []float32    `json:"vector"`
+		Limit       int          `json:"limit"`
+		Filter      qdrantFilter `json:"filter"`
+		WithPayload bool         `json:"with_payload"`
+	}{
+		Vector:      vector,
+		Limit:       k,
+		Filter:      qdrantFilter{Must: []qdrantCondition{{Key: "document_id", Match: map[string]interface{}{"any": documentIDs}}}},
+		WithPayload: true,
+	}
+	var out struct {
+		Result []qdrantMatch `json:"result"`
+	}
+	err := s.do(ctx, http.MethodPost, s.collectionPath()+"/points/search", body, &out)
+	if errors.Is(err, errQdrantNotFound) {
+		// Nothing was indexed yet.
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	matches := make([]Match, len(out.Result))
+	for i, m := range out.Result {
+		matches[i] = Match{ChunkID: m.ID, DocumentID: m.Payload.DocumentID, Score: m.Score}
+	}
+	return matches, nil
+}
+
+func (s *QdrantStore) DeleteByDocument(ctx context.Context, documentID uint) error {
+	body := struct {
+		Filter qdrantFilter `json:"filter"`
+	}{Filter: qdrantFilter{Must: []qdrantCondition{{Key: "document_id", Match: map[string]interface{}{"value": documentID}}}}}
+	err := s.do(ctx, http.MethodPost, s.collectionPath()+"/points/delete?wait=true", body, nil)
+	if errors.Is(err, errQdrantNotFound) {
+		return nil
+	}
+	return err
+}
+
+// ensureCollection creates the collection and its document_id index unless they exist.
+func (s *QdrantStore) ensureCollection(ctx context.Context, size int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.ready {
+		return nil
+	}
+	err := s.do(ctx, http.MethodGet, s.collectionPath(), nil, nil)
+	if errors.Is(err, errQdrantNotFound) {
+		create := map[string]interface{}{
+			"vectors": map[string]interface{}{"size": size, "distance": "Cosine"},
+		}
+		if err = s.do(ctx, http.MethodPut, s.collectionPath(), create, nil); err != nil {
+			return err
+		}
+		index := map[string]interface{}{"field_name": "document_id", "field_schema": "integer"}
+		err = s.do(ctx, http.MethodPut, s.collectionPath()+"/index?wait=true", index, nil)
+	}
+	if err != nil {
+		return err
+	}
+	s.ready = true
+	return nil
+}
+
+func (s *QdrantStore) collectionPath() string {
+	return "/collections/" + url.PathEscape(s.collection)
+}
+
+func (s *QdrantStore) do(ctx context.Context, method, path string, in, out interface{}) error {
+	var body io.Reader
+	if in != nil {
+		raw, err := json.Marshal(in)
+		if err != nil {
+			return fmt.Errorf("encode qdrant request failed: %w", err)
+		}
+		body = bytes.NewReader(raw)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, s.baseURL+path, body)
+	if err != nil {
+		return fmt.Errorf("build qdrant request failed: %w", err)
+	}
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if s.apiKey != "" {
+		req.Header.Set("api-key", s.apiKey)
+	}
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("qdrant request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return errQdrantNotFound
+	case resp.StatusCode >= 300:
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("qdrant %s %s failed: status %d: %s", method, path, resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("decode qdrant response failed: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,90 @@
+// Package rag holds the vector store backends that RAG retrieval can search instead of
+// scoring every chunk in MySQL.
+package rag
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"gopherai-resume/internal/model"
+)
+
+// Vector store backends selectable with [rag] vector_store.
+const (
+	BackendMySQL  = "mysql"
+	BackendMemory = "memory"
+	BackendQdrant = "qdrant"
+)
+
+// Point is one chunk embedding in the store. Chunks stay in MySQL; the store only keeps the
+// vector and the IDs needed to filter and resolve a match.
+type Point struct {
+	ChunkID    uint
+	DocumentID uint
+	Vector     []float32
+}
+
+// Match is a chunk found by Search with its similarity to the query.
+type Match struct {
+	ChunkID    uint
+	DocumentID uint
+	Score      float32
+}
+
+// VectorStore indexes chunk embeddings for nearest-neighbour search.
+type VectorStore interface {
+	// Upsert adds points, replacing any with the same chunk ID.
+	Upsert(ctx context.Context, points []Point) error
+	// Search returns up to k points of documentIDs most similar to vector, best first.
+	Search(ctx context.Context, vector []float32, documentIDs []uint, k int) ([]Match, error)
+	// DeleteByDocument removes every point of the document.
+	DeleteByDocument(ctx context.Context, documentID uint) error
+}
+
+// StoreConfig selects and configures the vector store backend.
+type StoreConfig struct {
+	Backend          string
+	QdrantURL        string
+	QdrantAPIKey     string
+	QdrantCollection string
+	// Loader and MemoryMaxPoints configure the memory backend.
+	Loader          Loader
+	MemoryMaxPoints int
+}
+
+// NewStore returns the configured backend. The MySQL backend (also the empty name) keeps
+// scoring chunks in process and returns a nil store.
+func NewStore(cfg StoreConfig) (VectorStore, error) {
+	switch strings.ToLower(strings.TrimSpace(cfg.Backend)) {
+	case "", BackendMySQL:
+		return nil, nil
+	case BackendMemory:
+		return NewMemoryStore(cfg.Loader, cfg.MemoryMaxPoints), nil
+	case BackendQdrant:
+		if cfg.QdrantURL == "" || cfg.QdrantCollection == "" {
+			return nil, fmt.Errorf("qdrant vector store needs a url and a collection")
+		}
+		return NewQdrantStore(cfg.QdrantURL, cfg.QdrantAPIKey, cfg.QdrantCollection), nil
+	default:
+		return nil, fmt.Errorf("unknown vector store backend %q", cfg.Backend)
+	}
+}
+
+// PointsFromChunks returns the points of chunks, decompressing archived embeddings in
+// memory. Chunks without an embedding are skipped.
+func PointsFromChunks(chunks []model.RAGChunk) ([]Point, error) {
+	points := make([]Point, 0, len(chunks))
+	for i := range chunks {
+		chunk := chunks[i]
+		if err := chunk.RestoreEmbedding(); err != nil {
+			return nil, fmt.Errorf("restore archived embedding of chunk %d failed: %w", chunk.ID, err)
+		}
+		vec := chunk.EmbeddingVector()
+		if len(vec) == 0 {
+			continue
+		}
+		points = append(points, Point{ChunkID: chunk.ID, DocumentID: chunk.DocumentID, Vector: vec})
+	}
+	return points, nil
+}
@@ -0,0 +1,53 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+
+	"gopherai-resume/internal/model"
+)
+
+type ActivityRepository struct {
+	db *gorm.DB
+}
+
+func NewActivityRepository(db *gorm.DB) *ActivityRepository {
+	return &ActivityRepository{db: db}
+}
+
+func (r *ActivityRepository) Create(ctx context.Context, event *model.ActivityEvent) error {
+	if err := r.db.WithContext(ctx).Create(event).Error; err != nil {
+		return fmt.Errorf("create activity event failed: %w", err)
+	}
+	return nil
+}
+
+// ListByUserID returns up to limit of the user's events with an ID below beforeID (0 for no
+// bound), newest first, optionally only those of the given types.
+func (r *ActivityRepository) ListByUserID(ctx context.Context, userID uint, types []string, beforeID uint, limit int) ([]model.ActivityEvent, error) {
+	query := r.db.WithContext(ctx).Where("user_id = ?", userID)
+	if len(types) > 0 {
+		query = query.Where("type IN ?", types)
+	}
+	if beforeID > 0 {
+		query = query.Where("id < ?", beforeID)
+	}
+	var list []model.ActivityEvent
+	if err := query.Order("id DESC").Limit(limit).Find(&list).Error; err != nil {
+		return nil, fmt.Errorf("list activity events failed: %w", err)
+	}
+	return list, nil
+}
+
+// DeleteBefore deletes up to limit events created before cutoff and returns how many it
+// deleted.
+func (r *ActivityRepository) DeleteBefore(ctx context.Context, cutoff time.Time, limit int) (int64, error) {
+	result := r.db.WithContext(ctx).Where("created_at < ?", cutoff).Limit(limit).Delete(&model.ActivityEvent{})
+	if result.Error != nil {
+		return 0, fmt.Errorf("delete old activity events failed: %w", result.Error)
+	}
+	return result.RowsAffected, nil
+}
@@ -0,0 +1,179 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+
+	"gopherai-resume/internal/model"
+)
+
+type ApplicationRepository struct {
+	db *gorm.DB
+}
+
+func NewApplicationRepository(db *gorm.DB) *ApplicationRepository {
+	return &ApplicationRepository{db: db}
+}
+
+// Create inserts the application and its initial status entry.
+func (r *ApplicationRepository) Create(ctx context.Context, application *model.Application, change *model.ApplicationStatusChange) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(application).Error; err != nil {
+			return fmt.Errorf("create application failed: %w", err)
+		}
+		change.ApplicationID = application.ID
+		if err := tx.Create(change).Error; err != nil {
+			return fmt.Errorf("create application status change failed: %w", err)
+		}
+		return nil
+	})
+}
+
+// Update saves the application and, when change is non-nil, records the status transition.
+func (r *ApplicationRepository) Update(ctx context.Context, application *model.Application, change *model.ApplicationStatusChange) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(application).Error; err != nil {
+			return fmt.Errorf("update application failed: %w", err)
+		}
+		if change == nil {
+			return nil
+		}
+		change.ApplicationID = application.ID
+		if err := tx.Create(change).Error; err != nil {
+			return fmt.Errorf("create application status change failed: %w", err)
+		}
+		return nil
+	})
+}
+
+func (r *ApplicationRepository) GetByID(ctx context.Context, id uint) (*model.Application, error) {
+	var application model.Application
+	if err := r.db.WithContext(ctx).First(&application, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("get application failed: %w", err)
+	}
+	return &application, nil
+}
+
+// ListByUserID lists the user's applications, most recently updated first. An empty status lists all.
+func (r *ApplicationRepository) ListByUserID(ctx context.Context, userID uint, status string) ([]model.Application, error) {
+	query := r.db.WithContext(ctx).Where("user_id = ?", userID)
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	var list []model.Application
+	if err := query.Order("updated_at DESC").Find(&list).Error; err != nil {
+		return nil, fmt.Errorf("list applications failed: %w", err)
+	}
+	return list, nil
+}
+
+// ListForBoard lists the user's applications in board order: placed cards by position,
+// then the rest most recently updated first. Callers group them by status.
+func (r *ApplicationRepository) ListForBoard(ctx context.Context, userID uint) ([]model.Application, error) {
+	var list []model.Application
+	err := r.db.WithContext(ctx).Where("user_id = ?", userID).
+		Order("CASE WHEN board_position = 0 THEN 1 ELSE 0 END").
+		Order("board_position ASC").
+		Order("updated_at DESC").
+		Find(&list).Error
+	if err != nil {
+		return nil, fmt.Errorf("list applications for board failed: %w", err)
+	}
+	return list, nil
+}
+
+// Move records a status change of application, when change is non-nil, and sets the board
+// position of the user's applications to their place in columnIDs, starting at 1.
+// UpdatedAt is only touched by the status change, so reordering does not count as activity.
+func (r *ApplicationRepository) Move(ctx context.Context, application *model.Application, change *model.ApplicationStatusChange, columnIDs []uint) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if change != nil {
+			if err := tx.Save(application).Error; err != nil {
+				return fmt.Errorf("update application failed: %w", err)
+			}
+			change.ApplicationID = application.ID
+			if err := tx.Create(change).Error; err != nil {
+				return fmt.Errorf("create application status change failed: %w", err)
+			}
+		}
+		for i, id := range columnIDs {
+			err := tx.Model(&model.Application{}).
+				Where("id = ? AND user_id = ?", id, application.UserID).
+				UpdateColumn("board_position", i+1).Error
+			if err != nil {
+				return fmt.Errorf("update application board position failed: %w", err)
+			}
+		}
+		return nil
+	})
+}
+
+func (r *ApplicationRepository) ListStatusChanges(ctx context.Context, applicationID uint) ([]model.ApplicationStatusChange, error) {
+	var list []model.ApplicationStatusChange
+	if err := r.db.WithContext(ctx).Where("application_id = ?", applicationID).Order("created_at ASC, id ASC").Find(&list).Error; err != nil {
+		return nil, fmt.Errorf("list application status changes failed: %w", err)
+	}
+	return list, nil
+}
+
+// ListDueReminders returns the user's applications in one of statuses whose reminder is at or before before.
+func (r *ApplicationRepository) ListDueReminders(ctx context.Context, userID uint, before time.Time, statuses []string) ([]model.Application, error) {
+	var list []model.Application
+	err := r.db.WithContext(ctx).Where("user_id = ? AND remind_at IS NOT NULL AND remind_at <= ? AND status IN ?", userID, before, statuses).
+		Order("remind_at ASC").
+		Find(&list).Error
+	if err != nil {
+		return nil, fmt.Errorf("list due application reminders failed: %w", err)
+	}
+	return list, nil
+}
+
+// ListUnsentReminders returns up to limit applications, across users, in one of statuses
+// whose reminder is due at before and has not been emailed yet, earliest first.
+func (r *ApplicationRepository) ListUnsentReminders(ctx context.Context, before time.Time, statuses []string, limit int) ([]model.Application, error) {
+	var list []model.Application
+	err := r.db.WithContext(ctx).Where("remind_at IS NOT NULL AND remind_at <= ? AND reminder_sent_at IS NULL AND status IN ?", before, statuses).
+		Order("remind_at ASC").
+		Limit(limit).
+		Find(&list).Error
+	if err != nil {
+		return nil, fmt.Errorf("list unsent application reminders failed: %w", err)
+	}
+	return list, nil
+}
+
+// MarkReminderSent records that the reminder due at remindAt was emailed. It does nothing
+// when the reminder was rescheduled in the meantime. UpdatedAt is left alone.
+func (r *ApplicationRepository) MarkReminderSent(ctx context.Context, id uint, remindAt, sentAt time.Time) error {
+	err := r.db.WithContext(ctx).Model(&model.Application{}).
+		Where("id = ? AND remind_at = ?", id, remindAt).
+		UpdateColumn("reminder_sent_at", sentAt).Error
+	if err != nil {
+		return fmt.Errorf("mark application reminder sent failed: %w", err)
+	}
+	return nil
+}
+
+// DeleteByIDAndUserID deletes the application and its status history.
+func (r *ApplicationRepository) DeleteByIDAndUserID(ctx context.Context, id, userID uint) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("id = ? AND user_id = ?", id, userID).Delete(&model.Application{})
+		if result.Error != nil {
+			return fmt.Errorf("delete application failed: %w", result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return nil
+		}
+		if err := tx.Where("application_id = ?", id).Delete(&model.ApplicationStatusChange{}).Error; err != nil {
+			return fmt.Errorf("delete application status changes failed: %w", err)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,74 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"gorm.io/gorm"
+
+	"gopherai-resume/internal/model"
+)
+
+type DataExportRepository struct {
+	db *gorm.DB
+}
+
+func NewDataExportRepository(db *gorm.DB) *DataExportRepository {
+	return &DataExportRepository{db: db}
+}
+
+func (r *DataExportRepository) Create(ctx context.Context, export *model.DataExport) error {
+	if err := r.db.WithContext(ctx).Create(export).Error; err != nil {
+		return fmt.Errorf("create data export failed: %w", err)
+	}
+	return nil
+}
+
+// CreateActive inserts export as the user's unfinished export, reporting false when the
+// user already has one.
+func (r *DataExportRepository) CreateActive(ctx context.Context, export *model.DataExport) (bool, error) {
+	userID := export.UserID
+	export.ActiveUserID = &userID
+	if err := r.db.WithContext(ctx).Create(export).Error; err != nil {
+		if isDuplicateKey(err) {
+			return false, nil
+		}
+		return false, fmt.Errorf("create data export failed: %w", err)
+	}
+	return true, nil
+}
+
+func (r *DataExportRepository) Update(ctx context.Context, export *model.DataExport) error {
+	if err := r.db.WithContext(ctx).Save(export).Error; err != nil {
+		return fmt.Errorf("update data export failed: %w", err)
+	}
+	return nil
+}
+
+func (r *DataExportRepository) GetByID(ctx context.Context, id uint) (*model.DataExport, error) {
+	var export model.DataExport
+	if err := r.db.WithContext(ctx).First(&export, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("get data export failed: %w", err)
+	}
+	return &export, nil
+}
+
+// ListByUserID lists the user's exports, newest first.
+func (r *DataExportRepository) ListByUserID(ctx context.Context, userID uint) ([]model.DataExport, error) {
+	var list []model.DataExport
+	if err := r.db.WithContext(ctx).Where("user_id = ?", userID).Order("id DESC").Find(&list).Error; err != nil {
+		return nil, fmt.Errorf("list data exports failed: %w", err)
+	}
+	return list, nil
+}
+
+func (r *DataExportRepository) DeleteByID(ctx context.Context, id uint) error {
+	if err := r.db.WithContext(ctx).Delete(&model.DataExport{}, id).Error; err != nil {
+		return fmt.Errorf("delete data export failed: %w", err)
+	}
+	return nil
+}
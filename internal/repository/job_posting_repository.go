@@ -0,0 +1,180 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"gorm.io/gorm"
+
+	"gopherai-resume/internal/model"
+)
+
+type JobPostingRepository struct {
+	db *gorm.DB
+}
+
+func NewJobPostingRepository(db *gorm.DB) *JobPostingRepository {
+	return &JobPostingRepository{db: db}
+}
+
+// JobPostingFilter narrows ListByWorkspaceID. Zero values do not filter.
+type JobPostingFilter struct {
+	Tag             string
+	Query           string // substring of title or company
+	IncludeArchived bool
+	OnlyArchived    bool
+}
+
+// Create inserts the posting with its tags.
+func (r *JobPostingRepository) Create(ctx context.Context, posting *model.JobPosting, tags []string) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(posting).Error; err != nil {
+			return fmt.Errorf("create job posting failed: %w", err)
+		}
+		return replaceJobPostingTags(tx, posting, tags)
+	})
+}
+
+// Update saves the posting and, when tags is non-nil, replaces its tags.
+func (r *JobPostingRepository) Update(ctx context.Context, posting *model.JobPosting, tags []string) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(posting).Error; err != nil {
+			return fmt.Errorf("update job posting failed: %w", err)
+		}
+		if tags == nil {
+			return nil
+		}
+		return replaceJobPostingTags(tx, posting, tags)
+	})
+}
+
+func replaceJobPostingTags(tx *gorm.DB, posting *model.JobPosting, tags []string) error {
+	if err := tx.Where("job_posting_id = ?", posting.ID).Delete(&model.JobPostingTag{}).Error; err != nil {
+		return fmt.Errorf("delete job posting tags failed: %w", err)
+	}
+	if len(tags) == 0 {
+		return nil
+	}
+	rows := make([]model.JobPostingTag, 0, len(tags))
+	for _, tag := range tags {
+		rows = append(rows, model.JobPostingTag{JobPostingID: posting.ID, WorkspaceID: posting.WorkspaceID, Tag: tag})
+	}
+	if err := tx.Create(&rows).Error; err != nil {
+		return fmt.Errorf("create job posting tags failed: %w", err)
+	}
+	return nil
+}
+
+func (r *JobPostingRepository) GetByIDAndWorkspaceID(ctx context.Context, id, workspaceID uint) (*model.JobPosting, error) {
+	var posting model.JobPosting
+	if err := r.db.WithContext(ctx).Where("id = ? AND workspace_id = ?", id, workspaceID).First(&posting).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("get job posting failed: %w", err)
+	}
+	return &posting, nil
+}
+
+// ListByWorkspaceID lists the workspace's postings, most recently updated first.
+func (r *JobPostingRepository) ListByWorkspaceID(ctx context.Context, workspaceID uint, filter JobPostingFilter) ([]model.JobPosting, error) {
+	query := r.db.WithContext(ctx).Where("workspace_id = ?", workspaceID)
+	switch {
+	case filter.OnlyArchived:
+		query = query.Where("archived_at IS NOT NULL")
+	case !filter.IncludeArchived:
+		query = query.Where("archived_at IS NULL")
+	}
+	if filter.Tag != "" {
+		query = query.Where("id IN (?)", r.db.WithContext(ctx).Model(&model.JobPostingTag{}).Select("job_posting_id").
+			Where("workspace_id = ? AND tag = ?", workspaceID, filter.Tag))
+	}
+	if filter.Query != "" {
+		like := "%" + escapeLike(filter.Query) + "%"
+		query = query.Where("title LIKE ? OR company LIKE ?", like, like)
+	}
+	var list []model.JobPosting
+	if err := query.Order("updated_at DESC").Find(&list).Error; err != nil {
+		return nil, fmt.Errorf("list job postings failed: %w", err)
+	}
+	return list, nil
+}
+
+// likeEscaper escapes LIKE's wildcards, so a search matches them literally.
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+func escapeLike(s string) string {
+	return likeEscaper.Replace(s)
+}
+
+// ListTags returns the tags of the given postings keyed by posting ID.
+func (r *JobPostingRepository) ListTags(ctx context.Context, postingIDs []uint) (map[uint][]string, error) {
+	tags := make(map[uint][]string, len(postingIDs))
+	if len(postingIDs) == 0 {
+		return tags, nil
+	}
+	var rows []model.JobPostingTag
+	if err := r.db.WithContext(ctx).Where("job_posting_id IN ?", postingIDs).Order("tag ASC").Find(&rows).Error; err != nil {
+		return nil, fmt.Errorf("list job posting tags failed: %w", err)
+	}
+	for _, row := range rows {
+		tags[row.JobPostingID] = append(tags[row.JobPostingID], row.Tag)
+	}
+	return tags, nil
+}
+
+// DeleteByIDAndWorkspaceID deletes the posting, its tags, screening results and report records.
+// Stored report files are left to the caller.
+func (r *JobPostingRepository) DeleteByIDAndWorkspaceID(ctx context.Context, id, workspaceID uint) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("id = ? AND workspace_id = ?", id, workspaceID).Delete(&model.JobPosting{})
+		if result.Error != nil {
+			return fmt.Errorf("delete job posting failed: %w", result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return nil
+		}
+		if err := tx.Where("job_posting_id = ?", id).Delete(&model.JobPostingTag{}).Error; err != nil {
+			return fmt.Errorf("delete job posting tags failed: %w", err)
+		}
+		if err := tx.Where("job_posting_id = ?", id).Delete(&model.ScreeningResult{}).Error; err != nil {
+			return fmt.Errorf("delete screening results failed: %w", err)
+		}
+		if err := tx.Where("job_posting_id = ?", id).Delete(&model.ScreeningReport{}).Error; err != nil {
+			return fmt.Errorf("delete screening reports failed: %w", err)
+		}
+		return nil
+	})
+}
+
+func (r *JobPostingRepository) CreateScreeningResults(ctx context.Context, results []model.ScreeningResult) error {
+	if len(results) == 0 {
+		return nil
+	}
+	if err := r.db.WithContext(ctx).Create(&results).Error; err != nil {
+		return fmt.Errorf("create screening results failed: %w", err)
+	}
+	return nil
+}
+
+// ListScreeningResults lists a posting's screening results, best score first.
+func (r *JobPostingRepository) ListScreeningResults(ctx context.Context, postingID uint) ([]model.ScreeningResult, error) {
+	var list []model.ScreeningResult
+	if err := r.db.WithContext(ctx).Where("job_posting_id = ?", postingID).Order("score DESC, created_at DESC").Find(&list).Error; err != nil {
+		return nil, fmt.Errorf("list screening results failed: %w", err)
+	}
+	return list, nil
+}
+
+func (r *JobPostingRepository) GetScreeningResult(ctx context.Context, id, postingID uint) (*model.ScreeningResult, error) {
+	var result model.ScreeningResult
+	if err := r.db.WithContext(ctx).Where("id = ? AND job_posting_id = ?", id, postingID).First(&result).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("get screening result failed: %w", err)
+	}
+	return &result, nil
+}
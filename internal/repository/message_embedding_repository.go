@@ -0,0 +1,68 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"gopherai-resume/internal/model"
+)
+
+// maxHistoryCandidates bounds how many recent messages are scored per history search.
+const maxHistoryCandidates = 2000
+
+type MessageEmbeddingRepository struct {
+	db *gorm.DB
+}
+
+func NewMessageEmbeddingRepository(db *gorm.DB) *MessageEmbeddingRepository {
+	return &MessageEmbeddingRepository{db: db}
+}
+
+// EmbeddedMessage is a chat message joined with its stored embedding.
+type EmbeddedMessage struct {
+	model.Message
+	Embedding string
+}
+
+// EmbeddingVector returns the parsed embedding slice; empty on parse error.
+func (m *EmbeddedMessage) EmbeddingVector() []float32 {
+	if m.Embedding == "" {
+		return nil
+	}
+	var v []float32
+	_ = json.Unmarshal([]byte(m.Embedding), &v)
+	return v
+}
+
+// Create stores the embedding, replacing any earlier one for the same message so
+// redelivered queue messages stay idempotent.
+func (r *MessageEmbeddingRepository) Create(ctx context.Context, embedding *model.MessageEmbedding) error {
+	err := r.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "message_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"embedding"}),
+	}).Create(embedding).Error
+	if err != nil {
+		return fmt.Errorf("create message embedding failed: %w", err)
+	}
+	return nil
+}
+
+// ListByUserID returns the user's most recent embedded messages, newest first.
+func (r *MessageEmbeddingRepository) ListByUserID(ctx context.Context, userID uint) ([]EmbeddedMessage, error) {
+	var rows []EmbeddedMessage
+	err := r.db.WithContext(ctx).Table("messages").
+		Select("messages.*, message_embeddings.embedding").
+		Joins("JOIN message_embeddings ON message_embeddings.message_id = messages.id").
+		Where("messages.user_id = ?", userID).
+		Order("messages.created_at DESC").
+		Limit(maxHistoryCandidates).
+		Scan(&rows).Error
+	if err != nil {
+		return nil, fmt.Errorf("list message embeddings failed: %w", err)
+	}
+	return rows, nil
+}
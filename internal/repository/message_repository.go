@@ -1,8 +1,11 @@
 package repository
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"slices"
+	"time"
 
 	"gorm.io/gorm"
 
@@ -17,41 +20,295 @@ func NewMessageRepository(db *gorm.DB) *MessageRepository {
 	return &MessageRepository{db: db}
 }
 
-func (r *MessageRepository) Create(message *model.Message) error {
-	if err := r.db.Create(message).Error; err != nil {
+// Create stores message. Unless it names its parent, the message follows the latest
+// message on its session's branch.
+func (r *MessageRepository) Create(ctx context.Context, message *model.Message) error {
+	if message.ParentMessageID == nil && message.SessionID != 0 {
+		branch, err := r.branchScope(ctx, message.SessionID)
+		if err != nil {
+			return err
+		}
+		var last []uint
+		if err := branch.Model(&model.Message{}).Order("id DESC").Limit(1).Pluck("id", &last).Error; err != nil {
+			return fmt.Errorf("find parent message failed: %w", err)
+		}
+		if len(last) > 0 {
+			message.ParentMessageID = &last[0]
+		}
+	}
+	if err := r.db.WithContext(ctx).Create(message).Error; err != nil {
 		return fmt.Errorf("create message failed: %w", err)
 	}
 	return nil
 }
 
-func (r *MessageRepository) ListBySessionID(sessionID uint, limit int) ([]model.Message, error) {
-	if limit <= 0 || limit > 200 {
-		limit = 100
+// branchScope matches the messages on sessionID's branch: its own and, for a fork, those
+// of each ancestor up to the message the branch left it at. Their IDs increase along the
+// branch, so ordering by ID gives the conversation order.
+func (r *MessageRepository) branchScope(ctx context.Context, sessionID uint) (*gorm.DB, error) {
+	lineage, err := loadLineage(r.db.WithContext(ctx), sessionID)
+	if err != nil {
+		return nil, err
 	}
+	cond := "session_id = ?"
+	args := []interface{}{sessionID}
+	for i := 1; i < len(lineage); i++ {
+		cond += " OR (session_id = ? AND id <= ?)"
+		args = append(args, lineage[i].ID, *lineage[i-1].ForkMessageID)
+	}
+	return r.db.WithContext(ctx).Where("("+cond+")", args...), nil
+}
 
+// ListPageBySessionID returns up to limit messages of the session's branch with IDs below
+// beforeID (the newest when beforeID is 0), oldest first, and whether older ones remain.
+func (r *MessageRepository) ListPageBySessionID(ctx context.Context, sessionID, beforeID uint, limit int) ([]model.Message, bool, error) {
+	if limit <= 0 || limit > 200 {
+		limit = 50
+	}
+	query, err := r.branchScope(ctx, sessionID)
+	if err != nil {
+		return nil, false, err
+	}
+	if beforeID > 0 {
+		query = query.Where("id < ?", beforeID)
+	}
 	var messages []model.Message
-	if err := r.db.Where("session_id = ?", sessionID).Order("created_at ASC").Limit(limit).Find(&messages).Error; err != nil {
-		return nil, fmt.Errorf("list messages failed: %w", err)
+	if err := query.Order("id DESC").Limit(limit + 1).Find(&messages).Error; err != nil {
+		return nil, false, fmt.Errorf("list message page failed: %w", err)
 	}
-	return messages, nil
+	hasMore := len(messages) > limit
+	if hasMore {
+		messages = messages[:limit]
+	}
+	slices.Reverse(messages)
+	return messages, hasMore, nil
 }
 
-func (r *MessageRepository) ListRecentBySessionID(sessionID uint, limit int) ([]model.Message, error) {
+func (r *MessageRepository) ListRecentBySessionID(ctx context.Context, sessionID uint, limit int) ([]model.Message, error) {
 	if limit <= 0 || limit > 200 {
 		limit = 20
 	}
 
+	branch, err := r.branchScope(ctx, sessionID)
+	if err != nil {
+		return nil, err
+	}
 	var messages []model.Message
-	if err := r.db.Where("session_id = ?", sessionID).Order("created_at DESC").Limit(limit).Find(&messages).Error; err != nil {
+	if err := branch.Order("id DESC").Limit(limit).Find(&messages).Error; err != nil {
 		return nil, fmt.Errorf("list recent messages failed: %w", err)
 	}
 	slices.Reverse(messages)
 	return messages, nil
 }
 
-func (r *MessageRepository) DeleteBySessionID(sessionID uint) error {
-	if err := r.db.Where("session_id = ?", sessionID).Delete(&model.Message{}).Error; err != nil {
-		return fmt.Errorf("delete messages by session failed: %w", err)
+// ListRangeBySessionID returns up to limit messages of the session's branch with
+// afterID < id < beforeID, oldest first.
+func (r *MessageRepository) ListRangeBySessionID(ctx context.Context, sessionID, afterID, beforeID uint, limit int) ([]model.Message, error) {
+	branch, err := r.branchScope(ctx, sessionID)
+	if err != nil {
+		return nil, err
 	}
-	return nil
+	var messages []model.Message
+	err = branch.Where("id > ? AND id < ?", afterID, beforeID).
+		Order("id ASC").
+		Limit(limit).
+		Find(&messages).Error
+	if err != nil {
+		return nil, fmt.Errorf("list message range failed: %w", err)
+	}
+	return messages, nil
+}
+
+// ListBranch returns up to limit messages of the session's branch in conversation order.
+func (r *MessageRepository) ListBranch(ctx context.Context, sessionID uint, limit int) ([]model.Message, error) {
+	branch, err := r.branchScope(ctx, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	var messages []model.Message
+	if err := branch.Order("id ASC").Limit(limit).Find(&messages).Error; err != nil {
+		return nil, fmt.Errorf("list branch messages failed: %w", err)
+	}
+	return messages, nil
+}
+
+// ModelUsage sums the token usage of one user's assistant messages from one model.
+type ModelUsage struct {
+	Model             string `json:"model"`
+	Messages          int64  `json:"messages"`
+	PromptTokens      int64  `json:"prompt_tokens"`
+	CompletionTokens  int64  `json:"completion_tokens"`
+	EstimatedMessages int64  `json:"estimated_messages"`
+}
+
+// DailyUsage sums one user's token usage for one day.
+type DailyUsage struct {
+	Day              string `json:"day"`
+	PromptTokens     int64  `json:"prompt_tokens"`
+	CompletionTokens int64  `json:"completion_tokens"`
+}
+
+// UsageByModel sums the usage of the user's assistant messages created since since, per model.
+func (r *MessageRepository) UsageByModel(ctx context.Context, userID uint, since time.Time) ([]ModelUsage, error) {
+	var rows []ModelUsage
+	if err := r.db.WithContext(ctx).Model(&model.Message{}).
+		Select("model, COUNT(*) AS messages, COALESCE(SUM(prompt_tokens), 0) AS prompt_tokens, "+
+			"COALESCE(SUM(completion_tokens), 0) AS completion_tokens, "+
+			"COALESCE(SUM(CASE WHEN usage_estimated THEN 1 ELSE 0 END), 0) AS estimated_messages").
+		Where("user_id = ? AND role = ? AND created_at >= ?", userID, "assistant", since).
+		Group("model").
+		Order("model").
+		Scan(&rows).Error; err != nil {
+		return nil, fmt.Errorf("sum message usage by model failed: %w", err)
+	}
+	return rows, nil
+}
+
+// UsageByDay sums the usage of the user's assistant messages created since since, per day.
+func (r *MessageRepository) UsageByDay(ctx context.Context, userID uint, since time.Time) ([]DailyUsage, error) {
+	var rows []DailyUsage
+	if err := r.db.WithContext(ctx).Model(&model.Message{}).
+		Select("DATE_FORMAT(created_at, '%Y-%m-%d') AS day, COALESCE(SUM(prompt_tokens), 0) AS prompt_tokens, "+
+			"COALESCE(SUM(completion_tokens), 0) AS completion_tokens").
+		Where("user_id = ? AND role = ? AND created_at >= ?", userID, "assistant", since).
+		Group("day").
+		Order("day").
+		Scan(&rows).Error; err != nil {
+		return nil, fmt.Errorf("sum message usage by day failed: %w", err)
+	}
+	return rows, nil
+}
+
+// GetOnBranch returns the message if it is on the session's branch, or nil.
+func (r *MessageRepository) GetOnBranch(ctx context.Context, sessionID, messageID uint) (*model.Message, error) {
+	branch, err := r.branchScope(ctx, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	var message model.Message
+	if err := branch.Where("id = ?", messageID).First(&message).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("get branch message failed: %w", err)
+	}
+	return &message, nil
+}
+
+// ListBySessionIDs returns up to limit messages of the sessions, in ID order.
+func (r *MessageRepository) ListBySessionIDs(ctx context.Context, sessionIDs []uint, limit int) ([]model.Message, error) {
+	var messages []model.Message
+	if err := r.db.WithContext(ctx).Where("session_id IN ?", sessionIDs).Order("id ASC").Limit(limit).Find(&messages).Error; err != nil {
+		return nil, fmt.Errorf("list messages by sessions failed: %w", err)
+	}
+	return messages, nil
+}
+
+func (r *MessageRepository) DeleteBySessionID(ctx context.Context, sessionID uint) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		sub := tx.Model(&model.Message{}).Select("id").Where("session_id = ?", sessionID)
+		if err := tx.Where("message_id IN (?)", sub).Delete(&model.MessageEmbedding{}).Error; err != nil {
+			return fmt.Errorf("delete message embeddings by session failed: %w", err)
+		}
+		if err := tx.Where("session_id = ?", sessionID).Delete(&model.Message{}).Error; err != nil {
+			return fmt.Errorf("delete messages by session failed: %w", err)
+		}
+		return nil
+	})
+}
+
+func (r *MessageRepository) GetByID(ctx context.Context, id uint) (*model.Message, error) {
+	var message model.Message
+	if err := r.db.WithContext(ctx).First(&message, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("get message failed: %w", err)
+	}
+	return &message, nil
+}
+
+// ListByUserIDAfterID returns up to limit of the user's messages with IDs above afterID, in
+// ID order, for walking all of them.
+func (r *MessageRepository) ListByUserIDAfterID(ctx context.Context, userID, afterID uint, limit int) ([]model.Message, error) {
+	var messages []model.Message
+	if err := r.db.WithContext(ctx).Where("user_id = ? AND id > ?", userID, afterID).Order("id ASC").Limit(limit).Find(&messages).Error; err != nil {
+		return nil, fmt.Errorf("list messages by user failed: %w", err)
+	}
+	return messages, nil
+}
+
+func (r *MessageRepository) ListByIDsAndUserID(ctx context.Context, ids []uint, userID uint) ([]model.Message, error) {
+	var messages []model.Message
+	if err := r.db.WithContext(ctx).Where("id IN ? AND user_id = ?", ids, userID).Find(&messages).Error; err != nil {
+		return nil, fmt.Errorf("list messages by ids failed: %w", err)
+	}
+	return messages, nil
+}
+
+// DeleteByIDs deletes messages and their embeddings.
+func (r *MessageRepository) DeleteByIDs(ctx context.Context, ids []uint) (int64, error) {
+	var removed int64
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("message_id IN ?", ids).Delete(&model.MessageEmbedding{}).Error; err != nil {
+			return fmt.Errorf("delete message embeddings failed: %w", err)
+		}
+		result := tx.Where("id IN ?", ids).Delete(&model.Message{})
+		if result.Error != nil {
+			return fmt.Errorf("delete messages failed: %w", result.Error)
+		}
+		removed = result.RowsAffected
+		return nil
+	})
+	return removed, err
+}
+
+// afterMessage matches the messages of message's session that come after it.
+func afterMessage(db *gorm.DB, message *model.Message) *gorm.DB {
+	return db.Where("session_id = ? AND (created_at > ? OR (created_at = ? AND id > ?))",
+		message.SessionID, message.CreatedAt, message.CreatedAt, message.ID)
+}
+
+// ReplaceContentAndTruncate sets message's content and deletes every later message in its
+// session, along with the embeddings of the edited and deleted messages. It returns the
+// number of messages deleted.
+func (r *MessageRepository) ReplaceContentAndTruncate(ctx context.Context, message *model.Message, content string) (int64, error) {
+	var removed int64
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(message).Update("content", content).Error; err != nil {
+			return fmt.Errorf("update message content failed: %w", err)
+		}
+		sub := afterMessage(tx.Model(&model.Message{}).Select("id"), message)
+		if err := tx.Where("message_id = ? OR message_id IN (?)", message.ID, sub).Delete(&model.MessageEmbedding{}).Error; err != nil {
+			return fmt.Errorf("delete message embeddings after edit failed: %w", err)
+		}
+		result := afterMessage(tx, message).Delete(&model.Message{})
+		if result.Error != nil {
+			return fmt.Errorf("delete messages after edit failed: %w", result.Error)
+		}
+		removed = result.RowsAffected
+		return nil
+	})
+	return removed, err
+}
+
+// Fork creates session and stores messages in it, in order, each following the one
+// before. The first keeps its ParentMessageID, which is the fork point. The stored messages
+// get their IDs.
+func (r *MessageRepository) Fork(ctx context.Context, session *model.Session, messages []model.Message) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(session).Error; err != nil {
+			return fmt.Errorf("create forked session failed: %w", err)
+		}
+		for i := range messages {
+			messages[i].SessionID = session.ID
+			if i > 0 {
+				messages[i].ParentMessageID = &messages[i-1].ID
+			}
+			if err := tx.Create(&messages[i]).Error; err != nil {
+				return fmt.Errorf("store forked session message failed: %w", err)
+			}
+		}
+		return nil
+	})
 }
@@ -0,0 +1,91 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+
+	"gopherai-resume/internal/model"
+)
+
+type NotificationRepository struct {
+	db *gorm.DB
+}
+
+func NewNotificationRepository(db *gorm.DB) *NotificationRepository {
+	return &NotificationRepository{db: db}
+}
+
+func (r *NotificationRepository) GetPreference(ctx context.Context, userID uint) (*model.NotificationPreference, error) {
+	var pref model.NotificationPreference
+	if err := r.db.WithContext(ctx).Where("user_id = ?", userID).First(&pref).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("get notification preference failed: %w", err)
+	}
+	return &pref, nil
+}
+
+// SavePreference inserts or updates the user's preferences.
+func (r *NotificationRepository) SavePreference(ctx context.Context, pref *model.NotificationPreference) error {
+	if err := r.db.WithContext(ctx).Save(pref).Error; err != nil {
+		return fmt.Errorf("save notification preference failed: %w", err)
+	}
+	return nil
+}
+
+func (r *NotificationRepository) CreateOutbox(ctx context.Context, email *model.EmailOutbox) error {
+	if err := r.db.WithContext(ctx).Create(email).Error; err != nil {
+		return fmt.Errorf("create email outbox entry failed: %w", err)
+	}
+	return nil
+}
+
+// ListDueOutbox returns up to limit pending emails whose next attempt is due, oldest first.
+func (r *NotificationRepository) ListDueOutbox(ctx context.Context, status string, now time.Time, limit int) ([]model.EmailOutbox, error) {
+	var list []model.EmailOutbox
+	err := r.db.WithContext(ctx).Where("status = ? AND next_attempt_at <= ?", status, now).
+		Order("next_attempt_at ASC, id ASC").
+		Limit(limit).
+		Find(&list).Error
+	if err != nil {
+		return nil, fmt.Errorf("list due email outbox failed: %w", err)
+	}
+	return list, nil
+}
+
+// ClaimOutbox takes a due email for one delivery attempt by counting the attempt and
+// moving its next attempt to leaseUntil, unless another sender claimed it first. An email
+// whose sender dies is retried once the lease runs out.
+func (r *NotificationRepository) ClaimOutbox(ctx context.Context, id uint, status string, now, leaseUntil time.Time) (bool, error) {
+	result := r.db.WithContext(ctx).Model(&model.EmailOutbox{}).
+		Where("id = ? AND status = ? AND next_attempt_at <= ?", id, status, now).
+		Updates(map[string]interface{}{
+			"attempts":        gorm.Expr("attempts + 1"),
+			"next_attempt_at": leaseUntil,
+		})
+	if result.Error != nil {
+		return false, fmt.Errorf("claim email outbox entry failed: %w", result.Error)
+	}
+	return result.RowsAffected == 1, nil
+}
+
+func (r *NotificationRepository) UpdateOutbox(ctx context.Context, email *model.EmailOutbox) error {
+	if err := r.db.WithContext(ctx).Save(email).Error; err != nil {
+		return fmt.Errorf("update email outbox entry failed: %w", err)
+	}
+	return nil
+}
+
+// ListOutboxByUserIDAfterID pages through the emails sent or queued for the user by ID.
+func (r *NotificationRepository) ListOutboxByUserIDAfterID(ctx context.Context, userID, afterID uint, limit int) ([]model.EmailOutbox, error) {
+	var list []model.EmailOutbox
+	if err := r.db.WithContext(ctx).Where("user_id = ? AND id > ?", userID, afterID).Order("id ASC").Limit(limit).Find(&list).Error; err != nil {
+		return nil, fmt.Errorf("list email outbox by user failed: %w", err)
+	}
+	return list, nil
+}
@@ -0,0 +1,52 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"gorm.io/gorm"
+
+	"gopherai-resume/internal/model"
+)
+
+type PortfolioAnalysisRepository struct {
+	db *gorm.DB
+}
+
+func NewPortfolioAnalysisRepository(db *gorm.DB) *PortfolioAnalysisRepository {
+	return &PortfolioAnalysisRepository{db: db}
+}
+
+func (r *PortfolioAnalysisRepository) Create(ctx context.Context, analysis *model.PortfolioAnalysis) error {
+	if err := r.db.WithContext(ctx).Create(analysis).Error; err != nil {
+		return fmt.Errorf("create portfolio analysis failed: %w", err)
+	}
+	return nil
+}
+
+func (r *PortfolioAnalysisRepository) GetByID(ctx context.Context, id uint) (*model.PortfolioAnalysis, error) {
+	var analysis model.PortfolioAnalysis
+	if err := r.db.WithContext(ctx).First(&analysis, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("get portfolio analysis failed: %w", err)
+	}
+	return &analysis, nil
+}
+
+func (r *PortfolioAnalysisRepository) ListByUserID(ctx context.Context, userID uint) ([]model.PortfolioAnalysis, error) {
+	var list []model.PortfolioAnalysis
+	if err := r.db.WithContext(ctx).Where("user_id = ?", userID).Order("created_at DESC").Find(&list).Error; err != nil {
+		return nil, fmt.Errorf("list portfolio analyses failed: %w", err)
+	}
+	return list, nil
+}
+
+func (r *PortfolioAnalysisRepository) DeleteByIDAndUserID(ctx context.Context, id, userID uint) error {
+	if err := r.db.WithContext(ctx).Where("id = ? AND user_id = ?", id, userID).Delete(&model.PortfolioAnalysis{}).Error; err != nil {
+		return fmt.Errorf("delete portfolio analysis failed: %w", err)
+	}
+	return nil
+}
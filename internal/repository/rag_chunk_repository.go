@@ -1,9 +1,12 @@
 package repository
 
 import (
+	"context"
 	"fmt"
+	"time"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 
 	"gopherai-resume/internal/model"
 )
@@ -16,39 +19,440 @@ func NewRAGChunkRepository(db *gorm.DB) *RAGChunkRepository {
 	return &RAGChunkRepository{db: db}
 }
 
-func (r *RAGChunkRepository) Create(chunk *model.RAGChunk) error {
-	if err := r.db.Create(chunk).Error; err != nil {
+func (r *RAGChunkRepository) Create(ctx context.Context, chunk *model.RAGChunk) error {
+	if err := r.db.WithContext(ctx).Create(chunk).Error; err != nil {
 		return fmt.Errorf("create rag chunk failed: %w", err)
 	}
 	return nil
 }
 
-func (r *RAGChunkRepository) CreateBatch(chunks []model.RAGChunk) error {
+// CreateBatch stores chunks. With a check, the user's storage is checked again in the same
+// transaction and the chunks are not stored if it fails.
+func (r *RAGChunkRepository) CreateBatch(ctx context.Context, chunks []model.RAGChunk, check *StorageCheck) error {
 	if len(chunks) == 0 {
 		return nil
 	}
-	if err := r.db.Create(&chunks).Error; err != nil {
-		return fmt.Errorf("create rag chunks batch failed: %w", err)
-	}
-	return nil
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := check.lock(tx); err != nil {
+			return err
+		}
+		if err := tx.Create(&chunks).Error; err != nil {
+			return fmt.Errorf("create rag chunks batch failed: %w", err)
+		}
+		return check.run(tx)
+	})
 }
 
 // ListByDocumentIDs returns all chunks for the given document IDs (for a user's docs).
 // Caller should filter document IDs by user ownership.
-func (r *RAGChunkRepository) ListByDocumentIDs(documentIDs []uint) ([]model.RAGChunk, error) {
+func (r *RAGChunkRepository) ListByDocumentIDs(ctx context.Context, documentIDs []uint) ([]model.RAGChunk, error) {
 	if len(documentIDs) == 0 {
 		return nil, nil
 	}
 	var chunks []model.RAGChunk
-	if err := r.db.Where("document_id IN ?", documentIDs).Find(&chunks).Error; err != nil {
+	if err := r.db.WithContext(ctx).Where("document_id IN ?", documentIDs).Order("document_id ASC, chunk_index ASC, id ASC").Find(&chunks).Error; err != nil {
 		return nil, fmt.Errorf("list rag chunks by document ids failed: %w", err)
 	}
 	return chunks, nil
 }
 
-func (r *RAGChunkRepository) DeleteByDocumentID(documentID uint) error {
-	if err := r.db.Where("document_id = ?", documentID).Delete(&model.RAGChunk{}).Error; err != nil {
+// ListContentByDocumentIDs returns the ID, document and content of every chunk of the
+// documents, without the embeddings, for keyword scoring.
+func (r *RAGChunkRepository) ListContentByDocumentIDs(ctx context.Context, documentIDs []uint) ([]model.RAGChunk, error) {
+	if len(documentIDs) == 0 {
+		return nil, nil
+	}
+	var chunks []model.RAGChunk
+	err := r.db.WithContext(ctx).Select("id", "document_id", "content").
+		Where("document_id IN ?", documentIDs).
+		Order("id ASC").
+		Find(&chunks).Error
+	if err != nil {
+		return nil, fmt.Errorf("list rag chunk content by document ids failed: %w", err)
+	}
+	return chunks, nil
+}
+
+// ListPageByDocumentID returns up to limit of the document's chunks in index order,
+// skipping the first offset.
+func (r *RAGChunkRepository) ListPageByDocumentID(ctx context.Context, documentID uint, offset, limit int) ([]model.RAGChunk, error) {
+	var chunks []model.RAGChunk
+	err := r.db.WithContext(ctx).Where("document_id = ?", documentID).
+		Order("chunk_index ASC, id ASC").Offset(offset).Limit(limit).Find(&chunks).Error
+	if err != nil {
+		return nil, fmt.Errorf("list rag chunks page failed: %w", err)
+	}
+	return chunks, nil
+}
+
+// CountByDocumentID returns how many chunks the document has.
+func (r *RAGChunkRepository) CountByDocumentID(ctx context.Context, documentID uint) (int64, error) {
+	var count int64
+	if err := r.db.WithContext(ctx).Model(&model.RAGChunk{}).Where("document_id = ?", documentID).Count(&count).Error; err != nil {
+		return 0, fmt.Errorf("count rag chunks failed: %w", err)
+	}
+	return count, nil
+}
+
+// ListByIDs returns the chunks with the given IDs; missing IDs are skipped.
+func (r *RAGChunkRepository) ListByIDs(ctx context.Context, ids []uint) ([]model.RAGChunk, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	var chunks []model.RAGChunk
+	if err := r.db.WithContext(ctx).Where("id IN ?", ids).Find(&chunks).Error; err != nil {
+		return nil, fmt.Errorf("list rag chunks by ids failed: %w", err)
+	}
+	return chunks, nil
+}
+
+// ListAfterID returns up to limit chunks with IDs above afterID in ID order, for walking
+// the whole table.
+func (r *RAGChunkRepository) ListAfterID(ctx context.Context, afterID uint, limit int) ([]model.RAGChunk, error) {
+	var chunks []model.RAGChunk
+	if err := r.db.WithContext(ctx).Where("id > ?", afterID).Order("id ASC").Limit(limit).Find(&chunks).Error; err != nil {
+		return nil, fmt.Errorf("list rag chunks after id failed: %w", err)
+	}
+	return chunks, nil
+}
+
+// ListJSONEmbeddedAfterID returns up to limit chunks with IDs above afterID, in ID order,
+// that still store their embedding as JSON.
+func (r *RAGChunkRepository) ListJSONEmbeddedAfterID(ctx context.Context, afterID uint, limit int) ([]model.RAGChunk, error) {
+	var chunks []model.RAGChunk
+	if err := r.db.WithContext(ctx).Where("id > ? AND embedding <> ''", afterID).Order("id ASC").Limit(limit).Find(&chunks).Error; err != nil {
+		return nil, fmt.Errorf("list json embedded rag chunks failed: %w", err)
+	}
+	return chunks, nil
+}
+
+// appendAttempts is how often AppendBatch tries again after another write took the chunk
+// indexes it chose.
+const appendAttempts = 3
+
+// AppendBatch stores chunks after a document's existing ones and returns the index of the
+// first. The document row is locked while the indexes are allocated, so concurrent appends
+// get distinct ones; a replace that races it fails on the unique index and is retried. The
+// chunks' indexes are set from the existing ones, their offsets moved past the existing
+// text and a newline, as DocumentText joins them, and the document's chunk count updated.
+// Documents whose chunks have no offsets keep the offsets of the appended text. With a
+// check, the user's storage is checked again before the append commits.
+func (r *RAGChunkRepository) AppendBatch(ctx context.Context, documentID uint, chunks []model.RAGChunk, check *StorageCheck) (int, error) {
+	for attempt := 1; ; attempt++ {
+		batch := append([]model.RAGChunk(nil), chunks...)
+		start, err := r.appendBatch(ctx, documentID, batch, check)
+		if err == nil {
+			copy(chunks, batch)
+			return start, nil
+		}
+		if !isDuplicateKey(err) || attempt == appendAttempts {
+			return 0, err
+		}
+	}
+}
+
+func (r *RAGChunkRepository) appendBatch(ctx context.Context, documentID uint, chunks []model.RAGChunk, check *StorageCheck) (int, error) {
+	var start int
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := check.lock(tx); err != nil {
+			return err
+		}
+		var doc model.RAGDocument
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id").First(&doc, documentID).Error; err != nil {
+			return fmt.Errorf("lock rag document failed: %w", err)
+		}
+		next, err := nextChunkIndex(tx, documentID)
+		if err != nil {
+			return err
+		}
+		var end int
+		if err := tx.Model(&model.RAGChunk{}).
+			Select("COALESCE(MAX(end_offset), 0)").
+			Where("document_id = ?", documentID).
+			Scan(&end).Error; err != nil {
+			return fmt.Errorf("get rag chunk end offset failed: %w", err)
+		}
+		for i := range chunks {
+			chunks[i].ChunkIndex = next + i
+			if end > 0 {
+				chunks[i].StartOffset += end + 1
+				chunks[i].EndOffset += end + 1
+			}
+		}
+		if err := tx.CreateInBatches(chunks, 100).Error; err != nil {
+			return fmt.Errorf("create rag chunks failed: %w", err)
+		}
+		if err := tx.Model(&model.RAGDocument{}).Where("id = ?", documentID).
+			Update("chunk_count", next+len(chunks)).Error; err != nil {
+			return fmt.Errorf("update rag document chunk count failed: %w", err)
+		}
+		start = next
+		return check.run(tx)
+	})
+	return start, err
+}
+
+// nextChunkIndex returns the index the next appended chunk of a document should use.
+// Documents ingested before chunk indexes existed had all-zero indexes until
+// RenumberChunkIndexes, so the chunk count is also considered.
+func nextChunkIndex(tx *gorm.DB, documentID uint) (int, error) {
+	var stats struct {
+		MaxIndex int
+		Total    int
+	}
+	if err := tx.Model(&model.RAGChunk{}).
+		Select("COALESCE(MAX(chunk_index), -1) AS max_index, COUNT(*) AS total").
+		Where("document_id = ?", documentID).
+		Scan(&stats).Error; err != nil {
+		return 0, fmt.Errorf("get next rag chunk index failed: %w", err)
+	}
+	next := stats.MaxIndex + 1
+	if stats.Total > next {
+		next = stats.Total
+	}
+	return next, nil
+}
+
+// RenumberChunkIndexes gives the chunks of documents with repeated chunk indexes, such as
+// those ingested before indexes existed, consecutive indexes in their current order. It
+// runs before the unique (document_id, chunk_index) index is created and does nothing once
+// it exists or before the chunk table does.
+func RenumberChunkIndexes(ctx context.Context, db *gorm.DB) error {
+	migrator := db.WithContext(ctx).Migrator()
+	if !migrator.HasTable(&model.RAGChunk{}) || migrator.HasIndex(&model.RAGChunk{}, model.RAGChunkIndexName) {
+		return nil
+	}
+	err := db.WithContext(ctx).Exec(`UPDATE rag_chunks
+JOIN (
+	SELECT id, ROW_NUMBER() OVER (PARTITION BY document_id ORDER BY chunk_index, id) - 1 AS position
+	FROM rag_chunks
+	WHERE document_id IN (
+		SELECT document_id FROM (
+			SELECT document_id FROM rag_chunks GROUP BY document_id, chunk_index HAVING COUNT(*) > 1
+		) AS repeated
+	)
+) AS numbered ON numbered.id = rag_chunks.id
+SET rag_chunks.chunk_index = numbered.position`).Error
+	if err != nil {
+		return fmt.Errorf("renumber rag chunk indexes failed: %w", err)
+	}
+	return nil
+}
+
+func (r *RAGChunkRepository) DeleteByDocumentID(ctx context.Context, documentID uint) error {
+	if err := r.db.WithContext(ctx).Where("document_id = ?", documentID).Delete(&model.RAGChunk{}).Error; err != nil {
 		return fmt.Errorf("delete rag chunks by document failed: %w", err)
 	}
 	return nil
 }
+
+// ReplaceByDocumentID swaps all chunks of a document for chunks in one transaction.
+func (r *RAGChunkRepository) ReplaceByDocumentID(ctx context.Context, documentID uint, chunks []model.RAGChunk, check *StorageCheck) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := check.lock(tx); err != nil {
+			return err
+		}
+		if err := tx.Where("document_id = ?", documentID).Delete(&model.RAGChunk{}).Error; err != nil {
+			return fmt.Errorf("delete rag chunks by document failed: %w", err)
+		}
+		if len(chunks) == 0 {
+			return nil
+		}
+		if err := tx.CreateInBatches(chunks, 100).Error; err != nil {
+			return fmt.Errorf("create rag chunks failed: %w", err)
+		}
+		return check.run(tx)
+	})
+}
+
+// RAGUserStorage summarizes RAG storage consumed by one user.
+type RAGUserStorage struct {
+	UserID         uint  `json:"user_id"`
+	DocumentCount  int64 `json:"document_count"`
+	ChunkCount     int64 `json:"chunk_count"`
+	ContentBytes   int64 `json:"content_bytes"`
+	EmbeddingBytes int64 `json:"embedding_bytes"`
+}
+
+// StorageCheck checks a user's RAG storage again inside the transaction of a write that
+// adds to it. The user's row stays locked until the write commits, so concurrent writes
+// of one user are checked one after another, each seeing the others' committed rows.
+// Check is given the storage as it is with the write and fails the write by returning
+// an error. A nil *StorageCheck checks nothing.
+type StorageCheck struct {
+	UserID uint
+	Check  func(RAGUserStorage) error
+}
+
+// lock takes the user's row lock before the write.
+func (c *StorageCheck) lock(tx *gorm.DB) error {
+	if c == nil {
+		return nil
+	}
+	var ids []uint
+	err := tx.Model(&model.User{}).Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("id = ?", c.UserID).Pluck("id", &ids).Error
+	if err != nil {
+		return fmt.Errorf("lock user for rag storage check failed: %w", err)
+	}
+	return nil
+}
+
+// run checks the storage with the write included.
+func (c *StorageCheck) run(tx *gorm.DB) error {
+	if c == nil {
+		return nil
+	}
+	used, err := storageByUserID(tx, c.UserID, 0)
+	if err != nil {
+		return err
+	}
+	return c.Check(used)
+}
+
+// CountOrphaned returns the number of chunks whose document no longer exists.
+func (r *RAGChunkRepository) CountOrphaned(ctx context.Context) (int64, error) {
+	var count int64
+	if err := r.orphanedQuery(ctx).Count(&count).Error; err != nil {
+		return 0, fmt.Errorf("count orphaned rag chunks failed: %w", err)
+	}
+	return count, nil
+}
+
+// DeleteOrphaned deletes chunks whose document no longer exists and returns how many were removed.
+func (r *RAGChunkRepository) DeleteOrphaned(ctx context.Context) (int64, error) {
+	result := r.orphanedQuery(ctx).Delete(&model.RAGChunk{})
+	if result.Error != nil {
+		return 0, fmt.Errorf("delete orphaned rag chunks failed: %w", result.Error)
+	}
+	return result.RowsAffected, nil
+}
+
+func (r *RAGChunkRepository) orphanedQuery(ctx context.Context) *gorm.DB {
+	return r.db.WithContext(ctx).Model(&model.RAGChunk{}).
+		Where("document_id NOT IN (?)", r.db.WithContext(ctx).Model(&model.RAGDocument{}).Select("id"))
+}
+
+// CountByDocument returns chunk counts keyed by document ID.
+func (r *RAGChunkRepository) CountByDocument(ctx context.Context) (map[uint]int, error) {
+	var rows []struct {
+		DocumentID uint
+		Total      int
+	}
+	if err := r.db.WithContext(ctx).Model(&model.RAGChunk{}).
+		Select("document_id, COUNT(*) AS total").
+		Group("document_id").
+		Scan(&rows).Error; err != nil {
+		return nil, fmt.Errorf("count rag chunks by document failed: %w", err)
+	}
+	counts := make(map[uint]int, len(rows))
+	for _, row := range rows {
+		counts[row.DocumentID] = row.Total
+	}
+	return counts, nil
+}
+
+// StorageByUser reports document/chunk counts and byte sizes per user.
+func (r *RAGChunkRepository) StorageByUser(ctx context.Context) ([]RAGUserStorage, error) {
+	var stats []RAGUserStorage
+	if err := r.db.WithContext(ctx).Table("rag_documents AS d").
+		Select("d.user_id AS user_id, COUNT(DISTINCT d.id) AS document_count, COUNT(c.id) AS chunk_count, " +
+			"COALESCE(SUM(LENGTH(c.content)), 0) AS content_bytes, " +
+			"COALESCE(SUM(COALESCE(LENGTH(c.embedding), 0) + COALESCE(LENGTH(c.embedding_blob), 0)), 0) AS embedding_bytes").
+		Joins("LEFT JOIN rag_chunks AS c ON c.document_id = d.id").
+		Group("d.user_id").
+		Order("embedding_bytes DESC").
+		Scan(&stats).Error; err != nil {
+		return nil, fmt.Errorf("report rag storage by user failed: %w", err)
+	}
+	return stats, nil
+}
+
+// StorageByUserID reports the user's document count, and the chunk count and content bytes
+// of their documents other than excludeDocumentID (0 = none).
+func (r *RAGChunkRepository) StorageByUserID(ctx context.Context, userID, excludeDocumentID uint) (RAGUserStorage, error) {
+	return storageByUserID(r.db.WithContext(ctx), userID, excludeDocumentID)
+}
+
+func storageByUserID(db *gorm.DB, userID, excludeDocumentID uint) (RAGUserStorage, error) {
+	stats := RAGUserStorage{UserID: userID}
+	if err := db.Model(&model.RAGDocument{}).Where("user_id = ?", userID).Count(&stats.DocumentCount).Error; err != nil {
+		return stats, fmt.Errorf("count rag documents of user failed: %w", err)
+	}
+	var chunks struct {
+		ChunkCount   int64
+		ContentBytes int64
+	}
+	if err := db.Table("rag_chunks AS c").
+		Select("COUNT(c.id) AS chunk_count, COALESCE(SUM(LENGTH(c.content)), 0) AS content_bytes").
+		Joins("JOIN rag_documents AS d ON d.id = c.document_id").
+		Where("d.user_id = ? AND c.document_id <> ?", userID, excludeDocumentID).
+		Scan(&chunks).Error; err != nil {
+		return stats, fmt.Errorf("report rag storage of user failed: %w", err)
+	}
+	stats.ChunkCount, stats.ContentBytes = chunks.ChunkCount, chunks.ContentBytes
+	return stats, nil
+}
+
+// RAGChunkFingerprint identifies a stored chunk's content; see model.RAGChunk.ContentHash.
+type RAGChunkFingerprint struct {
+	ContentHash string
+	SimHash     uint64
+}
+
+// ListFingerprints returns the fingerprints of the document's chunks. When hashes is not
+// empty, only chunks with one of those content hashes are returned.
+func (r *RAGChunkRepository) ListFingerprints(ctx context.Context, documentID uint, hashes []string) ([]RAGChunkFingerprint, error) {
+	q := r.db.WithContext(ctx).Table("rag_chunks AS c").
+		Select("c.content_hash, c.sim_hash").
+		Where("c.document_id = ? AND c.content_hash <> ''", documentID)
+	if len(hashes) > 0 {
+		q = q.Where("c.content_hash IN ?", hashes)
+	}
+	var list []RAGChunkFingerprint
+	if err := q.Scan(&list).Error; err != nil {
+		return nil, fmt.Errorf("list rag chunk fingerprints failed: %w", err)
+	}
+	return list, nil
+}
+
+// TouchAccessed sets last_accessed_at for the given chunks.
+func (r *RAGChunkRepository) TouchAccessed(ctx context.Context, ids []uint, at time.Time) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	if err := r.db.WithContext(ctx).Model(&model.RAGChunk{}).Where("id IN ?", ids).Update("last_accessed_at", at).Error; err != nil {
+		return fmt.Errorf("touch rag chunks failed: %w", err)
+	}
+	return nil
+}
+
+// ListColdDocumentIDs returns documents created before cutoff that still have hot embeddings
+// and none of whose chunks were retrieved since cutoff.
+func (r *RAGChunkRepository) ListColdDocumentIDs(ctx context.Context, cutoff time.Time) ([]uint, error) {
+	recent := r.db.WithContext(ctx).Model(&model.RAGChunk{}).Select("document_id").Where("last_accessed_at >= ?", cutoff)
+	var ids []uint
+	if err := r.db.WithContext(ctx).Model(&model.RAGChunk{}).
+		Distinct("rag_chunks.document_id").
+		Joins("JOIN rag_documents ON rag_documents.id = rag_chunks.document_id").
+		Where("rag_documents.created_at < ?", cutoff).
+		Where("rag_chunks.embedding_archive IS NULL").
+		Where("rag_chunks.document_id NOT IN (?)", recent).
+		Pluck("rag_chunks.document_id", &ids).Error; err != nil {
+		return nil, fmt.Errorf("list cold rag documents failed: %w", err)
+	}
+	return ids, nil
+}
+
+// SaveEmbeddingState persists the embedding columns after archiving or restoring a chunk.
+func (r *RAGChunkRepository) SaveEmbeddingState(ctx context.Context, chunk *model.RAGChunk) error {
+	if err := r.db.WithContext(ctx).Model(&model.RAGChunk{}).Where("id = ?", chunk.ID).Updates(map[string]interface{}{
+		"embedding":         chunk.Embedding,
+		"embedding_blob":    chunk.EmbeddingBlob,
+		"embedding_archive": chunk.EmbeddingArchive,
+		"archived_at":       chunk.ArchivedAt,
+	}).Error; err != nil {
+		return fmt.Errorf("save rag chunk embedding state failed: %w", err)
+	}
+	return nil
+}
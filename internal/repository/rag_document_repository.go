@@ -1,8 +1,10 @@
 package repository
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"time"
 
 	"gorm.io/gorm"
 
@@ -17,24 +19,33 @@ func NewRAGDocumentRepository(db *gorm.DB) *RAGDocumentRepository {
 	return &RAGDocumentRepository{db: db}
 }
 
-func (r *RAGDocumentRepository) Create(doc *model.RAGDocument) error {
-	if err := r.db.Create(doc).Error; err != nil {
-		return fmt.Errorf("create rag document failed: %w", err)
-	}
-	return nil
+// Create stores doc. With a check, the user's storage is checked again in the same
+// transaction and the document is not stored if it fails.
+func (r *RAGDocumentRepository) Create(ctx context.Context, doc *model.RAGDocument, check *StorageCheck) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := check.lock(tx); err != nil {
+			return err
+		}
+		if err := tx.Create(doc).Error; err != nil {
+			return fmt.Errorf("create rag document failed: %w", err)
+		}
+		return check.run(tx)
+	})
 }
 
-func (r *RAGDocumentRepository) ListByUserID(userID uint) ([]model.RAGDocument, error) {
+// ListByUserID lists the user's documents, newest first, leaving out temporary ones.
+func (r *RAGDocumentRepository) ListByUserID(ctx context.Context, userID uint) ([]model.RAGDocument, error) {
 	var list []model.RAGDocument
-	if err := r.db.Where("user_id = ?", userID).Order("created_at DESC").Find(&list).Error; err != nil {
+	if err := r.db.WithContext(ctx).Where("user_id = ? AND temporary = ?", userID, false).Order("created_at DESC").Find(&list).Error; err != nil {
 		return nil, fmt.Errorf("list rag documents failed: %w", err)
 	}
 	return list, nil
 }
 
 // ListByUserIDAndSessionID lists documents for user; if sessionID is 0, lists all user's docs.
-func (r *RAGDocumentRepository) ListByUserIDAndSessionID(userID, sessionID uint) ([]model.RAGDocument, error) {
-	q := r.db.Where("user_id = ?", userID)
+// Temporary documents are left out.
+func (r *RAGDocumentRepository) ListByUserIDAndSessionID(ctx context.Context, userID, sessionID uint) ([]model.RAGDocument, error) {
+	q := r.db.WithContext(ctx).Where("user_id = ? AND temporary = ?", userID, false)
 	if sessionID != 0 {
 		q = q.Where("session_id = ?", sessionID)
 	}
@@ -46,25 +57,25 @@ func (r *RAGDocumentRepository) ListByUserIDAndSessionID(userID, sessionID uint)
 }
 
 // ListBySessionID returns document IDs for a session (for cascade delete).
-func (r *RAGDocumentRepository) ListBySessionID(sessionID uint) ([]uint, error) {
+func (r *RAGDocumentRepository) ListBySessionID(ctx context.Context, sessionID uint) ([]uint, error) {
 	var ids []uint
-	if err := r.db.Model(&model.RAGDocument{}).Where("session_id = ?", sessionID).Pluck("id", &ids).Error; err != nil {
+	if err := r.db.WithContext(ctx).Model(&model.RAGDocument{}).Where("session_id = ?", sessionID).Pluck("id", &ids).Error; err != nil {
 		return nil, fmt.Errorf("list rag document ids by session failed: %w", err)
 	}
 	return ids, nil
 }
 
 // DeleteBySessionID deletes all documents in a session (caller must delete chunks first).
-func (r *RAGDocumentRepository) DeleteBySessionID(sessionID uint) error {
-	if err := r.db.Where("session_id = ?", sessionID).Delete(&model.RAGDocument{}).Error; err != nil {
+func (r *RAGDocumentRepository) DeleteBySessionID(ctx context.Context, sessionID uint) error {
+	if err := r.db.WithContext(ctx).Where("session_id = ?", sessionID).Delete(&model.RAGDocument{}).Error; err != nil {
 		return fmt.Errorf("delete rag documents by session failed: %w", err)
 	}
 	return nil
 }
 
-func (r *RAGDocumentRepository) GetByIDAndUserID(id, userID uint) (*model.RAGDocument, error) {
+func (r *RAGDocumentRepository) GetByID(ctx context.Context, id uint) (*model.RAGDocument, error) {
 	var doc model.RAGDocument
-	if err := r.db.Where("id = ? AND user_id = ?", id, userID).First(&doc).Error; err != nil {
+	if err := r.db.WithContext(ctx).First(&doc, id).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, nil
 		}
@@ -73,9 +84,169 @@ func (r *RAGDocumentRepository) GetByIDAndUserID(id, userID uint) (*model.RAGDoc
 	return &doc, nil
 }
 
-func (r *RAGDocumentRepository) DeleteByIDAndUserID(id, userID uint) error {
-	if err := r.db.Where("id = ? AND user_id = ?", id, userID).Delete(&model.RAGDocument{}).Error; err != nil {
+func (r *RAGDocumentRepository) DeleteByIDAndUserID(ctx context.Context, id, userID uint) error {
+	if err := r.db.WithContext(ctx).Where("id = ? AND user_id = ?", id, userID).Delete(&model.RAGDocument{}).Error; err != nil {
 		return fmt.Errorf("delete rag document failed: %w", err)
 	}
 	return nil
 }
+
+// ListAll returns every document; used by maintenance jobs.
+func (r *RAGDocumentRepository) ListAll(ctx context.Context) ([]model.RAGDocument, error) {
+	var list []model.RAGDocument
+	if err := r.db.WithContext(ctx).Order("id ASC").Find(&list).Error; err != nil {
+		return nil, fmt.Errorf("list all rag documents failed: %w", err)
+	}
+	return list, nil
+}
+
+// UpdateStatus sets the document's ingest status and error message.
+func (r *RAGDocumentRepository) UpdateStatus(ctx context.Context, id uint, status, errMsg string) error {
+	err := r.db.WithContext(ctx).Model(&model.RAGDocument{}).Where("id = ?", id).
+		Updates(map[string]interface{}{"status": status, "error": errMsg}).Error
+	if err != nil {
+		return fmt.Errorf("update rag document status failed: %w", err)
+	}
+	return nil
+}
+
+// Ingest statuses ClaimIngest and ReleaseIngest move documents between, as in app.
+const (
+	documentPending    = "pending"
+	documentProcessing = "processing"
+)
+
+// ClaimIngest moves a pending document, or a processing one whose lease ran out before
+// now, to processing with a lease until leaseUntil and counts the claim. It reports false
+// when the document is in neither state, such as when another worker holds it.
+func (r *RAGDocumentRepository) ClaimIngest(ctx context.Context, id uint, now, leaseUntil time.Time) (bool, error) {
+	result := r.db.WithContext(ctx).Model(&model.RAGDocument{}).
+		Where("id = ? AND (status = ? OR (status = ? AND (ingest_lease_until IS NULL OR ingest_lease_until < ?)))",
+			id, documentPending, documentProcessing, now).
+		Updates(map[string]interface{}{
+			"status":             documentProcessing,
+			"error":              "",
+			"ingest_lease_until": leaseUntil,
+			"ingest_claims":      gorm.Expr("ingest_claims + 1"),
+		})
+	if result.Error != nil {
+		return false, fmt.Errorf("claim rag document ingest failed: %w", result.Error)
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// ReleaseIngest puts a processing document back to pending for the next claim.
+func (r *RAGDocumentRepository) ReleaseIngest(ctx context.Context, id uint) error {
+	err := r.db.WithContext(ctx).Model(&model.RAGDocument{}).
+		Where("id = ? AND status = ?", id, documentProcessing).
+		Updates(map[string]interface{}{"status": documentPending, "ingest_lease_until": nil}).Error
+	if err != nil {
+		return fmt.Errorf("release rag document ingest failed: %w", err)
+	}
+	return nil
+}
+
+// ListByStatus returns every document in one of the statuses, oldest first.
+func (r *RAGDocumentRepository) ListByStatus(ctx context.Context, statuses []string) ([]model.RAGDocument, error) {
+	var list []model.RAGDocument
+	if err := r.db.WithContext(ctx).Where("status IN ?", statuses).Order("id ASC").Find(&list).Error; err != nil {
+		return nil, fmt.Errorf("list rag documents by status failed: %w", err)
+	}
+	return list, nil
+}
+
+// ListTemporaryBefore returns the temporary documents created before cutoff, oldest first.
+func (r *RAGDocumentRepository) ListTemporaryBefore(ctx context.Context, cutoff time.Time) ([]model.RAGDocument, error) {
+	var list []model.RAGDocument
+	err := r.db.WithContext(ctx).
+		Where("temporary = ? AND created_at < ?", true, cutoff).
+		Order("id ASC").
+		Find(&list).Error
+	if err != nil {
+		return nil, fmt.Errorf("list temporary rag documents failed: %w", err)
+	}
+	return list, nil
+}
+
+// ListByFileHash returns the user's documents in the session (0 = no session) uploaded from
+// the file with the given hash, newest first.
+func (r *RAGDocumentRepository) ListByFileHash(ctx context.Context, userID, sessionID uint, fileHash string) ([]model.RAGDocument, error) {
+	var list []model.RAGDocument
+	err := r.db.WithContext(ctx).
+		Where("user_id = ? AND session_id = ? AND file_hash = ?", userID, sessionID, fileHash).
+		Order("id DESC").
+		Find(&list).Error
+	if err != nil {
+		return nil, fmt.Errorf("list rag documents by file hash failed: %w", err)
+	}
+	return list, nil
+}
+
+func (r *RAGDocumentRepository) UpdateChunkCount(ctx context.Context, id uint, count int) error {
+	if err := r.db.WithContext(ctx).Model(&model.RAGDocument{}).Where("id = ?", id).Update("chunk_count", count).Error; err != nil {
+		return fmt.Errorf("update rag document chunk count failed: %w", err)
+	}
+	return nil
+}
+
+// UpdateDedupe records how many chunks an asynchronous ingest skipped as duplicates.
+func (r *RAGDocumentRepository) UpdateDedupe(ctx context.Context, id uint, duplicate, nearDuplicate int) error {
+	err := r.db.WithContext(ctx).Model(&model.RAGDocument{}).Where("id = ?", id).
+		Updates(map[string]interface{}{
+			"duplicate_chunks":      duplicate,
+			"near_duplicate_chunks": nearDuplicate,
+		}).Error
+	if err != nil {
+		return fmt.Errorf("update rag document dedupe counts failed: %w", err)
+	}
+	return nil
+}
+
+// UpdateContent saves the name, chunking, chunk and page counts and file hash of a document
+// whose content was replaced.
+func (r *RAGDocumentRepository) UpdateContent(ctx context.Context, doc *model.RAGDocument) error {
+	err := r.db.WithContext(ctx).Model(&model.RAGDocument{}).Where("id = ?", doc.ID).
+		Updates(map[string]interface{}{
+			"name":            doc.Name,
+			"chunk_count":     doc.ChunkCount,
+			"page_count":      doc.PageCount,
+			"chunk_size":      doc.ChunkSize,
+			"chunk_overlap":   doc.ChunkOverlap,
+			"chunk_strategy":  doc.ChunkStrategy,
+			"file_hash":       doc.FileHash,
+			"content_version": gorm.Expr("content_version + 1"),
+		}).Error
+	if err != nil {
+		return fmt.Errorf("update rag document content failed: %w", err)
+	}
+	return nil
+}
+
+// ListUnindexedIDs returns those of ids whose chunks are not all in the vector store.
+func (r *RAGDocumentRepository) ListUnindexedIDs(ctx context.Context, ids []uint) ([]uint, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	var unindexed []uint
+	err := r.db.WithContext(ctx).Model(&model.RAGDocument{}).
+		Where("id IN ? AND vector_indexed = ?", ids, false).
+		Pluck("id", &unindexed).Error
+	if err != nil {
+		return nil, fmt.Errorf("list unindexed rag documents failed: %w", err)
+	}
+	return unindexed, nil
+}
+
+// SetVectorIndexed records whether the documents' chunks are all in the vector store.
+func (r *RAGDocumentRepository) SetVectorIndexed(ctx context.Context, ids []uint, indexed bool) error {
+	for start := 0; start < len(ids); start += 500 {
+		end := min(start+500, len(ids))
+		err := r.db.WithContext(ctx).Model(&model.RAGDocument{}).
+			Where("id IN ?", ids[start:end]).
+			Update("vector_indexed", indexed).Error
+		if err != nil {
+			return fmt.Errorf("update rag document index state failed: %w", err)
+		}
+	}
+	return nil
+}
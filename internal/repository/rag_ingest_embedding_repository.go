@@ -0,0 +1,60 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"gopherai-resume/internal/model"
+)
+
+type RAGIngestEmbeddingRepository struct {
+	db *gorm.DB
+}
+
+func NewRAGIngestEmbeddingRepository(db *gorm.DB) *RAGIngestEmbeddingRepository {
+	return &RAGIngestEmbeddingRepository{db: db}
+}
+
+// UpsertBatch stores the embeddings, replacing any earlier one for the same chunk.
+func (r *RAGIngestEmbeddingRepository) UpsertBatch(ctx context.Context, embeddings []model.RAGIngestEmbedding) error {
+	if len(embeddings) == 0 {
+		return nil
+	}
+	err := r.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "document_id"}, {Name: "chunk_index"}},
+		DoUpdates: clause.AssignmentColumns([]string{"fingerprint", "embedding_blob"}),
+	}).Create(&embeddings).Error
+	if err != nil {
+		return fmt.Errorf("upsert rag ingest embeddings failed: %w", err)
+	}
+	return nil
+}
+
+// ListByDocumentID returns the embeddings saved for the document.
+func (r *RAGIngestEmbeddingRepository) ListByDocumentID(ctx context.Context, documentID uint) ([]model.RAGIngestEmbedding, error) {
+	var embeddings []model.RAGIngestEmbedding
+	if err := r.db.WithContext(ctx).Where("document_id = ?", documentID).Find(&embeddings).Error; err != nil {
+		return nil, fmt.Errorf("list rag ingest embeddings failed: %w", err)
+	}
+	return embeddings, nil
+}
+
+// CountByDocumentID returns how many embeddings are saved for the document.
+func (r *RAGIngestEmbeddingRepository) CountByDocumentID(ctx context.Context, documentID uint) (int64, error) {
+	var count int64
+	if err := r.db.WithContext(ctx).Model(&model.RAGIngestEmbedding{}).Where("document_id = ?", documentID).Count(&count).Error; err != nil {
+		return 0, fmt.Errorf("count rag ingest embeddings failed: %w", err)
+	}
+	return count, nil
+}
+
+// DeleteByDocumentID deletes the embeddings saved for the document.
+func (r *RAGIngestEmbeddingRepository) DeleteByDocumentID(ctx context.Context, documentID uint) error {
+	if err := r.db.WithContext(ctx).Where("document_id = ?", documentID).Delete(&model.RAGIngestEmbedding{}).Error; err != nil {
+		return fmt.Errorf("delete rag ingest embeddings failed: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,46 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"gorm.io/gorm"
+
+	"gopherai-resume/internal/model"
+)
+
+type RAGMessageRepository struct {
+	db *gorm.DB
+}
+
+func NewRAGMessageRepository(db *gorm.DB) *RAGMessageRepository {
+	return &RAGMessageRepository{db: db}
+}
+
+func (r *RAGMessageRepository) Create(ctx context.Context, message *model.RAGMessage) error {
+	if err := r.db.WithContext(ctx).Create(message).Error; err != nil {
+		return fmt.Errorf("create rag message failed: %w", err)
+	}
+	return nil
+}
+
+// ListRecentBySessionID returns up to limit of the session's newest messages with an ID below
+// beforeID (0 = no bound), newest first.
+func (r *RAGMessageRepository) ListRecentBySessionID(ctx context.Context, sessionID, beforeID uint, limit int) ([]model.RAGMessage, error) {
+	query := r.db.WithContext(ctx).Where("session_id = ?", sessionID)
+	if beforeID > 0 {
+		query = query.Where("id < ?", beforeID)
+	}
+	var list []model.RAGMessage
+	if err := query.Order("id DESC").Limit(limit).Find(&list).Error; err != nil {
+		return nil, fmt.Errorf("list rag messages failed: %w", err)
+	}
+	return list, nil
+}
+
+func (r *RAGMessageRepository) DeleteBySessionID(ctx context.Context, sessionID uint) error {
+	if err := r.db.WithContext(ctx).Where("session_id = ?", sessionID).Delete(&model.RAGMessage{}).Error; err != nil {
+		return fmt.Errorf("delete rag messages failed: %w", err)
+	}
+	return nil
+}
@@ -1,6 +1,7 @@
 package repository
 
 import (
+	"context"
 	"errors"
 	"fmt"
 
@@ -17,24 +18,24 @@ func NewRAGSessionRepository(db *gorm.DB) *RAGSessionRepository {
 	return &RAGSessionRepository{db: db}
 }
 
-func (r *RAGSessionRepository) Create(session *model.RAGSession) error {
-	if err := r.db.Create(session).Error; err != nil {
+func (r *RAGSessionRepository) Create(ctx context.Context, session *model.RAGSession) error {
+	if err := r.db.WithContext(ctx).Create(session).Error; err != nil {
 		return fmt.Errorf("create rag session failed: %w", err)
 	}
 	return nil
 }
 
-func (r *RAGSessionRepository) ListByUserID(userID uint) ([]model.RAGSession, error) {
+func (r *RAGSessionRepository) ListByUserID(ctx context.Context, userID uint) ([]model.RAGSession, error) {
 	var list []model.RAGSession
-	if err := r.db.Where("user_id = ?", userID).Order("created_at DESC").Find(&list).Error; err != nil {
+	if err := r.db.WithContext(ctx).Where("user_id = ?", userID).Order("created_at DESC").Find(&list).Error; err != nil {
 		return nil, fmt.Errorf("list rag sessions failed: %w", err)
 	}
 	return list, nil
 }
 
-func (r *RAGSessionRepository) GetByIDAndUserID(id, userID uint) (*model.RAGSession, error) {
+func (r *RAGSessionRepository) GetByID(ctx context.Context, id uint) (*model.RAGSession, error) {
 	var session model.RAGSession
-	if err := r.db.Where("id = ? AND user_id = ?", id, userID).First(&session).Error; err != nil {
+	if err := r.db.WithContext(ctx).First(&session, id).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, nil
 		}
@@ -43,8 +44,8 @@ func (r *RAGSessionRepository) GetByIDAndUserID(id, userID uint) (*model.RAGSess
 	return &session, nil
 }
 
-func (r *RAGSessionRepository) DeleteByIDAndUserID(id, userID uint) error {
-	if err := r.db.Where("id = ? AND user_id = ?", id, userID).Delete(&model.RAGSession{}).Error; err != nil {
+func (r *RAGSessionRepository) DeleteByIDAndUserID(ctx context.Context, id, userID uint) error {
+	if err := r.db.WithContext(ctx).Where("id = ? AND user_id = ?", id, userID).Delete(&model.RAGSession{}).Error; err != nil {
 		return fmt.Errorf("delete rag session failed: %w", err)
 	}
 	return nil
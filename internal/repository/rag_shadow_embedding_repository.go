@@ -0,0 +1,180 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"gopherai-resume/internal/model"
+)
+
+type RAGShadowEmbeddingRepository struct {
+	db *gorm.DB
+}
+
+func NewRAGShadowEmbeddingRepository(db *gorm.DB) *RAGShadowEmbeddingRepository {
+	return &RAGShadowEmbeddingRepository{db: db}
+}
+
+// UpsertBatch stores the embeddings, replacing any earlier one for the same chunk and model.
+func (r *RAGShadowEmbeddingRepository) UpsertBatch(ctx context.Context, embeddings []model.RAGShadowEmbedding) error {
+	if len(embeddings) == 0 {
+		return nil
+	}
+	err := r.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "chunk_id"}, {Name: "model"}},
+		DoUpdates: clause.AssignmentColumns([]string{"embedding_blob", "attempts", "error"}),
+	}).Create(&embeddings).Error
+	if err != nil {
+		return fmt.Errorf("upsert rag shadow embeddings failed: %w", err)
+	}
+	return nil
+}
+
+// RecordFailures counts a failed attempt to embed each chunk under the model and keeps
+// message as the last error.
+func (r *RAGShadowEmbeddingRepository) RecordFailures(ctx context.Context, modelName string, chunks []model.RAGChunk, message string) error {
+	if len(chunks) == 0 {
+		return nil
+	}
+	rows := make([]model.RAGShadowEmbedding, len(chunks))
+	for i, c := range chunks {
+		rows[i] = model.RAGShadowEmbedding{ChunkID: c.ID, DocumentID: c.DocumentID, Model: modelName, Attempts: 1, Error: message}
+	}
+	err := r.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "chunk_id"}, {Name: "model"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{
+			"attempts": gorm.Expr("attempts + 1"),
+			"error":    message,
+		}),
+	}).Create(&rows).Error
+	if err != nil {
+		return fmt.Errorf("record rag shadow embedding failures failed: %w", err)
+	}
+	return nil
+}
+
+// ListByDocumentIDs returns the model's embeddings of the given documents' chunks.
+func (r *RAGShadowEmbeddingRepository) ListByDocumentIDs(ctx context.Context, modelName string, documentIDs []uint) ([]model.RAGShadowEmbedding, error) {
+	if len(documentIDs) == 0 {
+		return nil, nil
+	}
+	var embeddings []model.RAGShadowEmbedding
+	if err := r.db.WithContext(ctx).Where("model = ? AND document_id IN ?", modelName, documentIDs).Find(&embeddings).Error; err != nil {
+		return nil, fmt.Errorf("list rag shadow embeddings failed: %w", err)
+	}
+	return embeddings, nil
+}
+
+// ListChunksMissing returns up to limit chunks with IDs above afterID, in ID order, that
+// have no embedding under the model yet and have failed fewer than maxAttempts times. It
+// walks the chunks by primary key and joins each to its embedding, so a run costs the
+// chunks it passes rather than the whole embedding table.
+func (r *RAGShadowEmbeddingRepository) ListChunksMissing(ctx context.Context, modelName string, afterID uint, limit, maxAttempts int) ([]model.RAGChunk, error) {
+	var chunks []model.RAGChunk
+	err := r.db.WithContext(ctx).
+		Joins("LEFT JOIN rag_shadow_embeddings ON rag_shadow_embeddings.chunk_id = rag_chunks.id AND rag_shadow_embeddings.model = ?", modelName).
+		Where("rag_chunks.id > ?", afterID).
+		Where("rag_shadow_embeddings.id IS NULL OR (rag_shadow_embeddings.embedding_blob IS NULL AND rag_shadow_embeddings.attempts < ?)", maxAttempts).
+		Order("rag_chunks.id ASC").Limit(limit).Find(&chunks).Error
+	if err != nil {
+		return nil, fmt.Errorf("list rag chunks missing shadow embeddings failed: %w", err)
+	}
+	return chunks, nil
+}
+
+// CountByModel returns how many chunks have an embedding under the model.
+func (r *RAGShadowEmbeddingRepository) CountByModel(ctx context.Context, modelName string) (int64, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&model.RAGShadowEmbedding{}).
+		Where("model = ? AND embedding_blob IS NOT NULL", modelName).
+		Count(&count).Error
+	if err != nil {
+		return 0, fmt.Errorf("count rag shadow embeddings failed: %w", err)
+	}
+	return count, nil
+}
+
+// CountFailedByModel returns how many chunks failed to embed under the model at least
+// maxAttempts times and are no longer tried.
+func (r *RAGShadowEmbeddingRepository) CountFailedByModel(ctx context.Context, modelName string, maxAttempts int) (int64, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&model.RAGShadowEmbedding{}).
+		Where("model = ? AND embedding_blob IS NULL AND attempts >= ?", modelName, maxAttempts).
+		Count(&count).Error
+	if err != nil {
+		return 0, fmt.Errorf("count failed rag shadow embeddings failed: %w", err)
+	}
+	return count, nil
+}
+
+// DeleteOrphaned deletes the embeddings among the first limit with IDs above afterID
+// whose chunk no longer exists. It returns the last ID examined, 0 once there are none
+// left, and how many were removed.
+func (r *RAGShadowEmbeddingRepository) DeleteOrphaned(ctx context.Context, afterID uint, limit int) (uint, int64, error) {
+	var rows []struct {
+		ID      uint
+		ChunkID *uint
+	}
+	err := r.db.WithContext(ctx).Model(&model.RAGShadowEmbedding{}).
+		Select("rag_shadow_embeddings.id, rag_chunks.id AS chunk_id").
+		Joins("LEFT JOIN rag_chunks ON rag_chunks.id = rag_shadow_embeddings.chunk_id").
+		Where("rag_shadow_embeddings.id > ?", afterID).
+		Order("rag_shadow_embeddings.id ASC").Limit(limit).
+		Scan(&rows).Error
+	if err != nil {
+		return 0, 0, fmt.Errorf("list orphaned rag shadow embeddings failed: %w", err)
+	}
+	if len(rows) == 0 {
+		return 0, 0, nil
+	}
+	var orphaned []uint
+	for _, row := range rows {
+		if row.ChunkID == nil {
+			orphaned = append(orphaned, row.ID)
+		}
+	}
+	var deleted int64
+	if len(orphaned) > 0 {
+		result := r.db.WithContext(ctx).Where("id IN ?", orphaned).Delete(&model.RAGShadowEmbedding{})
+		if result.Error != nil {
+			return 0, 0, fmt.Errorf("delete orphaned rag shadow embeddings failed: %w", result.Error)
+		}
+		deleted = result.RowsAffected
+	}
+	return rows[len(rows)-1].ID, deleted, nil
+}
+
+// RecordComparison adds one comparison ask's overlap share and Jaccard similarity to the
+// model's totals.
+func (r *RAGShadowEmbeddingRepository) RecordComparison(ctx context.Context, modelName string, overlap, jaccard float64) error {
+	stat := model.RAGShadowStat{Model: modelName, Comparisons: 1, OverlapSum: overlap, JaccardSum: jaccard}
+	err := r.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "model"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{
+			"comparisons": gorm.Expr("comparisons + 1"),
+			"overlap_sum": gorm.Expr("overlap_sum + ?", overlap),
+			"jaccard_sum": gorm.Expr("jaccard_sum + ?", jaccard),
+			"updated_at":  gorm.Expr("CURRENT_TIMESTAMP(3)"),
+		}),
+	}).Create(&stat).Error
+	if err != nil {
+		return fmt.Errorf("record rag shadow comparison failed: %w", err)
+	}
+	return nil
+}
+
+// GetStat returns the model's comparison totals, or nil before the first comparison.
+func (r *RAGShadowEmbeddingRepository) GetStat(ctx context.Context, modelName string) (*model.RAGShadowStat, error) {
+	var stat model.RAGShadowStat
+	if err := r.db.WithContext(ctx).Where("model = ?", modelName).First(&stat).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("get rag shadow stat failed: %w", err)
+	}
+	return &stat, nil
+}
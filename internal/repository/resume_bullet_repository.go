@@ -0,0 +1,95 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"gopherai-resume/internal/model"
+)
+
+type ResumeBulletRepository struct {
+	db *gorm.DB
+}
+
+func NewResumeBulletRepository(db *gorm.DB) *ResumeBulletRepository {
+	return &ResumeBulletRepository{db: db}
+}
+
+// ReplaceOpen makes bullets the document's flagged bullets. Open bullets (those not in one of
+// closedStatuses) whose text is flagged again are kept with their conversation and the rest
+// are deleted. A bullet whose text matches an existing one, open or closed, or the accepted
+// rewrite of one, is not inserted again.
+func (r *ResumeBulletRepository) ReplaceOpen(ctx context.Context, documentID uint, closedStatuses []string, bullets []model.ResumeBullet) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var existing []model.ResumeBullet
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("document_id = ?", documentID).Find(&existing).Error; err != nil {
+			return fmt.Errorf("list resume bullets failed: %w", err)
+		}
+		flagged := make(map[string]bool, len(bullets))
+		for _, b := range bullets {
+			flagged[b.Original] = true
+		}
+		known := make(map[string]bool, len(existing))
+		var stale []uint
+		for _, b := range existing {
+			if !slices.Contains(closedStatuses, b.Status) && !flagged[b.Original] {
+				stale = append(stale, b.ID)
+				continue
+			}
+			known[b.Original] = true
+			if b.Proposed != "" && slices.Contains(closedStatuses, b.Status) {
+				known[b.Proposed] = true
+			}
+		}
+		if len(stale) > 0 {
+			if err := tx.Delete(&model.ResumeBullet{}, stale).Error; err != nil {
+				return fmt.Errorf("delete resume bullets failed: %w", err)
+			}
+		}
+		var fresh []model.ResumeBullet
+		for _, b := range bullets {
+			if !known[b.Original] {
+				fresh = append(fresh, b)
+			}
+		}
+		if len(fresh) == 0 {
+			return nil
+		}
+		if err := tx.Create(&fresh).Error; err != nil {
+			return fmt.Errorf("create resume bullets failed: %w", err)
+		}
+		return nil
+	})
+}
+
+func (r *ResumeBulletRepository) Update(ctx context.Context, bullet *model.ResumeBullet) error {
+	if err := r.db.WithContext(ctx).Save(bullet).Error; err != nil {
+		return fmt.Errorf("update resume bullet failed: %w", err)
+	}
+	return nil
+}
+
+func (r *ResumeBulletRepository) GetByID(ctx context.Context, id uint) (*model.ResumeBullet, error) {
+	var bullet model.ResumeBullet
+	if err := r.db.WithContext(ctx).First(&bullet, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("get resume bullet failed: %w", err)
+	}
+	return &bullet, nil
+}
+
+func (r *ResumeBulletRepository) ListByDocumentID(ctx context.Context, userID, documentID uint) ([]model.ResumeBullet, error) {
+	var list []model.ResumeBullet
+	if err := r.db.WithContext(ctx).Where("user_id = ? AND document_id = ?", userID, documentID).Order("id ASC").Find(&list).Error; err != nil {
+		return nil, fmt.Errorf("list resume bullets failed: %w", err)
+	}
+	return list, nil
+}
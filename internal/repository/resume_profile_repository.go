@@ -0,0 +1,38 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"gorm.io/gorm"
+
+	"gopherai-resume/internal/model"
+)
+
+type ResumeProfileRepository struct {
+	db *gorm.DB
+}
+
+func NewResumeProfileRepository(db *gorm.DB) *ResumeProfileRepository {
+	return &ResumeProfileRepository{db: db}
+}
+
+// Save inserts a new profile or updates an existing one (ID set).
+func (r *ResumeProfileRepository) Save(ctx context.Context, profile *model.ResumeProfile) error {
+	if err := r.db.WithContext(ctx).Save(profile).Error; err != nil {
+		return fmt.Errorf("save resume profile failed: %w", err)
+	}
+	return nil
+}
+
+func (r *ResumeProfileRepository) GetByDocumentIDAndUserID(ctx context.Context, documentID, userID uint) (*model.ResumeProfile, error) {
+	var profile model.ResumeProfile
+	if err := r.db.WithContext(ctx).Where("document_id = ? AND user_id = ?", documentID, userID).First(&profile).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("get resume profile failed: %w", err)
+	}
+	return &profile, nil
+}
@@ -0,0 +1,106 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"gorm.io/gorm"
+
+	"gopherai-resume/internal/model"
+)
+
+type ResumeSchemaRepository struct {
+	db *gorm.DB
+}
+
+func NewResumeSchemaRepository(db *gorm.DB) *ResumeSchemaRepository {
+	return &ResumeSchemaRepository{db: db}
+}
+
+// schemaCreateAttempts bounds the retries of a version number another create took first.
+const schemaCreateAttempts = 3
+
+// Create stores schema as the next version, activating it when schema.Active is set. Two
+// creates racing for the same version collide on its unique index, and the loser retries.
+func (r *ResumeSchemaRepository) Create(ctx context.Context, schema *model.ResumeSchema) error {
+	for attempt := 1; ; attempt++ {
+		err := r.create(ctx, schema)
+		if err == nil || !isDuplicateKey(err) || attempt == schemaCreateAttempts {
+			return err
+		}
+		schema.ID = 0
+	}
+}
+
+func (r *ResumeSchemaRepository) create(ctx context.Context, schema *model.ResumeSchema) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var latest int
+		if err := tx.Model(&model.ResumeSchema{}).Select("COALESCE(MAX(version), 0)").Scan(&latest).Error; err != nil {
+			return fmt.Errorf("get latest resume schema version failed: %w", err)
+		}
+		schema.Version = latest + 1
+		if schema.Active {
+			if err := deactivateResumeSchemas(tx); err != nil {
+				return err
+			}
+		}
+		if err := tx.Create(schema).Error; err != nil {
+			return fmt.Errorf("create resume schema failed: %w", err)
+		}
+		return nil
+	})
+}
+
+func (r *ResumeSchemaRepository) List(ctx context.Context) ([]model.ResumeSchema, error) {
+	var list []model.ResumeSchema
+	if err := r.db.WithContext(ctx).Order("version DESC").Find(&list).Error; err != nil {
+		return nil, fmt.Errorf("list resume schemas failed: %w", err)
+	}
+	return list, nil
+}
+
+func (r *ResumeSchemaRepository) GetByVersion(ctx context.Context, version int) (*model.ResumeSchema, error) {
+	var schema model.ResumeSchema
+	if err := r.db.WithContext(ctx).Where("version = ?", version).First(&schema).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("get resume schema failed: %w", err)
+	}
+	return &schema, nil
+}
+
+func (r *ResumeSchemaRepository) GetActive(ctx context.Context) (*model.ResumeSchema, error) {
+	var schema model.ResumeSchema
+	if err := r.db.WithContext(ctx).Where("active = ?", true).Order("version DESC").First(&schema).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("get active resume schema failed: %w", err)
+	}
+	return &schema, nil
+}
+
+// Activate makes version the only active schema; version 0 deactivates all of them.
+func (r *ResumeSchemaRepository) Activate(ctx context.Context, version int) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := deactivateResumeSchemas(tx); err != nil {
+			return err
+		}
+		if version == 0 {
+			return nil
+		}
+		if err := tx.Model(&model.ResumeSchema{}).Where("version = ?", version).Update("active", true).Error; err != nil {
+			return fmt.Errorf("activate resume schema failed: %w", err)
+		}
+		return nil
+	})
+}
+
+func deactivateResumeSchemas(tx *gorm.DB) error {
+	if err := tx.Model(&model.ResumeSchema{}).Where("active = ?", true).Update("active", false).Error; err != nil {
+		return fmt.Errorf("deactivate resume schemas failed: %w", err)
+	}
+	return nil
+}
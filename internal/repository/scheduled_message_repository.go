@@ -0,0 +1,119 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+
+	"gopherai-resume/internal/model"
+)
+
+type ScheduledMessageRepository struct {
+	db *gorm.DB
+}
+
+func NewScheduledMessageRepository(db *gorm.DB) *ScheduledMessageRepository {
+	return &ScheduledMessageRepository{db: db}
+}
+
+func (r *ScheduledMessageRepository) Create(ctx context.Context, msg *model.ScheduledMessage) error {
+	if err := r.db.WithContext(ctx).Create(msg).Error; err != nil {
+		return fmt.Errorf("create scheduled message failed: %w", err)
+	}
+	return nil
+}
+
+func (r *ScheduledMessageRepository) GetByID(ctx context.Context, id uint) (*model.ScheduledMessage, error) {
+	var msg model.ScheduledMessage
+	if err := r.db.WithContext(ctx).First(&msg, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("get scheduled message failed: %w", err)
+	}
+	return &msg, nil
+}
+
+// ListByUserID lists the user's scheduled messages, soonest first. An empty status lists all.
+func (r *ScheduledMessageRepository) ListByUserID(ctx context.Context, userID uint, status string) ([]model.ScheduledMessage, error) {
+	query := r.db.WithContext(ctx).Where("user_id = ?", userID)
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	var list []model.ScheduledMessage
+	if err := query.Order("run_at ASC, id ASC").Find(&list).Error; err != nil {
+		return nil, fmt.Errorf("list scheduled messages failed: %w", err)
+	}
+	return list, nil
+}
+
+// CountByUserIDAndStatus counts the user's scheduled messages in status.
+func (r *ScheduledMessageRepository) CountByUserIDAndStatus(ctx context.Context, userID uint, status string) (int64, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&model.ScheduledMessage{}).
+		Where("user_id = ? AND status = ?", userID, status).
+		Count(&count).Error
+	if err != nil {
+		return 0, fmt.Errorf("count scheduled messages failed: %w", err)
+	}
+	return count, nil
+}
+
+// ListDue returns up to limit messages that are pending and due at now, or were claimed
+// at or before staleBefore and never finished, earliest first.
+func (r *ScheduledMessageRepository) ListDue(ctx context.Context, pending, running string, now, staleBefore time.Time, limit int) ([]model.ScheduledMessage, error) {
+	var list []model.ScheduledMessage
+	err := r.db.WithContext(ctx).Where("(status = ? AND run_at <= ?) OR (status = ? AND claimed_at <= ?)", pending, now, running, staleBefore).
+		Order("run_at ASC, id ASC").
+		Limit(limit).
+		Find(&list).Error
+	if err != nil {
+		return nil, fmt.Errorf("list due scheduled messages failed: %w", err)
+	}
+	return list, nil
+}
+
+// Claim moves msg to status and counts an attempt, unless another worker changed it since
+// it was read; Attempts serves as the version. It reports whether the claim won and
+// updates msg to match.
+func (r *ScheduledMessageRepository) Claim(ctx context.Context, msg *model.ScheduledMessage, status string, at time.Time) (bool, error) {
+	result := r.db.WithContext(ctx).Model(&model.ScheduledMessage{}).
+		Where("id = ? AND status = ? AND attempts = ?", msg.ID, msg.Status, msg.Attempts).
+		Updates(map[string]interface{}{
+			"status":     status,
+			"attempts":   msg.Attempts + 1,
+			"claimed_at": at,
+		})
+	if result.Error != nil {
+		return false, fmt.Errorf("claim scheduled message failed: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return false, nil
+	}
+	msg.Status = status
+	msg.Attempts++
+	msg.ClaimedAt = &at
+	return true, nil
+}
+
+func (r *ScheduledMessageRepository) Update(ctx context.Context, msg *model.ScheduledMessage) error {
+	if err := r.db.WithContext(ctx).Save(msg).Error; err != nil {
+		return fmt.Errorf("update scheduled message failed: %w", err)
+	}
+	return nil
+}
+
+// SetStatusIf moves the user's message from one status to another and reports whether it
+// was in from.
+func (r *ScheduledMessageRepository) SetStatusIf(ctx context.Context, id, userID uint, from, to string) (bool, error) {
+	result := r.db.WithContext(ctx).Model(&model.ScheduledMessage{}).
+		Where("id = ? AND user_id = ? AND status = ?", id, userID, from).
+		Update("status", to)
+	if result.Error != nil {
+		return false, fmt.Errorf("update scheduled message status failed: %w", result.Error)
+	}
+	return result.RowsAffected > 0, nil
+}
@@ -0,0 +1,67 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"gorm.io/gorm"
+
+	"gopherai-resume/internal/model"
+)
+
+type ScreeningReportRepository struct {
+	db *gorm.DB
+}
+
+func NewScreeningReportRepository(db *gorm.DB) *ScreeningReportRepository {
+	return &ScreeningReportRepository{db: db}
+}
+
+func (r *ScreeningReportRepository) CreateBatch(ctx context.Context, reports []model.ScreeningReport) error {
+	if len(reports) == 0 {
+		return nil
+	}
+	if err := r.db.WithContext(ctx).Create(&reports).Error; err != nil {
+		return fmt.Errorf("create screening reports failed: %w", err)
+	}
+	return nil
+}
+
+func (r *ScreeningReportRepository) Update(ctx context.Context, report *model.ScreeningReport) error {
+	if err := r.db.WithContext(ctx).Save(report).Error; err != nil {
+		return fmt.Errorf("update screening report failed: %w", err)
+	}
+	return nil
+}
+
+func (r *ScreeningReportRepository) GetByID(ctx context.Context, id uint) (*model.ScreeningReport, error) {
+	var report model.ScreeningReport
+	if err := r.db.WithContext(ctx).First(&report, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("get screening report failed: %w", err)
+	}
+	return &report, nil
+}
+
+func (r *ScreeningReportRepository) GetByIDAndPostingID(ctx context.Context, id, postingID uint) (*model.ScreeningReport, error) {
+	var report model.ScreeningReport
+	if err := r.db.WithContext(ctx).Where("id = ? AND job_posting_id = ?", id, postingID).First(&report).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("get screening report failed: %w", err)
+	}
+	return &report, nil
+}
+
+// ListByPostingID lists a posting's reports, newest first.
+func (r *ScreeningReportRepository) ListByPostingID(ctx context.Context, postingID uint) ([]model.ScreeningReport, error) {
+	var list []model.ScreeningReport
+	if err := r.db.WithContext(ctx).Where("job_posting_id = ?", postingID).Order("created_at DESC, id DESC").Find(&list).Error; err != nil {
+		return nil, fmt.Errorf("list screening reports failed: %w", err)
+	}
+	return list, nil
+}
@@ -1,10 +1,12 @@
 package repository
 
 import (
+	"context"
 	"errors"
 	"fmt"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 
 	"gopherai-resume/internal/model"
 )
@@ -17,24 +19,31 @@ func NewSessionRepository(db *gorm.DB) *SessionRepository {
 	return &SessionRepository{db: db}
 }
 
-func (r *SessionRepository) Create(session *model.Session) error {
-	if err := r.db.Create(session).Error; err != nil {
+func (r *SessionRepository) Create(ctx context.Context, session *model.Session) error {
+	if err := r.db.WithContext(ctx).Create(session).Error; err != nil {
 		return fmt.Errorf("create session failed: %w", err)
 	}
 	return nil
 }
 
-func (r *SessionRepository) ListByUserID(userID uint) ([]model.Session, error) {
+// ListByUserID lists pinned sessions first, then by custom order, then most recently updated.
+func (r *SessionRepository) ListByUserID(ctx context.Context, userID uint) ([]model.Session, error) {
 	var sessions []model.Session
-	if err := r.db.Where("user_id = ?", userID).Order("updated_at DESC").Find(&sessions).Error; err != nil {
+	err := r.db.WithContext(ctx).Where("user_id = ?", userID).
+		Order("pinned DESC").
+		Order("CASE WHEN sort_order = 0 THEN 1 ELSE 0 END").
+		Order("sort_order ASC").
+		Order("updated_at DESC").
+		Find(&sessions).Error
+	if err != nil {
 		return nil, fmt.Errorf("list sessions failed: %w", err)
 	}
 	return sessions, nil
 }
 
-func (r *SessionRepository) GetByIDAndUserID(sessionID, userID uint) (*model.Session, error) {
+func (r *SessionRepository) GetByID(ctx context.Context, id uint) (*model.Session, error) {
 	var session model.Session
-	if err := r.db.Where("id = ? AND user_id = ?", sessionID, userID).First(&session).Error; err != nil {
+	if err := r.db.WithContext(ctx).First(&session, id).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, nil
 		}
@@ -43,9 +52,187 @@ func (r *SessionRepository) GetByIDAndUserID(sessionID, userID uint) (*model.Ses
 	return &session, nil
 }
 
-func (r *SessionRepository) DeleteByIDAndUserID(sessionID, userID uint) error {
-	if err := r.db.Where("id = ? AND user_id = ?", sessionID, userID).Delete(&model.Session{}).Error; err != nil {
-		return fmt.Errorf("delete session failed: %w", err)
+func (r *SessionRepository) Update(ctx context.Context, session *model.Session) error {
+	if err := r.db.WithContext(ctx).Save(session).Error; err != nil {
+		return fmt.Errorf("update session failed: %w", err)
 	}
 	return nil
 }
+
+// CountByIDsAndUserID counts how many of the IDs are sessions owned by the user.
+func (r *SessionRepository) CountByIDsAndUserID(ctx context.Context, ids []uint, userID uint) (int64, error) {
+	var count int64
+	if err := r.db.WithContext(ctx).Model(&model.Session{}).Where("id IN ? AND user_id = ?", ids, userID).Count(&count).Error; err != nil {
+		return 0, fmt.Errorf("count sessions failed: %w", err)
+	}
+	return count, nil
+}
+
+// UpdateSortOrder gives the user's sessions in ids positions 1..n in that order. The user's
+// other ordered sessions follow them in their previous order, so every position stays unique.
+// UpdatedAt is left alone so reordering does not count as activity.
+func (r *SessionRepository) UpdateSortOrder(ctx context.Context, userID uint, ids []uint) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var ordered []model.Session
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id", "sort_order").
+			Where("user_id = ? AND sort_order > 0", userID).
+			Order("sort_order ASC, id ASC").Find(&ordered).Error
+		if err != nil {
+			return fmt.Errorf("list session sort order failed: %w", err)
+		}
+		current := make(map[uint]int, len(ordered))
+		for _, session := range ordered {
+			current[session.ID] = session.SortOrder
+		}
+		order := append([]uint(nil), ids...)
+		listed := make(map[uint]bool, len(ids))
+		for _, id := range ids {
+			listed[id] = true
+		}
+		for _, session := range ordered {
+			if !listed[session.ID] {
+				order = append(order, session.ID)
+			}
+		}
+		for i, id := range order {
+			if current[id] == i+1 {
+				continue
+			}
+			err := tx.Model(&model.Session{}).
+				Where("id = ? AND user_id = ?", id, userID).
+				UpdateColumn("sort_order", i+1).Error
+			if err != nil {
+				return fmt.Errorf("update session sort order failed: %w", err)
+			}
+		}
+		return nil
+	})
+}
+
+// UpdateSummary stores a session's rolling summary. UpdatedAt is left alone so it does not
+// count as activity, and other fields are untouched so concurrent setting changes survive.
+func (r *SessionRepository) UpdateSummary(ctx context.Context, sessionID uint, summary string, untilID uint) error {
+	columns := map[string]interface{}{"summary": summary, "summary_until_id": untilID}
+	if untilID == 0 {
+		// A cleared summary is rebuilt from the history the next send finds too long.
+		columns["summary_pending_id"] = 0
+	}
+	err := r.db.WithContext(ctx).Model(&model.Session{}).
+		Where("id = ?", sessionID).
+		UpdateColumns(columns).Error
+	if err != nil {
+		return fmt.Errorf("update session summary failed: %w", err)
+	}
+	return nil
+}
+
+// MarkSummaryPending records that the session's messages before beforeID should be
+// summarized. It never lowers an earlier mark.
+func (r *SessionRepository) MarkSummaryPending(ctx context.Context, sessionID, beforeID uint) error {
+	err := r.db.WithContext(ctx).Model(&model.Session{}).
+		Where("id = ?", sessionID).
+		UpdateColumn("summary_pending_id", gorm.Expr("GREATEST(summary_pending_id, ?)", beforeID)).Error
+	if err != nil {
+		return fmt.Errorf("mark session summary pending failed: %w", err)
+	}
+	return nil
+}
+
+// ListSummaryPending lists up to limit sessions with messages waiting to be summarized.
+func (r *SessionRepository) ListSummaryPending(ctx context.Context, limit int) ([]model.Session, error) {
+	var sessions []model.Session
+	err := r.db.WithContext(ctx).
+		Where("summary_pending_id > summary_until_id + 1").
+		Order("id ASC").Limit(limit).Find(&sessions).Error
+	if err != nil {
+		return nil, fmt.Errorf("list sessions pending summary failed: %w", err)
+	}
+	return sessions, nil
+}
+
+// maxLineage bounds how far up a chain of forks is followed.
+const maxLineage = 64
+
+// Lineage returns the session followed by the sessions it was forked from, nearest first.
+// A deleted ancestor ends the chain early.
+func (r *SessionRepository) Lineage(ctx context.Context, sessionID uint) ([]model.Session, error) {
+	return loadLineage(r.db.WithContext(ctx), sessionID)
+}
+
+// lineageQuery walks up from a session through its fork parents in one round trip.
+const lineageQuery = `WITH RECURSIVE lineage (id, parent_session_id, fork_message_id, depth) AS (
+	SELECT id, parent_session_id, fork_message_id, 0 FROM sessions WHERE id = ?
+	UNION ALL
+	SELECT s.id, s.parent_session_id, s.fork_message_id, l.depth + 1
+	FROM sessions s JOIN lineage l ON s.id = l.parent_session_id
+	WHERE l.fork_message_id IS NOT NULL AND l.depth + 1 < ?
+)
+SELECT sessions.* FROM sessions JOIN lineage ON lineage.id = sessions.id ORDER BY lineage.depth`
+
+func loadLineage(db *gorm.DB, sessionID uint) ([]model.Session, error) {
+	var lineage []model.Session
+	if err := db.Raw(lineageQuery, sessionID, maxLineage).Scan(&lineage).Error; err != nil {
+		return nil, fmt.Errorf("load session lineage failed: %w", err)
+	}
+	return lineage, nil
+}
+
+// treeQuery walks down from a root session through every fork of the user's in one round
+// trip.
+const treeQuery = `WITH RECURSIVE tree (id, depth) AS (
+	SELECT id, 0 FROM sessions WHERE id = ? AND user_id = ?
+	UNION ALL
+	SELECT s.id, t.depth + 1
+	FROM sessions s JOIN tree t ON s.parent_session_id = t.id
+	WHERE s.user_id = ? AND t.depth + 1 < ?
+)
+SELECT sessions.* FROM sessions JOIN tree ON tree.id = sessions.id ORDER BY tree.depth, sessions.id`
+
+// ListTree returns the user's session rootID followed by every session forked from it,
+// directly or not, level by level.
+func (r *SessionRepository) ListTree(ctx context.Context, rootID, userID uint) ([]model.Session, error) {
+	var sessions []model.Session
+	if err := r.db.WithContext(ctx).Raw(treeQuery, rootID, userID, userID, maxLineage).Scan(&sessions).Error; err != nil {
+		return nil, fmt.Errorf("list session tree failed: %w", err)
+	}
+	return sessions, nil
+}
+
+// CountForks counts the sessions forked directly from sessionID.
+func (r *SessionRepository) CountForks(ctx context.Context, sessionID uint) (int64, error) {
+	var count int64
+	if err := r.db.WithContext(ctx).Model(&model.Session{}).Where("parent_session_id = ?", sessionID).Count(&count).Error; err != nil {
+		return 0, fmt.Errorf("count session forks failed: %w", err)
+	}
+	return count, nil
+}
+
+// HasForksFrom reports whether a session was forked from sessionID at messageID or later,
+// so that its history includes the message.
+func (r *SessionRepository) HasForksFrom(ctx context.Context, sessionID, messageID uint) (bool, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&model.Session{}).
+		Where("parent_session_id = ? AND fork_message_id >= ?", sessionID, messageID).
+		Count(&count).Error
+	if err != nil {
+		return false, fmt.Errorf("check session forks failed: %w", err)
+	}
+	return count > 0, nil
+}
+
+// DeleteByIDAndUserID deletes the session and revokes its share link.
+func (r *SessionRepository) DeleteByIDAndUserID(ctx context.Context, sessionID, userID uint) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("id = ? AND user_id = ?", sessionID, userID).Delete(&model.Session{})
+		if result.Error != nil {
+			return fmt.Errorf("delete session failed: %w", result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return nil
+		}
+		if err := tx.Where("session_id = ?", sessionID).Delete(&model.SessionShare{}).Error; err != nil {
+			return fmt.Errorf("delete session share failed: %w", err)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,52 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"gorm.io/gorm"
+
+	"gopherai-resume/internal/model"
+)
+
+type SessionShareRepository struct {
+	db *gorm.DB
+}
+
+func NewSessionShareRepository(db *gorm.DB) *SessionShareRepository {
+	return &SessionShareRepository{db: db}
+}
+
+// Replace stores share and deletes the session's earlier share, so only the newest link works.
+func (r *SessionShareRepository) Replace(ctx context.Context, share *model.SessionShare) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("session_id = ?", share.SessionID).Delete(&model.SessionShare{}).Error; err != nil {
+			return fmt.Errorf("delete session share failed: %w", err)
+		}
+		if err := tx.Create(share).Error; err != nil {
+			return fmt.Errorf("create session share failed: %w", err)
+		}
+		return nil
+	})
+}
+
+func (r *SessionShareRepository) GetByTokenHash(ctx context.Context, hash string) (*model.SessionShare, error) {
+	var share model.SessionShare
+	if err := r.db.WithContext(ctx).Where("token_hash = ?", hash).First(&share).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("get session share failed: %w", err)
+	}
+	return &share, nil
+}
+
+// DeleteBySessionID revokes the session's share and reports whether there was one.
+func (r *SessionShareRepository) DeleteBySessionID(ctx context.Context, sessionID uint) (bool, error) {
+	result := r.db.WithContext(ctx).Where("session_id = ?", sessionID).Delete(&model.SessionShare{})
+	if result.Error != nil {
+		return false, fmt.Errorf("delete session share failed: %w", result.Error)
+	}
+	return result.RowsAffected > 0, nil
+}
@@ -0,0 +1,59 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+
+	"gopherai-resume/internal/model"
+)
+
+type UserTokenRepository struct {
+	db *gorm.DB
+}
+
+func NewUserTokenRepository(db *gorm.DB) *UserTokenRepository {
+	return &UserTokenRepository{db: db}
+}
+
+// Replace stores token and deletes the user's earlier tokens for the same purpose, so only
+// the most recently emailed one works.
+func (r *UserTokenRepository) Replace(ctx context.Context, token *model.UserToken) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ? AND purpose = ?", token.UserID, token.Purpose).Delete(&model.UserToken{}).Error; err != nil {
+			return fmt.Errorf("delete user tokens failed: %w", err)
+		}
+		if err := tx.Create(token).Error; err != nil {
+			return fmt.Errorf("create user token failed: %w", err)
+		}
+		return nil
+	})
+}
+
+// Consume marks an unused, unexpired token as used and returns it. It returns nil when no
+// such token exists or another request used it first.
+func (r *UserTokenRepository) Consume(ctx context.Context, hash, purpose string, now time.Time) (*model.UserToken, error) {
+	var token model.UserToken
+	err := r.db.WithContext(ctx).Where("token_hash = ? AND purpose = ? AND used_at IS NULL AND expires_at > ?", hash, purpose, now).
+		First(&token).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("get user token failed: %w", err)
+	}
+	result := r.db.WithContext(ctx).Model(&model.UserToken{}).
+		Where("id = ? AND used_at IS NULL", token.ID).
+		Update("used_at", now)
+	if result.Error != nil {
+		return nil, fmt.Errorf("mark user token used failed: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return nil, nil
+	}
+	token.UsedAt = &now
+	return &token, nil
+}
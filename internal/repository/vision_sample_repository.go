@@ -0,0 +1,87 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"gorm.io/gorm"
+
+	"gopherai-resume/internal/model"
+)
+
+type VisionSampleRepository struct {
+	db *gorm.DB
+}
+
+func NewVisionSampleRepository(db *gorm.DB) *VisionSampleRepository {
+	return &VisionSampleRepository{db: db}
+}
+
+func (r *VisionSampleRepository) Create(ctx context.Context, sample *model.VisionSample) error {
+	if err := r.db.WithContext(ctx).Create(sample).Error; err != nil {
+		return fmt.Errorf("create vision sample failed: %w", err)
+	}
+	return nil
+}
+
+func (r *VisionSampleRepository) GetByID(ctx context.Context, id uint) (*model.VisionSample, error) {
+	var sample model.VisionSample
+	if err := r.db.WithContext(ctx).First(&sample, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("get vision sample failed: %w", err)
+	}
+	return &sample, nil
+}
+
+func (r *VisionSampleRepository) ListByUserID(ctx context.Context, userID uint, limit int) ([]model.VisionSample, error) {
+	if limit <= 0 || limit > 200 {
+		limit = 50
+	}
+	var list []model.VisionSample
+	if err := r.db.WithContext(ctx).Where("user_id = ?", userID).Order("created_at DESC").Limit(limit).Find(&list).Error; err != nil {
+		return nil, fmt.Errorf("list vision samples failed: %w", err)
+	}
+	return list, nil
+}
+
+// ListByUserIDAfterID returns up to limit of the user's samples with IDs above afterID, in
+// ID order, for walking all of them.
+func (r *VisionSampleRepository) ListByUserIDAfterID(ctx context.Context, userID, afterID uint, limit int) ([]model.VisionSample, error) {
+	var list []model.VisionSample
+	if err := r.db.WithContext(ctx).Where("user_id = ? AND id > ?", userID, afterID).Order("id ASC").Limit(limit).Find(&list).Error; err != nil {
+		return nil, fmt.Errorf("list vision samples by user failed: %w", err)
+	}
+	return list, nil
+}
+
+// ListByImageHash returns the user's samples of the image with the given SHA-256, newest
+// first.
+func (r *VisionSampleRepository) ListByImageHash(ctx context.Context, userID uint, sha256 string) ([]model.VisionSample, error) {
+	var list []model.VisionSample
+	if err := r.db.WithContext(ctx).Where("user_id = ? AND sha256 = ?", userID, sha256).Order("id DESC").Find(&list).Error; err != nil {
+		return nil, fmt.Errorf("list vision samples by image hash failed: %w", err)
+	}
+	return list, nil
+}
+
+// ListRecent returns the most recent samples across all users, for model evaluation.
+func (r *VisionSampleRepository) ListRecent(ctx context.Context, limit int) ([]model.VisionSample, error) {
+	if limit <= 0 || limit > 1000 {
+		limit = 100
+	}
+	var list []model.VisionSample
+	if err := r.db.WithContext(ctx).Order("created_at DESC").Limit(limit).Find(&list).Error; err != nil {
+		return nil, fmt.Errorf("list recent vision samples failed: %w", err)
+	}
+	return list, nil
+}
+
+func (r *VisionSampleRepository) DeleteByIDAndUserID(ctx context.Context, id, userID uint) error {
+	if err := r.db.WithContext(ctx).Where("id = ? AND user_id = ?", id, userID).Delete(&model.VisionSample{}).Error; err != nil {
+		return fmt.Errorf("delete vision sample failed: %w", err)
+	}
+	return nil
+}
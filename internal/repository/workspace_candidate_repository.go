@@ -0,0 +1,84 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"gorm.io/gorm"
+
+	"gopherai-resume/internal/model"
+)
+
+type WorkspaceCandidateRepository struct {
+	db *gorm.DB
+}
+
+func NewWorkspaceCandidateRepository(db *gorm.DB) *WorkspaceCandidateRepository {
+	return &WorkspaceCandidateRepository{db: db}
+}
+
+func (r *WorkspaceCandidateRepository) Create(ctx context.Context, candidate *model.WorkspaceCandidate) error {
+	if err := r.db.WithContext(ctx).Create(candidate).Error; err != nil {
+		return fmt.Errorf("create workspace candidate failed: %w", err)
+	}
+	return nil
+}
+
+func (r *WorkspaceCandidateRepository) GetByIDAndWorkspaceID(ctx context.Context, id, workspaceID uint) (*model.WorkspaceCandidate, error) {
+	var candidate model.WorkspaceCandidate
+	if err := r.db.WithContext(ctx).Where("id = ? AND workspace_id = ?", id, workspaceID).First(&candidate).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("get workspace candidate failed: %w", err)
+	}
+	return &candidate, nil
+}
+
+func (r *WorkspaceCandidateRepository) GetByDocumentID(ctx context.Context, workspaceID, documentID uint) (*model.WorkspaceCandidate, error) {
+	var candidate model.WorkspaceCandidate
+	if err := r.db.WithContext(ctx).Where("workspace_id = ? AND document_id = ?", workspaceID, documentID).First(&candidate).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("get workspace candidate by document failed: %w", err)
+	}
+	return &candidate, nil
+}
+
+func (r *WorkspaceCandidateRepository) ListByWorkspaceID(ctx context.Context, workspaceID uint) ([]model.WorkspaceCandidate, error) {
+	var list []model.WorkspaceCandidate
+	if err := r.db.WithContext(ctx).Where("workspace_id = ?", workspaceID).Order("created_at DESC").Find(&list).Error; err != nil {
+		return nil, fmt.Errorf("list workspace candidates failed: %w", err)
+	}
+	return list, nil
+}
+
+// Search lists up to limit of the workspace's candidates that have every skill and at
+// least minYears of experience, most experienced first when byExperience is set and
+// newest first otherwise. Candidates with unknown experience fail a minYears filter.
+func (r *WorkspaceCandidateRepository) Search(ctx context.Context, workspaceID uint, skills []string, minYears *float64, byExperience bool, limit int) ([]model.WorkspaceCandidate, error) {
+	query := r.db.WithContext(ctx).Where("workspace_id = ?", workspaceID)
+	for _, skill := range skills {
+		query = query.Where("JSON_CONTAINS(skills, JSON_QUOTE(?))", skill)
+	}
+	if minYears != nil {
+		query = query.Where("years_experience >= ?", *minYears)
+	}
+	if byExperience {
+		query = query.Order("years_experience IS NULL, years_experience DESC")
+	}
+	var list []model.WorkspaceCandidate
+	if err := query.Order("created_at DESC").Order("id DESC").Limit(limit).Find(&list).Error; err != nil {
+		return nil, fmt.Errorf("search workspace candidates failed: %w", err)
+	}
+	return list, nil
+}
+
+func (r *WorkspaceCandidateRepository) DeleteByIDAndWorkspaceID(ctx context.Context, id, workspaceID uint) error {
+	if err := r.db.WithContext(ctx).Where("id = ? AND workspace_id = ?", id, workspaceID).Delete(&model.WorkspaceCandidate{}).Error; err != nil {
+		return fmt.Errorf("delete workspace candidate failed: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,108 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"gorm.io/gorm"
+
+	"gopherai-resume/internal/model"
+)
+
+type WorkspaceRepository struct {
+	db *gorm.DB
+}
+
+func NewWorkspaceRepository(db *gorm.DB) *WorkspaceRepository {
+	return &WorkspaceRepository{db: db}
+}
+
+// Create inserts the workspace and its owner's membership.
+func (r *WorkspaceRepository) Create(ctx context.Context, workspace *model.Workspace, owner *model.WorkspaceMember) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(workspace).Error; err != nil {
+			return fmt.Errorf("create workspace failed: %w", err)
+		}
+		owner.WorkspaceID = workspace.ID
+		if err := tx.Create(owner).Error; err != nil {
+			return fmt.Errorf("create workspace member failed: %w", err)
+		}
+		return nil
+	})
+}
+
+func (r *WorkspaceRepository) GetByID(ctx context.Context, id uint) (*model.Workspace, error) {
+	var workspace model.Workspace
+	if err := r.db.WithContext(ctx).First(&workspace, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("get workspace failed: %w", err)
+	}
+	return &workspace, nil
+}
+
+// ListByUserID lists the workspaces the user is a member of with the given membership
+// status.
+func (r *WorkspaceRepository) ListByUserID(ctx context.Context, userID uint, status string) ([]model.Workspace, error) {
+	var list []model.Workspace
+	err := r.db.WithContext(ctx).Joins("JOIN workspace_members ON workspace_members.workspace_id = workspaces.id").
+		Where("workspace_members.user_id = ? AND workspace_members.status = ?", userID, status).
+		Order("workspaces.name ASC").
+		Find(&list).Error
+	if err != nil {
+		return nil, fmt.Errorf("list workspaces failed: %w", err)
+	}
+	return list, nil
+}
+
+// UpdateModelAllowlist stores the workspace's allowed models and base URLs, as JSON.
+func (r *WorkspaceRepository) UpdateModelAllowlist(ctx context.Context, id uint, models, baseURLs string) error {
+	err := r.db.WithContext(ctx).Model(&model.Workspace{}).Where("id = ?", id).
+		Updates(map[string]any{"allowed_models": models, "allowed_base_urls": baseURLs}).Error
+	if err != nil {
+		return fmt.Errorf("update workspace model allowlist failed: %w", err)
+	}
+	return nil
+}
+
+func (r *WorkspaceRepository) GetMember(ctx context.Context, workspaceID, userID uint) (*model.WorkspaceMember, error) {
+	var member model.WorkspaceMember
+	if err := r.db.WithContext(ctx).Where("workspace_id = ? AND user_id = ?", workspaceID, userID).First(&member).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("get workspace member failed: %w", err)
+	}
+	return &member, nil
+}
+
+func (r *WorkspaceRepository) ListMembers(ctx context.Context, workspaceID uint) ([]model.WorkspaceMember, error) {
+	var list []model.WorkspaceMember
+	if err := r.db.WithContext(ctx).Where("workspace_id = ?", workspaceID).Order("created_at ASC, id ASC").Find(&list).Error; err != nil {
+		return nil, fmt.Errorf("list workspace members failed: %w", err)
+	}
+	return list, nil
+}
+
+func (r *WorkspaceRepository) AddMember(ctx context.Context, member *model.WorkspaceMember) error {
+	if err := r.db.WithContext(ctx).Create(member).Error; err != nil {
+		return fmt.Errorf("add workspace member failed: %w", err)
+	}
+	return nil
+}
+
+func (r *WorkspaceRepository) UpdateMember(ctx context.Context, member *model.WorkspaceMember) error {
+	if err := r.db.WithContext(ctx).Save(member).Error; err != nil {
+		return fmt.Errorf("update workspace member failed: %w", err)
+	}
+	return nil
+}
+
+func (r *WorkspaceRepository) RemoveMember(ctx context.Context, workspaceID, userID uint) error {
+	if err := r.db.WithContext(ctx).Where("workspace_id = ? AND user_id = ?", workspaceID, userID).Delete(&model.WorkspaceMember{}).Error; err != nil {
+		return fmt.Errorf("remove workspace member failed: %w", err)
+	}
+	return nil
+}
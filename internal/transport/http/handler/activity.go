@@ -0,0 +1,61 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"gopherai-resume/internal/app"
+	"gopherai-resume/internal/transport/http/response"
+)
+
+// ActivityHandler serves the user's activity timeline.
+type ActivityHandler struct {
+	activityService *app.ActivityService
+}
+
+func NewActivityHandler(activityService *app.ActivityService) *ActivityHandler {
+	return &ActivityHandler{activityService: activityService}
+}
+
+// List returns a page of the user's recent actions, newest first. ?type takes one or more
+// comma-separated event types, ?before_id continues from a page's next_before_id and ?limit
+// sets the page size (default 50, at most 200).
+func (h *ActivityHandler) List(c *gin.Context) {
+	userID, ok := getUserIDFromContext(c)
+	if !ok {
+		response.Error(c, http.StatusUnauthorized, response.CodeUnauthorized, "invalid token payload")
+		return
+	}
+
+	var query app.ActivityQuery
+	for _, raw := range c.QueryArray("type") {
+		for _, kind := range strings.Split(raw, ",") {
+			if kind = strings.TrimSpace(kind); kind != "" {
+				query.Types = append(query.Types, kind)
+			}
+		}
+	}
+	if raw := c.Query("before_id"); raw != "" {
+		beforeID, err := strconv.ParseUint(raw, 10, 64)
+		if err != nil {
+			response.Error(c, http.StatusBadRequest, response.CodeBadRequest, "invalid before_id")
+			return
+		}
+		query.BeforeID = uint(beforeID)
+	}
+	if raw := c.Query("limit"); raw != "" {
+		if parsed, err := strconv.Atoi(raw); err == nil {
+			query.Limit = parsed
+		}
+	}
+
+	page, err := h.activityService.List(c.Request.Context(), userID, query)
+	if err != nil {
+		writeError(c, err, "list activity failed")
+		return
+	}
+	response.OK(c, page)
+}
@@ -0,0 +1,117 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"gopherai-resume/internal/app"
+	"gopherai-resume/internal/transport/http/response"
+)
+
+// AdminHandler exposes maintenance operations to configured admin users.
+type AdminHandler struct {
+	ragMaintenance   *app.RAGMaintenanceService
+	archiveAfterDays int
+	visionSamples    *app.VisionSampleService
+	rag              *app.RAGService
+}
+
+func NewAdminHandler(
+	ragMaintenance *app.RAGMaintenanceService,
+	archiveAfterDays int,
+	visionSamples *app.VisionSampleService,
+	rag *app.RAGService,
+) *AdminHandler {
+	return &AdminHandler{
+		ragMaintenance:   ragMaintenance,
+		archiveAfterDays: archiveAfterDays,
+		visionSamples:    visionSamples,
+		rag:              rag,
+	}
+}
+
+// ShadowEmbeddingStats reports the shadow embedding model's progress and how its
+// retrieval compared with the primary model's in comparison asks.
+func (h *AdminHandler) ShadowEmbeddingStats(c *gin.Context) {
+	stats, err := h.rag.ShadowStats(c.Request.Context())
+	if err != nil {
+		writeError(c, err, "shadow stats failed")
+		return
+	}
+	response.OK(c, stats)
+}
+
+// VacuumRAG deletes orphaned chunks; pass dry_run=true to only count them.
+func (h *AdminHandler) VacuumRAG(c *gin.Context) {
+	dryRun := c.Query("dry_run") == "true"
+	result, err := h.ragMaintenance.Vacuum(c.Request.Context(), dryRun)
+	if err != nil {
+		writeError(c, err, "vacuum failed")
+		return
+	}
+	response.OK(c, result)
+}
+
+// RecountRAGChunks recomputes per-document chunk counts.
+func (h *AdminHandler) RecountRAGChunks(c *gin.Context) {
+	result, err := h.ragMaintenance.RecountChunks(c.Request.Context())
+	if err != nil {
+		writeError(c, err, "recount failed")
+		return
+	}
+	response.OK(c, result)
+}
+
+// RAGStorage reports RAG storage usage per user.
+func (h *AdminHandler) RAGStorage(c *gin.Context) {
+	report, err := h.ragMaintenance.StorageReport(c.Request.Context())
+	if err != nil {
+		writeError(c, err, "storage report failed")
+		return
+	}
+	response.OK(c, report)
+}
+
+// ArchiveColdRAG archives embeddings of documents not retrieved for ?days= days (default from config).
+func (h *AdminHandler) ArchiveColdRAG(c *gin.Context) {
+	days := h.archiveAfterDays
+	if raw := c.Query("days"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil {
+			response.Error(c, http.StatusBadRequest, response.CodeBadRequest, "invalid days")
+			return
+		}
+		days = parsed
+	}
+	result, err := h.ragMaintenance.ArchiveCold(c.Request.Context(), days)
+	if err != nil {
+		writeError(c, err, "archive failed")
+		return
+	}
+	response.OK(c, result)
+}
+
+// EvaluateVisionRequest selects the candidate model and how many recent samples to re-run.
+type EvaluateVisionRequest struct {
+	Model string `json:"model"`
+	Limit int    `json:"limit"`
+}
+
+// EvaluateVisionModel re-runs recent stored samples against a model and reports agreement
+// with the results users originally received.
+func (h *AdminHandler) EvaluateVisionModel(c *gin.Context) {
+	var req EvaluateVisionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, response.CodeBadRequest, "invalid request payload")
+		return
+	}
+	userID, _ := getUserIDFromContext(c)
+	result, err := h.visionSamples.Evaluate(c.Request.Context(), userID, req.Model, req.Limit)
+	if err != nil {
+		writeError(c, err, "evaluate failed")
+		return
+	}
+	response.OK(c, result)
+}
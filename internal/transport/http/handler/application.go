@@ -0,0 +1,284 @@
+package handler
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"gopherai-resume/internal/app"
+	"gopherai-resume/internal/transport/http/response"
+)
+
+// ApplicationHandler serves the job application tracker.
+type ApplicationHandler struct {
+	applicationService *app.ApplicationService
+}
+
+func NewApplicationHandler(applicationService *app.ApplicationService) *ApplicationHandler {
+	return &ApplicationHandler{applicationService: applicationService}
+}
+
+type CreateApplicationRequest struct {
+	Company          string     `json:"company" binding:"required,max=256"`
+	Role             string     `json:"role" binding:"required,max=256"`
+	Status           string     `json:"status"`
+	JobURL           string     `json:"job_url" binding:"max=1024"`
+	JobDescription   string     `json:"job_description"`
+	ResumeDocumentID *uint      `json:"resume_document_id"`
+	AppliedAt        *time.Time `json:"applied_at"`
+	RemindAt         *time.Time `json:"remind_at"`
+	ReminderNote     string     `json:"reminder_note" binding:"max=512"`
+	Notes            string     `json:"notes"`
+}
+
+// UpdateApplicationRequest is a partial update; omitted fields are unchanged.
+// Set clear_reminder to remove the reminder.
+type UpdateApplicationRequest struct {
+	Company          *string    `json:"company" binding:"omitempty,max=256"`
+	Role             *string    `json:"role" binding:"omitempty,max=256"`
+	Status           *string    `json:"status"`
+	StatusNote       string     `json:"status_note" binding:"max=512"`
+	JobURL           *string    `json:"job_url" binding:"omitempty,max=1024"`
+	JobDescription   *string    `json:"job_description"`
+	ResumeDocumentID *uint      `json:"resume_document_id"`
+	AppliedAt        *time.Time `json:"applied_at"`
+	RemindAt         *time.Time `json:"remind_at"`
+	ClearReminder    bool       `json:"clear_reminder"`
+	ReminderNote     *string    `json:"reminder_note" binding:"omitempty,max=512"`
+	Notes            *string    `json:"notes"`
+}
+
+func (h *ApplicationHandler) Create(c *gin.Context) {
+	userID, ok := getUserIDFromContext(c)
+	if !ok {
+		response.Error(c, http.StatusUnauthorized, response.CodeUnauthorized, "invalid token payload")
+		return
+	}
+	var req CreateApplicationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, response.CodeBadRequest, "invalid request payload")
+		return
+	}
+
+	application, err := h.applicationService.Create(c.Request.Context(), app.CreateApplicationInput{
+		UserID:           userID,
+		Company:          req.Company,
+		Role:             req.Role,
+		Status:           req.Status,
+		JobURL:           req.JobURL,
+		JobDescription:   req.JobDescription,
+		ResumeDocumentID: req.ResumeDocumentID,
+		AppliedAt:        req.AppliedAt,
+		RemindAt:         req.RemindAt,
+		ReminderNote:     req.ReminderNote,
+		Notes:            req.Notes,
+	})
+	if err != nil {
+		writeError(c, err, "create application failed")
+		return
+	}
+	response.OK(c, application)
+}
+
+func (h *ApplicationHandler) List(c *gin.Context) {
+	userID, ok := getUserIDFromContext(c)
+	if !ok {
+		response.Error(c, http.StatusUnauthorized, response.CodeUnauthorized, "invalid token payload")
+		return
+	}
+	list, err := h.applicationService.List(c.Request.Context(), userID, c.Query("status"))
+	if err != nil {
+		writeError(c, err, "list applications failed")
+		return
+	}
+	response.OK(c, list)
+}
+
+func (h *ApplicationHandler) Get(c *gin.Context) {
+	userID, ok := getUserIDFromContext(c)
+	if !ok {
+		response.Error(c, http.StatusUnauthorized, response.CodeUnauthorized, "invalid token payload")
+		return
+	}
+	applicationID, err := parseUintParam(c, "id")
+	if err != nil || applicationID == 0 {
+		response.Error(c, http.StatusBadRequest, response.CodeBadRequest, "invalid application id")
+		return
+	}
+	detail, err := h.applicationService.Get(c.Request.Context(), userID, applicationID)
+	if err != nil {
+		writeError(c, err, "get application failed")
+		return
+	}
+	response.OK(c, detail)
+}
+
+func (h *ApplicationHandler) Update(c *gin.Context) {
+	userID, ok := getUserIDFromContext(c)
+	if !ok {
+		response.Error(c, http.StatusUnauthorized, response.CodeUnauthorized, "invalid token payload")
+		return
+	}
+	applicationID, err := parseUintParam(c, "id")
+	if err != nil || applicationID == 0 {
+		response.Error(c, http.StatusBadRequest, response.CodeBadRequest, "invalid application id")
+		return
+	}
+	var req UpdateApplicationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, response.CodeBadRequest, "invalid request payload")
+		return
+	}
+
+	detail, err := h.applicationService.Update(c.Request.Context(), app.UpdateApplicationInput{
+		UserID:           userID,
+		ApplicationID:    applicationID,
+		Company:          req.Company,
+		Role:             req.Role,
+		Status:           req.Status,
+		StatusNote:       req.StatusNote,
+		JobURL:           req.JobURL,
+		JobDescription:   req.JobDescription,
+		ResumeDocumentID: req.ResumeDocumentID,
+		AppliedAt:        req.AppliedAt,
+		RemindAt:         req.RemindAt,
+		ClearReminder:    req.ClearReminder,
+		ReminderNote:     req.ReminderNote,
+		Notes:            req.Notes,
+	})
+	if err != nil {
+		writeError(c, err, "update application failed")
+		return
+	}
+	response.OK(c, detail)
+}
+
+func (h *ApplicationHandler) Delete(c *gin.Context) {
+	userID, ok := getUserIDFromContext(c)
+	if !ok {
+		response.Error(c, http.StatusUnauthorized, response.CodeUnauthorized, "invalid token payload")
+		return
+	}
+	applicationID, err := parseUintParam(c, "id")
+	if err != nil || applicationID == 0 {
+		response.Error(c, http.StatusBadRequest, response.CodeBadRequest, "invalid application id")
+		return
+	}
+	if err := h.applicationService.Delete(c.Request.Context(), userID, applicationID); err != nil {
+		writeError(c, err, "delete application failed")
+		return
+	}
+	response.OK(c, gin.H{"deleted_application_id": applicationID})
+}
+
+// Reminders lists open applications whose reminder is due. The optional "before" query
+// parameter (RFC 3339) defaults to now.
+func (h *ApplicationHandler) Reminders(c *gin.Context) {
+	userID, ok := getUserIDFromContext(c)
+	if !ok {
+		response.Error(c, http.StatusUnauthorized, response.CodeUnauthorized, "invalid token payload")
+		return
+	}
+	before := time.Now()
+	if raw := c.Query("before"); raw != "" {
+		parsed, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			response.Error(c, http.StatusBadRequest, response.CodeBadRequest, "invalid before (want RFC 3339)")
+			return
+		}
+		before = parsed
+	}
+	list, err := h.applicationService.DueReminders(c.Request.Context(), userID, before)
+	if err != nil {
+		writeError(c, err, "list reminders failed")
+		return
+	}
+	response.OK(c, list)
+}
+
+// ParseEmailRequest is a pasted recruiter email, optionally pinned to an application.
+type ParseEmailRequest struct {
+	Email         string `json:"email" binding:"required"`
+	ApplicationID uint   `json:"application_id"`
+	DryRun        bool   `json:"dry_run"`
+}
+
+// ParseEmail classifies a recruiter email and updates the matching application's status.
+func (h *ApplicationHandler) ParseEmail(c *gin.Context) {
+	userID, ok := getUserIDFromContext(c)
+	if !ok {
+		response.Error(c, http.StatusUnauthorized, response.CodeUnauthorized, "invalid token payload")
+		return
+	}
+	var req ParseEmailRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, response.CodeBadRequest, "invalid request payload")
+		return
+	}
+
+	result, err := h.applicationService.ParseEmail(c.Request.Context(), app.ParseEmailInput{
+		UserID:        userID,
+		Email:         req.Email,
+		ApplicationID: req.ApplicationID,
+		DryRun:        req.DryRun,
+	})
+	if err != nil {
+		writeError(c, err, "parse email failed")
+		return
+	}
+	response.OK(c, result)
+}
+
+func (h *ApplicationHandler) Board(c *gin.Context) {
+	userID, ok := getUserIDFromContext(c)
+	if !ok {
+		response.Error(c, http.StatusUnauthorized, response.CodeUnauthorized, "invalid token payload")
+		return
+	}
+	board, err := h.applicationService.Board(c.Request.Context(), userID)
+	if err != nil {
+		writeError(c, err, "get application board failed")
+		return
+	}
+	response.OK(c, board)
+}
+
+// MoveApplicationRequest drops a card into a column at a 1-based position. status defaults
+// to the current column; position 0 or omitted places it last.
+type MoveApplicationRequest struct {
+	Status   string `json:"status"`
+	Position int    `json:"position" binding:"min=0"`
+	Note     string `json:"note" binding:"max=512"`
+}
+
+func (h *ApplicationHandler) Move(c *gin.Context) {
+	userID, ok := getUserIDFromContext(c)
+	if !ok {
+		response.Error(c, http.StatusUnauthorized, response.CodeUnauthorized, "invalid token payload")
+		return
+	}
+	applicationID, err := parseUintParam(c, "id")
+	if err != nil || applicationID == 0 {
+		response.Error(c, http.StatusBadRequest, response.CodeBadRequest, "invalid application id")
+		return
+	}
+	var req MoveApplicationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, response.CodeBadRequest, "invalid request payload")
+		return
+	}
+
+	board, err := h.applicationService.Move(c.Request.Context(), app.MoveApplicationInput{
+		UserID:        userID,
+		ApplicationID: applicationID,
+		Status:        req.Status,
+		Position:      req.Position,
+		Note:          req.Note,
+	})
+	if err != nil {
+		writeError(c, err, "move application failed")
+		return
+	}
+	response.OK(c, board)
+}
@@ -1,7 +1,6 @@
 package handler
 
 import (
-	"errors"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
@@ -37,22 +36,13 @@ func (h *AuthHandler) Register(c *gin.Context) {
 		return
 	}
 
-	result, err := h.authService.Register(app.RegisterInput{
+	result, err := h.authService.Register(c.Request.Context(), app.RegisterInput{
 		Username: req.Username,
 		Email:    req.Email,
 		Password: req.Password,
 	})
 	if err != nil {
-		switch {
-		case errors.Is(err, app.ErrInvalidInput):
-			response.Error(c, http.StatusBadRequest, response.CodeBadRequest, err.Error())
-		case errors.Is(err, app.ErrUsernameExists):
-			response.Error(c, http.StatusBadRequest, response.CodeUsernameExists, err.Error())
-		case errors.Is(err, app.ErrEmailExists):
-			response.Error(c, http.StatusBadRequest, response.CodeEmailExists, err.Error())
-		default:
-			response.Error(c, http.StatusInternalServerError, response.CodeInternalServer, "register failed")
-		}
+		writeError(c, err, "register failed")
 		return
 	}
 
@@ -73,19 +63,12 @@ func (h *AuthHandler) Login(c *gin.Context) {
 		return
 	}
 
-	result, err := h.authService.Login(app.LoginInput{
+	result, err := h.authService.Login(c.Request.Context(), app.LoginInput{
 		Username: req.Username,
 		Password: req.Password,
 	})
 	if err != nil {
-		switch {
-		case errors.Is(err, app.ErrInvalidInput):
-			response.Error(c, http.StatusBadRequest, response.CodeBadRequest, err.Error())
-		case errors.Is(err, app.ErrInvalidCredential):
-			response.Error(c, http.StatusUnauthorized, response.CodeInvalidCredentials, err.Error())
-		default:
-			response.Error(c, http.StatusInternalServerError, response.CodeInternalServer, "login failed")
-		}
+		writeError(c, err, "login failed")
 		return
 	}
 
@@ -112,9 +95,9 @@ func (h *AuthHandler) Me(c *gin.Context) {
 		return
 	}
 
-	user, err := h.authService.GetUserByID(userID)
+	user, err := h.authService.GetUserByID(c.Request.Context(), userID)
 	if err != nil {
-		response.Error(c, http.StatusInternalServerError, response.CodeInternalServer, "fetch current user failed")
+		writeError(c, err, "fetch current user failed")
 		return
 	}
 	if user == nil {
@@ -123,8 +106,72 @@ func (h *AuthHandler) Me(c *gin.Context) {
 	}
 
 	response.OK(c, gin.H{
-		"id":       user.ID,
-		"username": user.Username,
-		"email":    user.Email,
+		"id":                user.ID,
+		"username":          user.Username,
+		"email":             user.Email,
+		"email_verified_at": user.EmailVerifiedAt,
 	})
 }
+
+type PasswordResetRequest struct {
+	Email string `json:"email" binding:"required,email,max=128"`
+}
+
+// RequestPasswordReset always answers OK so it cannot be used to probe for accounts.
+func (h *AuthHandler) RequestPasswordReset(c *gin.Context) {
+	var req PasswordResetRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, response.CodeBadRequest, "invalid request payload")
+		return
+	}
+	if err := h.authService.RequestPasswordReset(c.Request.Context(), req.Email); err != nil {
+		writeError(c, err, "request password reset failed")
+		return
+	}
+	response.OK(c, gin.H{"message": "if the email is registered, a reset link has been sent"})
+}
+
+type ResetPasswordRequest struct {
+	Token    string `json:"token" binding:"required"`
+	Password string `json:"password" binding:"required,min=8,max=128"`
+}
+
+func (h *AuthHandler) ResetPassword(c *gin.Context) {
+	var req ResetPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, response.CodeBadRequest, "invalid request payload")
+		return
+	}
+	if err := h.authService.ResetPassword(c.Request.Context(), req.Token, req.Password); err != nil {
+		writeError(c, err, "reset password failed")
+		return
+	}
+	response.OK(c, gin.H{"message": "password updated"})
+}
+
+// VerifyEmail is the target of the emailed verification link, so it takes the token as a
+// query parameter.
+func (h *AuthHandler) VerifyEmail(c *gin.Context) {
+	user, err := h.authService.VerifyEmail(c.Request.Context(), c.Query("token"))
+	if err != nil {
+		writeError(c, err, "verify email failed")
+		return
+	}
+	response.OK(c, gin.H{
+		"message":           "email verified",
+		"email_verified_at": user.EmailVerifiedAt,
+	})
+}
+
+func (h *AuthHandler) ResendVerification(c *gin.Context) {
+	userID, ok := getUserIDFromContext(c)
+	if !ok {
+		response.Error(c, http.StatusUnauthorized, response.CodeUnauthorized, "invalid token payload")
+		return
+	}
+	if err := h.authService.ResendVerification(c.Request.Context(), userID); err != nil {
+		writeError(c, err, "resend verification failed")
+		return
+	}
+	response.OK(c, gin.H{"message": "verification email sent"})
+}
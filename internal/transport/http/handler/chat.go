@@ -1,6 +1,8 @@
 package handler
 
 import (
+	"encoding/base64"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"net/http"
@@ -10,32 +12,105 @@ import (
 	"github.com/gin-gonic/gin"
 
 	"gopherai-resume/internal/app"
+	"gopherai-resume/internal/pkg/apperr"
+	"gopherai-resume/internal/pkg/limitwarn"
 	"gopherai-resume/internal/transport/http/middleware"
 	"gopherai-resume/internal/transport/http/response"
 )
 
 type ChatHandler struct {
-	chatService *app.ChatService
+	chatService   *app.ChatService
+	searchService *app.ChatSearchService
 }
 
 type CreateSessionRequest struct {
-	Title string `json:"title" binding:"max=128"`
+	Title        string `json:"title" binding:"max=128"`
+	RAGSessionID uint   `json:"rag_session_id"`
+}
+
+// UpdateSessionRequest changes a session's title and LLM settings; omitted fields are
+// unchanged. reset_llm clears the model and sampling settings first; rag_session_id 0
+// detaches the RAG session.
+type UpdateSessionRequest struct {
+	Title        *string  `json:"title" binding:"omitempty,max=128"`
+	Pinned       *bool    `json:"pinned"`
+	Model        *string  `json:"model" binding:"omitempty,max=128"`
+	Temperature  *float64 `json:"temperature"`
+	TopP         *float64 `json:"top_p"`
+	MaxTokens    *int     `json:"max_tokens"`
+	ResetLLM     bool     `json:"reset_llm"`
+	RAGSessionID *uint    `json:"rag_session_id"`
 }
 
 type SendMessageRequest struct {
-	SessionID uint       `json:"session_id" binding:"required,gt=0"`
-	Content   string     `json:"content" binding:"required"`
-	LLM       LLMRequest `json:"llm"`
+	SessionID uint              `json:"session_id" binding:"required,gt=0"`
+	Content   string            `json:"content" binding:"required"`
+	LLM       LLMRequest        `json:"llm"`
+	Images    []ImageAttachment `json:"images" binding:"max=4,dive"`
+}
+
+// EditMessageRequest replaces a user message. Mode "truncate" (default) deletes the later
+// messages; "fork" continues the edited conversation in a new session.
+type EditMessageRequest struct {
+	Content    string     `json:"content" binding:"required"`
+	Mode       string     `json:"mode" binding:"omitempty,oneof=truncate fork"`
+	Regenerate bool       `json:"regenerate"`
+	LLM        LLMRequest `json:"llm"`
+}
+
+// ImageAttachment is a base64-encoded image attached to a chat message.
+type ImageAttachment struct {
+	Data string `json:"data" binding:"required"` // base64, optionally as a data: URL
+}
+
+// decodeImageAttachments decodes base64 attachments and sniffs their MIME type.
+func decodeImageAttachments(attachments []ImageAttachment) ([]app.ChatImage, error) {
+	images := make([]app.ChatImage, 0, len(attachments))
+	for i, a := range attachments {
+		raw := a.Data
+		if idx := strings.Index(raw, ";base64,"); strings.HasPrefix(raw, "data:") && idx >= 0 {
+			raw = raw[idx+len(";base64,"):]
+		}
+		data, err := base64.StdEncoding.DecodeString(raw)
+		if err != nil {
+			return nil, fmt.Errorf("images[%d]: invalid base64", i)
+		}
+		if len(data) > maxImageSize {
+			return nil, fmt.Errorf("images[%d]: image too large (max 5MB)", i)
+		}
+		mimeType := http.DetectContentType(data)
+		if !strings.HasPrefix(mimeType, "image/") {
+			return nil, fmt.Errorf("images[%d]: not an image", i)
+		}
+		images = append(images, app.ChatImage{Data: data, MIMEType: mimeType})
+	}
+	return images, nil
 }
 
 type LLMRequest struct {
-	BaseURL string `json:"base_url"`
-	APIKey  string `json:"api_key"`
-	Model   string `json:"model"`
+	BaseURL     string   `json:"base_url"`
+	APIKey      string   `json:"api_key"`
+	Model       string   `json:"model"`
+	Temperature *float64 `json:"temperature"`
+	TopP        *float64 `json:"top_p"`
+	MaxTokens   int      `json:"max_tokens"`
+	Stop        []string `json:"stop"`
+}
+
+func (r LLMRequest) override() app.LLMOverride {
+	return app.LLMOverride{
+		BaseURL:     r.BaseURL,
+		APIKey:      r.APIKey,
+		Model:       r.Model,
+		Temperature: r.Temperature,
+		TopP:        r.TopP,
+		MaxTokens:   r.MaxTokens,
+		Stop:        r.Stop,
+	}
 }
 
-func NewChatHandler(chatService *app.ChatService) *ChatHandler {
-	return &ChatHandler{chatService: chatService}
+func NewChatHandler(chatService *app.ChatService, searchService *app.ChatSearchService) *ChatHandler {
+	return &ChatHandler{chatService: chatService, searchService: searchService}
 }
 
 func (h *ChatHandler) CreateSession(c *gin.Context) {
@@ -51,17 +126,13 @@ func (h *ChatHandler) CreateSession(c *gin.Context) {
 		return
 	}
 
-	session, err := h.chatService.CreateSession(app.CreateSessionInput{
-		UserID: userID,
-		Title:  req.Title,
+	session, err := h.chatService.CreateSession(c.Request.Context(), app.CreateSessionInput{
+		UserID:       userID,
+		Title:        req.Title,
+		RAGSessionID: req.RAGSessionID,
 	})
 	if err != nil {
-		switch {
-		case errors.Is(err, app.ErrInvalidInput):
-			response.Error(c, http.StatusBadRequest, response.CodeBadRequest, err.Error())
-		default:
-			response.Error(c, http.StatusInternalServerError, response.CodeInternalServer, "create session failed")
-		}
+		writeError(c, err, "create session failed")
 		return
 	}
 
@@ -75,14 +146,9 @@ func (h *ChatHandler) ListSessions(c *gin.Context) {
 		return
 	}
 
-	sessions, err := h.chatService.ListSessions(userID)
+	sessions, err := h.chatService.ListSessions(c.Request.Context(), userID)
 	if err != nil {
-		switch {
-		case errors.Is(err, app.ErrInvalidInput):
-			response.Error(c, http.StatusBadRequest, response.CodeBadRequest, err.Error())
-		default:
-			response.Error(c, http.StatusInternalServerError, response.CodeInternalServer, "list sessions failed")
-		}
+		writeError(c, err, "list sessions failed")
 		return
 	}
 
@@ -102,21 +168,137 @@ func (h *ChatHandler) DeleteSession(c *gin.Context) {
 		return
 	}
 
-	if err := h.chatService.DeleteSession(userID, uint(sessionID64)); err != nil {
-		switch {
-		case errors.Is(err, app.ErrInvalidInput):
-			response.Error(c, http.StatusBadRequest, response.CodeBadRequest, err.Error())
-		case errors.Is(err, app.ErrSessionNotFound):
-			response.Error(c, http.StatusNotFound, response.CodeSessionNotFound, err.Error())
-		default:
-			response.Error(c, http.StatusInternalServerError, response.CodeInternalServer, "delete session failed")
-		}
+	if err := h.chatService.DeleteSession(c.Request.Context(), userID, uint(sessionID64)); err != nil {
+		writeError(c, err, "delete session failed")
 		return
 	}
 
 	response.OK(c, gin.H{"deleted_session_id": uint(sessionID64)})
 }
 
+func (h *ChatHandler) UpdateSession(c *gin.Context) {
+	userID, ok := getUserIDFromContext(c)
+	if !ok {
+		response.Error(c, http.StatusUnauthorized, response.CodeUnauthorized, "invalid token payload")
+		return
+	}
+
+	sessionID64, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil || sessionID64 == 0 {
+		response.Error(c, http.StatusBadRequest, response.CodeBadRequest, "invalid session id")
+		return
+	}
+	var req UpdateSessionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, response.CodeBadRequest, "invalid request payload")
+		return
+	}
+
+	session, err := h.chatService.UpdateSession(c.Request.Context(), app.UpdateSessionInput{
+		UserID:       userID,
+		SessionID:    uint(sessionID64),
+		Title:        req.Title,
+		Pinned:       req.Pinned,
+		Model:        req.Model,
+		Temperature:  req.Temperature,
+		TopP:         req.TopP,
+		MaxTokens:    req.MaxTokens,
+		ResetLLM:     req.ResetLLM,
+		RAGSessionID: req.RAGSessionID,
+	})
+	if err != nil {
+		writeError(c, err, "update session failed")
+		return
+	}
+
+	response.OK(c, session)
+}
+
+// ForkSessionRequest names the message a new branch continues from; the title defaults
+// to the session's title marked "(branch)".
+type ForkSessionRequest struct {
+	MessageID uint   `json:"message_id" binding:"required,gt=0"`
+	Title     string `json:"title" binding:"max=128"`
+}
+
+func (h *ChatHandler) ForkSession(c *gin.Context) {
+	userID, ok := getUserIDFromContext(c)
+	if !ok {
+		response.Error(c, http.StatusUnauthorized, response.CodeUnauthorized, "invalid token payload")
+		return
+	}
+	sessionID, err := parseUintParam(c, "id")
+	if err != nil || sessionID == 0 {
+		response.Error(c, http.StatusBadRequest, response.CodeBadRequest, "invalid session id")
+		return
+	}
+	var req ForkSessionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, response.CodeBadRequest, "invalid request payload")
+		return
+	}
+
+	session, err := h.chatService.ForkSession(c.Request.Context(), app.ForkSessionInput{
+		UserID:    userID,
+		SessionID: sessionID,
+		MessageID: req.MessageID,
+		Title:     req.Title,
+	})
+	if err != nil {
+		writeError(c, err, "fork session failed")
+		return
+	}
+
+	response.OK(c, session)
+}
+
+func (h *ChatHandler) SessionTree(c *gin.Context) {
+	userID, ok := getUserIDFromContext(c)
+	if !ok {
+		response.Error(c, http.StatusUnauthorized, response.CodeUnauthorized, "invalid token payload")
+		return
+	}
+	sessionID, err := parseUintParam(c, "id")
+	if err != nil || sessionID == 0 {
+		response.Error(c, http.StatusBadRequest, response.CodeBadRequest, "invalid session id")
+		return
+	}
+
+	tree, err := h.chatService.GetSessionTree(c.Request.Context(), userID, sessionID)
+	if err != nil {
+		writeError(c, err, "get session tree failed")
+		return
+	}
+
+	response.OK(c, tree)
+}
+
+// ReorderSessionsRequest lists session IDs in the desired order, top first.
+type ReorderSessionsRequest struct {
+	SessionIDs []uint `json:"session_ids" binding:"required,min=1,max=500"`
+}
+
+func (h *ChatHandler) ReorderSessions(c *gin.Context) {
+	userID, ok := getUserIDFromContext(c)
+	if !ok {
+		response.Error(c, http.StatusUnauthorized, response.CodeUnauthorized, "invalid token payload")
+		return
+	}
+	var req ReorderSessionsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, response.CodeBadRequest, "invalid request payload")
+		return
+	}
+
+	sessions, err := h.chatService.ReorderSessions(c.Request.Context(), userID, req.SessionIDs)
+	if err != nil {
+		writeError(c, err, "reorder sessions failed")
+		return
+	}
+
+	response.OK(c, sessions)
+}
+
 func (h *ChatHandler) SendMessage(c *gin.Context) {
 	userID, ok := getUserIDFromContext(c)
 	if !ok {
@@ -129,36 +311,103 @@ func (h *ChatHandler) SendMessage(c *gin.Context) {
 		response.Error(c, http.StatusBadRequest, response.CodeBadRequest, "invalid request payload")
 		return
 	}
+	images, err := decodeImageAttachments(req.Images)
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, response.CodeBadRequest, err.Error())
+		return
+	}
 
-	result, err := h.chatService.SendMessage(app.SendMessageInput{
+	result, err := h.chatService.SendMessage(c.Request.Context(), app.SendMessageInput{
 		UserID:    userID,
 		SessionID: req.SessionID,
 		Content:   req.Content,
-		LLM: app.LLMOverride{
-			BaseURL: req.LLM.BaseURL,
-			APIKey:  req.LLM.APIKey,
-			Model:   req.LLM.Model,
-		},
+		LLM:       req.LLM.override(),
+		Images:    images,
 	})
 	if err != nil {
-		switch {
-		case errors.Is(err, app.ErrInvalidInput), errors.Is(err, app.ErrMessageEmpty):
-			response.Error(c, http.StatusBadRequest, response.CodeBadRequest, err.Error())
-		case errors.Is(err, app.ErrLLMConfig):
-			response.Error(c, http.StatusBadRequest, response.CodeBadRequest, err.Error())
-		case errors.Is(err, app.ErrMessageEnqueue):
-			response.Error(c, http.StatusServiceUnavailable, response.CodeInternalServer, err.Error())
-		case errors.Is(err, app.ErrSessionNotFound):
-			response.Error(c, http.StatusNotFound, response.CodeSessionNotFound, err.Error())
-		default:
-			response.Error(c, http.StatusInternalServerError, response.CodeInternalServer, "send message failed")
-		}
+		writeError(c, err, "send message failed")
+		return
+	}
+
+	response.OK(c, result)
+}
+
+func (h *ChatHandler) EditMessage(c *gin.Context) {
+	userID, ok := getUserIDFromContext(c)
+	if !ok {
+		response.Error(c, http.StatusUnauthorized, response.CodeUnauthorized, "invalid token payload")
+		return
+	}
+
+	messageID, err := parseUintParam(c, "id")
+	if err != nil || messageID == 0 {
+		response.Error(c, http.StatusBadRequest, response.CodeBadRequest, "invalid message id")
+		return
+	}
+	var req EditMessageRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, response.CodeBadRequest, "invalid request payload")
+		return
+	}
+
+	result, err := h.chatService.EditMessage(c.Request.Context(), app.EditMessageInput{
+		UserID:     userID,
+		MessageID:  messageID,
+		Content:    req.Content,
+		Fork:       req.Mode == "fork",
+		Regenerate: req.Regenerate,
+		LLM:        req.LLM.override(),
+	})
+	if err != nil {
+		writeError(c, err, "edit message failed")
 		return
 	}
 
 	response.OK(c, result)
 }
 
+func (h *ChatHandler) DeleteMessage(c *gin.Context) {
+	userID, ok := getUserIDFromContext(c)
+	if !ok {
+		response.Error(c, http.StatusUnauthorized, response.CodeUnauthorized, "invalid token payload")
+		return
+	}
+	messageID, err := parseUintParam(c, "id")
+	if err != nil || messageID == 0 {
+		response.Error(c, http.StatusBadRequest, response.CodeBadRequest, "invalid message id")
+		return
+	}
+	h.deleteMessages(c, userID, []uint{messageID})
+}
+
+// BulkDeleteMessagesRequest lists the messages to delete.
+type BulkDeleteMessagesRequest struct {
+	MessageIDs []uint `json:"message_ids" binding:"required,min=1,max=100"`
+}
+
+func (h *ChatHandler) BulkDeleteMessages(c *gin.Context) {
+	userID, ok := getUserIDFromContext(c)
+	if !ok {
+		response.Error(c, http.StatusUnauthorized, response.CodeUnauthorized, "invalid token payload")
+		return
+	}
+	var req BulkDeleteMessagesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, response.CodeBadRequest, "invalid request payload")
+		return
+	}
+	h.deleteMessages(c, userID, req.MessageIDs)
+}
+
+func (h *ChatHandler) deleteMessages(c *gin.Context, userID uint, messageIDs []uint) {
+	result, err := h.chatService.DeleteMessages(c.Request.Context(), userID, messageIDs)
+	if err != nil {
+		writeError(c, err, "delete messages failed")
+		return
+	}
+	response.OK(c, result)
+}
+
 func (h *ChatHandler) StreamMessage(c *gin.Context) {
 	userID, ok := getUserIDFromContext(c)
 	if !ok {
@@ -172,10 +421,21 @@ func (h *ChatHandler) StreamMessage(c *gin.Context) {
 		return
 	}
 
-	c.Header("Content-Type", "text/event-stream")
-	c.Header("Cache-Control", "no-cache")
-	c.Header("Connection", "keep-alive")
-	c.Header("X-Accel-Buffering", "no")
+	streamSSE(c, func(onStart, onChunk func(string) error) (string, error) {
+		return h.chatService.StreamMessage(c.Request.Context(), app.SendMessageInput{
+			UserID:    userID,
+			SessionID: req.SessionID,
+			Content:   req.Content,
+			LLM:       req.LLM.override(),
+		}, onStart, onChunk)
+	})
+}
+
+// streamSSE runs a streamed completion and writes it as server-sent events: "start" with the
+// stream ID, unnamed events per chunk, then "done", "cancelled" or "error". "done" follows a
+// "warnings" event when limits are nearly used up.
+func streamSSE(c *gin.Context, run func(onStart, onChunk func(string) error) (string, error)) {
+	setSSEHeaders(c)
 
 	flusher, ok := c.Writer.(http.Flusher)
 	if !ok {
@@ -183,15 +443,12 @@ func (h *ChatHandler) StreamMessage(c *gin.Context) {
 		return
 	}
 
-	full, err := h.chatService.StreamMessage(c.Request.Context(), app.SendMessageInput{
-		UserID:    userID,
-		SessionID: req.SessionID,
-		Content:   req.Content,
-		LLM: app.LLMOverride{
-			BaseURL: req.LLM.BaseURL,
-			APIKey:  req.LLM.APIKey,
-			Model:   req.LLM.Model,
-		},
+	full, err := run(func(streamID string) error {
+		if _, writeErr := c.Writer.Write([]byte("event: start\ndata: " + streamID + "\n\n")); writeErr != nil {
+			return writeErr
+		}
+		flusher.Flush()
+		return nil
 	}, func(chunk string) error {
 		if _, writeErr := c.Writer.Write([]byte("data: " + chunk + "\n\n")); writeErr != nil {
 			return writeErr
@@ -200,23 +457,55 @@ func (h *ChatHandler) StreamMessage(c *gin.Context) {
 		return nil
 	})
 	if err != nil {
+		if _, ok := apperr.As(err); ok && !c.Writer.Written() {
+			// Nothing has been streamed yet (e.g. the session is busy), so the client still
+			// gets a plain JSON error.
+			c.Header("Content-Type", "")
+			writeError(c, err, "stream failed")
+			return
+		}
+		if errors.Is(err, app.ErrStreamCancelled) {
+			if _, writeErr := c.Writer.Write([]byte("event: cancelled\ndata: " + sanitizeSSE(full) + "\n\n")); writeErr == nil {
+				flusher.Flush()
+			}
+			return
+		}
 		if errors.Is(err, app.ErrMessageEnqueue) {
 			if _, writeErr := c.Writer.Write([]byte("event: error\ndata: message enqueue failed\n\n")); writeErr == nil {
 				flusher.Flush()
 			}
 			return
 		}
-		if _, writeErr := c.Writer.Write([]byte(fmt.Sprintf("event: error\ndata: %s\n\n", sanitizeSSE(err.Error())))); writeErr == nil {
+		if _, writeErr := c.Writer.Write([]byte(fmt.Sprintf("event: error\ndata: %s\n\n", sanitizeSSE(apperr.Message(err, "stream failed"))))); writeErr == nil {
 			flusher.Flush()
 		}
 		return
 	}
 
+	writeWarningsSSE(c)
 	if _, writeErr := c.Writer.Write([]byte("event: done\ndata: " + sanitizeSSE(full) + "\n\n")); writeErr == nil {
 		flusher.Flush()
 	}
 }
 
+func (h *ChatHandler) CancelStream(c *gin.Context) {
+	userID, ok := getUserIDFromContext(c)
+	if !ok {
+		response.Error(c, http.StatusUnauthorized, response.CodeUnauthorized, "invalid token payload")
+		return
+	}
+
+	streamID := c.Param("id")
+	if err := h.chatService.CancelStream(userID, streamID); err != nil {
+		writeError(c, err, "cancel stream failed")
+		return
+	}
+
+	response.OK(c, gin.H{"cancelled_stream_id": streamID})
+}
+
+// GetHistory returns a HistoryPage of up to limit of a session's messages older than the
+// before_id query parameter, or the newest ones without it.
 func (h *ChatHandler) GetHistory(c *gin.Context) {
 	userID, ok := getUserIDFromContext(c)
 	if !ok {
@@ -231,29 +520,54 @@ func (h *ChatHandler) GetHistory(c *gin.Context) {
 		return
 	}
 
-	limit := 100
+	limit := 0
 	if raw := c.Query("limit"); raw != "" {
 		if parsed, parseErr := strconv.Atoi(raw); parseErr == nil {
 			limit = parsed
 		}
 	}
+	var beforeID uint64
+	if raw := c.Query("before_id"); raw != "" {
+		beforeID, err = strconv.ParseUint(raw, 10, 64)
+		if err != nil {
+			response.Error(c, http.StatusBadRequest, response.CodeBadRequest, "invalid before_id")
+			return
+		}
+	}
 
-	history, err := h.chatService.GetHistory(userID, uint(sessionID64), limit)
+	history, err := h.chatService.GetHistoryPage(c.Request.Context(), userID, uint(sessionID64), uint(beforeID), limit)
 	if err != nil {
-		switch {
-		case errors.Is(err, app.ErrInvalidInput):
-			response.Error(c, http.StatusBadRequest, response.CodeBadRequest, err.Error())
-		case errors.Is(err, app.ErrSessionNotFound):
-			response.Error(c, http.StatusNotFound, response.CodeSessionNotFound, err.Error())
-		default:
-			response.Error(c, http.StatusInternalServerError, response.CodeInternalServer, "get history failed")
-		}
+		writeError(c, err, "get history failed")
 		return
 	}
 
 	response.OK(c, history)
 }
 
+// Search performs semantic search over the user's past chat messages.
+func (h *ChatHandler) Search(c *gin.Context) {
+	userID, ok := getUserIDFromContext(c)
+	if !ok {
+		response.Error(c, http.StatusUnauthorized, response.CodeUnauthorized, "invalid token payload")
+		return
+	}
+
+	limit := 0
+	if raw := c.Query("limit"); raw != "" {
+		if parsed, parseErr := strconv.Atoi(raw); parseErr == nil {
+			limit = parsed
+		}
+	}
+
+	hits, err := h.searchService.Search(c.Request.Context(), userID, c.Query("q"), limit)
+	if err != nil {
+		writeError(c, err, "search messages failed")
+		return
+	}
+
+	response.OK(c, hits)
+}
+
 func getUserIDFromContext(c *gin.Context) (uint, bool) {
 	userIDAny, exists := c.Get(middleware.ContextUserIDKey)
 	if !exists {
@@ -263,6 +577,28 @@ func getUserIDFromContext(c *gin.Context) (uint, bool) {
 	return userID, ok
 }
 
+func setSSEHeaders(c *gin.Context) {
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+}
+
+// writeWarningsSSE writes the limitwarn warnings raised while serving the request as a
+// "warnings" event with their JSON array, the SSE counterpart of the envelope's warnings.
+// It writes nothing when there are none.
+func writeWarningsSSE(c *gin.Context) {
+	warnings := limitwarn.From(c.Request.Context())
+	if len(warnings) == 0 {
+		return
+	}
+	payload, err := json.Marshal(warnings)
+	if err != nil {
+		return
+	}
+	_, _ = c.Writer.Write([]byte("event: warnings\ndata: " + string(payload) + "\n\n"))
+}
+
 func sanitizeSSE(input string) string {
 	replaced := strings.ReplaceAll(input, "\r\n", "\\n")
 	replaced = strings.ReplaceAll(replaced, "\n", "\\n")
@@ -0,0 +1,81 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"gopherai-resume/internal/app"
+	"gopherai-resume/internal/transport/http/response"
+)
+
+// SaveDraftRequest replaces the session's draft; empty content clears it.
+type SaveDraftRequest struct {
+	Content string `json:"content"`
+}
+
+// ChatDraftHandler saves, fetches and clears unsent chat drafts.
+type ChatDraftHandler struct {
+	draftService *app.ChatDraftService
+}
+
+func NewChatDraftHandler(draftService *app.ChatDraftService) *ChatDraftHandler {
+	return &ChatDraftHandler{draftService: draftService}
+}
+
+func (h *ChatDraftHandler) Get(c *gin.Context) {
+	userID, sessionID, ok := draftParams(c)
+	if !ok {
+		return
+	}
+	draft, err := h.draftService.Get(c.Request.Context(), userID, sessionID)
+	if err != nil {
+		writeError(c, err, "get draft failed")
+		return
+	}
+	response.OK(c, draft)
+}
+
+func (h *ChatDraftHandler) Save(c *gin.Context) {
+	userID, sessionID, ok := draftParams(c)
+	if !ok {
+		return
+	}
+	var req SaveDraftRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, response.CodeBadRequest, "invalid request payload")
+		return
+	}
+	draft, err := h.draftService.Save(c.Request.Context(), userID, sessionID, req.Content)
+	if err != nil {
+		writeError(c, err, "save draft failed")
+		return
+	}
+	response.OK(c, draft)
+}
+
+func (h *ChatDraftHandler) Delete(c *gin.Context) {
+	userID, sessionID, ok := draftParams(c)
+	if !ok {
+		return
+	}
+	if err := h.draftService.Delete(c.Request.Context(), userID, sessionID); err != nil {
+		writeError(c, err, "delete draft failed")
+		return
+	}
+	response.OK(c, gin.H{"cleared_session_id": sessionID})
+}
+
+func draftParams(c *gin.Context) (uint, uint, bool) {
+	userID, ok := getUserIDFromContext(c)
+	if !ok {
+		response.Error(c, http.StatusUnauthorized, response.CodeUnauthorized, "invalid token payload")
+		return 0, 0, false
+	}
+	sessionID, err := parseUintParam(c, "id")
+	if err != nil || sessionID == 0 {
+		response.Error(c, http.StatusBadRequest, response.CodeBadRequest, "invalid session id")
+		return 0, 0, false
+	}
+	return userID, sessionID, true
+}
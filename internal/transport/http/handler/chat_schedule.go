@@ -0,0 +1,87 @@
+package handler
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"gopherai-resume/internal/app"
+	"gopherai-resume/internal/transport/http/response"
+)
+
+// ScheduleMessageRequest schedules content to be sent to a session at run_at (RFC 3339).
+type ScheduleMessageRequest struct {
+	SessionID uint      `json:"session_id" binding:"required,gt=0"`
+	Content   string    `json:"content" binding:"required"`
+	RunAt     time.Time `json:"run_at" binding:"required"`
+	Model     string    `json:"model" binding:"max=64"`
+}
+
+// ChatScheduleHandler schedules chat messages and lists or cancels them.
+type ChatScheduleHandler struct {
+	scheduleService *app.ChatScheduleService
+}
+
+func NewChatScheduleHandler(scheduleService *app.ChatScheduleService) *ChatScheduleHandler {
+	return &ChatScheduleHandler{scheduleService: scheduleService}
+}
+
+func (h *ChatScheduleHandler) Schedule(c *gin.Context) {
+	userID, ok := getUserIDFromContext(c)
+	if !ok {
+		response.Error(c, http.StatusUnauthorized, response.CodeUnauthorized, "invalid token payload")
+		return
+	}
+	var req ScheduleMessageRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, response.CodeBadRequest, "invalid request payload")
+		return
+	}
+	msg, err := h.scheduleService.Schedule(c.Request.Context(), app.ScheduleMessageInput{
+		UserID:    userID,
+		SessionID: req.SessionID,
+		Content:   req.Content,
+		Model:     req.Model,
+		RunAt:     req.RunAt,
+	})
+	if err != nil {
+		writeError(c, err, "schedule message failed")
+		return
+	}
+	response.OK(c, msg)
+}
+
+// List returns the user's scheduled messages; ?status= filters by status.
+func (h *ChatScheduleHandler) List(c *gin.Context) {
+	userID, ok := getUserIDFromContext(c)
+	if !ok {
+		response.Error(c, http.StatusUnauthorized, response.CodeUnauthorized, "invalid token payload")
+		return
+	}
+	list, err := h.scheduleService.List(c.Request.Context(), userID, c.Query("status"))
+	if err != nil {
+		writeError(c, err, "list scheduled messages failed")
+		return
+	}
+	response.OK(c, list)
+}
+
+func (h *ChatScheduleHandler) Cancel(c *gin.Context) {
+	userID, ok := getUserIDFromContext(c)
+	if !ok {
+		response.Error(c, http.StatusUnauthorized, response.CodeUnauthorized, "invalid token payload")
+		return
+	}
+	id, err := parseUintParam(c, "id")
+	if err != nil || id == 0 {
+		response.Error(c, http.StatusBadRequest, response.CodeBadRequest, "invalid scheduled message id")
+		return
+	}
+	msg, err := h.scheduleService.Cancel(c.Request.Context(), userID, id)
+	if err != nil {
+		writeError(c, err, "cancel scheduled message failed")
+		return
+	}
+	response.OK(c, msg)
+}
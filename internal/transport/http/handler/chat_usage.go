@@ -0,0 +1,34 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"gopherai-resume/internal/app"
+	"gopherai-resume/internal/transport/http/response"
+)
+
+// ChatUsageHandler reports the caller's chat token usage and estimated spend.
+type ChatUsageHandler struct {
+	usageService *app.ChatUsageService
+}
+
+func NewChatUsageHandler(usageService *app.ChatUsageService) *ChatUsageHandler {
+	return &ChatUsageHandler{usageService: usageService}
+}
+
+// Get reports usage for ?period=day|week|month|all (default month).
+func (h *ChatUsageHandler) Get(c *gin.Context) {
+	userID, ok := getUserIDFromContext(c)
+	if !ok {
+		response.Error(c, http.StatusUnauthorized, response.CodeUnauthorized, "invalid token payload")
+		return
+	}
+	report, err := h.usageService.Usage(c.Request.Context(), userID, c.Query("period"))
+	if err != nil {
+		writeError(c, err, "get chat usage failed")
+		return
+	}
+	response.OK(c, report)
+}
@@ -0,0 +1,66 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"gopherai-resume/internal/app"
+	"gopherai-resume/internal/model"
+	"gopherai-resume/internal/transport/http/response"
+)
+
+type DataExportHandler struct {
+	exportService *app.DataExportService
+}
+
+func NewDataExportHandler(exportService *app.DataExportService) *DataExportHandler {
+	return &DataExportHandler{exportService: exportService}
+}
+
+// DataExportView is an export with the link to download it once ready.
+type DataExportView struct {
+	model.DataExport
+	DownloadURL string `json:"download_url,omitempty"`
+}
+
+// Export starts an export of the user's data, or returns the one in progress or finished
+// within the last day. Poll it until status is "ready", then fetch download_url.
+func (h *DataExportHandler) Export(c *gin.Context) {
+	userID, ok := getUserIDFromContext(c)
+	if !ok {
+		response.Error(c, http.StatusUnauthorized, response.CodeUnauthorized, "invalid token payload")
+		return
+	}
+	export, err := h.exportService.Request(c.Request.Context(), userID)
+	if err != nil {
+		writeError(c, err, "export data failed")
+		return
+	}
+	view := DataExportView{DataExport: *export}
+	if export.Status == app.ExportReady {
+		view.DownloadURL = fmt.Sprintf("/api/v1/auth/me/export/%d/download", export.ID)
+	}
+	response.OK(c, view)
+}
+
+func (h *DataExportHandler) Download(c *gin.Context) {
+	userID, ok := getUserIDFromContext(c)
+	if !ok {
+		response.Error(c, http.StatusUnauthorized, response.CodeUnauthorized, "invalid token payload")
+		return
+	}
+	exportID, err := parseUintParam(c, "id")
+	if err != nil || exportID == 0 {
+		response.Error(c, http.StatusBadRequest, response.CodeBadRequest, "invalid export id")
+		return
+	}
+	data, fileName, err := h.exportService.Download(c.Request.Context(), userID, exportID)
+	if err != nil {
+		writeError(c, err, "download data export failed")
+		return
+	}
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", fileName))
+	c.Data(http.StatusOK, "application/zip", data)
+}
@@ -0,0 +1,57 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"gopherai-resume/internal/app"
+	"gopherai-resume/internal/transport/http/response"
+)
+
+type EmbeddingHandler struct {
+	embeddingService *app.EmbeddingService
+}
+
+// CreateEmbeddingRequest follows the OpenAI schema: input is a string or an array of strings.
+type CreateEmbeddingRequest struct {
+	Input json.RawMessage `json:"input" binding:"required"`
+}
+
+func NewEmbeddingHandler(embeddingService *app.EmbeddingService) *EmbeddingHandler {
+	return &EmbeddingHandler{embeddingService: embeddingService}
+}
+
+func (h *EmbeddingHandler) Create(c *gin.Context) {
+	userID, ok := getUserIDFromContext(c)
+	if !ok {
+		response.Error(c, http.StatusUnauthorized, response.CodeUnauthorized, "invalid token payload")
+		return
+	}
+
+	var req CreateEmbeddingRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, response.CodeBadRequest, "invalid request payload")
+		return
+	}
+	var inputs []string
+	var single string
+	if err := json.Unmarshal(req.Input, &single); err == nil {
+		inputs = []string{single}
+	} else if err := json.Unmarshal(req.Input, &inputs); err != nil {
+		response.Error(c, http.StatusBadRequest, response.CodeBadRequest, "input must be a string or an array of strings")
+		return
+	}
+
+	result, err := h.embeddingService.Embed(c.Request.Context(), app.EmbedInput{
+		UserID: userID,
+		Inputs: inputs,
+	})
+	if err != nil {
+		writeUpstreamError(c, err, "embedding request failed")
+		return
+	}
+
+	response.OK(c, result)
+}
@@ -0,0 +1,27 @@
+package handler
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"gopherai-resume/internal/pkg/apperr"
+)
+
+// writeError hands err to middleware.Errors, which writes the response. An error that is
+// not an *apperr.Error is answered as an internal error with fallback as its message.
+func writeError(c *gin.Context, err error, fallback string) {
+	if _, ok := apperr.As(err); !ok {
+		err = apperr.Internal(fallback, err)
+	}
+	_ = c.Error(err)
+	c.Abort()
+}
+
+// writeUpstreamError is writeError for calls whose unexpected failures come from a model or
+// another upstream service; those are answered with 502.
+func writeUpstreamError(c *gin.Context, err error, fallback string) {
+	if _, ok := apperr.As(err); !ok {
+		err = apperr.Upstream(fallback, err)
+	}
+	_ = c.Error(err)
+	c.Abort()
+}
@@ -16,6 +16,7 @@ type HealthHandler struct {
 
 type dependencyStatus struct {
 	OK      bool   `json:"ok"`
+	Enabled bool   `json:"enabled"`
 	Message string `json:"message,omitempty"`
 }
 
@@ -31,7 +32,10 @@ func (h *HealthHandler) Check(c *gin.Context) {
 	redisStatus := h.checkRedis(ctx)
 	rmqStatus := h.checkRabbitMQ()
 
-	allOK := mysqlStatus.OK && redisStatus.OK && rmqStatus.OK
+	// Dependencies turned off in config are not failures.
+	allOK := mysqlStatus.OK &&
+		(redisStatus.OK || !redisStatus.Enabled) &&
+		(rmqStatus.OK || !rmqStatus.Enabled)
 	statusCode := http.StatusOK
 	if !allOK {
 		statusCode = http.StatusServiceUnavailable
@@ -52,24 +56,74 @@ func (h *HealthHandler) Check(c *gin.Context) {
 func (h *HealthHandler) checkMySQL(ctx context.Context) dependencyStatus {
 	sqlDB, err := h.app.MySQL.DB()
 	if err != nil {
-		return dependencyStatus{OK: false, Message: err.Error()}
+		return dependencyStatus{OK: false, Enabled: true, Message: err.Error()}
 	}
 	if err := sqlDB.PingContext(ctx); err != nil {
-		return dependencyStatus{OK: false, Message: err.Error()}
+		return dependencyStatus{OK: false, Enabled: true, Message: err.Error()}
 	}
-	return dependencyStatus{OK: true}
+	return dependencyStatus{OK: true, Enabled: true}
 }
 
 func (h *HealthHandler) checkRedis(ctx context.Context) dependencyStatus {
+	if h.app.Redis == nil {
+		return dependencyStatus{Message: "disabled"}
+	}
 	if err := h.app.Redis.Ping(ctx).Err(); err != nil {
-		return dependencyStatus{OK: false, Message: err.Error()}
+		return dependencyStatus{OK: false, Enabled: true, Message: err.Error()}
 	}
-	return dependencyStatus{OK: true}
+	return dependencyStatus{OK: true, Enabled: true}
 }
 
 func (h *HealthHandler) checkRabbitMQ() dependencyStatus {
-	if h.app.MQConn == nil || h.app.MQConn.IsClosed() {
-		return dependencyStatus{OK: false, Message: "connection closed"}
+	if h.app.MQ == nil {
+		return dependencyStatus{Message: "disabled"}
+	}
+	if h.app.MQ.Conn() == nil {
+		return dependencyStatus{OK: false, Enabled: true, Message: "connection closed"}
 	}
-	return dependencyStatus{OK: true}
+	return dependencyStatus{OK: true, Enabled: true}
+}
+
+// readyFeatures reports which optional features currently work, from the dependency states.
+type readyFeatures struct {
+	HistoryCache bool `json:"history_cache"`
+	Quotas       bool `json:"quotas"`
+	Drafts       bool `json:"drafts"`
+	// MessageQueue is false when chat messages are stored directly instead of queued.
+	MessageQueue          bool `json:"message_queue"`
+	MessageEmbeddings     bool `json:"message_embeddings"`
+	AsyncScreeningReports bool `json:"async_screening_reports"`
+	AsyncRAGIngest        bool `json:"async_rag_ingest"`
+	Vision                bool `json:"vision"`
+}
+
+// Ready answers 503 only while a required dependency is down; a server running without
+// optional dependencies is ready but reports degraded and the features it lacks. It reads
+// the states kept by the dependency monitor instead of probing.
+func (h *HealthHandler) Ready(c *gin.Context) {
+	deps := h.app.Dependencies
+	redisUp := deps.Available(bootstrap.DependencyRedis)
+	mqUp := deps.Available(bootstrap.DependencyRabbitMQ)
+	mqCfg := h.app.Config.RabbitMQ
+
+	statusCode := http.StatusOK
+	if !deps.Ready() {
+		statusCode = http.StatusServiceUnavailable
+	}
+	c.JSON(statusCode, gin.H{
+		"ready":        deps.Ready(),
+		"degraded":     deps.Degraded(),
+		"lite_mode":    h.app.Config.App.LiteMode,
+		"dependencies": deps.Statuses(),
+		"features": readyFeatures{
+			HistoryCache:          redisUp || h.app.Config.App.LiteMode,
+			Quotas:                redisUp,
+			Drafts:                redisUp,
+			MessageQueue:          mqUp,
+			MessageEmbeddings:     mqUp && mqCfg.MessageEmbedQueue != "",
+			AsyncScreeningReports: mqUp && mqCfg.ScreeningReportQueue != "",
+			AsyncRAGIngest:        mqUp && mqCfg.RAGIngestQueue != "",
+			Vision:                deps.Available(bootstrap.DependencyVision),
+		},
+	})
 }
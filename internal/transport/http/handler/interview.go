@@ -0,0 +1,56 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"gopherai-resume/internal/app"
+	"gopherai-resume/internal/transport/http/response"
+)
+
+// InterviewHandler serves interview practice feedback.
+type InterviewHandler struct {
+	interviewService *app.InterviewService
+}
+
+func NewInterviewHandler(interviewService *app.InterviewService) *InterviewHandler {
+	return &InterviewHandler{interviewService: interviewService}
+}
+
+// EvaluateAnswerRequest is an answer to score. job_description or application_id supplies
+// the target role.
+type EvaluateAnswerRequest struct {
+	Question       string `json:"question" binding:"required,max=2000"`
+	Answer         string `json:"answer" binding:"required"`
+	QuestionType   string `json:"question_type" binding:"omitempty,oneof=behavioral technical"`
+	JobDescription string `json:"job_description"`
+	ApplicationID  uint   `json:"application_id"`
+}
+
+func (h *InterviewHandler) EvaluateAnswer(c *gin.Context) {
+	userID, ok := getUserIDFromContext(c)
+	if !ok {
+		response.Error(c, http.StatusUnauthorized, response.CodeUnauthorized, "invalid token payload")
+		return
+	}
+	var req EvaluateAnswerRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, response.CodeBadRequest, "invalid request payload")
+		return
+	}
+
+	evaluation, err := h.interviewService.EvaluateAnswer(c.Request.Context(), app.EvaluateAnswerInput{
+		UserID:         userID,
+		Question:       req.Question,
+		Answer:         req.Answer,
+		QuestionType:   req.QuestionType,
+		JobDescription: req.JobDescription,
+		ApplicationID:  req.ApplicationID,
+	})
+	if err != nil {
+		writeError(c, err, "evaluate answer failed")
+		return
+	}
+	response.OK(c, evaluation)
+}
@@ -0,0 +1,298 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"gopherai-resume/internal/app"
+	"gopherai-resume/internal/transport/http/response"
+)
+
+// JobPostingHandler serves a workspace's job posting library and its screening reports.
+type JobPostingHandler struct {
+	jobPostingService *app.JobPostingService
+	reportService     *app.ScreeningReportService
+}
+
+func NewJobPostingHandler(jobPostingService *app.JobPostingService, reportService *app.ScreeningReportService) *JobPostingHandler {
+	return &JobPostingHandler{jobPostingService: jobPostingService, reportService: reportService}
+}
+
+type CreateJobPostingRequest struct {
+	Title       string   `json:"title" binding:"max=256"`
+	Company     string   `json:"company" binding:"max=256"`
+	Location    string   `json:"location" binding:"max=256"`
+	URL         string   `json:"url" binding:"max=1024"`
+	Description string   `json:"description" binding:"required"`
+	Tags        []string `json:"tags"`
+}
+
+// UpdateJobPostingRequest is a partial update; omitted fields are unchanged.
+type UpdateJobPostingRequest struct {
+	Title       *string  `json:"title" binding:"omitempty,max=256"`
+	Company     *string  `json:"company" binding:"omitempty,max=256"`
+	Location    *string  `json:"location" binding:"omitempty,max=256"`
+	URL         *string  `json:"url" binding:"omitempty,max=1024"`
+	Description *string  `json:"description"`
+	Tags        []string `json:"tags"`
+	Archived    *bool    `json:"archived"`
+}
+
+type ScreenRequest struct {
+	ResumeDocumentIDs []uint `json:"resume_document_ids" binding:"required"`
+}
+
+type TrackJobPostingRequest struct {
+	ResumeDocumentID *uint `json:"resume_document_id"`
+}
+
+// jobPostingParams reads the workspace and posting IDs from the path.
+func jobPostingParams(c *gin.Context) (userID, workspaceID, postingID uint, ok bool) {
+	userID, ok = getUserIDFromContext(c)
+	if !ok {
+		response.Error(c, http.StatusUnauthorized, response.CodeUnauthorized, "invalid token payload")
+		return 0, 0, 0, false
+	}
+	workspaceID, err := parseUintParam(c, "id")
+	if err != nil || workspaceID == 0 {
+		response.Error(c, http.StatusBadRequest, response.CodeBadRequest, "invalid workspace id")
+		return 0, 0, 0, false
+	}
+	if c.Param("job_id") == "" {
+		return userID, workspaceID, 0, true
+	}
+	postingID, err = parseUintParam(c, "job_id")
+	if err != nil || postingID == 0 {
+		response.Error(c, http.StatusBadRequest, response.CodeBadRequest, "invalid job posting id")
+		return 0, 0, 0, false
+	}
+	return userID, workspaceID, postingID, true
+}
+
+func (h *JobPostingHandler) Create(c *gin.Context) {
+	userID, workspaceID, _, ok := jobPostingParams(c)
+	if !ok {
+		return
+	}
+	var req CreateJobPostingRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, response.CodeBadRequest, "invalid request payload")
+		return
+	}
+	posting, err := h.jobPostingService.Create(c.Request.Context(), app.CreateJobPostingInput{
+		UserID:      userID,
+		WorkspaceID: workspaceID,
+		Title:       req.Title,
+		Company:     req.Company,
+		Location:    req.Location,
+		URL:         req.URL,
+		Description: req.Description,
+		Tags:        req.Tags,
+	})
+	if err != nil {
+		writeError(c, err, "create job posting failed")
+		return
+	}
+	response.OK(c, posting)
+}
+
+// List supports the query parameters tag, q (title/company substring) and archived ("only" or "all").
+func (h *JobPostingHandler) List(c *gin.Context) {
+	userID, workspaceID, _, ok := jobPostingParams(c)
+	if !ok {
+		return
+	}
+	list, err := h.jobPostingService.List(c.Request.Context(), userID, workspaceID, c.Query("tag"), c.Query("q"), c.Query("archived"))
+	if err != nil {
+		writeError(c, err, "list job postings failed")
+		return
+	}
+	response.OK(c, list)
+}
+
+func (h *JobPostingHandler) Get(c *gin.Context) {
+	userID, workspaceID, postingID, ok := jobPostingParams(c)
+	if !ok {
+		return
+	}
+	posting, err := h.jobPostingService.Get(c.Request.Context(), userID, workspaceID, postingID)
+	if err != nil {
+		writeError(c, err, "get job posting failed")
+		return
+	}
+	response.OK(c, posting)
+}
+
+func (h *JobPostingHandler) Update(c *gin.Context) {
+	userID, workspaceID, postingID, ok := jobPostingParams(c)
+	if !ok {
+		return
+	}
+	var req UpdateJobPostingRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, response.CodeBadRequest, "invalid request payload")
+		return
+	}
+	posting, err := h.jobPostingService.Update(c.Request.Context(), app.UpdateJobPostingInput{
+		UserID:      userID,
+		WorkspaceID: workspaceID,
+		PostingID:   postingID,
+		Title:       req.Title,
+		Company:     req.Company,
+		Location:    req.Location,
+		URL:         req.URL,
+		Description: req.Description,
+		Tags:        req.Tags,
+		Archived:    req.Archived,
+	})
+	if err != nil {
+		writeError(c, err, "update job posting failed")
+		return
+	}
+	response.OK(c, posting)
+}
+
+func (h *JobPostingHandler) Delete(c *gin.Context) {
+	userID, workspaceID, postingID, ok := jobPostingParams(c)
+	if !ok {
+		return
+	}
+	if err := h.jobPostingService.Delete(c.Request.Context(), userID, workspaceID, postingID); err != nil {
+		writeError(c, err, "delete job posting failed")
+		return
+	}
+	response.OK(c, gin.H{"deleted_job_posting_id": postingID})
+}
+
+// Screen scores the caller's resume documents against the posting.
+func (h *JobPostingHandler) Screen(c *gin.Context) {
+	userID, workspaceID, postingID, ok := jobPostingParams(c)
+	if !ok {
+		return
+	}
+	var req ScreenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, response.CodeBadRequest, "invalid request payload")
+		return
+	}
+	results, err := h.jobPostingService.Screen(c.Request.Context(), userID, workspaceID, postingID, req.ResumeDocumentIDs)
+	if err != nil {
+		writeError(c, err, "screen resumes failed")
+		return
+	}
+	response.OK(c, results)
+}
+
+func (h *JobPostingHandler) Screenings(c *gin.Context) {
+	userID, workspaceID, postingID, ok := jobPostingParams(c)
+	if !ok {
+		return
+	}
+	results, err := h.jobPostingService.Screenings(c.Request.Context(), userID, workspaceID, postingID)
+	if err != nil {
+		writeError(c, err, "list screenings failed")
+		return
+	}
+	response.OK(c, results)
+}
+
+// Track adds the posting to the caller's application tracker.
+func (h *JobPostingHandler) Track(c *gin.Context) {
+	userID, workspaceID, postingID, ok := jobPostingParams(c)
+	if !ok {
+		return
+	}
+	var req TrackJobPostingRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			response.Error(c, http.StatusBadRequest, response.CodeBadRequest, "invalid request payload")
+			return
+		}
+	}
+	application, err := h.jobPostingService.Track(c.Request.Context(), userID, workspaceID, postingID, req.ResumeDocumentID)
+	if err != nil {
+		writeError(c, err, "track job posting failed")
+		return
+	}
+	response.OK(c, application)
+}
+
+// RequestReportsRequest selects screening results to explain; empty means all of the posting's results.
+type RequestReportsRequest struct {
+	ScreeningResultIDs []uint `json:"screening_result_ids"`
+	Format             string `json:"format"`
+}
+
+// RequestReports queues explanation reports; poll GetReport until the status is ready.
+func (h *JobPostingHandler) RequestReports(c *gin.Context) {
+	userID, workspaceID, postingID, ok := jobPostingParams(c)
+	if !ok {
+		return
+	}
+	var req RequestReportsRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			response.Error(c, http.StatusBadRequest, response.CodeBadRequest, "invalid request payload")
+			return
+		}
+	}
+	reports, err := h.reportService.Request(c.Request.Context(), userID, workspaceID, postingID, req.ScreeningResultIDs, req.Format)
+	if err != nil {
+		writeError(c, err, "request screening reports failed")
+		return
+	}
+	response.OK(c, reports)
+}
+
+func (h *JobPostingHandler) ListReports(c *gin.Context) {
+	userID, workspaceID, postingID, ok := jobPostingParams(c)
+	if !ok {
+		return
+	}
+	reports, err := h.reportService.List(c.Request.Context(), userID, workspaceID, postingID)
+	if err != nil {
+		writeError(c, err, "list screening reports failed")
+		return
+	}
+	response.OK(c, reports)
+}
+
+func (h *JobPostingHandler) GetReport(c *gin.Context) {
+	userID, workspaceID, postingID, ok := jobPostingParams(c)
+	if !ok {
+		return
+	}
+	reportID, err := parseUintParam(c, "report_id")
+	if err != nil || reportID == 0 {
+		response.Error(c, http.StatusBadRequest, response.CodeBadRequest, "invalid report id")
+		return
+	}
+	report, err := h.reportService.Get(c.Request.Context(), userID, workspaceID, postingID, reportID)
+	if err != nil {
+		writeError(c, err, "get screening report failed")
+		return
+	}
+	response.OK(c, report)
+}
+
+// DownloadReport serves a ready report as Markdown or PDF.
+func (h *JobPostingHandler) DownloadReport(c *gin.Context) {
+	userID, workspaceID, postingID, ok := jobPostingParams(c)
+	if !ok {
+		return
+	}
+	reportID, err := parseUintParam(c, "report_id")
+	if err != nil || reportID == 0 {
+		response.Error(c, http.StatusBadRequest, response.CodeBadRequest, "invalid report id")
+		return
+	}
+	data, contentType, fileName, err := h.reportService.Download(c.Request.Context(), userID, workspaceID, postingID, reportID)
+	if err != nil {
+		writeError(c, err, "download screening report failed")
+		return
+	}
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", fileName))
+	c.Data(http.StatusOK, contentType, data)
+}
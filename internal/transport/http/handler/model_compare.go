@@ -0,0 +1,63 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"gopherai-resume/internal/app"
+	"gopherai-resume/internal/transport/http/response"
+)
+
+// ModelCompareHandler serves side-by-side completions from several models.
+type ModelCompareHandler struct {
+	compareService *app.ModelCompareService
+}
+
+func NewModelCompareHandler(compareService *app.ModelCompareService) *ModelCompareHandler {
+	return &ModelCompareHandler{compareService: compareService}
+}
+
+// CompareModelsRequest is one prompt for 2 to 4 models; omitted models use the configured
+// defaults.
+type CompareModelsRequest struct {
+	Prompt       string   `json:"prompt" binding:"required"`
+	SystemPrompt string   `json:"system_prompt"`
+	Models       []string `json:"models" binding:"omitempty,min=2,max=4"`
+	Temperature  *float64 `json:"temperature"`
+	TopP         *float64 `json:"top_p"`
+	MaxTokens    int      `json:"max_tokens"`
+}
+
+func (h *ModelCompareHandler) Compare(c *gin.Context) {
+	userID, ok := getUserIDFromContext(c)
+	if !ok {
+		response.Error(c, http.StatusUnauthorized, response.CodeUnauthorized, "invalid token payload")
+		return
+	}
+	var req CompareModelsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, response.CodeBadRequest, "invalid request payload")
+		return
+	}
+
+	result, err := h.compareService.Compare(c.Request.Context(), app.CompareModelsInput{
+		UserID:       userID,
+		Prompt:       req.Prompt,
+		SystemPrompt: req.SystemPrompt,
+		Models:       req.Models,
+		Temperature:  req.Temperature,
+		TopP:         req.TopP,
+		MaxTokens:    req.MaxTokens,
+	})
+	if err != nil {
+		writeError(c, err, "compare models failed")
+		return
+	}
+	response.OK(c, result)
+}
+
+// Models lists the models available for comparison.
+func (h *ModelCompareHandler) Models(c *gin.Context) {
+	response.OK(c, gin.H{"models": h.compareService.Models()})
+}
@@ -0,0 +1,57 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"gopherai-resume/internal/app"
+	"gopherai-resume/internal/transport/http/response"
+)
+
+// NegotiationHandler streams salary negotiation briefs into chat sessions.
+type NegotiationHandler struct {
+	negotiationService *app.NegotiationService
+}
+
+func NewNegotiationHandler(negotiationService *app.NegotiationService) *NegotiationHandler {
+	return &NegotiationHandler{negotiationService: negotiationService}
+}
+
+type NegotiationBriefRequest struct {
+	SessionID        uint                  `json:"session_id" binding:"required,gt=0"`
+	Role             string                `json:"role" binding:"required,max=256"`
+	Location         string                `json:"location" binding:"max=256"`
+	ResumeDocumentID uint                  `json:"resume_document_id" binding:"required,gt=0"`
+	CurrentOffer     string                `json:"current_offer" binding:"max=1024"`
+	SalaryData       []app.SalaryDataPoint `json:"salary_data" binding:"max=50"`
+	LLM              LLMRequest            `json:"llm"`
+}
+
+// StreamBrief streams the brief as server-sent events, like /chat/stream.
+func (h *NegotiationHandler) StreamBrief(c *gin.Context) {
+	userID, ok := getUserIDFromContext(c)
+	if !ok {
+		response.Error(c, http.StatusUnauthorized, response.CodeUnauthorized, "invalid token payload")
+		return
+	}
+
+	var req NegotiationBriefRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, response.CodeBadRequest, "invalid request payload")
+		return
+	}
+
+	streamSSE(c, func(onStart, onChunk func(string) error) (string, error) {
+		return h.negotiationService.StreamBrief(c.Request.Context(), app.NegotiationBriefInput{
+			UserID:           userID,
+			SessionID:        req.SessionID,
+			Role:             req.Role,
+			Location:         req.Location,
+			ResumeDocumentID: req.ResumeDocumentID,
+			CurrentOffer:     req.CurrentOffer,
+			SalaryData:       req.SalaryData,
+			LLM:              req.LLM.override(),
+		}, onStart, onChunk)
+	})
+}
@@ -0,0 +1,64 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"gopherai-resume/internal/app"
+	"gopherai-resume/internal/transport/http/response"
+)
+
+// NotificationHandler serves the user's email notification preferences.
+type NotificationHandler struct {
+	notificationService *app.NotificationService
+}
+
+func NewNotificationHandler(notificationService *app.NotificationService) *NotificationHandler {
+	return &NotificationHandler{notificationService: notificationService}
+}
+
+// UpdateNotificationPreferencesRequest is a partial update; omitted fields are unchanged.
+type UpdateNotificationPreferencesRequest struct {
+	IngestionComplete    *bool `json:"ingestion_complete"`
+	ScreeningReports     *bool `json:"screening_reports"`
+	ApplicationReminders *bool `json:"application_reminders"`
+}
+
+func (h *NotificationHandler) Preferences(c *gin.Context) {
+	userID, ok := getUserIDFromContext(c)
+	if !ok {
+		response.Error(c, http.StatusUnauthorized, response.CodeUnauthorized, "invalid token payload")
+		return
+	}
+	pref, err := h.notificationService.Preferences(c.Request.Context(), userID)
+	if err != nil {
+		writeError(c, err, "get notification preferences failed")
+		return
+	}
+	response.OK(c, pref)
+}
+
+func (h *NotificationHandler) UpdatePreferences(c *gin.Context) {
+	userID, ok := getUserIDFromContext(c)
+	if !ok {
+		response.Error(c, http.StatusUnauthorized, response.CodeUnauthorized, "invalid token payload")
+		return
+	}
+	var req UpdateNotificationPreferencesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, response.CodeBadRequest, "invalid request payload")
+		return
+	}
+	pref, err := h.notificationService.UpdatePreferences(c.Request.Context(), app.UpdateNotificationPreferencesInput{
+		UserID:               userID,
+		IngestionComplete:    req.IngestionComplete,
+		ScreeningReports:     req.ScreeningReports,
+		ApplicationReminders: req.ApplicationReminders,
+	})
+	if err != nil {
+		writeError(c, err, "update notification preferences failed")
+		return
+	}
+	response.OK(c, pref)
+}
@@ -0,0 +1,60 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"gopherai-resume/internal/pkg/authz"
+	"gopherai-resume/internal/pkg/opsflags"
+	"gopherai-resume/internal/transport/http/response"
+)
+
+// OpsHandler lets admins change the log level and debug toggles without a restart.
+type OpsHandler struct {
+	store *opsflags.Store
+}
+
+func NewOpsHandler(store *opsflags.Store) *OpsHandler {
+	return &OpsHandler{store: store}
+}
+
+// Get returns the flags in effect and the configured defaults.
+func (h *OpsHandler) Get(c *gin.Context) {
+	response.OK(c, h.store.State())
+}
+
+// Update changes the flags present in the body and leaves the others as they are.
+func (h *OpsHandler) Update(c *gin.Context) {
+	var patch opsflags.Patch
+	if err := c.ShouldBindJSON(&patch); err != nil {
+		response.Error(c, http.StatusBadRequest, response.CodeBadRequest, "invalid request payload")
+		return
+	}
+	state, err := h.store.Update(c.Request.Context(), patch, opsActor(c))
+	if err != nil {
+		writeError(c, err, "update ops flags failed")
+		return
+	}
+	response.OK(c, state)
+}
+
+// Reset goes back to the configured defaults.
+func (h *OpsHandler) Reset(c *gin.Context) {
+	state, err := h.store.Reset(c.Request.Context(), opsActor(c))
+	if err != nil {
+		writeError(c, err, "reset ops flags failed")
+		return
+	}
+	response.OK(c, state)
+}
+
+// opsActor names the admin making a change in the log and the reported state.
+func opsActor(c *gin.Context) string {
+	principal, _ := authz.From(c.Request.Context())
+	if principal.Username != "" {
+		return principal.Username
+	}
+	return fmt.Sprintf("user %d", principal.UserID)
+}
@@ -0,0 +1,104 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"gopherai-resume/internal/app"
+	"gopherai-resume/internal/transport/http/response"
+)
+
+// PortfolioHandler serves GitHub portfolio analyses.
+type PortfolioHandler struct {
+	portfolioService *app.PortfolioService
+}
+
+func NewPortfolioHandler(portfolioService *app.PortfolioService) *PortfolioHandler {
+	return &PortfolioHandler{portfolioService: portfolioService}
+}
+
+// AnalyzePortfolioRequest names either a GitHub username or a repository URL.
+type AnalyzePortfolioRequest struct {
+	Username     string `json:"username" binding:"max=64"`
+	RepoURL      string `json:"repo_url" binding:"max=512"`
+	Ingest       bool   `json:"ingest"`
+	RAGSessionID uint   `json:"rag_session_id"`
+}
+
+func (h *PortfolioHandler) Analyze(c *gin.Context) {
+	userID, ok := getUserIDFromContext(c)
+	if !ok {
+		response.Error(c, http.StatusUnauthorized, response.CodeUnauthorized, "invalid token payload")
+		return
+	}
+	var req AnalyzePortfolioRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, response.CodeBadRequest, "invalid request payload")
+		return
+	}
+
+	result, err := h.portfolioService.Analyze(c.Request.Context(), app.AnalyzePortfolioInput{
+		UserID:       userID,
+		Username:     req.Username,
+		RepoURL:      req.RepoURL,
+		Ingest:       req.Ingest,
+		RAGSessionID: req.RAGSessionID,
+	})
+	if err != nil {
+		writeError(c, err, "analyze portfolio failed")
+		return
+	}
+	response.OK(c, result)
+}
+
+func (h *PortfolioHandler) List(c *gin.Context) {
+	userID, ok := getUserIDFromContext(c)
+	if !ok {
+		response.Error(c, http.StatusUnauthorized, response.CodeUnauthorized, "invalid token payload")
+		return
+	}
+	list, err := h.portfolioService.List(c.Request.Context(), userID)
+	if err != nil {
+		writeError(c, err, "list portfolio analyses failed")
+		return
+	}
+	response.OK(c, list)
+}
+
+func (h *PortfolioHandler) Get(c *gin.Context) {
+	userID, ok := getUserIDFromContext(c)
+	if !ok {
+		response.Error(c, http.StatusUnauthorized, response.CodeUnauthorized, "invalid token payload")
+		return
+	}
+	analysisID, err := parseUintParam(c, "id")
+	if err != nil || analysisID == 0 {
+		response.Error(c, http.StatusBadRequest, response.CodeBadRequest, "invalid analysis id")
+		return
+	}
+	result, err := h.portfolioService.Get(c.Request.Context(), userID, analysisID)
+	if err != nil {
+		writeError(c, err, "get portfolio analysis failed")
+		return
+	}
+	response.OK(c, result)
+}
+
+func (h *PortfolioHandler) Delete(c *gin.Context) {
+	userID, ok := getUserIDFromContext(c)
+	if !ok {
+		response.Error(c, http.StatusUnauthorized, response.CodeUnauthorized, "invalid token payload")
+		return
+	}
+	analysisID, err := parseUintParam(c, "id")
+	if err != nil || analysisID == 0 {
+		response.Error(c, http.StatusBadRequest, response.CodeBadRequest, "invalid analysis id")
+		return
+	}
+	if err := h.portfolioService.Delete(c.Request.Context(), userID, analysisID); err != nil {
+		writeError(c, err, "delete portfolio analysis failed")
+		return
+	}
+	response.OK(c, gin.H{"deleted_analysis_id": analysisID})
+}
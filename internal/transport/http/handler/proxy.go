@@ -0,0 +1,287 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"gopherai-resume/internal/ai"
+	"gopherai-resume/internal/app"
+	"gopherai-resume/internal/pkg/apperr"
+	"gopherai-resume/internal/pkg/requestid"
+)
+
+// ProxyHandler serves an OpenAI-compatible chat completions endpoint. Its responses and
+// errors use the OpenAI shapes rather than this API's envelope, so existing clients work
+// unchanged with the base URL set to /api/v1/proxy and a login token as the API key.
+type ProxyHandler struct {
+	proxy *app.ChatProxyService
+}
+
+func NewProxyHandler(proxy *app.ChatProxyService) *ProxyHandler {
+	return &ProxyHandler{proxy: proxy}
+}
+
+// ProxyChatRequest is the part of the OpenAI chat completions request the proxy supports.
+// Stop is a string or an array of strings; max_completion_tokens is the newer name of
+// max_tokens.
+type ProxyChatRequest struct {
+	Model               string              `json:"model"`
+	Messages            []ProxyChatMessage  `json:"messages"`
+	Stream              bool                `json:"stream"`
+	StreamOptions       *ProxyStreamOptions `json:"stream_options"`
+	Temperature         *float64            `json:"temperature"`
+	TopP                *float64            `json:"top_p"`
+	MaxTokens           int                 `json:"max_tokens"`
+	MaxCompletionTokens int                 `json:"max_completion_tokens"`
+	Stop                json.RawMessage     `json:"stop"`
+}
+
+type ProxyStreamOptions struct {
+	IncludeUsage bool `json:"include_usage"`
+}
+
+// ProxyChatMessage is an OpenAI chat message. Content is a string or an array of parts, of
+// which only text parts are supported.
+type ProxyChatMessage struct {
+	Role       string          `json:"role"`
+	Content    json.RawMessage `json:"content"`
+	ToolCallID string          `json:"tool_call_id,omitempty"`
+}
+
+type proxyChoice struct {
+	Index        int             `json:"index"`
+	Message      *ai.ChatMessage `json:"message,omitempty"`
+	Delta        *proxyDelta     `json:"delta,omitempty"`
+	FinishReason *string         `json:"finish_reason"`
+}
+
+type proxyDelta struct {
+	Role    string `json:"role,omitempty"`
+	Content string `json:"content,omitempty"`
+}
+
+type proxyUsage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+	TotalTokens      int `json:"total_tokens"`
+}
+
+type proxyCompletionBody struct {
+	ID      string        `json:"id"`
+	Object  string        `json:"object"`
+	Created int64         `json:"created"`
+	Model   string        `json:"model"`
+	Choices []proxyChoice `json:"choices"`
+	Usage   *proxyUsage   `json:"usage,omitempty"`
+}
+
+var errProxyContent = apperr.BadRequest("message content must be a string or an array of text parts")
+
+// ChatCompletions answers POST /proxy/chat/completions, streamed as OpenAI-style
+// chat.completion.chunk events when "stream" is true.
+func (h *ProxyHandler) ChatCompletions(c *gin.Context) {
+	userID, ok := getUserIDFromContext(c)
+	if !ok {
+		writeProxyError(c, apperr.New(http.StatusUnauthorized, apperr.CodeUnauthorized, "invalid token payload"))
+		return
+	}
+	var req ProxyChatRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		writeProxyError(c, apperr.BadRequest("invalid request payload"))
+		return
+	}
+	input, err := req.input(userID)
+	if err != nil {
+		writeProxyError(c, err)
+		return
+	}
+
+	if !req.Stream {
+		completion, err := h.proxy.Complete(c.Request.Context(), input)
+		if err != nil {
+			writeProxyError(c, err)
+			return
+		}
+		stop := "stop"
+		c.JSON(http.StatusOK, proxyCompletionBody{
+			ID:      completion.ID,
+			Object:  "chat.completion",
+			Created: completion.Created,
+			Model:   completion.Model,
+			Choices: []proxyChoice{{
+				Message:      &ai.ChatMessage{Role: "assistant", Content: completion.Content},
+				FinishReason: &stop,
+			}},
+			Usage: usageBody(completion),
+		})
+		return
+	}
+
+	flusher, ok := c.Writer.(http.Flusher)
+	if !ok {
+		writeProxyError(c, apperr.Internal("stream not supported", nil))
+		return
+	}
+	writeEvent := func(body interface{}) error {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		if _, err := c.Writer.Write([]byte("data: " + string(data) + "\n\n")); err != nil {
+			return err
+		}
+		flusher.Flush()
+		return nil
+	}
+	chunk := func(completion *app.ProxyCompletion, delta *proxyDelta, finish *string) proxyCompletionBody {
+		return proxyCompletionBody{
+			ID:      completion.ID,
+			Object:  "chat.completion.chunk",
+			Created: completion.Created,
+			Model:   completion.Model,
+			Choices: []proxyChoice{{Delta: delta, FinishReason: finish}},
+		}
+	}
+	started := false
+	completion, err := h.proxy.Stream(c.Request.Context(), input, func(completion *app.ProxyCompletion, text string) error {
+		if !started {
+			setSSEHeaders(c)
+			started = true
+			if err := writeEvent(chunk(completion, &proxyDelta{Role: "assistant"}, nil)); err != nil {
+				return err
+			}
+		}
+		return writeEvent(chunk(completion, &proxyDelta{Content: text}, nil))
+	})
+	if err != nil {
+		if !started {
+			// Nothing has been streamed yet, so the client still gets a plain JSON error.
+			writeProxyError(c, err)
+			return
+		}
+		logProxyError(c, err)
+		_ = writeEvent(gin.H{"error": proxyErrorBody(err)})
+		return
+	}
+	if !started {
+		setSSEHeaders(c)
+		_ = writeEvent(chunk(completion, &proxyDelta{Role: "assistant"}, nil))
+	}
+	stop := "stop"
+	_ = writeEvent(chunk(completion, &proxyDelta{}, &stop))
+	if req.StreamOptions != nil && req.StreamOptions.IncludeUsage {
+		final := chunk(completion, nil, nil)
+		final.Choices = []proxyChoice{}
+		final.Usage = usageBody(completion)
+		_ = writeEvent(final)
+	}
+	if _, err := c.Writer.Write([]byte("data: [DONE]\n\n")); err == nil {
+		flusher.Flush()
+	}
+}
+
+// input converts the request into the service input, flattening text parts.
+func (r ProxyChatRequest) input(userID uint) (app.ProxyChatInput, error) {
+	input := app.ProxyChatInput{
+		UserID:      userID,
+		Model:       r.Model,
+		Temperature: r.Temperature,
+		TopP:        r.TopP,
+		MaxTokens:   r.MaxTokens,
+	}
+	if r.MaxCompletionTokens != 0 {
+		input.MaxTokens = r.MaxCompletionTokens
+	}
+	if len(r.Stop) > 0 && string(r.Stop) != "null" {
+		var single string
+		if err := json.Unmarshal(r.Stop, &single); err == nil {
+			input.Stop = []string{single}
+		} else if err := json.Unmarshal(r.Stop, &input.Stop); err != nil {
+			return app.ProxyChatInput{}, apperr.BadRequest("stop must be a string or an array of strings")
+		}
+	}
+	for _, m := range r.Messages {
+		content, err := messageText(m.Content)
+		if err != nil {
+			return app.ProxyChatInput{}, err
+		}
+		input.Messages = append(input.Messages, ai.ChatMessage{Role: m.Role, Content: content, ToolCallID: m.ToolCallID})
+	}
+	return input, nil
+}
+
+func messageText(raw json.RawMessage) (string, error) {
+	if len(raw) == 0 || string(raw) == "null" {
+		return "", nil
+	}
+	var text string
+	if err := json.Unmarshal(raw, &text); err == nil {
+		return text, nil
+	}
+	var parts []struct {
+		Type string `json:"type"`
+		Text string `json:"text"`
+	}
+	if err := json.Unmarshal(raw, &parts); err != nil {
+		return "", errProxyContent
+	}
+	texts := make([]string, 0, len(parts))
+	for _, part := range parts {
+		if part.Type != "text" {
+			return "", errProxyContent
+		}
+		texts = append(texts, part.Text)
+	}
+	return strings.Join(texts, "\n"), nil
+}
+
+func usageBody(completion *app.ProxyCompletion) *proxyUsage {
+	return &proxyUsage{
+		PromptTokens:     completion.Usage.PromptTokens,
+		CompletionTokens: completion.Usage.CompletionTokens,
+		TotalTokens:      completion.Usage.PromptTokens + completion.Usage.CompletionTokens,
+	}
+}
+
+// writeProxyError answers with an OpenAI-style error. Like middleware.Errors it logs causes
+// and server errors, and unexpected failures, which come from the provider, are answered
+// with 502 without their details.
+func writeProxyError(c *gin.Context, err error) {
+	logProxyError(c, err)
+	appErr, ok := apperr.As(err)
+	if !ok {
+		appErr = apperr.Upstream("chat completion failed", err)
+	}
+	c.AbortWithStatusJSON(appErr.Status, gin.H{"error": proxyErrorBody(err)})
+}
+
+func logProxyError(c *gin.Context, err error) {
+	if appErr, ok := apperr.As(err); ok && appErr.Cause == nil && appErr.Status < 500 {
+		return
+	}
+	log.Printf("%s %s: err=%v request_id=%s", c.Request.Method, c.FullPath(), err, requestid.From(c.Request.Context()))
+}
+
+func proxyErrorBody(err error) gin.H {
+	appErr, ok := apperr.As(err)
+	if !ok {
+		appErr = apperr.Upstream("chat completion failed", err)
+	}
+	errType := "invalid_request_error"
+	switch {
+	case errors.Is(err, app.ErrQuotaExceeded):
+		errType = "insufficient_quota"
+	case appErr.Status == http.StatusUnauthorized:
+		errType = "authentication_error"
+	case appErr.Status == http.StatusTooManyRequests || errors.Is(err, ai.ErrProviderBusy):
+		errType = "rate_limit_error"
+	case appErr.Status >= 500:
+		errType = "api_error"
+	}
+	return gin.H{"message": appErr.Message, "type": errType, "code": appErr.Code}
+}
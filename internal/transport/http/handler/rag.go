@@ -1,23 +1,34 @@
 package handler
 
 import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"errors"
+	"io"
 	"net/http"
 	"path/filepath"
 	"strconv"
 	"strings"
+	"unicode/utf8"
 
 	"github.com/gin-gonic/gin"
 
 	"gopherai-resume/internal/app"
-	"gopherai-resume/internal/pkg/pdfextract"
+	"gopherai-resume/internal/pkg/apperr"
+	"gopherai-resume/internal/pkg/docextract"
 	"gopherai-resume/internal/transport/http/response"
 )
 
-const maxPDFSize = 10 << 20 // 10 MB
+const maxUploadSize = 10 << 20 // 10 MB
 
 type RAGHandler struct {
 	ragService *app.RAGService
+	// admitAsk takes a user's rate limit and an in-flight slot for one question of a batch
+	// ask, and hold only the slot; nil admits all. The funcs they return end the work.
+	admitAsk func(ctx context.Context, userID uint) (func(), error)
+	hold     func(ctx context.Context, userID uint) (func(), error)
 }
 
 type CreateRAGSessionRequest struct {
@@ -28,17 +39,69 @@ type CreateRAGDocumentRequest struct {
 	Name      string `json:"name"`
 	Content   string `json:"content" binding:"required"`
 	SessionID uint   `json:"session_id"`
+	// ChunkOverlap is a pointer so an explicit 0 can be told from an omitted value.
+	ChunkSize     int    `json:"chunk_size"`
+	ChunkOverlap  *int   `json:"chunk_overlap"`
+	ChunkStrategy string `json:"chunk_strategy"`
+	// Async returns the pending document at once and ingests it in the background.
+	Async bool `json:"async"`
+}
+
+type AppendRAGDocumentRequest struct {
+	Content string `json:"content" binding:"required"`
+}
+
+// ReplaceRAGDocumentRequest replaces a document's content; the name and chunking fields
+// default to the document's current ones.
+type ReplaceRAGDocumentRequest struct {
+	Name          string `json:"name"`
+	Content       string `json:"content" binding:"required"`
+	ChunkSize     int    `json:"chunk_size"`
+	ChunkOverlap  *int   `json:"chunk_overlap"`
+	ChunkStrategy string `json:"chunk_strategy"`
+}
+
+type CompareRAGRequest struct {
+	Question    string `json:"question" binding:"required"`
+	DocumentIDs []uint `json:"document_ids" binding:"required"`
+	TopK        int    `json:"top_k"`
 }
 
 type AskRAGRequest struct {
-	Question    string  `json:"question" binding:"required"`
-	SessionID   uint    `json:"session_id"`
-	DocumentIDs []uint  `json:"document_ids"`
-	TopK        int     `json:"top_k"`
+	Question           string  `json:"question" binding:"required"`
+	SessionID          uint    `json:"session_id"`
+	DocumentIDs        []uint  `json:"document_ids"`
+	TopK               int     `json:"top_k"`
+	IncludeChatHistory bool    `json:"include_chat_history"`
+	Rerank             bool    `json:"rerank"`
+	Diversify          bool    `json:"diversify"`
+	MinScore           float32 `json:"min_score"`
+	CompareShadow      bool    `json:"compare_shadow"`
+	// CitationMarkers and MultiQuery override the configured defaults when set.
+	CitationMarkers *bool `json:"citation_markers"`
+	MultiQuery      *bool `json:"multi_query"`
 }
 
-func NewRAGHandler(ragService *app.RAGService) *RAGHandler {
-	return &RAGHandler{ragService: ragService}
+// AskBatchRAGRequest asks every question with the same options; see AskRAGRequest.
+type AskBatchRAGRequest struct {
+	Questions          []string `json:"questions" binding:"required,min=1"`
+	SessionID          uint     `json:"session_id"`
+	DocumentIDs        []uint   `json:"document_ids"`
+	TopK               int      `json:"top_k"`
+	IncludeChatHistory bool     `json:"include_chat_history"`
+	Rerank             bool     `json:"rerank"`
+	Diversify          bool     `json:"diversify"`
+	MinScore           float32  `json:"min_score"`
+	CitationMarkers    *bool    `json:"citation_markers"`
+	MultiQuery         *bool    `json:"multi_query"`
+}
+
+func NewRAGHandler(
+	ragService *app.RAGService,
+	admitAsk func(ctx context.Context, userID uint) (func(), error),
+	hold func(ctx context.Context, userID uint) (func(), error),
+) *RAGHandler {
+	return &RAGHandler{ragService: ragService, admitAsk: admitAsk, hold: hold}
 }
 
 func (h *RAGHandler) CreateSession(c *gin.Context) {
@@ -52,16 +115,12 @@ func (h *RAGHandler) CreateSession(c *gin.Context) {
 		response.Error(c, http.StatusBadRequest, response.CodeBadRequest, "invalid request payload")
 		return
 	}
-	session, err := h.ragService.CreateSession(app.RAGCreateSessionInput{
+	session, err := h.ragService.CreateSession(c.Request.Context(), app.RAGCreateSessionInput{
 		UserID: userID,
 		Title:  req.Title,
 	})
 	if err != nil {
-		if errors.Is(err, app.ErrInvalidInput) {
-			response.Error(c, http.StatusBadRequest, response.CodeBadRequest, err.Error())
-		} else {
-			response.Error(c, http.StatusInternalServerError, response.CodeInternalServer, "create session failed")
-		}
+		writeError(c, err, "create session failed")
 		return
 	}
 	response.OK(c, session)
@@ -73,9 +132,9 @@ func (h *RAGHandler) ListSessions(c *gin.Context) {
 		response.Error(c, http.StatusUnauthorized, response.CodeUnauthorized, "invalid token payload")
 		return
 	}
-	sessions, err := h.ragService.ListSessions(userID)
+	sessions, err := h.ragService.ListSessions(c.Request.Context(), userID)
 	if err != nil {
-		response.Error(c, http.StatusInternalServerError, response.CodeInternalServer, "list sessions failed")
+		writeError(c, err, "list sessions failed")
 		return
 	}
 	response.OK(c, sessions)
@@ -92,18 +151,68 @@ func (h *RAGHandler) DeleteSession(c *gin.Context) {
 		response.Error(c, http.StatusBadRequest, response.CodeBadRequest, "invalid session id")
 		return
 	}
-	if err := h.ragService.DeleteSession(userID, sessionID); err != nil {
-		switch {
-		case errors.Is(err, app.ErrRAGSessionNotFound):
-			response.Error(c, http.StatusNotFound, response.CodeSessionNotFound, err.Error())
-		default:
-			response.Error(c, http.StatusInternalServerError, response.CodeInternalServer, "delete session failed")
-		}
+	if err := h.ragService.DeleteSession(c.Request.Context(), userID, sessionID); err != nil {
+		writeError(c, err, "delete session failed")
 		return
 	}
 	response.OK(c, gin.H{"deleted_session_id": sessionID})
 }
 
+// SuggestedQuestions returns LLM-proposed questions answerable from the session's documents.
+func (h *RAGHandler) SuggestedQuestions(c *gin.Context) {
+	userID, ok := getUserIDFromContext(c)
+	if !ok {
+		response.Error(c, http.StatusUnauthorized, response.CodeUnauthorized, "invalid token payload")
+		return
+	}
+	sessionID, err := parseUintParam(c, "id")
+	if err != nil || sessionID == 0 {
+		response.Error(c, http.StatusBadRequest, response.CodeBadRequest, "invalid session id")
+		return
+	}
+	result, err := h.ragService.SuggestQuestions(c.Request.Context(), userID, sessionID)
+	if err != nil {
+		writeError(c, err, "suggest questions failed")
+		return
+	}
+	response.OK(c, result)
+}
+
+// History lists a RAG session's questions and answers, oldest first. ?limit= (default 50,
+// max 200) and ?before_id= page back through older ones.
+func (h *RAGHandler) History(c *gin.Context) {
+	userID, ok := getUserIDFromContext(c)
+	if !ok {
+		response.Error(c, http.StatusUnauthorized, response.CodeUnauthorized, "invalid token payload")
+		return
+	}
+	sessionID, err := parseUintParam(c, "id")
+	if err != nil || sessionID == 0 {
+		response.Error(c, http.StatusBadRequest, response.CodeBadRequest, "invalid session id")
+		return
+	}
+	limit := 0
+	if raw := c.Query("limit"); raw != "" {
+		if parsed, parseErr := strconv.Atoi(raw); parseErr == nil {
+			limit = parsed
+		}
+	}
+	var beforeID uint64
+	if raw := c.Query("before_id"); raw != "" {
+		beforeID, err = strconv.ParseUint(raw, 10, 64)
+		if err != nil {
+			response.Error(c, http.StatusBadRequest, response.CodeBadRequest, "invalid before_id")
+			return
+		}
+	}
+	history, err := h.ragService.History(c.Request.Context(), userID, sessionID, uint(beforeID), limit)
+	if err != nil {
+		writeError(c, err, "list rag history failed")
+		return
+	}
+	response.OK(c, history)
+}
+
 func parseUintParam(c *gin.Context, key string) (uint, error) {
 	s := c.Param(key)
 	u, err := strconv.ParseUint(s, 10, 64)
@@ -123,65 +232,189 @@ func (h *RAGHandler) CreateDocument(c *gin.Context) {
 		return
 	}
 
-	result, err := h.ragService.Ingest(c.Request.Context(), app.IngestInput{
+	input := app.IngestInput{
 		UserID:    userID,
 		SessionID: req.SessionID,
 		Name:      req.Name,
 		Content:   req.Content,
-	})
+
+		ChunkSize:     req.ChunkSize,
+		ChunkOverlap:  req.ChunkOverlap,
+		ChunkStrategy: req.ChunkStrategy,
+	}
+	if req.Async {
+		h.ingestAsync(c, input)
+		return
+	}
+	result, err := h.ragService.Ingest(c.Request.Context(), input)
 	if err != nil {
-		switch {
-		case errors.Is(err, app.ErrInvalidInput):
-			response.Error(c, http.StatusBadRequest, response.CodeBadRequest, err.Error())
-		default:
-			response.Error(c, http.StatusInternalServerError, response.CodeInternalServer, "ingest failed: "+err.Error())
-		}
+		writeError(c, err, "ingest failed")
 		return
 	}
 
 	response.OK(c, result)
 }
 
-// UploadPDF accepts a multipart form with "file" (PDF) and optional "name", extracts text and ingests.
-func (h *RAGHandler) UploadPDF(c *gin.Context) {
+// UploadDocument accepts a multipart form with "file" (PDF, DOCX, HTML, Markdown or plain
+// text) and optional "name" and chunking fields (see chunkingForm), extracts text and
+// ingests. With async=true it answers with
+// the pending document and ingests in the background. A file already uploaded into the
+// same session is not ingested again unless allow_duplicate=true.
+func (h *RAGHandler) UploadDocument(c *gin.Context) {
 	userID, ok := getUserIDFromContext(c)
 	if !ok {
 		response.Error(c, http.StatusUnauthorized, response.CodeUnauthorized, "invalid token payload")
 		return
 	}
 
-	file, err := c.FormFile("file")
-	if err != nil {
-		response.Error(c, http.StatusBadRequest, response.CodeBadRequest, "missing file")
+	upload, ok := readDocumentUpload(c)
+	if !ok {
 		return
 	}
-	if file.Size > maxPDFSize {
-		response.Error(c, http.StatusBadRequest, response.CodeBadRequest, "file too large (max 10MB)")
+
+	size, overlap, strategy := chunkingForm(c)
+	input := app.IngestInput{
+		UserID:    userID,
+		SessionID: parseUintForm(c, "session_id"),
+		Name:      upload.name,
+		Content:   upload.text,
+		Paged:     upload.paged,
+
+		ChunkSize:      size,
+		ChunkOverlap:   overlap,
+		ChunkStrategy:  strategy,
+		FileHash:       upload.hash,
+		AllowDuplicate: c.PostForm("allow_duplicate") == "true",
+	}
+	if c.PostForm("async") == "true" {
+		h.ingestAsync(c, input)
 		return
 	}
-	ext := strings.ToLower(filepath.Ext(file.Filename))
-	if ext != ".pdf" {
-		response.Error(c, http.StatusBadRequest, response.CodeBadRequest, "only PDF files are allowed")
+	result, err := h.ragService.Ingest(c.Request.Context(), input)
+	if err != nil {
+		writeError(c, err, "ingest failed")
 		return
 	}
 
-	f, err := file.Open()
+	response.OK(c, result)
+}
+
+// ingestAsync queues the ingest and answers with the pending document.
+func (h *RAGHandler) ingestAsync(c *gin.Context, input app.IngestInput) {
+	doc, err := h.ragService.IngestAsync(c.Request.Context(), input)
 	if err != nil {
-		response.Error(c, http.StatusInternalServerError, response.CodeInternalServer, "failed to read file")
+		writeError(c, err, "ingest failed")
 		return
 	}
-	defer f.Close()
+	response.OK(c, doc)
+}
+
+// UploadDocumentStream takes the same form as UploadDocument but answers with SSE stage events so the
+// client can show progress: "extracted" (characters), "chunked" and "embedded" (an
+// IngestProgress JSON), then "done" with the IngestResult JSON or "error". Validation and
+// extraction failures are plain JSON errors, sent before the stream starts.
+func (h *RAGHandler) UploadDocumentStream(c *gin.Context) {
+	userID, ok := getUserIDFromContext(c)
+	if !ok {
+		response.Error(c, http.StatusUnauthorized, response.CodeUnauthorized, "invalid token payload")
+		return
+	}
+
+	flusher, ok := c.Writer.(http.Flusher)
+	if !ok {
+		response.Error(c, http.StatusInternalServerError, response.CodeInternalServer, "stream not supported")
+		return
+	}
+
+	upload, ok := readDocumentUpload(c)
+	if !ok {
+		return
+	}
+
+	setSSEHeaders(c)
+	writeEvent := func(event, data string) {
+		if _, err := c.Writer.Write([]byte("event: " + event + "\ndata: " + data + "\n\n")); err == nil {
+			flusher.Flush()
+		}
+	}
+	writeEvent("extracted", strconv.Itoa(utf8.RuneCountInString(upload.text)))
 
-	text, err := pdfextract.ExtractText(f)
+	size, overlap, strategy := chunkingForm(c)
+	result, err := h.ragService.Ingest(c.Request.Context(), app.IngestInput{
+		UserID:    userID,
+		SessionID: parseUintForm(c, "session_id"),
+		Name:      upload.name,
+		Content:   upload.text,
+		Paged:     upload.paged,
+
+		ChunkSize:      size,
+		ChunkOverlap:   overlap,
+		ChunkStrategy:  strategy,
+		FileHash:       upload.hash,
+		AllowDuplicate: c.PostForm("allow_duplicate") == "true",
+		Progress: func(p app.IngestProgress) {
+			payload, _ := json.Marshal(p)
+			writeEvent(p.Stage, string(payload))
+		},
+	})
 	if err != nil {
-		response.Error(c, http.StatusBadRequest, response.CodeBadRequest, "failed to extract text from PDF: "+err.Error())
+		writeEvent("error", sanitizeSSE(apperr.Message(err, "ingest failed")))
 		return
 	}
-	text = strings.TrimSpace(text)
-	if text == "" {
-		response.Error(c, http.StatusBadRequest, response.CodeBadRequest, "PDF contains no extractable text")
+	payload, err := json.Marshal(result)
+	if err != nil {
+		writeEvent("error", "ingest failed")
 		return
 	}
+	writeEvent("done", string(payload))
+}
+
+// documentUpload is the text extracted from an uploaded file. paged is set for PDFs and
+// hash is the hex SHA-256 of the file.
+type documentUpload struct {
+	name  string
+	text  string
+	paged bool
+	hash  string
+}
+
+// readDocumentUpload validates the "file" form field and extracts its text. The format is
+// picked by docextract.Detect from the file name and content. On failure it writes the
+// error response and returns ok=false.
+func readDocumentUpload(c *gin.Context) (documentUpload, bool) {
+	file, err := c.FormFile("file")
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, response.CodeBadRequest, "missing file")
+		return documentUpload{}, false
+	}
+	if file.Size > maxUploadSize {
+		response.Error(c, http.StatusBadRequest, response.CodeBadRequest, "file too large (max 10MB)")
+		return documentUpload{}, false
+	}
+
+	f, err := file.Open()
+	if err != nil {
+		writeError(c, err, "failed to read file")
+		return documentUpload{}, false
+	}
+	defer f.Close()
+
+	hash := sha256.New()
+	text, format, err := docextract.ExtractText(io.TeeReader(f, hash), file.Filename)
+	if errors.Is(err, docextract.ErrUnsupported) {
+		response.Error(c, http.StatusBadRequest, response.CodeBadRequest,
+			"unsupported file type, allowed: "+strings.Join(docextract.Extensions(), ", "))
+		return documentUpload{}, false
+	}
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, response.CodeBadRequest, "failed to extract text from "+string(format)+": "+err.Error())
+		return documentUpload{}, false
+	}
+	// Leading page breaks are kept so that the pages of a PDF are numbered from the first.
+	if strings.TrimSpace(text) == "" {
+		response.Error(c, http.StatusBadRequest, response.CodeBadRequest, "file contains no extractable text")
+		return documentUpload{}, false
+	}
 
 	name := strings.TrimSpace(c.PostForm("name"))
 	if name == "" {
@@ -190,25 +423,214 @@ func (h *RAGHandler) UploadPDF(c *gin.Context) {
 			name = "Untitled"
 		}
 	}
+	return documentUpload{
+		name:  name,
+		text:  text,
+		paged: format == docextract.FormatPDF,
+		hash:  hex.EncodeToString(hash.Sum(nil)),
+	}, true
+}
+
+// UploadImage accepts a photo or scan of a document (form field "image"), runs OCR, and ingests
+// the recognized text into the optional session_id. The response carries the OCR text too.
+// An image already ingested into the same session is not run through OCR again unless
+// allow_duplicate=true.
+func (h *RAGHandler) UploadImage(c *gin.Context) {
+	userID, ok := getUserIDFromContext(c)
+	if !ok {
+		response.Error(c, http.StatusUnauthorized, response.CodeUnauthorized, "invalid token payload")
+		return
+	}
 
-	sessionID := parseUintForm(c, "session_id")
+	image, ok := readImageUpload(c)
+	if !ok {
+		return
+	}
 
-	result, err := h.ragService.Ingest(c.Request.Context(), app.IngestInput{
+	result, err := h.ragService.IngestImage(c.Request.Context(), app.IngestImageInput{
 		UserID:    userID,
-		SessionID: sessionID,
-		Name:      name,
-		Content:   text,
+		SessionID: parseUintForm(c, "session_id"),
+		Name:      image.name,
+		Image:     image.data,
+		MIMEType:  image.mimeType,
+
+		AllowDuplicate: c.PostForm("allow_duplicate") == "true",
 	})
 	if err != nil {
-		switch {
-		case errors.Is(err, app.ErrInvalidInput):
-			response.Error(c, http.StatusBadRequest, response.CodeBadRequest, err.Error())
-		default:
-			response.Error(c, http.StatusInternalServerError, response.CodeInternalServer, "ingest failed: "+err.Error())
+		writeUpstreamError(c, err, "image ingest failed")
+		return
+	}
+
+	response.OK(c, result)
+}
+
+// ScreenImage answers screening questions about a resume screenshot in one call: the
+// "image" form field is run through OCR and ingested as a temporary document, each
+// "question" field is answered from it alone, and the document is deleted again. top_k is
+// as for Ask. Each question counts against the ask rate limit and takes an in-flight slot
+// like a batch ask; the OCR and ingest take one slot before them.
+func (h *RAGHandler) ScreenImage(c *gin.Context) {
+	userID, ok := getUserIDFromContext(c)
+	if !ok {
+		response.Error(c, http.StatusUnauthorized, response.CodeUnauthorized, "invalid token payload")
+		return
+	}
+
+	var questions []string
+	for _, q := range c.PostFormArray("question") {
+		if q = strings.TrimSpace(q); q != "" {
+			questions = append(questions, q)
 		}
+	}
+	if len(questions) == 0 {
+		response.Error(c, http.StatusBadRequest, response.CodeBadRequest, "missing questions (form field 'question')")
+		return
+	}
+	image, ok := readImageUpload(c)
+	if !ok {
+		return
+	}
+
+	input := app.ScreenImageInput{
+		UserID:    userID,
+		Name:      image.name,
+		Image:     image.data,
+		MIMEType:  image.mimeType,
+		Questions: questions,
+		TopK:      int(parseUintForm(c, "top_k")),
+	}
+	if h.admitAsk != nil {
+		input.Admit = func(ctx context.Context) (func(), error) { return h.admitAsk(ctx, userID) }
+	}
+	if h.hold != nil {
+		input.Hold = func(ctx context.Context) (func(), error) { return h.hold(ctx, userID) }
+	}
+	result, err := h.ragService.ScreenImage(c.Request.Context(), input)
+	if err != nil {
+		writeUpstreamError(c, err, "screenshot screening failed")
+		return
+	}
+
+	response.OK(c, result)
+}
+
+// imageUpload is an image read from the "image" form field, named after the "name" field
+// or the file name.
+type imageUpload struct {
+	name     string
+	data     []byte
+	mimeType string
+}
+
+// readImageUpload reads and checks the "image" form field. On failure it writes the error
+// response and returns ok=false.
+func readImageUpload(c *gin.Context) (imageUpload, bool) {
+	file, err := c.FormFile("image")
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, response.CodeBadRequest, "missing image file (form field 'image')")
+		return imageUpload{}, false
+	}
+	if file.Size > maxImageSize {
+		response.Error(c, http.StatusBadRequest, response.CodeBadRequest, "image too large (max 5MB)")
+		return imageUpload{}, false
+	}
+
+	f, err := file.Open()
+	if err != nil {
+		writeError(c, err, "failed to read file")
+		return imageUpload{}, false
+	}
+	defer f.Close()
+
+	data, err := io.ReadAll(f)
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, response.CodeBadRequest, "failed to read image")
+		return imageUpload{}, false
+	}
+	mimeType := http.DetectContentType(data)
+	if !strings.HasPrefix(mimeType, "image/") {
+		response.Error(c, http.StatusBadRequest, response.CodeBadRequest, "only image files are allowed")
+		return imageUpload{}, false
+	}
+
+	name := strings.TrimSpace(c.PostForm("name"))
+	if name == "" {
+		name = strings.TrimSuffix(file.Filename, filepath.Ext(file.Filename))
+	}
+	return imageUpload{name: name, data: data, mimeType: mimeType}, true
+}
+
+// AppendDocument adds new content to an existing document, embedding only the appended text.
+func (h *RAGHandler) AppendDocument(c *gin.Context) {
+	userID, ok := getUserIDFromContext(c)
+	if !ok {
+		response.Error(c, http.StatusUnauthorized, response.CodeUnauthorized, "invalid token payload")
 		return
 	}
+	docID, err := parseUintParam(c, "id")
+	if err != nil || docID == 0 {
+		response.Error(c, http.StatusBadRequest, response.CodeBadRequest, "invalid document id")
+		return
+	}
+
+	var req AppendRAGDocumentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, response.CodeBadRequest, "invalid request payload")
+		return
+	}
+
+	result, err := h.ragService.Append(c.Request.Context(), app.AppendInput{
+		UserID:     userID,
+		DocumentID: docID,
+		Content:    req.Content,
+	})
+	if err != nil {
+		writeError(c, err, "append failed")
+		return
+	}
+
+	response.OK(c, result)
+}
+
+// ReplaceDocument replaces a document's content in place, keeping its ID. It takes either
+// a JSON ReplaceRAGDocumentRequest or the multipart form of UploadDocument; a new file
+// keeps the document's name unless "name" is given.
+func (h *RAGHandler) ReplaceDocument(c *gin.Context) {
+	userID, ok := getUserIDFromContext(c)
+	if !ok {
+		response.Error(c, http.StatusUnauthorized, response.CodeUnauthorized, "invalid token payload")
+		return
+	}
+	docID, err := parseUintParam(c, "id")
+	if err != nil || docID == 0 {
+		response.Error(c, http.StatusBadRequest, response.CodeBadRequest, "invalid document id")
+		return
+	}
+
+	input := app.ReingestInput{UserID: userID, DocumentID: docID}
+	if strings.HasPrefix(c.ContentType(), "multipart/") {
+		upload, ok := readDocumentUpload(c)
+		if !ok {
+			return
+		}
+		input.Name = c.PostForm("name")
+		input.Content, input.Paged, input.FileHash = upload.text, upload.paged, upload.hash
+		input.ChunkSize, input.ChunkOverlap, input.ChunkStrategy = chunkingForm(c)
+	} else {
+		var req ReplaceRAGDocumentRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			response.Error(c, http.StatusBadRequest, response.CodeBadRequest, "invalid request payload")
+			return
+		}
+		input.Name, input.Content = req.Name, req.Content
+		input.ChunkSize, input.ChunkOverlap, input.ChunkStrategy = req.ChunkSize, req.ChunkOverlap, req.ChunkStrategy
+	}
 
+	result, err := h.ragService.Reingest(c.Request.Context(), input)
+	if err != nil {
+		writeError(c, err, "replace document failed")
+		return
+	}
 	response.OK(c, result)
 }
 
@@ -221,6 +643,18 @@ func parseUintForm(c *gin.Context, key string) uint {
 	return uint(u)
 }
 
+// chunkingForm reads the optional chunk_size, chunk_overlap and chunk_strategy form
+// fields; overlap is nil when omitted.
+func chunkingForm(c *gin.Context) (size int, overlap *int, strategy string) {
+	size = int(parseUintForm(c, "chunk_size"))
+	if raw := c.PostForm("chunk_overlap"); raw != "" {
+		if n, err := strconv.Atoi(raw); err == nil {
+			overlap = &n
+		}
+	}
+	return size, overlap, c.PostForm("chunk_strategy")
+}
+
 func (h *RAGHandler) ListDocuments(c *gin.Context) {
 	userID, ok := getUserIDFromContext(c)
 	if !ok {
@@ -234,9 +668,9 @@ func (h *RAGHandler) ListDocuments(c *gin.Context) {
 		}
 	}
 
-	docs, err := h.ragService.ListDocuments(userID, sessionID)
+	docs, err := h.ragService.ListDocuments(c.Request.Context(), userID, sessionID)
 	if err != nil {
-		response.Error(c, http.StatusInternalServerError, response.CodeInternalServer, "list documents failed")
+		writeError(c, err, "list documents failed")
 		return
 	}
 
@@ -254,17 +688,83 @@ func (h *RAGHandler) DeleteDocument(c *gin.Context) {
 		response.Error(c, http.StatusBadRequest, response.CodeBadRequest, "invalid document id")
 		return
 	}
-	if err := h.ragService.DeleteDocument(userID, docID); err != nil {
-		if errors.Is(err, app.ErrInvalidInput) {
-			response.Error(c, http.StatusBadRequest, response.CodeBadRequest, err.Error())
-		} else {
-			response.Error(c, http.StatusInternalServerError, response.CodeInternalServer, "delete document failed")
-		}
+	if err := h.ragService.DeleteDocument(c.Request.Context(), userID, docID); err != nil {
+		writeError(c, err, "delete document failed")
 		return
 	}
 	response.OK(c, gin.H{"deleted_document_id": docID})
 }
 
+// DocumentStatus reports how far a document's ingest has got.
+func (h *RAGHandler) DocumentStatus(c *gin.Context) {
+	userID, ok := getUserIDFromContext(c)
+	if !ok {
+		response.Error(c, http.StatusUnauthorized, response.CodeUnauthorized, "invalid token payload")
+		return
+	}
+	docID, err := parseUintParam(c, "id")
+	if err != nil || docID == 0 {
+		response.Error(c, http.StatusBadRequest, response.CodeBadRequest, "invalid document id")
+		return
+	}
+	status, err := h.ragService.DocumentStatus(c.Request.Context(), userID, docID)
+	if err != nil {
+		writeError(c, err, "get document status failed")
+		return
+	}
+	response.OK(c, status)
+}
+
+// Quota reports the user's RAG storage against the per-user limits.
+func (h *RAGHandler) Quota(c *gin.Context) {
+	userID, ok := getUserIDFromContext(c)
+	if !ok {
+		response.Error(c, http.StatusUnauthorized, response.CodeUnauthorized, "invalid token payload")
+		return
+	}
+	quota, err := h.ragService.StorageQuota(c.Request.Context(), userID)
+	if err != nil {
+		writeError(c, err, "get rag quota failed")
+		return
+	}
+	response.OK(c, quota)
+}
+
+// DocumentChunks lists a document's chunks in index order, ?limit (default 50, at most
+// 200) at a time from ?offset, with each chunk's embedding format and dimension.
+func (h *RAGHandler) DocumentChunks(c *gin.Context) {
+	userID, ok := getUserIDFromContext(c)
+	if !ok {
+		response.Error(c, http.StatusUnauthorized, response.CodeUnauthorized, "invalid token payload")
+		return
+	}
+	docID, err := parseUintParam(c, "id")
+	if err != nil || docID == 0 {
+		response.Error(c, http.StatusBadRequest, response.CodeBadRequest, "invalid document id")
+		return
+	}
+	offset := 0
+	if raw := c.Query("offset"); raw != "" {
+		offset, err = strconv.Atoi(raw)
+		if err != nil || offset < 0 {
+			response.Error(c, http.StatusBadRequest, response.CodeBadRequest, "invalid offset")
+			return
+		}
+	}
+	limit := 0
+	if raw := c.Query("limit"); raw != "" {
+		if parsed, parseErr := strconv.Atoi(raw); parseErr == nil {
+			limit = parsed
+		}
+	}
+	page, err := h.ragService.DocumentChunks(c.Request.Context(), userID, docID, offset, limit)
+	if err != nil {
+		writeError(c, err, "list document chunks failed")
+		return
+	}
+	response.OK(c, page)
+}
+
 func (h *RAGHandler) Ask(c *gin.Context) {
 	userID, ok := getUserIDFromContext(c)
 	if !ok {
@@ -279,23 +779,166 @@ func (h *RAGHandler) Ask(c *gin.Context) {
 	}
 
 	result, err := h.ragService.Ask(c.Request.Context(), app.AskInput{
+		UserID:             userID,
+		SessionID:          req.SessionID,
+		Question:           req.Question,
+		DocumentIDs:        req.DocumentIDs,
+		TopK:               req.TopK,
+		IncludeChatHistory: req.IncludeChatHistory,
+		Rerank:             req.Rerank,
+		Diversify:          req.Diversify,
+		MinScore:           req.MinScore,
+		CompareShadow:      req.CompareShadow,
+		CitationMarkers:    req.CitationMarkers,
+		MultiQuery:         req.MultiQuery,
+	})
+	if err != nil {
+		writeError(c, err, "ask failed")
+		return
+	}
+
+	response.OK(c, result)
+}
+
+// AskBatch answers several questions about the same documents concurrently, each on its
+// own, and reports per question and in summary how well the documents covered them. Each
+// question counts against the ask rate limit and takes an in-flight slot while it is
+// answered; those beyond either fail with 429's code while the rest are answered.
+func (h *RAGHandler) AskBatch(c *gin.Context) {
+	userID, ok := getUserIDFromContext(c)
+	if !ok {
+		response.Error(c, http.StatusUnauthorized, response.CodeUnauthorized, "invalid token payload")
+		return
+	}
+
+	var req AskBatchRAGRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, response.CodeBadRequest, "invalid request payload")
+		return
+	}
+
+	input := app.AskBatchInput{
+		Options: app.AskInput{
+			UserID:             userID,
+			SessionID:          req.SessionID,
+			DocumentIDs:        req.DocumentIDs,
+			TopK:               req.TopK,
+			IncludeChatHistory: req.IncludeChatHistory,
+			Rerank:             req.Rerank,
+			Diversify:          req.Diversify,
+			MinScore:           req.MinScore,
+			CitationMarkers:    req.CitationMarkers,
+			MultiQuery:         req.MultiQuery,
+		},
+		Questions: req.Questions,
+	}
+	if h.admitAsk != nil {
+		input.Admit = func(ctx context.Context) (func(), error) { return h.admitAsk(ctx, userID) }
+	}
+	result, err := h.ragService.AskBatch(c.Request.Context(), input)
+	if err != nil {
+		writeError(c, err, "batch ask failed")
+		return
+	}
+
+	response.OK(c, result)
+}
+
+// AskStream answers like Ask but streams the answer as server-sent events: "sources" with
+// the retrieved chunks and chat messages as JSON, unnamed events per chunk, then "done" or
+// "error", with "warnings" before "done" when limits are nearly used up. Failures before
+// retrieval completes get the same JSON errors as Ask.
+func (h *RAGHandler) AskStream(c *gin.Context) {
+	userID, ok := getUserIDFromContext(c)
+	if !ok {
+		response.Error(c, http.StatusUnauthorized, response.CodeUnauthorized, "invalid token payload")
+		return
+	}
+
+	var req AskRAGRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, response.CodeBadRequest, "invalid request payload")
+		return
+	}
+
+	flusher, ok := c.Writer.(http.Flusher)
+	if !ok {
+		response.Error(c, http.StatusInternalServerError, response.CodeInternalServer, "stream not supported")
+		return
+	}
+
+	started := false
+	full, err := h.ragService.AskStream(c.Request.Context(), app.AskInput{
+		UserID:             userID,
+		SessionID:          req.SessionID,
+		Question:           req.Question,
+		DocumentIDs:        req.DocumentIDs,
+		TopK:               req.TopK,
+		IncludeChatHistory: req.IncludeChatHistory,
+		Rerank:             req.Rerank,
+		Diversify:          req.Diversify,
+		MinScore:           req.MinScore,
+		CompareShadow:      req.CompareShadow,
+		CitationMarkers:    req.CitationMarkers,
+		MultiQuery:         req.MultiQuery,
+	}, func(sources app.AskSources) error {
+		payload, err := json.Marshal(sources)
+		if err != nil {
+			return err
+		}
+		setSSEHeaders(c)
+		started = true
+		if _, writeErr := c.Writer.Write([]byte("event: sources\ndata: " + string(payload) + "\n\n")); writeErr != nil {
+			return writeErr
+		}
+		flusher.Flush()
+		return nil
+	}, func(chunk string) error {
+		if _, writeErr := c.Writer.Write([]byte("data: " + chunk + "\n\n")); writeErr != nil {
+			return writeErr
+		}
+		flusher.Flush()
+		return nil
+	})
+	if err != nil {
+		if !started {
+			writeError(c, err, "ask failed")
+			return
+		}
+		if _, writeErr := c.Writer.Write([]byte("event: error\ndata: ask failed\n\n")); writeErr == nil {
+			flusher.Flush()
+		}
+		return
+	}
+
+	writeWarningsSSE(c)
+	if _, writeErr := c.Writer.Write([]byte("event: done\ndata: " + sanitizeSSE(full) + "\n\n")); writeErr == nil {
+		flusher.Flush()
+	}
+}
+
+// Compare produces a side-by-side comparison of 2-5 documents grounded in per-document evidence.
+func (h *RAGHandler) Compare(c *gin.Context) {
+	userID, ok := getUserIDFromContext(c)
+	if !ok {
+		response.Error(c, http.StatusUnauthorized, response.CodeUnauthorized, "invalid token payload")
+		return
+	}
+
+	var req CompareRAGRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, response.CodeBadRequest, "invalid request payload")
+		return
+	}
+
+	result, err := h.ragService.Compare(c.Request.Context(), app.CompareInput{
 		UserID:      userID,
-		SessionID:   req.SessionID,
 		Question:    req.Question,
 		DocumentIDs: req.DocumentIDs,
 		TopK:        req.TopK,
 	})
 	if err != nil {
-		switch {
-		case errors.Is(err, app.ErrInvalidInput):
-			response.Error(c, http.StatusBadRequest, response.CodeBadRequest, err.Error())
-		case errors.Is(err, app.ErrRAGNoDocuments):
-			response.Error(c, http.StatusBadRequest, response.CodeBadRequest, err.Error())
-		case errors.Is(err, app.ErrRAGNoChunks):
-			response.Error(c, http.StatusBadRequest, response.CodeBadRequest, err.Error())
-		default:
-			response.Error(c, http.StatusInternalServerError, response.CodeInternalServer, "ask failed")
-		}
+		writeError(c, err, "compare failed")
 		return
 	}
 
@@ -0,0 +1,148 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"gopherai-resume/internal/app"
+	"gopherai-resume/internal/transport/http/response"
+)
+
+// ResumeBulletHandler serves the achievement quantification assistant.
+type ResumeBulletHandler struct {
+	bulletService *app.ResumeBulletService
+}
+
+func NewResumeBulletHandler(bulletService *app.ResumeBulletService) *ResumeBulletHandler {
+	return &ResumeBulletHandler{bulletService: bulletService}
+}
+
+type ScanBulletsRequest struct {
+	ResumeDocumentID uint `json:"resume_document_id" binding:"required,gt=0"`
+	SessionID        uint `json:"session_id"`
+}
+
+type BulletReplyRequest struct {
+	Message string `json:"message" binding:"max=2000"`
+}
+
+type AcceptBulletRequest struct {
+	Text string `json:"text" binding:"max=1000"`
+}
+
+func (h *ResumeBulletHandler) Scan(c *gin.Context) {
+	userID, ok := getUserIDFromContext(c)
+	if !ok {
+		response.Error(c, http.StatusUnauthorized, response.CodeUnauthorized, "invalid token payload")
+		return
+	}
+	var req ScanBulletsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, response.CodeBadRequest, "invalid request payload")
+		return
+	}
+	bullets, err := h.bulletService.Scan(c.Request.Context(), app.ScanBulletsInput{
+		UserID:        userID,
+		DocumentID:    req.ResumeDocumentID,
+		ChatSessionID: req.SessionID,
+	})
+	if err != nil {
+		writeError(c, err, "scan resume failed")
+		return
+	}
+	response.OK(c, bullets)
+}
+
+func (h *ResumeBulletHandler) List(c *gin.Context) {
+	userID, ok := getUserIDFromContext(c)
+	if !ok {
+		response.Error(c, http.StatusUnauthorized, response.CodeUnauthorized, "invalid token payload")
+		return
+	}
+	documentID, err := strconv.ParseUint(c.Query("resume_document_id"), 10, 64)
+	if err != nil || documentID == 0 {
+		response.Error(c, http.StatusBadRequest, response.CodeBadRequest, "invalid resume_document_id")
+		return
+	}
+	bullets, err := h.bulletService.List(c.Request.Context(), userID, uint(documentID))
+	if err != nil {
+		writeError(c, err, "list bullets failed")
+		return
+	}
+	response.OK(c, bullets)
+}
+
+// Reply continues the guided conversation for one bullet; an empty message starts it.
+func (h *ResumeBulletHandler) Reply(c *gin.Context) {
+	userID, ok := getUserIDFromContext(c)
+	if !ok {
+		response.Error(c, http.StatusUnauthorized, response.CodeUnauthorized, "invalid token payload")
+		return
+	}
+	bulletID, err := parseUintParam(c, "id")
+	if err != nil || bulletID == 0 {
+		response.Error(c, http.StatusBadRequest, response.CodeBadRequest, "invalid bullet id")
+		return
+	}
+	var req BulletReplyRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			response.Error(c, http.StatusBadRequest, response.CodeBadRequest, "invalid request payload")
+			return
+		}
+	}
+	reply, err := h.bulletService.Reply(c.Request.Context(), userID, bulletID, req.Message)
+	if err != nil {
+		writeError(c, err, "bullet reply failed")
+		return
+	}
+	response.OK(c, reply)
+}
+
+// Accept saves the rewrite into the stored resume.
+func (h *ResumeBulletHandler) Accept(c *gin.Context) {
+	userID, ok := getUserIDFromContext(c)
+	if !ok {
+		response.Error(c, http.StatusUnauthorized, response.CodeUnauthorized, "invalid token payload")
+		return
+	}
+	bulletID, err := parseUintParam(c, "id")
+	if err != nil || bulletID == 0 {
+		response.Error(c, http.StatusBadRequest, response.CodeBadRequest, "invalid bullet id")
+		return
+	}
+	var req AcceptBulletRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			response.Error(c, http.StatusBadRequest, response.CodeBadRequest, "invalid request payload")
+			return
+		}
+	}
+	bullet, err := h.bulletService.Accept(c.Request.Context(), userID, bulletID, req.Text)
+	if err != nil {
+		writeError(c, err, "accept bullet failed")
+		return
+	}
+	response.OK(c, bullet)
+}
+
+func (h *ResumeBulletHandler) Skip(c *gin.Context) {
+	userID, ok := getUserIDFromContext(c)
+	if !ok {
+		response.Error(c, http.StatusUnauthorized, response.CodeUnauthorized, "invalid token payload")
+		return
+	}
+	bulletID, err := parseUintParam(c, "id")
+	if err != nil || bulletID == 0 {
+		response.Error(c, http.StatusBadRequest, response.CodeBadRequest, "invalid bullet id")
+		return
+	}
+	bullet, err := h.bulletService.Skip(c.Request.Context(), userID, bulletID)
+	if err != nil {
+		writeError(c, err, "skip bullet failed")
+		return
+	}
+	response.OK(c, bullet)
+}
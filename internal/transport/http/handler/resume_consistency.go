@@ -0,0 +1,43 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"gopherai-resume/internal/app"
+	"gopherai-resume/internal/transport/http/response"
+)
+
+// ResumeConsistencyHandler serves internal consistency checks of resumes.
+type ResumeConsistencyHandler struct {
+	consistencyService *app.ResumeConsistencyService
+}
+
+func NewResumeConsistencyHandler(consistencyService *app.ResumeConsistencyService) *ResumeConsistencyHandler {
+	return &ResumeConsistencyHandler{consistencyService: consistencyService}
+}
+
+type ResumeConsistencyRequest struct {
+	ResumeDocumentID uint `json:"resume_document_id" binding:"required"`
+}
+
+func (h *ResumeConsistencyHandler) Check(c *gin.Context) {
+	userID, ok := getUserIDFromContext(c)
+	if !ok {
+		response.Error(c, http.StatusUnauthorized, response.CodeUnauthorized, "invalid token payload")
+		return
+	}
+	var req ResumeConsistencyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, response.CodeBadRequest, "invalid request payload")
+		return
+	}
+
+	report, err := h.consistencyService.Check(c.Request.Context(), userID, req.ResumeDocumentID)
+	if err != nil {
+		writeError(c, err, "check resume consistency failed")
+		return
+	}
+	response.OK(c, report)
+}
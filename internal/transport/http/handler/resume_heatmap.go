@@ -0,0 +1,89 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"gopherai-resume/internal/app"
+	"gopherai-resume/internal/transport/http/response"
+)
+
+// ResumeHeatmapHandler serves resume-to-job-description similarity data.
+type ResumeHeatmapHandler struct {
+	heatmapService *app.ResumeHeatmapService
+}
+
+func NewResumeHeatmapHandler(heatmapService *app.ResumeHeatmapService) *ResumeHeatmapHandler {
+	return &ResumeHeatmapHandler{heatmapService: heatmapService}
+}
+
+// ResumeHeatmapRequest names the resume and the target job. requirements, job_description
+// and application_id are tried in that order.
+type ResumeHeatmapRequest struct {
+	ResumeDocumentID uint     `json:"resume_document_id" binding:"required"`
+	Requirements     []string `json:"requirements" binding:"omitempty,max=25"`
+	JobDescription   string   `json:"job_description"`
+	ApplicationID    uint     `json:"application_id"`
+}
+
+func (h *ResumeHeatmapHandler) Build(c *gin.Context) {
+	userID, ok := getUserIDFromContext(c)
+	if !ok {
+		response.Error(c, http.StatusUnauthorized, response.CodeUnauthorized, "invalid token payload")
+		return
+	}
+	var req ResumeHeatmapRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, response.CodeBadRequest, "invalid request payload")
+		return
+	}
+
+	heatmap, err := h.heatmapService.Build(c.Request.Context(), app.ResumeHeatmapInput{
+		UserID:           userID,
+		ResumeDocumentID: req.ResumeDocumentID,
+		Requirements:     req.Requirements,
+		JobDescription:   req.JobDescription,
+		ApplicationID:    req.ApplicationID,
+	})
+	if err != nil {
+		writeError(c, err, "build resume heatmap failed")
+		return
+	}
+	response.OK(c, heatmap)
+}
+
+// CompareResumesRequest names 2 to 5 resume versions and the target job, given the same way
+// as in ResumeHeatmapRequest.
+type CompareResumesRequest struct {
+	ResumeDocumentIDs []uint   `json:"resume_document_ids" binding:"required,min=2,max=5"`
+	Requirements      []string `json:"requirements" binding:"omitempty,max=25"`
+	JobDescription    string   `json:"job_description"`
+	ApplicationID     uint     `json:"application_id"`
+}
+
+func (h *ResumeHeatmapHandler) Compare(c *gin.Context) {
+	userID, ok := getUserIDFromContext(c)
+	if !ok {
+		response.Error(c, http.StatusUnauthorized, response.CodeUnauthorized, "invalid token payload")
+		return
+	}
+	var req CompareResumesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, response.CodeBadRequest, "invalid request payload")
+		return
+	}
+
+	comparison, err := h.heatmapService.CompareVersions(c.Request.Context(), app.CompareResumesInput{
+		UserID:            userID,
+		ResumeDocumentIDs: req.ResumeDocumentIDs,
+		Requirements:      req.Requirements,
+		JobDescription:    req.JobDescription,
+		ApplicationID:     req.ApplicationID,
+	})
+	if err != nil {
+		writeError(c, err, "compare resumes failed")
+		return
+	}
+	response.OK(c, comparison)
+}
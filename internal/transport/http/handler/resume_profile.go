@@ -0,0 +1,103 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"gopherai-resume/internal/app"
+	"gopherai-resume/internal/transport/http/response"
+)
+
+const maxJSONResumeSize = 1 << 20
+
+// ResumeProfileHandler imports and exports resumes in the JSON Resume schema.
+type ResumeProfileHandler struct {
+	profileService *app.ResumeProfileService
+}
+
+func NewResumeProfileHandler(profileService *app.ResumeProfileService) *ResumeProfileHandler {
+	return &ResumeProfileHandler{profileService: profileService}
+}
+
+// Import accepts a multipart form with "file" (a JSON Resume .json file) and optional "name"
+// and "session_id", and stores it as a resume document.
+func (h *ResumeProfileHandler) Import(c *gin.Context) {
+	userID, ok := getUserIDFromContext(c)
+	if !ok {
+		response.Error(c, http.StatusUnauthorized, response.CodeUnauthorized, "invalid token payload")
+		return
+	}
+
+	file, err := c.FormFile("file")
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, response.CodeBadRequest, "missing file")
+		return
+	}
+	if file.Size > maxJSONResumeSize {
+		response.Error(c, http.StatusBadRequest, response.CodeBadRequest, "file too large (max 1MB)")
+		return
+	}
+	f, err := file.Open()
+	if err != nil {
+		writeError(c, err, "failed to read file")
+		return
+	}
+	defer f.Close()
+	data, err := io.ReadAll(io.LimitReader(f, maxJSONResumeSize))
+	if err != nil {
+		writeError(c, err, "failed to read file")
+		return
+	}
+
+	name := strings.TrimSpace(c.PostForm("name"))
+	if name == "" {
+		name = strings.TrimSuffix(file.Filename, filepath.Ext(file.Filename))
+	}
+	result, err := h.profileService.Import(c.Request.Context(), app.ImportJSONResumeInput{
+		UserID:    userID,
+		SessionID: parseUintForm(c, "session_id"),
+		Name:      name,
+		Data:      data,
+	})
+	if err != nil {
+		writeError(c, err, "import resume failed")
+		return
+	}
+	response.OK(c, result)
+}
+
+// Export downloads resume_document_id as a JSON Resume file. The X-Resume-Schema-Version
+// header names the custom field schema the "custom" object follows (0 = none).
+func (h *ResumeProfileHandler) Export(c *gin.Context) {
+	userID, ok := getUserIDFromContext(c)
+	if !ok {
+		response.Error(c, http.StatusUnauthorized, response.CodeUnauthorized, "invalid token payload")
+		return
+	}
+	documentID, err := strconv.ParseUint(c.Query("resume_document_id"), 10, 64)
+	if err != nil || documentID == 0 {
+		response.Error(c, http.StatusBadRequest, response.CodeBadRequest, "invalid resume_document_id")
+		return
+	}
+
+	exported, err := h.profileService.Export(c.Request.Context(), userID, uint(documentID))
+	if err != nil {
+		writeError(c, err, "export resume failed")
+		return
+	}
+	data, err := json.MarshalIndent(exported.Resume, "", "  ")
+	if err != nil {
+		writeError(c, err, "export resume failed")
+		return
+	}
+	c.Header("X-Resume-Schema-Version", strconv.Itoa(exported.SchemaVersion))
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", fmt.Sprintf("resume-%d.json", documentID)))
+	c.Data(http.StatusOK, "application/json; charset=utf-8", data)
+}
@@ -0,0 +1,85 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"gopherai-resume/internal/app"
+	"gopherai-resume/internal/transport/http/response"
+)
+
+// ResumeSchemaHandler manages the registry of custom resume field schemas.
+type ResumeSchemaHandler struct {
+	schemaService *app.ResumeSchemaService
+}
+
+func NewResumeSchemaHandler(schemaService *app.ResumeSchemaService) *ResumeSchemaHandler {
+	return &ResumeSchemaHandler{schemaService: schemaService}
+}
+
+type CreateResumeSchemaRequest struct {
+	Description string                  `json:"description" binding:"max=512"`
+	Fields      []app.ResumeSchemaField `json:"fields" binding:"required"`
+	Activate    bool                    `json:"activate"`
+}
+
+// Create registers a new schema version (admin only).
+func (h *ResumeSchemaHandler) Create(c *gin.Context) {
+	userID, ok := getUserIDFromContext(c)
+	if !ok {
+		response.Error(c, http.StatusUnauthorized, response.CodeUnauthorized, "invalid token payload")
+		return
+	}
+	var req CreateResumeSchemaRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, response.CodeBadRequest, "invalid request payload")
+		return
+	}
+	schema, err := h.schemaService.Create(c.Request.Context(), app.CreateResumeSchemaInput{
+		UserID:      userID,
+		Description: req.Description,
+		Fields:      req.Fields,
+		Activate:    req.Activate,
+	})
+	if err != nil {
+		writeError(c, err, "create resume schema failed")
+		return
+	}
+	response.OK(c, schema)
+}
+
+// List returns every schema version, newest first (admin only).
+func (h *ResumeSchemaHandler) List(c *gin.Context) {
+	schemas, err := h.schemaService.List(c.Request.Context())
+	if err != nil {
+		writeError(c, err, "list resume schemas failed")
+		return
+	}
+	response.OK(c, schemas)
+}
+
+// Activate makes :version the active schema; version 0 switches custom fields off (admin only).
+func (h *ResumeSchemaHandler) Activate(c *gin.Context) {
+	version, err := strconv.Atoi(c.Param("version"))
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, response.CodeBadRequest, "invalid version")
+		return
+	}
+	if err := h.schemaService.Activate(c.Request.Context(), version); err != nil {
+		writeError(c, err, "activate resume schema failed")
+		return
+	}
+	response.OK(c, gin.H{"active_version": version})
+}
+
+// Active returns the schema the resume parser uses, or null when none is active.
+func (h *ResumeSchemaHandler) Active(c *gin.Context) {
+	schema, err := h.schemaService.Active(c.Request.Context())
+	if err != nil {
+		writeError(c, err, "get resume schema failed")
+		return
+	}
+	response.OK(c, schema)
+}
@@ -0,0 +1,69 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"gopherai-resume/internal/app"
+	"gopherai-resume/internal/transport/http/response"
+)
+
+// SessionShareHandler creates and revokes public links to chat sessions and serves them.
+type SessionShareHandler struct {
+	shareService *app.SessionShareService
+}
+
+func NewSessionShareHandler(shareService *app.SessionShareService) *SessionShareHandler {
+	return &SessionShareHandler{shareService: shareService}
+}
+
+func (h *SessionShareHandler) Share(c *gin.Context) {
+	userID, ok := getUserIDFromContext(c)
+	if !ok {
+		response.Error(c, http.StatusUnauthorized, response.CodeUnauthorized, "invalid token payload")
+		return
+	}
+	sessionID, err := parseUintParam(c, "id")
+	if err != nil || sessionID == 0 {
+		response.Error(c, http.StatusBadRequest, response.CodeBadRequest, "invalid session id")
+		return
+	}
+	link, err := h.shareService.Share(c.Request.Context(), userID, sessionID)
+	if err != nil {
+		writeError(c, err, "share session failed")
+		return
+	}
+	response.OK(c, link)
+}
+
+func (h *SessionShareHandler) Revoke(c *gin.Context) {
+	userID, ok := getUserIDFromContext(c)
+	if !ok {
+		response.Error(c, http.StatusUnauthorized, response.CodeUnauthorized, "invalid token payload")
+		return
+	}
+	sessionID, err := parseUintParam(c, "id")
+	if err != nil || sessionID == 0 {
+		response.Error(c, http.StatusBadRequest, response.CodeBadRequest, "invalid session id")
+		return
+	}
+	if err := h.shareService.Revoke(c.Request.Context(), userID, sessionID); err != nil {
+		writeError(c, err, "revoke share failed")
+		return
+	}
+	response.OK(c, gin.H{"revoked_session_id": sessionID})
+}
+
+// Transcript serves a shared session without authentication. Responses are not cached so
+// revoking a link takes effect at once.
+func (h *SessionShareHandler) Transcript(c *gin.Context) {
+	c.Header("Cache-Control", "no-store")
+	c.Header("X-Robots-Tag", "noindex")
+	transcript, err := h.shareService.Transcript(c.Request.Context(), c.Param("token"))
+	if err != nil {
+		writeError(c, err, "get shared session failed")
+		return
+	}
+	response.OK(c, transcript)
+}
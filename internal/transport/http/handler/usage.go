@@ -0,0 +1,34 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"gopherai-resume/internal/app"
+	"gopherai-resume/internal/transport/http/response"
+)
+
+// UsageHandler reports the caller's daily quota usage.
+type UsageHandler struct {
+	quota *app.QuotaService
+}
+
+func NewUsageHandler(quota *app.QuotaService) *UsageHandler {
+	return &UsageHandler{quota: quota}
+}
+
+// Get returns today's usage and limit for every metered metric.
+func (h *UsageHandler) Get(c *gin.Context) {
+	userID, ok := getUserIDFromContext(c)
+	if !ok {
+		response.Error(c, http.StatusUnauthorized, response.CodeUnauthorized, "invalid token payload")
+		return
+	}
+	usage, err := h.quota.Usage(c.Request.Context(), userID)
+	if err != nil {
+		writeError(c, err, "get usage failed")
+		return
+	}
+	response.OK(c, gin.H{"usage": usage})
+}
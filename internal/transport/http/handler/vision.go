@@ -1,33 +1,116 @@
 package handler
 
 import (
+	"encoding/json"
+	"fmt"
 	"io"
 	"net/http"
+	"strconv"
 	"strings"
 
 	"github.com/gin-gonic/gin"
 
-	"gopherai-resume/internal/vision"
+	"gopherai-resume/internal/app"
+	"gopherai-resume/internal/pkg/apperr"
 	"gopherai-resume/internal/transport/http/response"
+	"gopherai-resume/internal/vision"
 )
 
 const maxImageSize = 5 << 20 // 5 MB
 
 // VisionHandler handles image classification requests.
 type VisionHandler struct {
-	classifier *vision.Classifier
+	models     *vision.Registry
+	maxTopK    int
+	classifier *app.VisionService
+	samples    *app.VisionSampleService
+}
+
+// NewVisionHandler creates a vision handler that serves the models in registry through
+// classifier. maxTopK bounds the per-request top_k option.
+func NewVisionHandler(
+	models *vision.Registry,
+	maxTopK int,
+	classifier *app.VisionService,
+	samples *app.VisionSampleService,
+) *VisionHandler {
+	return &VisionHandler{
+		models:     models,
+		maxTopK:    maxTopK,
+		classifier: classifier,
+		samples:    samples,
+	}
 }
 
-// NewVisionHandler creates a vision handler that uses the given classifier.
-func NewVisionHandler(classifier *vision.Classifier) *VisionHandler {
-	return &VisionHandler{classifier: classifier}
+// ClassifyOptions are the per-request options. They may be sent as individual form fields
+// (top_k, model, probabilities, no_cache) or as a JSON form part named "options"; form fields win.
+// NoCache skips the cached result and stored sample lookups; the fresh result still
+// refreshes the cache.
+// Store records the user's consent to keep the image and result for later re-runs.
+type ClassifyOptions struct {
+	TopK          int    `json:"top_k"`
+	Model         string `json:"model"`
+	Probabilities bool   `json:"probabilities"`
+	NoCache       bool   `json:"no_cache"`
+	Store         bool   `json:"store"`
 }
 
-// ClassifyRequest can optionally send top_k in JSON body; we use form "image" for the file.
-// TopK is otherwise from config (default 5).
+func (h *VisionHandler) parseClassifyOptions(c *gin.Context) (ClassifyOptions, error) {
+	var opts ClassifyOptions
+	if raw := c.PostForm("options"); raw != "" {
+		if err := json.Unmarshal([]byte(raw), &opts); err != nil {
+			return opts, fmt.Errorf("invalid options JSON")
+		}
+	}
+	if raw := c.PostForm("top_k"); raw != "" {
+		topK, err := strconv.Atoi(raw)
+		if err != nil {
+			return opts, fmt.Errorf("invalid top_k")
+		}
+		opts.TopK = topK
+	}
+	if raw := c.PostForm("model"); raw != "" {
+		opts.Model = raw
+	}
+	if raw := c.PostForm("probabilities"); raw != "" {
+		probabilities, err := strconv.ParseBool(raw)
+		if err != nil {
+			return opts, fmt.Errorf("invalid probabilities")
+		}
+		opts.Probabilities = probabilities
+	}
+	if raw := c.PostForm("no_cache"); raw != "" {
+		noCache, err := strconv.ParseBool(raw)
+		if err != nil {
+			return opts, fmt.Errorf("invalid no_cache")
+		}
+		opts.NoCache = noCache
+	}
+	if raw := c.PostForm("store"); raw != "" {
+		store, err := strconv.ParseBool(raw)
+		if err != nil {
+			return opts, fmt.Errorf("invalid store")
+		}
+		opts.Store = store
+	}
+
+	if opts.TopK < 0 || (h.maxTopK > 0 && opts.TopK > h.maxTopK) {
+		return opts, fmt.Errorf("top_k must be between 1 and %d", h.maxTopK)
+	}
+	if _, ok := h.models.Get(opts.Model); !ok {
+		return opts, fmt.Errorf("unknown model %q (available: %s)", opts.Model, strings.Join(h.models.Names(), ", "))
+	}
+	return opts, nil
+}
 
-// Classify accepts a multipart form with "image" (image file), runs local MobileNetV2 classification, returns top-k labels.
+// Classify accepts a multipart form with "image" (image file), runs local ONNX classification, returns top-k labels.
 func (h *VisionHandler) Classify(c *gin.Context) {
+	opts, err := h.parseClassifyOptions(c)
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, response.CodeBadRequest, err.Error())
+		return
+	}
+
 	file, err := c.FormFile("image")
 	if err != nil {
 		response.Error(c, http.StatusBadRequest, response.CodeBadRequest, "missing image file (form field 'image')")
@@ -52,8 +135,24 @@ func (h *VisionHandler) Classify(c *gin.Context) {
 		return
 	}
 
-	results, err := h.classifier.Classify(data)
+	classifyOpts := vision.ClassifyOptions{
+		TopK:          opts.TopK,
+		Probabilities: opts.Probabilities,
+	}
+	userID, _ := getUserIDFromContext(c)
+	result, err := h.classifier.Classify(c.Request.Context(), app.ClassifyImageInput{
+		UserID:  userID,
+		Image:   data,
+		Model:   opts.Model,
+		Options: classifyOpts,
+		NoCache: opts.NoCache,
+		Store:   opts.Store,
+	})
 	if err != nil {
+		if _, ok := apperr.As(err); ok {
+			writeError(c, err, "classification failed")
+			return
+		}
 		msg := err.Error()
 		if strings.Contains(msg, "cannot open shared object file") || strings.Contains(msg, "Error loading ONNX shared library") {
 			msg = "ONNX Runtime library not found. Install it and set VISION_ONNX_LIB to the path to libonnxruntime.so (see README)."
@@ -64,5 +163,130 @@ func (h *VisionHandler) Classify(c *gin.Context) {
 		return
 	}
 
-	response.OK(c, gin.H{"predictions": results})
+	response.OK(c, result)
+}
+
+// CheckPhoto accepts a multipart form with "image" (a headshot) and reports resolution, sharpness,
+// face placement and background problems as actionable feedback for a resume photo.
+func (h *VisionHandler) CheckPhoto(c *gin.Context) {
+	file, err := c.FormFile("image")
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, response.CodeBadRequest, "missing image file (form field 'image')")
+		return
+	}
+	if file.Size > maxImageSize {
+		response.Error(c, http.StatusBadRequest, response.CodeBadRequest, "image too large (max 5MB)")
+		return
+	}
+	f, err := file.Open()
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, response.CodeBadRequest, "failed to open uploaded file")
+		return
+	}
+	defer f.Close()
+	data, err := io.ReadAll(f)
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, response.CodeBadRequest, "failed to read image")
+		return
+	}
+
+	userID, _ := getUserIDFromContext(c)
+	report, err := h.classifier.CheckPhoto(c.Request.Context(), userID, data)
+	if err != nil {
+		writeError(c, err, "photo check failed")
+		return
+	}
+	response.OK(c, report)
+}
+
+// VisionModelEntry is one item of the model listing.
+type VisionModelEntry struct {
+	vision.ModelInfo
+	Default bool   `json:"default"`
+	Error   string `json:"error,omitempty"`
+}
+
+// ListModels returns every configured model with its input/output shapes and label count.
+// Models are loaded on first listing; a model that fails to load is reported with its error.
+func (h *VisionHandler) ListModels(c *gin.Context) {
+	names := h.models.Names()
+	entries := make([]VisionModelEntry, 0, len(names))
+	for _, name := range names {
+		classifier, _ := h.models.Get(name)
+		info, err := classifier.Info()
+		entry := VisionModelEntry{ModelInfo: info, Default: name == h.models.DefaultModel()}
+		if err != nil {
+			entry.Error = err.Error()
+		}
+		entries = append(entries, entry)
+	}
+	response.OK(c, gin.H{"models": entries})
+}
+
+// RerunVisionSampleRequest selects the model to re-run a stored sample with.
+type RerunVisionSampleRequest struct {
+	Model string `json:"model"`
+}
+
+// ListSamples lists the caller's stored vision samples.
+func (h *VisionHandler) ListSamples(c *gin.Context) {
+	userID, ok := getUserIDFromContext(c)
+	if !ok {
+		response.Error(c, http.StatusUnauthorized, response.CodeUnauthorized, "invalid token payload")
+		return
+	}
+	limit, _ := strconv.Atoi(c.Query("limit"))
+	samples, err := h.samples.List(c.Request.Context(), userID, limit)
+	if err != nil {
+		writeError(c, err, "list samples failed")
+		return
+	}
+	response.OK(c, samples)
+}
+
+// DeleteSample removes a stored sample and its image.
+func (h *VisionHandler) DeleteSample(c *gin.Context) {
+	userID, ok := getUserIDFromContext(c)
+	if !ok {
+		response.Error(c, http.StatusUnauthorized, response.CodeUnauthorized, "invalid token payload")
+		return
+	}
+	sampleID, err := parseUintParam(c, "id")
+	if err != nil || sampleID == 0 {
+		response.Error(c, http.StatusBadRequest, response.CodeBadRequest, "invalid sample id")
+		return
+	}
+	if err := h.samples.Delete(c.Request.Context(), userID, sampleID); err != nil {
+		writeError(c, err, "delete sample failed")
+		return
+	}
+	response.OK(c, gin.H{"deleted": true})
+}
+
+// RerunSample re-classifies a stored sample with another model and compares the outputs.
+func (h *VisionHandler) RerunSample(c *gin.Context) {
+	userID, ok := getUserIDFromContext(c)
+	if !ok {
+		response.Error(c, http.StatusUnauthorized, response.CodeUnauthorized, "invalid token payload")
+		return
+	}
+	sampleID, err := parseUintParam(c, "id")
+	if err != nil || sampleID == 0 {
+		response.Error(c, http.StatusBadRequest, response.CodeBadRequest, "invalid sample id")
+		return
+	}
+	var req RerunVisionSampleRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			response.Error(c, http.StatusBadRequest, response.CodeBadRequest, "invalid request payload")
+			return
+		}
+	}
+
+	result, err := h.samples.Rerun(c.Request.Context(), userID, sampleID, req.Model)
+	if err != nil {
+		writeUpstreamError(c, err, "rerun failed")
+		return
+	}
+	response.OK(c, result)
 }
@@ -0,0 +1,249 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"gopherai-resume/internal/app"
+	"gopherai-resume/internal/transport/http/response"
+)
+
+// WorkspaceHandler serves workspaces and their membership.
+type WorkspaceHandler struct {
+	workspaceService *app.WorkspaceService
+}
+
+func NewWorkspaceHandler(workspaceService *app.WorkspaceService) *WorkspaceHandler {
+	return &WorkspaceHandler{workspaceService: workspaceService}
+}
+
+type CreateWorkspaceRequest struct {
+	Name string `json:"name" binding:"required,max=128"`
+}
+
+type AddWorkspaceMemberRequest struct {
+	Username string `json:"username" binding:"required"`
+	Role     string `json:"role" binding:"required"`
+}
+
+type UpdateWorkspaceMemberRequest struct {
+	Role string `json:"role" binding:"required"`
+}
+
+// SetModelAllowlistRequest replaces a workspace's allowlist; an empty list lifts that
+// restriction.
+type SetModelAllowlistRequest struct {
+	Models   []string `json:"models"`
+	BaseURLs []string `json:"base_urls"`
+}
+
+func (h *WorkspaceHandler) Create(c *gin.Context) {
+	userID, ok := getUserIDFromContext(c)
+	if !ok {
+		response.Error(c, http.StatusUnauthorized, response.CodeUnauthorized, "invalid token payload")
+		return
+	}
+	var req CreateWorkspaceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, response.CodeBadRequest, "invalid request payload")
+		return
+	}
+	workspace, err := h.workspaceService.Create(c.Request.Context(), userID, req.Name)
+	if err != nil {
+		writeError(c, err, "create workspace failed")
+		return
+	}
+	response.OK(c, workspace)
+}
+
+func (h *WorkspaceHandler) List(c *gin.Context) {
+	userID, ok := getUserIDFromContext(c)
+	if !ok {
+		response.Error(c, http.StatusUnauthorized, response.CodeUnauthorized, "invalid token payload")
+		return
+	}
+	list, err := h.workspaceService.List(c.Request.Context(), userID)
+	if err != nil {
+		writeError(c, err, "list workspaces failed")
+		return
+	}
+	response.OK(c, list)
+}
+
+// Invitations lists the workspaces the user has been invited to.
+func (h *WorkspaceHandler) Invitations(c *gin.Context) {
+	userID, ok := getUserIDFromContext(c)
+	if !ok {
+		response.Error(c, http.StatusUnauthorized, response.CodeUnauthorized, "invalid token payload")
+		return
+	}
+	list, err := h.workspaceService.Invitations(c.Request.Context(), userID)
+	if err != nil {
+		writeError(c, err, "list workspace invitations failed")
+		return
+	}
+	response.OK(c, list)
+}
+
+// AcceptInvitation joins a workspace the user has been invited to. Declining is
+// DELETE /workspaces/:id/members/:user_id with the user's own ID.
+func (h *WorkspaceHandler) AcceptInvitation(c *gin.Context) {
+	userID, ok := getUserIDFromContext(c)
+	if !ok {
+		response.Error(c, http.StatusUnauthorized, response.CodeUnauthorized, "invalid token payload")
+		return
+	}
+	workspaceID, err := parseUintParam(c, "id")
+	if err != nil || workspaceID == 0 {
+		response.Error(c, http.StatusBadRequest, response.CodeBadRequest, "invalid workspace id")
+		return
+	}
+	member, err := h.workspaceService.AcceptInvitation(c.Request.Context(), userID, workspaceID)
+	if err != nil {
+		writeError(c, err, "accept workspace invitation failed")
+		return
+	}
+	response.OK(c, member)
+}
+
+func (h *WorkspaceHandler) ListMembers(c *gin.Context) {
+	userID, ok := getUserIDFromContext(c)
+	if !ok {
+		response.Error(c, http.StatusUnauthorized, response.CodeUnauthorized, "invalid token payload")
+		return
+	}
+	workspaceID, err := parseUintParam(c, "id")
+	if err != nil || workspaceID == 0 {
+		response.Error(c, http.StatusBadRequest, response.CodeBadRequest, "invalid workspace id")
+		return
+	}
+	list, err := h.workspaceService.ListMembers(c.Request.Context(), userID, workspaceID)
+	if err != nil {
+		writeError(c, err, "list workspace members failed")
+		return
+	}
+	response.OK(c, list)
+}
+
+func (h *WorkspaceHandler) AddMember(c *gin.Context) {
+	userID, ok := getUserIDFromContext(c)
+	if !ok {
+		response.Error(c, http.StatusUnauthorized, response.CodeUnauthorized, "invalid token payload")
+		return
+	}
+	workspaceID, err := parseUintParam(c, "id")
+	if err != nil || workspaceID == 0 {
+		response.Error(c, http.StatusBadRequest, response.CodeBadRequest, "invalid workspace id")
+		return
+	}
+	var req AddWorkspaceMemberRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, response.CodeBadRequest, "invalid request payload")
+		return
+	}
+	member, err := h.workspaceService.AddMember(c.Request.Context(), userID, workspaceID, req.Username, req.Role)
+	if err != nil {
+		writeError(c, err, "add workspace member failed")
+		return
+	}
+	response.OK(c, member)
+}
+
+func (h *WorkspaceHandler) UpdateMember(c *gin.Context) {
+	userID, ok := getUserIDFromContext(c)
+	if !ok {
+		response.Error(c, http.StatusUnauthorized, response.CodeUnauthorized, "invalid token payload")
+		return
+	}
+	workspaceID, err := parseUintParam(c, "id")
+	if err != nil || workspaceID == 0 {
+		response.Error(c, http.StatusBadRequest, response.CodeBadRequest, "invalid workspace id")
+		return
+	}
+	memberUserID, err := parseUintParam(c, "user_id")
+	if err != nil || memberUserID == 0 {
+		response.Error(c, http.StatusBadRequest, response.CodeBadRequest, "invalid user id")
+		return
+	}
+	var req UpdateWorkspaceMemberRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, response.CodeBadRequest, "invalid request payload")
+		return
+	}
+	member, err := h.workspaceService.UpdateMemberRole(c.Request.Context(), userID, workspaceID, memberUserID, req.Role)
+	if err != nil {
+		writeError(c, err, "update workspace member failed")
+		return
+	}
+	response.OK(c, member)
+}
+
+func (h *WorkspaceHandler) RemoveMember(c *gin.Context) {
+	userID, ok := getUserIDFromContext(c)
+	if !ok {
+		response.Error(c, http.StatusUnauthorized, response.CodeUnauthorized, "invalid token payload")
+		return
+	}
+	workspaceID, err := parseUintParam(c, "id")
+	if err != nil || workspaceID == 0 {
+		response.Error(c, http.StatusBadRequest, response.CodeBadRequest, "invalid workspace id")
+		return
+	}
+	memberUserID, err := parseUintParam(c, "user_id")
+	if err != nil || memberUserID == 0 {
+		response.Error(c, http.StatusBadRequest, response.CodeBadRequest, "invalid user id")
+		return
+	}
+	if err := h.workspaceService.RemoveMember(c.Request.Context(), userID, workspaceID, memberUserID); err != nil {
+		writeError(c, err, "remove workspace member failed")
+		return
+	}
+	response.OK(c, gin.H{"removed_user_id": memberUserID})
+}
+
+func (h *WorkspaceHandler) ModelAllowlist(c *gin.Context) {
+	userID, ok := getUserIDFromContext(c)
+	if !ok {
+		response.Error(c, http.StatusUnauthorized, response.CodeUnauthorized, "invalid token payload")
+		return
+	}
+	workspaceID, err := parseUintParam(c, "id")
+	if err != nil || workspaceID == 0 {
+		response.Error(c, http.StatusBadRequest, response.CodeBadRequest, "invalid workspace id")
+		return
+	}
+	allowlist, err := h.workspaceService.ModelAllowlist(c.Request.Context(), userID, workspaceID)
+	if err != nil {
+		writeError(c, err, "get workspace model allowlist failed")
+		return
+	}
+	response.OK(c, allowlist)
+}
+
+func (h *WorkspaceHandler) SetModelAllowlist(c *gin.Context) {
+	userID, ok := getUserIDFromContext(c)
+	if !ok {
+		response.Error(c, http.StatusUnauthorized, response.CodeUnauthorized, "invalid token payload")
+		return
+	}
+	workspaceID, err := parseUintParam(c, "id")
+	if err != nil || workspaceID == 0 {
+		response.Error(c, http.StatusBadRequest, response.CodeBadRequest, "invalid workspace id")
+		return
+	}
+	var req SetModelAllowlistRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, response.CodeBadRequest, "invalid request payload")
+		return
+	}
+	allowlist, err := h.workspaceService.SetModelAllowlist(c.Request.Context(), userID, workspaceID, app.ModelAllowlist{
+		Models:   req.Models,
+		BaseURLs: req.BaseURLs,
+	})
+	if err != nil {
+		writeError(c, err, "set workspace model allowlist failed")
+		return
+	}
+	response.OK(c, allowlist)
+}
@@ -0,0 +1,105 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"gopherai-resume/internal/app"
+	"gopherai-resume/internal/transport/http/response"
+)
+
+// CandidatePoolHandler serves a workspace's candidate pool and recruiter search.
+type CandidatePoolHandler struct {
+	poolService *app.CandidatePoolService
+}
+
+func NewCandidatePoolHandler(poolService *app.CandidatePoolService) *CandidatePoolHandler {
+	return &CandidatePoolHandler{poolService: poolService}
+}
+
+type AddCandidateRequest struct {
+	ResumeDocumentID uint `json:"resume_document_id" binding:"required"`
+}
+
+// SearchCandidatesRequest is a recruiter query, e.g. "Go developer with Kafka experience,
+// 3+ years". skills and min_years add explicit filters.
+type SearchCandidatesRequest struct {
+	Query    string   `json:"query" binding:"max=1000"`
+	Skills   []string `json:"skills" binding:"max=20"`
+	MinYears *float64 `json:"min_years" binding:"omitempty,min=0,max=60"`
+	Limit    int      `json:"limit" binding:"omitempty,min=1,max=50"`
+}
+
+func (h *CandidatePoolHandler) Add(c *gin.Context) {
+	userID, workspaceID, _, ok := jobPostingParams(c)
+	if !ok {
+		return
+	}
+	var req AddCandidateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, response.CodeBadRequest, "invalid request payload")
+		return
+	}
+	candidate, err := h.poolService.Add(c.Request.Context(), userID, workspaceID, req.ResumeDocumentID)
+	if err != nil {
+		writeError(c, err, "add candidate failed")
+		return
+	}
+	response.OK(c, candidate)
+}
+
+func (h *CandidatePoolHandler) List(c *gin.Context) {
+	userID, workspaceID, _, ok := jobPostingParams(c)
+	if !ok {
+		return
+	}
+	candidates, err := h.poolService.List(c.Request.Context(), userID, workspaceID)
+	if err != nil {
+		writeError(c, err, "list candidates failed")
+		return
+	}
+	response.OK(c, candidates)
+}
+
+func (h *CandidatePoolHandler) Remove(c *gin.Context) {
+	userID, workspaceID, _, ok := jobPostingParams(c)
+	if !ok {
+		return
+	}
+	candidateID, err := parseUintParam(c, "candidate_id")
+	if err != nil || candidateID == 0 {
+		response.Error(c, http.StatusBadRequest, response.CodeBadRequest, "invalid candidate id")
+		return
+	}
+	if err := h.poolService.Remove(c.Request.Context(), userID, workspaceID, candidateID); err != nil {
+		writeError(c, err, "remove candidate failed")
+		return
+	}
+	response.OK(c, gin.H{"removed_candidate_id": candidateID})
+}
+
+func (h *CandidatePoolHandler) Search(c *gin.Context) {
+	userID, workspaceID, _, ok := jobPostingParams(c)
+	if !ok {
+		return
+	}
+	var req SearchCandidatesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, response.CodeBadRequest, "invalid request payload")
+		return
+	}
+	result, err := h.poolService.Search(c.Request.Context(), app.CandidateSearchInput{
+		UserID:      userID,
+		WorkspaceID: workspaceID,
+		Query:       req.Query,
+		Skills:      req.Skills,
+		MinYears:    req.MinYears,
+		Limit:       req.Limit,
+	})
+	if err != nil {
+		writeError(c, err, "search candidates failed")
+		return
+	}
+	response.OK(c, result)
+}
@@ -0,0 +1,26 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"gopherai-resume/internal/pkg/authz"
+	"gopherai-resume/internal/transport/http/response"
+)
+
+// RequireAdmin allows the request only if the authenticated principal is an admin; denials
+// are recorded with authz.Record. It must run after AuthJWT.
+func RequireAdmin() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+		principal, ok := authz.From(ctx)
+		if !ok || !principal.Admin {
+			authz.Record(ctx, authz.Deny, principal.UserID, authz.Administer, "route", 0, c.FullPath())
+			response.Error(c, http.StatusForbidden, response.CodeForbidden, "admin privileges required")
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,22 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"gopherai-resume/internal/transport/http/response"
+)
+
+// RequireAvailable rejects requests with 503 while available reports false, for routes
+// whose optional dependency is disabled or down.
+func RequireAvailable(feature string, available func() bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !available() {
+			response.Error(c, http.StatusServiceUnavailable, response.CodeFeatureUnavailable, feature+" is temporarily unavailable")
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
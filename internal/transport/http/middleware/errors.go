@@ -0,0 +1,34 @@
+package middleware
+
+import (
+	"log"
+
+	"github.com/gin-gonic/gin"
+
+	"gopherai-resume/internal/pkg/apperr"
+	"gopherai-resume/internal/pkg/requestid"
+	"gopherai-resume/internal/transport/http/response"
+)
+
+// Errors answers a request whose handler added an error with c.Error and wrote nothing. An
+// *apperr.Error is answered with its status, code and message; any other error is an
+// internal error. Causes and server errors are logged with the request ID and never sent
+// to the client.
+func Errors() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+		if len(c.Errors) == 0 || c.Writer.Written() {
+			return
+		}
+		err := c.Errors.Last().Err
+		appErr, ok := apperr.As(err)
+		if !ok {
+			appErr = apperr.Internal("internal server error", err)
+		}
+		if appErr.Cause != nil || appErr.Status >= 500 {
+			log.Printf("%s %s: status=%d err=%v request_id=%s",
+				c.Request.Method, c.FullPath(), appErr.Status, err, requestid.From(c.Request.Context()))
+		}
+		response.Error(c, appErr.Status, appErr.Code, appErr.Message)
+	}
+}
@@ -1,10 +1,14 @@
 package middleware
 
 import (
+	"context"
+	"log"
 	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 
+	"gopherai-resume/internal/pkg/authz"
 	"gopherai-resume/internal/pkg/jwtutil"
 	"gopherai-resume/internal/transport/http/response"
 )
@@ -14,7 +18,56 @@ const (
 	ContextUsernameKey = "username"
 )
 
-func AuthJWT(secret string) gin.HandlerFunc {
+// TokenVersions returns a user's current token version, or ok false when the user no longer
+// exists. Tokens carrying another version have been revoked.
+type TokenVersions func(ctx context.Context, userID uint) (version uint, ok bool, err error)
+
+// RenewedTokenHeader carries a refreshed token when sliding sessions are enabled; clients
+// should replace their stored token with it.
+const RenewedTokenHeader = "X-Renewed-Token"
+
+// SessionRenewal configures sliding sessions: a valid token that expires within Window is
+// answered with a new one valid for TTL. A zero Window disables renewal. With MaxLifetime
+// set, no token is renewed past that long after the user signed in, and renewed tokens
+// expire no later than that.
+type SessionRenewal struct {
+	Window      time.Duration
+	TTL         time.Duration
+	MaxLifetime time.Duration
+}
+
+// renewUntil is when a token renewed now for claims should expire, or false when the
+// session has reached its maximum lifetime.
+func (r SessionRenewal) renewUntil(claims *jwtutil.Claims, now time.Time) (time.Time, bool) {
+	expiresAt := now.Add(r.TTL)
+	if r.MaxLifetime <= 0 {
+		return expiresAt, true
+	}
+	sessionEnd := claims.SessionStart().Add(r.MaxLifetime)
+	if !sessionEnd.After(claims.ExpiresAt.Time) {
+		return time.Time{}, false
+	}
+	if expiresAt.After(sessionEnd) {
+		expiresAt = sessionEnd
+	}
+	return expiresAt, true
+}
+
+func AuthJWT(secret string, versions TokenVersions) gin.HandlerFunc {
+	return AuthJWTWithRenewal(secret, versions, SessionRenewal{}, nil)
+}
+
+// AuthJWTWithRenewal is AuthJWT with sliding expiration. Only a token that passed every
+// check is renewed, and the new one carries the same identity, so anything that would
+// reject the old token is applied again before the next renewal. Tokens whose version
+// differs from the user's, e.g. issued before a password reset, are rejected and never
+// renewed. Usernames in admins are marked as admins in the authz principal put in the
+// request context.
+func AuthJWTWithRenewal(secret string, versions TokenVersions, renewal SessionRenewal, admins []string) gin.HandlerFunc {
+	isAdmin := make(map[string]bool, len(admins))
+	for _, name := range admins {
+		isAdmin[name] = true
+	}
 	return func(c *gin.Context) {
 		authHeader := strings.TrimSpace(c.GetHeader("Authorization"))
 		if authHeader == "" {
@@ -37,9 +90,38 @@ func AuthJWT(secret string) gin.HandlerFunc {
 			c.Abort()
 			return
 		}
+		version, ok, err := versions(c.Request.Context(), claims.UserID)
+		if err != nil {
+			log.Printf("look up token version of user %d failed: %v", claims.UserID, err)
+			response.Error(c, 500, response.CodeInternalServer, "internal server error")
+			c.Abort()
+			return
+		}
+		if !ok || version != claims.TokenVersion {
+			response.Error(c, 401, response.CodeUnauthorized, "token has been revoked")
+			c.Abort()
+			return
+		}
 
 		c.Set(ContextUserIDKey, claims.UserID)
 		c.Set(ContextUsernameKey, claims.Username)
+		c.Request = c.Request.WithContext(authz.With(c.Request.Context(), authz.Principal{
+			UserID:   claims.UserID,
+			Username: claims.Username,
+			Admin:    isAdmin[claims.Username],
+		}))
+		if renewal.Window > 0 && claims.ExpiresAt != nil && time.Until(claims.ExpiresAt.Time) <= renewal.Window {
+			// A session past its maximum lifetime keeps its current token until it expires.
+			if expiresAt, ok := renewal.renewUntil(claims, time.Now()); ok {
+				renewed, err := jwtutil.RenewToken(secret, claims, expiresAt)
+				if err != nil {
+					// The current token is still valid, so the request goes ahead without renewal.
+					log.Printf("renew token for user %d failed: %v", claims.UserID, err)
+				} else {
+					c.Header(RenewedTokenHeader, renewed)
+				}
+			}
+		}
 		c.Next()
 	}
 }
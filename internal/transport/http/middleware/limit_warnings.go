@@ -0,0 +1,16 @@
+package middleware
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"gopherai-resume/internal/pkg/limitwarn"
+)
+
+// LimitWarnings collects the limitwarn warnings raised while the request is served, which
+// response.OK and response.Error add to the envelope.
+func LimitWarnings() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Request = c.Request.WithContext(limitwarn.With(c.Request.Context()))
+		c.Next()
+	}
+}
@@ -0,0 +1,161 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"gopherai-resume/internal/pkg/apperr"
+	"gopherai-resume/internal/pkg/limitwarn"
+	"gopherai-resume/internal/transport/http/response"
+)
+
+// RateLimiter counts requests per key in fixed windows. Allow also returns the count in the
+// current window.
+type RateLimiter interface {
+	Allow(ctx context.Context, key string, limit int, window time.Duration) (bool, int, time.Duration, error)
+}
+
+// RateLimit allows each user limit requests to the route per window and answers 429 with
+// Retry-After beyond that; requests close to the limit get a limitwarn warning. It must run
+// after AuthJWT. A limit of 0 disables it, and a limiter error lets the request through so a
+// Redis outage does not take the route down.
+func RateLimit(name string, limiter RateLimiter, limit int, window time.Duration) gin.HandlerFunc {
+	return rateLimit(name, limiter, limit, window, func(c *gin.Context) string {
+		return strconv.FormatUint(uint64(c.GetUint(ContextUserIDKey)), 10)
+	})
+}
+
+// RateLimitByIP is RateLimit keyed by the client IP, for routes that run before anyone is
+// authenticated, such as requesting a password reset.
+func RateLimitByIP(name string, limiter RateLimiter, limit int, window time.Duration) gin.HandlerFunc {
+	return rateLimit(name, limiter, limit, window, func(c *gin.Context) string {
+		return "ip:" + c.ClientIP()
+	})
+}
+
+// rateLimit counts requests under name and the key of the caller, a user ID as
+// TakeRateLimit counts them or "ip:" and an address.
+func rateLimit(name string, limiter RateLimiter, limit int, window time.Duration, key func(c *gin.Context) string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if limit <= 0 || limiter == nil {
+			c.Next()
+			return
+		}
+		caller := key(c)
+		allowed, count, retryAfter, err := limiter.Allow(c.Request.Context(), name+":"+caller, limit, window)
+		if err != nil {
+			log.Printf("rate limit %s for caller %s failed, allowing request: %v", name, caller, err)
+			c.Next()
+			return
+		}
+		if !allowed {
+			c.Header("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+			response.Error(c, http.StatusTooManyRequests, response.CodeRateLimited, "too many requests, retry later")
+			c.Abort()
+			return
+		}
+		limitwarn.Check(c.Request.Context(), limitwarn.KindRateLimit, name, int64(count), int64(limit))
+		c.Next()
+	}
+}
+
+// ErrRateLimited is returned by TakeRateLimit beyond the limit.
+var ErrRateLimited = apperr.New(http.StatusTooManyRequests, apperr.CodeRateLimited, "too many requests, retry later")
+
+// TakeRateLimit counts one request against userID's limit on name like RateLimit, for
+// handlers that count the units of work within a request, such as the questions of a batch
+// ask. It returns ErrRateLimited beyond the limit and lets the work through when limit is 0
+// or the limiter fails.
+func TakeRateLimit(ctx context.Context, name string, limiter RateLimiter, limit int, window time.Duration, userID uint) error {
+	if limit <= 0 || limiter == nil {
+		return nil
+	}
+	allowed, count, _, err := limiter.Allow(ctx, fmt.Sprintf("%s:%d", name, userID), limit, window)
+	if err != nil {
+		log.Printf("rate limit %s for user %d failed, allowing request: %v", name, userID, err)
+		return nil
+	}
+	if !allowed {
+		return ErrRateLimited
+	}
+	limitwarn.Check(ctx, limitwarn.KindRateLimit, name, int64(count), int64(limit))
+	return nil
+}
+
+// ConcurrencyLimiter caps how many requests each user has in flight on the routes it
+// guards, so one client cannot hold all embedding or inference capacity. Counts are kept per
+// server instance.
+type ConcurrencyLimiter struct {
+	max int
+
+	mu       sync.Mutex
+	inFlight map[uint]int
+}
+
+// NewConcurrencyLimiter allows max requests per user at once; 0 disables the cap.
+func NewConcurrencyLimiter(max int) *ConcurrencyLimiter {
+	return &ConcurrencyLimiter{max: max, inFlight: make(map[uint]int)}
+}
+
+// Handler rejects a request with 429 while the user already has max requests running. It
+// must run after AuthJWT.
+func (l *ConcurrencyLimiter) Handler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if l.max <= 0 {
+			c.Next()
+			return
+		}
+		userID := c.GetUint(ContextUserIDKey)
+		if !l.acquire(userID) {
+			response.Error(c, http.StatusTooManyRequests, response.CodeTooManyInFlight, "too many requests in progress")
+			c.Abort()
+			return
+		}
+		defer l.release(userID)
+		c.Next()
+	}
+}
+
+// ErrTooManyInFlight is returned by Acquire while the user already has max requests running.
+var ErrTooManyInFlight = apperr.New(http.StatusTooManyRequests, apperr.CodeTooManyInFlight, "too many requests in progress")
+
+// Acquire takes one of userID's slots like Handler, for work that does not arrive as its
+// own HTTP request, such as a message sent over a WebSocket. The returned func gives the
+// slot back.
+func (l *ConcurrencyLimiter) Acquire(userID uint) (func(), error) {
+	if l.max <= 0 {
+		return func() {}, nil
+	}
+	if !l.acquire(userID) {
+		return nil, ErrTooManyInFlight
+	}
+	return func() { l.release(userID) }, nil
+}
+
+func (l *ConcurrencyLimiter) acquire(userID uint) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.inFlight[userID] >= l.max {
+		return false
+	}
+	l.inFlight[userID]++
+	return true
+}
+
+func (l *ConcurrencyLimiter) release(userID uint) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.inFlight[userID] <= 1 {
+		delete(l.inFlight, userID)
+		return
+	}
+	l.inFlight[userID]--
+}
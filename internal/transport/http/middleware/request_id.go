@@ -0,0 +1,36 @@
+package middleware
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"gopherai-resume/internal/pkg/requestid"
+)
+
+// maxRequestIDLen bounds client-supplied request IDs; longer or non-printable ones are replaced.
+const maxRequestIDLen = 128
+
+// RequestID keeps the client's X-Request-ID, or assigns a new one, echoes it in the response
+// and puts it in the request context so outbound LLM calls can forward it.
+func RequestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(requestid.Header)
+		if !validRequestID(id) {
+			id = requestid.New()
+		}
+		c.Header(requestid.Header, id)
+		c.Request = c.Request.WithContext(requestid.With(c.Request.Context(), id))
+		c.Next()
+	}
+}
+
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLen {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] < 0x21 || id[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
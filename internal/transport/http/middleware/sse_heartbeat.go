@@ -0,0 +1,93 @@
+package middleware
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"gopherai-resume/internal/pkg/opsflags"
+)
+
+// heartbeatCheck is how often a stream is checked for silence.
+const heartbeatCheck = time.Second
+
+// SSEHeartbeat keeps server-sent event streams open through proxies that close idle
+// connections. Once the handler has started an event stream, a ": keep-alive" comment is
+// written whenever the stream has been silent for the sse_heartbeat_seconds ops flag, which
+// is read on every check so changes apply to streams already open. Nothing is written before
+// the handler's own first write, so errors answered as JSON are unaffected.
+func SSEHeartbeat() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		w := &heartbeatWriter{ResponseWriter: c.Writer, lastWrite: time.Now()}
+		c.Writer = w
+		stop := make(chan struct{})
+		var wg sync.WaitGroup
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ticker := time.NewTicker(heartbeatCheck)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-stop:
+					return
+				case <-ticker.C:
+					w.beat(opsflags.Get().SSEHeartbeat())
+				}
+			}
+		}()
+		defer func() {
+			close(stop)
+			wg.Wait()
+		}()
+		c.Next()
+	}
+}
+
+// heartbeatWriter serializes the handler's writes with the heartbeats.
+type heartbeatWriter struct {
+	gin.ResponseWriter
+
+	mu        sync.Mutex
+	lastWrite time.Time
+}
+
+func (w *heartbeatWriter) Write(data []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.lastWrite = time.Now()
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *heartbeatWriter) WriteString(s string) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.lastWrite = time.Now()
+	return w.ResponseWriter.WriteString(s)
+}
+
+func (w *heartbeatWriter) Flush() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.ResponseWriter.Flush()
+}
+
+// beat writes a comment if an event stream has been silent for interval; 0 disables it.
+func (w *heartbeatWriter) beat(interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if !w.ResponseWriter.Written() || time.Since(w.lastWrite) < interval ||
+		!strings.HasPrefix(w.ResponseWriter.Header().Get("Content-Type"), "text/event-stream") {
+		return
+	}
+	if _, err := w.ResponseWriter.WriteString(": keep-alive\n\n"); err != nil {
+		return
+	}
+	w.ResponseWriter.Flush()
+	w.lastWrite = time.Now()
+}
@@ -1,35 +1,82 @@
 package response
 
-import "github.com/gin-gonic/gin"
+import (
+	"github.com/gin-gonic/gin"
 
+	"gopherai-resume/internal/pkg/apperr"
+	"gopherai-resume/internal/pkg/limitwarn"
+)
+
+// API error codes; see package apperr.
 const (
-	CodeOK                 = 0
-	CodeBadRequest         = 40000
-	CodeUnauthorized       = 40100
-	CodeInternalServer     = 50000
-	CodeUsernameExists     = 40001
-	CodeEmailExists        = 40002
-	CodeInvalidCredentials = 40101
-	CodeSessionNotFound    = 40401
+	CodeOK                  = apperr.CodeOK
+	CodeBadRequest          = apperr.CodeBadRequest
+	CodeUnauthorized        = apperr.CodeUnauthorized
+	CodeForbidden           = apperr.CodeForbidden
+	CodeInternalServer      = apperr.CodeInternalServer
+	CodeFeatureUnavailable  = apperr.CodeFeatureUnavailable
+	CodeProviderBusy        = apperr.CodeProviderBusy
+	CodeUsernameExists      = apperr.CodeUsernameExists
+	CodeEmailExists         = apperr.CodeEmailExists
+	CodeInvalidCredentials  = apperr.CodeInvalidCredentials
+	CodeRouteNotFound       = apperr.CodeRouteNotFound
+	CodeSessionNotFound     = apperr.CodeSessionNotFound
+	CodeQuotaExceeded       = apperr.CodeQuotaExceeded
+	CodeRateLimited         = apperr.CodeRateLimited
+	CodeTooManyInFlight     = apperr.CodeTooManyInFlight
+	CodeDocumentNotFound    = apperr.CodeDocumentNotFound
+	CodeSampleNotFound      = apperr.CodeSampleNotFound
+	CodeStreamNotFound      = apperr.CodeStreamNotFound
+	CodeApplicationNotFound = apperr.CodeApplicationNotFound
+	CodeMessageNotFound     = apperr.CodeMessageNotFound
+	CodeBulletNotFound      = apperr.CodeBulletNotFound
+	CodePortfolioNotFound   = apperr.CodePortfolioNotFound
+	CodeRepoNotFound        = apperr.CodeRepoNotFound
+	CodeWorkspaceNotFound   = apperr.CodeWorkspaceNotFound
+	CodeJobPostingNotFound  = apperr.CodeJobPostingNotFound
+	CodeUserNotFound        = apperr.CodeUserNotFound
+	CodeReportNotFound      = apperr.CodeReportNotFound
+	CodeSchemaNotFound      = apperr.CodeSchemaNotFound
+	CodeCandidateNotFound   = apperr.CodeCandidateNotFound
+	CodeShareNotFound       = apperr.CodeShareNotFound
+	CodeScheduledNotFound   = apperr.CodeScheduledNotFound
+	CodeExportNotFound      = apperr.CodeExportNotFound
+	CodeInvalidTransition   = apperr.CodeInvalidTransition
+	CodeBulletConflict      = apperr.CodeBulletConflict
+	CodeMemberExists        = apperr.CodeMemberExists
+	CodeReportNotReady      = apperr.CodeReportNotReady
+	CodeCandidateExists     = apperr.CodeCandidateExists
+	CodeSessionHasForks     = apperr.CodeSessionHasForks
+	CodeScheduleNotPending  = apperr.CodeScheduleNotPending
+	CodeScheduleLimit       = apperr.CodeScheduleLimit
+	CodeSessionBusy         = apperr.CodeSessionBusy
+	CodeExportNotReady      = apperr.CodeExportNotReady
+	CodeDocumentNotReady    = apperr.CodeDocumentNotReady
+	CodeExportInProgress    = apperr.CodeExportInProgress
+	CodeMessageTooLong      = apperr.CodeMessageTooLong
 )
 
 type APIResponse struct {
 	Code    int         `json:"code"`
 	Message string      `json:"message"`
 	Data    interface{} `json:"data,omitempty"`
+	// Warnings lists the quotas and rate limits the request has nearly used up.
+	Warnings []limitwarn.Warning `json:"warnings,omitempty"`
 }
 
 func OK(c *gin.Context, data interface{}) {
 	c.JSON(200, APIResponse{
-		Code:    CodeOK,
-		Message: "ok",
-		Data:    data,
+		Code:     CodeOK,
+		Message:  "ok",
+		Data:     data,
+		Warnings: limitwarn.From(c.Request.Context()),
 	})
 }
 
 func Error(c *gin.Context, httpStatus, code int, message string) {
 	c.JSON(httpStatus, APIResponse{
-		Code:    code,
-		Message: message,
+		Code:     code,
+		Message:  message,
+		Warnings: limitwarn.From(c.Request.Context()),
 	})
 }
@@ -1,6 +1,8 @@
 package http
 
 import (
+	"context"
+	"log"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -9,120 +11,695 @@ import (
 	appsvc "gopherai-resume/internal/app"
 	"gopherai-resume/internal/bootstrap"
 	"gopherai-resume/internal/cache"
+	"gopherai-resume/internal/config"
+	"gopherai-resume/internal/pkg/opsflags"
+	"gopherai-resume/internal/platform/github"
 	rabbitmqPlatform "gopherai-resume/internal/platform/rabbitmq"
-	"gopherai-resume/internal/repository"
 	"gopherai-resume/internal/transport/http/handler"
 	"gopherai-resume/internal/transport/http/middleware"
+	"gopherai-resume/internal/transport/ws"
 	"gopherai-resume/internal/vision"
+	"gopherai-resume/internal/worker"
 )
 
 func NewRouter(app *bootstrap.App) *gin.Engine {
 	gin.SetMode(app.Config.App.GinMode)
 	router := gin.New()
-	router.Use(gin.Logger(), gin.Recovery())
+	// The access log is an info-level line, so it stops when the log_level ops flag is warn.
+	accessLog := gin.LoggerWithConfig(gin.LoggerConfig{Skip: func(*gin.Context) bool {
+		return !opsflags.Get().Enabled(opsflags.LevelInfo)
+	}})
+	router.Use(middleware.RequestID(), accessLog, gin.Recovery(), middleware.LimitWarnings(), middleware.Errors())
 
 	healthHandler := handler.NewHealthHandler(app)
-	router.StaticFile("/", "web/index.html")
-	router.StaticFile("/login", "web/login.html")
-	router.StaticFile("/register", "web/register.html")
-	router.StaticFile("/app", "web/app.html")
-	router.StaticFile("/chat", "web/chat.html")
-	router.StaticFile("/rag", "web/rag.html")
-	router.StaticFile("/vision", "web/vision.html")
+	registerStatic(router, app.Config.Web)
 	router.GET("/healthz", healthHandler.Check)
+	router.GET("/readyz", healthHandler.Ready)
 
-	userRepo := repository.NewUserRepository(app.MySQL)
-	sessionRepo := repository.NewSessionRepository(app.MySQL)
-	messageRepo := repository.NewMessageRepository(app.MySQL)
+	userRepo := app.Repos.Users
+	sessionRepo := app.Repos.Sessions
+	messageRepo := app.Repos.Messages
+	// The hub pushes notifications to open chat WebSockets.
+	wsHub := ws.NewHub()
+	notificationService := appsvc.NewNotificationService(
+		app.Repos.Notifications,
+		userRepo,
+		app.Mailer,
+		app.Config.Mail.AppBaseURL,
+		app.Config.Mail.OutboxMaxAttempts,
+		wsHub,
+	)
+	activityService := appsvc.NewActivityService(
+		app.Repos.Activity,
+		time.Duration(app.Config.App.ActivityRetentionDays)*24*time.Hour,
+	)
+	if app.ActivityPruneWorker != nil {
+		app.ActivityPruneWorker.Start(context.Background(), activityService)
+	}
 	authService := appsvc.NewAuthService(
 		userRepo,
+		app.Repos.UserTokens,
+		notificationService,
 		app.Config.Auth.JWTSecret,
 		time.Duration(app.Config.Auth.JWTExpireMinute)*time.Minute,
+		time.Duration(app.Config.Mail.PasswordResetMinutes)*time.Minute,
+		time.Duration(app.Config.Mail.VerifyEmailHours)*time.Hour,
+	)
+	// Without a broker connection chat messages are stored directly instead of queued. Serialized
+	// sends store them directly too, so the session lock covers the write of the turn.
+	messageStore := worker.NewRetryingMessageStore(messageRepo, app.WriteRetry)
+	var embedQueue worker.MessagePublisher
+	if !app.Config.App.LiteMode && app.MQ != nil && app.Config.RabbitMQ.MessageEmbedQueue != "" {
+		embedQueue = rabbitmqPlatform.NewMessagePublisher(app.MQ, app.Config.RabbitMQ.MessageEmbedQueue)
+	}
+	var messagePublisher appsvc.AsyncMessagePublisher
+	if app.Config.App.LiteMode {
+		messagePublisher = worker.NewDirectPublisher(messageStore)
+	} else if app.Config.LLM.SerializeSends {
+		messagePublisher = worker.NewEmbeddingDirectPublisher(messageStore, embedQueue)
+	} else {
+		var persistQueue worker.MessagePublisher
+		if app.MQ != nil {
+			persistQueue = rabbitmqPlatform.NewMessagePublisher(app.MQ, app.Config.RabbitMQ.MessagePersistQueue)
+		}
+		messagePublisher = worker.NewFallbackPublisher(persistQueue, messageStore)
+	}
+	// With Redis disabled the caches stay nil interfaces, which the services treat as off,
+	// and quotas go unenforced. A running client that loses Redis just misses the cache.
+	var historyCache appsvc.HistoryCache
+	var suggestionCache appsvc.SuggestionCache
+	var embeddingCache appsvc.EmbeddingCache
+	var usageCounter appsvc.UsageCounter
+	var draftStore appsvc.DraftStore
+	var rateLimiter middleware.RateLimiter = cache.NewMemoryRateLimiter()
+	var visionCache vision.ResultCache
+	if app.Config.App.LiteMode {
+		historyCache = cache.NewMemoryHistoryCache(
+			time.Duration(app.Config.Redis.HistoryTTLSeconds)*time.Second,
+			time.Duration(app.Config.Redis.HistoryDirtyTTLSeconds)*time.Second,
+		)
+	}
+	if app.Redis != nil {
+		historyCache = cache.NewHistoryCache(
+			app.Redis,
+			time.Duration(app.Config.Redis.HistoryTTLSeconds)*time.Second,
+			time.Duration(app.Config.Redis.HistoryDirtyTTLSeconds)*time.Second,
+		)
+		suggestionCache = cache.NewSuggestionCache(
+			app.Redis,
+			time.Duration(app.Config.Redis.SuggestionTTLSeconds)*time.Second,
+		)
+		embeddingCache = cache.NewEmbeddingCache(app.Redis, 0)
+		usageCounter = cache.NewUsageCounter(app.Redis)
+		rateLimiter = cache.NewRateLimiter(app.Redis)
+		draftStore = cache.NewDraftCache(app.Redis, time.Duration(app.Config.Redis.DraftTTLSeconds)*time.Second)
+		visionCache = cache.NewVisionCache(app.Redis, time.Duration(app.Config.Redis.VisionCacheTTLSeconds)*time.Second)
+	}
+	visionModels := vision.NewRegistry(app.Config.Vision.DefaultModel)
+	visionModels.Register(app.Config.Vision.DefaultModel, vision.NewClassifier(
+		app.Config.Vision.ModelPath,
+		app.Config.Vision.LabelsPath,
+		app.Config.Vision.ONNXSharedLibPath,
+		app.Config.Vision.TopK,
+		app.Config.Vision.SessionPoolSize,
+	))
+	for _, m := range app.Config.Vision.Models {
+		visionModels.Register(m.Name, vision.NewClassifier(
+			m.ModelPath,
+			m.LabelsPath,
+			app.Config.Vision.ONNXSharedLibPath,
+			app.Config.Vision.TopK,
+			app.Config.Vision.SessionPoolSize,
+		))
+	}
+	llmClient := app.LLMClient
+	ocrConfig := ai.ChatConfig{
+		BaseURL: app.Config.LLM.BaseURL,
+		APIKey:  app.Config.LLM.APIKey,
+		Model:   app.Config.LLM.OCRModel,
+		Headers: app.ProviderHeaders,
+	}
+	embedder := app.Embedder
+	embConfig := app.EmbeddingConfig
+	messageEmbRepo := app.Repos.MessageEmbeddings
+	chatConfig := ai.ChatConfig{
+		BaseURL: app.Config.LLM.BaseURL,
+		APIKey:  app.Config.LLM.APIKey,
+		Model:   app.Config.LLM.Model,
+		Headers: app.ProviderHeaders,
+	}
+	ragSessionRepo := app.Repos.RAGSessions
+	ragDocRepo := app.Repos.RAGDocuments
+	ragChunkRepo := app.Repos.RAGChunks
+	var ingestQueue appsvc.RAGIngestQueue
+	if app.IngestPublisher != nil {
+		ingestQueue = app.IngestPublisher
+	}
+	workspaceService := appsvc.NewWorkspaceService(
+		app.Repos.Workspaces,
+		userRepo,
+	)
+	var reranker ai.Reranker
+	if app.Config.RAG.RerankModel != "" {
+		reranker = llmClient
+	}
+	// Each question of a batch ask takes an in-flight slot, so a batch never runs more
+	// questions at once than a user may have requests in flight.
+	inFlight := middleware.NewConcurrencyLimiter(app.Config.RateLimit.MaxConcurrentPerUser)
+	batchConcurrency := app.Config.RAG.BatchConcurrency
+	if limit := app.Config.RateLimit.MaxConcurrentPerUser; limit > 0 && batchConcurrency > limit {
+		batchConcurrency = limit
+	}
+	quotaService := appsvc.NewQuotaService(
+		usageCounter,
+		map[string]int64{
+			appsvc.QuotaEmbeddingInputs:  int64(app.Config.Quota.EmbeddingInputsPerDay),
+			appsvc.QuotaVisionInferences: int64(app.Config.Quota.VisionInferencesPerDay),
+			appsvc.QuotaVisionPixels:     int64(app.Config.Quota.VisionMegapixelsPerDay) * 1_000_000,
+			appsvc.QuotaProxyTokens:      int64(app.Config.Quota.ProxyTokensPerDay),
+		},
+	)
+	ragService := appsvc.NewRAGService(
+		ragSessionRepo,
+		ragDocRepo,
+		ragChunkRepo,
+		app.Repos.RAGMessages,
+		messageEmbRepo,
+		app.VectorStore,
+		embedder,
+		llmClient,
+		llmClient,
+		embConfig,
+		chatConfig,
+		ocrConfig,
+		suggestionCache,
+		notificationService,
+		activityService,
+		app.Config.RAG.EmbeddingFormat == config.EmbeddingFormatInt8,
+		reranker,
+		ai.RerankConfig{
+			URL:     app.Config.RAG.RerankURL,
+			APIKey:  app.Config.LLM.APIKey,
+			Model:   app.Config.RAG.RerankModel,
+			Headers: app.ProviderHeaders,
+		},
+		app.Config.RAG.RerankCandidates,
+		app.Config.RAG.MMRLambda,
+		app.Config.RAG.CitationMarkers,
+		app.Config.RAG.MultiQuery,
+		app.Config.RAG.BatchMaxQuestions,
+		batchConcurrency,
+		appsvc.RAGStorageLimits{
+			Documents: app.Config.RAG.MaxDocumentsPerUser,
+			Chunks:    app.Config.RAG.MaxChunksPerUser,
+			Bytes:     int64(app.Config.RAG.MaxBytesPerUser),
+		},
+		app.Config.RAG.DedupeChunks,
+		app.Config.RAG.NearDuplicateDistance,
+		app.Repos.RAGShadowEmbeddings,
+		llmClient,
+		ai.EmbeddingConfig{
+			BaseURL: app.Config.LLM.BaseURL,
+			APIKey:  app.Config.LLM.APIKey,
+			Model:   app.Config.RAG.ShadowEmbeddingModel,
+			Headers: app.ProviderHeaders,
+		},
+		app.ObjectStore,
+		ingestQueue,
+		app.Repos.RAGIngestEmbeddings,
+		workspaceService,
+		quotaService,
+		func(ctx context.Context, userID uint) error {
+			return middleware.TakeRateLimit(ctx, "rag_ask", rateLimiter, app.Config.RateLimit.RAGAskPerMinute, time.Minute, userID)
+		},
 	)
-	messagePublisher := rabbitmqPlatform.NewMessagePublisher(
-		app.MQConn,
-		app.Config.RabbitMQ.MessagePersistQueue,
+	if app.IngestWorker != nil {
+		if err := app.IngestWorker.Start(context.Background(), ragService); err != nil {
+			log.Printf("start rag ingest worker failed, queued ingests wait for the next start: %v", err)
+		}
+	}
+	if n, err := ragService.ResumeIngests(context.Background()); err != nil {
+		log.Printf("resume interrupted rag ingests failed: %v", err)
+	} else if n > 0 {
+		log.Printf("resumed %d interrupted rag ingests", n)
+	}
+	if n, err := ragService.SweepTemporaryDocuments(context.Background()); err != nil {
+		log.Printf("sweep temporary rag documents failed: %v", err)
+	} else if n > 0 {
+		log.Printf("deleted %d left-over temporary rag documents", n)
+	}
+	var jobLocks worker.JobLocker
+	if app.Redis != nil {
+		jobLocks = cache.NewJobLock(app.Redis)
+	}
+	if app.ShadowWorker != nil {
+		app.ShadowWorker.Start(context.Background(), ragService, jobLocks)
+	}
+	visionSamples := appsvc.NewVisionSampleService(
+		app.Repos.VisionSamples,
+		app.ObjectStore,
+		visionModels,
+		quotaService,
 	)
-	historyCache := cache.NewHistoryCache(
-		app.Redis,
-		time.Duration(app.Config.Redis.HistoryTTLSeconds)*time.Second,
-		time.Duration(app.Config.Redis.HistoryDirtyTTLSeconds)*time.Second,
+	visionService := appsvc.NewVisionService(
+		visionModels,
+		visionCache,
+		visionSamples,
+		quotaService,
+		vision.NewPhotoQualityChecker(),
+		activityService,
 	)
+	chatTools := []appsvc.ChatTool{appsvc.NewOCRImageTool(llmClient, ocrConfig)}
+	if app.Config.Vision.Enabled {
+		chatTools = append([]appsvc.ChatTool{appsvc.NewClassifyImageTool(visionService)}, chatTools...)
+	}
+	var messageOverflow appsvc.MessageOverflow
+	if app.Config.LLM.OverflowToRAG {
+		messageOverflow = ragService
+	}
+	var sessionLocks appsvc.SessionLocker
+	if app.Config.LLM.SerializeSends {
+		if app.Redis != nil {
+			sessionLocks = cache.NewSessionLock(app.Redis)
+		} else {
+			sessionLocks = cache.NewMemorySessionLock()
+		}
+	}
 	chatService := appsvc.NewChatService(
 		sessionRepo,
 		messageRepo,
 		messagePublisher,
+		embedQueue,
 		historyCache,
-		ai.ChatConfig{
-			BaseURL: app.Config.LLM.BaseURL,
-			APIKey:  app.Config.LLM.APIKey,
-			Model:   app.Config.LLM.Model,
-		},
+		llmClient,
+		llmClient,
+		chatTools,
+		ragService,
+		chatConfig,
 		app.Config.LLM.MaxContextMessage,
+		app.Config.LLM.MaxContextTokens,
+		app.Config.LLM.MaxMessageChars,
+		messageOverflow,
+		sessionLocks,
+		time.Duration(app.Config.LLM.SendLockWaitMs)*time.Millisecond,
+		activityService,
+		workspaceService,
 	)
-	authHandler := handler.NewAuthHandler(authService)
-	chatHandler := handler.NewChatHandler(chatService)
-
-	embConfig := ai.EmbeddingConfig{
-		BaseURL: app.Config.LLM.BaseURL,
-		APIKey:  app.Config.LLM.APIKey,
-		Model:   app.Config.LLM.EmbeddingModel,
+	chatScheduleService := appsvc.NewChatScheduleService(app.Repos.ScheduledMessages, sessionRepo, chatService)
+	if app.ScheduleWorker != nil {
+		app.ScheduleWorker.Start(context.Background(), chatScheduleService)
 	}
-	chatConfig := ai.ChatConfig{
-		BaseURL: app.Config.LLM.BaseURL,
-		APIKey:  app.Config.LLM.APIKey,
-		Model:   app.Config.LLM.Model,
+	if app.SummaryWorker != nil {
+		app.SummaryWorker.Start(context.Background(), chatService, jobLocks)
 	}
-	ragSessionRepo := repository.NewRAGSessionRepository(app.MySQL)
-	ragDocRepo := repository.NewRAGDocumentRepository(app.MySQL)
-	ragChunkRepo := repository.NewRAGChunkRepository(app.MySQL)
-	ragService := appsvc.NewRAGService(
+	chatScheduleHandler := handler.NewChatScheduleHandler(chatScheduleService)
+	chatDraftHandler := handler.NewChatDraftHandler(appsvc.NewChatDraftService(draftStore, sessionRepo))
+	authHandler := handler.NewAuthHandler(authService)
+	dataExportHandler := handler.NewDataExportHandler(appsvc.NewDataExportService(
+		app.Repos.DataExports,
+		app.Repos.Users,
+		sessionRepo,
+		messageRepo,
 		ragSessionRepo,
 		ragDocRepo,
 		ragChunkRepo,
-		ai.NewOpenAICompatibleClient(),
-		embConfig,
+		app.Repos.RAGMessages,
+		app.Repos.VisionSamples,
+		app.Repos.Applications,
+		app.Repos.ResumeProfiles,
+		app.Repos.ResumeBullets,
+		app.Repos.PortfolioAnalyses,
+		app.Repos.Workspaces,
+		app.Repos.WorkspaceCandidates,
+		app.Repos.JobPostings,
+		app.Repos.ScreeningReports,
+		app.Repos.ScheduledMessages,
+		app.Repos.Notifications,
+		draftStore,
+		app.ObjectStore,
+	))
+	notificationHandler := handler.NewNotificationHandler(notificationService)
+	activityHandler := handler.NewActivityHandler(activityService)
+	modelCompareHandler := handler.NewModelCompareHandler(appsvc.NewModelCompareService(
+		llmClient,
+		chatConfig,
+		app.Config.LLM.CompareModels,
+		workspaceService,
+		app.Config.LLM.MaxMessageChars,
+	))
+	chatHandler := handler.NewChatHandler(
+		chatService,
+		appsvc.NewChatSearchService(messageEmbRepo, embedder, embConfig),
+	)
+	sessionShareHandler := handler.NewSessionShareHandler(appsvc.NewSessionShareService(
+		app.Repos.SessionShares,
+		sessionRepo,
+		messageRepo,
+	))
+	llmPrices := make(map[string]appsvc.ModelPrice, len(app.Config.LLM.Prices))
+	for name, price := range app.Config.LLM.Prices {
+		llmPrices[name] = appsvc.ModelPrice{
+			PromptPerMillion:     price.PromptPerMillion,
+			CompletionPerMillion: price.CompletionPerMillion,
+		}
+	}
+	chatUsageHandler := handler.NewChatUsageHandler(appsvc.NewChatUsageService(messageRepo, llmPrices, app.Config.LLM.PriceCurrency))
+	ragHandler := handler.NewRAGHandler(ragService,
+		func(ctx context.Context, userID uint) (func(), error) {
+			if err := middleware.TakeRateLimit(ctx, "rag_ask", rateLimiter, app.Config.RateLimit.RAGAskPerMinute, time.Minute, userID); err != nil {
+				return nil, err
+			}
+			return inFlight.Acquire(userID)
+		},
+		func(_ context.Context, userID uint) (func(), error) {
+			return inFlight.Acquire(userID)
+		})
+	resumeBulletHandler := handler.NewResumeBulletHandler(appsvc.NewResumeBulletService(
+		app.Repos.ResumeBullets,
+		ragService,
+		chatService,
+		llmClient,
+		chatConfig,
+		workspaceService,
+	))
+	negotiationHandler := handler.NewNegotiationHandler(appsvc.NewNegotiationService(chatService, ragDocRepo, ragChunkRepo))
+	usageHandler := handler.NewUsageHandler(quotaService)
+	proxyHandler := handler.NewProxyHandler(appsvc.NewChatProxyService(
+		llmClient,
+		chatConfig,
+		app.Config.LLM.ProxyModels,
+		quotaService,
+		app.Config.LLM.ProxyLogPrompts,
+		workspaceService,
+	))
+	applicationRepo := app.Repos.Applications
+	applicationService := appsvc.NewApplicationService(
+		applicationRepo,
+		ragDocRepo,
+		llmClient,
+		chatConfig,
+		workspaceService,
+		notificationService,
+		activityService,
+	)
+	if app.NotificationWorker != nil {
+		app.NotificationWorker.Start(context.Background(), applicationService, notificationService)
+	}
+	applicationHandler := handler.NewApplicationHandler(applicationService)
+	interviewHandler := handler.NewInterviewHandler(appsvc.NewInterviewService(applicationRepo, llmClient, chatConfig, workspaceService))
+	resumeSchemaService := appsvc.NewResumeSchemaService(app.Repos.ResumeSchemas)
+	resumeSchemaHandler := handler.NewResumeSchemaHandler(resumeSchemaService)
+	resumeProfileHandler := handler.NewResumeProfileHandler(appsvc.NewResumeProfileService(
+		app.Repos.ResumeProfiles,
+		resumeSchemaService,
+		ragService,
+		llmClient,
+		chatConfig,
+		workspaceService,
+	))
+	resumeHeatmapHandler := handler.NewResumeHeatmapHandler(appsvc.NewResumeHeatmapService(
+		ragService,
+		applicationRepo,
+		llmClient,
 		chatConfig,
+		workspaceService,
+	))
+	resumeConsistencyHandler := handler.NewResumeConsistencyHandler(appsvc.NewResumeConsistencyService(ragService, llmClient, chatConfig, workspaceService))
+	workspaceHandler := handler.NewWorkspaceHandler(workspaceService)
+	jobPostingRepo := app.Repos.JobPostings
+	var reportQueue appsvc.ScreeningReportQueue
+	if app.ReportPublisher != nil {
+		reportQueue = app.ReportPublisher
+	}
+	reportService := appsvc.NewScreeningReportService(
+		app.Repos.ScreeningReports,
+		jobPostingRepo,
+		ragDocRepo,
+		workspaceService,
+		ragService,
+		app.ObjectStore,
+		reportQueue,
+		llmClient,
+		chatConfig,
+		workspaceService,
+		notificationService,
 	)
-	ragHandler := handler.NewRAGHandler(ragService)
+	if app.ReportWorker != nil {
+		if err := app.ReportWorker.Start(context.Background(), reportService); err != nil {
+			log.Printf("start screening report worker failed, queued reports wait for the next start: %v", err)
+		}
+	}
+	jobPostingHandler := handler.NewJobPostingHandler(appsvc.NewJobPostingService(
+		jobPostingRepo,
+		workspaceService,
+		ragService,
+		applicationService,
+		reportService,
+		llmClient,
+		chatConfig,
+		workspaceService,
+	), reportService)
+	candidatePoolHandler := handler.NewCandidatePoolHandler(appsvc.NewCandidatePoolService(
+		app.Repos.WorkspaceCandidates,
+		workspaceService,
+		ragService,
+		llmClient,
+		chatConfig,
+		workspaceService,
+	))
+	portfolioHandler := handler.NewPortfolioHandler(appsvc.NewPortfolioService(
+		app.Repos.PortfolioAnalyses,
+		github.NewClient(app.Config.GitHub.APIBaseURL, app.Config.GitHub.Token),
+		ragService,
+		llmClient,
+		chatConfig,
+		workspaceService,
+		app.Config.GitHub.MaxRepos,
+	))
+	embeddingHandler := handler.NewEmbeddingHandler(appsvc.NewEmbeddingService(
+		embedder,
+		embConfig,
+		embeddingCache,
+		quotaService,
+	))
 
-	visionClassifier := vision.NewClassifier(
-		app.Config.Vision.ModelPath,
-		app.Config.Vision.LabelsPath,
-		app.Config.Vision.ONNXSharedLibPath,
-		app.Config.Vision.TopK,
+	visionHandler := handler.NewVisionHandler(
+		visionModels,
+		app.Config.Vision.MaxTopK,
+		visionService,
+		visionSamples,
+	)
+	opsHandler := handler.NewOpsHandler(app.Ops)
+	adminHandler := handler.NewAdminHandler(
+		appsvc.NewRAGMaintenanceService(ragDocRepo, ragChunkRepo),
+		app.Config.RAG.ArchiveAfterDays,
+		visionSamples,
+		ragService,
 	)
-	visionHandler := handler.NewVisionHandler(visionClassifier)
+
+	requireAuth := middleware.AuthJWTWithRenewal(app.Config.Auth.JWTSecret, authService.TokenVersion, middleware.SessionRenewal{
+		Window:      time.Duration(app.Config.Auth.RenewWithinMinutes) * time.Minute,
+		TTL:         time.Duration(app.Config.Auth.JWTExpireMinute) * time.Minute,
+		MaxLifetime: time.Duration(app.Config.Auth.MaxSessionHours) * time.Hour,
+	}, app.Config.Auth.AdminUsernames)
+	requireVision := middleware.RequireAvailable("vision", func() bool {
+		return app.Dependencies.Available(bootstrap.DependencyVision)
+	})
+	limits := app.Config.RateLimit
+	expensiveInFlight := inFlight.Handler()
+	heartbeat := middleware.SSEHeartbeat()
+	limitRAGAsk := middleware.RateLimit("rag_ask", rateLimiter, limits.RAGAskPerMinute, time.Minute)
+	limitRAGUpload := middleware.RateLimit("rag_upload", rateLimiter, limits.RAGUploadPerMinute, time.Minute)
+	limitVisionClassify := middleware.RateLimit("vision_classify", rateLimiter, limits.VisionClassifyPerMinute, time.Minute)
+	limitChat := middleware.RateLimit("chat", rateLimiter, limits.ChatPerMinute, time.Minute)
+	limitPasswordReset := middleware.RateLimitByIP("password_reset", rateLimiter, limits.AuthEmailsPerHour, time.Hour)
+	limitVerifyResend := middleware.RateLimit("verify_resend", rateLimiter, limits.AuthEmailsPerHour, time.Hour)
+	// WebSocket sends count like chat requests, one at a time per message.
+	wsHandler := ws.NewHandler(chatService, wsHub, app.Config.Auth.JWTSecret, authService.TokenVersion, app.Config.Auth.WebSocketOrigins,
+		func(ctx context.Context, userID uint) (func(), error) {
+			if err := middleware.TakeRateLimit(ctx, "chat", rateLimiter, limits.ChatPerMinute, time.Minute, userID); err != nil {
+				return nil, err
+			}
+			return inFlight.Acquire(userID)
+		})
+	requireDrafts := middleware.RequireAvailable("drafts", func() bool {
+		return app.Dependencies.Available(bootstrap.DependencyRedis)
+	})
+
+	// Share links are public: the token in the path is the only credential.
+	router.GET("/share/:token", sessionShareHandler.Transcript)
 
 	v1 := router.Group("/api/v1")
 	authGroup := v1.Group("/auth")
 	authGroup.POST("/register", authHandler.Register)
 	authGroup.POST("/login", authHandler.Login)
-	authGroup.GET("/me", middleware.AuthJWT(app.Config.Auth.JWTSecret), authHandler.Me)
+	authGroup.GET("/me", requireAuth, authHandler.Me)
+	authGroup.GET("/me/export", requireAuth, dataExportHandler.Export)
+	authGroup.GET("/me/export/:id/download", requireAuth, dataExportHandler.Download)
+	authGroup.POST("/password-reset", limitPasswordReset, authHandler.RequestPasswordReset)
+	authGroup.POST("/password-reset/confirm", authHandler.ResetPassword)
+	authGroup.GET("/verify-email", authHandler.VerifyEmail)
+	authGroup.POST("/verify-email/resend", requireAuth, limitVerifyResend, authHandler.ResendVerification)
+
+	notificationGroup := v1.Group("/notifications")
+	notificationGroup.Use(requireAuth)
+	notificationGroup.GET("/preferences", notificationHandler.Preferences)
+	notificationGroup.PATCH("/preferences", notificationHandler.UpdatePreferences)
 
 	chatGroup := v1.Group("/chat")
-	chatGroup.Use(middleware.AuthJWT(app.Config.Auth.JWTSecret))
+	chatGroup.Use(requireAuth)
 	chatGroup.POST("/sessions", chatHandler.CreateSession)
 	chatGroup.GET("/sessions", chatHandler.ListSessions)
+	chatGroup.PATCH("/sessions/:id", chatHandler.UpdateSession)
+	chatGroup.PUT("/sessions/order", chatHandler.ReorderSessions)
 	chatGroup.DELETE("/sessions/:id", chatHandler.DeleteSession)
+	chatGroup.POST("/sessions/:id/fork", chatHandler.ForkSession)
+	chatGroup.GET("/sessions/:id/tree", chatHandler.SessionTree)
+	chatGroup.POST("/sessions/:id/share", sessionShareHandler.Share)
+	chatGroup.DELETE("/sessions/:id/share", sessionShareHandler.Revoke)
+	chatGroup.GET("/sessions/:id/draft", requireDrafts, chatDraftHandler.Get)
+	chatGroup.PUT("/sessions/:id/draft", requireDrafts, chatDraftHandler.Save)
+	chatGroup.DELETE("/sessions/:id/draft", requireDrafts, chatDraftHandler.Delete)
 	chatGroup.POST("/messages", chatHandler.SendMessage)
-	chatGroup.POST("/stream", chatHandler.StreamMessage)
+	chatGroup.PATCH("/messages/:id", chatHandler.EditMessage)
+	chatGroup.DELETE("/messages/:id", chatHandler.DeleteMessage)
+	chatGroup.POST("/messages/bulk-delete", chatHandler.BulkDeleteMessages)
+	chatGroup.POST("/stream", heartbeat, chatHandler.StreamMessage)
+	chatGroup.POST("/stream/:id/cancel", chatHandler.CancelStream)
 	chatGroup.GET("/history", chatHandler.GetHistory)
+	chatGroup.GET("/search", chatHandler.Search)
+	chatGroup.GET("/usage", chatUsageHandler.Get)
+	chatGroup.POST("/schedule", chatScheduleHandler.Schedule)
+	chatGroup.GET("/schedule", chatScheduleHandler.List)
+	chatGroup.DELETE("/schedule/:id", chatScheduleHandler.Cancel)
+	chatGroup.GET("/compare/models", modelCompareHandler.Models)
+	chatGroup.POST("/compare", modelCompareHandler.Compare)
+	chatGroup.POST("/negotiation-brief", heartbeat, negotiationHandler.StreamBrief)
+	// The WebSocket endpoint authenticates itself (header or subprotocol), so it sits outside chatGroup.
+	v1.GET("/chat/ws", wsHandler.Serve)
 
 	ragGroup := v1.Group("/rag")
-	ragGroup.Use(middleware.AuthJWT(app.Config.Auth.JWTSecret))
+	ragGroup.Use(requireAuth)
 	ragGroup.POST("/sessions", ragHandler.CreateSession)
 	ragGroup.GET("/sessions", ragHandler.ListSessions)
 	ragGroup.DELETE("/sessions/:id", ragHandler.DeleteSession)
-	ragGroup.POST("/documents", ragHandler.CreateDocument)
-	ragGroup.POST("/documents/upload", ragHandler.UploadPDF)
+	ragGroup.GET("/sessions/:id/suggested-questions", ragHandler.SuggestedQuestions)
+	ragGroup.GET("/sessions/:id/messages", ragHandler.History)
+	ragGroup.POST("/documents", limitRAGUpload, expensiveInFlight, ragHandler.CreateDocument)
+	ragGroup.POST("/documents/upload", limitRAGUpload, expensiveInFlight, ragHandler.UploadDocument)
+	ragGroup.POST("/documents/upload/stream", limitRAGUpload, expensiveInFlight, heartbeat, ragHandler.UploadDocumentStream)
+	ragGroup.POST("/documents/image", limitRAGUpload, expensiveInFlight, ragHandler.UploadImage)
 	ragGroup.GET("/documents", ragHandler.ListDocuments)
+	ragGroup.PUT("/documents/:id", limitRAGUpload, expensiveInFlight, ragHandler.ReplaceDocument)
 	ragGroup.DELETE("/documents/:id", ragHandler.DeleteDocument)
-	ragGroup.POST("/ask", ragHandler.Ask)
+	ragGroup.GET("/documents/:id/status", ragHandler.DocumentStatus)
+	ragGroup.GET("/documents/:id/chunks", ragHandler.DocumentChunks)
+	ragGroup.POST("/documents/:id/append", limitRAGUpload, expensiveInFlight, ragHandler.AppendDocument)
+	ragGroup.POST("/ask", limitRAGAsk, expensiveInFlight, ragHandler.Ask)
+	// Batch and screenshot asks take in-flight slots per question instead of per request.
+	ragGroup.POST("/ask/batch", ragHandler.AskBatch)
+	ragGroup.POST("/screenshot/ask", limitRAGUpload, ragHandler.ScreenImage)
+	ragGroup.POST("/ask/stream", limitRAGAsk, expensiveInFlight, heartbeat, ragHandler.AskStream)
+	ragGroup.POST("/compare", limitRAGAsk, expensiveInFlight, ragHandler.Compare)
+	ragGroup.GET("/quota", ragHandler.Quota)
 
 	visionGroup := v1.Group("/vision")
-	visionGroup.Use(middleware.AuthJWT(app.Config.Auth.JWTSecret))
-	visionGroup.POST("/classify", visionHandler.Classify)
+	visionGroup.Use(requireAuth, requireVision)
+	visionGroup.POST("/classify", limitVisionClassify, expensiveInFlight, visionHandler.Classify)
+	visionGroup.POST("/photo-check", limitVisionClassify, expensiveInFlight, visionHandler.CheckPhoto)
+	visionGroup.GET("/models", visionHandler.ListModels)
+	visionGroup.GET("/samples", visionHandler.ListSamples)
+	visionGroup.DELETE("/samples/:id", visionHandler.DeleteSample)
+	visionGroup.POST("/samples/:id/rerun", limitVisionClassify, expensiveInFlight, visionHandler.RerunSample)
+
+	resumeGroup := v1.Group("/resume")
+	resumeGroup.Use(requireAuth)
+	resumeGroup.POST("/bullets/scan", resumeBulletHandler.Scan)
+	resumeGroup.GET("/bullets", resumeBulletHandler.List)
+	resumeGroup.POST("/bullets/:id/reply", resumeBulletHandler.Reply)
+	resumeGroup.POST("/bullets/:id/accept", resumeBulletHandler.Accept)
+	resumeGroup.POST("/bullets/:id/skip", resumeBulletHandler.Skip)
+	resumeGroup.POST("/heatmap", resumeHeatmapHandler.Build)
+	resumeGroup.POST("/compare", resumeHeatmapHandler.Compare)
+	resumeGroup.POST("/consistency", resumeConsistencyHandler.Check)
+	resumeGroup.POST("/import", resumeProfileHandler.Import)
+	resumeGroup.GET("/export", resumeProfileHandler.Export)
+	resumeGroup.GET("/schema", resumeSchemaHandler.Active)
+
+	applicationGroup := v1.Group("/applications")
+	applicationGroup.Use(requireAuth)
+	applicationGroup.POST("", applicationHandler.Create)
+	applicationGroup.GET("", applicationHandler.List)
+	applicationGroup.GET("/reminders", applicationHandler.Reminders)
+	applicationGroup.GET("/board", applicationHandler.Board)
+	applicationGroup.POST("/parse-email", applicationHandler.ParseEmail)
+	applicationGroup.GET("/:id", applicationHandler.Get)
+	applicationGroup.PATCH("/:id", applicationHandler.Update)
+	applicationGroup.DELETE("/:id", applicationHandler.Delete)
+	applicationGroup.POST("/:id/move", applicationHandler.Move)
+
+	interviewGroup := v1.Group("/interview")
+	interviewGroup.Use(requireAuth)
+	interviewGroup.POST("/evaluate", interviewHandler.EvaluateAnswer)
+
+	workspaceGroup := v1.Group("/workspaces")
+	workspaceGroup.Use(requireAuth)
+	workspaceGroup.POST("", workspaceHandler.Create)
+	workspaceGroup.GET("", workspaceHandler.List)
+	workspaceGroup.GET("/invitations", workspaceHandler.Invitations)
+	workspaceGroup.POST("/:id/accept", workspaceHandler.AcceptInvitation)
+	workspaceGroup.GET("/:id/members", workspaceHandler.ListMembers)
+	workspaceGroup.POST("/:id/members", workspaceHandler.AddMember)
+	workspaceGroup.PATCH("/:id/members/:user_id", workspaceHandler.UpdateMember)
+	workspaceGroup.DELETE("/:id/members/:user_id", workspaceHandler.RemoveMember)
+	workspaceGroup.GET("/:id/model-allowlist", workspaceHandler.ModelAllowlist)
+	workspaceGroup.PUT("/:id/model-allowlist", workspaceHandler.SetModelAllowlist)
+	workspaceGroup.POST("/:id/candidates", candidatePoolHandler.Add)
+	workspaceGroup.GET("/:id/candidates", candidatePoolHandler.List)
+	workspaceGroup.POST("/:id/candidates/search", candidatePoolHandler.Search)
+	workspaceGroup.DELETE("/:id/candidates/:candidate_id", candidatePoolHandler.Remove)
+	workspaceGroup.POST("/:id/jobs", jobPostingHandler.Create)
+	workspaceGroup.GET("/:id/jobs", jobPostingHandler.List)
+	workspaceGroup.GET("/:id/jobs/:job_id", jobPostingHandler.Get)
+	workspaceGroup.PATCH("/:id/jobs/:job_id", jobPostingHandler.Update)
+	workspaceGroup.DELETE("/:id/jobs/:job_id", jobPostingHandler.Delete)
+	workspaceGroup.POST("/:id/jobs/:job_id/screen", jobPostingHandler.Screen)
+	workspaceGroup.GET("/:id/jobs/:job_id/screenings", jobPostingHandler.Screenings)
+	workspaceGroup.POST("/:id/jobs/:job_id/track", jobPostingHandler.Track)
+	workspaceGroup.POST("/:id/jobs/:job_id/reports", jobPostingHandler.RequestReports)
+	workspaceGroup.GET("/:id/jobs/:job_id/reports", jobPostingHandler.ListReports)
+	workspaceGroup.GET("/:id/jobs/:job_id/reports/:report_id", jobPostingHandler.GetReport)
+	workspaceGroup.GET("/:id/jobs/:job_id/reports/:report_id/download", jobPostingHandler.DownloadReport)
+
+	portfolioGroup := v1.Group("/portfolio/analyses")
+	portfolioGroup.Use(requireAuth)
+	portfolioGroup.POST("", portfolioHandler.Analyze)
+	portfolioGroup.GET("", portfolioHandler.List)
+	portfolioGroup.GET("/:id", portfolioHandler.Get)
+	portfolioGroup.DELETE("/:id", portfolioHandler.Delete)
+
+	v1.POST("/embeddings", requireAuth, embeddingHandler.Create)
+	v1.GET("/usage", requireAuth, usageHandler.Get)
+	v1.GET("/activity", requireAuth, activityHandler.List)
+	// OpenAI-compatible clients use /api/v1/proxy as their base URL.
+	if app.Config.LLM.ProxyEnabled {
+		v1.POST("/proxy/chat/completions", requireAuth, limitChat, expensiveInFlight, heartbeat, proxyHandler.ChatCompletions)
+	}
+
+	adminGroup := v1.Group("/admin")
+	adminGroup.Use(requireAuth, middleware.RequireAdmin())
+	adminGroup.POST("/rag/vacuum", adminHandler.VacuumRAG)
+	adminGroup.POST("/rag/recount", adminHandler.RecountRAGChunks)
+	adminGroup.GET("/rag/storage", adminHandler.RAGStorage)
+	adminGroup.POST("/rag/archive", adminHandler.ArchiveColdRAG)
+	adminGroup.GET("/rag/shadow", adminHandler.ShadowEmbeddingStats)
+	adminGroup.POST("/vision/evaluate", requireVision, adminHandler.EvaluateVisionModel)
+	adminGroup.POST("/resume-schemas", resumeSchemaHandler.Create)
+	adminGroup.GET("/resume-schemas", resumeSchemaHandler.List)
+	adminGroup.POST("/resume-schemas/:version/activate", resumeSchemaHandler.Activate)
+	adminGroup.GET("/ops", opsHandler.Get)
+	adminGroup.PATCH("/ops", opsHandler.Update)
+	adminGroup.DELETE("/ops", opsHandler.Reset)
 
 	return router
 }
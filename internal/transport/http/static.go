@@ -0,0 +1,100 @@
+package http
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"gopherai-resume/internal/config"
+	"gopherai-resume/internal/transport/http/response"
+)
+
+// Web modes; see config.WebConfig.
+const (
+	webModePages = "pages"
+	webModeSPA   = "spa"
+	webModeOff   = "off"
+)
+
+// bundledPages are the pages served in pages mode, by path.
+var bundledPages = map[string]string{
+	"/":               "index.html",
+	"/login":          "login.html",
+	"/register":       "register.html",
+	"/reset-password": "reset-password.html",
+	"/app":            "app.html",
+	"/chat":           "chat.html",
+	"/rag":            "rag.html",
+	"/vision":         "vision.html",
+}
+
+// registerStatic serves the frontend as cfg says and answers unknown paths. Unknown /api
+// paths always get the JSON error envelope, never a page.
+func registerStatic(router *gin.Engine, cfg config.WebConfig) {
+	mode := strings.ToLower(strings.TrimSpace(cfg.Mode))
+	switch mode {
+	case webModePages, webModeSPA, webModeOff:
+	default:
+		log.Printf("unknown web.mode %q, using %s", cfg.Mode, webModePages)
+		mode = webModePages
+	}
+
+	if mode == webModePages {
+		for route, file := range bundledPages {
+			file := filepath.Join(cfg.Dir, file)
+			serve := func(c *gin.Context) {
+				c.Header("Cache-Control", "no-cache")
+				c.File(file)
+			}
+			router.GET(route, serve)
+			router.HEAD(route, serve)
+		}
+	}
+
+	assetCache := "no-cache"
+	if cfg.AssetMaxAgeSeconds > 0 {
+		assetCache = fmt.Sprintf("public, max-age=%d", cfg.AssetMaxAgeSeconds)
+	}
+	index := filepath.Join(cfg.Dir, cfg.Index)
+	router.NoRoute(func(c *gin.Context) {
+		p := c.Request.URL.Path
+		if p == "/api" || strings.HasPrefix(p, "/api/") || mode != webModeSPA ||
+			(c.Request.Method != http.MethodGet && c.Request.Method != http.MethodHead) {
+			notFound(c, p)
+			return
+		}
+		name := path.Clean("/" + p)
+		file := filepath.Join(cfg.Dir, filepath.FromSlash(name))
+		if info, err := os.Stat(file); err == nil && info.Mode().IsRegular() {
+			if file == index {
+				c.Header("Cache-Control", "no-cache")
+			} else {
+				c.Header("Cache-Control", assetCache)
+			}
+			c.File(file)
+			return
+		}
+		// A missing file such as /assets/app.js is a broken link, not a client-side route;
+		// answering it with the index would only hide the error.
+		if path.Ext(name) != "" {
+			notFound(c, p)
+			return
+		}
+		c.Header("Cache-Control", "no-cache")
+		c.File(index)
+	})
+}
+
+func notFound(c *gin.Context, p string) {
+	if p == "/api" || strings.HasPrefix(p, "/api/") {
+		response.Error(c, http.StatusNotFound, response.CodeRouteNotFound, "route not found")
+		return
+	}
+	c.String(http.StatusNotFound, "404 page not found")
+}
@@ -0,0 +1,256 @@
+package ws
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"gopherai-resume/internal/app"
+	"gopherai-resume/internal/pkg/apperr"
+)
+
+const (
+	writeWait      = 10 * time.Second
+	pongWait       = 60 * time.Second
+	pingPeriod     = 30 * time.Second
+	maxFrameSize   = 64 << 10
+	sendBufferSize = 256
+	// sendQueueSize is how many chat sends may wait behind the one being answered.
+	sendQueueSize = 8
+)
+
+// Frame types.
+const (
+	FrameSend      = "send"      // client: send a chat message and stream the reply
+	FramePing      = "ping"      // client: application-level keepalive
+	FrameCancel    = "cancel"    // client: abort the stream stream_id
+	FrameReady     = "ready"     // server: connection authenticated
+	FrameStart     = "start"     // server: stream_id assigned to request_id
+	FrameDelta     = "delta"     // server: token delta for request_id
+	FrameDone      = "done"      // server: full reply for request_id
+	FrameCancelled = "cancelled" // server: request_id was cancelled; data holds the partial reply
+	FrameError     = "error"     // server: request_id (or the connection) failed
+	FramePong      = "pong"      // server: reply to ping
+	FrameEvent     = "event"     // server: server-initiated event
+)
+
+// ClientFrame is a message from the client.
+type ClientFrame struct {
+	Type      string      `json:"type"`
+	RequestID string      `json:"request_id,omitempty"`
+	StreamID  string      `json:"stream_id,omitempty"`
+	SessionID uint        `json:"session_id,omitempty"`
+	Content   string      `json:"content,omitempty"`
+	LLM       LLMSettings `json:"llm"`
+}
+
+// LLMSettings overrides the LLM for one send, like the "llm" object of /chat/stream.
+type LLMSettings struct {
+	BaseURL     string   `json:"base_url"`
+	APIKey      string   `json:"api_key"`
+	Model       string   `json:"model"`
+	Temperature *float64 `json:"temperature"`
+	TopP        *float64 `json:"top_p"`
+	MaxTokens   int      `json:"max_tokens"`
+	Stop        []string `json:"stop"`
+}
+
+func (l LLMSettings) override() app.LLMOverride {
+	return app.LLMOverride{
+		BaseURL:     l.BaseURL,
+		APIKey:      l.APIKey,
+		Model:       l.Model,
+		Temperature: l.Temperature,
+		TopP:        l.TopP,
+		MaxTokens:   l.MaxTokens,
+		Stop:        l.Stop,
+	}
+}
+
+// ServerFrame is a message to the client.
+type ServerFrame struct {
+	Type      string      `json:"type"`
+	RequestID string      `json:"request_id,omitempty"`
+	StreamID  string      `json:"stream_id,omitempty"`
+	Data      string      `json:"data,omitempty"`
+	Error     string      `json:"error,omitempty"`
+	Event     string      `json:"event,omitempty"`
+	Payload   interface{} `json:"payload,omitempty"`
+}
+
+type conn struct {
+	ws          *websocket.Conn
+	userID      uint
+	chatService *app.ChatService
+	admit       Admit
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	send   chan ServerFrame
+	// sends queues the chat sends, which are answered one at a time.
+	sends chan ClientFrame
+	wg    sync.WaitGroup
+}
+
+func newConn(parent context.Context, ws *websocket.Conn, userID uint, chatService *app.ChatService, admit Admit) *conn {
+	ctx, cancel := context.WithCancel(parent)
+	return &conn{
+		ws:          ws,
+		userID:      userID,
+		chatService: chatService,
+		admit:       admit,
+		ctx:         ctx,
+		cancel:      cancel,
+		send:        make(chan ServerFrame, sendBufferSize),
+		sends:       make(chan ClientFrame, sendQueueSize),
+	}
+}
+
+// trySend queues a frame without blocking; it reports false if the buffer is full or closed.
+func (c *conn) trySend(frame ServerFrame) bool {
+	select {
+	case <-c.ctx.Done():
+		return false
+	case c.send <- frame:
+		return true
+	default:
+		return false
+	}
+}
+
+// sendFrame queues a frame, waiting for buffer space until the connection closes.
+func (c *conn) sendFrame(frame ServerFrame) error {
+	select {
+	case <-c.ctx.Done():
+		return c.ctx.Err()
+	case c.send <- frame:
+		return nil
+	}
+}
+
+// run serves the connection until the client disconnects. In-flight completions are
+// cancelled when it returns.
+func (c *conn) run() {
+	go c.writeLoop()
+	c.wg.Add(1)
+	go func() {
+		defer c.wg.Done()
+		c.sendLoop()
+	}()
+	c.readLoop()
+	c.cancel()
+	c.wg.Wait()
+}
+
+// sendLoop answers the queued chat sends in order, so a connection has one reply streaming
+// at a time, like a client waiting for each /chat/stream.
+func (c *conn) sendLoop() {
+	for {
+		select {
+		case <-c.ctx.Done():
+			return
+		case frame := <-c.sends:
+			c.handleSend(frame)
+		}
+	}
+}
+
+func (c *conn) readLoop() {
+	c.ws.SetReadLimit(maxFrameSize)
+	_ = c.ws.SetReadDeadline(time.Now().Add(pongWait))
+	c.ws.SetPongHandler(func(string) error {
+		return c.ws.SetReadDeadline(time.Now().Add(pongWait))
+	})
+
+	for {
+		var frame ClientFrame
+		if err := c.ws.ReadJSON(&frame); err != nil {
+			var syntaxErr *json.SyntaxError
+			if errors.As(err, &syntaxErr) {
+				_ = c.sendFrame(ServerFrame{Type: FrameError, Error: "invalid frame"})
+				continue
+			}
+			return
+		}
+		_ = c.ws.SetReadDeadline(time.Now().Add(pongWait))
+
+		switch frame.Type {
+		case FramePing:
+			_ = c.sendFrame(ServerFrame{Type: FramePong, RequestID: frame.RequestID})
+		case FrameCancel:
+			if err := c.chatService.CancelStream(c.userID, frame.StreamID); err != nil {
+				_ = c.sendFrame(ServerFrame{Type: FrameError, RequestID: frame.RequestID, StreamID: frame.StreamID, Error: apperr.Message(err, "cancel failed")})
+			}
+		case FrameSend:
+			select {
+			case c.sends <- frame:
+			default:
+				_ = c.sendFrame(ServerFrame{Type: FrameError, RequestID: frame.RequestID, Error: "too many messages waiting on this connection"})
+			}
+		default:
+			_ = c.sendFrame(ServerFrame{Type: FrameError, RequestID: frame.RequestID, Error: "unknown frame type"})
+		}
+	}
+}
+
+func (c *conn) handleSend(frame ClientFrame) {
+	if c.admit != nil {
+		done, err := c.admit(c.ctx, c.userID)
+		if err != nil {
+			_ = c.sendFrame(ServerFrame{Type: FrameError, RequestID: frame.RequestID, Error: apperr.Message(err, "send failed")})
+			return
+		}
+		defer done()
+	}
+	full, err := c.chatService.StreamMessage(c.ctx, app.SendMessageInput{
+		UserID:    c.userID,
+		SessionID: frame.SessionID,
+		Content:   frame.Content,
+		LLM:       frame.LLM.override(),
+	}, func(streamID string) error {
+		return c.sendFrame(ServerFrame{Type: FrameStart, RequestID: frame.RequestID, StreamID: streamID})
+	}, func(chunk string) error {
+		return c.sendFrame(ServerFrame{Type: FrameDelta, RequestID: frame.RequestID, Data: chunk})
+	})
+	if errors.Is(err, app.ErrStreamCancelled) {
+		_ = c.sendFrame(ServerFrame{Type: FrameCancelled, RequestID: frame.RequestID, Data: full})
+		return
+	}
+	if err != nil {
+		_ = c.sendFrame(ServerFrame{Type: FrameError, RequestID: frame.RequestID, Error: apperr.Message(err, "send failed")})
+		return
+	}
+	_ = c.sendFrame(ServerFrame{Type: FrameDone, RequestID: frame.RequestID, Data: full})
+}
+
+func (c *conn) writeLoop() {
+	ticker := time.NewTicker(pingPeriod)
+	defer func() {
+		ticker.Stop()
+		_ = c.ws.Close()
+	}()
+
+	for {
+		select {
+		case <-c.ctx.Done():
+			_ = c.ws.WriteControl(websocket.CloseMessage,
+				websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""), time.Now().Add(writeWait))
+			return
+		case frame := <-c.send:
+			_ = c.ws.SetWriteDeadline(time.Now().Add(writeWait))
+			if err := c.ws.WriteJSON(frame); err != nil {
+				c.cancel()
+				return
+			}
+		case <-ticker.C:
+			if err := c.ws.WriteControl(websocket.PingMessage, nil, time.Now().Add(writeWait)); err != nil {
+				c.cancel()
+				return
+			}
+		}
+	}
+}
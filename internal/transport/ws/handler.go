@@ -0,0 +1,107 @@
+package ws
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+
+	"gopherai-resume/internal/app"
+	"gopherai-resume/internal/pkg/jwtutil"
+	"gopherai-resume/internal/transport/http/middleware"
+	"gopherai-resume/internal/transport/http/response"
+)
+
+// bearerProtocol is the WebSocket subprotocol that carries the token: browsers, which cannot
+// set headers on WebSocket requests, offer "bearer" followed by the token.
+const bearerProtocol = "bearer"
+
+// Admit lets one chat send of userID through the rate and in-flight limits, returning the
+// func that ends it, or the error to report to the client.
+type Admit func(ctx context.Context, userID uint) (func(), error)
+
+// Handler upgrades authenticated requests to chat WebSocket connections.
+type Handler struct {
+	chatService *app.ChatService
+	hub         *Hub
+	jwtSecret   string
+	versions    middleware.TokenVersions
+	admit       Admit
+	upgrader    websocket.Upgrader
+}
+
+// NewHandler serves the chat WebSocket. Pages may connect from the server's own origin or
+// one of origins; clients that send no Origin, which are not browsers, always may.
+func NewHandler(chatService *app.ChatService, hub *Hub, jwtSecret string, versions middleware.TokenVersions, origins []string, admit Admit) *Handler {
+	allowed := make(map[string]bool, len(origins))
+	for _, origin := range origins {
+		if origin = strings.TrimRight(strings.TrimSpace(origin), "/"); origin != "" {
+			allowed[strings.ToLower(origin)] = true
+		}
+	}
+	return &Handler{
+		chatService: chatService,
+		hub:         hub,
+		jwtSecret:   jwtSecret,
+		versions:    versions,
+		admit:       admit,
+		upgrader: websocket.Upgrader{
+			ReadBufferSize:  4096,
+			WriteBufferSize: 4096,
+			Subprotocols:    []string{bearerProtocol},
+			CheckOrigin: func(r *http.Request) bool {
+				origin := r.Header.Get("Origin")
+				if origin == "" || allowed[strings.ToLower(origin)] {
+					return true
+				}
+				u, err := url.Parse(origin)
+				return err == nil && strings.EqualFold(u.Host, r.Host)
+			},
+		},
+	}
+}
+
+// Serve authenticates with the Authorization header or, from a browser, the subprotocols
+// "bearer" and the token, then serves the connection. Tokens are not accepted in the URL,
+// where proxies and access logs would keep them.
+func (h *Handler) Serve(c *gin.Context) {
+	token := strings.TrimSpace(strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer "))
+	if protocols := websocket.Subprotocols(c.Request); token == "" && len(protocols) == 2 && protocols[0] == bearerProtocol {
+		token = protocols[1]
+	}
+	if token == "" {
+		response.Error(c, http.StatusUnauthorized, response.CodeUnauthorized, "missing token")
+		return
+	}
+	claims, err := jwtutil.ParseToken(h.jwtSecret, token)
+	if err != nil {
+		response.Error(c, http.StatusUnauthorized, response.CodeUnauthorized, "invalid or expired token")
+		return
+	}
+	version, ok, err := h.versions(c.Request.Context(), claims.UserID)
+	if err != nil {
+		log.Printf("look up token version of user %d failed: %v", claims.UserID, err)
+		response.Error(c, http.StatusInternalServerError, response.CodeInternalServer, "internal server error")
+		return
+	}
+	if !ok || version != claims.TokenVersion {
+		response.Error(c, http.StatusUnauthorized, response.CodeUnauthorized, "token has been revoked")
+		return
+	}
+
+	wsConn, err := h.upgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		return // the upgrader has already written an HTTP error
+	}
+
+	conn := newConn(c.Request.Context(), wsConn, claims.UserID, h.chatService, h.admit)
+	h.hub.add(conn)
+	defer h.hub.remove(conn)
+
+	_ = conn.sendFrame(ServerFrame{Type: FrameReady})
+	conn.run()
+}
@@ -0,0 +1,50 @@
+// Package ws serves chat over a persistent WebSocket connection: clients authenticate once,
+// send messages, receive token deltas, and can be sent server-initiated events.
+package ws
+
+import "sync"
+
+// Hub tracks open connections per user so the server can push events to them.
+type Hub struct {
+	mu    sync.RWMutex
+	conns map[uint]map[*conn]struct{}
+}
+
+func NewHub() *Hub {
+	return &Hub{conns: make(map[uint]map[*conn]struct{})}
+}
+
+func (h *Hub) add(c *conn) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	set, ok := h.conns[c.userID]
+	if !ok {
+		set = make(map[*conn]struct{})
+		h.conns[c.userID] = set
+	}
+	set[c] = struct{}{}
+}
+
+func (h *Hub) remove(c *conn) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	set := h.conns[c.userID]
+	delete(set, c)
+	if len(set) == 0 {
+		delete(h.conns, c.userID)
+	}
+}
+
+// Push sends an event frame to every open connection of userID and returns how many
+// connections accepted it. Slow connections whose send buffer is full are skipped.
+func (h *Hub) Push(userID uint, event string, payload interface{}) int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	delivered := 0
+	for c := range h.conns[userID] {
+		if c.trySend(ServerFrame{Type: FrameEvent, Event: event, Payload: payload}) {
+			delivered++
+		}
+	}
+	return delivered
+}
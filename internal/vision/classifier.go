@@ -8,13 +8,17 @@ import (
 	"image/jpeg"
 	"image/png"
 	"io"
+	"math"
 	"os"
+	"path/filepath"
 	"sort"
 	"strings"
 	"sync"
+	"time"
 
-	ort "github.com/yalue/onnxruntime_go"
 	"golang.org/x/image/draw"
+
+	"gopherai-resume/internal/onnx"
 )
 
 // ImageNet normalization (standard for torchvision models).
@@ -26,6 +30,9 @@ var (
 const (
 	width  = 224
 	height = 224
+
+	// PreprocessingMode describes the input pipeline applied by preprocess.
+	PreprocessingMode = "resize_224_catmullrom_rgb_nchw_imagenet_norm"
 )
 
 // LabelScore holds a class label and its score (logit or probability).
@@ -35,24 +42,59 @@ type LabelScore struct {
 	Score float32 `json:"score"`
 }
 
-// Classifier runs MobileNetV2 ONNX inference and maps outputs to labels.
+// ModelInfo describes a loaded classifier model.
+type ModelInfo struct {
+	Name        string  `json:"name"`
+	File        string  `json:"file"`
+	Version     int64   `json:"version"`
+	Producer    string  `json:"producer,omitempty"`
+	GraphName   string  `json:"graph_name,omitempty"`
+	InputShape  []int64 `json:"input_shape"`
+	OutputShape []int64 `json:"output_shape"`
+	LabelCount  int     `json:"label_count"`
+}
+
+// Timing reports per-stage latency of one classification in milliseconds.
+type Timing struct {
+	DecodeMs     float64 `json:"decode_ms"`
+	PreprocessMs float64 `json:"preprocess_ms"`
+	InferenceMs  float64 `json:"inference_ms"`
+	TotalMs      float64 `json:"total_ms"`
+}
+
+// ClassifyResult is the output of ClassifyWithOptions: predictions plus model and timing details.
+type ClassifyResult struct {
+	Predictions   []LabelScore `json:"predictions"`
+	Model         ModelInfo    `json:"model"`
+	Preprocessing string       `json:"preprocessing"`
+	Probabilities bool         `json:"probabilities"`
+	Timing        Timing       `json:"timing"`
+	Cached        bool         `json:"cached"`
+	SampleID      uint         `json:"sample_id,omitempty"` // set when the image was stored for re-runs
+	// Duplicate is set when the result is that of the user's stored sample SampleID of the
+	// same image, returned instead of classifying it again.
+	Duplicate bool `json:"duplicate,omitempty"`
+}
+
+// Classifier runs MobileNetV2-style ONNX image classification and maps outputs to labels.
+// Session handling lives in onnx.Runner; the classifier only supplies pre/post-processing.
 type Classifier struct {
 	mu sync.Mutex
 
+	name       string // set by Registry.Register
 	modelPath  string
 	labelsPath string
 	topK       int
-	libPath    string
 
-	session *ort.AdvancedSession
-	input   *ort.Tensor[float32]
-	output  *ort.Tensor[float32]
-	labels  []string
-	inited  bool
+	runner *onnx.Runner
+	labels []string
+	info   ModelInfo
+	inited bool
 }
 
 // NewClassifier creates a classifier that will lazily load the ONNX model and labels.
-func NewClassifier(modelPath, labelsPath, onnxLibPath string, topK int) *Classifier {
+// poolSize sessions may run concurrently.
+func NewClassifier(modelPath, labelsPath, onnxLibPath string, topK, poolSize int) *Classifier {
 	if topK <= 0 {
 		topK = 5
 	}
@@ -60,11 +102,15 @@ func NewClassifier(modelPath, labelsPath, onnxLibPath string, topK int) *Classif
 		modelPath:  modelPath,
 		labelsPath: labelsPath,
 		topK:       topK,
-		libPath:    onnxLibPath,
+		runner: onnx.NewRunner(onnx.RunnerConfig{
+			ModelPath: modelPath,
+			LibPath:   onnxLibPath,
+			PoolSize:  poolSize,
+		}),
 	}
 }
 
-// initOnce loads the ONNX shared library, environment, labels, and session.
+// initOnce loads the labels and the ONNX runner.
 func (c *Classifier) initOnce() error {
 	c.mu.Lock()
 	defer c.mu.Unlock()
@@ -72,64 +118,43 @@ func (c *Classifier) initOnce() error {
 		return nil
 	}
 
-	if c.libPath != "" {
-		ort.SetSharedLibraryPath(c.libPath)
-	}
-
-	if err := ort.InitializeEnvironment(); err != nil {
-		return fmt.Errorf("onnx init environment: %w", err)
-	}
-
 	labels, err := loadLabels(c.labelsPath)
 	if err != nil {
 		return fmt.Errorf("load labels: %w", err)
 	}
-	c.labels = labels
-
-	inputs, outputs, err := ort.GetInputOutputInfo(c.modelPath)
-	if err != nil {
-		return fmt.Errorf("onnx get input/output info: %w", err)
-	}
-	if len(inputs) == 0 || len(outputs) == 0 {
-		return fmt.Errorf("onnx model has no inputs or outputs")
-	}
-	inputShape := inputs[0].Dimensions
-	outputShape := outputs[0].Dimensions
 
-	inputTensor, err := ort.NewEmptyTensor[float32](inputShape)
+	runnerInfo, err := c.runner.Info()
 	if err != nil {
-		return fmt.Errorf("onnx new input tensor: %w", err)
-	}
-	c.input = inputTensor
-
-	outputTensor, err := ort.NewEmptyTensor[float32](outputShape)
-	if err != nil {
-		inputTensor.Destroy()
-		return fmt.Errorf("onnx new output tensor: %w", err)
-	}
-	c.output = outputTensor
-
-	inputNames := make([]string, len(inputs))
-	for i := range inputs {
-		inputNames[i] = inputs[i].Name
+		return err
 	}
-	outputNames := make([]string, len(outputs))
-	for i := range outputs {
-		outputNames[i] = outputs[i].Name
+	if len(runnerInfo.InputShapes) != 1 {
+		return fmt.Errorf("classifier model must have exactly one input, has %d", len(runnerInfo.InputShapes))
 	}
 
-	session, err := ort.NewAdvancedSession(c.modelPath, inputNames, outputNames,
-		[]ort.Value{c.input}, []ort.Value{c.output}, nil)
-	if err != nil {
-		outputTensor.Destroy()
-		inputTensor.Destroy()
-		return fmt.Errorf("onnx new session: %w", err)
+	c.labels = labels
+	c.info = ModelInfo{
+		File:        runnerInfo.File,
+		Version:     runnerInfo.Version,
+		Producer:    runnerInfo.Producer,
+		GraphName:   runnerInfo.GraphName,
+		InputShape:  runnerInfo.InputShapes[0],
+		OutputShape: runnerInfo.OutputShapes[0],
+		LabelCount:  len(labels),
 	}
-	c.session = session
 	c.inited = true
 	return nil
 }
 
+// Info loads the model if needed and returns its metadata.
+func (c *Classifier) Info() (ModelInfo, error) {
+	if err := c.initOnce(); err != nil {
+		return ModelInfo{Name: c.name, File: filepath.Base(c.modelPath)}, err
+	}
+	info := c.info
+	info.Name = c.name
+	return info, nil
+}
+
 func loadLabels(path string) ([]string, error) {
 	f, err := os.Open(path)
 	if err != nil {
@@ -147,38 +172,60 @@ func loadLabels(path string) ([]string, error) {
 	return labels, nil
 }
 
+// ClassifyOptions overrides per-call output settings. Zero values fall back to the classifier defaults.
+type ClassifyOptions struct {
+	TopK int
+	// Probabilities converts logits to softmax probabilities before ranking.
+	Probabilities bool
+}
+
 // Classify decodes the image, preprocesses it for MobileNetV2, runs inference, and returns top-k label scores.
 func (c *Classifier) Classify(imageData []byte) ([]LabelScore, error) {
+	result, err := c.ClassifyWithOptions(imageData, ClassifyOptions{})
+	if err != nil {
+		return nil, err
+	}
+	return result.Predictions, nil
+}
+
+// ClassifyWithOptions is Classify with per-call top-k and score format; it also reports
+// model details and per-stage latency.
+func (c *Classifier) ClassifyWithOptions(imageData []byte, opts ClassifyOptions) (*ClassifyResult, error) {
 	if err := c.initOnce(); err != nil {
 		return nil, err
 	}
 
+	start := time.Now()
 	img, err := decodeImage(imageData)
 	if err != nil {
 		return nil, fmt.Errorf("decode image: %w", err)
 	}
+	decoded := time.Now()
 
 	// Preprocess: resize to 224x224, RGB, NCHW, ImageNet normalized float32.
 	inputData := preprocess(img)
 	if len(inputData) == 0 {
 		return nil, fmt.Errorf("preprocess failed")
 	}
+	preprocessed := time.Now()
 
-	c.mu.Lock()
-	inData := c.input.GetData()
-	if len(inData) < len(inputData) {
-		c.mu.Unlock()
-		return nil, fmt.Errorf("input tensor size %d < preprocessed %d", len(inData), len(inputData))
-	}
-	copy(inData, inputData)
-	err = c.session.Run()
-	c.mu.Unlock()
+	var outData []float32
+	err = c.runner.Run([][]float32{inputData}, func(outputs [][]float32) error {
+		outData = append([]float32(nil), outputs[0]...)
+		return nil
+	})
 	if err != nil {
-		return nil, fmt.Errorf("onnx run: %w", err)
+		return nil, err
 	}
+	inferred := time.Now()
 
-	outData := c.output.GetData()
+	if opts.Probabilities {
+		outData = softmax(outData)
+	}
 	k := c.topK
+	if opts.TopK > 0 {
+		k = opts.TopK
+	}
 	if k > len(c.labels) {
 		k = len(c.labels)
 	}
@@ -210,7 +257,49 @@ func (c *Classifier) Classify(imageData []byte) ([]LabelScore, error) {
 			Score: scored[i].score,
 		})
 	}
-	return result, nil
+
+	info := c.info
+	info.Name = c.name
+	return &ClassifyResult{
+		Predictions:   result,
+		Model:         info,
+		Preprocessing: PreprocessingMode,
+		Probabilities: opts.Probabilities,
+		Timing: Timing{
+			DecodeMs:     millis(decoded.Sub(start)),
+			PreprocessMs: millis(preprocessed.Sub(decoded)),
+			InferenceMs:  millis(inferred.Sub(preprocessed)),
+			TotalMs:      millis(time.Since(start)),
+		},
+	}, nil
+}
+
+func millis(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
+
+// softmax returns a normalized copy of logits.
+func softmax(logits []float32) []float32 {
+	out := make([]float32, len(logits))
+	if len(logits) == 0 {
+		return out
+	}
+	maxLogit := logits[0]
+	for _, v := range logits {
+		if v > maxLogit {
+			maxLogit = v
+		}
+	}
+	var sum float64
+	for i, v := range logits {
+		e := math.Exp(float64(v - maxLogit))
+		out[i] = float32(e)
+		sum += e
+	}
+	for i := range out {
+		out[i] = float32(float64(out[i]) / sum)
+	}
+	return out
 }
 
 func decodeImage(data []byte) (image.Image, error) {
@@ -253,6 +342,16 @@ func preprocess(img image.Image) []float32 {
 	return out
 }
 
+// ImageDimensions reads only the image header and returns its width and height, so callers
+// can meter or reject an upload before paying for a full decode.
+func ImageDimensions(data []byte) (int, int, error) {
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return 0, 0, err
+	}
+	return cfg.Width, cfg.Height, nil
+}
+
 // DecodeImageFromReader decodes an image from r (e.g. multipart form file). Used by handler.
 func DecodeImageFromReader(r io.Reader) (image.Image, error) {
 	img, _, err := image.Decode(r)
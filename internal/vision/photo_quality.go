@@ -0,0 +1,199 @@
+package vision
+
+import (
+	"fmt"
+	"image"
+	"math"
+
+	"golang.org/x/image/draw"
+)
+
+// Photo check statuses, ordered from best to worst.
+const (
+	CheckPass = "pass"
+	CheckWarn = "warn"
+	CheckFail = "fail"
+)
+
+// Thresholds for resume headshots. Blur and background values are measured on a copy
+// scaled to analysisSize on its longer side so they do not depend on upload resolution.
+const (
+	analysisSize = 512
+
+	minPhotoSide  = 400
+	goodPhotoSide = 600
+
+	blurFailVariance = 50
+	blurWarnVariance = 120
+
+	backgroundWarnStdDev = 25
+	backgroundFailStdDev = 40
+	backgroundBorder     = 0.08 // fraction of each side treated as background
+)
+
+// PhotoCheck is the outcome of one quality check.
+type PhotoCheck struct {
+	Name    string  `json:"name"`
+	Status  string  `json:"status"`
+	Value   float64 `json:"value"`
+	Message string  `json:"message"`
+}
+
+// PhotoQualityReport summarizes a headshot evaluation. Feedback lists the messages of
+// checks that did not pass, worst first.
+type PhotoQualityReport struct {
+	Width    int          `json:"width"`
+	Height   int          `json:"height"`
+	Status   string       `json:"status"`
+	Checks   []PhotoCheck `json:"checks"`
+	Feedback []string     `json:"feedback"`
+}
+
+// PhotoQualityChecker evaluates whether an image is usable as a resume headshot.
+type PhotoQualityChecker struct{}
+
+func NewPhotoQualityChecker() *PhotoQualityChecker {
+	return &PhotoQualityChecker{}
+}
+
+// Check decodes imageData and runs the resolution, blur and background checks.
+func (c *PhotoQualityChecker) Check(imageData []byte) (*PhotoQualityReport, error) {
+	img, err := decodeImage(imageData)
+	if err != nil {
+		return nil, fmt.Errorf("decode image: %w", err)
+	}
+	bounds := img.Bounds()
+	gray := scaledGray(img)
+
+	report := &PhotoQualityReport{
+		Width:  bounds.Dx(),
+		Height: bounds.Dy(),
+		Checks: []PhotoCheck{
+			checkResolution(bounds.Dx(), bounds.Dy()),
+			checkBlur(gray),
+			checkBackground(gray),
+		},
+	}
+
+	report.Status = CheckPass
+	for _, severity := range []string{CheckFail, CheckWarn} {
+		for _, check := range report.Checks {
+			if check.Status == severity {
+				report.Feedback = append(report.Feedback, check.Message)
+				if report.Status == CheckPass {
+					report.Status = severity
+				}
+			}
+		}
+	}
+	return report, nil
+}
+
+func checkResolution(w, h int) PhotoCheck {
+	side := w
+	if h < side {
+		side = h
+	}
+	check := PhotoCheck{Name: "resolution", Value: float64(side)}
+	switch {
+	case side < minPhotoSide:
+		check.Status = CheckFail
+		check.Message = fmt.Sprintf("Resolution is too low (%dx%d); use a photo at least %dpx on its shorter side.", w, h, goodPhotoSide)
+	case side < goodPhotoSide:
+		check.Status = CheckWarn
+		check.Message = fmt.Sprintf("Resolution is acceptable (%dx%d) but %dpx or more on the shorter side prints more sharply.", w, h, goodPhotoSide)
+	default:
+		check.Status = CheckPass
+		check.Message = "Resolution is sufficient."
+	}
+	return check
+}
+
+// checkBlur uses the variance of the Laplacian: sharp edges give high variance.
+func checkBlur(gray *image.Gray) PhotoCheck {
+	b := gray.Bounds()
+	var sum, sumSq float64
+	n := 0
+	for y := b.Min.Y + 1; y < b.Max.Y-1; y++ {
+		for x := b.Min.X + 1; x < b.Max.X-1; x++ {
+			lap := 4*float64(gray.GrayAt(x, y).Y) -
+				float64(gray.GrayAt(x-1, y).Y) - float64(gray.GrayAt(x+1, y).Y) -
+				float64(gray.GrayAt(x, y-1).Y) - float64(gray.GrayAt(x, y+1).Y)
+			sum += lap
+			sumSq += lap * lap
+			n++
+		}
+	}
+	variance := 0.0
+	if n > 0 {
+		mean := sum / float64(n)
+		variance = sumSq/float64(n) - mean*mean
+	}
+
+	check := PhotoCheck{Name: "sharpness", Value: math.Round(variance*10) / 10}
+	switch {
+	case variance < blurFailVariance:
+		check.Status = CheckFail
+		check.Message = "The photo looks blurry; hold the camera steady and make sure the face is in focus."
+	case variance < blurWarnVariance:
+		check.Status = CheckWarn
+		check.Message = "The photo is slightly soft; a sharper shot will look more professional."
+	default:
+		check.Status = CheckPass
+		check.Message = "The photo is sharp."
+	}
+	return check
+}
+
+// checkBackground measures the brightness spread of the border strips, where a
+// headshot's background usually is.
+func checkBackground(gray *image.Gray) PhotoCheck {
+	b := gray.Bounds()
+	borderX := int(float64(b.Dx()) * backgroundBorder)
+	borderY := int(float64(b.Dy()) * backgroundBorder)
+	var sum, sumSq float64
+	n := 0
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			if x >= b.Min.X+borderX && x < b.Max.X-borderX && y >= b.Min.Y+borderY {
+				continue // interior; the bottom strip is usually clothing, so only top and sides count
+			}
+			v := float64(gray.GrayAt(x, y).Y)
+			sum += v
+			sumSq += v * v
+			n++
+		}
+	}
+	stdDev := 0.0
+	if n > 0 {
+		mean := sum / float64(n)
+		stdDev = math.Sqrt(math.Max(sumSq/float64(n)-mean*mean, 0))
+	}
+
+	check := PhotoCheck{Name: "background", Value: math.Round(stdDev*10) / 10}
+	switch {
+	case stdDev > backgroundFailStdDev:
+		check.Status = CheckFail
+		check.Message = "The background is busy; stand in front of a plain wall or use a solid backdrop."
+	case stdDev > backgroundWarnStdDev:
+		check.Status = CheckWarn
+		check.Message = "The background is somewhat uneven; a plainer background keeps attention on you."
+	default:
+		check.Status = CheckPass
+		check.Message = "The background is uniform."
+	}
+	return check
+}
+
+// scaledGray converts img to grayscale, scaled so its longer side is at most analysisSize.
+func scaledGray(img image.Image) *image.Gray {
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	if longer := max(w, h); longer > analysisSize {
+		w = w * analysisSize / longer
+		h = h * analysisSize / longer
+	}
+	dst := image.NewGray(image.Rect(0, 0, max(w, 1), max(h, 1)))
+	draw.ApproxBiLinear.Scale(dst, dst.Bounds(), img, b, draw.Src, nil)
+	return dst
+}
@@ -0,0 +1,47 @@
+package vision
+
+import "sort"
+
+// Registry holds the classifiers available to the API, keyed by model name.
+type Registry struct {
+	defaultModel string
+	classifiers  map[string]*Classifier
+}
+
+// NewRegistry creates an empty registry; defaultModel is used when a request names no model.
+func NewRegistry(defaultModel string) *Registry {
+	return &Registry{
+		defaultModel: defaultModel,
+		classifiers:  make(map[string]*Classifier),
+	}
+}
+
+// Register adds or replaces the classifier for name.
+func (r *Registry) Register(name string, c *Classifier) {
+	c.name = name
+	r.classifiers[name] = c
+}
+
+// Get returns the classifier for name, or the default classifier when name is empty.
+func (r *Registry) Get(name string) (*Classifier, bool) {
+	if name == "" {
+		name = r.defaultModel
+	}
+	c, ok := r.classifiers[name]
+	return c, ok
+}
+
+// DefaultModel returns the name used when a request names no model.
+func (r *Registry) DefaultModel() string {
+	return r.defaultModel
+}
+
+// Names returns the registered model names in sorted order.
+func (r *Registry) Names() []string {
+	names := make([]string, 0, len(r.classifiers))
+	for name := range r.classifiers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
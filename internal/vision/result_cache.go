@@ -0,0 +1,21 @@
+package vision
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+)
+
+// ResultCache caches classification results so repeated uploads of the same image skip
+// decode, preprocessing and inference.
+type ResultCache interface {
+	GetResult(ctx context.Context, key string) (*ClassifyResult, bool, error)
+	SetResult(ctx context.Context, key string, result *ClassifyResult) error
+}
+
+// ResultCacheKey identifies a classification by image content, model and output options.
+func ResultCacheKey(imageData []byte, model string, opts ClassifyOptions) string {
+	sum := sha256.Sum256(imageData)
+	return fmt.Sprintf("%s:%s:k%d:p%t", model, hex.EncodeToString(sum[:]), opts.TopK, opts.Probabilities)
+}
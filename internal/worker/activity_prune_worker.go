@@ -0,0 +1,71 @@
+package worker
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+)
+
+// ActivityPruner deletes activity events past their retention.
+type ActivityPruner interface {
+	Prune(ctx context.Context) (int64, error)
+}
+
+// ActivityPruneWorker periodically deletes activity events past their retention. The
+// pruner is supplied at Start because it is built with the HTTP services. Pruning is
+// idempotent, so every instance runs it.
+type ActivityPruneWorker struct {
+	interval time.Duration
+	timeout  time.Duration
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+func NewActivityPruneWorker(interval time.Duration) *ActivityPruneWorker {
+	if interval <= 0 {
+		interval = time.Hour
+	}
+	return &ActivityPruneWorker{interval: interval, timeout: 10 * time.Minute}
+}
+
+func (w *ActivityPruneWorker) Start(ctx context.Context, pruner ActivityPruner) {
+	if w.cancel != nil {
+		return
+	}
+	workerCtx, cancel := context.WithCancel(ctx)
+	w.cancel = cancel
+
+	w.wg.Add(1)
+	go func() {
+		defer w.wg.Done()
+		ticker := time.NewTicker(w.interval)
+		defer ticker.Stop()
+		for {
+			w.runOnce(workerCtx, pruner)
+			select {
+			case <-workerCtx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+func (w *ActivityPruneWorker) runOnce(ctx context.Context, pruner ActivityPruner) {
+	runCtx, cancel := context.WithTimeout(ctx, w.timeout)
+	defer cancel()
+	if n, err := pruner.Prune(runCtx); err != nil && ctx.Err() == nil {
+		log.Printf("activity prune worker run failed: %v", err)
+	} else if n > 0 {
+		log.Printf("activity prune worker deleted %d old events", n)
+	}
+}
+
+func (w *ActivityPruneWorker) Close() {
+	if w.cancel != nil {
+		w.cancel()
+	}
+	w.wg.Wait()
+}
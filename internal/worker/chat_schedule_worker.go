@@ -0,0 +1,64 @@
+package worker
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+)
+
+// ScheduledMessageRunner sends the scheduled chat messages that are due.
+type ScheduledMessageRunner interface {
+	RunDue(ctx context.Context) (int, error)
+}
+
+// ChatScheduleWorker periodically sends due scheduled chat messages. The runner is
+// supplied at Start because it is built with the HTTP services.
+type ChatScheduleWorker struct {
+	interval time.Duration
+	timeout  time.Duration
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+func NewChatScheduleWorker(interval time.Duration) *ChatScheduleWorker {
+	if interval <= 0 {
+		interval = 15 * time.Second
+	}
+	return &ChatScheduleWorker{interval: interval, timeout: 5 * time.Minute}
+}
+
+func (w *ChatScheduleWorker) Start(ctx context.Context, runner ScheduledMessageRunner) {
+	if w.cancel != nil {
+		return
+	}
+	workerCtx, cancel := context.WithCancel(ctx)
+	w.cancel = cancel
+
+	w.wg.Add(1)
+	go func() {
+		defer w.wg.Done()
+		ticker := time.NewTicker(w.interval)
+		defer ticker.Stop()
+		for {
+			runCtx, cancelRun := context.WithTimeout(workerCtx, w.timeout)
+			if _, err := runner.RunDue(runCtx); err != nil && workerCtx.Err() == nil {
+				log.Printf("chat schedule worker run failed: %v", err)
+			}
+			cancelRun()
+			select {
+			case <-workerCtx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+func (w *ChatScheduleWorker) Close() {
+	if w.cancel != nil {
+		w.cancel()
+	}
+	w.wg.Wait()
+}
@@ -0,0 +1,86 @@
+package worker
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+)
+
+// ChatHistorySummarizer folds chat history that no longer fits the prompt into session summaries.
+type ChatHistorySummarizer interface {
+	SummarizePending(ctx context.Context) (int, error)
+}
+
+// chatSummaryJob names the summarizer's lock.
+const chatSummaryJob = "chat-summary"
+
+// ChatSummaryWorker periodically summarizes chat history that sends marked as too long, so
+// replies never wait for the summarization calls. The summarizer is supplied at Start
+// because it is built with the HTTP services. With a locker, only the instance holding the
+// lock runs each pass; without one every instance does.
+type ChatSummaryWorker struct {
+	interval time.Duration
+	timeout  time.Duration
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+func NewChatSummaryWorker(interval time.Duration) *ChatSummaryWorker {
+	if interval <= 0 {
+		interval = 10 * time.Second
+	}
+	return &ChatSummaryWorker{interval: interval, timeout: 5 * time.Minute}
+}
+
+func (w *ChatSummaryWorker) Start(ctx context.Context, summarizer ChatHistorySummarizer, locks JobLocker) {
+	if w.cancel != nil {
+		return
+	}
+	workerCtx, cancel := context.WithCancel(ctx)
+	w.cancel = cancel
+
+	w.wg.Add(1)
+	go func() {
+		defer w.wg.Done()
+		ticker := time.NewTicker(w.interval)
+		defer ticker.Stop()
+		for {
+			w.run(workerCtx, summarizer, locks)
+			select {
+			case <-workerCtx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// run summarizes once unless another instance holds the lock. A lock error skips the run
+// rather than risk two instances paying for the same summaries.
+func (w *ChatSummaryWorker) run(ctx context.Context, summarizer ChatHistorySummarizer, locks JobLocker) {
+	if locks != nil {
+		release, ok, err := locks.TryLock(ctx, chatSummaryJob)
+		if err != nil {
+			log.Printf("chat summary worker lock failed, skipping run: %v", err)
+			return
+		}
+		if !ok {
+			return
+		}
+		defer release()
+	}
+	runCtx, cancel := context.WithTimeout(ctx, w.timeout)
+	defer cancel()
+	if _, err := summarizer.SummarizePending(runCtx); err != nil && ctx.Err() == nil {
+		log.Printf("chat summary worker run failed: %v", err)
+	}
+}
+
+func (w *ChatSummaryWorker) Close() {
+	if w.cancel != nil {
+		w.cancel()
+	}
+	w.wg.Wait()
+}
@@ -0,0 +1,65 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+
+	"gopherai-resume/internal/model"
+	"gopherai-resume/internal/platform/rabbitmq"
+)
+
+// DirectPublisher stores chat messages synchronously in place of the persist queue. It is
+// the publisher in lite mode, where the server runs without RabbitMQ. Directly stored
+// messages are not embedded unless the publisher has an embed queue.
+type DirectPublisher struct {
+	repo  MessageStore
+	embed MessagePublisher
+}
+
+func NewDirectPublisher(repo MessageStore) *DirectPublisher {
+	return &DirectPublisher{repo: repo}
+}
+
+// NewEmbeddingDirectPublisher stores chat messages synchronously and then hands each stored
+// message to embed, as the persist worker does. It replaces the persist queue when sends are
+// serialized per session, so a send only returns, and releases the session lock, once its
+// turn is in the database. embed may be nil.
+func NewEmbeddingDirectPublisher(repo MessageStore, embed MessagePublisher) *DirectPublisher {
+	return &DirectPublisher{repo: repo, embed: embed}
+}
+
+func (p *DirectPublisher) Publish(ctx context.Context, msg model.Message) error {
+	if err := p.repo.Create(ctx, &msg); err != nil {
+		return fmt.Errorf("store message directly failed: %w", err)
+	}
+	if p.embed != nil {
+		if err := p.embed.Publish(ctx, msg); err != nil {
+			log.Printf("enqueue message embedding failed: %v", err)
+		}
+	}
+	return nil
+}
+
+// FallbackPublisher sends chat messages to the persist queue and stores them directly
+// while the broker is unavailable or disabled, so chat keeps working without RabbitMQ.
+type FallbackPublisher struct {
+	queue  MessagePublisher
+	direct *DirectPublisher
+}
+
+// NewFallbackPublisher builds the publisher; queue may be nil to always store directly.
+func NewFallbackPublisher(queue MessagePublisher, repo MessageStore) *FallbackPublisher {
+	return &FallbackPublisher{queue: queue, direct: NewDirectPublisher(repo)}
+}
+
+func (p *FallbackPublisher) Publish(ctx context.Context, msg model.Message) error {
+	if p.queue != nil {
+		err := p.queue.Publish(ctx, msg)
+		if !errors.Is(err, rabbitmq.ErrUnavailable) {
+			return err
+		}
+	}
+	return p.direct.Publish(ctx, msg)
+}
@@ -0,0 +1,204 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+
+	"gopherai-resume/internal/ai"
+	"gopherai-resume/internal/model"
+	"gopherai-resume/internal/platform/rabbitmq"
+)
+
+const (
+	// embedMaxRetries is how often a message whose embedding failed is queued again before
+	// it is dropped; embedRetryDelay doubles with each retry.
+	embedMaxRetries = 5
+	embedRetryDelay = 2 * time.Second
+	// embedRetryHeader counts the retries of a requeued message.
+	embedRetryHeader = "x-retry-count"
+)
+
+// MessageEmbeddingStore stores message embeddings; app.MessageEmbeddingRepository satisfies it.
+type MessageEmbeddingStore interface {
+	Create(ctx context.Context, embedding *model.MessageEmbedding) error
+}
+
+// MessageEmbedWorker consumes persisted messages and stores their embeddings for semantic
+// chat search and chat-history RAG. It runs off the send path so a slow or failing
+// embedding provider never delays chat.
+type MessageEmbedWorker struct {
+	conns         *rabbitmq.Connector
+	embeddingRepo MessageEmbeddingStore
+	embedder      ai.Embedder
+	embConfig     ai.EmbeddingConfig
+	queueName     string
+
+	mu     sync.Mutex
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+func NewMessageEmbedWorker(
+	conns *rabbitmq.Connector,
+	embeddingRepo MessageEmbeddingStore,
+	embedder ai.Embedder,
+	embConfig ai.EmbeddingConfig,
+	queueName string,
+) *MessageEmbedWorker {
+	return &MessageEmbedWorker{
+		conns:         conns,
+		embeddingRepo: embeddingRepo,
+		embedder:      embedder,
+		embConfig:     embConfig,
+		queueName:     queueName,
+	}
+}
+
+func (w *MessageEmbedWorker) Start(ctx context.Context) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.cancel != nil {
+		return nil
+	}
+
+	workerCtx, cancel := context.WithCancel(ctx)
+
+	ch, err := w.conns.Channel()
+	if err != nil {
+		cancel()
+		return fmt.Errorf("open embed worker channel failed: %w", err)
+	}
+
+	_, err = ch.QueueDeclare(
+		w.queueName,
+		true,
+		false,
+		false,
+		false,
+		nil,
+	)
+	if err != nil {
+		_ = ch.Close()
+		cancel()
+		return fmt.Errorf("declare embed worker queue failed: %w", err)
+	}
+
+	deliveries, err := ch.Consume(
+		w.queueName,
+		"",
+		false,
+		false,
+		false,
+		false,
+		nil,
+	)
+	if err != nil {
+		_ = ch.Close()
+		cancel()
+		return fmt.Errorf("consume embed queue failed: %w", err)
+	}
+
+	w.cancel = cancel
+	w.wg.Add(1)
+	go func() {
+		defer w.wg.Done()
+		defer ch.Close()
+
+		for {
+			select {
+			case <-workerCtx.Done():
+				return
+			case d, ok := <-deliveries:
+				if !ok {
+					return
+				}
+
+				var msg model.Message
+				if err := json.Unmarshal(d.Body, &msg); err != nil || msg.ID == 0 {
+					log.Printf("embed worker decode message failed: %v", err)
+					_ = d.Nack(false, false)
+					continue
+				}
+				if strings.TrimSpace(msg.Content) == "" {
+					_ = d.Ack(false)
+					continue
+				}
+
+				if err := w.embed(workerCtx, msg); err != nil {
+					log.Printf("embed worker embed message %d failed: %v", msg.ID, err)
+					w.retry(workerCtx, ch, d, msg.ID)
+					continue
+				}
+
+				_ = d.Ack(false)
+			}
+		}
+	}()
+
+	return nil
+}
+
+func (w *MessageEmbedWorker) embed(ctx context.Context, msg model.Message) error {
+	vec, err := w.embedder.Embed(ctx, w.embConfig, msg.Content)
+	if err != nil {
+		return err
+	}
+	embedding := model.MessageEmbedding{MessageID: msg.ID, UserID: msg.UserID}
+	embedding.SetEmbedding(vec)
+	return w.embeddingRepo.Create(ctx, &embedding)
+}
+
+// retry queues a message whose embedding failed again, after a delay that doubles with
+// each retry so a provider outage is not hammered, with its retry count in a header. After
+// embedMaxRetries the message is dropped. A worker stopping meanwhile, or a failed
+// publish, puts the delivery back on the queue as it was.
+func (w *MessageEmbedWorker) retry(ctx context.Context, ch *amqp.Channel, d amqp.Delivery, messageID uint) {
+	retries, _ := d.Headers[embedRetryHeader].(int32)
+	if retries >= embedMaxRetries {
+		log.Printf("embed worker drops message %d after %d retries", messageID, retries)
+		_ = d.Ack(false)
+		return
+	}
+	select {
+	case <-ctx.Done():
+		_ = d.Nack(false, true)
+		return
+	case <-time.After(embedRetryDelay << retries):
+	}
+	err := ch.PublishWithContext(ctx, "", w.queueName, false, false, amqp.Publishing{
+		ContentType:  d.ContentType,
+		Body:         d.Body,
+		DeliveryMode: amqp.Persistent,
+		Headers:      amqp.Table{embedRetryHeader: retries + 1},
+	})
+	if err != nil {
+		log.Printf("embed worker requeue message %d failed: %v", messageID, err)
+		_ = d.Nack(false, true)
+		return
+	}
+	_ = d.Ack(false)
+}
+
+// Restart stops the consumer and starts it again, e.g. on a new broker connection after
+// the old one closed its deliveries.
+func (w *MessageEmbedWorker) Restart(ctx context.Context) error {
+	w.Close()
+	return w.Start(ctx)
+}
+
+func (w *MessageEmbedWorker) Close() {
+	w.mu.Lock()
+	if w.cancel != nil {
+		w.cancel()
+		w.cancel = nil
+	}
+	w.mu.Unlock()
+	w.wg.Wait()
+}
@@ -7,38 +7,56 @@ import (
 	"log"
 	"sync"
 
-	amqp "github.com/rabbitmq/amqp091-go"
-
 	"gopherai-resume/internal/model"
-	"gopherai-resume/internal/repository"
+	"gopherai-resume/internal/platform/rabbitmq"
 )
 
+// MessageStore stores chat messages; app.MessageRepository satisfies it.
+type MessageStore interface {
+	Create(ctx context.Context, message *model.Message) error
+}
+
+// MessagePublisher forwards a persisted message to a downstream queue.
+type MessagePublisher interface {
+	Publish(ctx context.Context, msg model.Message) error
+}
+
 type MessagePersistWorker struct {
-	conn      *amqp.Connection
-	repo      *repository.MessageRepository
+	conns     *rabbitmq.Connector
+	repo      MessageStore
 	queueName string
+	// embedPublisher, when set, hands each stored message (with its ID) to the embed queue.
+	embedPublisher MessagePublisher
 
+	mu     sync.Mutex
 	cancel context.CancelFunc
 	wg     sync.WaitGroup
 }
 
-func NewMessagePersistWorker(conn *amqp.Connection, repo *repository.MessageRepository, queueName string) *MessagePersistWorker {
+func NewMessagePersistWorker(
+	conns *rabbitmq.Connector,
+	repo MessageStore,
+	queueName string,
+	embedPublisher MessagePublisher,
+) *MessagePersistWorker {
 	return &MessagePersistWorker{
-		conn:      conn,
-		repo:      repo,
-		queueName: queueName,
+		conns:          conns,
+		repo:           repo,
+		queueName:      queueName,
+		embedPublisher: embedPublisher,
 	}
 }
 
 func (w *MessagePersistWorker) Start(ctx context.Context) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
 	if w.cancel != nil {
 		return nil
 	}
 
 	workerCtx, cancel := context.WithCancel(ctx)
-	w.cancel = cancel
 
-	ch, err := w.conn.Channel()
+	ch, err := w.conns.Channel()
 	if err != nil {
 		cancel()
 		return fmt.Errorf("open worker channel failed: %w", err)
@@ -73,6 +91,7 @@ func (w *MessagePersistWorker) Start(ctx context.Context) error {
 		return fmt.Errorf("consume queue failed: %w", err)
 	}
 
+	w.cancel = cancel
 	w.wg.Add(1)
 	go func() {
 		defer w.wg.Done()
@@ -94,11 +113,16 @@ func (w *MessagePersistWorker) Start(ctx context.Context) error {
 					continue
 				}
 
-				if err := w.repo.Create(&msg); err != nil {
+				if err := w.repo.Create(workerCtx, &msg); err != nil {
 					log.Printf("worker persist message failed: %v", err)
 					_ = d.Nack(false, false)
 					continue
 				}
+				if w.embedPublisher != nil {
+					if err := w.embedPublisher.Publish(workerCtx, msg); err != nil {
+						log.Printf("worker enqueue message embedding failed: %v", err)
+					}
+				}
 
 				_ = d.Ack(false)
 			}
@@ -108,9 +132,19 @@ func (w *MessagePersistWorker) Start(ctx context.Context) error {
 	return nil
 }
 
+// Restart stops the consumer and starts it again, e.g. on a new broker connection after
+// the old one closed its deliveries.
+func (w *MessagePersistWorker) Restart(ctx context.Context) error {
+	w.Close()
+	return w.Start(ctx)
+}
+
 func (w *MessagePersistWorker) Close() {
+	w.mu.Lock()
 	if w.cancel != nil {
 		w.cancel()
+		w.cancel = nil
 	}
+	w.mu.Unlock()
 	w.wg.Wait()
 }
@@ -0,0 +1,77 @@
+package worker
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+)
+
+// ReminderDispatcher queues emails for due application reminders.
+type ReminderDispatcher interface {
+	DispatchReminders(ctx context.Context) (int, error)
+}
+
+// OutboxDeliverer sends queued emails that are due.
+type OutboxDeliverer interface {
+	DeliverDue(ctx context.Context) (int, error)
+}
+
+// NotificationWorker periodically turns due application reminders into emails and delivers
+// the email outbox. Its dependencies are supplied at Start because they are built with the
+// HTTP services.
+type NotificationWorker struct {
+	interval time.Duration
+	timeout  time.Duration
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+func NewNotificationWorker(interval time.Duration) *NotificationWorker {
+	if interval <= 0 {
+		interval = 10 * time.Second
+	}
+	return &NotificationWorker{interval: interval, timeout: 2 * time.Minute}
+}
+
+func (w *NotificationWorker) Start(ctx context.Context, reminders ReminderDispatcher, outbox OutboxDeliverer) {
+	if w.cancel != nil {
+		return
+	}
+	workerCtx, cancel := context.WithCancel(ctx)
+	w.cancel = cancel
+
+	w.wg.Add(1)
+	go func() {
+		defer w.wg.Done()
+		ticker := time.NewTicker(w.interval)
+		defer ticker.Stop()
+		for {
+			w.runOnce(workerCtx, reminders, outbox)
+			select {
+			case <-workerCtx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+func (w *NotificationWorker) runOnce(ctx context.Context, reminders ReminderDispatcher, outbox OutboxDeliverer) {
+	runCtx, cancel := context.WithTimeout(ctx, w.timeout)
+	defer cancel()
+	if _, err := reminders.DispatchReminders(runCtx); err != nil {
+		log.Printf("notification worker dispatch reminders failed: %v", err)
+	}
+	if _, err := outbox.DeliverDue(runCtx); err != nil && ctx.Err() == nil {
+		log.Printf("notification worker deliver outbox failed: %v", err)
+	}
+}
+
+func (w *NotificationWorker) Close() {
+	if w.cancel != nil {
+		w.cancel()
+	}
+	w.wg.Wait()
+}
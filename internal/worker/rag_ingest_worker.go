@@ -0,0 +1,147 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"gopherai-resume/internal/platform/rabbitmq"
+)
+
+// RAGIngestProcessor chunks, embeds and stores one pending document and records the
+// outcome on it. An ingest that ctx cut short returns ctx's error in its chain.
+type RAGIngestProcessor interface {
+	ProcessIngest(ctx context.Context, documentID uint) error
+}
+
+// RAGIngestWorker ingests documents queued by the HTTP API, so large uploads do not hold
+// the request open for the whole embedding loop. The processor is supplied at Start
+// because it is built with the HTTP services.
+type RAGIngestWorker struct {
+	conns     *rabbitmq.Connector
+	queueName string
+	timeout   time.Duration
+	// processor is kept from Start for Restart.
+	processor RAGIngestProcessor
+
+	mu     sync.Mutex
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+func NewRAGIngestWorker(conns *rabbitmq.Connector, queueName string) *RAGIngestWorker {
+	return &RAGIngestWorker{
+		conns:     conns,
+		queueName: queueName,
+		timeout:   30 * time.Minute,
+	}
+}
+
+func (w *RAGIngestWorker) Start(ctx context.Context, processor RAGIngestProcessor) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.cancel != nil {
+		return nil
+	}
+	w.processor = processor
+
+	workerCtx, cancel := context.WithCancel(ctx)
+
+	ch, err := w.conns.Channel()
+	if err != nil {
+		cancel()
+		return fmt.Errorf("open ingest worker channel failed: %w", err)
+	}
+	if _, err := ch.QueueDeclare(w.queueName, true, false, false, false, nil); err != nil {
+		_ = ch.Close()
+		cancel()
+		return fmt.Errorf("declare ingest worker queue failed: %w", err)
+	}
+	// Ingests are bound by the embedding API; take one at a time.
+	if err := ch.Qos(1, 0, false); err != nil {
+		_ = ch.Close()
+		cancel()
+		return fmt.Errorf("set ingest worker qos failed: %w", err)
+	}
+	deliveries, err := ch.Consume(w.queueName, "", false, false, false, false, nil)
+	if err != nil {
+		_ = ch.Close()
+		cancel()
+		return fmt.Errorf("consume ingest queue failed: %w", err)
+	}
+
+	w.cancel = cancel
+	w.wg.Add(1)
+	go func() {
+		defer w.wg.Done()
+		defer ch.Close()
+
+		for {
+			select {
+			case <-workerCtx.Done():
+				return
+			case d, ok := <-deliveries:
+				if !ok {
+					return
+				}
+
+				var job rabbitmq.RAGIngestJob
+				if err := json.Unmarshal(d.Body, &job); err != nil || job.DocumentID == 0 {
+					log.Printf("ingest worker decode job failed: %v", err)
+					_ = d.Nack(false, false)
+					continue
+				}
+
+				// The processor marks the document failed itself, so the job is acked either way,
+				// unless the ingest was cut short by the worker stopping or the job timing out:
+				// then it goes back to the queue, and the processor gives up after a few tries.
+				jobCtx, cancelJob := context.WithTimeout(workerCtx, w.timeout)
+				err := processor.ProcessIngest(jobCtx, job.DocumentID)
+				cancelJob()
+				if workerCtx.Err() != nil {
+					log.Printf("ingest worker stopped during document %d, requeueing", job.DocumentID)
+					_ = d.Nack(false, true)
+					return
+				}
+				if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+					log.Printf("ingest worker ingest of document %d interrupted, requeueing: %v", job.DocumentID, err)
+					_ = d.Nack(false, true)
+					continue
+				}
+				if err != nil {
+					log.Printf("ingest worker ingest document %d failed: %v", job.DocumentID, err)
+				}
+				_ = d.Ack(false)
+			}
+		}
+	}()
+
+	return nil
+}
+
+// Restart stops the consumer and starts it again with the processor from the last Start;
+// it does nothing if the worker was never started.
+func (w *RAGIngestWorker) Restart(ctx context.Context) error {
+	w.mu.Lock()
+	processor := w.processor
+	w.mu.Unlock()
+	if processor == nil {
+		return nil
+	}
+	w.Close()
+	return w.Start(ctx, processor)
+}
+
+func (w *RAGIngestWorker) Close() {
+	w.mu.Lock()
+	if w.cancel != nil {
+		w.cancel()
+		w.cancel = nil
+	}
+	w.mu.Unlock()
+	w.wg.Wait()
+}
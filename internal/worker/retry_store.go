@@ -0,0 +1,41 @@
+package worker
+
+import (
+	"context"
+
+	"gopherai-resume/internal/model"
+	"gopherai-resume/internal/platform/mysql"
+)
+
+// RetryingMessageStore retries message writes that fail with a transient MySQL error, so
+// a brief database failover does not dead-letter queued messages or fail chat requests.
+type RetryingMessageStore struct {
+	store MessageStore
+	retry mysql.RetryPolicy
+}
+
+func NewRetryingMessageStore(store MessageStore, retry mysql.RetryPolicy) *RetryingMessageStore {
+	return &RetryingMessageStore{store: store, retry: retry}
+}
+
+func (s *RetryingMessageStore) Create(ctx context.Context, message *model.Message) error {
+	return s.retry.Do(ctx, func() error {
+		return s.store.Create(ctx, message)
+	})
+}
+
+// RetryingEmbeddingStore is RetryingMessageStore for message embeddings.
+type RetryingEmbeddingStore struct {
+	store MessageEmbeddingStore
+	retry mysql.RetryPolicy
+}
+
+func NewRetryingEmbeddingStore(store MessageEmbeddingStore, retry mysql.RetryPolicy) *RetryingEmbeddingStore {
+	return &RetryingEmbeddingStore{store: store, retry: retry}
+}
+
+func (s *RetryingEmbeddingStore) Create(ctx context.Context, embedding *model.MessageEmbedding) error {
+	return s.retry.Do(ctx, func() error {
+		return s.store.Create(ctx, embedding)
+	})
+}
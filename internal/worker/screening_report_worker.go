@@ -0,0 +1,131 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"gopherai-resume/internal/platform/rabbitmq"
+)
+
+// ScreeningReportGenerator builds one screening report and records the outcome on it.
+type ScreeningReportGenerator interface {
+	GenerateReport(ctx context.Context, reportID uint) error
+}
+
+// ScreeningReportWorker generates screening reports queued by the HTTP API. The generator is
+// supplied at Start because it is built with the HTTP services.
+type ScreeningReportWorker struct {
+	conns     *rabbitmq.Connector
+	queueName string
+	timeout   time.Duration
+	// generator is kept from Start for Restart.
+	generator ScreeningReportGenerator
+
+	mu     sync.Mutex
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+func NewScreeningReportWorker(conns *rabbitmq.Connector, queueName string) *ScreeningReportWorker {
+	return &ScreeningReportWorker{
+		conns:     conns,
+		queueName: queueName,
+		timeout:   5 * time.Minute,
+	}
+}
+
+func (w *ScreeningReportWorker) Start(ctx context.Context, generator ScreeningReportGenerator) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.cancel != nil {
+		return nil
+	}
+	w.generator = generator
+
+	workerCtx, cancel := context.WithCancel(ctx)
+
+	ch, err := w.conns.Channel()
+	if err != nil {
+		cancel()
+		return fmt.Errorf("open report worker channel failed: %w", err)
+	}
+	if _, err := ch.QueueDeclare(w.queueName, true, false, false, false, nil); err != nil {
+		_ = ch.Close()
+		cancel()
+		return fmt.Errorf("declare report worker queue failed: %w", err)
+	}
+	// Reports are LLM-bound; take one at a time.
+	if err := ch.Qos(1, 0, false); err != nil {
+		_ = ch.Close()
+		cancel()
+		return fmt.Errorf("set report worker qos failed: %w", err)
+	}
+	deliveries, err := ch.Consume(w.queueName, "", false, false, false, false, nil)
+	if err != nil {
+		_ = ch.Close()
+		cancel()
+		return fmt.Errorf("consume report queue failed: %w", err)
+	}
+
+	w.cancel = cancel
+	w.wg.Add(1)
+	go func() {
+		defer w.wg.Done()
+		defer ch.Close()
+
+		for {
+			select {
+			case <-workerCtx.Done():
+				return
+			case d, ok := <-deliveries:
+				if !ok {
+					return
+				}
+
+				var job rabbitmq.ScreeningReportJob
+				if err := json.Unmarshal(d.Body, &job); err != nil || job.ReportID == 0 {
+					log.Printf("report worker decode job failed: %v", err)
+					_ = d.Nack(false, false)
+					continue
+				}
+
+				// The generator marks the report failed itself, so the job is acked either way.
+				jobCtx, cancelJob := context.WithTimeout(workerCtx, w.timeout)
+				if err := generator.GenerateReport(jobCtx, job.ReportID); err != nil {
+					log.Printf("report worker generate report %d failed: %v", job.ReportID, err)
+				}
+				cancelJob()
+				_ = d.Ack(false)
+			}
+		}
+	}()
+
+	return nil
+}
+
+// Restart stops the consumer and starts it again with the generator from the last Start;
+// it does nothing if the worker was never started.
+func (w *ScreeningReportWorker) Restart(ctx context.Context) error {
+	w.mu.Lock()
+	generator := w.generator
+	w.mu.Unlock()
+	if generator == nil {
+		return nil
+	}
+	w.Close()
+	return w.Start(ctx, generator)
+}
+
+func (w *ScreeningReportWorker) Close() {
+	w.mu.Lock()
+	if w.cancel != nil {
+		w.cancel()
+		w.cancel = nil
+	}
+	w.mu.Unlock()
+	w.wg.Wait()
+}
@@ -0,0 +1,94 @@
+package worker
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+)
+
+// ShadowEmbeddingBackfiller embeds the chunks that have no shadow embedding yet.
+type ShadowEmbeddingBackfiller interface {
+	BackfillShadowEmbeddings(ctx context.Context) (int, error)
+}
+
+// JobLocker lets one server instance at a time run a background job.
+type JobLocker interface {
+	// TryLock takes the lock for the job name if it is free. When ok, release must be
+	// called once the run is done.
+	TryLock(ctx context.Context, name string) (release func(), ok bool, err error)
+}
+
+// shadowEmbedJob names the backfill's lock.
+const shadowEmbedJob = "shadow-embed"
+
+// ShadowEmbedWorker periodically embeds new chunks with the shadow embedding model, off
+// the ingest path so evaluating a model never slows uploads down. The backfiller is
+// supplied at Start because it is built with the HTTP services. With a locker, only the
+// instance holding the lock runs each backfill; without one every instance does.
+type ShadowEmbedWorker struct {
+	interval time.Duration
+	timeout  time.Duration
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+func NewShadowEmbedWorker(interval time.Duration) *ShadowEmbedWorker {
+	if interval <= 0 {
+		interval = time.Minute
+	}
+	return &ShadowEmbedWorker{interval: interval, timeout: 30 * time.Minute}
+}
+
+func (w *ShadowEmbedWorker) Start(ctx context.Context, backfiller ShadowEmbeddingBackfiller, locks JobLocker) {
+	if w.cancel != nil {
+		return
+	}
+	workerCtx, cancel := context.WithCancel(ctx)
+	w.cancel = cancel
+
+	w.wg.Add(1)
+	go func() {
+		defer w.wg.Done()
+		ticker := time.NewTicker(w.interval)
+		defer ticker.Stop()
+		for {
+			w.run(workerCtx, backfiller, locks)
+			select {
+			case <-workerCtx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// run backfills once unless another instance holds the lock. A lock error skips the run
+// rather than risk two instances embedding the same chunks.
+func (w *ShadowEmbedWorker) run(ctx context.Context, backfiller ShadowEmbeddingBackfiller, locks JobLocker) {
+	if locks != nil {
+		release, ok, err := locks.TryLock(ctx, shadowEmbedJob)
+		if err != nil {
+			log.Printf("shadow embed worker lock failed, skipping run: %v", err)
+			return
+		}
+		if !ok {
+			return
+		}
+		defer release()
+	}
+	runCtx, cancel := context.WithTimeout(ctx, w.timeout)
+	defer cancel()
+	n, err := backfiller.BackfillShadowEmbeddings(runCtx)
+	if err != nil && ctx.Err() == nil {
+		log.Printf("shadow embed worker run failed after %d chunks: %v", n, err)
+	}
+}
+
+func (w *ShadowEmbedWorker) Close() {
+	if w.cancel != nil {
+		w.cancel()
+	}
+	w.wg.Wait()
+}